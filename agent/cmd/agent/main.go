@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 	"github.com/aetherpanel/aether-panel/agent/internal/docker"
 	"github.com/aetherpanel/aether-panel/agent/internal/server"
 	"github.com/aetherpanel/aether-panel/agent/internal/api"
+	"github.com/aetherpanel/aether-panel/agent/internal/tracing"
+	"github.com/aetherpanel/aether-panel/agent/internal/upload"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +31,12 @@ func main() {
 		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
 
+	// Initialize tracing (a no-op unless enabled)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
 	// Initialize Docker client
 	dockerClient, err := docker.NewClient()
 	if err != nil {
@@ -39,8 +48,18 @@ func main() {
 	// Initialize server manager
 	serverManager := server.NewManager(dockerClient, cfg, logger)
 
+	// Initialize chunked upload storage
+	uploads, err := upload.NewStore(
+		filepath.Join(cfg.Storage.TmpPath, "uploads"),
+		time.Duration(cfg.Uploads.AbandonAfter)*time.Minute,
+		logger,
+	)
+	if err != nil {
+		logger.Fatal("Failed to initialize upload storage", zap.Error(err))
+	}
+
 	// Initialize API server for panel communication
-	apiServer := api.NewServer(cfg, serverManager, logger)
+	apiServer := api.NewServer(cfg, serverManager, uploads, logger)
 
 	// Start background services
 	ctx, cancel := context.WithCancel(context.Background())
@@ -49,12 +68,24 @@ func main() {
 	// Start health check routine
 	go serverManager.StartHealthCheck(ctx)
 
+	// Recover from Docker daemon restarts
+	go serverManager.StartDockerMonitor(ctx)
+
 	// Start metrics collection
 	go serverManager.StartMetricsCollection(ctx)
 
+	// Follow container events for immediate status updates and OOM detection
+	go serverManager.StartEventWatch(ctx)
+
+	// Buffer chat lines from server consoles for the panel
+	go serverManager.StartChatWatch(ctx)
+
 	// Start console streaming
 	go serverManager.StartConsoleStreaming(ctx)
 
+	// Discard abandoned uploads
+	go uploads.StartCleanup(ctx)
+
 	// Register with panel
 	if err := apiServer.RegisterWithPanel(ctx); err != nil {
 		logger.Warn("Failed to register with panel, will retry", zap.Error(err))
@@ -83,5 +114,9 @@ func main() {
 		logger.Error("API server shutdown error", zap.Error(err))
 	}
 
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to flush traces", zap.Error(err))
+	}
+
 	logger.Info("👋 Agent stopped")
 }
@@ -1,14 +1,23 @@
 package api
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aetherpanel/aether-panel/agent/internal/config"
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
 	"github.com/aetherpanel/aether-panel/agent/internal/server"
+	"github.com/aetherpanel/aether-panel/agent/internal/tracing"
+	"github.com/aetherpanel/aether-panel/agent/internal/upload"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/websocket/v2"
@@ -20,28 +29,40 @@ type Server struct {
 	app     *fiber.App
 	config  *config.Config
 	manager *server.Manager
+	uploads *upload.Store
 	logger  *zap.Logger
+
+	uploadLimit atomic.Int64 // Node upload limit pushed by the panel in bytes, 0 if unset
 }
 
 // NewServer creates a new API server
-func NewServer(cfg *config.Config, manager *server.Manager, log *zap.Logger) *Server {
+func NewServer(cfg *config.Config, manager *server.Manager, uploads *upload.Store, log *zap.Logger) *Server {
 	app := fiber.New(fiber.Config{
 		AppName:               "Aether Agent",
 		ReadTimeout:           30 * time.Second,
 		WriteTimeout:          30 * time.Second,
 		IdleTimeout:           120 * time.Second,
+		BodyLimit:             int(cfg.Uploads.MaxPartSize+1) * 1024 * 1024, // Room for one upload part
 		DisableStartupMessage: true,
 	})
 
 	app.Use(logger.New())
+	app.Use(tracing.Middleware())
 
 	s := &Server{
 		app:     app,
 		config:  cfg,
 		manager: manager,
+		uploads: uploads,
 		logger:  log,
 	}
 
+	state, err := loadNodeState(cfg.Storage.StateFile)
+	if err != nil {
+		log.Warn("Failed to load node state, using the agent config", zap.Error(err))
+	}
+	s.uploadLimit.Store(state.MaxUploadSize)
+
 	s.setupRoutes()
 	return s
 }
@@ -54,10 +75,42 @@ func (s *Server) setupRoutes() {
 	// API routes with token authentication
 	api := s.app.Group("/api", s.authMiddleware)
 
+	// Fail fast while the Docker daemon is unreachable
+	api.Use("/servers", s.requireDocker)
+	api.Use("/system", s.requireDocker)
+	api.Use("/images", s.requireDocker)
+
 	// Server management
 	api.Post("/servers", s.createServer)
+	api.Get("/servers/discover", s.discoverServers)
+	api.Post("/servers/orphans", s.cleanOrphans)
+	api.Get("/servers/statuses", s.getServerStatuses)
 	api.Get("/servers/:id", s.getServer)
 	api.Delete("/servers/:id", s.deleteServer)
+	api.Put("/servers/:id/image", s.updateServerImage)
+	api.Put("/servers/:id/startup", s.updateServerStartup)
+	api.Put("/servers/:id/crash-recovery", s.updateCrashRecovery)
+	api.Put("/servers/:id/console", s.updateConsoleOutput)
+	api.Put("/servers/:id/mounts", s.updateMounts)
+	api.Put("/servers/:id/bandwidth", s.updateBandwidth)
+	api.Post("/servers/:id/reinstall", s.reinstallServer)
+	api.Post("/servers/:id/backups", s.createBackup)
+	api.Post("/servers/:id/backups/:backupId/restore", s.restoreBackup)
+	api.Get("/servers/:id/backups/:backupId/download", s.downloadBackup)
+	api.Post("/backups/:backupId/verify", s.verifyBackup)
+
+	// Minecraft worlds
+	api.Get("/servers/:id/worlds", s.listWorlds)
+	api.Delete("/servers/:id/worlds/:world", s.deleteWorld)
+	api.Post("/servers/:id/worlds/:world/backups", s.backupWorld)
+	api.Post("/servers/:id/worlds/:world/backups/:backupId/restore", s.restoreWorld)
+
+	// Chunked uploads
+	api.Post("/servers/:id/uploads", s.createUpload)
+	api.Get("/servers/:id/uploads/:uploadId", s.getUpload)
+	api.Put("/servers/:id/uploads/:uploadId/parts/:part", s.uploadPart)
+	api.Post("/servers/:id/uploads/:uploadId/complete", s.completeUpload)
+	api.Delete("/servers/:id/uploads/:uploadId", s.abortUpload)
 
 	// Power actions
 	api.Post("/servers/:id/power/start", s.startServer)
@@ -68,10 +121,21 @@ func (s *Server) setupRoutes() {
 	// Console
 	api.Post("/servers/:id/command", s.sendCommand)
 	api.Get("/servers/:id/logs", s.getLogs)
+	api.Get("/servers/:id/chat", s.getChat)
 
 	// Stats
 	api.Get("/servers/:id/stats", s.getStats)
 
+	// Private registry credentials
+	api.Put("/registries", s.updateRegistries)
+
+	// Image warmup
+	api.Get("/images", s.listImages)
+	api.Post("/images/pull", s.pullImage)
+
+	// Node limits pushed by the panel
+	api.Put("/limits", s.updateLimits)
+
 	// System info
 	api.Get("/system", s.getSystemInfo)
 
@@ -96,12 +160,33 @@ func (s *Server) authMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
-// healthCheck returns agent health status
+// requireDocker rejects requests with 503 while Docker is unavailable
+func (s *Server) requireDocker(c *fiber.Ctx) error {
+	if !s.manager.DockerAvailable() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": docker.ErrUnavailable.Error(),
+		})
+	}
+	return c.Next()
+}
+
+// healthCheck returns agent health status. The node reports unhealthy while
+// the Docker daemon is unreachable.
 func (s *Server) healthCheck(c *fiber.Ctx) error {
+	if !s.manager.DockerAvailable() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "unhealthy",
+			"node_id": s.config.NodeID,
+			"version": "1.0.0",
+			"docker":  false,
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"status":  "healthy",
 		"node_id": s.config.NodeID,
 		"version": "1.0.0",
+		"docker":  true,
 	})
 }
 
@@ -114,7 +199,17 @@ func (s *Server) createServer(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.manager.CreateServer(c.Context(), &cfg); err != nil {
+	if err := s.manager.CreateServer(c.UserContext(), &cfg); err != nil {
+		if errors.Is(err, server.ErrServerCreating) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, server.ErrMountNotAllowed) || errors.Is(err, server.ErrCapabilityNotAllowed) || errors.Is(err, server.ErrInvalidStartup) || errors.Is(err, server.ErrInvalidProtocol) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		s.logger.Error("Failed to create server", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -127,18 +222,60 @@ func (s *Server) createServer(c *fiber.Ctx) error {
 	})
 }
 
+// discoverServers lists Aether-managed containers present on this node
+func (s *Server) discoverServers(c *fiber.Ctx) error {
+	servers, err := s.manager.DiscoverServers(c.UserContext())
+	if err != nil {
+		s.logger.Error("Failed to discover servers", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"servers": servers,
+	})
+}
+
+// cleanOrphans reports, and unless dry_run is set removes, the containers and
+// data directories of servers missing from the panel's list
+func (s *Server) cleanOrphans(c *fiber.Ctx) error {
+	var req struct {
+		Known  []string `json:"known"`
+		DryRun bool     `json:"dry_run"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Known == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Known servers are required",
+		})
+	}
+
+	orphans, err := s.manager.CleanOrphans(c.UserContext(), req.Known, req.DryRun)
+	if err != nil {
+		s.logger.Error("Failed to clean orphans", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run": req.DryRun,
+		"orphans": orphans,
+	})
+}
+
 // getServer returns server information
 func (s *Server) getServer(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	status, err := s.manager.GetServerStatus(c.Context(), serverID)
+	status, err := s.manager.GetServerStatus(c.UserContext(), serverID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Server not found",
 		})
 	}
 
-	stats, _ := s.manager.GetServerStats(c.Context(), serverID)
+	stats, _ := s.manager.GetServerStats(c.UserContext(), serverID)
 
 	return c.JSON(fiber.Map{
 		"id":     serverID,
@@ -147,11 +284,19 @@ func (s *Server) getServer(c *fiber.Ctx) error {
 	})
 }
 
+// getServerStatuses returns the status of every server on this node, so the
+// panel can correct statuses that drifted
+func (s *Server) getServerStatuses(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"statuses": s.manager.ServerStatuses(c.UserContext()),
+	})
+}
+
 // deleteServer removes a server
 func (s *Server) deleteServer(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	if err := s.manager.DeleteServer(c.Context(), serverID); err != nil {
+	if err := s.manager.DeleteServer(c.UserContext(), serverID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -163,11 +308,104 @@ func (s *Server) deleteServer(c *fiber.Ctx) error {
 	})
 }
 
-// startServer starts a server
-func (s *Server) startServer(c *fiber.Ctx) error {
+// updateServerImage changes the Docker image a server runs on
+func (s *Server) updateServerImage(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req struct {
+		Image string `json:"image"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Image == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Image is required",
+		})
+	}
+
+	if err := s.manager.UpdateServerImage(serverID, req.Image); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Image updated, it will be pulled on next start",
+	})
+}
+
+// reinstallServer rebuilds a server, optionally wiping its data
+func (s *Server) reinstallServer(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req struct {
+		WipeData bool `json:"wipe_data"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if err := s.manager.ReinstallServer(c.UserContext(), serverID, req.WipeData); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"wipe_data": req.WipeData,
+	})
+}
+
+// createBackup archives a server's data directory and reports the archive's
+// size and checksum
+func (s *Server) createBackup(c *fiber.Ctx) error {
+	var req struct {
+		BackupID string `json:"backup_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.BackupID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Backup id is required",
+		})
+	}
+
+	archive, err := s.manager.CreateBackup(c.UserContext(), c.Params("id"), req.BackupID)
+	if err != nil {
+		s.logger.Error("Failed to create backup", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(archive)
+}
+
+// restoreBackup restores a backup archive into a server's data directory
+func (s *Server) restoreBackup(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	if err := s.manager.StartServer(c.Context(), serverID); err != nil {
+	var req struct {
+		Checksum string `json:"checksum"`
+		WipeData bool   `json:"wipe_data"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Checksum == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Checksum is required",
+		})
+	}
+
+	err := s.manager.RestoreBackup(c.UserContext(), serverID, c.Params("backupId"), server.RestoreOptions{
+		Checksum: req.Checksum,
+		WipeData: req.WipeData,
+	})
+	if errors.Is(err, server.ErrChecksumMismatch) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -175,20 +413,219 @@ func (s *Server) startServer(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"status":  "starting",
 	})
 }
 
-// stopServer stops a server
-func (s *Server) stopServer(c *fiber.Ctx) error {
+// updateServerStartup replaces a server's startup command, environment and allocations
+func (s *Server) updateServerStartup(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	if err := s.manager.StopServer(c.Context(), serverID); err != nil {
+	var req server.StartupSpec
+	if err := c.BodyParser(&req); err != nil || req.StartupCmd == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Startup command is required",
+		})
+	}
+
+	err := s.manager.UpdateServerStartup(serverID, req)
+	if errors.Is(err, server.ErrMountNotAllowed) || errors.Is(err, server.ErrCapabilityNotAllowed) || errors.Is(err, server.ErrInvalidStartup) || errors.Is(err, server.ErrInvalidProtocol) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Startup updated, it applies on next start",
+	})
+}
+
+// updateBandwidth replaces a server's bandwidth limits
+func (s *Server) updateBandwidth(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req struct {
+		NetworkIn  int64 `json:"network_in"`  // bytes/s, 0 for unlimited
+		NetworkOut int64 `json:"network_out"` // bytes/s, 0 for unlimited
+	}
+	if err := c.BodyParser(&req); err != nil || req.NetworkIn < 0 || req.NetworkOut < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bandwidth limits",
+		})
+	}
+
+	if err := s.manager.UpdateBandwidth(c.UserContext(), serverID, req.NetworkIn, req.NetworkOut); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// updateCrashRecovery replaces a server's crash recovery policy
+func (s *Server) updateCrashRecovery(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req server.CrashRecovery
+	if err := c.BodyParser(&req); err != nil || req.MaxRestarts < 0 || req.Window < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid crash recovery policy",
+		})
+	}
+
+	if err := s.manager.UpdateCrashRecovery(serverID, req); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// updateMounts replaces the extra host paths mounted into a server
+func (s *Server) updateMounts(c *fiber.Ctx) error {
+	var req struct {
+		Mounts []server.Mount `json:"mounts"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	err := s.manager.UpdateMounts(c.Params("id"), req.Mounts)
+	if errors.Is(err, server.ErrMountNotAllowed) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// downloadBackup streams a backup archive once it has been verified against
+// the checksum given as a query parameter
+func (s *Server) downloadBackup(c *fiber.Ctx) error {
+	backupID := c.Params("backupId")
+
+	f, size, err := s.manager.OpenBackup(backupID, c.Query("checksum"))
+	if err != nil {
+		return backupError(c, err)
+	}
+
+	c.Set("Content-Type", "application/gzip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backupID+".tar.gz"))
+	return c.SendStream(f, int(size))
+}
+
+// verifyBackup recomputes the checksum of a stored backup archive
+func (s *Server) verifyBackup(c *fiber.Ctx) error {
+	var req struct {
+		Checksum string `json:"checksum"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Checksum == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Checksum is required",
+		})
+	}
+
+	if err := s.manager.VerifyBackup(c.Params("backupId"), req.Checksum); err != nil {
+		return backupError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// backupError maps backup archive errors to HTTP responses
+func backupError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, server.ErrBackupNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, server.ErrChecksumMismatch):
+		status = fiber.StatusUnprocessableEntity
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// updateConsoleOutput sets whether a server's console is sent as plain text
+func (s *Server) updateConsoleOutput(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req struct {
+		StripANSI bool `json:"strip_ansi"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := s.manager.UpdateConsoleOutput(serverID, req.StripANSI); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// powerError maps power action errors to HTTP responses
+func powerError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, server.ErrServerCreating) {
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// startServer starts a server
+func (s *Server) startServer(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	if err := s.manager.StartServer(c.UserContext(), serverID); err != nil {
+		return powerError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"status":  "starting",
+	})
+}
+
+// stopServer stops a server
+func (s *Server) stopServer(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	if err := s.manager.StopServer(c.UserContext(), serverID); err != nil {
+		return powerError(c, err)
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"status":  "stopping",
@@ -199,10 +636,8 @@ func (s *Server) stopServer(c *fiber.Ctx) error {
 func (s *Server) restartServer(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	if err := s.manager.RestartServer(c.Context(), serverID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.manager.RestartServer(c.UserContext(), serverID); err != nil {
+		return powerError(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -215,10 +650,8 @@ func (s *Server) restartServer(c *fiber.Ctx) error {
 func (s *Server) killServer(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	if err := s.manager.KillServer(c.Context(), serverID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.manager.KillServer(c.UserContext(), serverID); err != nil {
+		return powerError(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -240,10 +673,8 @@ func (s *Server) sendCommand(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.manager.SendCommand(c.Context(), serverID, req.Command); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.manager.SendCommand(c.UserContext(), serverID, req.Command); err != nil {
+		return powerError(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -251,14 +682,130 @@ func (s *Server) sendCommand(c *fiber.Ctx) error {
 	})
 }
 
-// getLogs returns server logs
+// Limits for log responses
+const (
+	defaultLogLines = 100
+	maxLogLines     = 5000
+	maxLogBytes     = 1 << 20 // 1 MiB
+)
+
+// getLogs returns the last lines of a server's container output. With
+// follow=true the response stays open and streams new lines as NDJSON.
 func (s *Server) getLogs(c *fiber.Ctx) error {
 	serverID := c.Params("id")
-	_ = serverID
 
-	// TODO: Implement log retrieval
+	tail := c.QueryInt("tail", defaultLogLines)
+	if tail <= 0 || tail > maxLogLines {
+		tail = maxLogLines
+	}
+
+	opts := docker.LogOptions{
+		Tail:       strconv.Itoa(tail),
+		Timestamps: c.QueryBool("timestamps", false),
+		Follow:     c.QueryBool("follow", false),
+	}
+
+	if opts.Follow {
+		return s.followLogs(c, serverID, opts)
+	}
+
+	reader, err := s.manager.GetServerLogs(c.UserContext(), serverID, opts)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Server not found",
+		})
+	}
+	defer reader.Close()
+
+	strip := s.manager.StripsANSI(serverID)
+	var lines []docker.LogLine
+	size := 0
+	err = docker.DemuxLogs(reader, func(line docker.LogLine) error {
+		line.Line = server.SanitizeConsoleLine(line.Line, strip)
+		lines = append(lines, line)
+		size += len(line.Line)
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("Failed to read logs", zap.String("server", serverID), zap.Error(err))
+	}
+
+	// Keep the most recent lines within the size cap
+	truncated := false
+	for size > maxLogBytes && len(lines) > 0 {
+		size -= len(lines[0].Line)
+		lines = lines[1:]
+		truncated = true
+	}
+
+	return c.JSON(fiber.Map{
+		"logs":      lines,
+		"truncated": truncated,
+	})
+}
+
+// followLogs streams log lines as newline delimited JSON until the client goes away
+func (s *Server) followLogs(c *fiber.Ctx, serverID string, opts docker.LogOptions) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reader, err := s.manager.GetServerLogs(ctx, serverID, opts)
+	if err != nil {
+		cancel()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Server not found",
+		})
+	}
+
+	streamLogs(c, reader, s.manager.StripsANSI(serverID), cancel)
+	return nil
+}
+
+// streamLogs writes the lines of a container log stream to the client as
+// newline delimited JSON, then closes reader and calls done. Followed logs
+// run for as long as the client watches, so the server's write timeout is
+// lifted for them; a client that goes away fails the next flush instead.
+func streamLogs(c *fiber.Ctx, reader io.ReadCloser, strip bool, done func()) {
+	conn := c.Context().Conn()
+
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Set("Cache-Control", "no-cache")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer done()
+		defer reader.Close()
+
+		_ = conn.SetWriteDeadline(time.Time{})
+
+		enc := json.NewEncoder(w)
+		_ = docker.DemuxLogs(reader, func(line docker.LogLine) error {
+			line.Line = server.SanitizeConsoleLine(line.Line, strip)
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+			// A failed flush means the client disconnected
+			return w.Flush()
+		})
+	})
+}
+
+// getChat returns the chat messages read from a server's console after the
+// sequence number given as after
+func (s *Server) getChat(c *fiber.Ctx) error {
+	after, err := strconv.ParseInt(c.Query("after", "0"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+		})
+	}
+
+	messages, err := s.manager.ChatSince(c.Params("id"), after)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Server not found",
+		})
+	}
+
 	return c.JSON(fiber.Map{
-		"logs": []string{},
+		"messages": messages,
 	})
 }
 
@@ -266,7 +813,7 @@ func (s *Server) getLogs(c *fiber.Ctx) error {
 func (s *Server) getStats(c *fiber.Ctx) error {
 	serverID := c.Params("id")
 
-	stats, err := s.manager.GetServerStats(c.Context(), serverID)
+	stats, err := s.manager.GetServerStats(c.UserContext(), serverID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Server not found",
@@ -276,16 +823,75 @@ func (s *Server) getStats(c *fiber.Ctx) error {
 	return c.JSON(stats)
 }
 
+// updateRegistries replaces the registry credentials used for image pulls
+func (s *Server) updateRegistries(c *fiber.Ctx) error {
+	var req struct {
+		Registries []config.RegistryAuth `json:"registries"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	s.manager.SetRegistries(req.Registries)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Registries updated",
+	})
+}
+
+// listImages returns the images present on the node
+func (s *Server) listImages(c *fiber.Ctx) error {
+	images, err := s.manager.ListImages(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"images": images,
+	})
+}
+
+// pullImage pulls an image for the panel's image warmup
+func (s *Server) pullImage(c *fiber.Ctx) error {
+	var req struct {
+		Image string `json:"image"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Image == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Image is required",
+		})
+	}
+
+	if err := s.manager.PullImage(c.UserContext(), req.Image); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Image pulled",
+	})
+}
+
 // getSystemInfo returns node system information
 func (s *Server) getSystemInfo(c *fiber.Ctx) error {
-	// TODO: Implement system info collection
+	info := collectSystemInfo(s.config.Storage.ServerDataPath)
 	return c.JSON(fiber.Map{
-		"node_id":    s.config.NodeID,
-		"cpu_cores":  0,
-		"memory_mb":  0,
-		"disk_mb":    0,
-		"os":         "linux",
-		"docker":     "running",
+		"node_id":        s.config.NodeID,
+		"cpu_cores":      info.CPUCores,
+		"load":           info.Load,
+		"memory_mb":      info.MemoryMB,
+		"memory_used_mb": info.MemoryUsedMB,
+		"disk_mb":        info.DiskMB,
+		"disk_used_mb":   info.DiskUsedMB,
+		"os":             "linux",
+		"docker":         "running",
 	})
 }
 
@@ -299,7 +905,30 @@ func (s *Server) consoleWebSocket(c *websocket.Conn) {
 		c.Close()
 	}()
 
-	// TODO: Implement console streaming via Docker attach
+	// Events and console output are written from separate goroutines
+	var writeMu sync.Mutex
+	send := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return c.WriteJSON(v)
+	}
+
+	// Forward server events such as image pull progress
+	events, unsubscribe := s.manager.Events().Subscribe(serverID)
+	defer unsubscribe()
+	go func() {
+		for event := range events {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Stream console output, sanitized so every frame is valid JSON
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.streamConsole(ctx, serverID, send)
+
 	for {
 		_, msg, err := c.ReadMessage()
 		if err != nil {
@@ -311,6 +940,30 @@ func (s *Server) consoleWebSocket(c *websocket.Conn) {
 	}
 }
 
+// streamConsole follows a server's container output and sends each line as a
+// console event until the context is cancelled or a write fails
+func (s *Server) streamConsole(ctx context.Context, serverID string, send func(interface{}) error) {
+	reader, err := s.manager.GetServerLogs(ctx, serverID, docker.LogOptions{
+		Tail:   strconv.Itoa(defaultLogLines),
+		Follow: true,
+	})
+	if err != nil {
+		s.logger.Debug("Console output unavailable", zap.String("server", serverID), zap.Error(err))
+		return
+	}
+	defer reader.Close()
+
+	strip := s.manager.StripsANSI(serverID)
+	_ = docker.DemuxLogs(reader, func(line docker.LogLine) error {
+		return send(server.Event{
+			Type:      server.EventConsoleOutput,
+			ServerID:  serverID,
+			Data:      server.SanitizeConsoleLine(line.Line, strip),
+			Timestamp: time.Now(),
+		})
+	})
+}
+
 // RegisterWithPanel registers this node with the panel
 func (s *Server) RegisterWithPanel(ctx context.Context) error {
 	client := &http.Client{Timeout: 10 * time.Second}
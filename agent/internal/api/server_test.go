@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+	"github.com/gofiber/fiber/v2"
+)
+
+// serveLogs streams the log output written to the returned pipe from a server
+// with a short write timeout, returning the URL and a channel closed once the
+// stream has ended
+func serveLogs(t *testing.T) (string, *io.PipeWriter, <-chan struct{}) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	ended := make(chan struct{})
+
+	app := fiber.New(fiber.Config{WriteTimeout: 100 * time.Millisecond, DisableStartupMessage: true})
+	app.Get("/logs", func(c *fiber.Ctx) error {
+		streamLogs(c, pr, true, func() { close(ended) })
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() {
+		pw.Close()
+		_ = app.Shutdown()
+	})
+	return "http://" + ln.Addr().String() + "/logs", pw, ended
+}
+
+func TestStreamLogsOutlivesWriteTimeout(t *testing.T) {
+	url, pw, ended := serveLogs(t)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(60 * time.Millisecond)
+			fmt.Fprintf(pw, "\x1b[32mline %d\x1b[0m\n", i)
+		}
+		pw.Close()
+	}()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for i := 0; i < 5; i++ {
+		var line docker.LogLine
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("line %d", i); line.Line != want || line.Stream != docker.StreamStdout {
+			t.Errorf("line %d = %+v, want stdout %q", i, line, want)
+		}
+	}
+
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end with its log output")
+	}
+}
+
+func TestStreamLogsEndsWhenClientLeaves(t *testing.T) {
+	url, pw, ended := serveLogs(t)
+
+	go fmt.Fprintln(pw, "Starting server")
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line docker.LogLine
+	if err := json.NewDecoder(resp.Body).Decode(&line); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The next lines fail to reach the client, which ends the stream
+	go func() {
+		for {
+			if _, err := fmt.Fprintln(pw, "Done"); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream kept running after the client left")
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/config"
+	"go.uber.org/zap"
+)
+
+// newLimitsServer returns an API server keeping its state in stateFile
+func newLimitsServer(t *testing.T, stateFile string) *Server {
+	t.Helper()
+	cfg := &config.Config{Token: "secret"}
+	cfg.Storage.StateFile = stateFile
+	cfg.Uploads.MaxPartSize = 64
+	cfg.Uploads.MaxUploadSize = 1024
+	return NewServer(cfg, nil, nil, zap.NewNop())
+}
+
+func putLimits(t *testing.T, s *Server, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, "/api/limits", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestUploadLimitSurvivesRestart(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "aether", "state.json")
+
+	s := newLimitsServer(t, stateFile)
+	if status := putLimits(t, s, `{"max_upload_size": 100}`); status != http.StatusOK {
+		t.Fatalf("PUT /api/limits = %d, want %d", status, http.StatusOK)
+	}
+
+	restarted := newLimitsServer(t, stateFile)
+	if limit := restarted.maxUploadSize(); limit != 100*1024*1024 {
+		t.Errorf("limit after restart = %d, want %d", limit, 100*1024*1024)
+	}
+
+	// Clearing the limit falls back to the agent config
+	if status := putLimits(t, restarted, `{"max_upload_size": 0}`); status != http.StatusOK {
+		t.Fatalf("PUT /api/limits = %d, want %d", status, http.StatusOK)
+	}
+	if limit := newLimitsServer(t, stateFile).maxUploadSize(); limit != 1024*1024*1024 {
+		t.Errorf("limit after clearing = %d, want %d", limit, 1024*1024*1024)
+	}
+}
+
+func TestUploadLimitNotAppliedWhenUnsaved(t *testing.T) {
+	// The state file's directory is a regular file, so it cannot be written
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newLimitsServer(t, filepath.Join(blocker, "state.json"))
+	if status := putLimits(t, s, `{"max_upload_size": 100}`); status != http.StatusInternalServerError {
+		t.Errorf("PUT /api/limits = %d, want %d", status, http.StatusInternalServerError)
+	}
+	if limit := s.maxUploadSize(); limit != 1024*1024*1024 {
+		t.Errorf("limit = %d, want the agent config's %d", limit, 1024*1024*1024)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// systemInfo is the node's capacity and current usage
+type systemInfo struct {
+	CPUCores     int     `json:"cpu_cores"`
+	Load         float64 `json:"load"` // 1 minute load average
+	MemoryMB     int64   `json:"memory_mb"`
+	MemoryUsedMB int64   `json:"memory_used_mb"`
+	DiskMB       int64   `json:"disk_mb"` // Filesystem holding the server data
+	DiskUsedMB   int64   `json:"disk_used_mb"`
+}
+
+// collectSystemInfo reads the node's usage from /proc and the filesystem of
+// dataPath. Values that cannot be read are left 0.
+func collectSystemInfo(dataPath string) systemInfo {
+	info := systemInfo{CPUCores: runtime.NumCPU()}
+
+	if mem, err := readMemInfo(); err == nil {
+		info.MemoryMB = mem["MemTotal"] / 1024
+		info.MemoryUsedMB = (mem["MemTotal"] - mem["MemAvailable"]) / 1024
+	}
+
+	if raw, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if fields := strings.Fields(string(raw)); len(fields) > 0 {
+			info.Load, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dataPath, &fs); err == nil {
+		blockSize := int64(fs.Bsize)
+		info.DiskMB = int64(fs.Blocks) * blockSize / 1024 / 1024
+		info.DiskUsedMB = int64(fs.Blocks-fs.Bfree) * blockSize / 1024 / 1024
+	}
+
+	return info
+}
+
+// readMemInfo returns the fields of /proc/meminfo in kB
+func readMemInfo() (map[string]int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value := strings.Fields(rest)
+		if len(value) == 0 {
+			continue
+		}
+		if n, err := strconv.ParseInt(value[0], 10, 64); err == nil {
+			fields[name] = n
+		}
+	}
+	return fields, scanner.Err()
+}
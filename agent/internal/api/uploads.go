@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/server"
+	"github.com/aetherpanel/aether-panel/agent/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+var sha256Pattern = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+
+// createUpload starts a chunked upload after checking the declared size
+// against the server's disk limit
+func (s *Server) createUpload(c *fiber.Ctx) error {
+	serverID := c.Params("id")
+
+	var req struct {
+		Kind      string `json:"kind"`
+		Target    string `json:"target"` // File path, or backup id for backups
+		TotalSize int64  `json:"total_size"`
+		PartSize  int64  `json:"part_size"`
+		Checksum  string `json:"checksum"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	maxPart := s.config.Uploads.MaxPartSize * 1024 * 1024
+	switch {
+	case req.Kind != upload.KindFile && req.Kind != upload.KindBackup:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kind must be file or backup"})
+	case req.TotalSize <= 0:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Total size must be positive"})
+	case req.PartSize <= 0 || req.PartSize > maxPart:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Part size must be between 1 byte and " + strconv.FormatInt(maxPart, 10) + " bytes"})
+	case !sha256Pattern.MatchString(req.Checksum):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Checksum must be a SHA-256 hex digest"})
+	}
+
+	if limit := s.maxUploadSize(); limit > 0 && req.TotalSize > limit {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":           "Upload exceeds the node's upload limit of " + strconv.FormatInt(limit/1024/1024, 10) + " MB",
+			"max_upload_size": limit,
+		})
+	}
+
+	var err error
+	if req.Kind == upload.KindFile {
+		_, err = s.manager.ServerFilePath(serverID, req.Target)
+	} else {
+		_, err = s.manager.BackupArchivePath(req.Target)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Files add to the data directory, backups only need to fit the limit on their own
+	if err := s.manager.CheckDiskQuota(serverID, req.TotalSize, req.Kind == upload.KindFile); err != nil {
+		status := fiber.StatusNotFound
+		if errors.Is(err, server.ErrDiskQuotaExceeded) {
+			status = fiber.StatusRequestEntityTooLarge
+		}
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	u, err := s.uploads.Create(&upload.Upload{
+		ServerID:  serverID,
+		Kind:      req.Kind,
+		Target:    req.Target,
+		TotalSize: req.TotalSize,
+		PartSize:  req.PartSize,
+		Checksum:  req.Checksum,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"upload":          u,
+		"parts":           u.PartCount(),
+		"max_upload_size": s.maxUploadSize(),
+	})
+}
+
+// maxUploadSize returns the largest upload accepted in bytes, 0 for no limit.
+// The panel's per-node limit applies within the one in the agent config.
+func (s *Server) maxUploadSize() int64 {
+	limit := s.config.Uploads.MaxUploadSize * 1024 * 1024
+	if node := s.uploadLimit.Load(); node > 0 && (limit == 0 || node < limit) {
+		limit = node
+	}
+	return limit
+}
+
+// updateLimits replaces the node limits set from the panel
+func (s *Server) updateLimits(c *fiber.Ctx) error {
+	var req struct {
+		MaxUploadSize int64 `json:"max_upload_size"` // MB, 0 to clear
+	}
+	if err := c.BodyParser(&req); err != nil || req.MaxUploadSize < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	limit := req.MaxUploadSize * 1024 * 1024
+	if err := saveNodeState(s.config.Storage.StateFile, nodeState{MaxUploadSize: limit}); err != nil {
+		s.logger.Error("Failed to save node limits", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save limits",
+		})
+	}
+	s.uploadLimit.Store(limit)
+
+	return c.JSON(fiber.Map{
+		"success":         true,
+		"max_upload_size": s.maxUploadSize(),
+	})
+}
+
+// getUpload reports which parts have been received so a client can resume
+func (s *Server) getUpload(c *fiber.Ctx) error {
+	status, err := s.serverUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	return c.JSON(status)
+}
+
+// uploadPart stores one part of an upload. The X-Checksum-SHA256 header, when
+// sent, must match the part's body.
+func (s *Server) uploadPart(c *fiber.Ctx) error {
+	status, err := s.serverUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+
+	part, err := strconv.Atoi(c.Params("part"))
+	if err != nil {
+		return uploadError(c, upload.ErrInvalidPart)
+	}
+
+	if err := s.uploads.WritePart(status.ID, part, bytes.NewReader(c.Body()), c.Get("X-Checksum-SHA256")); err != nil {
+		return uploadError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"part":    part,
+	})
+}
+
+// completeUpload assembles an upload into its target. Backup uploads can be
+// restored right away by setting restore.
+func (s *Server) completeUpload(c *fiber.Ctx) error {
+	status, err := s.serverUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+
+	var req struct {
+		Restore  bool `json:"restore"`
+		WipeData bool `json:"wipe_data"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	var dest string
+	var verify func() error
+	if status.Kind == upload.KindFile {
+		dest, err = s.manager.ServerFilePath(status.ServerID, status.Target)
+		// The server may have swapped a directory for a symlink meanwhile
+		verify = func() error {
+			_, err := s.manager.ServerFilePath(status.ServerID, status.Target)
+			return err
+		}
+	} else {
+		dest, err = s.manager.BackupArchivePath(status.Target)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.uploads.Complete(status.ID, dest, verify); err != nil {
+		return uploadError(c, err)
+	}
+
+	s.logger.Info("Upload completed",
+		zap.String("server", status.ServerID),
+		zap.String("kind", status.Kind),
+		zap.String("target", status.Target),
+		zap.Int64("size", status.TotalSize),
+	)
+
+	if status.Kind == upload.KindBackup && req.Restore {
+		err := s.manager.RestoreBackup(c.UserContext(), status.ServerID, status.Target, server.RestoreOptions{
+			Checksum: status.Checksum,
+			WipeData: req.WipeData,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"restored": status.Kind == upload.KindBackup && req.Restore,
+	})
+}
+
+// abortUpload discards an upload and its parts
+func (s *Server) abortUpload(c *fiber.Ctx) error {
+	status, err := s.serverUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	if err := s.uploads.Abort(status.ID); err != nil {
+		return uploadError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// serverUpload returns the upload named in the route if it belongs to the server
+func (s *Server) serverUpload(c *fiber.Ctx) (*upload.Status, error) {
+	status, err := s.uploads.Get(c.Params("uploadId"))
+	if err != nil {
+		return nil, err
+	}
+	if status.ServerID != c.Params("id") {
+		return nil, upload.ErrUploadNotFound
+	}
+	return status, nil
+}
+
+// uploadError maps upload errors to HTTP responses
+func uploadError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, upload.ErrUploadNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, upload.ErrInvalidPart), errors.Is(err, upload.ErrPartSize), errors.Is(err, server.ErrInvalidPath):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, upload.ErrPartChecksum), errors.Is(err, upload.ErrChecksumMismatch):
+		status = fiber.StatusUnprocessableEntity
+	case errors.Is(err, upload.ErrIncomplete):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/server"
+	"github.com/gofiber/fiber/v2"
+)
+
+// listWorlds returns the Minecraft worlds in a server's data directory
+func (s *Server) listWorlds(c *fiber.Ctx) error {
+	worlds, err := s.manager.ListWorlds(c.Params("id"))
+	if err != nil {
+		return worldError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"worlds": worlds,
+	})
+}
+
+// backupWorld archives a world and reports the archive's size and checksum
+func (s *Server) backupWorld(c *fiber.Ctx) error {
+	var req struct {
+		BackupID string `json:"backup_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.BackupID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Backup id is required",
+		})
+	}
+
+	archive, err := s.manager.BackupWorld(c.UserContext(), c.Params("id"), c.Params("world"), req.BackupID)
+	if err != nil {
+		return worldError(c, err)
+	}
+	return c.JSON(archive)
+}
+
+// restoreWorld replaces a world with one of its backups
+func (s *Server) restoreWorld(c *fiber.Ctx) error {
+	var req struct {
+		Checksum string `json:"checksum"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Checksum == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Checksum is required",
+		})
+	}
+
+	err := s.manager.RestoreWorld(c.UserContext(), c.Params("id"), c.Params("world"), c.Params("backupId"), req.Checksum)
+	if err != nil {
+		return worldError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// deleteWorld removes a world folder
+func (s *Server) deleteWorld(c *fiber.Ctx) error {
+	if err := s.manager.DeleteWorld(c.UserContext(), c.Params("id"), c.Params("world")); err != nil {
+		return worldError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// worldError maps world errors to HTTP responses
+func worldError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, server.ErrWorldNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, server.ErrInvalidPath):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, server.ErrServerRunning):
+		status = fiber.StatusConflict
+	case errors.Is(err, server.ErrChecksumMismatch):
+		status = fiber.StatusUnprocessableEntity
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
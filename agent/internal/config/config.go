@@ -16,6 +16,12 @@ type Config struct {
 	Docker      DockerConfig `mapstructure:"docker"`
 	Storage     StorageConfig `mapstructure:"storage"`
 	Metrics     MetricsConfig `mapstructure:"metrics"`
+	Tracing     TracingConfig `mapstructure:"tracing"`
+	Uploads     UploadConfig  `mapstructure:"uploads"`
+
+	// Host paths, and everything below them, that servers may mount. Empty
+	// refuses all extra mounts.
+	AllowedMounts []string `mapstructure:"allowed_mounts"`
 }
 
 // PanelConfig holds panel connection settings
@@ -36,14 +42,35 @@ type APIConfig struct {
 
 // DockerConfig holds Docker settings
 type DockerConfig struct {
-	Socket          string            `mapstructure:"socket"`
-	Network         string            `mapstructure:"network"`
-	NetworkMode     string            `mapstructure:"network_mode"`
-	DNS             []string          `mapstructure:"dns"`
-	LogDriver       string            `mapstructure:"log_driver"`
-	LogOpts         map[string]string `mapstructure:"log_opts"`
-	StopTimeout     int               `mapstructure:"stop_timeout"`
-	PullPolicy      string            `mapstructure:"pull_policy"`
+	Socket         string            `mapstructure:"socket"`
+	Network        string            `mapstructure:"network"`
+	NetworkMode    string            `mapstructure:"network_mode"` // Default for servers: node, isolated or bridge
+	Labels         map[string]string `mapstructure:"labels"`       // Added to every server container and network
+	DNS            []string          `mapstructure:"dns"`
+	LogDriver      string            `mapstructure:"log_driver"`
+	LogOpts        map[string]string `mapstructure:"log_opts"`
+	StopTimeout    int               `mapstructure:"stop_timeout"`
+	PullPolicy     string            `mapstructure:"pull_policy"`     // always, if-not-present, never
+	HealthInterval int               `mapstructure:"health_interval"` // seconds between daemon pings
+	PidsLimit      int64             `mapstructure:"pids_limit"`      // Processes and threads per server container, 0 for unlimited
+	NoFile         int64             `mapstructure:"nofile"`          // Open files per server process, 0 for Docker's default
+	NProc          int64             `mapstructure:"nproc"`           // Processes per container user, 0 for Docker's default
+	TmpfsSize      string            `mapstructure:"tmpfs_size"`      // Size of each tmpfs mount of read-only servers, e.g. 64m
+	Registries     []RegistryAuth    `mapstructure:"registries"`
+
+	// Server containers drop every capability but CapAdd, eggs may add back
+	// those in AllowedCaps
+	CapAdd          []string `mapstructure:"cap_add"`
+	AllowedCaps     []string `mapstructure:"allowed_caps"`
+	NoNewPrivileges bool     `mapstructure:"no_new_privileges"`
+	SeccompProfile  string   `mapstructure:"seccomp_profile"` // Path to a seccomp profile JSON, empty for Docker's default
+}
+
+// RegistryAuth holds credentials for a private image registry
+type RegistryAuth struct {
+	Host     string `mapstructure:"host" json:"host"` // e.g. ghcr.io, docker.io for Docker Hub
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"password"`
 }
 
 // StorageConfig holds storage settings
@@ -51,6 +78,14 @@ type StorageConfig struct {
 	ServerDataPath string `mapstructure:"server_data_path"`
 	BackupPath     string `mapstructure:"backup_path"`
 	TmpPath        string `mapstructure:"tmp_path"`
+	StateFile      string `mapstructure:"state_file"` // Settings the panel pushed, kept across restarts
+}
+
+// UploadConfig holds chunked upload settings
+type UploadConfig struct {
+	MaxPartSize   int64 `mapstructure:"max_part_size"`   // MB
+	MaxUploadSize int64 `mapstructure:"max_upload_size"` // MB per upload, 0 for no limit; the panel can lower it per node
+	AbandonAfter  int   `mapstructure:"abandon_after"`   // Minutes without a new part before an upload is discarded
 }
 
 // MetricsConfig holds metrics settings
@@ -60,6 +95,15 @@ type MetricsConfig struct {
 	RetentionHours  int  `mapstructure:"retention_hours"`
 }
 
+// TracingConfig holds OpenTelemetry tracing settings
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"` // OTLP/HTTP collector host:port
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRate  float64 `mapstructure:"sample_rate"`
+}
+
 // Load loads configuration from file and environment
 func Load() (*Config, error) {
 	v := viper.New()
@@ -107,7 +151,7 @@ func setDefaults(v *viper.Viper) {
 	// Docker defaults
 	v.SetDefault("docker.socket", "unix:///var/run/docker.sock")
 	v.SetDefault("docker.network", "aether_network")
-	v.SetDefault("docker.network_mode", "bridge")
+	v.SetDefault("docker.network_mode", "node")
 	v.SetDefault("docker.dns", []string{"1.1.1.1", "8.8.8.8"})
 	v.SetDefault("docker.log_driver", "json-file")
 	v.SetDefault("docker.log_opts", map[string]string{
@@ -116,14 +160,43 @@ func setDefaults(v *viper.Viper) {
 	})
 	v.SetDefault("docker.stop_timeout", 30)
 	v.SetDefault("docker.pull_policy", "if-not-present")
+	v.SetDefault("docker.health_interval", 10)
+	v.SetDefault("docker.pids_limit", 1024)
+	v.SetDefault("docker.nofile", 65536)
+	// nproc is counted per user ID across the whole host, and server
+	// containers share one, so it is off unless a node opts in
+	v.SetDefault("docker.nproc", 0)
+	v.SetDefault("docker.tmpfs_size", "64m")
+	// Servers run as an unprivileged user, so they need no capabilities
+	// unless their egg asks for one
+	v.SetDefault("docker.cap_add", []string{})
+	v.SetDefault("docker.allowed_caps", []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "SETUID", "SETGID", "KILL", "NET_BIND_SERVICE", "NET_RAW", "SYS_NICE"})
+	v.SetDefault("docker.no_new_privileges", true)
+	v.SetDefault("docker.seccomp_profile", "")
 
 	// Storage defaults
 	v.SetDefault("storage.server_data_path", "/var/lib/aether/servers")
 	v.SetDefault("storage.backup_path", "/var/lib/aether/backups")
 	v.SetDefault("storage.tmp_path", "/tmp/aether")
+	v.SetDefault("storage.state_file", "/var/lib/aether/state.json")
+
+	// Mounts are refused unless the node allows their source
+	v.SetDefault("allowed_mounts", []string{})
+
+	// Upload defaults
+	v.SetDefault("uploads.max_part_size", 64)
+	v.SetDefault("uploads.max_upload_size", 0)
+	v.SetDefault("uploads.abandon_after", 360)
 
 	// Metrics defaults
 	v.SetDefault("metrics.enabled", true)
 	v.SetDefault("metrics.collect_interval", 5)
 	v.SetDefault("metrics.retention_hours", 24)
+
+	// Tracing defaults
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "aether-agent")
+	v.SetDefault("tracing.sample_rate", 1.0)
 }
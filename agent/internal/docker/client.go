@@ -2,22 +2,37 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aetherpanel/aether-panel/agent/internal/tracing"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrUnavailable is returned by every operation while the Docker daemon cannot
+// be reached
+var ErrUnavailable = errors.New("docker unavailable")
+
 // Client wraps Docker client with additional functionality
 type Client struct {
-	cli *client.Client
+	cli       *client.Client
+	mu        sync.RWMutex
+	available atomic.Bool
 }
 
 // NewClient creates a new Docker client
@@ -35,33 +50,104 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to ping docker: %w", err)
 	}
 
-	return &Client{cli: cli}, nil
+	c := &Client{cli: cli}
+	c.available.Store(true)
+	return c, nil
 }
 
 // Close closes the Docker client
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.cli.Close()
 }
 
+// api returns the Docker API client, or ErrUnavailable during an outage
+func (c *Client) api() (*client.Client, error) {
+	if !c.available.Load() {
+		return nil, ErrUnavailable
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cli, nil
+}
+
+// Available reports whether the daemon answered the last health check
+func (c *Client) Available() bool {
+	return c.available.Load()
+}
+
+// Ping checks that the daemon answers. A failed ping marks the client
+// unavailable until Reconnect succeeds.
+func (c *Client) Ping(ctx context.Context) error {
+	c.mu.RLock()
+	cli := c.cli
+	c.mu.RUnlock()
+
+	if _, err := cli.Ping(ctx); err != nil {
+		c.available.Store(false)
+		return err
+	}
+	return nil
+}
+
+// Reconnect replaces the API client with a new connection to the daemon and
+// marks the client available again once it answers
+func (c *Client) Reconnect(ctx context.Context) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	if _, err := cli.Ping(ctx); err != nil {
+		cli.Close()
+		return fmt.Errorf("failed to ping docker: %w", err)
+	}
+
+	c.mu.Lock()
+	old := c.cli
+	c.cli = cli
+	c.mu.Unlock()
+	old.Close()
+
+	c.available.Store(true)
+	return nil
+}
+
 // ContainerConfig represents container configuration
 type ContainerConfig struct {
-	Name        string
-	Image       string
-	Cmd         []string
-	Env         []string
-	WorkingDir  string
-	User        string
-	Labels      map[string]string
-	Mounts      []MountConfig
-	Ports       []PortConfig
-	Memory      int64 // bytes
-	MemorySwap  int64 // bytes
-	CPUQuota    int64
-	CPUPeriod   int64
-	IOWeight    uint16
-	NetworkMode string
-	DNS         []string
-	StopTimeout int
+	Name          string
+	Image         string
+	Entrypoint    []string // Replaces the image's entrypoint, empty keeps it
+	Cmd           []string
+	Env           []string
+	WorkingDir    string
+	User          string
+	Labels        map[string]string
+	Mounts        []MountConfig
+	Ports         []PortConfig
+	Memory        int64 // bytes
+	MemorySwap    int64 // bytes
+	CPUQuota      int64
+	CPUPeriod     int64
+	CpusetCpus    string // Cores the container may run on, e.g. "0-3,8"; empty for any
+	CpusetMems    string // NUMA memory nodes the container may allocate from
+	IOWeight      uint16
+	NetworkMode   string
+	DNS           []string
+	StopTimeout   int
+	RestartPolicy string // Docker restart policy, unless-stopped when empty
+	Healthcheck   *container.HealthConfig
+	PidsLimit     int64 // Processes and threads, 0 for unlimited
+	NoFile        int64 // nofile ulimit, 0 for Docker's default
+	NProc         int64 // nproc ulimit, 0 for Docker's default
+
+	// Root filesystem read-only, leaving only mounts and Tmpfs writable
+	ReadOnlyRootfs bool
+	Tmpfs          map[string]string // Container path to tmpfs mount options
+
+	CapDrop     []string // e.g. ALL
+	CapAdd      []string // Added back after CapDrop
+	SecurityOpt []string // e.g. no-new-privileges, seccomp=<profile JSON>
 }
 
 // MountConfig represents a mount configuration
@@ -80,7 +166,15 @@ type PortConfig struct {
 }
 
 // CreateContainer creates a new container
-func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (string, error) {
+func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (id string, err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.create", attribute.String("container.image", cfg.Image))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return "", err
+	}
+
 	// Prepare environment
 	env := cfg.Env
 
@@ -112,6 +206,7 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (str
 	// Container config
 	containerCfg := &container.Config{
 		Image:        cfg.Image,
+		Entrypoint:   cfg.Entrypoint,
 		Cmd:          cfg.Cmd,
 		Env:          env,
 		WorkingDir:   cfg.WorkingDir,
@@ -123,10 +218,15 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (str
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
+		Healthcheck:  cfg.Healthcheck,
 	}
 
 	// Host config
 	stopTimeout := cfg.StopTimeout
+	restartPolicy := cfg.RestartPolicy
+	if restartPolicy == "" {
+		restartPolicy = "unless-stopped"
+	}
 	hostCfg := &container.HostConfig{
 		Mounts:       mounts,
 		PortBindings: portBindings,
@@ -135,12 +235,20 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (str
 			MemorySwap: cfg.MemorySwap,
 			CPUQuota:   cfg.CPUQuota,
 			CPUPeriod:  cfg.CPUPeriod,
+			CpusetCpus: cfg.CpusetCpus,
+			CpusetMems: cfg.CpusetMems,
 			BlkioWeight: cfg.IOWeight,
+			Ulimits:     ulimits(cfg.NoFile, cfg.NProc),
 		},
-		NetworkMode:   container.NetworkMode(cfg.NetworkMode),
-		DNS:           cfg.DNS,
-		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
-		StopTimeout:   &stopTimeout,
+		NetworkMode:    container.NetworkMode(cfg.NetworkMode),
+		DNS:            cfg.DNS,
+		RestartPolicy:  container.RestartPolicy{Name: restartPolicy},
+		StopTimeout:    &stopTimeout,
+		ReadonlyRootfs: cfg.ReadOnlyRootfs,
+		Tmpfs:          cfg.Tmpfs,
+		CapDrop:        cfg.CapDrop,
+		CapAdd:         cfg.CapAdd,
+		SecurityOpt:    cfg.SecurityOpt,
 		LogConfig: container.LogConfig{
 			Type: "json-file",
 			Config: map[string]string{
@@ -150,11 +258,21 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (str
 		},
 	}
 
-	// Network config
+	if cfg.PidsLimit > 0 {
+		pidsLimit := cfg.PidsLimit
+		hostCfg.Resources.PidsLimit = &pidsLimit
+	}
+
+	// Network config, user defined networks need an endpoint to attach to
 	networkCfg := &network.NetworkingConfig{}
+	if hostCfg.NetworkMode.IsUserDefined() {
+		networkCfg.EndpointsConfig = map[string]*network.EndpointSettings{
+			cfg.NetworkMode: {},
+		}
+	}
 
 	// Create container
-	resp, err := c.cli.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, cfg.Name)
+	resp, err := cli.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, cfg.Name)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
@@ -162,31 +280,82 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *ContainerConfig) (str
 	return resp.ID, nil
 }
 
+// ulimits returns the nofile and nproc ulimits, soft and hard alike, leaving
+// out those that are 0
+func ulimits(nofile, nproc int64) []*units.Ulimit {
+	var limits []*units.Ulimit
+	if nofile > 0 {
+		limits = append(limits, &units.Ulimit{Name: "nofile", Soft: nofile, Hard: nofile})
+	}
+	if nproc > 0 {
+		limits = append(limits, &units.Ulimit{Name: "nproc", Soft: nproc, Hard: nproc})
+	}
+	return limits
+}
+
 // StartContainer starts a container
-func (c *Client) StartContainer(ctx context.Context, containerID string) error {
-	return c.cli.ContainerStart(ctx, containerID, container.StartOptions{})
+func (c *Client) StartContainer(ctx context.Context, containerID string) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.start", attribute.String("container.id", containerID))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	return cli.ContainerStart(ctx, containerID, container.StartOptions{})
 }
 
 // StopContainer stops a container gracefully
-func (c *Client) StopContainer(ctx context.Context, containerID string, timeout int) error {
-	timeoutDuration := time.Duration(timeout) * time.Second
-	return c.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout, Signal: "SIGTERM"})
-	_ = timeoutDuration
+func (c *Client) StopContainer(ctx context.Context, containerID string, timeout int) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.stop", attribute.String("container.id", containerID))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	return cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout, Signal: "SIGTERM"})
 }
 
 // KillContainer forcefully stops a container
-func (c *Client) KillContainer(ctx context.Context, containerID string) error {
-	return c.cli.ContainerKill(ctx, containerID, "SIGKILL")
+func (c *Client) KillContainer(ctx context.Context, containerID string) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.kill", attribute.String("container.id", containerID))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	return cli.ContainerKill(ctx, containerID, "SIGKILL")
 }
 
 // RestartContainer restarts a container
-func (c *Client) RestartContainer(ctx context.Context, containerID string, timeout int) error {
-	return c.cli.ContainerRestart(ctx, containerID, container.StopOptions{Timeout: &timeout})
+func (c *Client) RestartContainer(ctx context.Context, containerID string, timeout int) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.restart", attribute.String("container.id", containerID))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	return cli.ContainerRestart(ctx, containerID, container.StopOptions{Timeout: &timeout})
 }
 
 // RemoveContainer removes a container
-func (c *Client) RemoveContainer(ctx context.Context, containerID string, force bool) error {
-	return c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
+func (c *Client) RemoveContainer(ctx context.Context, containerID string, force bool) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.container.remove", attribute.String("container.id", containerID))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	return cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
 		Force:         force,
 		RemoveVolumes: true,
 	})
@@ -207,26 +376,123 @@ type ContainerStats struct {
 
 // GetContainerStats retrieves container statistics
 func (c *Client) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
-	stats, err := c.cli.ContainerStatsOneShot(ctx, containerID)
+	cli, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := cli.ContainerStatsOneShot(ctx, containerID)
 	if err != nil {
 		return nil, err
 	}
 	defer stats.Body.Close()
 
-	// Parse stats - simplified version
-	// In production, would parse the JSON stream properly
-	return &ContainerStats{}, nil
+	var raw types.StatsJSON
+	if err := json.NewDecoder(stats.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	result := &ContainerStats{
+		CPUPercent:  calculateCPUPercent(&raw),
+		MemoryUsage: calculateMemoryUsage(&raw),
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+
+	if result.MemoryLimit > 0 {
+		result.MemoryPercent = float64(result.MemoryUsage) / float64(result.MemoryLimit) * 100.0
+	}
+
+	for _, n := range raw.Networks {
+		result.NetworkRx += n.RxBytes
+		result.NetworkTx += n.TxBytes
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "Read", "read":
+			result.BlockRead += entry.Value
+		case "Write", "write":
+			result.BlockWrite += entry.Value
+		}
+	}
+
+	return result, nil
+}
+
+// calculateCPUPercent calculates CPU usage relative to a single core
+func calculateCPUPercent(stats *types.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	return (cpuDelta / systemDelta) * cpus * 100.0
+}
+
+// calculateMemoryUsage returns memory usage excluding the page cache
+func calculateMemoryUsage(stats *types.StatsJSON) uint64 {
+	usage := stats.MemoryStats.Usage
+
+	// cgroup v2 reports inactive_file, cgroup v1 reports cache
+	if v, ok := stats.MemoryStats.Stats["inactive_file"]; ok && v < usage {
+		return usage - v
+	}
+	if v, ok := stats.MemoryStats.Stats["cache"]; ok && v < usage {
+		return usage - v
+	}
+	return usage
 }
 
 // GetContainerStatus returns container status
 func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (string, error) {
-	info, err := c.cli.ContainerInspect(ctx, containerID)
+	info, err := c.InspectContainer(ctx, containerID)
 	if err != nil {
 		return "", err
 	}
 	return info.State.Status, nil
 }
 
+// GetContainerState returns a container's status and, when it has a
+// healthcheck, its health: starting, healthy or unhealthy
+func (c *Client) GetContainerState(ctx context.Context, containerID string) (status, health string, err error) {
+	info, err := c.InspectContainer(ctx, containerID)
+	if err != nil {
+		return "", "", err
+	}
+	if info.State.Health != nil {
+		health = info.State.Health.Status
+	}
+	return info.State.Status, health, nil
+}
+
+// InspectContainer returns the full container configuration and state
+func (c *Client) InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	cli, err := c.api()
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	return cli.ContainerInspect(ctx, containerID)
+}
+
+// ContainerPID returns the host PID of a running container's main process
+func (c *Client) ContainerPID(ctx context.Context, containerID string) (int, error) {
+	info, err := c.InspectContainer(ctx, containerID)
+	if err != nil {
+		return 0, err
+	}
+	if info.State == nil || info.State.Pid == 0 {
+		return 0, fmt.Errorf("container %s is not running", containerID)
+	}
+	return info.State.Pid, nil
+}
+
 // IsContainerRunning checks if container is running
 func (c *Client) IsContainerRunning(ctx context.Context, containerID string) (bool, error) {
 	status, err := c.GetContainerStatus(ctx, containerID)
@@ -244,12 +510,17 @@ func (c *Client) ExecCommand(ctx context.Context, containerID string, cmd []stri
 		AttachStderr: true,
 	}
 
-	execID, err := c.cli.ContainerExecCreate(ctx, containerID, execCfg)
+	cli, err := c.api()
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := c.cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	execID, err := cli.ContainerExecCreate(ctx, containerID, execCfg)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
 	if err != nil {
 		return "", err
 	}
@@ -265,7 +536,11 @@ func (c *Client) ExecCommand(ctx context.Context, containerID string, cmd []stri
 
 // AttachContainer attaches to container stdin/stdout
 func (c *Client) AttachContainer(ctx context.Context, containerID string) (types.HijackedResponse, error) {
-	return c.cli.ContainerAttach(ctx, containerID, container.AttachOptions{
+	cli, err := c.api()
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	return cli.ContainerAttach(ctx, containerID, container.AttachOptions{
 		Stream: true,
 		Stdin:  true,
 		Stdout: true,
@@ -273,33 +548,109 @@ func (c *Client) AttachContainer(ctx context.Context, containerID string) (types
 	})
 }
 
+// LogOptions controls which container logs are returned
+type LogOptions struct {
+	Tail       string // Number of lines from the end, or "all"
+	Timestamps bool
+	Follow     bool
+}
+
 // GetContainerLogs retrieves container logs
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail string) (io.ReadCloser, error) {
-	return c.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string, opts LogOptions) (io.ReadCloser, error) {
+	cli, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+	return cli.ContainerLogs(ctx, containerID, container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
-		Tail:       tail,
-		Timestamps: true,
+		Tail:       opts.Tail,
+		Timestamps: opts.Timestamps,
+		Follow:     opts.Follow,
 	})
 }
 
 // PullImage pulls a Docker image
 func (c *Client) PullImage(ctx context.Context, image string) error {
-	reader, err := c.cli.ImagePull(ctx, image, types.ImagePullOptions{})
+	return c.PullImageWithProgress(ctx, image, "", nil)
+}
+
+// PullProgress is a single progress message from an image pull
+type PullProgress struct {
+	ID      string `json:"id,omitempty"` // Layer ID
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// pullMessage is the JSON message streamed by the Docker daemon during a pull
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+// PullImageWithProgress pulls an image, reporting each progress message to
+// onProgress. registryAuth is an encoded auth config or empty for anonymous pulls.
+func (c *Client) PullImageWithProgress(ctx context.Context, image, registryAuth string, onProgress func(PullProgress)) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.image.pull", attribute.String("image", image))
+	defer func() { tracing.End(span, err) }()
+
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: registryAuth})
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
-	// Consume the output
-	_, err = io.Copy(io.Discard, reader)
-	return err
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull failed: %s", msg.Error)
+		}
+		if onProgress != nil {
+			onProgress(PullProgress{
+				ID:      msg.ID,
+				Status:  msg.Status,
+				Current: msg.ProgressDetail.Current,
+				Total:   msg.ProgressDetail.Total,
+			})
+		}
+	}
+}
+
+// EncodeRegistryAuth encodes registry credentials for use with PullImageWithProgress
+func EncodeRegistryAuth(server, username, password string) (string, error) {
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: server,
+	})
 }
 
 // ImageExists checks if an image exists locally
 func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
-	_, _, err := c.cli.ImageInspectWithRaw(ctx, image)
+	cli, err := c.api()
 	if err != nil {
+		return false, err
+	}
+
+	if _, _, err := cli.ImageInspectWithRaw(ctx, image); err != nil {
 		if client.IsErrNotFound(err) {
 			return false, nil
 		}
@@ -308,28 +659,115 @@ func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
 	return true, nil
 }
 
+// ListImages returns the tags of the images present locally
+func (c *Client) ListImages(ctx context.Context) ([]string, error) {
+	cli, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, image := range images {
+		tags = append(tags, image.RepoTags...)
+	}
+	return tags, nil
+}
+
 // ListContainersByLabel lists containers with specific labels
 func (c *Client) ListContainersByLabel(ctx context.Context, labels map[string]string) ([]types.Container, error) {
+	cli, err := c.api()
+	if err != nil {
+		return nil, err
+	}
+
 	filterArgs := filters.NewArgs()
 	for k, v := range labels {
 		filterArgs.Add("label", fmt.Sprintf("%s=%s", k, v))
 	}
 
-	return c.cli.ContainerList(ctx, container.ListOptions{
+	return cli.ContainerList(ctx, container.ListOptions{
 		All:     true,
 		Filters: filterArgs,
 	})
 }
 
+// WatchContainerEvents streams the given actions of containers carrying the
+// labels until ctx is done or the stream fails
+func (c *Client) WatchContainerEvents(ctx context.Context, labels map[string]string, actions ...string) (<-chan events.Message, <-chan error) {
+	cli, err := c.api()
+	if err != nil {
+		errs := make(chan error, 1)
+		errs <- err
+		return nil, errs
+	}
+
+	filterArgs := filters.NewArgs(filters.Arg("type", "container"))
+	for k, v := range labels {
+		filterArgs.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+	for _, action := range actions {
+		filterArgs.Add("event", action)
+	}
+
+	return cli.Events(ctx, types.EventsOptions{Filters: filterArgs})
+}
+
 // CreateNetwork creates a Docker network
-func (c *Client) CreateNetwork(ctx context.Context, name string) error {
-	_, err := c.cli.NetworkCreate(ctx, name, types.NetworkCreate{
-		Driver: "bridge",
+func (c *Client) CreateNetwork(ctx context.Context, name string, labels map[string]string) error {
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.NetworkCreate(ctx, name, types.NetworkCreate{
+		Driver:         "bridge",
+		CheckDuplicate: true,
+		Labels:         labels,
 	})
 	return err
 }
 
+// EnsureNetwork creates a bridge network unless it already exists
+func (c *Client) EnsureNetwork(ctx context.Context, name string, labels map[string]string) error {
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	if _, err := cli.NetworkInspect(ctx, name, types.NetworkInspectOptions{}); err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return fmt.Errorf("failed to inspect network: %w", err)
+	}
+
+	if err := c.CreateNetwork(ctx, name, labels); err != nil {
+		return fmt.Errorf("failed to create network: %w", err)
+	}
+	return nil
+}
+
+// RemoveNetwork removes a Docker network, ignoring networks that do not exist
+func (c *Client) RemoveNetwork(ctx context.Context, name string) error {
+	cli, err := c.api()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.NetworkRemove(ctx, name); err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // GetSystemInfo returns Docker system information
 func (c *Client) GetSystemInfo(ctx context.Context) (types.Info, error) {
-	return c.cli.Info(ctx)
+	cli, err := c.api()
+	if err != nil {
+		return types.Info{}, err
+	}
+	return cli.Info(ctx)
 }
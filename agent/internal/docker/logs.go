@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Log stream names
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// maxLogLine bounds a single log line; longer lines are split
+const maxLogLine = 64 * 1024
+
+// LogLine is a single line of container output
+type LogLine struct {
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// DemuxLogs reads a container log stream and calls fn for every line. Streams
+// of containers without a TTY carry 8-byte headers (stream type, 3 zero bytes,
+// big-endian frame size) which are stripped and used to tell stdout from stderr.
+// TTY streams have no headers and are reported as stdout.
+func DemuxLogs(r io.Reader, fn func(LogLine) error) error {
+	br := bufio.NewReaderSize(r, maxLogLine)
+
+	header, err := br.Peek(8)
+	if err != nil && len(header) == 0 {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+
+	if !isFrameHeader(header) {
+		return splitLines(br, StreamStdout, fn)
+	}
+
+	// Partial lines are carried over between frames of the same stream
+	pending := map[string]*lineBuffer{
+		StreamStdout: {stream: StreamStdout},
+		StreamStderr: {stream: StreamStderr},
+	}
+
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+
+		stream := StreamStdout
+		if hdr[0] == 2 {
+			stream = StreamStderr
+		}
+
+		frame := make([]byte, binary.BigEndian.Uint32(hdr[4:]))
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return err
+		}
+		if err := pending[stream].write(frame, fn); err != nil {
+			return err
+		}
+	}
+
+	for _, stream := range []string{StreamStdout, StreamStderr} {
+		if err := pending[stream].flush(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isFrameHeader reports whether b starts with a multiplexed stream header
+func isFrameHeader(b []byte) bool {
+	return len(b) == 8 && b[0] <= 2 && b[1] == 0 && b[2] == 0 && b[3] == 0
+}
+
+// splitLines reports every line of an unframed stream
+func splitLines(r io.Reader, stream string, fn func(LogLine) error) error {
+	lines := &lineBuffer{stream: stream}
+	chunk := make([]byte, 32*1024)
+	for {
+		n, readErr := r.Read(chunk)
+		if err := lines.write(chunk[:n], fn); err != nil {
+			return err
+		}
+		if errors.Is(readErr, io.EOF) {
+			return lines.flush(fn)
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// lineBuffer collects the output of one stream into lines, splitting lines
+// longer than maxLogLine
+type lineBuffer struct {
+	stream string
+	buf    strings.Builder
+}
+
+// write reports every line completed by p and keeps the rest for later
+func (l *lineBuffer) write(p []byte, fn func(LogLine) error) error {
+	for _, b := range p {
+		if b == '\n' || l.buf.Len() >= maxLogLine {
+			if err := fn(LogLine{Stream: l.stream, Line: strings.TrimSuffix(l.buf.String(), "\r")}); err != nil {
+				return err
+			}
+			l.buf.Reset()
+			if b == '\n' {
+				continue
+			}
+		}
+		l.buf.WriteByte(b)
+	}
+	return nil
+}
+
+// flush reports a last line without a trailing newline
+func (l *lineBuffer) flush(fn func(LogLine) error) error {
+	if l.buf.Len() == 0 {
+		return nil
+	}
+	line := strings.TrimSuffix(l.buf.String(), "\r")
+	l.buf.Reset()
+	return fn(LogLine{Stream: l.stream, Line: line})
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// frame builds a multiplexed log frame of a stream, 1 for stdout and 2 for stderr
+func frame(stream byte, payload string) []byte {
+	hdr := make([]byte, 8)
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func demux(t *testing.T, data []byte) []LogLine {
+	t.Helper()
+	var lines []LogLine
+	err := DemuxLogs(bytes.NewReader(data), func(line LogLine) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DemuxLogs = %v", err)
+	}
+	return lines
+}
+
+func TestDemuxLogsFramed(t *testing.T) {
+	var data []byte
+	data = append(data, frame(1, "Starting server\r\nDone (3.2s)!")...)
+	data = append(data, frame(2, "WARN: low memory\n")...)
+	data = append(data, frame(1, " For help, type \"help\"\n")...)
+	data = append(data, frame(2, "unterminated")...)
+
+	want := []LogLine{
+		{Stream: StreamStdout, Line: "Starting server"},
+		{Stream: StreamStderr, Line: "WARN: low memory"},
+		{Stream: StreamStdout, Line: "Done (3.2s)! For help, type \"help\""},
+		{Stream: StreamStderr, Line: "unterminated"},
+	}
+	got := demux(t, data)
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines %q, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDemuxLogsTTY(t *testing.T) {
+	got := demux(t, []byte("Starting server\r\n> list\nThere are 0 players online"))
+	want := []string{"Starting server", "> list", "There are 0 players online"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines %q, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i].Stream != StreamStdout || got[i].Line != want[i] {
+			t.Errorf("line %d = %+v, want stdout %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDemuxLogsSplitsLongLines(t *testing.T) {
+	long := strings.Repeat("x", maxLogLine+100)
+	for name, data := range map[string][]byte{
+		"tty":    []byte(long + "\nafter\n"),
+		"framed": append(frame(1, long[:maxLogLine/2]), frame(1, long[maxLogLine/2:]+"\nafter\n")...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := demux(t, data)
+			if len(got) != 3 {
+				t.Fatalf("got %d lines, want the long line in 2 parts and the next line", len(got))
+			}
+			if len(got[0].Line) != maxLogLine || len(got[1].Line) != 100 {
+				t.Errorf("long line split into %d and %d bytes, want %d and 100", len(got[0].Line), len(got[1].Line), maxLogLine)
+			}
+			if got[2].Line != "after" {
+				t.Errorf("line after the long one = %q, want %q", got[2].Line, "after")
+			}
+		})
+	}
+}
+
+func TestDemuxLogsEmpty(t *testing.T) {
+	if got := demux(t, nil); len(got) != 0 {
+		t.Errorf("got %q from an empty stream", got)
+	}
+}
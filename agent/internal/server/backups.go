@@ -0,0 +1,369 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrChecksumMismatch is returned when a backup archive does not match the
+// checksum recorded by the panel
+var ErrChecksumMismatch = errors.New("backup checksum mismatch")
+
+// ErrBackupNotFound is returned when a backup archive is missing from the node
+var ErrBackupNotFound = errors.New("backup archive not found")
+
+// RestoreOptions controls how a backup is restored
+type RestoreOptions struct {
+	Checksum string // Expected SHA-256 of the archive, hex encoded
+	WipeData bool   // Empty the data directory before extracting
+}
+
+// RestoreProgress is published while a backup is restored
+type RestoreProgress struct {
+	BackupID string `json:"backup_id"`
+	Stage    string `json:"stage"` // verifying, extracting
+	Current  int64  `json:"current"`
+	Total    int64  `json:"total"`
+}
+
+// BackupArchivePath returns where the archive of a backup is stored
+func (m *Manager) BackupArchivePath(backupID string) (string, error) {
+	if backupID == "" || backupID != filepath.Base(backupID) {
+		return "", fmt.Errorf("invalid backup id: %q", backupID)
+	}
+	return filepath.Join(m.config.Storage.BackupPath, backupID+".tar.gz"), nil
+}
+
+// BackupArchive describes a server backup archive written by the node
+type BackupArchive struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"` // SHA-256, hex encoded
+}
+
+// CreateBackup archives a server's data directory and returns the archive's
+// size and checksum once it is complete. The server's lock is held for
+// reading throughout, so a reinstall or restore waits for the backup to
+// finish instead of changing the data under it.
+func (m *Manager) CreateBackup(ctx context.Context, serverID, backupID string) (*BackupArchive, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+	archivePath, err := m.BackupArchivePath(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	serverPath := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	size, checksum, err := writeArchiveFile(ctx, serverPath, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Server backed up",
+		zap.String("id", serverID),
+		zap.String("backup", backupID),
+		zap.Int64("size", size),
+	)
+	return &BackupArchive{Size: size, Checksum: checksum}, nil
+}
+
+// RestoreBackup restores a backup archive into a server's data directory. The
+// archive is verified against opts.Checksum before the server is touched. A
+// running server is stopped for the restore and started again afterwards.
+func (m *Manager) RestoreBackup(ctx context.Context, serverID, backupID string, opts RestoreOptions) error {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return err
+	}
+
+	archivePath, err := m.BackupArchivePath(backupID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.verifyBackup(serverID, backupID, archivePath, opts.Checksum); err != nil {
+		m.events.Publish(serverID, EventBackupRestoreFailed, map[string]string{"backup_id": backupID, "error": err.Error()})
+		return err
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	status, err := m.docker.GetContainerStatus(ctx, server.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container status: %w", err)
+	}
+	wasRunning := status == "running"
+
+	m.logger.Info("Restoring backup",
+		zap.String("id", serverID),
+		zap.String("backup", backupID),
+		zap.Bool("wipe_data", opts.WipeData),
+	)
+
+	if wasRunning {
+		server.expectStop()
+		if err := m.docker.StopContainer(ctx, server.ContainerID, m.config.Docker.StopTimeout); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
+		server.Status = "stopped"
+		server.StartedAt = nil
+	}
+
+	serverPath := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	if err := m.extractBackup(serverID, backupID, archivePath, serverPath, opts.WipeData); err != nil {
+		server.Status = "error"
+		m.events.Publish(serverID, EventBackupRestoreFailed, map[string]string{"backup_id": backupID, "error": err.Error()})
+		return err
+	}
+
+	if wasRunning {
+		if err := m.docker.StartContainer(ctx, server.ContainerID); err != nil {
+			return fmt.Errorf("backup restored but failed to start container: %w", err)
+		}
+		now := time.Now()
+		server.Status = "running"
+		server.StartedAt = &now
+	}
+
+	m.events.Publish(serverID, EventBackupRestoreComplete, map[string]string{"backup_id": backupID})
+	m.logger.Info("Backup restored", zap.String("id", serverID), zap.String("backup", backupID))
+	return nil
+}
+
+// verifyBackup compares the SHA-256 of an archive with the expected checksum
+func (m *Manager) verifyBackup(serverID, backupID, archivePath, checksum string) error {
+	if checksum == "" {
+		return fmt.Errorf("%w: no checksum recorded", ErrChecksumMismatch)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat backup archive: %w", err)
+	}
+
+	return matchChecksum(m.progressReader(serverID, backupID, "verifying", f, info.Size()), checksum)
+}
+
+// VerifyBackup checks a stored backup archive against its checksum without
+// touching the server it belongs to
+func (m *Manager) VerifyBackup(backupID, checksum string) error {
+	f, _, err := m.OpenBackup(backupID, checksum)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// OpenBackup opens a backup archive for download after verifying it against
+// its checksum, returning the archive rewound to the start and its size
+func (m *Manager) OpenBackup(backupID, checksum string) (*os.File, int64, error) {
+	if checksum == "" {
+		return nil, 0, fmt.Errorf("%w: no checksum recorded", ErrChecksumMismatch)
+	}
+
+	archivePath, err := m.BackupArchivePath(backupID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.Open(archivePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err == nil {
+		err = matchChecksum(f, checksum)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// matchChecksum compares the SHA-256 of everything read from r with checksum
+func matchChecksum(r io.Reader, checksum string) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return fmt.Errorf("failed to read backup archive: %w", err)
+	}
+
+	if !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), checksum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// extractBackup unpacks a gzipped tar archive into dest, emptying dest first
+// when wipe is set. Entries escaping dest and anything other than regular
+// files and directories are skipped, and symlinks already in dest are
+// replaced rather than followed.
+func (m *Manager) extractBackup(serverID, backupID, archivePath, dest string, wipe bool) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat backup archive: %w", err)
+	}
+
+	if wipe {
+		if err := clearDirectory(dest); err != nil {
+			return fmt.Errorf("failed to wipe server data: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("failed to create server directory: %w", err)
+	}
+
+	gz, err := gzip.NewReader(m.progressReader(serverID, backupID, "extracting", f, info.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup archive: %w", err)
+		}
+
+		target := filepath.Join(dest, header.Name)
+		if target != dest && !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+			m.logger.Warn("Skipping backup entry outside data directory", zap.String("entry", header.Name))
+			continue
+		}
+		if err := removeSymlinks(dest, target); err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeBackupFile(target, tr, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			m.logger.Debug("Skipping unsupported backup entry", zap.String("entry", header.Name))
+		}
+	}
+}
+
+// removeSymlinks removes the first symlink on the way from dest to target,
+// target included, so an entry is written inside dest. Servers can create
+// symlinks in their data directory, which a restore without wipe would
+// otherwise follow as root to anywhere on the host. Nothing below a removed
+// symlink exists, so the entry's directories are then created afresh.
+func removeSymlinks(dest, target string) error {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == "." {
+		return err
+	}
+
+	path := dest
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return os.Remove(path)
+		}
+	}
+	return nil
+}
+
+// writeBackupFile writes one archive entry to disk, replacing any existing file
+func writeBackupFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return out.Close()
+}
+
+// progressReader publishes restore progress roughly every percent read
+func (m *Manager) progressReader(serverID, backupID, stage string, r io.Reader, total int64) io.Reader {
+	return &restoreProgressReader{
+		r: r,
+		report: func(current int64) {
+			m.events.Publish(serverID, EventBackupRestore, RestoreProgress{
+				BackupID: backupID,
+				Stage:    stage,
+				Current:  current,
+				Total:    total,
+			})
+		},
+		step: total / 100,
+	}
+}
+
+type restoreProgressReader struct {
+	r        io.Reader
+	report   func(current int64)
+	step     int64
+	current  int64
+	reported int64
+}
+
+func (p *restoreProgressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.current += int64(n)
+	if p.current-p.reported >= p.step || err == io.EOF {
+		p.reported = p.current
+		p.report(p.current)
+	}
+	return n, err
+}
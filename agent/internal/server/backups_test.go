@@ -0,0 +1,290 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestArchive writes a gzipped tar archive of regular files to path
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractBackupReplacesSymlinks(t *testing.T) {
+	m, server, root := newTestManager(t)
+
+	outside := t.TempDir()
+	hostFile := filepath.Join(outside, "passwd")
+	if err := os.WriteFile(hostFile, []byte("root:x:0:0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "config")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(hostFile, filepath.Join(root, "server.properties")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "kept.txt"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	writeTestArchive(t, archive, map[string]string{
+		"config/passwd":     "restored",
+		"server.properties": "motd=restored\n",
+	})
+
+	if err := m.extractBackup(server.ID, "backup-1", archive, root, false); err != nil {
+		t.Fatalf("extractBackup: %v", err)
+	}
+
+	content, err := os.ReadFile(hostFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "root:x:0:0\n" {
+		t.Errorf("host file was rewritten: %q", content)
+	}
+
+	for name, want := range map[string]string{
+		"config/passwd":     "restored",
+		"server.properties": "motd=restored\n",
+		"kept.txt":          "kept",
+	} {
+		path := filepath.Join(root, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("%s is not a regular file: %v", name, info.Mode())
+		}
+		if content, _ := os.ReadFile(path); string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+// newRestoreTestServer tracks a server with a data directory holding
+// world.dat and writes a backup archive replacing it, returning the server,
+// its data directory and the archive's checksum
+func newRestoreTestServer(t *testing.T, m *Manager) (*ServerState, string, string) {
+	t.Helper()
+
+	m.config.Storage.BackupPath = t.TempDir()
+	server := addTestServer(m, "restore")
+	root := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "world.dat"), []byte("current"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := m.BackupArchivePath("backup-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestArchive(t, archive, map[string]string{"world.dat": "restored"})
+	content, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return server, root, hex.EncodeToString(sum[:])
+}
+
+func TestRestoreBackupChecksumMismatchLeavesServerUntouched(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	server, root, _ := newRestoreTestServer(t, m)
+	server.Status = "running"
+
+	err := m.RestoreBackup(context.Background(), server.ID, "backup-1", RestoreOptions{Checksum: hex.EncodeToString(make([]byte, sha256.Size)), WipeData: true})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("RestoreBackup = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if fake.requested("/containers/") {
+		t.Error("container was touched before the checksum was verified")
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "world.dat")); string(content) != "current" {
+		t.Errorf("world.dat = %q, want the data left as it was", content)
+	}
+	if server.Status != "running" {
+		t.Errorf("status = %s, want running", server.Status)
+	}
+}
+
+func TestCorruptedBackupFailsVerification(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	server, root, checksum := newRestoreTestServer(t, m)
+
+	if err := m.VerifyBackup("backup-1", checksum); err != nil {
+		t.Fatalf("VerifyBackup of the intact archive = %v", err)
+	}
+
+	// Flip one byte of the archive after its checksum was recorded
+	archive, _ := m.BackupArchivePath("backup-1")
+	content, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[len(content)/2] ^= 0xff
+	if err := os.WriteFile(archive, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.VerifyBackup("backup-1", checksum); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("VerifyBackup = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if _, _, err := m.OpenBackup("backup-1", checksum); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("OpenBackup = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if err := m.RestoreBackup(context.Background(), server.ID, "backup-1", RestoreOptions{Checksum: checksum, WipeData: true}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("RestoreBackup = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if fake.requested("/containers/") {
+		t.Error("container was touched for a corrupted backup")
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "world.dat")); string(content) != "current" {
+		t.Errorf("world.dat = %q, want the data left as it was", content)
+	}
+	if err := m.VerifyBackup("backup-2", checksum); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("VerifyBackup of a missing archive = %v, want %v", err, ErrBackupNotFound)
+	}
+}
+
+func TestRestoreBackupKeepsPowerState(t *testing.T) {
+	for _, state := range []string{"running", "exited"} {
+		t.Run(state, func(t *testing.T) {
+			m, fake := newDockerTestManager(t)
+			fake.state = state
+			server, root, checksum := newRestoreTestServer(t, m)
+
+			if err := m.RestoreBackup(context.Background(), server.ID, "backup-1", RestoreOptions{Checksum: checksum}); err != nil {
+				t.Fatalf("RestoreBackup: %v", err)
+			}
+			if content, _ := os.ReadFile(filepath.Join(root, "world.dat")); string(content) != "restored" {
+				t.Errorf("world.dat = %q, want the restored data", content)
+			}
+
+			running := state == "running"
+			if got := fake.requested("/stop"); got != running {
+				t.Errorf("container stopped = %v, want %v", got, running)
+			}
+			if got := fake.requested("/start"); got != running {
+				t.Errorf("container started = %v, want %v", got, running)
+			}
+			want := "stopped"
+			if running {
+				want = "running"
+			}
+			if server.Status != want {
+				t.Errorf("status = %s, want %s", server.Status, want)
+			}
+		})
+	}
+}
+
+func TestReinstallServerWipesOnlyWhenAsked(t *testing.T) {
+	for _, wipe := range []bool{false, true} {
+		t.Run(fmt.Sprintf("wipe=%v", wipe), func(t *testing.T) {
+			m, fake := newDockerTestManager(t)
+			close(fake.pull)
+			server := addTestServer(m, "reinstall")
+			server.Config.Image = "game:1"
+			root := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+			if err := os.MkdirAll(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+			marker := filepath.Join(root, "marker.txt")
+			if err := os.WriteFile(marker, []byte("kept"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.ReinstallServer(context.Background(), server.ID, wipe); err != nil {
+				t.Fatalf("ReinstallServer: %v", err)
+			}
+			_, err := os.Stat(marker)
+			if wipe && !os.IsNotExist(err) {
+				t.Errorf("marker file after a wipe: %v, want it removed", err)
+			}
+			if !wipe && err != nil {
+				t.Errorf("marker file without a wipe: %v, want it kept", err)
+			}
+			if _, err := os.Stat(root); err != nil {
+				t.Errorf("data directory: %v, want it kept", err)
+			}
+			if server.Status != "stopped" || server.ContainerID != "container-new" {
+				t.Errorf("status %s, container %s, want a stopped new container", server.Status, server.ContainerID)
+			}
+		})
+	}
+}
+
+func TestCreateBackupHoldsServerLock(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	m.config.Storage.BackupPath = t.TempDir()
+	server := addTestServer(m, "backup")
+	root := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "world.dat"), []byte("world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reinstall in progress holds the lock, so the backup waits for it
+	server.mu.Lock()
+	backedUp := make(chan error, 1)
+	go func() {
+		_, err := m.CreateBackup(context.Background(), server.ID, "backup-1")
+		backedUp <- err
+	}()
+	select {
+	case err := <-backedUp:
+		t.Fatalf("CreateBackup = %v during a reinstall, want it to wait", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	server.mu.Unlock()
+
+	if err := <-backedUp; err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	if !server.mu.TryLock() {
+		t.Fatal("server lock still held after the backup")
+	}
+	server.mu.Unlock()
+}
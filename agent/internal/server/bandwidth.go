@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// containerInterface is the network interface of a container on its one network
+const containerInterface = "eth0"
+
+// minBurst is the smallest burst in bytes a shaped interface is given, so
+// full-size packets always fit
+const minBurst = 16 * 1024
+
+// commandRunner runs a host command, returning its output in the error
+type commandRunner func(ctx context.Context, name string, args ...string) error
+
+func runCommand(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// bandwidthCommands returns the tc commands capping a container's interface.
+// Outgoing traffic is shaped with a token bucket, incoming traffic is policed
+// since it can only be dropped once it arrived. A limit of 0 adds no rule.
+func bandwidthCommands(in, out int64) [][]string {
+	var cmds [][]string
+	if out > 0 {
+		cmds = append(cmds, []string{
+			"qdisc", "add", "dev", containerInterface, "root", "tbf",
+			"rate", bitRate(out), "burst", burst(out), "latency", "50ms",
+		})
+	}
+	if in > 0 {
+		cmds = append(cmds,
+			[]string{"qdisc", "add", "dev", containerInterface, "handle", "ffff:", "ingress"},
+			[]string{
+				"filter", "add", "dev", containerInterface, "parent", "ffff:", "protocol", "all",
+				"u32", "match", "u32", "0", "0",
+				"police", "rate", bitRate(in), "burst", burst(in), "drop", "flowid", ":1",
+			},
+		)
+	}
+	return cmds
+}
+
+// clearCommands returns the tc commands removing any caps from a container's interface
+func clearCommands() [][]string {
+	return [][]string{
+		{"qdisc", "del", "dev", containerInterface, "root"},
+		{"qdisc", "del", "dev", containerInterface, "ingress"},
+	}
+}
+
+func bitRate(bytesPerSecond int64) string {
+	return strconv.FormatInt(bytesPerSecond*8, 10) + "bit"
+}
+
+// burst allows a tenth of a second at full rate
+func burst(bytesPerSecond int64) string {
+	return strconv.FormatInt(max(bytesPerSecond/10, minBurst), 10) + "b"
+}
+
+// applyBandwidth caps a running server's network traffic to its configured
+// limits, replacing earlier caps. The rules live in the container's network
+// namespace, so they are gone once the container stops and are applied again
+// on every start.
+func (m *Manager) applyBandwidth(ctx context.Context, server *ServerState) error {
+	server.mu.RLock()
+	if server.Config == nil {
+		server.mu.RUnlock()
+		return nil
+	}
+	in, out := server.Config.NetworkIn, server.Config.NetworkOut
+	server.mu.RUnlock()
+
+	pid, err := m.docker.ContainerPID(ctx, server.container())
+	if err != nil {
+		return err
+	}
+
+	// Removing caps that do not exist fails, which is expected
+	for _, args := range clearCommands() {
+		_ = m.tc(ctx, pid, args)
+	}
+	for _, args := range bandwidthCommands(in, out) {
+		if err := m.tc(ctx, pid, args); err != nil {
+			return err
+		}
+	}
+
+	if in > 0 || out > 0 {
+		m.logger.Debug("Server bandwidth limited",
+			zap.String("id", server.ID),
+			zap.Int64("network_in", in),
+			zap.Int64("network_out", out),
+		)
+	}
+	return nil
+}
+
+// tc runs a tc command inside the network namespace of a process
+func (m *Manager) tc(ctx context.Context, pid int, args []string) error {
+	return m.run(ctx, "nsenter", append([]string{"-t", strconv.Itoa(pid), "-n", "tc"}, args...)...)
+}
+
+// UpdateBandwidth replaces a server's bandwidth limits in bytes/s, 0 for
+// unlimited. A running server is capped right away.
+func (m *Manager) UpdateBandwidth(ctx context.Context, serverID string, in, out int64) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	if server.Config == nil {
+		server.mu.Unlock()
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+	server.Config.NetworkIn = in
+	server.Config.NetworkOut = out
+	running := server.Status == "running"
+	server.mu.Unlock()
+
+	m.logger.Info("Server bandwidth changed",
+		zap.String("id", serverID),
+		zap.Int64("network_in", in),
+		zap.Int64("network_out", out),
+	)
+	if !running {
+		return nil
+	}
+	return m.applyBandwidth(ctx, server)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUpdateBandwidthRunsTC(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		in, out int64
+		want    []string
+	}{
+		{"unlimited", 0, 0, nil},
+		{"outgoing", 0, 1 << 20, []string{
+			"qdisc add dev eth0 root tbf rate 8388608bit burst 104857b latency 50ms",
+		}},
+		{"both", 100 << 10, 1 << 20, []string{
+			"qdisc add dev eth0 root tbf rate 8388608bit burst 104857b latency 50ms",
+			"qdisc add dev eth0 handle ffff: ingress",
+			// Slow rates still let full-size packets through
+			"filter add dev eth0 parent ffff: protocol all u32 match u32 0 0 police rate 819200bit burst 16384b drop flowid :1",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := newDockerTestManager(t)
+			server := addTestServer(m, "shaped")
+			server.Status = "running"
+			var ran []string
+			m.run = func(ctx context.Context, name string, args ...string) error {
+				ran = append(ran, name+" "+strings.Join(args, " "))
+				return nil
+			}
+
+			if err := m.UpdateBandwidth(context.Background(), "shaped", tc.in, tc.out); err != nil {
+				t.Fatalf("UpdateBandwidth: %v", err)
+			}
+
+			// Earlier caps are always cleared first
+			want := []string{"qdisc del dev eth0 root", "qdisc del dev eth0 ingress"}
+			want = append(want, tc.want...)
+			if len(ran) != len(want) {
+				t.Fatalf("ran %q, want %d tc commands", ran, len(want))
+			}
+			for i, cmd := range want {
+				if ran[i] != "nsenter -t "+strconv.Itoa(testContainerPID)+" -n tc "+cmd {
+					t.Errorf("command %d = %q, want tc %s in the container's namespace", i, ran[i], cmd)
+				}
+			}
+			if server.Config.NetworkIn != tc.in || server.Config.NetworkOut != tc.out {
+				t.Errorf("config in %d out %d, want %d and %d", server.Config.NetworkIn, server.Config.NetworkOut, tc.in, tc.out)
+			}
+		})
+	}
+}
+
+func TestUpdateBandwidthOfStoppedServer(t *testing.T) {
+	m, _ := newDockerTestManager(t)
+	addTestServer(m, "idle")
+	m.run = func(ctx context.Context, name string, args ...string) error {
+		t.Errorf("ran %s %v on a stopped server", name, args)
+		return nil
+	}
+
+	// The limit is kept for the next start
+	if err := m.UpdateBandwidth(context.Background(), "idle", 1<<20, 1<<20); err != nil {
+		t.Fatalf("UpdateBandwidth: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+	"go.uber.org/zap"
+)
+
+// chatBufferSize is the number of chat messages kept per server until the
+// panel collects them
+const chatBufferSize = 500
+
+// chatPatterns match the chat lines in a game's console output. The first
+// group is the sender, the second the message.
+var chatPatterns = map[string]*regexp.Regexp{
+	// Vanilla "[12:00:00] [Server thread/INFO]: <Steve> hi", Paper "[12:00:00 INFO]: <Steve> hi"
+	"minecraft": regexp.MustCompile(`^\[[\d:]+(?: INFO)?\](?: \[[^\]]+/INFO\])?: (?:\[Not Secure\] )?<([^<>\s]+)> (.+)$`),
+	// "[CHAT] Steve[76561198000000000] : hi"
+	"rust": regexp.MustCompile(`^\[CHAT\] (.+?)\[\d+\] : (.+)$`),
+	// "<Steve> hi"
+	"terraria": regexp.MustCompile(`^<([^<>]+)> (.+)$`),
+}
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// ChatMessage is a chat line read from a server's console
+type ChatMessage struct {
+	Seq      int64     `json:"seq"`
+	Username string    `json:"username"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// ParseChatLine extracts the sender and message from a console line of the
+// given game. It reports false for lines that are not chat.
+func ParseChatLine(game, line string) (username, message string, ok bool) {
+	pattern, known := chatPatterns[strings.ToLower(game)]
+	if !known {
+		return "", "", false
+	}
+
+	line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return "", "", false
+	}
+	return strings.TrimSpace(match[1]), match[2], true
+}
+
+// chatBuffer holds the most recent chat messages of a server
+type chatBuffer struct {
+	mu        sync.Mutex
+	messages  []ChatMessage
+	seq       int64
+	following string // Container whose log is being followed
+}
+
+func newChatBuffer() *chatBuffer {
+	// Sequence numbers start at the current time so they keep growing across
+	// agent restarts and the panel's cursor never skips new messages
+	return &chatBuffer{seq: time.Now().UnixMicro()}
+}
+
+func (b *chatBuffer) add(username, message string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	b.messages = append(b.messages, ChatMessage{
+		Seq:      b.seq,
+		Username: username,
+		Message:  message,
+		SentAt:   time.Now(),
+	})
+	if len(b.messages) > chatBufferSize {
+		b.messages = b.messages[len(b.messages)-chatBufferSize:]
+	}
+}
+
+// ChatSince returns the buffered chat messages of a server newer than after,
+// oldest first
+func (m *Manager) ChatSince(serverID string, after int64) ([]ChatMessage, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	server.mu.RLock()
+	buf := server.chat
+	server.mu.RUnlock()
+
+	messages := make([]ChatMessage, 0)
+	if buf == nil {
+		return messages, nil
+	}
+
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	for _, msg := range buf.messages {
+		if msg.Seq > after {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// StartChatWatch follows the console output of running servers whose game
+// has a known chat format and buffers the chat lines for the panel
+func (m *Manager) StartChatWatch(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, server := range m.servers.Snapshot() {
+			m.followChat(ctx, server)
+		}
+	}
+}
+
+// followChat starts reading a running server's log unless that is already
+// happening for its current container
+func (m *Manager) followChat(ctx context.Context, server *ServerState) {
+	server.mu.Lock()
+	if server.Status != "running" || server.Config == nil || chatPatterns[strings.ToLower(server.Config.Game)] == nil {
+		server.mu.Unlock()
+		return
+	}
+	if server.chat == nil {
+		server.chat = newChatBuffer()
+	}
+	buf := server.chat
+	containerID := server.ContainerID
+	game := server.Config.Game
+	server.mu.Unlock()
+
+	buf.mu.Lock()
+	if buf.following == containerID {
+		buf.mu.Unlock()
+		return
+	}
+	buf.following = containerID
+	buf.mu.Unlock()
+
+	go func() {
+		defer func() {
+			buf.mu.Lock()
+			if buf.following == containerID {
+				buf.following = ""
+			}
+			buf.mu.Unlock()
+		}()
+
+		// Only new output; earlier lines were read by a previous follower
+		reader, err := m.docker.GetContainerLogs(ctx, containerID, docker.LogOptions{Tail: "0", Follow: true})
+		if err != nil {
+			m.logger.Debug("Failed to follow server log for chat", zap.String("id", server.ID), zap.Error(err))
+			return
+		}
+		defer reader.Close()
+
+		_ = docker.DemuxLogs(reader, func(line docker.LogLine) error {
+			if username, message, ok := ParseChatLine(game, line.Line); ok {
+				buf.add(username, message)
+			}
+			return nil
+		})
+	}()
+}
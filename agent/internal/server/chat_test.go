@@ -0,0 +1,26 @@
+package server
+
+import "testing"
+
+func TestParseChatLine(t *testing.T) {
+	for _, tc := range []struct {
+		game, line        string
+		username, message string
+		ok                bool
+	}{
+		{"minecraft", "[12:00:00] [Server thread/INFO]: <Steve> hello there", "Steve", "hello there", true},
+		{"Minecraft", "[12:00:00 INFO]: <Alex> gg", "Alex", "gg", true},
+		{"minecraft", "[12:00:00 INFO]: [Not Secure] <Alex> <3 you all", "Alex", "<3 you all", true},
+		{"minecraft", "\x1b[32m[12:00:00 INFO]: <Steve> colored\x1b[0m", "Steve", "colored", true},
+		{"minecraft", "[12:00:00 INFO]: Steve joined the game", "", "", false},
+		{"minecraft", "[12:00:00 WARN]: <Steve> not chat", "", "", false},
+		{"rust", "[CHAT] Steve[76561198000000000] : raid at dawn", "Steve", "raid at dawn", true},
+		{"terraria", "<Guide> hello", "Guide", "hello", true},
+		{"factorio", "<Steve> hello", "", "", false}, // No known chat format
+	} {
+		username, message, ok := ParseChatLine(tc.game, tc.line)
+		if ok != tc.ok || username != tc.username || message != tc.message {
+			t.Errorf("ParseChatLine(%s, %q) = %q, %q, %v, want %q, %q, %v", tc.game, tc.line, username, message, ok, tc.username, tc.message, tc.ok)
+		}
+	}
+}
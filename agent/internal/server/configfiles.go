@@ -0,0 +1,506 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileSpec is one file of an egg's config spec, in the Pterodactyl
+// format: the parser reading the file and the values to set, keyed by the
+// setting's name or, for structured files, its path such as
+// "listeners[0].host"
+type configFileSpec struct {
+	Parser string                     `json:"parser"`
+	Find   map[string]json.RawMessage `json:"find"`
+}
+
+// configParser returns content with the values set at their keys. content is
+// empty for a file that does not exist yet.
+type configParser func(content []byte, values map[string]string) ([]byte, error)
+
+var configParsers = map[string]configParser{
+	"properties": setProperties,
+	"file":       setLines,
+	"ini":        setINI,
+	"json":       setJSON,
+	"yaml":       setYAML,
+}
+
+// configPlaceholder matches placeholders such as {{server.build.default.port}}
+// and {{env.MOTD}} in config values
+var configPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*\}\}`)
+
+// applyConfigFiles rewrites the config files named by a server's egg with the
+// server's current values. A file that cannot be updated is logged and
+// skipped, so a bad spec never keeps a server from starting. The caller must
+// hold the server lock.
+func (m *Manager) applyConfigFiles(server *ServerState) {
+	cfg := server.Config
+	if cfg == nil || len(cfg.ConfigFiles) == 0 {
+		return
+	}
+
+	var specs map[string]configFileSpec
+	if err := json.Unmarshal(cfg.ConfigFiles, &specs); err != nil {
+		m.logger.Warn("Invalid config file spec", zap.String("id", server.ID), zap.Error(err))
+		return
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := m.applyConfigFile(server.ID, cfg, name, specs[name]); err != nil {
+			m.logger.Warn("Failed to apply config file",
+				zap.String("id", server.ID),
+				zap.String("file", name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// applyConfigFile sets the values of one spec in a file of the server's data
+// directory, creating the file when it does not exist yet
+func (m *Manager) applyConfigFile(serverID string, cfg *ServerConfig, name string, spec configFileSpec) error {
+	parse, ok := configParsers[spec.Parser]
+	if !ok {
+		return fmt.Errorf("unsupported parser %q", spec.Parser)
+	}
+
+	path, err := m.ServerFilePath(serverID, name)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(spec.Find))
+	for key, raw := range spec.Find {
+		value, ok := configValue(raw)
+		if !ok {
+			continue
+		}
+		values[key] = expandConfigValue(value, cfg.Environment)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	updated, err := parse(content, values)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(updated, content) {
+		return nil
+	}
+	return os.WriteFile(path, updated, mode)
+}
+
+// configValue returns a spec value as text. Strings, numbers and booleans are
+// supported, other values are skipped.
+func configValue(raw json.RawMessage) (string, bool) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64, bool:
+		return string(bytes.TrimSpace(raw)), true
+	}
+	return "", false
+}
+
+// expandConfigValue replaces the placeholders in a config value with the
+// server's values. Unknown placeholders are left untouched.
+func expandConfigValue(value string, env map[string]string) string {
+	return configPlaceholder.ReplaceAllStringFunc(value, func(match string) string {
+		if v, ok := configPlaceholderValue(configPlaceholder.FindStringSubmatch(match)[1], env); ok {
+			return v
+		}
+		return match
+	})
+}
+
+func configPlaceholderValue(name string, env map[string]string) (string, bool) {
+	switch name {
+	case "server.build.default.port":
+		name = "SERVER_PORT"
+	case "server.build.default.ip":
+		name = "SERVER_IP"
+	case "server.build.memory":
+		name = "SERVER_MEMORY"
+	default:
+		var ok bool
+		if name, ok = strings.CutPrefix(name, "server.build.env."); !ok {
+			if name, ok = strings.CutPrefix(name, "env."); !ok {
+				return "", false
+			}
+		}
+	}
+	value, ok := env[name]
+	return value, ok
+}
+
+// setProperties sets key=value lines, replacing existing ones and appending
+// missing keys
+func setProperties(content []byte, values map[string]string) ([]byte, error) {
+	lines := splitLines(content)
+	seen := make(map[string]bool, len(values))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			continue
+		}
+		key := trimmed
+		if sep := strings.IndexAny(trimmed, "=:"); sep >= 0 {
+			key = strings.TrimSpace(trimmed[:sep])
+		}
+		if value, ok := values[key]; ok {
+			lines[i] = key + "=" + value
+			seen[key] = true
+		}
+	}
+	for _, key := range sortedKeys(values) {
+		if !seen[key] {
+			lines = append(lines, key+"="+values[key])
+		}
+	}
+	return joinLines(lines), nil
+}
+
+// setLines replaces every line starting with a key by the key's value, for
+// files without a structure the other parsers understand
+func setLines(content []byte, values map[string]string) ([]byte, error) {
+	keys := sortedKeys(values)
+	lines := splitLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		for _, key := range keys {
+			if strings.HasPrefix(trimmed, key) {
+				lines[i] = values[key]
+				break
+			}
+		}
+	}
+	return joinLines(lines), nil
+}
+
+// setINI sets ini values addressed as "section.key", or "key" for values
+// before the first section. Missing keys are added at the end of their
+// section, missing sections at the end of the file.
+func setINI(content []byte, values map[string]string) ([]byte, error) {
+	pending := make(map[string][]string)
+	for _, name := range sortedKeys(values) {
+		section, key := "", name
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			section, key = name[:dot], name[dot+1:]
+		}
+		pending[section] = append(pending[section], key)
+	}
+	valueOf := func(section, key string) (string, bool) {
+		if section == "" {
+			v, ok := values[key]
+			return v, ok
+		}
+		v, ok := values[section+"."+key]
+		return v, ok
+	}
+
+	var out []string
+	section := ""
+	flush := func() {
+		for _, key := range pending[section] {
+			v, _ := valueOf(section, key)
+			out = append(out, key+" = "+v)
+		}
+		delete(pending, section)
+	}
+
+	for _, line := range splitLines(content) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			flush()
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			out = append(out, line)
+			continue
+		}
+		if trimmed != "" && trimmed[0] != ';' && trimmed[0] != '#' {
+			if sep := strings.IndexByte(trimmed, '='); sep >= 0 {
+				key := strings.TrimSpace(trimmed[:sep])
+				if v, ok := valueOf(section, key); ok {
+					line = line[:strings.IndexByte(line, '=')+1] + " " + v
+					keys := pending[section]
+					for i, k := range keys {
+						if k == key {
+							pending[section] = append(keys[:i:i], keys[i+1:]...)
+							break
+						}
+					}
+				}
+			}
+		}
+		out = append(out, line)
+	}
+	flush()
+
+	sections := make([]string, 0, len(pending))
+	for s := range pending {
+		sections = append(sections, s)
+	}
+	sort.Strings(sections)
+	for _, s := range sections {
+		if len(pending[s]) == 0 {
+			continue
+		}
+		out = append(out, "["+s+"]")
+		section = s
+		flush()
+	}
+	return joinLines(out), nil
+}
+
+// setJSON sets values at their paths in a JSON document, creating missing
+// objects on the way
+func setJSON(content []byte, values map[string]string) ([]byte, error) {
+	var root interface{} = map[string]interface{}{}
+	if len(bytes.TrimSpace(content)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&root); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+	}
+
+	for _, key := range sortedKeys(values) {
+		path, err := parseConfigPath(key)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = setJSONPath(root, path, typedConfigValue(values[key])); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	out, err := json.MarshalIndent(root, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func setJSONPath(node interface{}, path []configPathPart, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	part := path[0]
+
+	if part.index >= 0 {
+		list, ok := node.([]interface{})
+		if !ok || part.index >= len(list) {
+			return nil, fmt.Errorf("no element %d", part.index)
+		}
+		child, err := setJSONPath(list[part.index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list[part.index] = child
+		return list, nil
+	}
+
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		if node != nil {
+			return nil, fmt.Errorf("%s is not an object", part.key)
+		}
+		obj = make(map[string]interface{})
+	}
+	child, err := setJSONPath(obj[part.key], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[part.key] = child
+	return obj, nil
+}
+
+// setYAML sets values at their paths in a YAML document, creating missing
+// mappings on the way. Comments and key order are kept.
+func setYAML(content []byte, values map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	if len(bytes.TrimSpace(content)) > 0 {
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	for _, key := range sortedKeys(values) {
+		path, err := parseConfigPath(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := setYAMLPath(doc.Content[0], path, values[key]); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func setYAMLPath(node *yaml.Node, path []configPathPart, value string) error {
+	if len(path) == 0 {
+		node.Kind = yaml.ScalarNode
+		node.Style = 0
+		node.Content = nil
+		node.Value = value
+		switch typedConfigValue(value).(type) {
+		case bool:
+			node.Tag = "!!bool"
+		case int64:
+			node.Tag = "!!int"
+		case float64:
+			node.Tag = "!!float"
+		default:
+			node.Tag = "!!str"
+		}
+		return nil
+	}
+	part := path[0]
+
+	if part.index >= 0 {
+		if node.Kind != yaml.SequenceNode || part.index >= len(node.Content) {
+			return fmt.Errorf("no element %d", part.index)
+		}
+		return setYAMLPath(node.Content[part.index], path[1:], value)
+	}
+
+	if node.Kind != yaml.MappingNode {
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!null" {
+			return fmt.Errorf("%s is not a mapping", part.key)
+		}
+		node.Kind, node.Tag, node.Value = yaml.MappingNode, "!!map", ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == part.key {
+			return setYAMLPath(node.Content[i+1], path[1:], value)
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part.key}, child)
+	return setYAMLPath(child, path[1:], value)
+}
+
+// configPathPart is a key, or with index >= 0 a list element, of a path
+type configPathPart struct {
+	key   string
+	index int
+}
+
+// parseConfigPath splits a path such as "listeners[0].host" into its parts
+func parseConfigPath(path string) ([]configPathPart, error) {
+	var parts []configPathPart
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key != "" {
+			parts = append(parts, configPathPart{key: key, index: -1})
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			parts = append(parts, configPathPart{index: n})
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	return parts, nil
+}
+
+// typedConfigValue returns a value as a bool or number when it is written as
+// one exactly, so "25565" becomes a number but "1.20" stays a string
+func typedConfigValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		return f
+	}
+	return value
+}
+
+func splitLines(content []byte) []string {
+	text := strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func joinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
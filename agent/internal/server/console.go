@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// SanitizeConsoleLine makes a line of container output safe to send as JSON
+// and to render. Invalid UTF-8 is replaced, and control characters other than
+// tabs are dropped, carriage returns included. Terminal escape sequences that
+// set colours or move the cursor are kept unless stripANSI is set; any other
+// escape, such as a window title change, is always removed.
+func SanitizeConsoleLine(line string, stripANSI bool) string {
+	line = strings.ToValidUTF8(line, "\uFFFD")
+
+	var b strings.Builder
+	b.Grow(len(line))
+	for i := 0; i < len(line); {
+		if line[i] == 0x1b {
+			n, csi := escapeLen(line[i:])
+			if csi && !stripANSI {
+				b.WriteString(line[i : i+n])
+			}
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if r == '\t' || !unicode.IsControl(r) {
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// escapeLen returns the length of the escape sequence at the start of s, which
+// begins with ESC, and whether it is a complete CSI sequence. A sequence cut
+// off by the end of the line runs to the end of it.
+func escapeLen(s string) (int, bool) {
+	if len(s) < 2 {
+		return len(s), false
+	}
+
+	switch s[1] {
+	case '[':
+		// CSI: parameter bytes, intermediate bytes, then a final byte
+		i := 2
+		for i < len(s) && s[i] >= 0x30 && s[i] <= 0x3f {
+			i++
+		}
+		for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+			i++
+		}
+		if i < len(s) && s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1, true
+		}
+		return i, false
+	case ']':
+		// OSC: ends with BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1, false
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2, false
+			}
+		}
+		return len(s), false
+	}
+
+	// Any other escape: intermediate bytes, such as the "(" of a character
+	// set selection, then a final byte
+	i := 1
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+		i++
+	}
+	if i < len(s) && s[i] >= 0x30 && s[i] <= 0x7e {
+		return i + 1, false
+	}
+	return i, false
+}
+
+// StripsANSI reports whether a server's console output is sent as plain text
+func (m *Manager) StripsANSI(serverID string) bool {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return false
+	}
+
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	return server.Config != nil && server.Config.StripANSI
+}
+
+// UpdateConsoleOutput sets whether a server's console output has its colour
+// and cursor escapes stripped. Consoles opened afterwards use the new setting.
+func (m *Manager) UpdateConsoleOutput(serverID string, stripANSI bool) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
+
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+	server.Config.StripANSI = stripANSI
+
+	m.logger.Info("Server console output changed",
+		zap.String("id", serverID),
+		zap.Bool("strip_ansi", stripANSI),
+	)
+	return nil
+}
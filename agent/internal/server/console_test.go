@@ -0,0 +1,46 @@
+package server
+
+import "testing"
+
+func TestSanitizeConsoleLine(t *testing.T) {
+	for _, tc := range []struct {
+		name, line     string
+		colours, plain string
+	}{
+		{"plain text", "Done (3.2s)!", "Done (3.2s)!", "Done (3.2s)!"},
+		{"colours", "\x1b[32m[INFO]\x1b[0m Started", "\x1b[32m[INFO]\x1b[0m Started", "[INFO] Started"},
+		{"cursor movement", "\x1b[2K\x1b[1Gloading 50%", "\x1b[2K\x1b[1Gloading 50%", "loading 50%"},
+		{"window title", "\x1b]0;Minecraft server\x07ready", "ready", "ready"},
+		{"title ended by ST", "\x1b]2;title\x1b\\ready", "ready", "ready"},
+		{"other escapes", "\x1bMup\x1b7\x1b(Bdone", "updone", "updone"},
+		{"cut off sequence", "done\x1b[3", "done", "done"},
+		{"control characters", "a\x00b\x07c\rd\te", "abcd\te", "abcd\te"},
+		{"invalid UTF-8", "caf\xe9 \xff\xfeok", "caf� �ok", "caf� �ok"},
+		{"valid UTF-8", "§aWelcome, Zoë 👋", "§aWelcome, Zoë 👋", "§aWelcome, Zoë 👋"},
+	} {
+		if got := SanitizeConsoleLine(tc.line, false); got != tc.colours {
+			t.Errorf("%s: SanitizeConsoleLine(%q, false) = %q, want %q", tc.name, tc.line, got, tc.colours)
+		}
+		if got := SanitizeConsoleLine(tc.line, true); got != tc.plain {
+			t.Errorf("%s: SanitizeConsoleLine(%q, true) = %q, want %q", tc.name, tc.line, got, tc.plain)
+		}
+	}
+}
+
+func TestUpdateConsoleOutput(t *testing.T) {
+	m, _ := newDockerTestManager(t)
+	addTestServer(m, "game")
+
+	if m.StripsANSI("game") {
+		t.Error("new server strips ANSI, want colours kept")
+	}
+	if err := m.UpdateConsoleOutput("game", true); err != nil {
+		t.Fatal(err)
+	}
+	if !m.StripsANSI("game") {
+		t.Error("server keeps ANSI after opting into plain text")
+	}
+	if err := m.UpdateConsoleOutput("missing", true); err == nil {
+		t.Error("updating an unknown server succeeded")
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EventServerCrashed is published when a server exits without being asked to
+const EventServerCrashed = "server_crashed"
+
+// CrashRecovery is a server's policy for restarting after a crash
+type CrashRecovery struct {
+	Enabled     bool `json:"enabled"`
+	MaxRestarts int  `json:"max_restarts"` // Automatic restarts allowed within Window
+	Window      int  `json:"window"`       // seconds
+}
+
+// CrashReport describes the last crash of a server
+type CrashReport struct {
+	CrashedAt time.Time `json:"crashed_at"`
+	ExitCode  int       `json:"exit_code"`
+	Restarts  int       `json:"restarts"` // Automatic restarts within the window, including this one
+	GaveUp    bool      `json:"gave_up"`  // The restart limit was reached and the server left stopped
+}
+
+// UpdateCrashRecovery replaces a server's crash recovery policy. Recovery
+// needs the container's own restart policy off, so turning it on or off
+// recreates the container on the next start.
+func (m *Manager) UpdateCrashRecovery(serverID string, recovery CrashRecovery) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
+
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+
+	if server.Config.CrashRecovery.Enabled != recovery.Enabled {
+		server.ConfigDirty = true
+	}
+	server.Config.CrashRecovery = recovery
+	server.crashes = nil
+
+	m.logger.Info("Server crash recovery changed",
+		zap.String("id", serverID),
+		zap.Bool("enabled", recovery.Enabled),
+		zap.Int("max_restarts", recovery.MaxRestarts),
+		zap.Int("window", recovery.Window),
+	)
+	return nil
+}
+
+// expectStop marks the next exit of a server as requested, so it is not taken
+// for a crash. The caller must hold the server lock.
+func (s *ServerState) expectStop() {
+	s.stopRequested = true
+}
+
+// handleExit decides whether a container exit was a crash and, when the
+// server's policy allows, restarts it. Exits after a power action and clean
+// exits, such as a stop typed into the console, are not crashes. It returns
+// whether the exit was handled as a crash.
+func (m *Manager) handleExit(ctx context.Context, server *ServerState, exitCode string) bool {
+	code, _ := strconv.Atoi(exitCode)
+
+	server.mu.Lock()
+	requested := server.stopRequested
+	server.stopRequested = false
+	if requested || code == 0 || server.Config == nil || !server.Config.CrashRecovery.Enabled {
+		server.mu.Unlock()
+		return false
+	}
+
+	policy := server.Config.CrashRecovery
+	now := time.Now()
+	window := time.Duration(policy.Window) * time.Second
+	recent := server.crashes[:0]
+	for _, at := range server.crashes {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+
+	report := &CrashReport{CrashedAt: now, ExitCode: code}
+	if len(recent) >= policy.MaxRestarts {
+		report.Restarts = len(recent)
+		report.GaveUp = true
+		server.crashes = nil
+	} else {
+		server.crashes = append(recent, now)
+		report.Restarts = len(server.crashes)
+	}
+	server.Crash = report
+	server.StartedAt = nil
+	server.mu.Unlock()
+
+	m.events.Publish(server.ID, EventServerCrashed, report)
+
+	if report.GaveUp {
+		m.logger.Warn("Server keeps crashing, giving up on restarts",
+			zap.String("id", server.ID),
+			zap.Int("exit_code", code),
+			zap.Int("restarts", report.Restarts),
+		)
+		m.setStatus(server, "crashed")
+		return true
+	}
+
+	m.logger.Warn("Server crashed, restarting",
+		zap.String("id", server.ID),
+		zap.Int("exit_code", code),
+		zap.Int("restart", report.Restarts),
+		zap.Int("max_restarts", policy.MaxRestarts),
+	)
+	m.setStatus(server, "restarting")
+
+	// Starting takes the server lock and may recreate the container, so it
+	// must not hold up the event stream
+	go func() {
+		if err := m.StartServer(ctx, server.ID); err != nil {
+			m.logger.Error("Failed to restart crashed server", zap.String("id", server.ID), zap.Error(err))
+			m.setStatus(server, "error")
+		}
+	}()
+	return true
+}
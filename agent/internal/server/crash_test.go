@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// addCrashTestServer tracks a running server restarted after up to
+// maxRestarts crashes a minute
+func addCrashTestServer(m *Manager, maxRestarts int) *ServerState {
+	server := addTestServer(m, "crashy")
+	server.Status = "running"
+	server.Config.CrashRecovery = CrashRecovery{Enabled: true, MaxRestarts: maxRestarts, Window: 60}
+	return server
+}
+
+// waitForStatus waits for the status a restart in the background leaves
+func waitForStatus(t *testing.T, server *ServerState, status string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.mu.Lock()
+		current := server.Status
+		server.mu.Unlock()
+		if current == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %s, want %s", current, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestedStopsAreNotCrashes(t *testing.T) {
+	for name, stop := range map[string]func(m *Manager, serverID, checksum string) error{
+		"stop": func(m *Manager, serverID, _ string) error {
+			return m.StopServer(context.Background(), serverID)
+		},
+		"kill": func(m *Manager, serverID, _ string) error {
+			return m.KillServer(context.Background(), serverID)
+		},
+		"restore": func(m *Manager, serverID, checksum string) error {
+			return m.RestoreBackup(context.Background(), serverID, "backup-1", RestoreOptions{Checksum: checksum})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, _ := newDockerTestManager(t)
+			server, _, checksum := newRestoreTestServer(t, m)
+			server.Status = "running"
+			server.Config.CrashRecovery = CrashRecovery{Enabled: true, MaxRestarts: 3, Window: 60}
+
+			if err := stop(m, server.ID, checksum); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if m.handleExit(context.Background(), server, "143") {
+				t.Errorf("exit after a %s was handled as a crash", name)
+			}
+			if server.Crash != nil {
+				t.Errorf("crash report %+v recorded for a %s", server.Crash, name)
+			}
+		})
+	}
+}
+
+func TestCrashRecoveryGivesUpAtTheLimit(t *testing.T) {
+	m, _ := newDockerTestManager(t)
+	server := addCrashTestServer(m, 2)
+	events, cancel := m.events.Subscribe(server.ID)
+	defer cancel()
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		if !m.handleExit(ctx, server, "1") {
+			t.Fatalf("crash %d was not handled", i)
+		}
+		waitForStatus(t, server, "running")
+	}
+
+	if !m.handleExit(ctx, server, "1") {
+		t.Fatal("crash beyond the limit was not handled")
+	}
+	if server.Status != "crashed" {
+		t.Errorf("status = %s after the limit, want crashed", server.Status)
+	}
+	if server.Crash == nil || !server.Crash.GaveUp || server.Crash.Restarts != 2 {
+		t.Errorf("crash report = %+v, want one giving up after 2 restarts", server.Crash)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if server.Status != "crashed" {
+		t.Errorf("server was restarted after the limit, status %s", server.Status)
+	}
+
+	var reports []*CrashReport
+	for len(events) > 0 {
+		if event := <-events; event.Type == EventServerCrashed {
+			reports = append(reports, event.Data.(*CrashReport))
+		}
+	}
+	if len(reports) != 3 || reports[0].GaveUp || reports[1].GaveUp || !reports[2].GaveUp {
+		t.Errorf("crash events = %+v, want two restarts and one giving up", reports)
+	}
+}
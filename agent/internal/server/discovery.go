@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"go.uber.org/zap"
+)
+
+// DiscoveredServer describes an Aether-managed container found on this node
+type DiscoveredServer struct {
+	ServerID    string            `json:"server_id"`
+	UUID        string            `json:"uuid"`
+	ContainerID string            `json:"container_id"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	State       string            `json:"state"`
+	StartupCmd  string            `json:"startup_cmd"`
+	Environment map[string]string `json:"environment"`
+	MemoryLimit int64             `json:"memory_limit"` // MB
+	CPULimit    int               `json:"cpu_limit"`    // percentage
+	Allocations []Allocation      `json:"allocations"`
+	Labels      map[string]string `json:"labels"`
+	Tracked     bool              `json:"tracked"` // Already known to this agent
+}
+
+// DiscoverServers lists every container carrying the aether.managed label,
+// including containers the agent is not currently tracking
+func (m *Manager) DiscoverServers(ctx context.Context) ([]DiscoveredServer, error) {
+	containers, err := m.docker.ListContainersByLabel(ctx, map[string]string{
+		"aether.managed": "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	servers := m.servers.Snapshot()
+	tracked := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		tracked[s.container()] = true
+	}
+
+	discovered := make([]DiscoveredServer, 0, len(containers))
+	for _, c := range containers {
+		info, err := m.docker.InspectContainer(ctx, c.ID)
+		if err != nil {
+			m.logger.Warn("Failed to inspect container", zap.String("container", c.ID), zap.Error(err))
+			continue
+		}
+
+		d := DiscoveredServer{
+			ServerID:    c.Labels["aether.server.id"],
+			UUID:        c.Labels["aether.server.uuid"],
+			ContainerID: c.ID,
+			Name:        strings.TrimPrefix(info.Name, "/"),
+			Image:       c.Image,
+			State:       c.State,
+			Environment: make(map[string]string),
+			Labels:      c.Labels,
+			Tracked:     tracked[c.ID],
+		}
+
+		if info.Config != nil {
+			d.Image = info.Config.Image
+			d.StartupCmd = startupFromCmd(info.Config.Cmd)
+			for _, kv := range info.Config.Env {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					d.Environment[k] = v
+				}
+			}
+		}
+
+		if info.HostConfig != nil {
+			d.MemoryLimit = info.HostConfig.Memory / 1024 / 1024
+			d.CPULimit = int(info.HostConfig.CPUQuota / 1000)
+			d.Allocations = allocationsFromBindings(info.HostConfig.PortBindings)
+		}
+
+		discovered = append(discovered, d)
+	}
+
+	return discovered, nil
+}
+
+// startupFromCmd reverses the "<shell> -c <cmd>" wrapping used by CreateServer
+func startupFromCmd(cmd []string) string {
+	if len(cmd) == 3 && cmd[1] == "-c" {
+		return cmd[2]
+	}
+	return strings.Join(cmd, " ")
+}
+
+// allocationsFromBindings collapses the tcp/udp bindings of a host port into a
+// single allocation, bound for both protocols when both are present. The
+// lowest port is treated as the primary allocation.
+func allocationsFromBindings(bindings nat.PortMap) []Allocation {
+	index := make(map[string]int)
+	var allocations []Allocation
+	for containerPort, hostBindings := range bindings {
+		protocol := containerPort.Proto()
+		if protocol != protocolTCP && protocol != protocolUDP {
+			continue
+		}
+		for _, b := range hostBindings {
+			port, err := strconv.Atoi(b.HostPort)
+			if err != nil {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", b.HostIP, port)
+			if i, ok := index[key]; ok {
+				if allocations[i].Protocol != protocol {
+					allocations[i].Protocol = protocolBoth
+				}
+				continue
+			}
+			index[key] = len(allocations)
+			allocations = append(allocations, Allocation{IP: b.HostIP, Port: port, Protocol: protocol})
+		}
+	}
+
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Port < allocations[j].Port
+	})
+	if len(allocations) > 0 {
+		allocations[0].IsPrimary = true
+	}
+	return allocations
+}
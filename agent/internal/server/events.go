@@ -0,0 +1,90 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published for servers
+const (
+	EventImagePull         = "image_pull"
+	EventImagePullComplete = "image_pull_complete"
+	EventImagePullFailed   = "image_pull_failed"
+
+	EventBackupRestore         = "backup_restore"
+	EventBackupRestoreComplete = "backup_restore_complete"
+	EventBackupRestoreFailed   = "backup_restore_failed"
+
+	EventStatus    = "status"
+	EventServerOOM = "server_oom"
+
+	EventConsoleOutput = "console_output"
+)
+
+// Event is a server scoped notification streamed to console subscribers
+type Event struct {
+	Type      string      `json:"type"`
+	ServerID  string      `json:"server_id"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// EventBus fans out server events to subscribers
+type EventBus struct {
+	subscribers map[string]map[chan Event]struct{}
+	mu          sync.RWMutex
+}
+
+// NewEventBus creates a new EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving events for a server and a function
+// that cancels the subscription
+func (b *EventBus) Subscribe(serverID string) (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+
+	b.mu.Lock()
+	if b.subscribers[serverID] == nil {
+		b.subscribers[serverID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[serverID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if subs, ok := b.subscribers[serverID]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+			if len(subs) == 0 {
+				delete(b.subscribers, serverID)
+			}
+		}
+	}
+}
+
+// Publish delivers an event to the server's subscribers. Slow subscribers
+// miss events rather than blocking the publisher.
+func (b *EventBus) Publish(serverID, eventType string, data interface{}) {
+	event := Event{
+		Type:      eventType,
+		ServerID:  serverID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[serverID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+// StartDockerMonitor pings the Docker daemon periodically. When it stops
+// answering, the node reports itself unhealthy and the client is recreated
+// with exponential backoff. Server states are reconciled once Docker is back.
+func (m *Manager) StartDockerMonitor(ctx context.Context) {
+	interval := time.Duration(m.config.Docker.HealthInterval) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := m.docker.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		m.logger.Error("Docker daemon unavailable", zap.Error(err))
+		if !m.reconnectDocker(ctx) {
+			return
+		}
+
+		m.logger.Info("Docker daemon reconnected, reconciling servers")
+		m.checkAllServers(ctx)
+	}
+}
+
+// reconnectDocker retries connecting to the daemon until it succeeds or ctx
+// is done, and reports whether it reconnected
+func (m *Manager) reconnectDocker(ctx context.Context) bool {
+	delay := reconnectMinDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := m.docker.Reconnect(attemptCtx)
+		cancel()
+		if err == nil {
+			return true
+		}
+
+		delay = min(delay*2, reconnectMaxDelay)
+		m.logger.Warn("Failed to reconnect to Docker", zap.Duration("retry_in", delay), zap.Error(err))
+	}
+}
+
+// DockerAvailable reports whether the Docker daemon is reachable
+func (m *Manager) DockerAvailable() bool {
+	return m.docker.Available()
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+)
+
+func TestDockerMonitorReconnectsAndReconciles(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	m.config.Docker.HealthInterval = 1
+	// The container stops while the daemon restarts
+	fake.state = "exited"
+	server := addTestServer(m, "watched")
+	server.Status = "running"
+	addTestServer(m, "idle")
+	fake.down.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.StartDockerMonitor(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.DockerAvailable() {
+		if time.Now().After(deadline) {
+			t.Fatal("the daemon drop was not noticed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Operations fail clearly during the outage, and states are left alone
+	if err := m.StartServer(context.Background(), "idle"); !errors.Is(err, docker.ErrUnavailable) {
+		t.Errorf("StartServer during the outage = %v, want %v", err, docker.ErrUnavailable)
+	}
+	m.checkAllServers(context.Background())
+	server.mu.RLock()
+	status := server.Status
+	server.mu.RUnlock()
+	if status != "running" {
+		t.Errorf("status = %s during the outage, want the last known running", status)
+	}
+
+	fake.down.Store(false)
+	waitForStatus(t, server, "exited")
+	if !m.DockerAvailable() {
+		t.Error("Docker is not available after reconnecting")
+	}
+}
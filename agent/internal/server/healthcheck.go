@@ -0,0 +1,69 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+)
+
+// EventServerUnhealthy is published when a server's healthcheck starts failing
+const EventServerUnhealthy = "server_unhealthy"
+
+// Container health states reported by Docker
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Healthcheck is an egg-defined command Docker runs inside the container to
+// tell whether the game server is actually serving
+type Healthcheck struct {
+	Test        string `json:"test"`         // Shell command, exit code 0 is healthy
+	Interval    int    `json:"interval"`     // seconds
+	Timeout     int    `json:"timeout"`      // seconds
+	Retries     int    `json:"retries"`      // Consecutive failures before unhealthy
+	StartPeriod int    `json:"start_period"` // seconds of failures ignored after start
+}
+
+// dockerConfig returns the healthcheck as Docker takes it, nil when unset
+func (h *Healthcheck) dockerConfig() *container.HealthConfig {
+	if h == nil || h.Test == "" {
+		return nil
+	}
+	return &container.HealthConfig{
+		Test:        []string{"CMD-SHELL", h.Test},
+		Interval:    time.Duration(h.Interval) * time.Second,
+		Timeout:     time.Duration(h.Timeout) * time.Second,
+		Retries:     h.Retries,
+		StartPeriod: time.Duration(h.StartPeriod) * time.Second,
+	}
+}
+
+// healthFromAction returns the health carried by a Docker health_status
+// event, whose action reads like "health_status: unhealthy"
+func healthFromAction(action string) (string, bool) {
+	health, ok := strings.CutPrefix(action, "health_status:")
+	return strings.TrimSpace(health), ok
+}
+
+// setHealth records a server's container health and publishes an event when
+// it turns unhealthy
+func (m *Manager) setHealth(server *ServerState, health string) {
+	server.mu.Lock()
+	previous := server.Health
+	server.Health = health
+	server.mu.Unlock()
+
+	if previous == health || health != HealthUnhealthy {
+		return
+	}
+
+	m.logger.Warn("Server is unhealthy", zap.String("id", server.ID), zap.String("previous", previous))
+	m.events.Publish(server.ID, EventServerUnhealthy, map[string]string{
+		"health":   health,
+		"previous": previous,
+	})
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCreateServerPassesHealthcheck(t *testing.T) {
+	for _, check := range []*Healthcheck{nil, {Test: "pgrep java", Interval: 30, Timeout: 5, Retries: 3, StartPeriod: 60}} {
+		m, fake := newDockerTestManager(t)
+		close(fake.pull)
+
+		err := m.CreateServer(context.Background(), &ServerConfig{
+			ID:          "new",
+			UUID:        "uuid-new",
+			Image:       "ghcr.io/example/game:latest",
+			StartupCmd:  "./start.sh",
+			Healthcheck: check,
+		})
+		if err != nil {
+			t.Fatalf("CreateServer: %v", err)
+		}
+
+		got := fake.lastCreated(t).Config.Healthcheck
+		if check == nil {
+			if got != nil {
+				t.Errorf("healthcheck %+v without one configured, want the image's own", got)
+			}
+			continue
+		}
+		if got == nil {
+			t.Fatal("the configured healthcheck was not passed to Docker")
+		}
+		if len(got.Test) != 2 || got.Test[0] != "CMD-SHELL" || got.Test[1] != "pgrep java" {
+			t.Errorf("test = %q, want the command run through the shell", got.Test)
+		}
+		if got.Interval != 30*time.Second || got.Timeout != 5*time.Second || got.StartPeriod != time.Minute || got.Retries != 3 {
+			t.Errorf("healthcheck %+v, want every 30s with a 5s timeout, 3 retries and a minute to start", got)
+		}
+	}
+}
+
+func TestUnhealthyServerIsReported(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	fake.health = HealthUnhealthy
+	server := addTestServer(m, "sick")
+	server.Status = "running"
+	server.Health = "healthy"
+	events, unsubscribe := m.events.Subscribe("sick")
+	defer unsubscribe()
+
+	// Polling reports the health Docker inspects, once per change
+	m.checkAllServers(context.Background())
+	m.checkAllServers(context.Background())
+	if server.Health != HealthUnhealthy {
+		t.Errorf("health = %q, want unhealthy", server.Health)
+	}
+	var reports []map[string]string
+	for _, e := range drain(events) {
+		if e.Type == EventServerUnhealthy {
+			reports = append(reports, e.Data.(map[string]string))
+		}
+	}
+	if len(reports) != 1 || reports[0]["previous"] != "healthy" {
+		t.Errorf("published %v, want one report of the server turning unhealthy", reports)
+	}
+
+	// Health events from the stream are surfaced as well
+	watchEvents(t, m)
+	fake.events <- containerEvent(t, server, "health_status: healthy", nil)
+	fake.events <- containerEvent(t, server, "health_status: unhealthy", nil)
+	deadline := time.After(5 * time.Second)
+	for reported := false; !reported; {
+		select {
+		case e := <-events:
+			reported = e.Type == EventServerUnhealthy
+		case <-deadline:
+			t.Fatal("the unhealthy event was not reported")
+		}
+	}
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	if server.Health != HealthUnhealthy {
+		t.Errorf("health = %q, want unhealthy", server.Health)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/config"
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+	"go.uber.org/zap"
+)
+
+// Image pull policies
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+// ensureImage makes sure a server's image is available according to the
+// configured pull policy. force pulls even a present image, unless the policy
+// is never. Pull progress is published on the server's event bus.
+func (m *Manager) ensureImage(ctx context.Context, serverID, image string, force bool) error {
+	policy := m.config.Docker.PullPolicy
+
+	exists, err := m.docker.ImageExists(ctx, image)
+	if err != nil {
+		return fmt.Errorf("failed to check image: %w", err)
+	}
+
+	switch policy {
+	case PullNever:
+		if !exists {
+			return fmt.Errorf("image %s is not present on this node and pull_policy is %q", image, PullNever)
+		}
+		return nil
+	case PullAlways:
+	default:
+		if exists && !force {
+			return nil
+		}
+	}
+
+	auth, err := m.registryAuth(image)
+	if err != nil {
+		return fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+
+	m.logger.Info("Pulling image", zap.String("image", image))
+	m.events.Publish(serverID, EventImagePull, docker.PullProgress{Status: "Pulling " + image})
+
+	err = m.docker.PullImageWithProgress(ctx, image, auth, func(p docker.PullProgress) {
+		m.events.Publish(serverID, EventImagePull, p)
+	})
+	if err != nil {
+		m.events.Publish(serverID, EventImagePullFailed, map[string]string{"image": image, "error": err.Error()})
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	m.events.Publish(serverID, EventImagePullComplete, map[string]string{"image": image})
+	return nil
+}
+
+// ListImages returns the tags of the images present on the node
+func (m *Manager) ListImages(ctx context.Context) ([]string, error) {
+	return m.docker.ListImages(ctx)
+}
+
+// PullImage pulls an image ahead of the servers using it, so their first start
+// does not wait on the download. The never pull policy still applies.
+func (m *Manager) PullImage(ctx context.Context, image string) error {
+	return m.ensureImage(ctx, "", image, true)
+}
+
+// SetRegistries replaces the registry credentials pushed by the panel. They take
+// precedence over credentials from the agent configuration file.
+func (m *Manager) SetRegistries(registries []config.RegistryAuth) {
+	m.registryMu.Lock()
+	defer m.registryMu.Unlock()
+	m.registries = registries
+}
+
+// registryAuth returns the encoded credentials for an image's registry, or an
+// empty string for an anonymous pull when no credentials match
+func (m *Manager) registryAuth(image string) (string, error) {
+	host := registryHost(image)
+
+	m.registryMu.RLock()
+	candidates := append([]config.RegistryAuth{}, m.registries...)
+	m.registryMu.RUnlock()
+	candidates = append(candidates, m.config.Docker.Registries...)
+
+	for _, creds := range candidates {
+		if creds.Host == host {
+			return docker.EncodeRegistryAuth(host, creds.Username, creds.Password)
+		}
+	}
+	return "", nil
+}
+
+// registryHost returns the registry an image reference is pulled from
+func registryHost(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/config"
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+)
+
+const testImage = "ghcr.io/example/game:latest"
+
+// drain returns the events published so far on a subscription
+func drain(events <-chan Event) []Event {
+	var out []Event
+	for {
+		select {
+		case e := <-events:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func TestEnsureImagePullPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy  string
+		present bool
+		force   bool
+		pulled  bool
+		fails   bool
+	}{
+		{PullNever, false, false, false, true},
+		{PullNever, true, true, false, false},
+		{PullIfNotPresent, false, false, true, false},
+		{PullIfNotPresent, true, false, false, false},
+		{PullIfNotPresent, true, true, true, false},
+		{PullAlways, true, false, true, false},
+	} {
+		m, fake := newDockerTestManager(t)
+		close(fake.pull)
+		fake.images = map[string]bool{testImage: tc.present}
+		m.config.Docker.PullPolicy = tc.policy
+
+		err := m.ensureImage(context.Background(), "server-1", testImage, tc.force)
+		if (err != nil) != tc.fails {
+			t.Errorf("%s, present %v, force %v: err = %v, want failure %v", tc.policy, tc.present, tc.force, err, tc.fails)
+		}
+		if pulled := fake.requested("POST /images/create"); pulled != tc.pulled {
+			t.Errorf("%s, present %v, force %v: pulled = %v, want %v", tc.policy, tc.present, tc.force, pulled, tc.pulled)
+		}
+	}
+}
+
+func TestEnsureImagePublishesPullProgress(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	m.config.Docker.PullPolicy = PullIfNotPresent
+	events, unsubscribe := m.events.Subscribe("server-1")
+	defer unsubscribe()
+
+	if err := m.ensureImage(context.Background(), "server-1", testImage, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	published := drain(events)
+	for _, e := range published {
+		if p, ok := e.Data.(docker.PullProgress); ok && e.Type == EventImagePull {
+			statuses = append(statuses, p.Status)
+		}
+	}
+	if len(statuses) != 2 || !strings.HasPrefix(statuses[0], "Pulling") || statuses[1] != "Downloaded newer image" {
+		t.Errorf("pull progress %q, want the pull announced and the daemon's progress", statuses)
+	}
+	if last := published[len(published)-1]; last.Type != EventImagePullComplete {
+		t.Errorf("last event %s, want %s", last.Type, EventImagePullComplete)
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	for image, want := range map[string]string{
+		"itzg/minecraft-server:java21":   "docker.io",
+		"ubuntu":                         "docker.io",
+		"ghcr.io/example/game:latest":    "ghcr.io",
+		"registry.example.com:5000/game": "registry.example.com:5000",
+		"localhost/game":                 "localhost",
+	} {
+		if got := registryHost(image); got != want {
+			t.Errorf("registryHost(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestRegistryAuthMatchesHost(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	m.config.Docker.Registries = []config.RegistryAuth{
+		{Host: "ghcr.io", Username: "from-config", Password: "secret"},
+		{Host: "docker.io", Username: "hub", Password: "secret"},
+	}
+	m.SetRegistries([]config.RegistryAuth{{Host: "ghcr.io", Username: "from-panel", Password: "secret"}})
+
+	for image, want := range map[string]string{
+		"ghcr.io/example/game:latest":    "from-panel", // Panel credentials win
+		"itzg/minecraft-server":          "hub",
+		"quay.io/example/game":           "", // Anonymous
+		"registry.example.com/ghcr.io/x": "",
+	} {
+		encoded, err := m.registryAuth(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == "" {
+			if encoded != "" {
+				t.Errorf("%s: credentials %q, want an anonymous pull", image, encoded)
+			}
+			continue
+		}
+
+		data, err := base64.URLEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("%s: %v", image, err)
+		}
+		var auth struct {
+			Username      string `json:"username"`
+			ServerAddress string `json:"serveraddress"`
+		}
+		if err := json.Unmarshal(data, &auth); err != nil {
+			t.Fatalf("%s: %v", image, err)
+		}
+		if auth.Username != want || auth.ServerAddress != registryHost(image) {
+			t.Errorf("%s: credentials of %s for %s, want %s for %s", image, auth.Username, auth.ServerAddress, want, registryHost(image))
+		}
+	}
+}
+
+func TestWarmupImages(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	fake.images = map[string]bool{testImage: true, "itzg/minecraft-server:java21": true, "gone:1": false}
+	m.config.Docker.PullPolicy = PullIfNotPresent
+
+	images, err := m.ListImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{testImage, "itzg/minecraft-server:java21"}; strings.Join(images, ",") != strings.Join(want, ",") {
+		t.Errorf("images %q, want %q", images, want)
+	}
+
+	// The panel picked the image for pulling, so a present image is refreshed
+	if err := m.PullImage(context.Background(), testImage); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.requested("POST /images/create") {
+		t.Error("image not pulled")
+	}
+
+	m.config.Docker.PullPolicy = PullNever
+	if err := m.PullImage(context.Background(), "gone:1"); err == nil {
+		t.Error("pulled a missing image with the never pull policy")
+	}
+}
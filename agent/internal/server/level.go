@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// NBT tag types used by Minecraft's level.dat
+const (
+	tagEnd byte = iota
+	tagByte
+	tagShort
+	tagInt
+	tagLong
+	tagFloat
+	tagDouble
+	tagByteArray
+	tagString
+	tagList
+	tagCompound
+	tagIntArray
+	tagLongArray
+)
+
+// maxNBTDepth bounds nesting so a corrupt file cannot exhaust the stack
+const maxNBTDepth = 64
+
+var errInvalidNBT = errors.New("invalid nbt data")
+
+// readLevelDat decodes a gzip compressed level.dat and returns its root compound
+func readLevelDat(path string) (map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read level.dat: %w", err)
+	}
+	defer gz.Close()
+
+	r := &nbtReader{r: bufio.NewReader(gz)}
+	tag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagCompound {
+		return nil, errInvalidNBT
+	}
+	if _, err := r.string(); err != nil {
+		return nil, err
+	}
+
+	root, err := r.payload(tagCompound, 0)
+	if err != nil {
+		return nil, err
+	}
+	return root.(map[string]interface{}), nil
+}
+
+// nbtReader decodes big endian NBT payloads into Go values. Compounds become
+// maps and lists become slices.
+type nbtReader struct {
+	r io.Reader
+}
+
+func (n *nbtReader) payload(tag byte, depth int) (interface{}, error) {
+	if depth > maxNBTDepth {
+		return nil, errInvalidNBT
+	}
+
+	switch tag {
+	case tagByte:
+		return n.byte()
+	case tagShort:
+		var v int16
+		err := binary.Read(n.r, binary.BigEndian, &v)
+		return v, err
+	case tagInt:
+		var v int32
+		err := binary.Read(n.r, binary.BigEndian, &v)
+		return v, err
+	case tagLong:
+		var v int64
+		err := binary.Read(n.r, binary.BigEndian, &v)
+		return v, err
+	case tagFloat:
+		var v uint32
+		err := binary.Read(n.r, binary.BigEndian, &v)
+		return math.Float32frombits(v), err
+	case tagDouble:
+		var v uint64
+		err := binary.Read(n.r, binary.BigEndian, &v)
+		return math.Float64frombits(v), err
+	case tagString:
+		return n.string()
+	case tagByteArray:
+		return n.array(1)
+	case tagIntArray:
+		return n.array(4)
+	case tagLongArray:
+		return n.array(8)
+	case tagList:
+		elem, err := n.byte()
+		if err != nil {
+			return nil, err
+		}
+		length, err := n.length()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, 0, min(length, 1024))
+		for i := 0; i < length; i++ {
+			v, err := n.payload(elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case tagCompound:
+		compound := make(map[string]interface{})
+		for {
+			child, err := n.byte()
+			if err != nil {
+				return nil, err
+			}
+			if child == tagEnd {
+				return compound, nil
+			}
+			name, err := n.string()
+			if err != nil {
+				return nil, err
+			}
+			v, err := n.payload(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			compound[name] = v
+		}
+	default:
+		return nil, errInvalidNBT
+	}
+}
+
+func (n *nbtReader) byte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(n.r, b[:])
+	return b[0], err
+}
+
+func (n *nbtReader) string() (string, error) {
+	var length uint16
+	if err := binary.Read(n.r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(n.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (n *nbtReader) length() (int, error) {
+	var length int32
+	if err := binary.Read(n.r, binary.BigEndian, &length); err != nil {
+		return 0, err
+	}
+	if length < 0 {
+		return 0, errInvalidNBT
+	}
+	return int(length), nil
+}
+
+// array skips over an array payload; level metadata never needs its contents
+func (n *nbtReader) array(size int64) (interface{}, error) {
+	length, err := n.length()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, n.r, int64(length)*size); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -13,28 +16,71 @@ import (
 	"go.uber.org/zap"
 )
 
+// statusCreating marks a server whose container is still being built
+const statusCreating = "creating"
+
+// ErrServerCreating is returned for actions on a server that is still being created
+var ErrServerCreating = errors.New("server is still being created")
+
 // ServerState represents the state of a managed server
 type ServerState struct {
 	ID          string
 	UUID        string
 	ContainerID string
 	Status      string
+	DiskLimit   int64 // MB
+	Config      *ServerConfig
+	ImageDirty  bool // Image changed, container is recreated on next start or restart
+	ConfigDirty bool // Startup or allocations changed, container is recreated on next start or restart
 	StartedAt   *time.Time
 	Stats       *ServerStats
+	OOMKill     *OOMKill     // Set when the container was last stopped by the OOM killer
+	Crash       *CrashReport // Set when the server last crashed, cleared on start
+	Health      string       // Container health, empty without a healthcheck or while stopped
+	LastMemory  uint64       // bytes, from the last sample while running
+	chat        *chatBuffer  // Recent chat lines, nil until followed
 	mu          sync.RWMutex
+
+	stopRequested bool        // A power action is stopping the container, its exit is not a crash
+	crashes       []time.Time // Automatic restarts within the crash recovery window
+}
+
+// container returns the current container ID, which changes when the
+// container is recreated. The caller must not hold the server lock.
+func (s *ServerState) container() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ContainerID
+}
+
+// createdContainer returns the current container ID, refusing servers whose
+// container is still being created and has no ID yet. The caller must not
+// hold the server lock.
+func (s *ServerState) createdContainer() (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Status == statusCreating {
+		return "", ErrServerCreating
+	}
+	return s.ContainerID, nil
 }
 
 // ServerStats represents server resource usage
 type ServerStats struct {
+	Status        string    `json:"status"`
 	CPUPercent    float64   `json:"cpu_percent"`
 	MemoryUsage   uint64    `json:"memory_usage"`
 	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
 	DiskUsage     uint64    `json:"disk_usage"`
 	DiskLimit     uint64    `json:"disk_limit"`
 	NetworkRx     uint64    `json:"network_rx"`
 	NetworkTx     uint64    `json:"network_tx"`
 	Uptime        int64     `json:"uptime"`
-	CollectedAt   time.Time `json:"collected_at"`
+	Health        string    `json:"health,omitempty"` // Container health when the egg defines a healthcheck
+	OOMKill       *OOMKill     `json:"oom_kill,omitempty"`
+	Crash         *CrashReport `json:"crash,omitempty"`
+	CollectedAt   time.Time    `json:"collected_at"`
 }
 
 // Manager manages game servers on this node
@@ -42,8 +88,13 @@ type Manager struct {
 	docker  *docker.Client
 	config  *config.Config
 	logger  *zap.Logger
-	servers map[string]*ServerState
-	mu      sync.RWMutex
+	servers *serverMap
+	events  *EventBus
+
+	registries []config.RegistryAuth // Pushed by the panel
+	registryMu sync.RWMutex
+
+	run commandRunner // Runs host commands such as tc
 }
 
 // NewManager creates a new server manager
@@ -52,10 +103,17 @@ func NewManager(dockerClient *docker.Client, cfg *config.Config, logger *zap.Log
 		docker:  dockerClient,
 		config:  cfg,
 		logger:  logger,
-		servers: make(map[string]*ServerState),
+		servers: newServerMap(),
+		events:  NewEventBus(),
+		run:     runCommand,
 	}
 }
 
+// Events returns the bus carrying server events such as image pull progress
+func (m *Manager) Events() *EventBus {
+	return m.events
+}
+
 // ServerConfig represents server configuration from panel
 type ServerConfig struct {
 	ID           string            `json:"id"`
@@ -65,10 +123,46 @@ type ServerConfig struct {
 	StartupCmd   string            `json:"startup_cmd"`
 	Environment  map[string]string `json:"environment"`
 	MemoryLimit  int64             `json:"memory_limit"`  // MB
+	SwapLimit    int64             `json:"swap_limit"`    // MB beyond memory, 0 for none, -1 for unlimited
 	DiskLimit    int64             `json:"disk_limit"`    // MB
 	CPULimit     int               `json:"cpu_limit"`     // percentage
+	CPUSet       string            `json:"cpu_set"`       // cores to pin to, empty for quota only
+	NetworkMode  string            `json:"network_mode"`  // node, isolated or bridge; empty for the agent default
+	NetworkIn    int64             `json:"network_in"`    // bytes/s, 0 for unlimited
+	NetworkOut   int64             `json:"network_out"`   // bytes/s, 0 for unlimited
+	Game         string            `json:"game"`          // selects the console chat format, e.g. minecraft
+	StripANSI    bool              `json:"strip_ansi"`    // console output as plain text instead of with colours
 	Allocations  []Allocation      `json:"allocations"`
 	Mounts       []Mount           `json:"mounts"`
+
+	// Restarts after crashes, handled by the agent instead of Docker
+	CrashRecovery CrashRecovery `json:"crash_recovery"`
+	Healthcheck   *Healthcheck  `json:"healthcheck,omitempty"`
+	ProcessLimits ProcessLimits `json:"process_limits"`
+
+	// Root filesystem read-only, only the data volume, mounts and the Tmpfs
+	// paths (e.g. /tmp) stay writable
+	ReadOnlyRootfs bool     `json:"read_only_rootfs"`
+	Tmpfs          []string `json:"tmpfs,omitempty"`
+
+	// Capabilities added back on top of the node's base set, all others are
+	// dropped
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Entrypoint and shell of the container, from the egg
+	ContainerCommand
+
+	// Egg config file rules, written into the data directory before each start
+	ConfigFiles json.RawMessage `json:"config_files,omitempty"`
+}
+
+// ProcessLimits caps the processes and open files of a server's container,
+// guarding the node against fork bombs and descriptor exhaustion. Fields left
+// 0 use the agent's defaults.
+type ProcessLimits struct {
+	Pids   int64 `json:"pids"`   // Processes and threads in the container
+	NoFile int64 `json:"nofile"` // Open files per process
+	NProc  int64 `json:"nproc"`  // Processes per user
 }
 
 // Allocation represents a port allocation
@@ -76,6 +170,7 @@ type Allocation struct {
 	IP        string `json:"ip"`
 	Port      int    `json:"port"`
 	IsPrimary bool   `json:"is_primary"`
+	Protocol  string `json:"protocol,omitempty"` // tcp, udp or both; empty for both
 }
 
 // Mount represents a volume mount
@@ -85,17 +180,142 @@ type Mount struct {
 	ReadOnly bool   `json:"read_only"`
 }
 
-// CreateServer creates and starts a new server
+// CreateServer creates and starts a new server. It is idempotent per server
+// UUID: a container already labelled with the UUID is adopted instead of
+// creating a second one, and repeating a finished create succeeds.
 func (m *Manager) CreateServer(ctx context.Context, cfg *ServerConfig) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err := m.validateMounts(cfg.Mounts); err != nil {
+		return err
+	}
+	if err := validateTmpfs(cfg.Tmpfs); err != nil {
+		return err
+	}
+	if err := m.validateCapabilities(cfg.Capabilities); err != nil {
+		return err
+	}
+	if _, err := cfg.command(cfg.StartupCmd); err != nil {
+		return err
+	}
+	if err := validateAllocations(cfg.Allocations); err != nil {
+		return err
+	}
+
+	// Claim the ID first so a duplicate create is answered right away. The
+	// image pull and container creation then run without holding any lock,
+	// actions on the server are refused until they finish.
+	server := &ServerState{
+		ID:        cfg.ID,
+		UUID:      cfg.UUID,
+		Status:    statusCreating,
+		DiskLimit: cfg.DiskLimit,
+		Config:    cfg,
+	}
+	if !m.servers.SetIfAbsent(server) {
+		return m.alreadyCreated(cfg)
+	}
 
 	m.logger.Info("Creating server", zap.String("id", cfg.ID), zap.String("name", cfg.Name))
 
+	// A create retried by the panel, such as after a timeout, may find the
+	// container of the earlier attempt even though it was never tracked
+	containerID, status, err := m.findServerContainer(ctx, cfg.UUID)
+	if err != nil {
+		m.servers.DeleteIf(server)
+		return err
+	}
+
+	adopted := containerID != ""
+	if adopted {
+		serverPath := filepath.Join(m.config.Storage.ServerDataPath, cfg.UUID)
+		if err := os.MkdirAll(serverPath, 0755); err != nil {
+			m.servers.DeleteIf(server)
+			return fmt.Errorf("failed to create server directory: %w", err)
+		}
+		m.logger.Info("Adopting existing container", zap.String("id", cfg.ID), zap.String("container", containerID))
+		status = adoptedStatus(status)
+	} else {
+		containerID, err = m.buildContainer(ctx, cfg, true)
+		if err != nil {
+			m.servers.DeleteIf(server)
+			return err
+		}
+		status = "created"
+	}
+
+	server.mu.Lock()
+	server.ContainerID = containerID
+	server.Status = status
+	// An adopted container was built from the config of an earlier attempt,
+	// which may differ, so it is recreated on its next start
+	server.ConfigDirty = adopted
+	server.mu.Unlock()
+
+	m.logger.Info("Server created", zap.String("id", cfg.ID), zap.String("container", containerID))
+	return nil
+}
+
+// adoptedStatus maps the Docker state of an adopted container onto the
+// statuses the agent reports for servers it created
+func adoptedStatus(state string) string {
+	switch state {
+	case "running", "restarting":
+		return "running"
+	case "created":
+		return "created"
+	default:
+		return "stopped"
+	}
+}
+
+// alreadyCreated decides a create for a server ID that is already tracked. A
+// repeat of the same server succeeds once its creation has finished.
+func (m *Manager) alreadyCreated(cfg *ServerConfig) error {
+	existing, ok := m.servers.Get(cfg.ID)
+	if !ok {
+		return fmt.Errorf("server already exists: %s", cfg.ID)
+	}
+
+	existing.mu.Lock()
+	uuid, status := existing.UUID, existing.Status
+	existing.mu.Unlock()
+
+	if uuid != cfg.UUID {
+		return fmt.Errorf("server already exists: %s", cfg.ID)
+	}
+	if status == statusCreating {
+		return ErrServerCreating
+	}
+	return nil
+}
+
+// findServerContainer returns the container labelled with a server's UUID and
+// its state, or an empty ID when there is none
+func (m *Manager) findServerContainer(ctx context.Context, uuid string) (string, string, error) {
+	containers, err := m.docker.ListContainersByLabel(ctx, map[string]string{
+		"aether.server.uuid": uuid,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list containers: %w", err)
+	}
+	if len(containers) == 0 {
+		return "", "", nil
+	}
+	if len(containers) > 1 {
+		m.logger.Warn("Several containers carry the same server UUID",
+			zap.String("uuid", uuid),
+			zap.Int("containers", len(containers)),
+		)
+	}
+	return containers[0].ID, containers[0].State, nil
+}
+
+// buildContainer prepares the data directory, pulls the image if pull is set
+// and the pull policy requires it, and creates the container for a server
+func (m *Manager) buildContainer(ctx context.Context, cfg *ServerConfig, pull bool) (string, error) {
 	// Create server data directory
 	serverPath := filepath.Join(m.config.Storage.ServerDataPath, cfg.UUID)
 	if err := os.MkdirAll(serverPath, 0755); err != nil {
-		return fmt.Errorf("failed to create server directory: %w", err)
+		return "", fmt.Errorf("failed to create server directory: %w", err)
 	}
 
 	// Prepare environment variables
@@ -104,7 +324,11 @@ func (m *Manager) CreateServer(ctx context.Context, cfg *ServerConfig) error {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Prepare mounts
+	// Prepare mounts, checked again as the allowlist may have shrunk since
+	// the server was created
+	if err := m.validateMounts(cfg.Mounts); err != nil {
+		return "", err
+	}
 	var mounts []docker.MountConfig
 	mounts = append(mounts, docker.MountConfig{
 		Source: serverPath,
@@ -118,34 +342,38 @@ func (m *Manager) CreateServer(ctx context.Context, cfg *ServerConfig) error {
 		})
 	}
 
-	// Prepare ports
-	var ports []docker.PortConfig
-	for _, alloc := range cfg.Allocations {
-		ports = append(ports, docker.PortConfig{
-			HostIP:   alloc.IP,
-			HostPort: fmt.Sprintf("%d", alloc.Port),
-			ContPort: fmt.Sprintf("%d", alloc.Port),
-			Protocol: "tcp",
-		})
-		// Also bind UDP for game servers
-		ports = append(ports, docker.PortConfig{
-			HostIP:   alloc.IP,
-			HostPort: fmt.Sprintf("%d", alloc.Port),
-			ContPort: fmt.Sprintf("%d", alloc.Port),
-			Protocol: "udp",
-		})
+	// Prepare ports, bound only for the protocols each allocation needs
+	ports, err := portConfigs(cfg.Allocations)
+	if err != nil {
+		return "", err
+	}
+
+	// Pull image according to the pull policy
+	if pull {
+		if err := m.ensureImage(ctx, cfg.ID, cfg.Image, false); err != nil {
+			return "", err
+		}
 	}
 
-	// Pull image if needed
-	exists, err := m.docker.ImageExists(ctx, cfg.Image)
+	networkName, err := m.serverNetwork(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to check image: %w", err)
+		return "", err
 	}
-	if !exists {
-		m.logger.Info("Pulling image", zap.String("image", cfg.Image))
-		if err := m.docker.PullImage(ctx, cfg.Image); err != nil {
-			return fmt.Errorf("failed to pull image: %w", err)
-		}
+
+	limits := m.processLimits(cfg.ProcessLimits)
+
+	// Checked again as the allowlist may have shrunk since the server was
+	// created
+	if err := m.validateCapabilities(cfg.Capabilities); err != nil {
+		return "", err
+	}
+	securityOpt, err := m.securityOptions()
+	if err != nil {
+		return "", err
+	}
+	cmd, err := cfg.command(cfg.StartupCmd)
+	if err != nil {
+		return "", err
 	}
 
 	// Create container
@@ -153,57 +381,90 @@ func (m *Manager) CreateServer(ctx context.Context, cfg *ServerConfig) error {
 	containerCfg := &docker.ContainerConfig{
 		Name:        containerName,
 		Image:       cfg.Image,
-		Cmd:         []string{"/bin/bash", "-c", cfg.StartupCmd},
+		Entrypoint:  cfg.Entrypoint,
+		Cmd:         cmd,
 		Env:         env,
 		WorkingDir:  "/home/container",
 		User:        "container",
-		Labels: map[string]string{
+		Labels: m.labels(map[string]string{
 			"aether.server.id":   cfg.ID,
 			"aether.server.uuid": cfg.UUID,
-			"aether.managed":     "true",
-		},
+		}),
 		Mounts:      mounts,
 		Ports:       ports,
 		Memory:      cfg.MemoryLimit * 1024 * 1024,      // Convert MB to bytes
-		MemorySwap:  cfg.MemoryLimit * 1024 * 1024 * 2,  // 2x memory for swap
+		MemorySwap:  memorySwap(cfg.MemoryLimit, cfg.SwapLimit),
 		CPUQuota:    int64(cfg.CPULimit) * 1000,         // CPU quota
 		CPUPeriod:   100000,                              // 100ms period
+		CpusetCpus:  cfg.CPUSet,
+		CpusetMems:  numaNodes(cfg.CPUSet),
 		IOWeight:    500,
-		NetworkMode: m.config.Docker.NetworkMode,
+		NetworkMode: networkName,
 		DNS:         m.config.Docker.DNS,
 		StopTimeout: m.config.Docker.StopTimeout,
+		Healthcheck: cfg.Healthcheck.dockerConfig(),
+		PidsLimit:   limits.Pids,
+		NoFile:      limits.NoFile,
+		NProc:       limits.NProc,
+		CapDrop:     []string{"ALL"},
+		CapAdd:      m.capabilities(cfg.Capabilities),
+		SecurityOpt: securityOpt,
+	}
+	if cfg.ReadOnlyRootfs {
+		containerCfg.ReadOnlyRootfs = true
+		containerCfg.Tmpfs = m.tmpfsMounts(cfg.Tmpfs)
+	}
+	// Docker would restart a crashed container itself, bypassing the limit
+	if cfg.CrashRecovery.Enabled {
+		containerCfg.RestartPolicy = "no"
 	}
 
 	containerID, err := m.docker.CreateContainer(ctx, containerCfg)
 	if err != nil {
-		return fmt.Errorf("failed to create container: %w", err)
+		return "", fmt.Errorf("failed to create container: %w", err)
 	}
+	return containerID, nil
+}
 
-	// Store server state
-	m.servers[cfg.ID] = &ServerState{
-		ID:          cfg.ID,
-		UUID:        cfg.UUID,
-		ContainerID: containerID,
-		Status:      "created",
+// memorySwap returns Docker's MemorySwap in bytes for a server's memory and
+// swap in MB. Docker counts memory and swap together and takes -1 for
+// unlimited swap, so no swap is a total equal to the memory.
+func memorySwap(memory, swap int64) int64 {
+	if swap < 0 {
+		return -1
 	}
+	return (memory + swap) * 1024 * 1024
+}
 
-	m.logger.Info("Server created", zap.String("id", cfg.ID), zap.String("container", containerID))
-	return nil
+// processLimits fills the limits a server leaves 0 with the agent's defaults
+func (m *Manager) processLimits(limits ProcessLimits) ProcessLimits {
+	if limits.Pids == 0 {
+		limits.Pids = m.config.Docker.PidsLimit
+	}
+	if limits.NoFile == 0 {
+		limits.NoFile = m.config.Docker.NoFile
+	}
+	if limits.NProc == 0 {
+		limits.NProc = m.config.Docker.NProc
+	}
+	return limits
 }
 
 // StartServer starts a server
 func (m *Manager) StartServer(ctx context.Context, serverID string) error {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("server not found: %s", serverID)
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
 	}
-
-	server.mu.Lock()
 	defer server.mu.Unlock()
 
+	if server.ImageDirty || server.ConfigDirty {
+		if err := m.recreateContainer(ctx, server); err != nil {
+			return err
+		}
+	}
+
+	m.applyConfigFiles(server)
 	if err := m.docker.StartContainer(ctx, server.ContainerID); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
@@ -211,24 +472,178 @@ func (m *Manager) StartServer(ctx context.Context, serverID string) error {
 	now := time.Now()
 	server.Status = "running"
 	server.StartedAt = &now
+	server.OOMKill = nil
+	server.Crash = nil
+	server.Health = ""
+	server.stopRequested = false
 
 	m.logger.Info("Server started", zap.String("id", serverID))
 	return nil
 }
 
-// StopServer stops a server gracefully
-func (m *Manager) StopServer(ctx context.Context, serverID string) error {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
+// UpdateServerImage changes the image of a server. The new image is pulled and
+// the container recreated the next time the server starts or restarts.
+func (m *Manager) UpdateServerImage(serverID, image string) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
 
-	if !exists {
-		return fmt.Errorf("server not found: %s", serverID)
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+	if server.Config.Image == image {
+		return nil
 	}
 
-	server.mu.Lock()
+	server.Config.Image = image
+	server.ImageDirty = true
+
+	m.logger.Info("Server image changed", zap.String("id", serverID), zap.String("image", image))
+	return nil
+}
+
+// StartupSpec is the startup configuration of a server replaced by
+// UpdateServerStartup
+type StartupSpec struct {
+	StartupCmd     string            `json:"startup_cmd"`
+	Environment    map[string]string `json:"environment"`
+	Allocations    []Allocation      `json:"allocations"` // Nil keeps the current allocations
+	Healthcheck    *Healthcheck      `json:"healthcheck"`
+	ProcessLimits  ProcessLimits     `json:"process_limits"`
+	ConfigFiles    json.RawMessage   `json:"config_files"`
+	ReadOnlyRootfs bool              `json:"read_only_rootfs"`
+	Tmpfs          []string          `json:"tmpfs"`
+	Capabilities   []string          `json:"capabilities"`
+	ContainerCommand
+}
+
+// UpdateServerStartup replaces the startup command, environment, allocations,
+// healthcheck, process limits, config file rules, root filesystem mode and
+// capabilities of a server. The container is recreated the next time the
+// server starts.
+func (m *Manager) UpdateServerStartup(serverID string, spec StartupSpec) error {
+	if err := validateTmpfs(spec.Tmpfs); err != nil {
+		return err
+	}
+	if err := m.validateCapabilities(spec.Capabilities); err != nil {
+		return err
+	}
+	if _, err := spec.ContainerCommand.command(spec.StartupCmd); err != nil {
+		return err
+	}
+	if err := validateAllocations(spec.Allocations); err != nil {
+		return err
+	}
+
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
 	defer server.mu.Unlock()
 
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+
+	server.Config.StartupCmd = spec.StartupCmd
+	server.Config.Environment = spec.Environment
+	if spec.Allocations != nil {
+		server.Config.Allocations = spec.Allocations
+	}
+	server.Config.Healthcheck = spec.Healthcheck
+	server.Config.ProcessLimits = spec.ProcessLimits
+	server.Config.ConfigFiles = spec.ConfigFiles
+	server.Config.ReadOnlyRootfs = spec.ReadOnlyRootfs
+	server.Config.Tmpfs = spec.Tmpfs
+	server.Config.Capabilities = spec.Capabilities
+	server.Config.ContainerCommand = spec.ContainerCommand
+	server.ConfigDirty = true
+
+	m.logger.Info("Server startup changed", zap.String("id", serverID))
+	return nil
+}
+
+// recreateContainer replaces a server's container, pulling its image first
+// when the image changed. The caller must hold the server lock.
+func (m *Manager) recreateContainer(ctx context.Context, server *ServerState) error {
+	if server.ImageDirty {
+		if err := m.ensureImage(ctx, server.ID, server.Config.Image, true); err != nil {
+			return err
+		}
+	}
+
+	if err := m.docker.RemoveContainer(ctx, server.ContainerID, true); err != nil {
+		m.logger.Warn("Failed to remove container", zap.Error(err))
+	}
+
+	containerID, err := m.buildContainer(ctx, server.Config, false)
+	if err != nil {
+		return err
+	}
+
+	server.ContainerID = containerID
+	server.ImageDirty = false
+	server.ConfigDirty = false
+	return nil
+}
+
+// ReinstallServer stops a server and rebuilds its container from the current
+// configuration. With wipeData the data directory is emptied first, otherwise
+// existing files are left in place.
+func (m *Manager) ReinstallServer(ctx context.Context, serverID string, wipeData bool) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
+
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+
+	m.logger.Info("Reinstalling server", zap.String("id", serverID), zap.Bool("wipe_data", wipeData))
+	server.Status = "installing"
+	server.expectStop()
+
+	if err := m.docker.RemoveContainer(ctx, server.ContainerID, true); err != nil {
+		m.logger.Warn("Failed to remove container", zap.Error(err))
+	}
+	server.StartedAt = nil
+
+	if wipeData {
+		serverPath := filepath.Join(m.config.Storage.ServerDataPath, server.Config.UUID)
+		if err := clearDirectory(serverPath); err != nil {
+			server.Status = "error"
+			return fmt.Errorf("failed to wipe server data: %w", err)
+		}
+	}
+
+	containerID, err := m.buildContainer(ctx, server.Config, true)
+	if err != nil {
+		server.Status = "error"
+		return err
+	}
+
+	server.ContainerID = containerID
+	server.ImageDirty = false
+	server.ConfigDirty = false
+	server.Status = "stopped"
+
+	m.logger.Info("Server reinstalled", zap.String("id", serverID), zap.String("container", containerID))
+	return nil
+}
+
+// StopServer stops a server gracefully
+func (m *Manager) StopServer(ctx context.Context, serverID string) error {
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
+
+	server.expectStop()
 	if err := m.docker.StopContainer(ctx, server.ContainerID, m.config.Docker.StopTimeout); err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
@@ -242,17 +657,13 @@ func (m *Manager) StopServer(ctx context.Context, serverID string) error {
 
 // KillServer forcefully stops a server
 func (m *Manager) KillServer(ctx context.Context, serverID string) error {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("server not found: %s", serverID)
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
 	}
-
-	server.mu.Lock()
 	defer server.mu.Unlock()
 
+	server.expectStop()
 	if err := m.docker.KillContainer(ctx, server.ContainerID); err != nil {
 		return fmt.Errorf("failed to kill container: %w", err)
 	}
@@ -266,24 +677,39 @@ func (m *Manager) KillServer(ctx context.Context, serverID string) error {
 
 // RestartServer restarts a server
 func (m *Manager) RestartServer(ctx context.Context, serverID string) error {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("server not found: %s", serverID)
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
 	}
-
-	server.mu.Lock()
 	defer server.mu.Unlock()
 
-	if err := m.docker.RestartContainer(ctx, server.ContainerID, m.config.Docker.StopTimeout); err != nil {
-		return fmt.Errorf("failed to restart container: %w", err)
+	server.expectStop()
+
+	// A changed image or configuration needs a new container, not a restart
+	if server.ImageDirty || server.ConfigDirty {
+		if err := m.docker.StopContainer(ctx, server.ContainerID, m.config.Docker.StopTimeout); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
+		if err := m.recreateContainer(ctx, server); err != nil {
+			server.Status = "stopped"
+			server.StartedAt = nil
+			return err
+		}
+		m.applyConfigFiles(server)
+		if err := m.docker.StartContainer(ctx, server.ContainerID); err != nil {
+			return fmt.Errorf("failed to start container: %w", err)
+		}
+	} else {
+		m.applyConfigFiles(server)
+		if err := m.docker.RestartContainer(ctx, server.ContainerID, m.config.Docker.StopTimeout); err != nil {
+			return fmt.Errorf("failed to restart container: %w", err)
+		}
 	}
 
 	now := time.Now()
 	server.Status = "running"
 	server.StartedAt = &now
+	server.Crash = nil
 
 	m.logger.Info("Server restarted", zap.String("id", serverID))
 	return nil
@@ -291,40 +717,81 @@ func (m *Manager) RestartServer(ctx context.Context, serverID string) error {
 
 // SendCommand sends a command to server console
 func (m *Manager) SendCommand(ctx context.Context, serverID, command string) error {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return err
+	}
 
-	if !exists {
-		return fmt.Errorf("server not found: %s", serverID)
+	containerID, err := server.createdContainer()
+	if err != nil {
+		return err
 	}
 
-	// Execute command in container
-	_, err := m.docker.ExecCommand(ctx, server.ContainerID, []string{"/bin/bash", "-c", command})
+	// Execute command in container, in the shell its startup uses
+	shell := defaultShell
+	if server.Config != nil {
+		shell = server.Config.shell()
+	}
+	_, err = m.docker.ExecCommand(ctx, containerID, []string{shell, "-c", command})
 	return err
 }
 
-// GetServerStatus returns server status
+// GetServerStatus returns server status, "creating" while its container is
+// still being created
 func (m *Manager) GetServerStatus(ctx context.Context, serverID string) (string, error) {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return "", err
+	}
+	containerID, err := server.createdContainer()
+	if errors.Is(err, ErrServerCreating) {
+		return statusCreating, nil
+	}
+
+	return m.docker.GetContainerStatus(ctx, containerID)
+}
 
-	if !exists {
-		return "", fmt.Errorf("server not found: %s", serverID)
+// ServerStatuses returns the live container status of every managed server,
+// keyed by server ID. Servers being created or installed, or left stopped after an error
+// or repeated crashes, report that state rather than their container's.
+func (m *Manager) ServerStatuses(ctx context.Context) map[string]string {
+	statuses := make(map[string]string)
+	for _, server := range m.servers.Snapshot() {
+		server.mu.RLock()
+		status := server.Status
+		server.mu.RUnlock()
+
+		switch status {
+		case statusCreating, "installing", "crashed", "error":
+		default:
+			if live, err := m.docker.GetContainerStatus(ctx, server.container()); err == nil {
+				status = live
+			}
+		}
+		statuses[server.ID] = status
 	}
+	return statuses
+}
 
-	return m.docker.GetContainerStatus(ctx, server.ContainerID)
+// GetServerLogs opens the log stream of a server's container
+func (m *Manager) GetServerLogs(ctx context.Context, serverID string, opts docker.LogOptions) (io.ReadCloser, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+	containerID, err := server.createdContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.docker.GetContainerLogs(ctx, containerID, opts)
 }
 
 // GetServerStats returns server resource statistics
 func (m *Manager) GetServerStats(ctx context.Context, serverID string) (*ServerStats, error) {
-	m.mu.RLock()
-	server, exists := m.servers[serverID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("server not found: %s", serverID)
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
 	}
 
 	server.mu.RLock()
@@ -348,17 +815,21 @@ func (m *Manager) StartHealthCheck(ctx context.Context) {
 	}
 }
 
-// checkAllServers checks health of all servers
+// checkAllServers reconciles server status with Docker, catching anything
+// the event stream missed
 func (m *Manager) checkAllServers(ctx context.Context) {
-	m.mu.RLock()
-	servers := make([]*ServerState, 0, len(m.servers))
-	for _, s := range m.servers {
-		servers = append(servers, s)
+	// States are reconciled by the Docker monitor once the daemon is back
+	if !m.docker.Available() {
+		return
 	}
-	m.mu.RUnlock()
 
-	for _, server := range servers {
-		status, err := m.docker.GetContainerStatus(ctx, server.ContainerID)
+	for _, server := range m.servers.Snapshot() {
+		// A container still being created has no ID to ask Docker about
+		containerID, err := server.createdContainer()
+		if err != nil {
+			continue
+		}
+		status, health, err := m.docker.GetContainerState(ctx, containerID)
 		if err != nil {
 			m.logger.Warn("Failed to get container status",
 				zap.String("id", server.ID),
@@ -366,9 +837,21 @@ func (m *Manager) checkAllServers(ctx context.Context) {
 			continue
 		}
 
-		server.mu.Lock()
-		server.Status = status
-		server.mu.Unlock()
+		server.mu.RLock()
+		wasRunning := server.Status == "running"
+		oomKilled := server.OOMKill != nil
+		server.mu.RUnlock()
+
+		if oomKilled && status != "running" {
+			status = "error"
+		}
+		m.setStatus(server, status)
+		m.setHealth(server, health)
+
+		// Catch OOM kills whose Docker event was missed
+		if wasRunning && status != "running" {
+			m.checkOOM(ctx, server)
+		}
 	}
 }
 
@@ -390,27 +873,48 @@ func (m *Manager) StartMetricsCollection(ctx context.Context) {
 
 // collectAllMetrics collects metrics for all servers
 func (m *Manager) collectAllMetrics(ctx context.Context) {
-	m.mu.RLock()
-	servers := make([]*ServerState, 0, len(m.servers))
-	for _, s := range m.servers {
-		servers = append(servers, s)
-	}
-	m.mu.RUnlock()
+	for _, server := range m.servers.Snapshot() {
+		server.mu.RLock()
+		status := server.Status
+		containerID := server.ContainerID
+		server.mu.RUnlock()
+
+		diskUsage := dirSize(filepath.Join(m.config.Storage.ServerDataPath, server.UUID))
+
+		// Stopped containers report no usage, only keep disk figures for them
+		if status != "running" {
+			server.mu.Lock()
+			server.Stats = &ServerStats{
+				Status:      status,
+				DiskUsage:   diskUsage,
+				DiskLimit:   uint64(server.DiskLimit) * 1024 * 1024,
+				OOMKill:     server.OOMKill,
+				Crash:       server.Crash,
+				CollectedAt: time.Now(),
+			}
+			server.mu.Unlock()
+			continue
+		}
 
-	for _, server := range servers {
-		stats, err := m.docker.GetContainerStats(ctx, server.ContainerID)
+		stats, err := m.docker.GetContainerStats(ctx, containerID)
 		if err != nil {
 			continue
 		}
 
 		server.mu.Lock()
+		server.LastMemory = stats.MemoryUsage
 		server.Stats = &ServerStats{
-			CPUPercent:  stats.CPUPercent,
-			MemoryUsage: stats.MemoryUsage,
-			MemoryLimit: stats.MemoryLimit,
-			NetworkRx:   stats.NetworkRx,
-			NetworkTx:   stats.NetworkTx,
-			CollectedAt: time.Now(),
+			Status:        status,
+			CPUPercent:    stats.CPUPercent,
+			MemoryUsage:   stats.MemoryUsage,
+			MemoryLimit:   stats.MemoryLimit,
+			MemoryPercent: stats.MemoryPercent,
+			DiskUsage:     diskUsage,
+			DiskLimit:     uint64(server.DiskLimit) * 1024 * 1024,
+			NetworkRx:     stats.NetworkRx,
+			NetworkTx:     stats.NetworkTx,
+			Health:        server.Health,
+			CollectedAt:   time.Now(),
 		}
 		if server.StartedAt != nil {
 			server.Stats.Uptime = int64(time.Since(*server.StartedAt).Seconds())
@@ -419,6 +923,40 @@ func (m *Manager) collectAllMetrics(ctx context.Context) {
 	}
 }
 
+// dirSize returns the total size in bytes of all files under a directory
+func dirSize(path string) uint64 {
+	var size uint64
+	_ = filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// clearDirectory removes everything inside a directory but keeps the directory
+func clearDirectory(path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // StartConsoleStreaming starts console streaming for all servers
 func (m *Manager) StartConsoleStreaming(ctx context.Context) {
 	// Console streaming would be implemented here
@@ -426,13 +964,17 @@ func (m *Manager) StartConsoleStreaming(ctx context.Context) {
 	<-ctx.Done()
 }
 
-// DeleteServer removes a server
+// DeleteServer removes a server. Only the server's own lock is held while its
+// container is removed, other servers are not blocked.
 func (m *Manager) DeleteServer(ctx context.Context, serverID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
 
-	server, exists := m.servers[serverID]
-	if !exists {
+	// A concurrent delete may have finished while this one waited
+	if current, _ := m.servers.Get(serverID); current != server {
 		return fmt.Errorf("server not found: %s", serverID)
 	}
 
@@ -441,8 +983,13 @@ func (m *Manager) DeleteServer(ctx context.Context, serverID string) error {
 		m.logger.Warn("Failed to remove container", zap.Error(err))
 	}
 
+	// Remove the server's own network, if it had one
+	if err := m.docker.RemoveNetwork(ctx, isolatedNetworkName(server.UUID)); err != nil {
+		m.logger.Warn("Failed to remove server network", zap.Error(err))
+	}
+
 	// Remove from map
-	delete(m.servers, serverID)
+	m.servers.DeleteIf(server)
 
 	m.logger.Info("Server deleted", zap.String("id", serverID))
 	return nil
@@ -451,6 +998,8 @@ func (m *Manager) DeleteServer(ctx context.Context, serverID string) error {
 // LoadServers loads existing servers from panel configuration
 func (m *Manager) LoadServers(ctx context.Context, configs []ServerConfig) error {
 	for _, cfg := range configs {
+		cfg := cfg
+
 		// Check if container already exists
 		containers, err := m.docker.ListContainersByLabel(ctx, map[string]string{
 			"aether.server.id": cfg.ID,
@@ -463,12 +1012,14 @@ func (m *Manager) LoadServers(ctx context.Context, configs []ServerConfig) error
 		if len(containers) > 0 {
 			// Container exists, just track it
 			container := containers[0]
-			m.servers[cfg.ID] = &ServerState{
+			m.servers.Set(&ServerState{
 				ID:          cfg.ID,
 				UUID:        cfg.UUID,
 				ContainerID: container.ID,
 				Status:      container.State,
-			}
+				DiskLimit:   cfg.DiskLimit,
+				Config:      &cfg,
+			})
 		}
 	}
 
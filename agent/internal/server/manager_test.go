@@ -0,0 +1,532 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// testContainerPID is the process of every running container of the fake daemon
+const testContainerPID = 4242
+
+// fakeDocker answers the Docker API calls the manager makes for creating and
+// powering servers. Image pulls block until pull is closed. Only the images
+// in images are present. Containers report state, or "running" when it is
+// empty, running as testContainerPID, with health when it is set, and
+// having been OOM killed when oomKilled is set. The event stream
+// sends the messages written to events and drops when an empty one is. Container lists return listed, or no containers when it is empty.
+// The body of the last container create is kept in created, the networks
+// created so far in networks. While down is set the daemon fails every call.
+type fakeDocker struct {
+	pull      chan struct{}
+	images    map[string]bool
+	state     string
+	oomKilled bool
+	health    string
+	listed    string
+	events    chan string
+	down      atomic.Bool
+
+	mu       sync.Mutex
+	requests []string
+	created  []byte
+	networks map[string]bool
+}
+
+// containerCreate is the body of a container create request
+type containerCreate struct {
+	container.Config
+	HostConfig       container.HostConfig
+	NetworkingConfig network.NetworkingConfig
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/v1.") {
+		if i := strings.Index(path[1:], "/"); i >= 0 {
+			path = path[i+1:]
+		}
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+path)
+	f.mu.Unlock()
+
+	w.Header().Set("Api-Version", "1.43")
+	w.Header().Set("Content-Type", "application/json")
+	if f.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"daemon restarting"}`))
+		return
+	}
+	switch {
+	case path == "/_ping":
+		_, _ = w.Write([]byte("OK"))
+	case path == "/events":
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case msg := <-f.events:
+				if msg == "" {
+					return
+				}
+				_, _ = w.Write([]byte(msg + "\n"))
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case path == "/networks/create":
+		var req struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		if f.networks == nil {
+			f.networks = make(map[string]bool)
+		}
+		f.networks[req.Name] = true
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"Id":"network-%s"}`, req.Name)
+	case strings.HasPrefix(path, "/networks/"):
+		name := strings.TrimPrefix(path, "/networks/")
+		f.mu.Lock()
+		exists := f.networks[name]
+		f.mu.Unlock()
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"network not found"}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"Name":%q,"Id":"network-%s"}`, name, name)
+	case path == "/containers/json":
+		listed := f.listed
+		if listed == "" {
+			listed = "[]"
+		}
+		_, _ = w.Write([]byte(listed))
+	case path == "/images/create":
+		select {
+		case <-f.pull:
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"Downloaded newer image"}` + "\n"))
+	case path == "/images/json":
+		var tags []string
+		for image, present := range f.images {
+			if present {
+				tags = append(tags, image)
+			}
+		}
+		sort.Strings(tags)
+		listed, _ := json.Marshal([]map[string][]string{{"RepoTags": tags}})
+		_, _ = w.Write(listed)
+	case strings.HasPrefix(path, "/images/") && f.images[strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")]:
+		_, _ = w.Write([]byte(`{"Id":"sha256:image"}`))
+	case strings.HasPrefix(path, "/images/"):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"No such image"}`))
+	case path == "/containers/create":
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.created = body
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"container-new","Warnings":[]}`))
+	case strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		state := f.state
+		if state == "" {
+			state = "running"
+		}
+		pid := 0
+		if state == "running" {
+			pid = testContainerPID
+		}
+		health := ""
+		if f.health != "" {
+			health = fmt.Sprintf(`,"Health":{"Status":%q}`, f.health)
+		}
+		_, _ = fmt.Fprintf(w, `{"Id":"container","State":{"Status":%q,"Running":%t,"Pid":%d,"OOMKilled":%t,"FinishedAt":"2026-10-16T12:00:00Z"%s},"HostConfig":{"Memory":2147483648}}`,
+			state, state == "running", pid, f.oomKilled, health)
+	case strings.HasPrefix(path, "/containers/"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"not implemented"}`))
+	}
+}
+
+// requested reports whether a request whose method and path contain s was made
+func (f *fakeDocker) requested(s string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if strings.Contains(r, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastCreated returns the last container created
+func (f *fakeDocker) lastCreated(t *testing.T) containerCreate {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.created == nil {
+		t.Fatal("no container was created")
+	}
+	var created containerCreate
+	if err := json.Unmarshal(f.created, &created); err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+// newDockerTestManager returns a test manager talking to a fake Docker daemon
+func newDockerTestManager(t *testing.T) (*Manager, *fakeDocker) {
+	t.Helper()
+
+	fake := &fakeDocker{pull: make(chan struct{}), events: make(chan string)}
+	daemon := httptest.NewServer(fake)
+	t.Cleanup(daemon.Close)
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+	t.Setenv("DOCKER_API_VERSION", "")
+
+	client, err := docker.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _, _ := newTestManager(t)
+	m.docker = client
+	m.config.Docker.NetworkMode = NetworkModeBridge
+	m.config.Docker.StopTimeout = 1
+	return m, fake
+}
+
+// addTestServer tracks a stopped server with an existing container
+func addTestServer(m *Manager, id string) *ServerState {
+	server := &ServerState{
+		ID:          id,
+		UUID:        "uuid-" + id,
+		ContainerID: "container-" + id,
+		Status:      "stopped",
+		Config:      &ServerConfig{ID: id, UUID: "uuid-" + id},
+	}
+	m.servers.Set(server)
+	return server
+}
+
+func TestCreateServerDoesNotBlockOtherServers(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	addTestServer(m, "other")
+
+	created := make(chan error, 1)
+	go func() {
+		created <- m.CreateServer(context.Background(), &ServerConfig{
+			ID:         "new",
+			UUID:       "uuid-new",
+			Image:      "ghcr.io/example/game:latest",
+			StartupCmd: "./start.sh",
+		})
+	}()
+
+	// Wait for the create to block on the image pull
+	deadline := time.Now().Add(5 * time.Second)
+	for !fake.requested("POST /images/create") {
+		if time.Now().After(deadline) {
+			t.Fatal("create never pulled the image")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.StartServer(ctx, "other"); err != nil {
+		t.Fatalf("StartServer on another server: %v", err)
+	}
+	if err := m.StopServer(ctx, "other"); err != nil {
+		t.Fatalf("StopServer on another server: %v", err)
+	}
+
+	// The server being created has no container to act on yet
+	if err := m.StartServer(ctx, "new"); !errors.Is(err, ErrServerCreating) {
+		t.Errorf("StartServer while creating = %v, want %v", err, ErrServerCreating)
+	}
+	if err := m.SendCommand(ctx, "new", "say hi"); !errors.Is(err, ErrServerCreating) {
+		t.Errorf("SendCommand while creating = %v, want %v", err, ErrServerCreating)
+	}
+	if status, err := m.GetServerStatus(ctx, "new"); err != nil || status != statusCreating {
+		t.Errorf("GetServerStatus while creating = %q, %v", status, err)
+	}
+	m.checkAllServers(ctx)
+	if fake.requested("/containers//") {
+		t.Error("Docker was asked about a container without an ID")
+	}
+
+	close(fake.pull)
+	select {
+	case err := <-created:
+		if err != nil {
+			t.Fatalf("CreateServer: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CreateServer did not finish after the pull")
+	}
+
+	server, err := m.getServer("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := server.container(); id != "container-new" {
+		t.Errorf("container = %q, want %q", id, "container-new")
+	}
+}
+
+func TestCreateServerAdoptsExistingContainer(t *testing.T) {
+	for state, want := range map[string]string{"exited": "stopped", "created": "created", "running": "running", "dead": "stopped"} {
+		t.Run(state, func(t *testing.T) {
+			m, fake := newDockerTestManager(t)
+			fake.listed = fmt.Sprintf(`[{"Id":"container-earlier","State":%q,"Labels":{"aether.server.uuid":"uuid-new"}}]`, state)
+
+			err := m.CreateServer(context.Background(), &ServerConfig{
+				ID:         "new",
+				UUID:       "uuid-new",
+				Image:      "ghcr.io/example/game:latest",
+				StartupCmd: "./start.sh",
+			})
+			if err != nil {
+				t.Fatalf("CreateServer: %v", err)
+			}
+			if fake.requested("/containers/create") || fake.requested("/images/create") {
+				t.Error("a new container was built instead of adopting the existing one")
+			}
+
+			server, err := m.getServer("new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server.ContainerID != "container-earlier" {
+				t.Errorf("container = %q, want the existing container-earlier", server.ContainerID)
+			}
+			if server.Status != want {
+				t.Errorf("status = %q, want %q", server.Status, want)
+			}
+			if !server.ConfigDirty {
+				t.Error("adopted container is not marked for recreation")
+			}
+		})
+	}
+}
+
+// TestParallelPowerActions runs start and stop on many servers at once while
+// the server map is read and changed, and is meant to be run with -race
+func TestParallelPowerActions(t *testing.T) {
+	m, _ := newDockerTestManager(t)
+
+	const servers = 16
+	for i := 0; i < servers; i++ {
+		addTestServer(m, fmt.Sprintf("server-%d", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < servers; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				if err := m.StartServer(ctx, id); err != nil {
+					t.Errorf("StartServer(%s): %v", id, err)
+					return
+				}
+				if err := m.StopServer(ctx, id); err != nil {
+					t.Errorf("StopServer(%s): %v", id, err)
+					return
+				}
+			}
+		}(fmt.Sprintf("server-%d", i))
+	}
+
+	// Readers and servers coming and going alongside the power actions
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			_ = m.ServerStatuses(ctx)
+			m.collectAllMetrics(ctx)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 50; n++ {
+			server := addTestServer(m, fmt.Sprintf("extra-%d", n%4))
+			m.servers.DeleteIf(server)
+		}
+	}()
+	wg.Wait()
+
+	for i := 0; i < servers; i++ {
+		server, err := m.getServer(fmt.Sprintf("server-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.mu.RLock()
+		status := server.Status
+		server.mu.RUnlock()
+		if status != "stopped" {
+			t.Errorf("%s is %q after its last stop", server.ID, status)
+		}
+	}
+}
+
+func TestUpdateServerStartup(t *testing.T) {
+	m, server, _ := newTestManager(t)
+	m.config.Docker.AllowedCaps = []string{"NET_ADMIN"}
+	server.Config.Allocations = []Allocation{{IP: "0.0.0.0", Port: 25565, IsPrimary: true}}
+
+	spec := StartupSpec{
+		StartupCmd:   "java -jar server.jar",
+		Environment:  map[string]string{"MEMORY": "2048"},
+		Tmpfs:        []string{"/tmp"},
+		Capabilities: []string{"NET_ADMIN"},
+	}
+	if err := m.UpdateServerStartup("server-1", spec); err != nil {
+		t.Fatalf("UpdateServerStartup: %v", err)
+	}
+	if server.Config.StartupCmd != spec.StartupCmd || server.Config.Environment["MEMORY"] != "2048" || !server.ConfigDirty {
+		t.Errorf("config = %+v, dirty = %v", server.Config, server.ConfigDirty)
+	}
+	// Nil allocations keep the current ones
+	if len(server.Config.Allocations) != 1 {
+		t.Errorf("allocations = %v, want the current ones kept", server.Config.Allocations)
+	}
+
+	server.ConfigDirty = false
+	spec.Capabilities = []string{"SYS_ADMIN"}
+	if err := m.UpdateServerStartup("server-1", spec); !errors.Is(err, ErrCapabilityNotAllowed) {
+		t.Errorf("UpdateServerStartup with SYS_ADMIN = %v, want %v", err, ErrCapabilityNotAllowed)
+	}
+	if server.ConfigDirty || server.Config.Capabilities[0] != "NET_ADMIN" {
+		t.Error("a refused update changed the config")
+	}
+}
+
+func TestCreateServerPinsCPUs(t *testing.T) {
+	for set, want := range map[string]string{"": "", "0-1,3": "0-1,3"} {
+		m, fake := newDockerTestManager(t)
+		close(fake.pull)
+
+		err := m.CreateServer(context.Background(), &ServerConfig{
+			ID:         "new",
+			UUID:       "uuid-new",
+			Image:      "ghcr.io/example/game:latest",
+			StartupCmd: "./start.sh",
+			CPULimit:   200,
+			CPUSet:     set,
+		})
+		if err != nil {
+			t.Fatalf("CreateServer: %v", err)
+		}
+
+		resources := fake.lastCreated(t).HostConfig.Resources
+		if resources.CpusetCpus != want {
+			t.Errorf("cpu set %q: CpusetCpus = %q, want %q", set, resources.CpusetCpus, want)
+		}
+		// Pinning does not replace the quota
+		if resources.CPUQuota != 200000 || resources.CPUPeriod != 100000 {
+			t.Errorf("cpu set %q: quota %d per %d, want 200000 per 100000", set, resources.CPUQuota, resources.CPUPeriod)
+		}
+	}
+}
+
+func TestCreateServerLimitsSwap(t *testing.T) {
+	for swap, want := range map[int64]int64{0: 2048 << 20, 512: 2560 << 20, -1: -1} {
+		m, fake := newDockerTestManager(t)
+		close(fake.pull)
+
+		err := m.CreateServer(context.Background(), &ServerConfig{
+			ID:          "new",
+			UUID:        "uuid-new",
+			Image:       "ghcr.io/example/game:latest",
+			StartupCmd:  "./start.sh",
+			MemoryLimit: 2048,
+			SwapLimit:   swap,
+		})
+		if err != nil {
+			t.Fatalf("CreateServer: %v", err)
+		}
+
+		resources := fake.lastCreated(t).HostConfig.Resources
+		if resources.Memory != 2048<<20 || resources.MemorySwap != want {
+			t.Errorf("swap %d: memory %d swap %d, want %d and %d", swap, resources.Memory, resources.MemorySwap, 2048<<20, want)
+		}
+	}
+}
+
+func TestCreateServerLimitsProcesses(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		limits ProcessLimits
+		pids   int64
+		ulimit map[string]int64
+	}{
+		// nproc is left to Docker by the agent's defaults
+		{"defaults", ProcessLimits{}, 512, map[string]int64{"nofile": 65536}},
+		{"per server", ProcessLimits{Pids: 100, NoFile: 1024, NProc: 256}, 100, map[string]int64{"nofile": 1024, "nproc": 256}},
+	} {
+		m, fake := newDockerTestManager(t)
+		m.config.Docker.PidsLimit = 512
+		m.config.Docker.NoFile = 65536
+		close(fake.pull)
+
+		err := m.CreateServer(context.Background(), &ServerConfig{
+			ID:            "new",
+			UUID:          "uuid-new",
+			Image:         "ghcr.io/example/game:latest",
+			StartupCmd:    "./start.sh",
+			ProcessLimits: tc.limits,
+		})
+		if err != nil {
+			t.Fatalf("CreateServer: %v", err)
+		}
+
+		resources := fake.lastCreated(t).HostConfig.Resources
+		if resources.PidsLimit == nil || *resources.PidsLimit != tc.pids {
+			t.Errorf("%s: pids limit %v, want %d", tc.name, resources.PidsLimit, tc.pids)
+		}
+		ulimits := map[string]int64{}
+		for _, u := range resources.Ulimits {
+			if u.Soft != u.Hard {
+				t.Errorf("%s: %s soft %d hard %d, want them equal", tc.name, u.Name, u.Soft, u.Hard)
+			}
+			ulimits[u.Name] = u.Hard
+		}
+		if len(ulimits) != len(tc.ulimit) {
+			t.Errorf("%s: ulimits %v, want %v", tc.name, ulimits, tc.ulimit)
+		}
+		for name, want := range tc.ulimit {
+			if ulimits[name] != want {
+				t.Errorf("%s: %s ulimit %d, want %d", tc.name, name, ulimits[name], want)
+			}
+		}
+	}
+}
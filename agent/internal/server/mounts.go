@@ -0,0 +1,132 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrMountNotAllowed is returned for a mount whose source the node does not
+// allow or whose target would shadow the server's data
+var ErrMountNotAllowed = errors.New("mount not allowed")
+
+// dataTarget is where a server's data directory is mounted in its container
+const dataTarget = "/home/container"
+
+// UpdateMounts replaces the extra mounts of a server. Mounts are fixed when
+// the container is created, so the change applies on the next start.
+func (m *Manager) UpdateMounts(serverID string, mounts []Mount) error {
+	if err := m.validateMounts(mounts); err != nil {
+		return err
+	}
+
+	server, err := m.lockServer(serverID)
+	if err != nil {
+		return err
+	}
+	defer server.mu.Unlock()
+
+	if server.Config == nil {
+		return fmt.Errorf("server configuration not loaded: %s", serverID)
+	}
+	server.Config.Mounts = mounts
+	server.ConfigDirty = true
+
+	m.logger.Info("Server mounts changed", zap.String("id", serverID), zap.Int("mounts", len(mounts)))
+	return nil
+}
+
+// validateMounts checks every mount against the node's allowlist. Sources are
+// resolved through symlinks first, so a link inside an allowed path cannot
+// point a server at the rest of the host. An allowlist entry of the root
+// itself is ignored, it would hand every server the whole host.
+func (m *Manager) validateMounts(mounts []Mount) error {
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	allowed := make([]string, 0, len(m.config.AllowedMounts))
+	for _, path := range m.config.AllowedMounts {
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		if path = resolvePath(path); path == "/" {
+			continue
+		}
+		allowed = append(allowed, path)
+	}
+
+	for _, mount := range mounts {
+		target := filepath.Clean(mount.Target)
+		if !filepath.IsAbs(mount.Target) || target == "/" || withinPath(target, dataTarget) || withinPath(dataTarget, target) {
+			return fmt.Errorf("%w: invalid target %q", ErrMountNotAllowed, mount.Target)
+		}
+
+		if !filepath.IsAbs(mount.Source) {
+			return fmt.Errorf("%w: source %q is not an absolute path", ErrMountNotAllowed, mount.Source)
+		}
+		source := resolvePath(mount.Source)
+		permitted := false
+		for _, path := range allowed {
+			if withinPath(source, path) {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return fmt.Errorf("%w: %q is outside the node's allowed mounts", ErrMountNotAllowed, mount.Source)
+		}
+		if withinPath(source, m.config.Storage.ServerDataPath) || withinPath(m.config.Storage.ServerDataPath, source) {
+			return fmt.Errorf("%w: %q overlaps server data", ErrMountNotAllowed, mount.Source)
+		}
+	}
+	return nil
+}
+
+// validateTmpfs checks the tmpfs paths of a read-only server, which may
+// neither cover the whole root nor shadow the server's data
+func validateTmpfs(paths []string) error {
+	for _, path := range paths {
+		target := filepath.Clean(path)
+		if !filepath.IsAbs(path) || target == "/" || withinPath(target, dataTarget) || withinPath(dataTarget, target) {
+			return fmt.Errorf("%w: invalid tmpfs path %q", ErrMountNotAllowed, path)
+		}
+	}
+	return nil
+}
+
+// tmpfsMounts returns the tmpfs mounts of a read-only server, each capped at
+// the node's tmpfs size
+func (m *Manager) tmpfsMounts(paths []string) map[string]string {
+	if len(paths) == 0 {
+		return nil
+	}
+	options := "rw,nosuid,nodev"
+	if m.config.Docker.TmpfsSize != "" {
+		options += ",size=" + m.config.Docker.TmpfsSize
+	}
+	tmpfs := make(map[string]string, len(paths))
+	for _, path := range paths {
+		tmpfs[filepath.Clean(path)] = options
+	}
+	return tmpfs
+}
+
+// resolvePath cleans a path and follows its symlinks when it exists
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// withinPath reports whether path is dir or below it
+func withinPath(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateMounts(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	shared := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(shared, "maps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(shared, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	m.config.AllowedMounts = []string{shared, "relative/path"}
+
+	allowed := map[string]Mount{
+		"allowed path":  {Source: shared, Target: "/mnt/shared"},
+		"subdirectory":  {Source: filepath.Join(shared, "maps"), Target: "/mnt/maps", ReadOnly: true},
+		"cleaned above": {Source: filepath.Join(shared, "maps", ".."), Target: "/mnt/shared/"},
+	}
+	for name, mount := range allowed {
+		if err := m.validateMounts([]Mount{mount}); err != nil {
+			t.Errorf("%s: %v, want allowed", name, err)
+		}
+	}
+
+	disallowed := map[string]Mount{
+		"outside the allowlist": {Source: outside, Target: "/mnt/outside"},
+		"symlink out":           {Source: filepath.Join(shared, "escape"), Target: "/mnt/escape"},
+		"parent traversal":      {Source: filepath.Join(shared, "..", filepath.Base(outside)), Target: "/mnt/outside"},
+		"relative source":       {Source: "relative/path", Target: "/mnt/relative"},
+		"server data":           {Source: m.config.Storage.ServerDataPath, Target: "/mnt/data"},
+		"data target":           {Source: shared, Target: "/home/container"},
+		"below data target":     {Source: shared, Target: "/home/container/plugins"},
+		"root target":           {Source: shared, Target: "/"},
+		"relative target":       {Source: shared, Target: "mnt"},
+	}
+	for name, mount := range disallowed {
+		if err := m.validateMounts([]Mount{mount}); !errors.Is(err, ErrMountNotAllowed) {
+			t.Errorf("%s: %v, want %v", name, err, ErrMountNotAllowed)
+		}
+	}
+
+	// Allowing the root would allow every host path, so it is ignored
+	m.config.AllowedMounts = []string{"/", "/./"}
+	for _, source := range []string{"/etc", "/", outside} {
+		if err := m.validateMounts([]Mount{{Source: source, Target: "/mnt/host"}}); !errors.Is(err, ErrMountNotAllowed) {
+			t.Errorf("%s with / allowed = %v, want %v", source, err, ErrMountNotAllowed)
+		}
+	}
+}
+
+func TestCreateServerPassesMounts(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	shared := t.TempDir()
+	m.config.AllowedMounts = []string{shared}
+	m.config.Docker.TmpfsSize = "64m"
+
+	err := m.CreateServer(context.Background(), &ServerConfig{
+		ID:             "new",
+		UUID:           "uuid-new",
+		Image:          "ghcr.io/example/game:latest",
+		StartupCmd:     "./start.sh",
+		Mounts:         []Mount{{Source: shared, Target: "/mnt/maps", ReadOnly: true}},
+		ReadOnlyRootfs: true,
+		Tmpfs:          []string{"/tmp", "/var/run/"},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+
+	host := fake.lastCreated(t).HostConfig
+	if !host.ReadonlyRootfs {
+		t.Error("root filesystem is writable, want it read-only")
+	}
+	want := map[string]string{"/tmp": "rw,nosuid,nodev,size=64m", "/var/run": "rw,nosuid,nodev,size=64m"}
+	if !maps.Equal(host.Tmpfs, want) {
+		t.Errorf("tmpfs %v, want %v", host.Tmpfs, want)
+	}
+	found := false
+	for _, mount := range host.Mounts {
+		if mount.Target == "/mnt/maps" {
+			found = true
+			if mount.Source != shared || !mount.ReadOnly {
+				t.Errorf("mount %+v, want %s mounted read-only", mount, shared)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("mounts %+v, want /mnt/maps", host.Mounts)
+	}
+
+	// Tmpfs paths are only mounted for read-only servers, and never over data
+	if err := m.UpdateServerStartup("new", StartupSpec{StartupCmd: "./start.sh", Tmpfs: []string{"/tmp"}}); err != nil {
+		t.Fatal(err)
+	}
+	server, err := m.lockServer("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.recreateContainer(context.Background(), server)
+	server.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := fake.lastCreated(t).HostConfig; host.ReadonlyRootfs || host.Tmpfs != nil {
+		t.Errorf("writable server read-only %v with tmpfs %v, want neither", host.ReadonlyRootfs, host.Tmpfs)
+	}
+	for _, path := range []string{"/", "tmp", "/home/container/cache", "/home"} {
+		if err := m.UpdateServerStartup("new", StartupSpec{StartupCmd: "./start.sh", ReadOnlyRootfs: true, Tmpfs: []string{path}}); !errors.Is(err, ErrMountNotAllowed) {
+			t.Errorf("tmpfs %q = %v, want %v", path, err, ErrMountNotAllowed)
+		}
+	}
+}
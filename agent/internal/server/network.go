@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+)
+
+// Server network modes
+const (
+	NetworkModeNode     = "node"     // Shared bridge network of this node
+	NetworkModeIsolated = "isolated" // Bridge network of the server's own
+	NetworkModeBridge   = "bridge"   // Docker's default bridge
+)
+
+// serverNetwork returns the Docker network a server's container joins,
+// creating the node or server network when it does not exist yet. Published
+// ports are bound on the host the same way for every mode.
+func (m *Manager) serverNetwork(ctx context.Context, cfg *ServerConfig) (string, error) {
+	mode := cfg.NetworkMode
+	if mode == "" {
+		mode = m.config.Docker.NetworkMode
+	}
+
+	switch mode {
+	case NetworkModeNode:
+		name := m.config.Docker.Network
+		if err := m.docker.EnsureNetwork(ctx, name, m.labels(nil)); err != nil {
+			return "", err
+		}
+		return name, nil
+	case NetworkModeIsolated:
+		// Containers on different bridge networks cannot reach each other
+		name := isolatedNetworkName(cfg.UUID)
+		labels := m.labels(map[string]string{
+			"aether.server.id":   cfg.ID,
+			"aether.server.uuid": cfg.UUID,
+		})
+		if err := m.docker.EnsureNetwork(ctx, name, labels); err != nil {
+			return "", err
+		}
+		return name, nil
+	case NetworkModeBridge:
+		return NetworkModeBridge, nil
+	default:
+		return "", fmt.Errorf("unknown network mode: %q", mode)
+	}
+}
+
+// isolatedNetworkName returns the name of a server's own network
+func isolatedNetworkName(serverUUID string) string {
+	return "aether_net_" + serverUUID
+}
+
+// labels returns the configured labels merged with the given ones and the
+// label marking resources as managed by Aether, which always win
+func (m *Manager) labels(extra map[string]string) map[string]string {
+	labels := make(map[string]string, len(m.config.Docker.Labels)+len(extra)+1)
+	for k, v := range m.config.Docker.Labels {
+		labels[k] = v
+	}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	labels["aether.managed"] = "true"
+	return labels
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+// createOnNetwork creates a server in network mode and returns its container
+func createOnNetwork(t *testing.T, m *Manager, fake *fakeDocker, id, mode string) containerCreate {
+	t.Helper()
+	err := m.CreateServer(context.Background(), &ServerConfig{
+		ID:          id,
+		UUID:        "uuid-" + id,
+		Image:       "ghcr.io/example/game:latest",
+		StartupCmd:  "./start.sh",
+		NetworkMode: mode,
+		Allocations: []Allocation{{IP: "203.0.113.10", Port: 25565, IsPrimary: true, Protocol: "tcp"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer(%s): %v", id, err)
+	}
+	return fake.lastCreated(t)
+}
+
+// endpoints returns the networks a container joins
+func endpoints(created containerCreate) []string {
+	var names []string
+	for name := range created.NetworkingConfig.EndpointsConfig {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestServersJoinTheNodeNetwork(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	m.config.Docker.Network = "aether_network"
+
+	for _, id := range []string{"alpha", "beta"} {
+		created := createOnNetwork(t, m, fake, id, NetworkModeNode)
+		if created.HostConfig.NetworkMode != "aether_network" {
+			t.Errorf("%s network mode %q, want the node network", id, created.HostConfig.NetworkMode)
+		}
+		if names := endpoints(created); len(names) != 1 || names[0] != "aether_network" {
+			t.Errorf("%s joins %v, want only the node network", id, names)
+		}
+		// Ports are published on the host whatever the network
+		if bindings := created.HostConfig.PortBindings["25565/tcp"]; len(bindings) != 1 || bindings[0].HostPort != "25565" {
+			t.Errorf("%s port bindings %v, want 25565 published", id, created.HostConfig.PortBindings)
+		}
+	}
+	if len(fake.networks) != 1 {
+		t.Errorf("created networks %v, want the node network once", fake.networks)
+	}
+}
+
+func TestIsolatedServersShareNoNetwork(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+
+	alpha := createOnNetwork(t, m, fake, "alpha", NetworkModeIsolated)
+	beta := createOnNetwork(t, m, fake, "beta", NetworkModeIsolated)
+
+	// Containers on different bridge networks cannot reach each other
+	for id, created := range map[string]containerCreate{"alpha": alpha, "beta": beta} {
+		want := isolatedNetworkName("uuid-" + id)
+		if names := endpoints(created); string(created.HostConfig.NetworkMode) != want || len(names) != 1 || names[0] != want {
+			t.Errorf("%s on %q joining %v, want only its own network %s", id, created.HostConfig.NetworkMode, names, want)
+		}
+		if !fake.networks[want] {
+			t.Errorf("network %s was not created", want)
+		}
+		if bindings := created.HostConfig.PortBindings["25565/tcp"]; len(bindings) != 1 || bindings[0].HostIP != "203.0.113.10" {
+			t.Errorf("%s port bindings %v, want 25565 published on its IP", id, created.HostConfig.PortBindings)
+		}
+	}
+	if alpha.HostConfig.NetworkMode == beta.HostConfig.NetworkMode {
+		t.Errorf("both servers are on %s", alpha.HostConfig.NetworkMode)
+	}
+}
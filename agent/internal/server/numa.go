@@ -0,0 +1,89 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numaNodePath is where the kernel lists NUMA nodes and their cores
+const numaNodePath = "/sys/devices/system/node"
+
+// numaNodes returns the NUMA memory nodes holding the cores of a CPU set, so
+// a pinned server allocates memory close to the cores it runs on. An empty
+// string is returned on single-node hosts or when the topology is unknown,
+// leaving memory placement to the kernel.
+func numaNodes(cpuset string) string {
+	if cpuset == "" {
+		return ""
+	}
+	cpus, err := parseCPUList(cpuset)
+	if err != nil {
+		return ""
+	}
+
+	dirs, err := filepath.Glob(filepath.Join(numaNodePath, "node[0-9]*"))
+	if err != nil || len(dirs) < 2 {
+		return ""
+	}
+
+	var nodes []int
+	for _, dir := range dirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			continue
+		}
+		nodeCPUs, err := parseCPUList(strings.TrimSpace(string(data)))
+		if err != nil {
+			continue
+		}
+		for cpu := range nodeCPUs {
+			if cpus[cpu] {
+				nodes = append(nodes, node)
+				break
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	sort.Ints(nodes)
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = strconv.Itoa(node)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseCPUList parses a kernel CPU list such as "0-3,8,10-11"
+func parseCPUList(list string) (map[int]bool, error) {
+	cpus := make(map[int]bool)
+	if list == "" {
+		return cpus, nil
+	}
+
+	for _, part := range strings.Split(list, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus[cpu] = true
+		}
+	}
+	return cpus, nil
+}
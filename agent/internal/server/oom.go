@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OOMKill describes a container stopped by the kernel for exceeding its memory limit
+type OOMKill struct {
+	KilledAt    time.Time `json:"killed_at"`
+	MemoryLimit uint64    `json:"memory_limit"` // bytes
+	MemoryUsage uint64    `json:"memory_usage"` // bytes, last sample before the kill
+}
+
+// checkOOM inspects a server's container and, when the OOM killer stopped it,
+// marks the server as errored and publishes an event. Each kill is reported
+// once. It returns whether the container was stopped by the OOM killer.
+func (m *Manager) checkOOM(ctx context.Context, server *ServerState) bool {
+	info, err := m.docker.InspectContainer(ctx, server.ContainerID)
+	if err != nil || info.ContainerJSONBase == nil || info.State == nil || !info.State.OOMKilled {
+		return false
+	}
+	if info.State.Running {
+		return false
+	}
+
+	killedAt, err := time.Parse(time.RFC3339Nano, info.State.FinishedAt)
+	if err != nil {
+		killedAt = time.Now()
+	}
+
+	server.mu.Lock()
+	if server.OOMKill != nil && server.OOMKill.KilledAt.Equal(killedAt) {
+		server.mu.Unlock()
+		return true
+	}
+
+	oom := &OOMKill{
+		KilledAt:    killedAt,
+		MemoryUsage: server.LastMemory,
+	}
+	if info.HostConfig != nil {
+		oom.MemoryLimit = uint64(info.HostConfig.Memory)
+	}
+	server.OOMKill = oom
+	server.StartedAt = nil
+	server.mu.Unlock()
+
+	m.setStatus(server, "error")
+
+	m.logger.Warn("Server was killed for running out of memory",
+		zap.String("id", server.ID),
+		zap.Uint64("memory_limit", oom.MemoryLimit),
+		zap.Uint64("memory_usage", oom.MemoryUsage),
+	)
+	m.events.Publish(server.ID, EventServerOOM, oom)
+	return true
+}
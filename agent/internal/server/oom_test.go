@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckOOMReportsKillOnce(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	fake.state = "exited"
+	fake.oomKilled = true
+	server := addTestServer(m, "hungry")
+	server.Status = "running"
+	server.LastMemory = 2140 << 20
+	events, unsubscribe := m.events.Subscribe("hungry")
+	defer unsubscribe()
+
+	for i := 0; i < 2; i++ {
+		if !m.checkOOM(context.Background(), server) {
+			t.Fatal("an OOM killed container was not reported")
+		}
+	}
+
+	if server.Status != "error" {
+		t.Errorf("status = %s, want error", server.Status)
+	}
+	var kills []*OOMKill
+	for _, e := range drain(events) {
+		if e.Type == EventServerOOM {
+			kills = append(kills, e.Data.(*OOMKill))
+		}
+	}
+	if len(kills) != 1 {
+		t.Fatalf("published %d OOM events, want one per kill", len(kills))
+	}
+	if kills[0].MemoryLimit != 2<<30 || kills[0].MemoryUsage != 2140<<20 || kills[0].KilledAt.IsZero() {
+		t.Errorf("OOM event %+v, want the container's limit, the last usage and when it was killed", kills[0])
+	}
+}
+
+func TestCheckOOMIgnoresOtherExits(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	fake.state = "exited"
+	server := addTestServer(m, "clean")
+	events, unsubscribe := m.events.Subscribe("clean")
+	defer unsubscribe()
+
+	if m.checkOOM(context.Background(), server) {
+		t.Error("a container that exited on its own was reported as OOM killed")
+	}
+	if published := drain(events); len(published) != 0 {
+		t.Errorf("published %v, want nothing", published)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Orphan kinds
+const (
+	OrphanContainer = "container"
+	OrphanData      = "data"
+)
+
+// Orphan is a container or data directory left on this node for a server the
+// panel no longer knows about
+type Orphan struct {
+	Kind        string `json:"kind"`
+	UUID        string `json:"uuid"`
+	ServerID    string `json:"server_id,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Size        uint64 `json:"size,omitempty"` // Bytes, data directories only
+	Removed     bool   `json:"removed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CleanOrphans finds the Aether-managed containers and server data directories
+// whose server UUID is not in known, the UUIDs of every server the panel has
+// on this node. Nothing is removed on a dry run, so the panel can report what
+// a cleanup would delete before an admin confirms it.
+func (m *Manager) CleanOrphans(ctx context.Context, known []string, dryRun bool) ([]Orphan, error) {
+	keep := make(map[string]bool, len(known))
+	for _, u := range known {
+		keep[u] = true
+	}
+
+	containers, err := m.docker.ListContainersByLabel(ctx, map[string]string{
+		"aether.managed": "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	orphans := make([]Orphan, 0)
+	for _, c := range containers {
+		uuid := c.Labels["aether.server.uuid"]
+		if keep[uuid] {
+			continue
+		}
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		orphans = append(orphans, Orphan{
+			Kind:        OrphanContainer,
+			UUID:        uuid,
+			ServerID:    c.Labels["aether.server.id"],
+			ContainerID: c.ID,
+			Name:        name,
+		})
+	}
+
+	entries, err := os.ReadDir(m.config.Storage.ServerDataPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read server data directory: %w", err)
+	}
+	for _, entry := range entries {
+		// Only directories named like a server UUID are server data
+		if !entry.IsDir() || !isServerUUID(entry.Name()) || keep[entry.Name()] {
+			continue
+		}
+		path := filepath.Join(m.config.Storage.ServerDataPath, entry.Name())
+		orphans = append(orphans, Orphan{
+			Kind: OrphanData,
+			UUID: entry.Name(),
+			Path: path,
+			Size: dirSize(path),
+		})
+	}
+
+	if dryRun {
+		return orphans, nil
+	}
+
+	for i := range orphans {
+		orphan := &orphans[i]
+		if err := m.removeOrphan(ctx, orphan); err != nil {
+			orphan.Error = err.Error()
+			m.logger.Warn("Failed to remove orphan",
+				zap.String("kind", orphan.Kind),
+				zap.String("uuid", orphan.UUID),
+				zap.Error(err),
+			)
+			continue
+		}
+		orphan.Removed = true
+	}
+
+	m.logger.Info("Orphans cleaned", zap.Int("count", len(orphans)))
+	return orphans, nil
+}
+
+// removeOrphan deletes an orphaned container, untracking it if the agent still
+// manages it, or an orphaned data directory
+func (m *Manager) removeOrphan(ctx context.Context, orphan *Orphan) error {
+	if orphan.Kind == OrphanData {
+		return os.RemoveAll(orphan.Path)
+	}
+
+	if server, ok := m.servers.Get(orphan.ServerID); ok && server.ContainerID == orphan.ContainerID {
+		return m.DeleteServer(ctx, orphan.ServerID)
+	}
+	if err := m.docker.RemoveContainer(ctx, orphan.ContainerID, true); err != nil {
+		return err
+	}
+	if orphan.UUID != "" {
+		if err := m.docker.RemoveNetwork(ctx, isolatedNetworkName(orphan.UUID)); err != nil {
+			m.logger.Warn("Failed to remove server network", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// isServerUUID reports whether name has the 8-4-4-4-12 form of a server UUID
+func isServerUUID(name string) bool {
+	if len(name) != 36 {
+		return false
+	}
+	for i, r := range name {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanOrphans(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	const (
+		known   = "11111111-1111-1111-1111-111111111111"
+		gone    = "22222222-2222-2222-2222-222222222222"
+		loose   = "33333333-3333-3333-3333-333333333333"
+		foreign = "not-a-server"
+	)
+	fake.listed = `[
+		{"Id":"container-known","Names":["/known"],"Labels":{"aether.managed":"true","aether.server.uuid":"` + known + `","aether.server.id":"known"}},
+		{"Id":"container-gone","Names":["/gone"],"Labels":{"aether.managed":"true","aether.server.uuid":"` + gone + `","aether.server.id":"gone"}}
+	]`
+	for _, dir := range []string{known, loose, foreign} {
+		if err := os.MkdirAll(filepath.Join(m.config.Storage.ServerDataPath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lostData := filepath.Join(m.config.Storage.ServerDataPath, loose)
+	if err := os.WriteFile(filepath.Join(lostData, "world.dat"), make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(orphans []Orphan, removed bool) {
+		t.Helper()
+		if len(orphans) != 2 {
+			t.Fatalf("orphans %+v, want the deleted server's container and the loose data", orphans)
+		}
+		container, data := orphans[0], orphans[1]
+		if container.Kind != OrphanContainer || container.UUID != gone || container.ContainerID != "container-gone" || container.Name != "gone" {
+			t.Errorf("container orphan %+v, want the deleted server's container", container)
+		}
+		if data.Kind != OrphanData || data.UUID != loose || data.Path != lostData || data.Size != 1000 {
+			t.Errorf("data orphan %+v, want %s holding 1000 bytes", data, lostData)
+		}
+		for _, orphan := range orphans {
+			if orphan.Removed != removed || orphan.Error != "" {
+				t.Errorf("%s orphan removed %v (%s), want %v", orphan.Kind, orphan.Removed, orphan.Error, removed)
+			}
+		}
+	}
+
+	// A dry run only reports
+	orphans, err := m.CleanOrphans(context.Background(), []string{known}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(orphans, false)
+	if fake.requested("DELETE") {
+		t.Error("a dry run removed a container")
+	}
+	if _, err := os.Stat(lostData); err != nil {
+		t.Errorf("a dry run removed the data: %v", err)
+	}
+
+	orphans, err = m.CleanOrphans(context.Background(), []string{known}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(orphans, true)
+	if !fake.requested("DELETE /containers/container-gone") || fake.requested("DELETE /containers/container-known") {
+		t.Error("cleanup did not remove only the orphaned container")
+	}
+	if _, err := os.Stat(lostData); !os.IsNotExist(err) {
+		t.Errorf("orphaned data still there: %v", err)
+	}
+	for _, dir := range []string{known, foreign} {
+		if _, err := os.Stat(filepath.Join(m.config.Storage.ServerDataPath, dir)); err != nil {
+			t.Errorf("%s was removed: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/docker"
+)
+
+// Protocols an allocation can be bound for
+const (
+	protocolTCP  = "tcp"
+	protocolUDP  = "udp"
+	protocolBoth = "both"
+)
+
+// ErrInvalidProtocol is returned for an allocation with an unknown protocol
+var ErrInvalidProtocol = errors.New("invalid allocation protocol")
+
+// protocols returns the transport protocols the allocation is bound for.
+// Allocations without a protocol bind both, as all did before it was set.
+func (a Allocation) protocols() ([]string, error) {
+	switch a.Protocol {
+	case protocolTCP:
+		return []string{protocolTCP}, nil
+	case protocolUDP:
+		return []string{protocolUDP}, nil
+	case protocolBoth, "":
+		return []string{protocolTCP, protocolUDP}, nil
+	}
+	return nil, fmt.Errorf("%w: %q on port %d", ErrInvalidProtocol, a.Protocol, a.Port)
+}
+
+func validateAllocations(allocations []Allocation) error {
+	for _, alloc := range allocations {
+		if _, err := alloc.protocols(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// portConfigs returns the port bindings of a server's allocations, one per
+// protocol each is bound for
+func portConfigs(allocations []Allocation) ([]docker.PortConfig, error) {
+	var ports []docker.PortConfig
+	for _, alloc := range allocations {
+		protocols, err := alloc.protocols()
+		if err != nil {
+			return nil, err
+		}
+		for _, protocol := range protocols {
+			ports = append(ports, docker.PortConfig{
+				HostIP:   alloc.IP,
+				HostPort: fmt.Sprintf("%d", alloc.Port),
+				ContPort: fmt.Sprintf("%d", alloc.Port),
+				Protocol: protocol,
+			})
+		}
+	}
+	return ports, nil
+}
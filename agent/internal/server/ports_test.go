@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCreateServerBindsAllocationProtocols(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+
+	err := m.CreateServer(context.Background(), &ServerConfig{
+		ID:         "new",
+		UUID:       "uuid-new",
+		Image:      "ghcr.io/example/game:latest",
+		StartupCmd: "./start.sh",
+		Allocations: []Allocation{
+			{IP: "0.0.0.0", Port: 25565, IsPrimary: true, Protocol: protocolTCP},
+			{IP: "0.0.0.0", Port: 27015, Protocol: protocolBoth},
+			{IP: "0.0.0.0", Port: 2456, Protocol: protocolUDP},
+			// Sent by panels from before protocols were set
+			{IP: "0.0.0.0", Port: 8080},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+
+	created := fake.lastCreated(t)
+	var bound, exposed []string
+	for port, bindings := range created.HostConfig.PortBindings {
+		bound = append(bound, string(port))
+		if len(bindings) != 1 || bindings[0].HostIP != "0.0.0.0" || bindings[0].HostPort != port.Port() {
+			t.Errorf("%s bound to %+v, want the same host port", port, bindings)
+		}
+	}
+	for port := range created.Config.ExposedPorts {
+		exposed = append(exposed, string(port))
+	}
+	slices.Sort(bound)
+	slices.Sort(exposed)
+	want := []string{"2456/udp", "25565/tcp", "27015/tcp", "27015/udp", "8080/tcp", "8080/udp"}
+	if !slices.Equal(bound, want) || !slices.Equal(exposed, want) {
+		t.Errorf("bound %v and exposed %v, want %v", bound, exposed, want)
+	}
+
+	err = m.CreateServer(context.Background(), &ServerConfig{
+		ID:          "sctp",
+		UUID:        "uuid-sctp",
+		Image:       "ghcr.io/example/game:latest",
+		StartupCmd:  "./start.sh",
+		Allocations: []Allocation{{IP: "0.0.0.0", Port: 25566, Protocol: "sctp"}},
+	})
+	if !errors.Is(err, ErrInvalidProtocol) {
+		t.Errorf("unknown protocol = %v, want %v", err, ErrInvalidProtocol)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrCapabilityNotAllowed is returned for a capability the node does not let
+// servers add back
+var ErrCapabilityNotAllowed = errors.New("capability not allowed")
+
+// validateCapabilities checks the capabilities a server adds back against the
+// node's allowlist
+func (m *Manager) validateCapabilities(caps []string) error {
+	for _, capability := range caps {
+		name := capabilityName(capability)
+		permitted := false
+		for _, allowed := range m.config.Docker.AllowedCaps {
+			if capabilityName(allowed) == name {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return fmt.Errorf("%w: %q", ErrCapabilityNotAllowed, capability)
+		}
+	}
+	return nil
+}
+
+// capabilities returns the capabilities a server's container keeps once all
+// others are dropped: the node's base set and those its egg adds back
+func (m *Manager) capabilities(caps []string) []string {
+	added := make([]string, 0, len(m.config.Docker.CapAdd)+len(caps))
+	seen := make(map[string]bool, cap(added))
+	for _, capability := range append(append([]string{}, m.config.Docker.CapAdd...), caps...) {
+		name := capabilityName(capability)
+		if !seen[name] {
+			seen[name] = true
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+// securityOptions returns the Docker security options of server containers.
+// Docker takes a seccomp profile's content rather than its path, so the
+// node's profile is read on every container creation.
+func (m *Manager) securityOptions() ([]string, error) {
+	var opts []string
+	if m.config.Docker.NoNewPrivileges {
+		opts = append(opts, "no-new-privileges")
+	}
+	if path := m.config.Docker.SeccompProfile; path != "" {
+		profile, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+		opts = append(opts, "seccomp="+string(profile))
+	}
+	return opts, nil
+}
+
+// capabilityName normalizes a capability to Docker's form, e.g. net_raw and
+// CAP_NET_RAW both become NET_RAW
+func capabilityName(capability string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+}
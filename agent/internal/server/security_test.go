@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCreateServerPassesSecurityOptions(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.config.Docker.CapAdd = []string{"chown"}
+	m.config.Docker.AllowedCaps = []string{"NET_RAW", "CAP_SYS_NICE"}
+	m.config.Docker.NoNewPrivileges = true
+	m.config.Docker.SeccompProfile = profile
+
+	err := m.CreateServer(context.Background(), &ServerConfig{
+		ID:           "new",
+		UUID:         "uuid-new",
+		Image:        "ghcr.io/example/game:latest",
+		StartupCmd:   "./start.sh",
+		Capabilities: []string{"cap_net_raw", "SYS_NICE", "CHOWN"},
+	})
+	if !errors.Is(err, ErrCapabilityNotAllowed) {
+		t.Fatalf("CreateServer adding back CHOWN = %v, want %v", err, ErrCapabilityNotAllowed)
+	}
+	if fake.requested("/containers/create") {
+		t.Error("a container was created with a capability outside the allowlist")
+	}
+
+	err = m.CreateServer(context.Background(), &ServerConfig{
+		ID:           "new",
+		UUID:         "uuid-new",
+		Image:        "ghcr.io/example/game:latest",
+		StartupCmd:   "./start.sh",
+		Capabilities: []string{"cap_net_raw", "SYS_NICE"},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer: %v", err)
+	}
+
+	host := fake.lastCreated(t).HostConfig
+	if !slices.Equal([]string(host.CapDrop), []string{"ALL"}) {
+		t.Errorf("dropped %v, want ALL", host.CapDrop)
+	}
+	if want := []string{"CHOWN", "NET_RAW", "SYS_NICE"}; !slices.Equal([]string(host.CapAdd), want) {
+		t.Errorf("added back %v, want %v", host.CapAdd, want)
+	}
+	if want := []string{"no-new-privileges", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`}; !slices.Equal(host.SecurityOpt, want) {
+		t.Errorf("security options %q, want %q", host.SecurityOpt, want)
+	}
+
+	// Pushed capabilities are held to the allowlist as well
+	if err := m.UpdateServerStartup("new", StartupSpec{StartupCmd: "./start.sh", Capabilities: []string{"SYS_ADMIN"}}); !errors.Is(err, ErrCapabilityNotAllowed) {
+		t.Errorf("UpdateServerStartup adding back SYS_ADMIN = %v, want %v", err, ErrCapabilityNotAllowed)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// serverShards is the number of locks the server map is split over
+const serverShards = 32
+
+// serverMap holds the managed servers, sharded by ID so that lookups of
+// different servers rarely contend on the same lock. The map only guards
+// membership, each ServerState carries its own lock.
+type serverMap struct {
+	shards [serverShards]serverShard
+}
+
+type serverShard struct {
+	mu      sync.RWMutex
+	servers map[string]*ServerState
+}
+
+func newServerMap() *serverMap {
+	m := &serverMap{}
+	for i := range m.shards {
+		m.shards[i].servers = make(map[string]*ServerState)
+	}
+	return m
+}
+
+func (m *serverMap) shard(id string) *serverShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return &m.shards[h.Sum32()%serverShards]
+}
+
+// Get returns the server with the given ID
+func (m *serverMap) Get(id string) (*ServerState, bool) {
+	s := m.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	server, ok := s.servers[id]
+	return server, ok
+}
+
+// Set stores a server, replacing any server with the same ID
+func (m *serverMap) Set(server *ServerState) {
+	s := m.shard(server.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.servers[server.ID] = server
+}
+
+// SetIfAbsent stores a server unless one with the same ID exists and reports
+// whether it was stored
+func (m *serverMap) SetIfAbsent(server *ServerState) bool {
+	s := m.shard(server.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.servers[server.ID]; ok {
+		return false
+	}
+	s.servers[server.ID] = server
+	return true
+}
+
+// DeleteIf removes a server only if the stored one is still server, so a
+// server recreated under the same ID is left alone
+func (m *serverMap) DeleteIf(server *ServerState) bool {
+	s := m.shard(server.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.servers[server.ID] != server {
+		return false
+	}
+	delete(s.servers, server.ID)
+	return true
+}
+
+// Snapshot returns the servers at the time of the call. Callers iterate the
+// copy without holding any map lock.
+func (m *serverMap) Snapshot() []*ServerState {
+	var servers []*ServerState
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		for _, server := range s.servers {
+			servers = append(servers, server)
+		}
+		s.mu.RUnlock()
+	}
+	return servers
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultShell wraps the startup of servers whose egg names no shell
+const defaultShell = "/bin/bash"
+
+// ErrInvalidStartup is returned for a direct exec startup that cannot be split
+// into arguments
+var ErrInvalidStartup = errors.New("invalid startup command")
+
+// ContainerCommand controls how a server's startup becomes its container's
+// command. The zero value runs the startup through /bin/bash -c with the
+// image's own entrypoint.
+type ContainerCommand struct {
+	Entrypoint []string `json:"entrypoint,omitempty"` // Replaces the image's entrypoint, empty keeps it
+	Shell      string   `json:"shell,omitempty"`      // Shell the startup runs in, /bin/bash when empty
+	DirectExec bool     `json:"direct_exec"`          // Run the startup's arguments without a shell
+}
+
+// shell returns the shell a server's startup and console commands run in
+func (cfg *ContainerCommand) shell() string {
+	if cfg.Shell != "" {
+		return cfg.Shell
+	}
+	return defaultShell
+}
+
+// command returns the container command for a startup: the startup wrapped as
+// "<shell> -c <startup>", or with DirectExec the startup split into arguments
+// and run as is, for images without a shell or with an entrypoint that execs
+// its arguments
+func (cfg *ContainerCommand) command(startup string) ([]string, error) {
+	if !cfg.DirectExec {
+		return []string{cfg.shell(), "-c", startup}, nil
+	}
+	args, err := splitCommand(startup)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 && len(cfg.Entrypoint) == 0 {
+		return nil, fmt.Errorf("%w: empty command", ErrInvalidStartup)
+	}
+	return args, nil
+}
+
+// splitCommand splits a command line into arguments the way a POSIX shell
+// would, honouring single and double quotes and backslash escapes but without
+// any expansion
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%w: unterminated %c quote", ErrInvalidStartup, quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("%w: trailing backslash", ErrInvalidStartup)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
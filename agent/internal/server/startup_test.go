@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		want    []string
+	}{
+		{"./server --port 25565", []string{"./server", "--port", "25565"}},
+		{"  java\t-Xmx2G  -jar server.jar ", []string{"java", "-Xmx2G", "-jar", "server.jar"}},
+		{`./run --motd "Hello, world" --name 'Zoe''s'`, []string{"./run", "--motd", "Hello, world", "--name", "Zoes"}},
+		{`./run --path my\ world --empty ""`, []string{"./run", "--path", "my world", "--empty", ""}},
+		{`./run "say \"hi\"" '$HOME'`, []string{"./run", `say "hi"`, "$HOME"}},
+		{"", nil},
+	} {
+		got, err := splitCommand(tc.command)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("splitCommand(%q) = %q, %v, want %q", tc.command, got, err, tc.want)
+		}
+	}
+	for _, command := range []string{`./run "unterminated`, "./run 'open", `./run trailing\`} {
+		if _, err := splitCommand(command); !errors.Is(err, ErrInvalidStartup) {
+			t.Errorf("splitCommand(%q) = %v, want %v", command, err, ErrInvalidStartup)
+		}
+	}
+}
+
+func TestCreateServerContainerCommand(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		command    ContainerCommand
+		entrypoint []string
+		cmd        []string
+	}{
+		{"default", ContainerCommand{}, nil, []string{"/bin/bash", "-c", `./start.sh --motd "Hi there"`}},
+		{"shell", ContainerCommand{Shell: "/bin/sh"}, nil, []string{"/bin/sh", "-c", `./start.sh --motd "Hi there"`}},
+		{"direct exec", ContainerCommand{DirectExec: true, Entrypoint: []string{"/entrypoint"}}, []string{"/entrypoint"}, []string{"./start.sh", "--motd", "Hi there"}},
+	} {
+		m, fake := newDockerTestManager(t)
+		close(fake.pull)
+
+		err := m.CreateServer(context.Background(), &ServerConfig{
+			ID:               "new",
+			UUID:             "uuid-new",
+			Image:            "ghcr.io/example/game:latest",
+			StartupCmd:       `./start.sh --motd "Hi there"`,
+			ContainerCommand: tc.command,
+		})
+		if err != nil {
+			t.Fatalf("%s: CreateServer: %v", tc.name, err)
+		}
+		created := fake.lastCreated(t)
+		if !slices.Equal([]string(created.Entrypoint), tc.entrypoint) || !slices.Equal([]string(created.Cmd), tc.cmd) {
+			t.Errorf("%s: entrypoint %q cmd %q, want %q %q", tc.name, created.Entrypoint, created.Cmd, tc.entrypoint, tc.cmd)
+		}
+	}
+
+	// A direct exec startup that cannot be split is refused up front
+	m, fake := newDockerTestManager(t)
+	close(fake.pull)
+	err := m.CreateServer(context.Background(), &ServerConfig{
+		ID:               "new",
+		UUID:             "uuid-new",
+		Image:            "ghcr.io/example/game:latest",
+		StartupCmd:       `./start.sh --motd "Hi there`,
+		ContainerCommand: ContainerCommand{DirectExec: true},
+	})
+	if !errors.Is(err, ErrInvalidStartup) || fake.requested("/containers/create") {
+		t.Errorf("unterminated quote = %v, want %v before any container is created", err, ErrInvalidStartup)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrDiskQuotaExceeded = errors.New("server disk limit would be exceeded")
+	ErrInvalidPath       = errors.New("path is outside the server data directory")
+)
+
+// ServerFilePath resolves a path relative to a server's data directory and
+// rejects paths escaping it, as text or through symlinks in the directory
+func (m *Manager) ServerFilePath(serverID, rel string) (string, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return "", err
+	}
+
+	root := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	path := filepath.Join(root, filepath.Clean("/"+rel))
+	if path == root || !strings.HasPrefix(path, root+string(os.PathSeparator)) {
+		return "", ErrInvalidPath
+	}
+	if err := checkInRoot(root, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// checkInRoot resolves the symlinks of path, or of its deepest existing parent
+// when it does not exist yet, and rejects it unless the result is inside root.
+// Servers can create symlinks in their data directory, which the agent would
+// otherwise follow as root to any file on the host. A dangling symlink is
+// rejected as writing through it would create its target.
+func checkInRoot(root, path string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	existing := path
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return nil
+		}
+		existing = parent
+	}
+
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return ErrInvalidPath
+	}
+	if real != realRoot && !strings.HasPrefix(real, realRoot+string(os.PathSeparator)) {
+		return ErrInvalidPath
+	}
+	return nil
+}
+
+// CheckDiskQuota reports whether size more bytes fit in a server's disk limit.
+// When existing is set the current size of the data directory is counted too.
+func (m *Manager) CheckDiskQuota(serverID string, size int64, existing bool) error {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return err
+	}
+	if server.DiskLimit <= 0 {
+		return nil
+	}
+
+	used := uint64(0)
+	if existing {
+		used = dirSize(filepath.Join(m.config.Storage.ServerDataPath, server.UUID))
+	}
+	if used+uint64(size) > uint64(server.DiskLimit)*1024*1024 {
+		return ErrDiskQuotaExceeded
+	}
+	return nil
+}
+
+// getServer returns the state of a tracked server
+func (m *Manager) getServer(serverID string) (*ServerState, error) {
+	server, exists := m.servers.Get(serverID)
+	if !exists {
+		return nil, fmt.Errorf("server not found: %s", serverID)
+	}
+	return server, nil
+}
+
+// lockServer returns a tracked server with its lock held for writing. Servers
+// whose container is still being created are refused.
+func (m *Manager) lockServer(serverID string) (*ServerState, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	server.mu.Lock()
+	if server.Status == statusCreating {
+		server.mu.Unlock()
+		return nil, ErrServerCreating
+	}
+	return server, nil
+}
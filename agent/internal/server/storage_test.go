@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/agent/internal/config"
+	"go.uber.org/zap"
+)
+
+// newTestManager returns a manager without Docker tracking one server whose
+// data directory is created under a temporary directory
+func newTestManager(t *testing.T) (*Manager, *ServerState, string) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Storage.ServerDataPath = t.TempDir()
+	m := &Manager{
+		config:  cfg,
+		logger:  zap.NewNop(),
+		servers: newServerMap(),
+		events:  NewEventBus(),
+	}
+
+	server := &ServerState{ID: "server-1", UUID: "uuid-1", Status: "stopped", Config: &ServerConfig{}}
+	m.servers.Set(server)
+
+	root := filepath.Join(cfg.Storage.ServerDataPath, server.UUID)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return m, server, root
+}
+
+func TestServerFilePathRejectsEscapes(t *testing.T) {
+	m, server, root := newTestManager(t)
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "plugins"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("plugins", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel  string
+		want error
+	}{
+		{"server.properties", nil},
+		{"world/level.dat", nil},
+		{"inside/config.yml", nil},
+		{"../../etc/passwd", nil}, // cleaned to the root
+		{"", ErrInvalidPath},
+		{"escape", ErrInvalidPath},
+		{"escape/passwd", ErrInvalidPath},
+		{"escape/new/file", ErrInvalidPath},
+		{"dangling", ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		_, err := m.ServerFilePath(server.ID, tt.rel)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("ServerFilePath(%q) = %v, want %v", tt.rel, err, tt.want)
+		}
+	}
+}
+
+func TestApplyConfigFileDoesNotFollowSymlinks(t *testing.T) {
+	m, server, root := newTestManager(t)
+
+	target := filepath.Join(t.TempDir(), "passwd")
+	if err := os.WriteFile(target, []byte("root:x:0:0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(root, "server.properties")); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := configFileSpec{
+		Parser: "properties",
+		Find:   map[string]json.RawMessage{"motd": json.RawMessage(`"hello"`)},
+	}
+	err := m.applyConfigFile(server.ID, server.Config, "server.properties", spec)
+	if !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("applyConfigFile = %v, want %v", err, ErrInvalidPath)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "root:x:0:0\n" {
+		t.Errorf("host file was rewritten: %q", content)
+	}
+}
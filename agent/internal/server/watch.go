@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"go.uber.org/zap"
+)
+
+// watchedActions are the container events that change a server's status
+var watchedActions = []string{"start", "die", "stop", "oom", "destroy", "health_status"}
+
+// eventReconnectDelay is how long a dropped event stream waits to reopen
+var eventReconnectDelay = 5 * time.Second
+
+// StartEventWatch follows Docker events of managed containers and updates
+// server status as soon as they happen. When the stream drops it is reopened
+// after a short delay and all servers are reconciled, since events may have
+// been missed in between. The health check poller remains as a backstop.
+func (m *Manager) StartEventWatch(ctx context.Context) {
+	for {
+		messages, errs := m.docker.WatchContainerEvents(ctx, map[string]string{
+			"aether.managed": "true",
+		}, watchedActions...)
+
+	stream:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-messages:
+				m.handleContainerEvent(ctx, msg)
+			case err := <-errs:
+				if ctx.Err() != nil {
+					return
+				}
+				m.logger.Warn("Docker event stream failed, reconnecting", zap.Error(err))
+				break stream
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventReconnectDelay):
+		}
+		m.checkAllServers(ctx)
+	}
+}
+
+// handleContainerEvent applies a Docker container event to its server
+func (m *Manager) handleContainerEvent(ctx context.Context, msg events.Message) {
+	server, err := m.getServer(msg.Actor.Attributes["aether.server.id"])
+	if err != nil {
+		return
+	}
+
+	// Events of a container that was already replaced are stale
+	server.mu.RLock()
+	containerID := server.ContainerID
+	server.mu.RUnlock()
+	if msg.Actor.ID != containerID {
+		return
+	}
+
+	// Health events carry the new state in the action itself
+	if health, ok := healthFromAction(msg.Action); ok {
+		m.setHealth(server, health)
+		return
+	}
+
+	switch msg.Action {
+	case "start":
+		server.mu.Lock()
+		if server.StartedAt == nil {
+			now := time.Now()
+			server.StartedAt = &now
+		}
+		server.mu.Unlock()
+		m.setStatus(server, "running")
+
+		// Each start brings a new network namespace without the caps
+		if err := m.applyBandwidth(ctx, server); err != nil {
+			m.logger.Warn("Failed to limit server bandwidth", zap.String("id", server.ID), zap.Error(err))
+		}
+	case "oom":
+		// The kernel may kill a process without stopping the container;
+		// the die event that follows a fatal kill settles the status
+		m.checkOOM(ctx, server)
+	case "die", "stop":
+		if m.checkOOM(ctx, server) {
+			return
+		}
+		// A stop request is answered by both a die and a stop event, the
+		// exit code only comes with die
+		if msg.Action == "die" && m.handleExit(ctx, server, msg.Actor.Attributes["exitCode"]) {
+			return
+		}
+		server.mu.Lock()
+		server.StartedAt = nil
+		server.mu.Unlock()
+		m.setStatus(server, "exited")
+	case "destroy":
+		server.mu.Lock()
+		server.StartedAt = nil
+		server.mu.Unlock()
+		m.setStatus(server, "removed")
+	}
+}
+
+// setStatus records a server's status and publishes it when it changed
+func (m *Manager) setStatus(server *ServerState, status string) {
+	server.mu.Lock()
+	previous := server.Status
+	server.Status = status
+	// Health is only meaningful while the container runs
+	if status != "running" {
+		server.Health = ""
+	}
+	server.mu.Unlock()
+
+	if previous == status {
+		return
+	}
+
+	m.logger.Debug("Server status changed",
+		zap.String("id", server.ID),
+		zap.String("from", previous),
+		zap.String("to", status),
+	)
+	m.events.Publish(server.ID, EventStatus, map[string]string{
+		"status":   status,
+		"previous": previous,
+	})
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// containerEvent returns the Docker event of action on a server's container
+func containerEvent(t *testing.T, server *ServerState, action string, attributes map[string]string) string {
+	t.Helper()
+	attrs := map[string]string{"aether.server.id": server.ID, "aether.managed": "true"}
+	for k, v := range attributes {
+		attrs[k] = v
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"Type":   "container",
+		"Action": action,
+		"Actor":  map[string]interface{}{"ID": server.ContainerID, "Attributes": attrs},
+		"time":   time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// watchEvents follows the fake daemon's events until the test ends
+func watchEvents(t *testing.T, m *Manager) {
+	t.Helper()
+	m.run = func(ctx context.Context, name string, args ...string) error { return nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.StartEventWatch(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestDieEventStopsServer(t *testing.T) {
+	m, fake := newDockerTestManager(t)
+	fake.state = "exited"
+	server := addTestServer(m, "watched")
+	server.Status = "running"
+	events, unsubscribe := m.events.Subscribe("watched")
+	defer unsubscribe()
+	watchEvents(t, m)
+
+	// Events of a container the server no longer uses are ignored
+	stale := &ServerState{ID: server.ID, ContainerID: "container-replaced"}
+	fake.events <- containerEvent(t, stale, "die", map[string]string{"exitCode": "0"})
+	fake.events <- containerEvent(t, server, "die", map[string]string{"exitCode": "0"})
+	waitForStatus(t, server, "exited")
+
+	// The status is published right after it is set
+	var published []Event
+	select {
+	case e := <-events:
+		published = append(published, e)
+	case <-time.After(5 * time.Second):
+	}
+	var statuses []string
+	for _, e := range append(published, drain(events)...) {
+		if e.Type == EventStatus {
+			statuses = append(statuses, e.Data.(map[string]string)["status"])
+		}
+	}
+	if len(statuses) != 1 || statuses[0] != "exited" {
+		t.Errorf("published statuses %q, want the server exited once", statuses)
+	}
+}
+
+func TestEventWatchResumesAfterDrop(t *testing.T) {
+	delay := eventReconnectDelay
+	eventReconnectDelay = 10 * time.Millisecond
+	t.Cleanup(func() { eventReconnectDelay = delay })
+
+	m, fake := newDockerTestManager(t)
+	// The container stopped while the stream was down, its die event is lost
+	fake.state = "exited"
+	server := addTestServer(m, "watched")
+	server.Status = "running"
+	watchEvents(t, m)
+
+	fake.events <- ""
+	// Servers are reconciled once the stream is back
+	waitForStatus(t, server, "exited")
+
+	fake.events <- containerEvent(t, server, "start", nil)
+	waitForStatus(t, server, "running")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	streams := 0
+	for _, r := range fake.requests {
+		if strings.HasSuffix(r, " /events") {
+			streams++
+		}
+	}
+	if streams != 2 {
+		t.Errorf("opened the event stream %d times, want it reopened once", streams)
+	}
+}
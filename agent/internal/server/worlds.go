@@ -0,0 +1,350 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWorldNotFound = errors.New("world not found")
+	ErrServerRunning = errors.New("server must be stopped first")
+)
+
+var (
+	gameModes    = []string{"survival", "creative", "adventure", "spectator"}
+	difficulties = []string{"peaceful", "easy", "normal", "hard"}
+)
+
+// World is a Minecraft world folder in a server's data directory
+type World struct {
+	FolderName string     `json:"folder_name"`
+	Name       string     `json:"name"`
+	Dimension  string     `json:"dimension"` // overworld, nether, end
+	Seed       string     `json:"seed,omitempty"`
+	GameMode   string     `json:"game_mode,omitempty"`
+	Difficulty string     `json:"difficulty,omitempty"`
+	Size       int64      `json:"size"` // bytes
+	LastPlayed *time.Time `json:"last_played,omitempty"`
+}
+
+// WorldArchive describes a world backup written by BackupWorld
+type WorldArchive struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"` // SHA-256 of the archive, hex encoded
+}
+
+// ListWorlds returns the world folders of a server, recognised by the
+// level.dat they contain. Metadata is read from level.dat where possible.
+func (m *Manager) ListWorlds(serverID string) ([]World, error) {
+	server, err := m.getServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataPath := filepath.Join(m.config.Storage.ServerDataPath, server.UUID)
+	entries, err := os.ReadDir(dataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []World{}, nil
+		}
+		return nil, fmt.Errorf("failed to read server directory: %w", err)
+	}
+
+	worlds := make([]World, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dataPath, entry.Name())
+		if _, err := os.Stat(filepath.Join(path, "level.dat")); err != nil {
+			continue
+		}
+		worlds = append(worlds, readWorld(path, m.logger))
+	}
+	return worlds, nil
+}
+
+// readWorld describes the world folder at path
+func readWorld(path string, logger *zap.Logger) World {
+	world := World{
+		FolderName: filepath.Base(path),
+		Name:       filepath.Base(path),
+		Dimension:  worldDimension(path),
+		Size:       int64(dirSize(path)),
+	}
+
+	level, err := readLevelDat(filepath.Join(path, "level.dat"))
+	if err != nil {
+		logger.Debug("Failed to parse level.dat", zap.String("world", path), zap.Error(err))
+		return world
+	}
+	data, _ := level["Data"].(map[string]interface{})
+	if data == nil {
+		return world
+	}
+
+	if name, ok := data["LevelName"].(string); ok && name != "" {
+		world.Name = name
+	}
+
+	// Seeds moved into WorldGenSettings in 1.16
+	if seed, ok := data["RandomSeed"].(int64); ok {
+		world.Seed = strconv.FormatInt(seed, 10)
+	} else if settings, ok := data["WorldGenSettings"].(map[string]interface{}); ok {
+		if seed, ok := settings["seed"].(int64); ok {
+			world.Seed = strconv.FormatInt(seed, 10)
+		}
+	}
+
+	if mode, ok := data["GameType"].(int32); ok && mode >= 0 && int(mode) < len(gameModes) {
+		world.GameMode = gameModes[mode]
+	}
+	if difficulty, ok := data["Difficulty"].(byte); ok && int(difficulty) < len(difficulties) {
+		world.Difficulty = difficulties[difficulty]
+	}
+	if played, ok := data["LastPlayed"].(int64); ok && played > 0 {
+		t := time.UnixMilli(played)
+		world.LastPlayed = &t
+	}
+
+	return world
+}
+
+// worldDimension guesses the dimension of a world folder. Bukkit based
+// servers keep the nether and end in their own folders holding only DIM-1 or
+// DIM1, vanilla keeps them inside the overworld folder.
+func worldDimension(path string) string {
+	if dirExists(filepath.Join(path, "region")) {
+		return "overworld"
+	}
+	if dirExists(filepath.Join(path, "DIM-1")) {
+		return "nether"
+	}
+	if dirExists(filepath.Join(path, "DIM1")) {
+		return "end"
+	}
+	return "overworld"
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// WorldBackupPath returns where the archive of a world backup is stored
+func (m *Manager) WorldBackupPath(backupID string) (string, error) {
+	if backupID == "" || backupID != filepath.Base(backupID) {
+		return "", fmt.Errorf("invalid backup id: %q", backupID)
+	}
+	return filepath.Join(m.config.Storage.BackupPath, "worlds", backupID+".tar.gz"), nil
+}
+
+// BackupWorld archives a world folder and returns the archive's size and checksum
+func (m *Manager) BackupWorld(ctx context.Context, serverID, folder, backupID string) (*WorldArchive, error) {
+	worldPath, err := m.worldPath(serverID, folder)
+	if err != nil {
+		return nil, err
+	}
+	archivePath, err := m.WorldBackupPath(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	size, checksum, err := writeArchiveFile(ctx, worldPath, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &WorldArchive{Size: size, Checksum: checksum}
+	m.logger.Info("World backed up",
+		zap.String("id", serverID),
+		zap.String("world", folder),
+		zap.String("backup", backupID),
+		zap.Int64("size", archive.Size),
+	)
+	return archive, nil
+}
+
+// writeArchiveFile archives dir to archivePath and returns the archive's size
+// and SHA-256. The archive is written next to its destination first, so a
+// failed or cancelled backup never leaves a partial archive behind.
+func writeArchiveFile(ctx context.Context, dir, archivePath string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return 0, "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(archivePath), "."+filepath.Base(archivePath)+".*.tmp")
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(out, hash)}
+	if err := writeArchive(ctx, counter, dir); err != nil {
+		out.Close()
+		return 0, "", err
+	}
+	if err := out.Close(); err != nil {
+		return 0, "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(out.Name(), archivePath); err != nil {
+		return 0, "", fmt.Errorf("failed to store archive: %w", err)
+	}
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeArchive writes the contents of dir as a gzipped tar to w
+func writeArchive(ctx context.Context, w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return gz.Close()
+}
+
+// RestoreWorld replaces a world folder with a backup after verifying the
+// archive checksum. The server has to be stopped.
+func (m *Manager) RestoreWorld(ctx context.Context, serverID, folder, backupID, checksum string) error {
+	worldPath, err := m.worldFolderPath(serverID, folder)
+	if err != nil {
+		return err
+	}
+	archivePath, err := m.WorldBackupPath(backupID)
+	if err != nil {
+		return err
+	}
+	if err := m.requireStopped(ctx, serverID); err != nil {
+		return err
+	}
+
+	if err := m.verifyBackup(serverID, backupID, archivePath, checksum); err != nil {
+		return err
+	}
+	if err := m.extractBackup(serverID, backupID, archivePath, worldPath, true); err != nil {
+		return err
+	}
+
+	m.logger.Info("World restored",
+		zap.String("id", serverID),
+		zap.String("world", folder),
+		zap.String("backup", backupID),
+	)
+	return nil
+}
+
+// DeleteWorld removes a world folder. The server has to be stopped.
+func (m *Manager) DeleteWorld(ctx context.Context, serverID, folder string) error {
+	worldPath, err := m.worldPath(serverID, folder)
+	if err != nil {
+		return err
+	}
+	if err := m.requireStopped(ctx, serverID); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(worldPath); err != nil {
+		return fmt.Errorf("failed to delete world: %w", err)
+	}
+
+	m.logger.Info("World deleted", zap.String("id", serverID), zap.String("world", folder))
+	return nil
+}
+
+// worldPath returns the path of an existing world folder
+func (m *Manager) worldPath(serverID, folder string) (string, error) {
+	path, err := m.worldFolderPath(serverID, folder)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(path, "level.dat")); err != nil {
+		return "", ErrWorldNotFound
+	}
+	return path, nil
+}
+
+// worldFolderPath returns the path a world folder has, whether or not it exists
+func (m *Manager) worldFolderPath(serverID, folder string) (string, error) {
+	if folder == "" || folder != filepath.Base(folder) || folder == "." || folder == ".." {
+		return "", ErrInvalidPath
+	}
+	return m.ServerFilePath(serverID, folder)
+}
+
+// requireStopped fails when the server's container is running
+func (m *Manager) requireStopped(ctx context.Context, serverID string) error {
+	status, err := m.GetServerStatus(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if status == "running" || status == "restarting" {
+		return ErrServerRunning
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,327 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Upload kinds
+const (
+	KindFile   = "file"   // Target is a path inside the server's data directory
+	KindBackup = "backup" // Target is the id of the backup archive
+)
+
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrInvalidPart      = errors.New("invalid part number")
+	ErrPartSize         = errors.New("part size does not match the upload")
+	ErrPartChecksum     = errors.New("part checksum mismatch")
+	ErrIncomplete       = errors.New("upload is missing parts")
+	ErrChecksumMismatch = errors.New("upload checksum mismatch")
+)
+
+// Upload is a chunked upload in progress. Parts are stored separately and
+// assembled into the final file once every part has arrived.
+type Upload struct {
+	ID        string    `json:"id"`
+	ServerID  string    `json:"server_id"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	TotalSize int64     `json:"total_size"`
+	PartSize  int64     `json:"part_size"`
+	Checksum  string    `json:"checksum"` // SHA-256 of the assembled file
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	parts map[int]string // Part number to SHA-256
+}
+
+// PartCount returns the number of parts the upload is split into
+func (u *Upload) PartCount() int {
+	if u.PartSize <= 0 {
+		return 0
+	}
+	return int((u.TotalSize + u.PartSize - 1) / u.PartSize)
+}
+
+// partLength returns the expected size of a part; only the last may be short
+func (u *Upload) partLength(n int) int64 {
+	if n == u.PartCount() {
+		if rest := u.TotalSize % u.PartSize; rest != 0 {
+			return rest
+		}
+	}
+	return u.PartSize
+}
+
+// Status describes an upload and the parts received so far, letting clients
+// resume by sending only the missing parts
+type Status struct {
+	*Upload
+	Parts         int   `json:"parts"`
+	ReceivedParts []int `json:"received_parts"`
+}
+
+// Store keeps chunked uploads on disk until they are completed or abandoned
+type Store struct {
+	dir     string
+	ttl     time.Duration
+	logger  *zap.Logger
+	uploads map[string]*Upload
+	mu      sync.Mutex
+}
+
+// NewStore creates a new Store in dir. Leftover parts from a previous run are
+// removed since their upload state was not kept.
+func NewStore(dir string, ttl time.Duration, logger *zap.Logger) (*Store, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear upload directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	return &Store{
+		dir:     dir,
+		ttl:     ttl,
+		logger:  logger,
+		uploads: make(map[string]*Upload),
+	}, nil
+}
+
+// Create registers a new upload and assigns its id
+func (s *Store) Create(u *Upload) (*Upload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+
+	now := time.Now()
+	u.ID = hex.EncodeToString(id)
+	u.Checksum = strings.ToLower(u.Checksum)
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	u.parts = make(map[int]string)
+
+	if err := os.MkdirAll(s.uploadDir(u.ID), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	s.mu.Lock()
+	s.uploads[u.ID] = u
+	s.mu.Unlock()
+
+	return u, nil
+}
+
+// Get returns a copy of an upload and the parts received so far, safe to use
+// while parts keep arriving
+func (s *Store) Get(id string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+
+	received := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		received = append(received, n)
+	}
+	sort.Ints(received)
+
+	snapshot := *u
+	snapshot.parts = nil
+	return &Status{Upload: &snapshot, Parts: u.PartCount(), ReceivedParts: received}, nil
+}
+
+// WritePart stores part n, numbered from 1. The part is written to a temporary
+// file and only kept when its size and checksum match, so an interrupted
+// transfer can simply be retried. Sending a part again replaces it.
+func (s *Store) WritePart(id string, n int, r io.Reader, checksum string) error {
+	s.mu.Lock()
+	u, ok := s.uploads[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrUploadNotFound
+	}
+	if n < 1 || n > u.PartCount() {
+		return ErrInvalidPart
+	}
+
+	expected := u.partLength(n)
+	path := s.partPath(id, n)
+	tmp, err := os.CreateTemp(s.uploadDir(id), "part-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create part: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, expected+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write part: %w", err)
+	}
+
+	if written != expected {
+		return ErrPartSize
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && !strings.EqualFold(sum, checksum) {
+		return ErrPartChecksum
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store part: %w", err)
+	}
+
+	s.mu.Lock()
+	u.parts[n] = sum
+	u.UpdatedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Complete assembles the parts into dest once all have arrived and verifies
+// the checksum of the result. dest is only replaced when the checksum matches.
+// verify, when set, is called right before dest is replaced to check it once
+// more, as the directory may have changed while the parts were assembled. The
+// upload is removed afterwards either way, except when parts are missing.
+func (s *Store) Complete(id, dest string, verify func() error) error {
+	s.mu.Lock()
+	u, ok := s.uploads[id]
+	if ok && len(u.parts) != u.PartCount() {
+		s.mu.Unlock()
+		return ErrIncomplete
+	}
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	if !ok {
+		return ErrUploadNotFound
+	}
+	defer os.RemoveAll(s.uploadDir(id))
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.upload")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	hash := sha256.New()
+	w := io.MultiWriter(out, hash)
+	for n := 1; n <= u.PartCount(); n++ {
+		if err := appendPart(w, s.partPath(id, n)); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if u.Checksum != "" && hex.EncodeToString(hash.Sum(nil)) != u.Checksum {
+		return ErrChecksumMismatch
+	}
+
+	if verify != nil {
+		if err := verify(); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(out.Name(), dest); err != nil {
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return nil
+}
+
+// Abort discards an upload and its parts
+func (s *Store) Abort(id string) error {
+	s.mu.Lock()
+	_, ok := s.uploads[id]
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	if !ok {
+		return ErrUploadNotFound
+	}
+	return os.RemoveAll(s.uploadDir(id))
+}
+
+// Cleanup removes uploads that received no part within the TTL and returns
+// how many were removed
+func (s *Store) Cleanup(now time.Time) int {
+	s.mu.Lock()
+	var expired []string
+	for id, u := range s.uploads {
+		if now.Sub(u.UpdatedAt) > s.ttl {
+			expired = append(expired, id)
+			delete(s.uploads, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range expired {
+		if err := os.RemoveAll(s.uploadDir(id)); err != nil {
+			s.logger.Warn("Failed to remove abandoned upload", zap.String("upload", id), zap.Error(err))
+		}
+	}
+	return len(expired)
+}
+
+// StartCleanup periodically removes abandoned uploads until ctx is done
+func (s *Store) StartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := s.Cleanup(now); n > 0 {
+				s.logger.Info("Removed abandoned uploads", zap.Int("count", n))
+			}
+		}
+	}
+}
+
+func (s *Store) uploadDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *Store) partPath(id string, n int) string {
+	return filepath.Join(s.uploadDir(id), fmt.Sprintf("%06d.part", n))
+}
+
+func appendPart(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open part: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to assemble part: %w", err)
+	}
+	return nil
+}
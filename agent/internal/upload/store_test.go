@@ -0,0 +1,109 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "uploads"), time.Hour, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestGetReturnsSnapshot(t *testing.T) {
+	s := newTestStore(t)
+	u, err := s.Create(&Upload{ServerID: "server-1", Kind: KindFile, Target: "a.bin", TotalSize: 64 * 4, PartSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Status is encoded while parts arrive, as the status endpoint does;
+	// run with -race to catch shared state
+	var wg sync.WaitGroup
+	for n := 1; n <= 4; n++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			if err := s.WritePart(u.ID, n, bytes.NewReader(make([]byte, 64)), ""); err != nil {
+				t.Error(err)
+			}
+		}(n)
+		go func() {
+			defer wg.Done()
+			status, err := s.Get(u.ID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := json.Marshal(status); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	before, err := s.Get(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := before.UpdatedAt
+	if err := s.WritePart(u.ID, 1, bytes.NewReader(make([]byte, 64)), ""); err != nil {
+		t.Fatal(err)
+	}
+	if !before.UpdatedAt.Equal(updated) {
+		t.Error("status changed after it was returned")
+	}
+	if len(before.ReceivedParts) != 4 {
+		t.Errorf("received parts = %v, want 4 parts", before.ReceivedParts)
+	}
+}
+
+func TestCompleteVerifiesBeforeReplacing(t *testing.T) {
+	s := newTestStore(t)
+	dest := filepath.Join(t.TempDir(), "world", "level.dat")
+	refused := errors.New("refused")
+
+	u, err := s.Create(&Upload{ServerID: "server-1", Kind: KindFile, Target: "world/level.dat", TotalSize: 5, PartSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePart(u.ID, 1, bytes.NewReader([]byte("hello")), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Complete(u.ID, dest, func() error { return refused }); !errors.Is(err, refused) {
+		t.Fatalf("Complete = %v, want %v", err, refused)
+	}
+	if _, err := os.Stat(dest); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("destination written despite failed verification: %v", err)
+	}
+	if _, err := s.Get(u.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("upload kept after completion: %v", err)
+	}
+
+	u, err = s.Create(&Upload{ServerID: "server-1", Kind: KindFile, Target: "world/level.dat", TotalSize: 5, PartSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WritePart(u.ID, 1, bytes.NewReader([]byte("hello")), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(u.ID, dest, func() error { return nil }); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if content, _ := os.ReadFile(dest); string(content) != "hello" {
+		t.Errorf("destination = %q, want %q", content, "hello")
+	}
+}
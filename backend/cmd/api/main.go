@@ -6,12 +6,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
+	_ "time/tzdata" // Schedule timezones resolve on images without a zoneinfo database
 
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/mail"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/shutdown"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/tracing"
 	"github.com/aetherpanel/aether-panel/internal/interfaces/http"
 	"go.uber.org/zap"
 )
@@ -28,6 +33,7 @@ func main() {
 	// Initialize logger
 	log := logger.NewLogger()
 	defer log.Sync()
+	zap.ReplaceGlobals(log) // Services log through logger.Ctx
 
 	log.Info("🔥 Starting Aether Panel API Server...")
 
@@ -36,6 +42,15 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to load configuration", zap.Error(err))
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// Initialize tracing (a no-op unless enabled)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
 
 	// Initialize database connection
 	db, err := database.NewPostgresConnection(cfg.Database)
@@ -44,6 +59,12 @@ func main() {
 	}
 	log.Info("✅ Database connection established")
 
+	if cfg.Tracing.Enabled {
+		if err := tracing.InstrumentDB(db); err != nil {
+			log.Fatal("Failed to instrument database", zap.Error(err))
+		}
+	}
+
 	// Run database migrations (temporarily disabled for quick fix)
 	// if err := database.AutoMigrate(db); err != nil {
 	// 	log.Fatal("Failed to run database migrations", zap.Error(err))
@@ -58,8 +79,49 @@ func main() {
 	}
 	log.Info("✅ Redis connection established")
 
+	// Announce new system events and audit logs to the admin feed
+	if err := database.PublishCreated(db, rdb, log); err != nil {
+		log.Fatal("Failed to register the event feed", zap.Error(err))
+	}
+
+	// Deliver new system events to the admin webhooks once committed
+	webhooks := services.NewWebhookService(database.NewWebhookRepository(db), cfg)
+	if err := database.NotifyCreatedEvents(db, webhooks.Notify); err != nil {
+		log.Fatal("Failed to register the event webhooks", zap.Error(err))
+	}
+	webhooks.Start()
+
+	// Start collecting live server stats from node agents
+	statsCtx, stopStats := context.WithCancel(context.Background())
+	defer stopStats()
+	agentClient := agent.NewClient(cfg.Agents, db)
+	hooks := agent.NewServerWebhooks(db, cfg.Webhooks, log)
+	notifier := agent.NewNotifier(db, rdb, mail.NewMailer(cfg.Mail), log)
+	go agent.NewNodeHealthChecker(agentClient, db, cfg.Agents, log).Start(statsCtx)
+	go agent.NewStatsCollector(agentClient, db, rdb, hooks, notifier, cfg.Agents, log).Start(statsCtx)
+	go agent.NewChatCollector(agentClient, db, rdb, cfg.Chat, log).Start(statsCtx)
+	go agent.NewImageWarmer(agentClient, db, rdb, cfg.Agents, log).Start(statsCtx)
+	agent.NewImageRollouts(agentClient, db, cfg.Agents, log).FailInterrupted(statsCtx)
+	go agent.NewStatusReconciler(agentClient, db, hooks, cfg.Agents, log).Start(statsCtx)
+	go agent.NewBackupScanner(agentClient, db, cfg.Agents, log).Start(statsCtx)
+
+	// Renew, suspend and expire subscriptions
+	go services.NewSubscriptionService(
+		database.NewSubscriptionRepository(db),
+		database.NewServerRepository(db),
+		database.NewAuditLogRepository(db),
+		database.NewNotificationRepository(db),
+		database.NewUnitOfWork(db, database.NewTxRepositories),
+		agentClient,
+		cfg,
+	).Start(statsCtx)
+
+	// Purge logs past their retention
+	go database.NewRetentionPurger(db, cfg.Retention, log).Start(statsCtx)
+
 	// Initialize HTTP server
-	server := http.NewServer(cfg, db, rdb, log)
+	ops := shutdown.New()
+	server := http.NewServer(cfg, db, rdb, log, ops)
 
 	// Start server in goroutine
 	go func() {
@@ -77,12 +139,30 @@ func main() {
 
 	log.Info("🛑 Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	// Refuse new WebSockets and long operations and close the open sockets,
+	// then stop listening and let in-flight requests and operations finish
+	closed := ops.Drain()
+	log.Info("Closed WebSocket connections", zap.Int("count", closed))
+
 	if err := server.ShutdownWithContext(ctx); err != nil {
 		log.Error("Server forced to shutdown", zap.Error(err))
 	}
+	if err := ops.Wait(ctx); err != nil {
+		log.Error("Operations cut off by shutdown", zap.Error(err))
+	}
+	stopStats()
+
+	// Deliver the system events still queued for the webhooks
+	if err := webhooks.Shutdown(ctx); err != nil {
+		log.Error("Webhook deliveries cut off by shutdown", zap.Error(err))
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("Failed to flush traces", zap.Error(err))
+	}
 
 	log.Info("👋 Server exited properly")
 }
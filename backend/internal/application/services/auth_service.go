@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -11,9 +13,12 @@ import (
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,6 +30,7 @@ var (
 	ErrTokenExpired       = errors.New("token has expired")
 	ErrInvalid2FACode     = errors.New("invalid 2FA code")
 	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
 )
 
 // AuthService handles authentication operations
@@ -33,6 +39,7 @@ type AuthService struct {
 	sessionRepo repositories.SessionRepository
 	auditRepo   repositories.AuditLogRepository
 	config      *config.Config
+	keys        *crypto.KeyRing
 }
 
 // NewAuthService creates a new AuthService
@@ -47,6 +54,7 @@ func NewAuthService(
 		sessionRepo: sessionRepo,
 		auditRepo:   auditRepo,
 		config:      cfg,
+		keys:        crypto.NewKeyRing(cfg.JWT),
 	}
 }
 
@@ -97,8 +105,10 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 		return nil, ErrAccountLocked
 	}
 
-	// Check if account is active
-	if !user.IsActive() {
+	// Check if account is active, accounts awaiting verification are told so
+	// once the password checks out
+	awaitingVerification := user.Status == entities.UserStatusPending && !user.EmailVerified
+	if !user.IsActive() && !awaitingVerification {
 		return nil, ErrAccountInactive
 	}
 
@@ -117,6 +127,12 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 		return nil, ErrInvalidCredentials
 	}
 
+	// Only accounts registered while verification was required wait for it,
+	// existing and admin-created accounts are not locked out by enabling it
+	if awaitingVerification {
+		return nil, ErrEmailNotVerified
+	}
+
 	// Check 2FA if enabled
 	if user.TwoFactorEnabled {
 		if req.TwoFACode == "" {
@@ -182,26 +198,34 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
 	return s.sessionRepo.RevokeAllByUserID(ctx, userID)
 }
 
-// RefreshToken refreshes an access token
+// RefreshToken refreshes an access token. Refresh tokens are single use: each
+// refresh replaces the session's token, and presenting a replaced one revokes
+// the session, as it means the token leaked. A replaced token is accepted
+// again within the JWT RefreshReuseGrace window, so a client retrying a
+// refresh whose response it lost is not logged out.
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
 	// Find session by refresh token
 	session, err := s.sessionRepo.GetByRefreshToken(ctx, refreshToken)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return s.refreshWithUsedToken(ctx, refreshToken)
 	}
 
 	if !session.IsValid() {
 		return nil, ErrTokenExpired
 	}
 
-	// Get user
-	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	user, err := s.refreshUser(ctx, session)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, err
 	}
 
-	if !user.IsActive() {
-		return nil, ErrAccountInactive
+	// Claim the token first, a concurrent refresh with it loses here
+	if err := s.sessionRepo.MarkRefreshTokenUsed(ctx, &entities.UsedRefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashRefreshToken(refreshToken),
+		UsedAt:    time.Now(),
+	}); err != nil {
+		return s.refreshWithUsedToken(ctx, refreshToken)
 	}
 
 	// Generate new tokens
@@ -222,14 +246,75 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*T
 	return tokens, nil
 }
 
+// refreshWithUsedToken handles a refresh token that is not the current one of
+// any session. Within the grace window of its rotation it returns the
+// session's current refresh token with a new access token, after that the
+// session is revoked.
+func (s *AuthService) refreshWithUsedToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	used, err := s.sessionRepo.GetUsedRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, used.SessionID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !session.IsValid() {
+		return nil, ErrTokenExpired
+	}
+
+	if time.Since(used.UsedAt) > s.config.JWT.RefreshReuseGrace {
+		if err := s.sessionRepo.Revoke(ctx, session.ID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		s.logAudit(ctx, session.UserID, entities.AuditActionLogout, "session", &session.ID, "", "")
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.refreshUser(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.generateTokenPair(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	// Keep the refresh token the first request rotated to
+	tokens.RefreshToken = session.RefreshToken
+	session.Token = tokens.AccessToken
+	session.LastActivity = time.Now()
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// refreshUser loads the user of a session being refreshed
+func (s *AuthService) refreshUser(ctx context.Context, session *entities.Session) (*entities.User, error) {
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !user.IsActive() {
+		return nil, ErrAccountInactive
+	}
+	return user, nil
+}
+
+// hashRefreshToken returns the hex SHA-256 of a refresh token, as used
+// refresh tokens are stored
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // ValidateToken validates an access token and returns claims
 func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWT.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.keys.Keyfunc)
 
 	if err != nil {
 		return nil, ErrInvalidToken
@@ -327,8 +412,7 @@ func (s *AuthService) generateTokenPair(user *entities.User) (*TokenPair, error)
 	}
 
 	// Generate access token
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWT.Secret))
+	accessTokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		return nil, err
 	}
@@ -358,7 +442,9 @@ func (s *AuthService) logAudit(ctx context.Context, userID uuid.UUID, action ent
 		IPAddress:  ip,
 		UserAgent:  ua,
 	}
-	_ = s.auditRepo.Create(ctx, log)
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", resource), zap.Error(err))
+	}
 }
 
 // HashPassword hashes a password using bcrypt
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// fakeSessions keeps sessions and used refresh tokens in memory
+type fakeSessions struct {
+	repositories.SessionRepository
+	sessions map[uuid.UUID]*entities.Session
+	used     map[string]*entities.UsedRefreshToken
+}
+
+func (f *fakeSessions) GetByID(ctx context.Context, id uuid.UUID) (*entities.Session, error) {
+	if session, ok := f.sessions[id]; ok {
+		copied := *session
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeSessions) Create(ctx context.Context, session *entities.Session) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	copied := *session
+	f.sessions[session.ID] = &copied
+	return nil
+}
+
+func (f *fakeSessions) GetByRefreshToken(ctx context.Context, refreshToken string) (*entities.Session, error) {
+	for _, session := range f.sessions {
+		if session.RefreshToken == refreshToken {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeSessions) Update(ctx context.Context, session *entities.Session) error {
+	copied := *session
+	f.sessions[session.ID] = &copied
+	return nil
+}
+
+func (f *fakeSessions) Revoke(ctx context.Context, id uuid.UUID) error {
+	if session, ok := f.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeSessions) MarkRefreshTokenUsed(ctx context.Context, used *entities.UsedRefreshToken) error {
+	if _, ok := f.used[used.TokenHash]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	copied := *used
+	f.used[used.TokenHash] = &copied
+	return nil
+}
+
+func (f *fakeSessions) GetUsedRefreshToken(ctx context.Context, tokenHash string) (*entities.UsedRefreshToken, error) {
+	if used, ok := f.used[tokenHash]; ok {
+		return used, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func newTestAuthService(t *testing.T) (*AuthService, *fakeSessions, *entities.Session) {
+	t.Helper()
+
+	user := &entities.User{ID: uuid.New(), Username: "player", Email: "player@example.com", Status: entities.UserStatusActive}
+	session := &entities.Session{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		Token:        "access-1",
+		RefreshToken: "refresh-1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	sessions := &fakeSessions{
+		sessions: map[uuid.UUID]*entities.Session{session.ID: session},
+		used:     map[string]*entities.UsedRefreshToken{},
+	}
+
+	cfg := &config.Config{JWT: config.JWTConfig{
+		Keys:              []config.JWTKey{{ID: "test", Secret: "test-secret"}},
+		CurrentKey:        "test",
+		AccessExpiry:      15 * time.Minute,
+		RefreshReuseGrace: 30 * time.Second,
+	}}
+	store := newFakeStore()
+	store.users[user.ID] = user
+	return NewAuthService(fakeUsers{store: store}, sessions, fakeAuditLogs{}, cfg), sessions, session
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	s, sessions, session := newTestAuthService(t)
+	ctx := context.Background()
+
+	tokens, err := s.RefreshToken(ctx, "refresh-1")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if tokens.RefreshToken == "refresh-1" {
+		t.Fatal("refresh token was not rotated")
+	}
+	if got := sessions.sessions[session.ID].RefreshToken; got != tokens.RefreshToken {
+		t.Errorf("session refresh token = %q, want %q", got, tokens.RefreshToken)
+	}
+
+	// A retry within the grace window gets the token the first refresh issued
+	retried, err := s.RefreshToken(ctx, "refresh-1")
+	if err != nil {
+		t.Fatalf("RefreshToken retry within grace: %v", err)
+	}
+	if retried.RefreshToken != tokens.RefreshToken {
+		t.Errorf("retry refresh token = %q, want %q", retried.RefreshToken, tokens.RefreshToken)
+	}
+	if sessions.sessions[session.ID].RevokedAt != nil {
+		t.Error("session revoked by a retry within the grace window")
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	s, sessions, session := newTestAuthService(t)
+	ctx := context.Background()
+
+	tokens, err := s.RefreshToken(ctx, "refresh-1")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+
+	// Replay the rotated token after the grace window
+	sessions.used[hashRefreshToken("refresh-1")].UsedAt = time.Now().Add(-time.Minute)
+	if _, err := s.RefreshToken(ctx, "refresh-1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RefreshToken with reused token = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if sessions.sessions[session.ID].RevokedAt == nil {
+		t.Fatal("session not revoked after refresh token reuse")
+	}
+
+	// The token the session was rotated to is revoked with it
+	if _, err := s.RefreshToken(ctx, tokens.RefreshToken); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("RefreshToken with current token after reuse = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestRefreshTokenUnknown(t *testing.T) {
+	s, _, _ := newTestAuthService(t)
+
+	if _, err := s.RefreshToken(context.Background(), "never-issued"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("RefreshToken with unknown token = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestLoginEmailVerificationGating(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newFakeStore()
+	addAccount := func(email string, status entities.UserStatus, verified bool) {
+		user := &entities.User{ID: uuid.New(), Email: email, Username: email, PasswordHash: string(hash), Status: status, EmailVerified: verified}
+		store.users[user.ID] = user
+	}
+	// Registered while verification was required
+	addAccount("pending@example.com", entities.UserStatusPending, false)
+	// Created before verification was enabled, or by an admin
+	addAccount("existing@example.com", entities.UserStatusActive, false)
+	addAccount("verified@example.com", entities.UserStatusActive, true)
+	addAccount("suspended@example.com", entities.UserStatusSuspended, true)
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			Keys:         []config.JWTKey{{ID: "test", Secret: "test-secret"}},
+			CurrentKey:   "test",
+			AccessExpiry: 15 * time.Minute,
+		},
+		Security: config.SecurityConfig{RequireEmailVerification: true, MaxLoginAttempts: 5},
+	}
+	sessions := &fakeSessions{sessions: map[uuid.UUID]*entities.Session{}, used: map[string]*entities.UsedRefreshToken{}}
+	s := NewAuthService(fakeUsers{store: store}, sessions, fakeAuditLogs{}, cfg)
+
+	for _, tc := range []struct {
+		email, password string
+		want            error
+	}{
+		{"pending@example.com", "correct horse", ErrEmailNotVerified},
+		{"pending@example.com", "wrong", ErrInvalidCredentials},
+		{"existing@example.com", "correct horse", nil},
+		{"verified@example.com", "correct horse", nil},
+		{"suspended@example.com", "correct horse", ErrAccountInactive},
+	} {
+		resp, err := s.Login(context.Background(), &LoginRequest{Email: tc.email, Password: tc.password})
+		if !errors.Is(err, tc.want) {
+			t.Errorf("Login(%s, %q) = %v, want %v", tc.email, tc.password, err, tc.want)
+			continue
+		}
+		if tc.want == nil && (resp.Tokens == nil || resp.Tokens.AccessToken == "") {
+			t.Errorf("Login(%s) returned no tokens", tc.email)
+		}
+	}
+	if len(sessions.sessions) != 2 {
+		t.Errorf("%d sessions created, want 2", len(sessions.sessions))
+	}
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+	ErrSelfTransfer        = errors.New("cannot transfer credits to yourself")
+	ErrTransferNotAllowed  = errors.New("transfer to this account is not allowed")
+	ErrTransferRateLimited = errors.New("too many transfers, try again later")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInsufficientCredits = repositories.ErrInsufficientCredits
+)
+
+// BillingService handles credits and billing operations
+type BillingService struct {
+	transactionRepo repositories.TransactionRepository
+	userRepo        repositories.UserRepository
+	auditRepo       repositories.AuditLogRepository
+	uow             repositories.UnitOfWork
+	config          config.BillingConfig
+}
+
+// NewBillingService creates a new BillingService
+func NewBillingService(
+	transactionRepo repositories.TransactionRepository,
+	userRepo repositories.UserRepository,
+	auditRepo repositories.AuditLogRepository,
+	uow repositories.UnitOfWork,
+	cfg *config.Config,
+) *BillingService {
+	return &BillingService{
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		auditRepo:       auditRepo,
+		uow:             uow,
+		config:          cfg.Billing,
+	}
+}
+
+// Transfer moves credits from one user to another. Both sides are recorded as
+// paired transfer transactions sharing a reference and pointing at each other.
+func (s *BillingService) Transfer(ctx context.Context, fromUserID, toUserID uuid.UUID, amount float64) (*entities.Transaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if fromUserID == toUserID {
+		return nil, ErrSelfTransfer
+	}
+
+	sender, err := s.userRepo.GetByID(ctx, fromUserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	receiver, err := s.userRepo.GetByID(ctx, toUserID)
+	if err != nil || !receiver.IsActive() {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.checkTransferScope(ctx, sender, receiver); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTransferLimit(ctx, fromUserID); err != nil {
+		return nil, err
+	}
+
+	var debit *entities.Transaction
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		debit, err = s.transfer(ctx, repos, sender, receiver, amount)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientCredits) {
+			return nil, ErrInsufficientCredits
+		}
+		return nil, fmt.Errorf("failed to transfer credits: %w", err)
+	}
+
+	s.auditTransfer(ctx, debit, receiver)
+	return debit, nil
+}
+
+// checkTransferLimit refuses transfers beyond the configured number per window
+func (s *BillingService) checkTransferLimit(ctx context.Context, userID uuid.UUID) error {
+	if s.config.TransferLimit <= 0 {
+		return nil
+	}
+	count, err := s.transactionRepo.CountOutgoingTransfers(ctx, userID, time.Now().Add(-s.config.TransferWindow))
+	if err != nil {
+		return err
+	}
+	if count >= int64(s.config.TransferLimit) {
+		return ErrTransferRateLimited
+	}
+	return nil
+}
+
+// transfer moves credits from sender to receiver with the repositories of a
+// unit of work, recording both sides as paired transfer transactions sharing a
+// reference. It returns the sender's side.
+func (s *BillingService) transfer(ctx context.Context, repos repositories.TxRepositories, sender, receiver *entities.User, amount float64) (*entities.Transaction, error) {
+	fromUserID, toUserID := sender.ID, receiver.ID
+	now := time.Now()
+	reference := "TRF-" + uuid.New().String()
+	debitID, creditID := uuid.New(), uuid.New()
+
+	debit := &entities.Transaction{
+		ID:            debitID,
+		UserID:        fromUserID,
+		Type:          entities.TransactionTypeTransfer,
+		Status:        entities.TransactionStatusCompleted,
+		Amount:        -amount,
+		Description:   fmt.Sprintf("Transfer to %s", receiver.Username),
+		Reference:     reference,
+		PaymentMethod: entities.PaymentMethodInternal,
+		PaymentDetails: map[string]interface{}{
+			"direction":             "outgoing",
+			"counterparty_id":       toUserID,
+			"paired_transaction_id": creditID,
+		},
+		ProcessedAt: &now,
+		ProcessedBy: &fromUserID,
+	}
+
+	credit := &entities.Transaction{
+		ID:            creditID,
+		UserID:        toUserID,
+		Type:          entities.TransactionTypeTransfer,
+		Status:        entities.TransactionStatusCompleted,
+		Amount:        amount,
+		Description:   fmt.Sprintf("Transfer from %s", sender.Username),
+		Reference:     reference,
+		PaymentMethod: entities.PaymentMethodInternal,
+		PaymentDetails: map[string]interface{}{
+			"direction":             "incoming",
+			"counterparty_id":       fromUserID,
+			"paired_transaction_id": debitID,
+		},
+		ProcessedAt: &now,
+		ProcessedBy: &fromUserID,
+	}
+
+	// Move the balance and record both sides
+	if err := repos.Users().UpdateCredits(ctx, fromUserID, -amount); err != nil {
+		return nil, err
+	}
+	from, err := repos.Users().GetByID(ctx, fromUserID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Credits < 0 {
+		return nil, ErrInsufficientCredits
+	}
+
+	if err := repos.Users().UpdateCredits(ctx, toUserID, amount); err != nil {
+		return nil, err
+	}
+	to, err := repos.Users().GetByID(ctx, toUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	debit.BalanceBefore, debit.BalanceAfter = from.Credits+amount, from.Credits
+	credit.BalanceBefore, credit.BalanceAfter = to.Credits-amount, to.Credits
+
+	if err := repos.Transactions().Create(ctx, debit); err != nil {
+		return nil, err
+	}
+	if err := repos.Transactions().Create(ctx, credit); err != nil {
+		return nil, err
+	}
+	return debit, nil
+}
+
+// auditTransfer records a committed transfer on the sender's audit log
+func (s *BillingService) auditTransfer(ctx context.Context, debit *entities.Transaction, receiver *entities.User) {
+	log := &entities.AuditLog{
+		UserID:      &debit.UserID,
+		Action:      entities.AuditActionUpdate,
+		Resource:    "transaction",
+		ResourceID:  &debit.ID,
+		Description: fmt.Sprintf("Transferred %.2f credits to %s", -debit.Amount, receiver.Username),
+		Metadata: map[string]interface{}{
+			"reference": debit.Reference,
+			"to_user":   receiver.ID,
+			"amount":    -debit.Amount,
+		},
+	}
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", log.Resource), zap.Error(err))
+	}
+}
+
+// checkTransferScope restricts resellers to transferring within their own sub-accounts
+func (s *BillingService) checkTransferScope(ctx context.Context, sender, receiver *entities.User) error {
+	if !s.config.ResellerTransfersOwnOnly {
+		return nil
+	}
+
+	subAccounts, err := s.userRepo.GetByResellerID(ctx, sender.ID)
+	if err != nil {
+		return err
+	}
+
+	// Users without sub-accounts are not resellers
+	if len(subAccounts) == 0 {
+		return nil
+	}
+
+	if receiver.ResellerID == nil || *receiver.ResellerID != sender.ID {
+		return ErrTransferNotAllowed
+	}
+	return nil
+}
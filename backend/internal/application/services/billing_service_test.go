@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// failingAuditLogs refuses every audit entry
+type failingAuditLogs struct {
+	repositories.AuditLogRepository
+}
+
+func (failingAuditLogs) Create(ctx context.Context, log *entities.AuditLog) error {
+	return errors.New("audit_logs: connection reset")
+}
+
+func newTestBillingService(store *fakeStore, audits repositories.AuditLogRepository, billing config.BillingConfig) *BillingService {
+	return NewBillingService(fakeTransactions{store: store}, fakeUsers{store: store}, audits, fakeUnitOfWork{store: store}, &config.Config{Billing: billing})
+}
+
+// addUser stores an active user holding credits
+func addUser(store *fakeStore, name string, credits float64) *entities.User {
+	user := &entities.User{ID: uuid.New(), Username: name, Credits: credits, Status: entities.UserStatusActive}
+	store.users[user.ID] = user
+	return user
+}
+
+func TestTransfer(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := addUser(store, "alice", 100), addUser(store, "bob", 5)
+	s := newTestBillingService(store, fakeAuditLogs{store: store}, config.BillingConfig{})
+
+	debit, err := s.Transfer(context.Background(), alice.ID, bob.ID, 40)
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if store.users[alice.ID].Credits != 60 || store.users[bob.ID].Credits != 45 {
+		t.Errorf("balances = %.2f and %.2f, want 60 and 45", store.users[alice.ID].Credits, store.users[bob.ID].Credits)
+	}
+
+	if len(store.transactions) != 2 {
+		t.Fatalf("recorded %d transactions, want 2", len(store.transactions))
+	}
+	credit := store.transactions[1]
+	if debit.Amount != -40 || debit.BalanceBefore != 100 || debit.BalanceAfter != 60 {
+		t.Errorf("debit = %.2f from %.2f to %.2f, want -40 from 100 to 60", debit.Amount, debit.BalanceBefore, debit.BalanceAfter)
+	}
+	if credit.UserID != bob.ID || credit.Amount != 40 || credit.BalanceBefore != 5 || credit.BalanceAfter != 45 {
+		t.Errorf("credit = %.2f for %s from %.2f to %.2f, want 40 for bob from 5 to 45", credit.Amount, credit.UserID, credit.BalanceBefore, credit.BalanceAfter)
+	}
+	if credit.Reference != debit.Reference || debit.PaymentDetails["paired_transaction_id"] != credit.ID || credit.PaymentDetails["paired_transaction_id"] != debit.ID {
+		t.Error("the two sides of the transfer do not point at each other")
+	}
+
+	if actions := store.audited(alice.ID); len(actions) != 1 || actions[0] != entities.AuditActionUpdate {
+		t.Errorf("audited %v for the sender, want one update", actions)
+	}
+}
+
+func TestTransferRejections(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := addUser(store, "alice", 100), addUser(store, "bob", 0)
+	suspended := addUser(store, "carol", 0)
+	suspended.Status = entities.UserStatusSuspended
+	s := newTestBillingService(store, fakeAuditLogs{store: store}, config.BillingConfig{})
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		to     uuid.UUID
+		amount float64
+		want   error
+	}{
+		"zero amount":         {bob.ID, 0, ErrInvalidAmount},
+		"to themselves":       {alice.ID, 10, ErrSelfTransfer},
+		"to a suspended user": {suspended.ID, 10, ErrUserNotFound},
+		"to a missing user":   {uuid.New(), 10, ErrUserNotFound},
+		"above their balance": {bob.ID, 150, ErrInsufficientCredits},
+		"negative amount":     {bob.ID, -10, ErrInvalidAmount},
+	} {
+		if _, err := s.Transfer(ctx, alice.ID, tc.to, tc.amount); !errors.Is(err, tc.want) {
+			t.Errorf("Transfer %s = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	if store.users[alice.ID].Credits != 100 || store.users[bob.ID].Credits != 0 || len(store.transactions) != 0 {
+		t.Errorf("rejected transfers left balances %.2f and %.2f with %d transactions", store.users[alice.ID].Credits, store.users[bob.ID].Credits, len(store.transactions))
+	}
+	if actions := store.audited(alice.ID); len(actions) != 0 {
+		t.Errorf("audited %v for rejected transfers", actions)
+	}
+}
+
+func TestTransferRateLimit(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := addUser(store, "alice", 100), addUser(store, "bob", 0)
+	s := newTestBillingService(store, fakeAuditLogs{}, config.BillingConfig{TransferLimit: 2, TransferWindow: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Transfer(ctx, alice.ID, bob.ID, 10); err != nil {
+			t.Fatalf("transfer %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.Transfer(ctx, alice.ID, bob.ID, 10); !errors.Is(err, ErrTransferRateLimited) {
+		t.Errorf("third transfer = %v, want %v", err, ErrTransferRateLimited)
+	}
+	if got := store.users[bob.ID].Credits; got != 20 {
+		t.Errorf("receiver credits = %.2f, want 20", got)
+	}
+}
+
+func TestTransferResellerScope(t *testing.T) {
+	store := newFakeStore()
+	reseller, outsider := addUser(store, "reseller", 100), addUser(store, "outsider", 10)
+	sub := addUser(store, "sub", 0)
+	sub.ResellerID = &reseller.ID
+	s := newTestBillingService(store, fakeAuditLogs{}, config.BillingConfig{ResellerTransfersOwnOnly: true})
+	ctx := context.Background()
+
+	if _, err := s.Transfer(ctx, reseller.ID, outsider.ID, 10); !errors.Is(err, ErrTransferNotAllowed) {
+		t.Errorf("transfer outside the reseller's accounts = %v, want %v", err, ErrTransferNotAllowed)
+	}
+	if _, err := s.Transfer(ctx, reseller.ID, sub.ID, 10); err != nil {
+		t.Errorf("transfer to a sub-account = %v", err)
+	}
+	// Users without sub-accounts are not limited
+	if _, err := s.Transfer(ctx, outsider.ID, reseller.ID, 5); err != nil {
+		t.Errorf("transfer from a regular user = %v", err)
+	}
+}
+
+func TestTransferLogsAuditFailures(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	store := newFakeStore()
+	alice, bob := addUser(store, "alice", 100), addUser(store, "bob", 0)
+	s := newTestBillingService(store, failingAuditLogs{}, config.BillingConfig{})
+
+	if _, err := s.Transfer(context.Background(), alice.ID, bob.ID, 10); err != nil {
+		t.Fatalf("Transfer with a failing audit log = %v, want the transfer to go through", err)
+	}
+	entries := logs.FilterMessage("Failed to write audit log").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d audit failures, want 1", len(entries))
+	}
+	if fields := entries[0].ContextMap(); fields["resource"] != "transaction" || fields["error"] == nil {
+		t.Errorf("log fields = %v, want the resource and the error", fields)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+)
+
+// newHistoryService returns a ServerService for a running server, recording
+// up to size commands per user in an in-memory Redis
+func newHistoryService(t *testing.T, size int) (*ServerService, *entities.Server) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	store := newFakeStore()
+	server := &entities.Server{ID: uuid.New(), OwnerID: uuid.New(), Status: entities.ServerStatusRunning}
+	store.servers[server.ID] = server
+	return &ServerService{
+		serverRepo:   fakeServers{store: store},
+		auditRepo:    fakeAuditLogs{store: store},
+		activityRepo: fakeActivityLogs{},
+		history:      redis.NewCommandHistory(rdb, size, time.Hour),
+		nodeClient:   &fakeNodeClient{},
+	}, server
+}
+
+func TestSendCommandRecordsHistory(t *testing.T) {
+	s, server := newHistoryService(t, 10)
+	ctx := context.Background()
+
+	for _, command := range []string{"say hi", "list", "list", "time set day"} {
+		if err := s.SendCommand(ctx, server.ID, command, server.OwnerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := s.CommandHistory(ctx, server.ID, server.OwnerID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Newest first, with the repeated command stored once
+	if want := []string{"time set day", "list", "say hi"}; !slices.Equal(history, want) {
+		t.Errorf("history %q, want %q", history, want)
+	}
+	if history, _ := s.CommandHistory(ctx, server.ID, server.OwnerID, 2); len(history) != 2 {
+		t.Errorf("history limited to 2 = %q", history)
+	}
+}
+
+func TestCommandHistoryIsCapped(t *testing.T) {
+	s, server := newHistoryService(t, 3)
+	ctx := context.Background()
+
+	for i := range 5 {
+		if err := s.SendCommand(ctx, server.ID, fmt.Sprintf("say %d", i), server.OwnerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := s.CommandHistory(ctx, server.ID, server.OwnerID, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"say 4", "say 3", "say 2"}; !slices.Equal(history, want) {
+		t.Errorf("history %q, want only the newest %q", history, want)
+	}
+}
+
+func TestCommandHistoryIsPerUser(t *testing.T) {
+	s, server := newHistoryService(t, 10)
+	ctx := context.Background()
+	subuser := uuid.New()
+
+	if err := s.SendCommand(ctx, server.ID, "op Steve", server.OwnerID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendCommand(ctx, server.ID, "say hi", subuser); err != nil {
+		t.Fatal(err)
+	}
+
+	for user, want := range map[uuid.UUID][]string{server.OwnerID: {"op Steve"}, subuser: {"say hi"}, uuid.New(): {}} {
+		history, err := s.CommandHistory(ctx, server.ID, user, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(history, want) {
+			t.Errorf("history of %s %q, want %q", user, history, want)
+		}
+	}
+	if history, _ := s.CommandHistory(ctx, uuid.New(), server.OwnerID, 0); len(history) != 0 {
+		t.Errorf("history on another server %q, want none", history)
+	}
+}
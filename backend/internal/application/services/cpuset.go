@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// ValidateCPUSet checks a CPU set such as "0-3,8" against the cores of a node.
+// An empty set is valid and leaves the server on its CPU quota alone.
+func ValidateCPUSet(set string, node *entities.Node) error {
+	if set == "" {
+		return nil
+	}
+
+	// Node capacity is tracked in percent, 100 per core
+	cores := node.CPUTotal / 100
+	if cores <= 0 {
+		return fmt.Errorf("%w: node core count is unknown", ErrInvalidCPUSet)
+	}
+
+	for _, part := range strings.Split(set, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 0 {
+			return fmt.Errorf("%w: invalid core %q", ErrInvalidCPUSet, lo)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil || end < start {
+				return fmt.Errorf("%w: invalid range %q", ErrInvalidCPUSet, part)
+			}
+		}
+		if end >= cores {
+			return fmt.Errorf("%w: core %d is out of range, node has %d cores", ErrInvalidCPUSet, end, cores)
+		}
+	}
+	return nil
+}
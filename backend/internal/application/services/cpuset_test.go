@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+func TestValidateCPUSet(t *testing.T) {
+	node := &entities.Node{CPUTotal: 800} // 8 cores
+
+	for set, valid := range map[string]bool{
+		"":        true,
+		"0":       true,
+		"0-3,7":   true,
+		"4-7":     true,
+		"8":       false,
+		"6-9":     false,
+		"3-1":     false,
+		"-1":      false,
+		"a":       false,
+		"0,,1":    false,
+		"0-3,8-9": false,
+	} {
+		err := ValidateCPUSet(set, node)
+		if valid && err != nil {
+			t.Errorf("ValidateCPUSet(%q) = %v, want valid", set, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidCPUSet) {
+			t.Errorf("ValidateCPUSet(%q) = %v, want %v", set, err, ErrInvalidCPUSet)
+		}
+	}
+
+	if err := ValidateCPUSet("0", &entities.Node{}); !errors.Is(err, ErrInvalidCPUSet) {
+		t.Errorf("set on a node with unknown cores = %v, want %v", err, ErrInvalidCPUSet)
+	}
+}
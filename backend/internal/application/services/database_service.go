@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDatabaseLimitReached    = errors.New("database limit reached")
+	ErrDatabaseNotFound        = errors.New("database not found")
+	ErrDatabaseExists          = errors.New("database already exists")
+	ErrDatabaseHostNotFound    = errors.New("database host not found")
+	ErrNoDatabaseHost          = errors.New("no database host available")
+	ErrDatabaseHostUnavailable = errors.New("database host failed the request")
+	ErrInvalidDatabaseName     = errors.New("database name may only contain letters, numbers and underscores")
+	ErrInvalidDatabaseRemote   = errors.New("invalid database remote")
+)
+
+var (
+	databaseNamePattern   = regexp.MustCompile(`^[A-Za-z0-9_]{1,48}$`)
+	databaseRemotePattern = regexp.MustCompile(`^[A-Za-z0-9.%_:-]{1,255}$`)
+)
+
+const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// DatabaseHostCredentials are the decrypted connection details of a database host
+type DatabaseHostCredentials struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// DatabaseProvisioner creates and drops databases and their users on a database host
+type DatabaseProvisioner interface {
+	CreateDatabase(ctx context.Context, host DatabaseHostCredentials, database, username, password, remote string) error
+	RotatePassword(ctx context.Context, host DatabaseHostCredentials, username, remote, password string) error
+	DropDatabase(ctx context.Context, host DatabaseHostCredentials, database, username, remote string) error
+}
+
+// Secrets encrypts values stored at rest
+type Secrets interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// DatabaseService provisions MySQL databases for servers
+type DatabaseService struct {
+	databaseRepo repositories.ServerDatabaseRepository
+	hostRepo     repositories.DatabaseHostRepository
+	serverRepo   repositories.ServerRepository
+	auditRepo    repositories.AuditLogRepository
+	provisioner  DatabaseProvisioner
+	secrets      Secrets
+}
+
+// NewDatabaseService creates a new DatabaseService
+func NewDatabaseService(
+	databaseRepo repositories.ServerDatabaseRepository,
+	hostRepo repositories.DatabaseHostRepository,
+	serverRepo repositories.ServerRepository,
+	auditRepo repositories.AuditLogRepository,
+	provisioner DatabaseProvisioner,
+	secrets Secrets,
+) *DatabaseService {
+	return &DatabaseService{
+		databaseRepo: databaseRepo,
+		hostRepo:     hostRepo,
+		serverRepo:   serverRepo,
+		auditRepo:    auditRepo,
+		provisioner:  provisioner,
+		secrets:      secrets,
+	}
+}
+
+// DatabaseCredentials is a server database with its plain text password, only
+// returned when the password is created or rotated
+type DatabaseCredentials struct {
+	*entities.ServerDatabase
+	Password string `json:"password"`
+}
+
+// Create provisions a database for a server within its database limit
+func (s *DatabaseService) Create(ctx context.Context, serverID uuid.UUID, name, remote string, userID uuid.UUID) (*DatabaseCredentials, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, ErrServerNotFound
+	}
+
+	if remote == "" {
+		remote = "%"
+	}
+	if err := ValidateDatabase(name, remote); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.databaseRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= server.DatabaseLimit {
+		return nil, ErrDatabaseLimitReached
+	}
+	for _, database := range existing {
+		if database.Database == DatabaseName(serverID, name) {
+			return nil, ErrDatabaseExists
+		}
+	}
+
+	hosts, err := s.hostRepo.GetForNode(ctx, server.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, ErrNoDatabaseHost
+	}
+	host := hosts[0]
+	creds, err := s.hostCredentials(host)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := GenerateDatabasePassword()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.secrets.Encrypt(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	database := &entities.ServerDatabase{
+		ServerID:          serverID,
+		DatabaseHostID:    host.ID,
+		Database:          DatabaseName(serverID, name),
+		Username:          DatabaseUsername(serverID),
+		Remote:            remote,
+		PasswordEncrypted: encrypted,
+	}
+
+	if err := s.provisioner.CreateDatabase(ctx, creds, database.Database, database.Username, password, remote); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseHostUnavailable, err)
+	}
+	if err := s.databaseRepo.Create(ctx, database); err != nil {
+		_ = s.provisioner.DropDatabase(ctx, creds, database.Database, database.Username, remote)
+		return nil, err
+	}
+
+	database.DatabaseHost = host
+	s.logAudit(ctx, userID, entities.AuditActionCreate, database)
+	return &DatabaseCredentials{ServerDatabase: database, Password: password}, nil
+}
+
+// List returns the databases of a server
+func (s *DatabaseService) List(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerDatabase, error) {
+	return s.databaseRepo.GetByServerID(ctx, serverID)
+}
+
+// RotatePassword gives a database user a new random password
+func (s *DatabaseService) RotatePassword(ctx context.Context, serverID uuid.UUID, databaseID uuid.UUID, userID uuid.UUID) (*DatabaseCredentials, error) {
+	database, host, err := s.getDatabase(ctx, serverID, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.hostCredentials(host)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := GenerateDatabasePassword()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.secrets.Encrypt(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := s.provisioner.RotatePassword(ctx, creds, database.Username, database.Remote, password); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseHostUnavailable, err)
+	}
+	database.PasswordEncrypted = encrypted
+	if err := s.databaseRepo.Update(ctx, database); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionUpdate, database)
+	return &DatabaseCredentials{ServerDatabase: database, Password: password}, nil
+}
+
+// Delete drops a database and its user, returning the dropped database
+func (s *DatabaseService) Delete(ctx context.Context, serverID uuid.UUID, databaseID uuid.UUID, userID uuid.UUID) (*entities.ServerDatabase, error) {
+	database, host, err := s.getDatabase(ctx, serverID, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.drop(ctx, database, host); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionDelete, database)
+	return database, nil
+}
+
+// DeleteAllForServer drops every database of a server, as part of deleting it
+func (s *DatabaseService) DeleteAllForServer(ctx context.Context, serverID uuid.UUID) error {
+	databases, err := s.databaseRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, database := range databases {
+		host, err := s.hostRepo.GetByID(ctx, database.DatabaseHostID)
+		if err != nil {
+			errs = append(errs, ErrDatabaseHostNotFound)
+			continue
+		}
+		if err := s.drop(ctx, database, host); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *DatabaseService) drop(ctx context.Context, database *entities.ServerDatabase, host *entities.DatabaseHost) error {
+	creds, err := s.hostCredentials(host)
+	if err != nil {
+		return err
+	}
+	if err := s.provisioner.DropDatabase(ctx, creds, database.Database, database.Username, database.Remote); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseHostUnavailable, err)
+	}
+	return s.databaseRepo.Delete(ctx, database.ID)
+}
+
+// getDatabase loads a database of a server and its host
+func (s *DatabaseService) getDatabase(ctx context.Context, serverID uuid.UUID, databaseID uuid.UUID) (*entities.ServerDatabase, *entities.DatabaseHost, error) {
+	database, err := s.databaseRepo.GetByID(ctx, databaseID)
+	if err != nil || database.ServerID != serverID {
+		return nil, nil, ErrDatabaseNotFound
+	}
+	host, err := s.hostRepo.GetByID(ctx, database.DatabaseHostID)
+	if err != nil {
+		return nil, nil, ErrDatabaseHostNotFound
+	}
+	return database, host, nil
+}
+
+func (s *DatabaseService) hostCredentials(host *entities.DatabaseHost) (DatabaseHostCredentials, error) {
+	password, err := s.secrets.Decrypt(host.PasswordEncrypted)
+	if err != nil {
+		return DatabaseHostCredentials{}, fmt.Errorf("failed to decrypt database host password: %w", err)
+	}
+	return DatabaseHostCredentials{
+		Host:     host.Host,
+		Port:     host.Port,
+		Username: host.Username,
+		Password: password,
+	}, nil
+}
+
+func (s *DatabaseService) logAudit(ctx context.Context, userID uuid.UUID, action entities.AuditAction, database *entities.ServerDatabase) {
+	log := &entities.AuditLog{
+		UserID:     &userID,
+		Action:     action,
+		Resource:   "database",
+		ResourceID: &database.ID,
+		Metadata:   map[string]interface{}{"server_id": database.ServerID, "database": database.Database},
+	}
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", "database"), zap.Error(err))
+	}
+}
+
+// ValidateDatabase checks a requested database name and remote
+func ValidateDatabase(name, remote string) error {
+	if !databaseNamePattern.MatchString(name) {
+		return ErrInvalidDatabaseName
+	}
+	if !databaseRemotePattern.MatchString(remote) {
+		return ErrInvalidDatabaseRemote
+	}
+	return nil
+}
+
+// DatabaseName returns the name of a server's database, prefixed with the
+// server so databases of different servers never collide on a host
+func DatabaseName(serverID uuid.UUID, name string) string {
+	return "s" + serverPrefix(serverID) + "_" + name
+}
+
+// DatabaseUsername returns a new random user name for a server's database.
+// MySQL 5.7 limits user names to 32 characters.
+func DatabaseUsername(serverID uuid.UUID) string {
+	suffix, _ := randomString(10, "abcdefghijklmnopqrstuvwxyz0123456789")
+	return "u" + serverPrefix(serverID) + "_" + suffix
+}
+
+// GenerateDatabasePassword returns a random 24 character password
+func GenerateDatabasePassword() (string, error) {
+	return randomString(24, passwordAlphabet)
+}
+
+func serverPrefix(serverID uuid.UUID) string {
+	return strings.ReplaceAll(serverID.String(), "-", "")[:8]
+}
+
+func randomString(n int, alphabet string) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	out := make([]byte, n)
+	for i := range out {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = alphabet[idx.Int64()]
+	}
+	return string(out), nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeServerDatabases keeps server databases in memory
+type fakeServerDatabases struct {
+	repositories.ServerDatabaseRepository
+	databases map[uuid.UUID]*entities.ServerDatabase
+}
+
+func (f fakeServerDatabases) Create(ctx context.Context, database *entities.ServerDatabase) error {
+	database.ID = uuid.New()
+	copied := *database
+	f.databases[database.ID] = &copied
+	return nil
+}
+
+func (f fakeServerDatabases) GetByID(ctx context.Context, id uuid.UUID) (*entities.ServerDatabase, error) {
+	if database, ok := f.databases[id]; ok {
+		copied := *database
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeServerDatabases) Update(ctx context.Context, database *entities.ServerDatabase) error {
+	copied := *database
+	f.databases[database.ID] = &copied
+	return nil
+}
+
+func (f fakeServerDatabases) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(f.databases, id)
+	return nil
+}
+
+func (f fakeServerDatabases) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerDatabase, error) {
+	var databases []*entities.ServerDatabase
+	for _, database := range f.databases {
+		if database.ServerID == serverID {
+			copied := *database
+			databases = append(databases, &copied)
+		}
+	}
+	return databases, nil
+}
+
+// fakeDatabaseHosts serves a single database host to every node
+type fakeDatabaseHosts struct {
+	repositories.DatabaseHostRepository
+	host *entities.DatabaseHost
+}
+
+func (f fakeDatabaseHosts) GetByID(ctx context.Context, id uuid.UUID) (*entities.DatabaseHost, error) {
+	if id != f.host.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.host, nil
+}
+
+func (f fakeDatabaseHosts) GetForNode(ctx context.Context, nodeID uuid.UUID) ([]*entities.DatabaseHost, error) {
+	return []*entities.DatabaseHost{f.host}, nil
+}
+
+// fakeProvisioner records the users and passwords on its host
+type fakeProvisioner struct {
+	passwords map[string]string
+	err       error
+}
+
+func (f *fakeProvisioner) CreateDatabase(ctx context.Context, host DatabaseHostCredentials, database, username, password, remote string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.passwords[username] = password
+	return nil
+}
+
+func (f *fakeProvisioner) RotatePassword(ctx context.Context, host DatabaseHostCredentials, username, remote, password string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.passwords[username] = password
+	return nil
+}
+
+func (f *fakeProvisioner) DropDatabase(ctx context.Context, host DatabaseHostCredentials, database, username, remote string) error {
+	if f.err != nil {
+		return f.err
+	}
+	delete(f.passwords, username)
+	return nil
+}
+
+// fakeSecrets marks values as encrypted without encrypting them
+type fakeSecrets struct{}
+
+func (fakeSecrets) Encrypt(plaintext string) (string, error) { return "enc:" + plaintext, nil }
+
+func (fakeSecrets) Decrypt(ciphertext string) (string, error) {
+	plaintext, ok := strings.CutPrefix(ciphertext, "enc:")
+	if !ok {
+		return "", errors.New("not encrypted")
+	}
+	return plaintext, nil
+}
+
+type databaseTest struct {
+	service     *DatabaseService
+	store       *fakeStore
+	databases   fakeServerDatabases
+	provisioner *fakeProvisioner
+	owner       uuid.UUID
+}
+
+func newDatabaseTest() *databaseTest {
+	store := newFakeStore()
+	dt := &databaseTest{
+		store:       store,
+		databases:   fakeServerDatabases{databases: map[uuid.UUID]*entities.ServerDatabase{}},
+		provisioner: &fakeProvisioner{passwords: map[string]string{}},
+		owner:       uuid.New(),
+	}
+	host := &entities.DatabaseHost{ID: uuid.New(), Host: "mysql.internal", Port: 3306, Username: "root", PasswordEncrypted: "enc:secret"}
+	dt.service = NewDatabaseService(dt.databases, fakeDatabaseHosts{host: host}, fakeServers{store: store},
+		fakeAuditLogs{store: store}, dt.provisioner, fakeSecrets{})
+	return dt
+}
+
+func (dt *databaseTest) addServer(limit int) *entities.Server {
+	server := &entities.Server{ID: uuid.New(), OwnerID: dt.owner, NodeID: uuid.New(), DatabaseLimit: limit}
+	dt.store.servers[server.ID] = server
+	return server
+}
+
+func TestDatabaseCreateWithinServerLimit(t *testing.T) {
+	dt := newDatabaseTest()
+	ctx := context.Background()
+	server := dt.addServer(2)
+	other := dt.addServer(1)
+
+	for _, name := range []string{"world", "stats"} {
+		if _, err := dt.service.Create(ctx, server.ID, name, "", dt.owner); err != nil {
+			t.Fatalf("Create(%s) = %v", name, err)
+		}
+	}
+	if _, err := dt.service.Create(ctx, server.ID, "extra", "", dt.owner); !errors.Is(err, ErrDatabaseLimitReached) {
+		t.Fatalf("Create over the limit = %v, want %v", err, ErrDatabaseLimitReached)
+	}
+	if len(dt.provisioner.passwords) != 2 {
+		t.Errorf("provisioned %d users, want 2", len(dt.provisioner.passwords))
+	}
+
+	// The limit is counted per server
+	if _, err := dt.service.Create(ctx, other.ID, "world", "", dt.owner); err != nil {
+		t.Errorf("Create on another server = %v", err)
+	}
+	if _, err := dt.service.Create(ctx, other.ID, "stats", "", dt.owner); !errors.Is(err, ErrDatabaseLimitReached) {
+		t.Errorf("Create over the other server's limit = %v, want %v", err, ErrDatabaseLimitReached)
+	}
+
+	none := dt.addServer(0)
+	if _, err := dt.service.Create(ctx, none.ID, "world", "", dt.owner); !errors.Is(err, ErrDatabaseLimitReached) {
+		t.Errorf("Create without databases allowed = %v, want %v", err, ErrDatabaseLimitReached)
+	}
+}
+
+func TestDatabaseCreate(t *testing.T) {
+	dt := newDatabaseTest()
+	ctx := context.Background()
+	server := dt.addServer(3)
+
+	created, err := dt.service.Create(ctx, server.ID, "world", "", dt.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Remote != "%" || !strings.HasSuffix(created.Database, "_world") {
+		t.Errorf("created %s from %s", created.Database, created.Remote)
+	}
+	if dt.provisioner.passwords[created.Username] != created.Password || created.PasswordEncrypted != "enc:"+created.Password {
+		t.Error("returned password differs from the provisioned or stored one")
+	}
+	if got := dt.store.audited(dt.owner); len(got) != 1 || got[0] != entities.AuditActionCreate {
+		t.Errorf("audited %v, want a create", got)
+	}
+
+	if _, err := dt.service.Create(ctx, server.ID, "world", "", dt.owner); !errors.Is(err, ErrDatabaseExists) {
+		t.Errorf("Create of an existing name = %v, want %v", err, ErrDatabaseExists)
+	}
+	if _, err := dt.service.Create(ctx, server.ID, "drop;table", "", dt.owner); !errors.Is(err, ErrInvalidDatabaseName) {
+		t.Errorf("Create with an invalid name = %v, want %v", err, ErrInvalidDatabaseName)
+	}
+
+	dt.provisioner.err = errors.New("access denied for user 'root'")
+	if _, err := dt.service.Create(ctx, server.ID, "stats", "", dt.owner); !errors.Is(err, ErrDatabaseHostUnavailable) {
+		t.Errorf("Create on a failing host = %v, want %v", err, ErrDatabaseHostUnavailable)
+	}
+	if len(dt.databases.databases) != 1 {
+		t.Errorf("stored %d databases, want only the first", len(dt.databases.databases))
+	}
+}
+
+func TestDatabaseRotatePassword(t *testing.T) {
+	dt := newDatabaseTest()
+	ctx := context.Background()
+	server := dt.addServer(1)
+	created, err := dt.service.Create(ctx, server.ID, "world", "", dt.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := dt.service.RotatePassword(ctx, server.ID, created.ID, dt.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Password == created.Password {
+		t.Error("password was not changed")
+	}
+	if dt.provisioner.passwords[created.Username] != rotated.Password {
+		t.Error("host has a different password than the one returned")
+	}
+	if stored := dt.databases.databases[created.ID]; stored.PasswordEncrypted != "enc:"+rotated.Password {
+		t.Error("stored password was not updated")
+	}
+
+	// A database is only found through its own server
+	if _, err := dt.service.RotatePassword(ctx, dt.addServer(1).ID, created.ID, dt.owner); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("RotatePassword through another server = %v, want %v", err, ErrDatabaseNotFound)
+	}
+
+	dt.provisioner.err = errors.New("connection refused")
+	if _, err := dt.service.RotatePassword(ctx, server.ID, created.ID, dt.owner); !errors.Is(err, ErrDatabaseHostUnavailable) {
+		t.Errorf("RotatePassword on a failing host = %v, want %v", err, ErrDatabaseHostUnavailable)
+	}
+	if stored := dt.databases.databases[created.ID]; stored.PasswordEncrypted != "enc:"+rotated.Password {
+		t.Error("stored password changed although the host kept the old one")
+	}
+}
+
+func TestDatabaseDelete(t *testing.T) {
+	dt := newDatabaseTest()
+	ctx := context.Background()
+	server := dt.addServer(2)
+	created, err := dt.service.Create(ctx, server.ID, "world", "", dt.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dt.service.Delete(ctx, dt.addServer(1).ID, created.ID, dt.owner); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Fatalf("Delete through another server = %v, want %v", err, ErrDatabaseNotFound)
+	}
+
+	dt.provisioner.err = errors.New("connection refused")
+	if _, err := dt.service.Delete(ctx, server.ID, created.ID, dt.owner); !errors.Is(err, ErrDatabaseHostUnavailable) {
+		t.Fatalf("Delete on a failing host = %v, want %v", err, ErrDatabaseHostUnavailable)
+	}
+	if _, ok := dt.databases.databases[created.ID]; !ok {
+		t.Fatal("database was forgotten although the host still has it")
+	}
+
+	dt.provisioner.err = nil
+	deleted, err := dt.service.Delete(ctx, server.ID, created.ID, dt.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != created.ID || len(dt.databases.databases) != 0 || len(dt.provisioner.passwords) != 0 {
+		t.Error("database was not dropped and forgotten")
+	}
+	if got := dt.store.audited(dt.owner); len(got) != 2 || got[1] != entities.AuditActionDelete {
+		t.Errorf("audited %v, want a create and a delete", got)
+	}
+
+	// The freed slot can be used again
+	if _, err := dt.service.Create(ctx, server.ID, "world", "", dt.owner); err != nil {
+		t.Errorf("Create after Delete = %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var (
+	ErrInvalidEggLimits     = errors.New("invalid egg resource limits")
+	ErrResourceOutOfBounds  = errors.New("resource outside the egg's limits")
+	ErrResourceNotSpecified = errors.New("resource not specified")
+)
+
+// ValidateEggLimits checks that each resource's bounds are ordered as
+// minimum, recommended, maximum
+func ValidateEggLimits(limits entities.EggLimits) error {
+	for _, r := range eggResources(limits) {
+		b := r.bounds
+		if b.Min < 0 || b.Recommended < 0 || b.Max < 0 {
+			return fmt.Errorf("%w: %s bounds may not be negative", ErrInvalidEggLimits, r.name)
+		}
+		if b.Max > 0 && b.Min > b.Max {
+			return fmt.Errorf("%w: %s minimum %d is above its maximum %d", ErrInvalidEggLimits, r.name, b.Min, b.Max)
+		}
+		if b.Recommended > 0 && (b.Recommended < b.Min || (b.Max > 0 && b.Recommended > b.Max)) {
+			return fmt.Errorf("%w: %s recommended %d is outside %s", ErrInvalidEggLimits, r.name, b.Recommended, describeBounds(b, r.unit))
+		}
+	}
+	return nil
+}
+
+// ApplyEggLimits fills resources left at 0 with the egg's recommended values
+// and checks every resource against the egg's bounds. It fails when a
+// resource is outside its bounds, or omitted with no recommendation.
+func ApplyEggLimits(limits entities.EggLimits, memory, disk, cpu *int) error {
+	values := []*int{memory, disk, cpu}
+	for i, r := range eggResources(limits) {
+		if *values[i] == 0 {
+			*values[i] = r.bounds.Recommended
+		}
+		if *values[i] == 0 {
+			return fmt.Errorf("%w: %s is required", ErrResourceNotSpecified, r.name)
+		}
+	}
+	return CheckEggLimits(limits, *memory, *disk, *cpu)
+}
+
+// CheckEggLimits checks a server's resources against its egg's bounds
+func CheckEggLimits(limits entities.EggLimits, memory, disk, cpu int) error {
+	values := []int{memory, disk, cpu}
+	for i, r := range eggResources(limits) {
+		b, v := r.bounds, values[i]
+		if (b.Min > 0 && v < b.Min) || (b.Max > 0 && v > b.Max) {
+			return fmt.Errorf("%w: %s of %d%s is outside the egg's %s", ErrResourceOutOfBounds, r.name, v, r.unit, describeBounds(b, r.unit))
+		}
+	}
+	return nil
+}
+
+type eggResource struct {
+	name   string
+	unit   string
+	bounds entities.ResourceBounds
+}
+
+func eggResources(limits entities.EggLimits) []eggResource {
+	return []eggResource{
+		{"memory", " MB", limits.Memory},
+		{"disk", " MB", limits.Disk},
+		{"cpu", "%", limits.CPU},
+	}
+}
+
+func describeBounds(b entities.ResourceBounds, unit string) string {
+	switch {
+	case b.Min > 0 && b.Max > 0:
+		return fmt.Sprintf("range of %d%s to %d%s", b.Min, unit, b.Max, unit)
+	case b.Min > 0:
+		return fmt.Sprintf("minimum of %d%s", b.Min, unit)
+	case b.Max > 0:
+		return fmt.Sprintf("maximum of %d%s", b.Max, unit)
+	}
+	return "limits"
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// minecraftLimits bounds memory and CPU and recommends memory and disk
+var minecraftLimits = entities.EggLimits{
+	Memory: entities.ResourceBounds{Min: 1024, Recommended: 4096, Max: 16384},
+	Disk:   entities.ResourceBounds{Recommended: 10240},
+	CPU:    entities.ResourceBounds{Min: 50, Max: 400},
+}
+
+func TestApplyEggLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		memory, disk, cpu int
+		want              [3]int
+		err               error
+		message           string
+	}{
+		{"recommended defaults", 0, 0, 100, [3]int{4096, 10240, 100}, nil, ""},
+		{"requested values kept", 2048, 20480, 200, [3]int{2048, 20480, 200}, nil, ""},
+		{"bounds inclusive", 1024, 1, 400, [3]int{1024, 1, 400}, nil, ""},
+		{"memory below minimum", 512, 0, 100, [3]int{}, ErrResourceOutOfBounds, "memory of 512 MB is outside the egg's range of 1024 MB to 16384 MB"},
+		{"cpu below minimum", 0, 0, 25, [3]int{}, ErrResourceOutOfBounds, "cpu of 25% is outside the egg's range of 50% to 400%"},
+		{"memory above maximum", 32768, 0, 100, [3]int{}, ErrResourceOutOfBounds, "memory of 32768 MB"},
+		{"omitted without recommendation", 0, 0, 0, [3]int{}, ErrResourceNotSpecified, "cpu is required"},
+	}
+	for _, tt := range tests {
+		memory, disk, cpu := tt.memory, tt.disk, tt.cpu
+		err := ApplyEggLimits(minecraftLimits, &memory, &disk, &cpu)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: error %v, want %v mentioning %q", tt.name, err, tt.err, tt.message)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := [3]int{memory, disk, cpu}; got != tt.want {
+			t.Errorf("%s: memory, disk and cpu = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEggLimits(t *testing.T) {
+	if err := ValidateEggLimits(minecraftLimits); err != nil {
+		t.Errorf("valid limits: %v", err)
+	}
+	if err := ValidateEggLimits(entities.EggLimits{}); err != nil {
+		t.Errorf("no limits: %v", err)
+	}
+	for name, limits := range map[string]entities.EggLimits{
+		"negative":                  {Disk: entities.ResourceBounds{Min: -1}},
+		"minimum above maximum":     {Memory: entities.ResourceBounds{Min: 4096, Max: 2048}},
+		"recommended below minimum": {CPU: entities.ResourceBounds{Min: 100, Recommended: 50}},
+		"recommended above maximum": {Memory: entities.ResourceBounds{Recommended: 8192, Max: 4096}},
+	} {
+		if err := ValidateEggLimits(limits); !errors.Is(err, ErrInvalidEggLimits) {
+			t.Errorf("%s: error %v, want %v", name, err, ErrInvalidEggLimits)
+		}
+	}
+}
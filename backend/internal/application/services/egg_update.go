@@ -0,0 +1,44 @@
+package services
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// EggChanged reports whether an edit to an egg changes what servers are built
+// from, and so needs a new egg version
+func EggChanged(old, updated *entities.Egg) bool {
+	return old.StartupCommand != updated.StartupCommand || !slices.Equal(old.DockerImages, updated.DockerImages)
+}
+
+// ReapplyEgg builds a server's startup and image from the current version of
+// its egg. The server's variable values are kept; variables the egg gained
+// since the server was built get their default. The server keeps its image
+// while the egg still offers it, otherwise it moves to the egg's first image.
+func ReapplyEgg(egg *entities.Egg, eggVars []*entities.EggVariable, server *entities.Server, allocations []*entities.Allocation) (*Startup, string) {
+	environment := maps.Clone(server.Environment)
+	if environment == nil {
+		environment = make(map[string]string, len(eggVars))
+	}
+	for _, v := range eggVars {
+		if _, ok := environment[v.EnvVariable]; !ok {
+			environment[v.EnvVariable] = v.DefaultValue
+		}
+	}
+	startup := NewStartupBuilder(egg, server, allocations, environment).Build()
+
+	image := server.DockerImage
+	if !slices.Contains(egg.DockerImages, image) && len(egg.DockerImages) > 0 {
+		image = egg.DockerImages[0]
+	}
+	return startup, image
+}
+
+// EggOutdated reports whether reapplying the egg would change the server's
+// startup command or image
+func EggOutdated(egg *entities.Egg, eggVars []*entities.EggVariable, server *entities.Server, allocations []*entities.Allocation) bool {
+	startup, image := ReapplyEgg(egg, eggVars, server, allocations)
+	return startup.Command != server.StartupCmd || image != server.DockerImage
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// reapplyEgg is version 2 of an egg that gained a DIFFICULTY variable and a
+// --nogui flag, and dropped the game:1 image
+var reapplyEgg = &entities.Egg{
+	StartupCommand: "./run --world {{WORLD}} --difficulty {{DIFFICULTY}} --port {{SERVER_PORT}} --nogui",
+	DockerImages:   []string{"game:2", "game:3"},
+	Version:        2,
+}
+
+var reapplyVars = []*entities.EggVariable{
+	{EnvVariable: "WORLD", DefaultValue: "survival"},
+	{EnvVariable: "DIFFICULTY", DefaultValue: "normal"},
+}
+
+func TestReapplyEggKeepsVariableValues(t *testing.T) {
+	primary := &entities.Allocation{ID: uuid.New(), IP: "203.0.113.10", Port: 25565}
+	server := &entities.Server{
+		AllocationID: primary.ID,
+		StartupCmd:   "./run --world hardcore --port 25565",
+		DockerImage:  "game:1",
+		Environment:  map[string]string{"WORLD": "hardcore", "MOTD": "welcome"},
+	}
+
+	startup, image := ReapplyEgg(reapplyEgg, reapplyVars, server, []*entities.Allocation{primary})
+	if want := "./run --world hardcore --difficulty normal --port 25565 --nogui"; startup.Command != want {
+		t.Errorf("command %q, want %q", startup.Command, want)
+	}
+	for name, want := range map[string]string{"WORLD": "hardcore", "DIFFICULTY": "normal", "MOTD": "welcome"} {
+		if got := startup.Environment[name]; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if server.Environment["DIFFICULTY"] != "" {
+		t.Error("reapplying changed the server's environment in place")
+	}
+	if image != "game:2" {
+		t.Errorf("image %s, want the egg's first image in place of the dropped game:1", image)
+	}
+
+	server.DockerImage = "game:3"
+	if _, image := ReapplyEgg(reapplyEgg, reapplyVars, server, []*entities.Allocation{primary}); image != "game:3" {
+		t.Errorf("image %s, want game:3 kept as the egg still offers it", image)
+	}
+}
+
+func TestEggOutdated(t *testing.T) {
+	primary := &entities.Allocation{ID: uuid.New(), IP: "203.0.113.10", Port: 25565}
+	current := "./run --world survival --difficulty hard --port 25565 --nogui"
+	for name, tc := range map[string]struct {
+		server   entities.Server
+		outdated bool
+	}{
+		"built from the new version": {entities.Server{StartupCmd: current, DockerImage: "game:2", Environment: map[string]string{"DIFFICULTY": "hard"}}, false},
+		"older command":              {entities.Server{StartupCmd: "./run --world survival --port 25565", DockerImage: "game:2", Environment: map[string]string{"DIFFICULTY": "hard"}}, true},
+		"dropped image":              {entities.Server{StartupCmd: current, DockerImage: "game:1", Environment: map[string]string{"DIFFICULTY": "hard"}}, true},
+	} {
+		server := tc.server
+		server.AllocationID = primary.ID
+		if got := EggOutdated(reapplyEgg, reapplyVars, &server, []*entities.Allocation{primary}); got != tc.outdated {
+			t.Errorf("%s: outdated = %v, want %v", name, got, tc.outdated)
+		}
+	}
+}
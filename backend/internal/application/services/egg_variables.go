@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var (
+	ErrVariableNotFound    = errors.New("variable not found")
+	ErrVariableNotEditable = errors.New("variable is not editable")
+)
+
+// VariableError reports an egg variable whose value is missing or breaks its rules
+type VariableError struct {
+	Variable string `json:"variable"`
+	Message  string `json:"message"`
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("variable %s %s", e.Variable, e.Message)
+}
+
+// ResolvedVariable is the value chosen for an egg variable on a server
+type ResolvedVariable struct {
+	Variable *entities.EggVariable
+	Value    string
+}
+
+// ResolveEggVariables merges egg defaults with user supplied values keyed by
+// environment variable name. User values win over defaults, every value is
+// checked against the variable's rules and a required variable that ends up
+// empty is rejected. Variables whose condition is not met are not checked. The
+// returned environment also keeps overrides that are not egg variables.
+func ResolveEggVariables(vars []*entities.EggVariable, overrides map[string]string) ([]ResolvedVariable, map[string]string, error) {
+	active := ActiveVariables(vars, overrides)
+
+	sorted := make([]*entities.EggVariable, len(vars))
+	copy(sorted, vars)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SortOrder < sorted[j].SortOrder
+	})
+
+	env := make(map[string]string, len(overrides)+len(sorted))
+	for k, v := range overrides {
+		env[k] = v
+	}
+
+	resolved := make([]ResolvedVariable, 0, len(sorted))
+	for _, v := range sorted {
+		value := variableValue(v, overrides)
+		if active[v.EnvVariable] {
+			if err := ValidateVariableValue(v.Rules, value); err != nil {
+				return nil, nil, &VariableError{Variable: v.EnvVariable, Message: err.Error()}
+			}
+		}
+
+		env[v.EnvVariable] = value
+		resolved = append(resolved, ResolvedVariable{Variable: v, Value: value})
+	}
+
+	return resolved, env, nil
+}
+
+// ServerVariableValue is an egg variable of a server with its current value
+type ServerVariableValue struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	EnvVariable  string `json:"env_variable"`
+	DefaultValue string `json:"default_value"`
+	Value        string `json:"value"`
+	Rules        string `json:"rules"`
+	Editable     bool   `json:"editable"`
+	Active       bool   `json:"active"` // Whether its condition is met, only false for admins
+}
+
+// VisibleServerVariables returns the egg variables a user may see, with their
+// current values taken from the server environment. Variables whose condition
+// is not met are hidden. Admins see every variable.
+func VisibleServerVariables(vars []*entities.EggVariable, env map[string]string, admin bool) []ServerVariableValue {
+	active := ActiveVariables(vars, env)
+
+	sorted := make([]*entities.EggVariable, len(vars))
+	copy(sorted, vars)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SortOrder < sorted[j].SortOrder
+	})
+
+	visible := make([]ServerVariableValue, 0, len(sorted))
+	for _, v := range sorted {
+		if !admin && (!v.UserViewable || !active[v.EnvVariable]) {
+			continue
+		}
+		visible = append(visible, ServerVariableValue{
+			Name:         v.Name,
+			Description:  v.Description,
+			EnvVariable:  v.EnvVariable,
+			DefaultValue: v.DefaultValue,
+			Value:        variableValue(v, env),
+			Rules:        v.Rules,
+			Editable:     admin || v.UserEditable,
+			Active:       active[v.EnvVariable],
+		})
+	}
+	return visible
+}
+
+// ResolveVariableEdits checks edits keyed by environment variable name and
+// returns the variables whose value changes. Variables hidden from the user,
+// including those whose condition the edits leave unmet, are reported as not
+// found and only admins may edit non-editable ones. Conditions are evaluated
+// with the edits applied, and a variable the edits bring into effect has its
+// current value checked too.
+func ResolveVariableEdits(vars []*entities.EggVariable, env map[string]string, edits map[string]string, admin bool) ([]ResolvedVariable, error) {
+	byEnv := make(map[string]*entities.EggVariable, len(vars))
+	for _, v := range vars {
+		byEnv[v.EnvVariable] = v
+	}
+
+	edited := maps.Clone(env)
+	if edited == nil {
+		edited = make(map[string]string, len(edits))
+	}
+	maps.Copy(edited, edits)
+	before := ActiveVariables(vars, env)
+	after := ActiveVariables(vars, edited)
+
+	names := make([]string, 0, len(edits))
+	for name := range edits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changed := make([]ResolvedVariable, 0, len(edits))
+	for _, name := range names {
+		v, ok := byEnv[name]
+		if !ok || (!admin && (!v.UserViewable || !after[name])) {
+			return nil, fmt.Errorf("%w: %s", ErrVariableNotFound, name)
+		}
+		if !admin && !v.UserEditable {
+			return nil, fmt.Errorf("%w: %s", ErrVariableNotEditable, name)
+		}
+
+		value := edits[name]
+		if after[name] {
+			if err := ValidateVariableValue(v.Rules, value); err != nil {
+				return nil, &VariableError{Variable: name, Message: err.Error()}
+			}
+		}
+		if current, ok := env[name]; ok && current == value {
+			continue
+		}
+		changed = append(changed, ResolvedVariable{Variable: v, Value: value})
+	}
+
+	for _, v := range vars {
+		if _, ok := edits[v.EnvVariable]; ok || before[v.EnvVariable] || !after[v.EnvVariable] {
+			continue
+		}
+		if err := ValidateVariableValue(v.Rules, variableValue(v, edited)); err != nil {
+			return nil, &VariableError{Variable: v.EnvVariable, Message: err.Error()}
+		}
+	}
+	return changed, nil
+}
+
+// ActiveVariables reports by environment variable name which egg variables
+// have their condition met, reading values from env and falling back to egg
+// defaults. A variable applies while the variable it depends on applies and
+// holds one of its values, so a whole chain drops out once its first
+// condition fails. Variables in a dependency cycle never apply.
+func ActiveVariables(vars []*entities.EggVariable, env map[string]string) map[string]bool {
+	byEnv := make(map[string]*entities.EggVariable, len(vars))
+	for _, v := range vars {
+		byEnv[v.EnvVariable] = v
+	}
+
+	active := make(map[string]bool, len(vars))
+	visiting := make(map[string]bool)
+	var resolve func(v *entities.EggVariable) bool
+	resolve = func(v *entities.EggVariable) bool {
+		if result, ok := active[v.EnvVariable]; ok {
+			return result
+		}
+		if visiting[v.EnvVariable] {
+			return false
+		}
+		visiting[v.EnvVariable] = true
+
+		result := true
+		if v.DependsOn != "" {
+			parent, ok := byEnv[v.DependsOn]
+			result = ok && resolve(parent) &&
+				containsString(strings.Split(v.DependsValue, ","), variableValue(parent, env))
+		}
+		active[v.EnvVariable] = result
+		return result
+	}
+
+	for _, v := range vars {
+		resolve(v)
+	}
+	return active
+}
+
+// variableValue returns the value of an egg variable in env, its default when
+// env does not set it
+func variableValue(v *entities.EggVariable, env map[string]string) string {
+	if value, ok := env[v.EnvVariable]; ok {
+		return value
+	}
+	return v.DefaultValue
+}
+
+// ValidateVariableValue checks a value against pipe separated egg rules such as
+// "required|integer|min:1|max:100". Unknown rules are ignored.
+func ValidateVariableValue(rules, value string) error {
+	if strings.TrimSpace(rules) == "" {
+		return nil
+	}
+
+	parts := strings.Split(rules, "|")
+	numeric := false
+	for _, rule := range parts {
+		switch strings.TrimSpace(rule) {
+		case "numeric", "integer":
+			numeric = true
+		}
+	}
+
+	for _, rule := range parts {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), ":")
+
+		if value == "" {
+			if name == "required" {
+				return fmt.Errorf("is required")
+			}
+			// Remaining rules only apply to non-empty values
+			continue
+		}
+
+		switch name {
+		case "integer":
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+		case "numeric":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("must be a number")
+			}
+		case "boolean":
+			switch value {
+			case "0", "1", "true", "false":
+			default:
+				return fmt.Errorf("must be a boolean")
+			}
+		case "in":
+			if !containsString(strings.Split(param, ","), value) {
+				return fmt.Errorf("must be one of: %s", param)
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			size := float64(len(value))
+			if numeric {
+				size, _ = strconv.ParseFloat(value, 64)
+			}
+			if name == "min" && size < limit {
+				return fmt.Errorf("must be at least %s", param)
+			}
+			if name == "max" && size > limit {
+				return fmt.Errorf("must be at most %s", param)
+			}
+		case "regex":
+			pattern := strings.Trim(param, "/")
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			if !re.MatchString(value) {
+				return fmt.Errorf("has an invalid format")
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// gameVariables are the variables of a game egg: a player cap with a default
+// and a world name that must be given
+func gameVariables() []*entities.EggVariable {
+	return []*entities.EggVariable{
+		{EnvVariable: "MAX_PLAYERS", DefaultValue: "20", Rules: "required|integer|max:100", SortOrder: 1},
+		{EnvVariable: "WORLD", Rules: "required|string", SortOrder: 0},
+	}
+}
+
+func TestResolveEggVariablesUsesDefaults(t *testing.T) {
+	resolved, env, err := ResolveEggVariables(gameVariables(), map[string]string{"WORLD": "survival"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["MAX_PLAYERS"] != "20" || env["WORLD"] != "survival" {
+		t.Errorf("environment %v, want the MAX_PLAYERS default and the given WORLD", env)
+	}
+	if len(resolved) != 2 || resolved[0].Variable.EnvVariable != "WORLD" || resolved[1].Value != "20" {
+		t.Errorf("resolved %+v, want both variables in sort order", resolved)
+	}
+}
+
+func TestResolveEggVariablesOverridesWin(t *testing.T) {
+	_, env, err := ResolveEggVariables(gameVariables(), map[string]string{"WORLD": "survival", "MAX_PLAYERS": "64", "EXTRA": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["MAX_PLAYERS"] != "64" {
+		t.Errorf("MAX_PLAYERS = %q, want the override 64", env["MAX_PLAYERS"])
+	}
+	if env["EXTRA"] != "1" {
+		t.Errorf("EXTRA = %q, want overrides that are not egg variables kept", env["EXTRA"])
+	}
+
+	// Overrides are checked against the rules
+	_, _, err = ResolveEggVariables(gameVariables(), map[string]string{"WORLD": "survival", "MAX_PLAYERS": "500"})
+	var varErr *VariableError
+	if !errors.As(err, &varErr) || varErr.Variable != "MAX_PLAYERS" {
+		t.Errorf("MAX_PLAYERS over its max = %v, want a MAX_PLAYERS variable error", err)
+	}
+}
+
+func TestResolveEggVariablesRejectsMissingRequired(t *testing.T) {
+	_, _, err := ResolveEggVariables(gameVariables(), nil)
+	var varErr *VariableError
+	if !errors.As(err, &varErr) || varErr.Variable != "WORLD" || varErr.Message != "is required" {
+		t.Errorf("missing WORLD = %v, want it reported as required", err)
+	}
+
+	if _, _, err := ResolveEggVariables(gameVariables(), map[string]string{"WORLD": ""}); !errors.As(err, &varErr) {
+		t.Errorf("empty WORLD = %v, want it rejected", err)
+	}
+}
+
+// modpackVariables are the variables of an egg whose modpack settings only
+// apply to modded servers
+func modpackVariables() []*entities.EggVariable {
+	return []*entities.EggVariable{
+		{EnvVariable: "MODE", DefaultValue: "vanilla", Rules: "required|in:vanilla,modded,forge", UserViewable: true, UserEditable: true},
+		{EnvVariable: "MODPACK", Rules: "required", DependsOn: "MODE", DependsValue: "modded,forge", UserViewable: true, UserEditable: true, SortOrder: 1},
+		{EnvVariable: "MODPACK_URL", Rules: "required|regex:/^https:/", DependsOn: "MODPACK", DependsValue: "custom", UserViewable: true, UserEditable: true, SortOrder: 2},
+		// Depending on each other, neither ever applies
+		{EnvVariable: "LOOP_A", DependsOn: "LOOP_B", UserViewable: true, SortOrder: 3},
+		{EnvVariable: "LOOP_B", DependsOn: "LOOP_A", UserViewable: true, SortOrder: 4},
+	}
+}
+
+func TestActiveVariables(t *testing.T) {
+	for _, tc := range []struct {
+		env  map[string]string
+		want map[string]bool
+	}{
+		{nil, map[string]bool{"MODE": true}},
+		{map[string]string{"MODE": "forge"}, map[string]bool{"MODE": true, "MODPACK": true}},
+		{map[string]string{"MODE": "modded", "MODPACK": "custom"}, map[string]bool{"MODE": true, "MODPACK": true, "MODPACK_URL": true}},
+		// The chain drops out at its first unmet condition
+		{map[string]string{"MODE": "vanilla", "MODPACK": "custom"}, map[string]bool{"MODE": true}},
+	} {
+		active := ActiveVariables(modpackVariables(), tc.env)
+		for _, v := range modpackVariables() {
+			if active[v.EnvVariable] != tc.want[v.EnvVariable] {
+				t.Errorf("%v: %s active %v, want %v", tc.env, v.EnvVariable, active[v.EnvVariable], tc.want[v.EnvVariable])
+			}
+		}
+	}
+}
+
+func TestResolveEggVariablesValidatesMetConditions(t *testing.T) {
+	// The required modpack is not asked for on a vanilla server
+	if _, _, err := ResolveEggVariables(modpackVariables(), nil); err != nil {
+		t.Errorf("vanilla server = %v, want the modpack left unchecked", err)
+	}
+
+	var varErr *VariableError
+	if _, _, err := ResolveEggVariables(modpackVariables(), map[string]string{"MODE": "modded"}); !errors.As(err, &varErr) || varErr.Variable != "MODPACK" {
+		t.Errorf("modded server without a modpack = %v, want a MODPACK variable error", err)
+	}
+	_, _, err := ResolveEggVariables(modpackVariables(), map[string]string{"MODE": "modded", "MODPACK": "custom", "MODPACK_URL": "ftp://example.com/pack.zip"})
+	if !errors.As(err, &varErr) || varErr.Variable != "MODPACK_URL" {
+		t.Errorf("custom modpack with an ftp URL = %v, want a MODPACK_URL variable error", err)
+	}
+	if _, _, err := ResolveEggVariables(modpackVariables(), map[string]string{"MODE": "modded", "MODPACK": "rlcraft"}); err != nil {
+		t.Errorf("modded server with a modpack = %v", err)
+	}
+}
+
+func TestVisibleServerVariablesHidesUnmetConditions(t *testing.T) {
+	names := func(visible []ServerVariableValue) []string {
+		var out []string
+		for _, v := range visible {
+			out = append(out, v.EnvVariable)
+		}
+		return out
+	}
+
+	if got := names(VisibleServerVariables(modpackVariables(), map[string]string{"MODE": "vanilla"}, false)); !slices.Equal(got, []string{"MODE"}) {
+		t.Errorf("user sees %v on a vanilla server, want only MODE", got)
+	}
+	if got := names(VisibleServerVariables(modpackVariables(), map[string]string{"MODE": "forge"}, false)); !slices.Equal(got, []string{"MODE", "MODPACK"}) {
+		t.Errorf("user sees %v on a forge server, want MODE and MODPACK", got)
+	}
+
+	// Admins see every variable, marked with whether it applies
+	admin := VisibleServerVariables(modpackVariables(), map[string]string{"MODE": "vanilla"}, true)
+	if len(admin) != 5 || !admin[0].Active || admin[1].Active || admin[3].Active {
+		t.Errorf("admin sees %+v, want all five with only MODE active", admin)
+	}
+}
+
+func TestResolveVariableEditsFollowConditions(t *testing.T) {
+	env := map[string]string{"MODE": "vanilla"}
+
+	// A variable whose condition stays unmet cannot be set by users
+	if _, err := ResolveVariableEdits(modpackVariables(), env, map[string]string{"MODPACK": "rlcraft"}, false); !errors.Is(err, ErrVariableNotFound) {
+		t.Errorf("setting MODPACK on a vanilla server = %v, want %v", err, ErrVariableNotFound)
+	}
+
+	// Switching to modded brings the required modpack into effect
+	var varErr *VariableError
+	if _, err := ResolveVariableEdits(modpackVariables(), env, map[string]string{"MODE": "modded"}, false); !errors.As(err, &varErr) || varErr.Variable != "MODPACK" {
+		t.Errorf("switching to modded without a modpack = %v, want a MODPACK variable error", err)
+	}
+
+	changed, err := ResolveVariableEdits(modpackVariables(), env, map[string]string{"MODE": "modded", "MODPACK": "rlcraft"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 || changed[0].Variable.EnvVariable != "MODE" || changed[1].Value != "rlcraft" {
+		t.Errorf("changed %+v, want MODE and MODPACK", changed)
+	}
+}
@@ -0,0 +1,806 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeStore holds the rows behind the in-memory repositories below. The
+// repositories embed their interface, so methods a test does not expect to
+// be called panic.
+type fakeStore struct {
+	users         map[uuid.UUID]*entities.User
+	roles         map[uuid.UUID]*entities.Role
+	quotas        map[uuid.UUID]*entities.UserQuota
+	servers       map[uuid.UUID]*entities.Server
+	nodes         map[uuid.UUID]*entities.Node
+	allocations   []*entities.Allocation
+	backups       []*entities.Backup
+	eggs          map[uuid.UUID]*entities.Egg
+	packages      map[uuid.UUID]*entities.Package
+	subscriptions map[uuid.UUID]*entities.Subscription
+	transactions  []*entities.Transaction
+	invoices      []*entities.Invoice
+	notifications []*entities.Notification
+	audits        []*entities.AuditLog
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:         map[uuid.UUID]*entities.User{},
+		roles:         map[uuid.UUID]*entities.Role{},
+		quotas:        map[uuid.UUID]*entities.UserQuota{},
+		servers:       map[uuid.UUID]*entities.Server{},
+		nodes:         map[uuid.UUID]*entities.Node{},
+		eggs:          map[uuid.UUID]*entities.Egg{},
+		packages:      map[uuid.UUID]*entities.Package{},
+		subscriptions: map[uuid.UUID]*entities.Subscription{},
+	}
+}
+
+// clone copies the store deeply enough to restore it after a rollback. Eggs
+// and packages are only read and are shared.
+func (s *fakeStore) clone() *fakeStore {
+	c := newFakeStore()
+	c.eggs, c.packages = s.eggs, s.packages
+	for id, user := range s.users {
+		copied := *user
+		c.users[id] = &copied
+	}
+	for id, role := range s.roles {
+		copied := *role
+		c.roles[id] = &copied
+	}
+	for id, quota := range s.quotas {
+		copied := *quota
+		c.quotas[id] = &copied
+	}
+	for id, server := range s.servers {
+		copied := *server
+		c.servers[id] = &copied
+	}
+	for id, node := range s.nodes {
+		copied := *node
+		c.nodes[id] = &copied
+	}
+	for id, sub := range s.subscriptions {
+		copied := *sub
+		c.subscriptions[id] = &copied
+	}
+	for _, allocation := range s.allocations {
+		copied := *allocation
+		c.allocations = append(c.allocations, &copied)
+	}
+	for _, backup := range s.backups {
+		copied := *backup
+		c.backups = append(c.backups, &copied)
+	}
+	c.transactions = append(c.transactions, s.transactions...)
+	c.invoices = append(c.invoices, s.invoices...)
+	c.notifications = append(c.notifications, s.notifications...)
+	c.audits = append(c.audits, s.audits...)
+	return c
+}
+
+// notified returns the types of the notifications sent to a user
+func (s *fakeStore) notified(userID uuid.UUID) []string {
+	var kinds []string
+	for _, n := range s.notifications {
+		if n.UserID == userID {
+			kinds = append(kinds, n.Type)
+		}
+	}
+	return kinds
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	store *fakeStore
+}
+
+func (f fakeUsers) GetByID(ctx context.Context, id uuid.UUID) (*entities.User, error) {
+	if user, ok := f.store.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeUsers) find(match func(user *entities.User) bool) (*entities.User, error) {
+	for _, user := range f.store.users {
+		if match(user) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeUsers) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	return f.find(func(user *entities.User) bool { return user.Email == email })
+}
+
+func (f fakeUsers) GetByUsername(ctx context.Context, username string) (*entities.User, error) {
+	return f.find(func(user *entities.User) bool { return user.Username == username })
+}
+
+func (f fakeUsers) Create(ctx context.Context, user *entities.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	copied := *user
+	f.store.users[user.ID] = &copied
+	return nil
+}
+
+func (f fakeUsers) GetByResellerID(ctx context.Context, resellerID uuid.UUID) ([]*entities.User, error) {
+	var users []*entities.User
+	for _, user := range f.store.users {
+		if user.ResellerID != nil && *user.ResellerID == resellerID {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	return users, nil
+}
+
+func (f fakeUsers) UpdateCredits(ctx context.Context, id uuid.UUID, amount float64) error {
+	user, ok := f.store.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Credits += amount
+	return nil
+}
+
+func (f fakeUsers) Update(ctx context.Context, user *entities.User) error {
+	if _, ok := f.store.users[user.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	copied := *user
+	f.store.users[user.ID] = &copied
+	return nil
+}
+
+func (f fakeUsers) IncrementFailedLogin(ctx context.Context, id uuid.UUID) error {
+	if user, ok := f.store.users[id]; ok {
+		user.FailedLoginCount++
+	}
+	return nil
+}
+
+func (f fakeUsers) ResetFailedLogin(ctx context.Context, id uuid.UUID) error {
+	if user, ok := f.store.users[id]; ok {
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+	}
+	return nil
+}
+
+func (f fakeUsers) UpdateLastLogin(ctx context.Context, id uuid.UUID, ip string) error {
+	if user, ok := f.store.users[id]; ok {
+		now := time.Now()
+		user.LastLoginAt = &now
+		user.LastLoginIP = ip
+	}
+	return nil
+}
+
+type fakeRoles struct {
+	repositories.RoleRepository
+	store *fakeStore
+}
+
+func (f fakeRoles) GetByID(ctx context.Context, id uuid.UUID) (*entities.Role, error) {
+	if role, ok := f.store.roles[id]; ok {
+		copied := *role
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeRoles) GetDefault(ctx context.Context) (*entities.Role, error) {
+	for _, role := range f.store.roles {
+		if role.IsDefault {
+			copied := *role
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeQuotas struct {
+	repositories.UserQuotaRepository
+	store *fakeStore
+}
+
+func (f fakeQuotas) GetByUserID(ctx context.Context, userID uuid.UUID) (*entities.UserQuota, error) {
+	if quota, ok := f.store.quotas[userID]; ok {
+		copied := *quota
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeQuotas) Upsert(ctx context.Context, quota *entities.UserQuota) error {
+	copied := *quota
+	f.store.quotas[quota.UserID] = &copied
+	return nil
+}
+
+type fakeServers struct {
+	repositories.ServerRepository
+	store *fakeStore
+}
+
+func (f fakeServers) GetByID(ctx context.Context, id uuid.UUID) (*entities.Server, error) {
+	if server, ok := f.store.servers[id]; ok {
+		copied := *server
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeServers) Create(ctx context.Context, server *entities.Server) error {
+	if server.ID == uuid.Nil {
+		server.ID = uuid.New()
+	}
+	copied := *server
+	f.store.servers[server.ID] = &copied
+	return nil
+}
+
+func (f fakeServers) CountByOwnerID(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	servers, err := f.GetByOwnerID(ctx, ownerID)
+	return int64(len(servers)), err
+}
+
+func (f fakeServers) GetByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*entities.Server, error) {
+	servers, _, err := f.GetByOwnerIDs(ctx, []uuid.UUID{ownerID}, repositories.ListParams{})
+	return servers, err
+}
+
+func (f fakeServers) GetByOwnerIDs(ctx context.Context, ownerIDs []uuid.UUID, params repositories.ListParams) ([]*entities.Server, int64, error) {
+	var servers []*entities.Server
+	for _, server := range f.store.servers {
+		if slices.Contains(ownerIDs, server.OwnerID) {
+			copied := *server
+			servers = append(servers, &copied)
+		}
+	}
+	return servers, int64(len(servers)), nil
+}
+
+func (f fakeServers) GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Server, error) {
+	var servers []*entities.Server
+	for _, server := range f.store.servers {
+		if server.NodeID == nodeID {
+			copied := *server
+			servers = append(servers, &copied)
+		}
+	}
+	return servers, nil
+}
+
+func (f fakeServers) Update(ctx context.Context, server *entities.Server) error {
+	if _, ok := f.store.servers[server.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	copied := *server
+	f.store.servers[server.ID] = &copied
+	return nil
+}
+
+func (f fakeServers) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, ok := f.store.servers[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(f.store.servers, id)
+	return nil
+}
+
+func (f fakeServers) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.ServerStatus) error {
+	server, ok := f.store.servers[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	server.Status = status
+	return nil
+}
+
+func (f fakeServers) Suspend(ctx context.Context, id uuid.UUID, reason string) error {
+	server, ok := f.store.servers[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	server.Suspended = true
+	server.SuspendedReason = reason
+	server.Status = entities.ServerStatusSuspended
+	return nil
+}
+
+type fakeNodes struct {
+	repositories.NodeRepository
+	store *fakeStore
+}
+
+func (f fakeNodes) GetByID(ctx context.Context, id uuid.UUID) (*entities.Node, error) {
+	if node, ok := f.store.nodes[id]; ok {
+		copied := *node
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeNodes) GetAvailable(ctx context.Context, memoryRequired, diskRequired int64) ([]*entities.Node, error) {
+	var nodes []*entities.Node
+	for _, node := range f.store.nodes {
+		if node.IsOnline && !node.MaintenanceMode && node.AvailableMemory() >= memoryRequired && node.AvailableDisk() >= diskRequired {
+			copied := *node
+			nodes = append(nodes, &copied)
+		}
+	}
+	return nodes, nil
+}
+
+// UpdateResources adds to the allocated resources of a node, as the GORM
+// repository does
+func (f fakeNodes) UpdateResources(ctx context.Context, id uuid.UUID, memoryAlloc, diskAlloc int64, cpuAlloc int) error {
+	node, ok := f.store.nodes[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	node.MemoryAllocated += memoryAlloc
+	node.DiskAllocated += diskAlloc
+	node.CPUAllocated += cpuAlloc
+	return nil
+}
+
+func (f fakeNodes) SetMaintenanceMode(ctx context.Context, id uuid.UUID, maintenance bool) error {
+	node, ok := f.store.nodes[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	node.MaintenanceMode = maintenance
+	return nil
+}
+
+type fakeBackups struct {
+	repositories.BackupRepository
+	store *fakeStore
+}
+
+func (f fakeBackups) Create(ctx context.Context, backup *entities.Backup) error {
+	backup.ID = uuid.New()
+	copied := *backup
+	f.store.backups = append(f.store.backups, &copied)
+	return nil
+}
+
+func (f fakeBackups) Update(ctx context.Context, backup *entities.Backup) error {
+	for i, stored := range f.store.backups {
+		if stored.ID == backup.ID {
+			copied := *backup
+			f.store.backups[i] = &copied
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (f fakeBackups) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.BackupStatus) error {
+	for _, backup := range f.store.backups {
+		if backup.ID == id {
+			backup.Status = status
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (f fakeBackups) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.Backup, error) {
+	var backups []*entities.Backup
+	for _, backup := range f.store.backups {
+		if backup.ServerID == serverID {
+			copied := *backup
+			backups = append(backups, &copied)
+		}
+	}
+	return backups, nil
+}
+
+func (f fakeBackups) CountByServerID(ctx context.Context, serverID uuid.UUID) (int64, error) {
+	var count int64
+	for _, backup := range f.store.backups {
+		if backup.ServerID == serverID && backup.Status != entities.BackupStatusFailed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type fakeAllocations struct {
+	repositories.AllocationRepository
+	store *fakeStore
+}
+
+func (f fakeAllocations) CreateBatch(ctx context.Context, allocations []*entities.Allocation) error {
+	for _, allocation := range allocations {
+		allocation.ID = uuid.New()
+	}
+	f.store.allocations = append(f.store.allocations, allocations...)
+	return nil
+}
+
+func (f fakeAllocations) GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	for _, a := range f.store.allocations {
+		if a.NodeID == nodeID {
+			copied := *a
+			allocations = append(allocations, &copied)
+		}
+	}
+	return allocations, nil
+}
+
+func (f fakeAllocations) AssignToServer(ctx context.Context, id uuid.UUID, serverID uuid.UUID, isPrimary bool) error {
+	for _, a := range f.store.allocations {
+		if a.ID == id && a.ServerID == nil {
+			a.ServerID = &serverID
+			a.IsPrimary = isPrimary
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (f fakeAllocations) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	for _, a := range f.store.allocations {
+		if a.ServerID != nil && *a.ServerID == serverID {
+			copied := *a
+			allocations = append(allocations, &copied)
+		}
+	}
+	return allocations, nil
+}
+
+func (f fakeAllocations) Unassign(ctx context.Context, id uuid.UUID) error {
+	for _, a := range f.store.allocations {
+		if a.ID == id {
+			a.ServerID = nil
+			a.IsPrimary = false
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (f fakeAllocations) IsPortAvailable(ctx context.Context, nodeID uuid.UUID, ip string, port int) (bool, error) {
+	for _, a := range f.store.allocations {
+		if a.NodeID == nodeID && a.IP == ip && a.Port == port {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f fakeAllocations) GetOnOtherNodes(ctx context.Context, nodeID uuid.UUID, ip string, portStart, portEnd int) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	for _, a := range f.store.allocations {
+		if a.NodeID != nodeID && a.IP == ip && a.Port >= portStart && a.Port <= portEnd {
+			allocations = append(allocations, a)
+		}
+	}
+	return allocations, nil
+}
+
+type fakeSubscriptions struct {
+	repositories.SubscriptionRepository
+	store *fakeStore
+}
+
+func (f fakeSubscriptions) find(match func(sub *entities.Subscription) bool) []*entities.Subscription {
+	var subs []*entities.Subscription
+	for _, sub := range f.store.subscriptions {
+		if match(sub) {
+			copied := *sub
+			subs = append(subs, &copied)
+		}
+	}
+	return subs
+}
+
+func (f fakeSubscriptions) Create(ctx context.Context, sub *entities.Subscription) error {
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	copied := *sub
+	f.store.subscriptions[sub.ID] = &copied
+	return nil
+}
+
+func (f fakeSubscriptions) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Subscription, error) {
+	return f.find(func(sub *entities.Subscription) bool { return sub.UserID == userID }), nil
+}
+
+func (f fakeSubscriptions) GetExpiring(ctx context.Context, before time.Time) ([]*entities.Subscription, error) {
+	return f.find(func(sub *entities.Subscription) bool {
+		return sub.Status == entities.SubscriptionStatusActive && sub.EndDate.Before(before)
+	}), nil
+}
+
+func (f fakeSubscriptions) GetExpired(ctx context.Context) ([]*entities.Subscription, error) {
+	now := time.Now()
+	return f.find(func(sub *entities.Subscription) bool {
+		return (sub.Status == entities.SubscriptionStatusActive || sub.Status == entities.SubscriptionStatusCancelled) &&
+			sub.EndDate.Before(now)
+	}), nil
+}
+
+func (f fakeSubscriptions) Update(ctx context.Context, sub *entities.Subscription) error {
+	copied := *sub
+	f.store.subscriptions[sub.ID] = &copied
+	return nil
+}
+
+func (f fakeSubscriptions) Suspend(ctx context.Context, id uuid.UUID) error {
+	sub, ok := f.store.subscriptions[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	sub.Status = entities.SubscriptionStatusSuspended
+	sub.SuspendedAt = &now
+	return nil
+}
+
+func (f fakeSubscriptions) Renew(ctx context.Context, id uuid.UUID, newEndDate time.Time) error {
+	sub, ok := f.store.subscriptions[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	sub.Status = entities.SubscriptionStatusActive
+	sub.EndDate = newEndDate
+	sub.NextBillingDate = &newEndDate
+	sub.PaymentFailedAt = nil
+	return nil
+}
+
+func (f fakeSubscriptions) MarkPaymentFailed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	sub, ok := f.store.subscriptions[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	sub.PaymentFailedAt = &at
+	return nil
+}
+
+type fakePackages struct {
+	repositories.PackageRepository
+	store *fakeStore
+}
+
+func (f fakePackages) GetByID(ctx context.Context, id uuid.UUID) (*entities.Package, error) {
+	if pkg, ok := f.store.packages[id]; ok {
+		copied := *pkg
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeEggs struct {
+	repositories.EggRepository
+	store *fakeStore
+}
+
+func (f fakeEggs) GetByID(ctx context.Context, id uuid.UUID) (*entities.Egg, error) {
+	if egg, ok := f.store.eggs[id]; ok {
+		copied := *egg
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeEggVariables has no variables for any egg
+type fakeEggVariables struct {
+	repositories.EggVariableRepository
+}
+
+func (fakeEggVariables) GetByEggID(ctx context.Context, eggID uuid.UUID) ([]*entities.EggVariable, error) {
+	return nil, nil
+}
+
+type fakeTransactions struct {
+	repositories.TransactionRepository
+	store *fakeStore
+}
+
+func (f fakeTransactions) Create(ctx context.Context, tx *entities.Transaction) error {
+	if tx.ID == uuid.Nil {
+		tx.ID = uuid.New()
+	}
+	f.store.transactions = append(f.store.transactions, tx)
+	return nil
+}
+
+func (f fakeTransactions) CountOutgoingTransfers(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	for _, tx := range f.store.transactions {
+		if tx.UserID == userID && tx.Type == entities.TransactionTypeTransfer && tx.Amount < 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type fakeInvoices struct {
+	repositories.InvoiceRepository
+	store *fakeStore
+}
+
+func (f fakeInvoices) Create(ctx context.Context, invoice *entities.Invoice) error {
+	f.store.invoices = append(f.store.invoices, invoice)
+	return nil
+}
+
+func (f fakeInvoices) GenerateNumber(ctx context.Context) (string, error) {
+	return "INV-" + uuid.NewString()[:8], nil
+}
+
+type fakeNotifications struct {
+	repositories.NotificationRepository
+	store *fakeStore
+}
+
+func (f fakeNotifications) Create(ctx context.Context, notification *entities.Notification) error {
+	f.store.notifications = append(f.store.notifications, notification)
+	return nil
+}
+
+// fakeAuditLogs records audit entries in its store, or discards them without one
+type fakeAuditLogs struct {
+	repositories.AuditLogRepository
+	store *fakeStore
+}
+
+func (f fakeAuditLogs) Create(ctx context.Context, log *entities.AuditLog) error {
+	if f.store != nil {
+		f.store.audits = append(f.store.audits, log)
+	}
+	return nil
+}
+
+// audited returns the audit actions recorded for a user
+func (s *fakeStore) audited(userID uuid.UUID) []entities.AuditAction {
+	var actions []entities.AuditAction
+	for _, log := range s.audits {
+		if log.UserID != nil && *log.UserID == userID {
+			actions = append(actions, log.Action)
+		}
+	}
+	return actions
+}
+
+// fakeActivityLogs discards activity entries
+type fakeActivityLogs struct {
+	repositories.ActivityLogRepository
+}
+
+func (fakeActivityLogs) Create(ctx context.Context, log *entities.ActivityLog) error {
+	return nil
+}
+
+// fakeCommandHistory discards command history
+type fakeCommandHistory struct {
+	CommandHistory
+}
+
+func (fakeCommandHistory) Push(ctx context.Context, serverID uuid.UUID, userID uuid.UUID, command string) error {
+	return nil
+}
+
+// fakeUnitOfWork runs fn on the store's repositories and restores the store
+// when fn fails, like a rolled back transaction
+type fakeUnitOfWork struct {
+	store *fakeStore
+}
+
+func (u fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repositories.TxRepositories) error) error {
+	snapshot := u.store.clone()
+	if err := fn(ctx, fakeTxRepositories{u.store}); err != nil {
+		*u.store = *snapshot
+		return err
+	}
+	return nil
+}
+
+type fakeTxRepositories struct {
+	store *fakeStore
+}
+
+func (r fakeTxRepositories) Users() repositories.UserRepository {
+	return fakeUsers{store: r.store}
+}
+
+func (r fakeTxRepositories) UserQuotas() repositories.UserQuotaRepository {
+	return fakeQuotas{store: r.store}
+}
+
+func (r fakeTxRepositories) Servers() repositories.ServerRepository {
+	return fakeServers{store: r.store}
+}
+
+func (r fakeTxRepositories) Nodes() repositories.NodeRepository {
+	return fakeNodes{store: r.store}
+}
+
+func (r fakeTxRepositories) Allocations() repositories.AllocationRepository {
+	return fakeAllocations{store: r.store}
+}
+
+func (r fakeTxRepositories) ServerVariables() repositories.ServerVariableRepository {
+	return nil
+}
+
+func (r fakeTxRepositories) Transactions() repositories.TransactionRepository {
+	return fakeTransactions{store: r.store}
+}
+
+func (r fakeTxRepositories) Subscriptions() repositories.SubscriptionRepository {
+	return fakeSubscriptions{store: r.store}
+}
+
+func (r fakeTxRepositories) Invoices() repositories.InvoiceRepository {
+	return fakeInvoices{store: r.store}
+}
+
+// fakeNodeClient records the servers it was asked to start and stop, the
+// commands it was asked to send and the node calls in order. Backups fail
+// with backupErr when it is set.
+type fakeNodeClient struct {
+	NodeClient
+	started   []uuid.UUID
+	stopped   []uuid.UUID
+	commands  []string
+	calls     []string
+	backupErr error
+}
+
+func (f *fakeNodeClient) CreateBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID) (*BackupArchive, error) {
+	f.calls = append(f.calls, "backup")
+	if f.backupErr != nil {
+		return nil, f.backupErr
+	}
+	return &BackupArchive{Size: 1024, Checksum: "sha256"}, nil
+}
+
+func (f *fakeNodeClient) ReinstallServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, wipeData bool) error {
+	call := "reinstall"
+	if wipeData {
+		call = "reinstall wipe"
+	}
+	f.calls = append(f.calls, call)
+	return nil
+}
+
+func (f *fakeNodeClient) StartServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	f.started = append(f.started, serverID)
+	return nil
+}
+
+func (f *fakeNodeClient) SendCommand(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, command string) error {
+	f.commands = append(f.commands, command)
+	return nil
+}
+
+func (f *fakeNodeClient) StopServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	f.stopped = append(f.stopped, serverID)
+	return nil
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRolloutUnhealthy is returned when a server does not come up on a new image
+var ErrRolloutUnhealthy = errors.New("server did not come up on the new image")
+
+// CheckRolloutHealth returns an error unless a server recreated on a new image
+// is running, has not crashed since, and is not reported unhealthy by its
+// egg's healthcheck. A server still in its healthcheck start period passes.
+func CheckRolloutHealth(stats *ServerStats) error {
+	switch {
+	case stats == nil:
+		return ErrRolloutUnhealthy
+	case stats.Crash != nil:
+		return fmt.Errorf("%w: crashed", ErrRolloutUnhealthy)
+	case stats.Status != "running":
+		return fmt.Errorf("%w: status %q", ErrRolloutUnhealthy, stats.Status)
+	case stats.Health == "unhealthy":
+		return fmt.Errorf("%w: healthcheck failing", ErrRolloutUnhealthy)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/go-pdf/fpdf"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+	ErrInvoiceAccessDenied = errors.New("access to invoice denied")
+)
+
+// InvoiceCache stores rendered invoice documents
+type InvoiceCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// InvoiceService renders invoices for customers
+type InvoiceService struct {
+	invoiceRepo repositories.InvoiceRepository
+	userRepo    repositories.UserRepository
+	cache       InvoiceCache
+	config      config.BillingConfig
+}
+
+// NewInvoiceService creates a new InvoiceService
+func NewInvoiceService(
+	invoiceRepo repositories.InvoiceRepository,
+	userRepo repositories.UserRepository,
+	cache InvoiceCache,
+	cfg *config.Config,
+) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo: invoiceRepo,
+		userRepo:    userRepo,
+		cache:       cache,
+		config:      cfg.Billing,
+	}
+}
+
+// Download returns the PDF of an invoice for its owner, or for anyone allowed
+// to manage billing
+func (s *InvoiceService) Download(ctx context.Context, invoiceID uuid.UUID, userID uuid.UUID, canManage bool) (*entities.Invoice, []byte, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, nil, ErrInvoiceNotFound
+	}
+	if !canManage && invoice.UserID != userID {
+		return nil, nil, ErrInvoiceAccessDenied
+	}
+
+	pdf, err := s.render(ctx, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	return invoice, pdf, nil
+}
+
+// RenderPDF returns the PDF of an invoice, rendering it unless a copy of its
+// current version is cached
+func (s *InvoiceService) RenderPDF(ctx context.Context, invoiceID uuid.UUID) ([]byte, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	return s.render(ctx, invoice)
+}
+
+func (s *InvoiceService) render(ctx context.Context, invoice *entities.Invoice) ([]byte, error) {
+	key := InvoicePDFKey(invoice)
+	if cached, err := s.cache.Get(ctx, key); err == nil {
+		return []byte(cached), nil
+	}
+
+	customer, err := s.userRepo.GetByID(ctx, invoice.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	pdf, err := RenderInvoicePDF(invoice, customer, s.config.Company)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.Set(ctx, key, pdf, s.config.InvoiceCacheTTL)
+	return pdf, nil
+}
+
+// InvoicePDFKey returns the cache key of an invoice's PDF. It changes whenever
+// the invoice is updated, so stale documents are never served.
+func InvoicePDFKey(invoice *entities.Invoice) string {
+	return fmt.Sprintf("invoice:pdf:%s:%d", invoice.ID, invoice.UpdatedAt.Unix())
+}
+
+// InvoiceFilename returns the download file name of an invoice
+func InvoiceFilename(invoice *entities.Invoice) string {
+	return "invoice-" + invoice.InvoiceNumber + ".pdf"
+}
+
+// RenderInvoicePDF renders an invoice with its line items and totals as an A4 PDF
+func RenderInvoicePDF(invoice *entities.Invoice, customer *entities.User, company config.CompanyConfig) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+invoice.InvoiceNumber, true)
+	pdf.SetAuthor(company.Name, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+
+	// The core fonts only cover cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	text := func(w, h float64, s, align string) {
+		pdf.CellFormat(w, h, tr(s), "", 0, align, false, 0, "")
+	}
+	line := func(w, h float64, s, align string) {
+		pdf.CellFormat(w, h, tr(s), "", 1, align, false, 0, "")
+	}
+
+	// Company on the left, invoice details on the right
+	top := pdf.GetY()
+	pdf.SetFont("Helvetica", "B", 16)
+	line(100, 8, company.Name, "L")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, l := range companyLines(company) {
+		line(100, 4.5, l, "L")
+	}
+	bottom := pdf.GetY()
+
+	pdf.SetXY(120, top)
+	pdf.SetFont("Helvetica", "B", 16)
+	line(70, 8, "INVOICE", "R")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, l := range []string{
+		"Number: " + invoice.InvoiceNumber,
+		"Issued: " + invoice.IssueDate.Format("2006-01-02"),
+		"Due: " + invoice.DueDate.Format("2006-01-02"),
+		"Status: " + strings.ToUpper(invoice.Status),
+	} {
+		pdf.SetX(120)
+		line(70, 4.5, l, "R")
+	}
+	if pdf.GetY() > bottom {
+		bottom = pdf.GetY()
+	}
+
+	// Customer
+	pdf.SetXY(20, bottom+10)
+	pdf.SetFont("Helvetica", "B", 10)
+	line(0, 5, "Bill to", "L")
+	pdf.SetFont("Helvetica", "", 9)
+	if name := strings.TrimSpace(customer.FirstName + " " + customer.LastName); name != "" {
+		line(0, 4.5, name, "L")
+	}
+	line(0, 4.5, customer.Username, "L")
+	line(0, 4.5, customer.Email, "L")
+	pdf.Ln(8)
+
+	// Line items
+	widths := []float64{95, 20, 27.5, 27.5}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, h := range []string{"Description", "Qty", "Unit price", "Total"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, item := range invoice.Items {
+		text(widths[0], 6, item.Description, "L")
+		text(widths[1], 6, fmt.Sprintf("%d", item.Quantity), "R")
+		text(widths[2], 6, formatMoney(item.UnitPrice, invoice.Currency), "R")
+		text(widths[3], 6, formatMoney(item.Total, invoice.Currency), "R")
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	// Totals
+	totals := [][2]string{{"Subtotal", formatMoney(invoice.Subtotal, invoice.Currency)}}
+	if invoice.Discount != 0 {
+		totals = append(totals, [2]string{"Discount", "-" + formatMoney(invoice.Discount, invoice.Currency)})
+	}
+	if invoice.Tax != 0 {
+		totals = append(totals, [2]string{"Tax", formatMoney(invoice.Tax, invoice.Currency)})
+	}
+	totals = append(totals, [2]string{"Total", formatMoney(invoice.Total, invoice.Currency)})
+
+	for i, t := range totals {
+		if i == len(totals)-1 {
+			pdf.SetFont("Helvetica", "B", 10)
+		}
+		pdf.SetX(20 + widths[0] + widths[1])
+		text(widths[2], 6, t[0], "R")
+		line(widths[3], 6, t[1], "R")
+	}
+
+	if invoice.PaidAt != nil {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "", 9)
+		line(0, 5, "Paid on "+invoice.PaidAt.Format("2006-01-02"), "L")
+	}
+
+	if invoice.Notes != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 4.5, tr(invoice.Notes), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render invoice: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// companyLines returns the configured company details printed on invoices
+func companyLines(company config.CompanyConfig) []string {
+	var lines []string
+	for _, l := range strings.Split(company.Address, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if company.Email != "" {
+		lines = append(lines, company.Email)
+	}
+	if company.Website != "" {
+		lines = append(lines, company.Website)
+	}
+	if company.TaxID != "" {
+		lines = append(lines, "Tax ID: "+company.TaxID)
+	}
+	return lines
+}
+
+func formatMoney(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// Errors for server mounts
+var (
+	ErrInvalidMount       = errors.New("mount paths must be absolute and the target must not overlap the server's data")
+	ErrMountNotAllowed    = errors.New("mount source is outside the node's allowed mounts")
+	ErrMountTargetInUse   = errors.New("a mount already uses this target")
+	ErrMountNotFound      = errors.New("mount not found")
+	ErrInvalidAllowedPath = errors.New("allowed mounts must be absolute paths other than /")
+)
+
+// serverDataTarget is where a server's own data is mounted in its container
+const serverDataTarget = "/home/container"
+
+// ValidateAllowedMounts checks the allowed mount sources of a node and returns
+// them cleaned. The root is refused, as it would expose the whole host.
+func ValidateAllowedMounts(paths []string) ([]string, error) {
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !path.IsAbs(p) || path.Clean(p) == "/" {
+			return nil, ErrInvalidAllowedPath
+		}
+		cleaned = append(cleaned, path.Clean(p))
+	}
+	return cleaned, nil
+}
+
+// CheckMount verifies that a mount's source lies within one of allowed and
+// that its target leaves the server's data alone. The mount is returned with
+// its paths cleaned.
+func CheckMount(allowed []string, mount entities.Mount) (entities.Mount, error) {
+	if !path.IsAbs(mount.Source) || !path.IsAbs(mount.Target) {
+		return mount, ErrInvalidMount
+	}
+	mount.Source = path.Clean(mount.Source)
+	mount.Target = path.Clean(mount.Target)
+
+	if mount.Target == "/" || within(mount.Target, serverDataTarget) || within(serverDataTarget, mount.Target) {
+		return mount, ErrInvalidMount
+	}
+	for _, dir := range allowed {
+		if within(mount.Source, dir) {
+			return mount, nil
+		}
+	}
+	return mount, ErrMountNotAllowed
+}
+
+// within reports whether p is dir or below it
+func within(p, dir string) bool {
+	dir = path.Clean(dir)
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -10,23 +10,30 @@ import (
 
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrNodeNotFound     = errors.New("node not found")
-	ErrNodeOffline      = errors.New("node is offline")
-	ErrNodeMaintenance  = errors.New("node is in maintenance mode")
-	ErrLocationNotFound = errors.New("location not found")
+	ErrNodeNotFound       = errors.New("node not found")
+	ErrNodeOffline        = errors.New("node is offline")
+	ErrNodeTimeout        = errors.New("node did not respond in time")
+	ErrNodeMaintenance    = errors.New("node is in maintenance mode")
+	ErrLocationNotFound   = errors.New("location not found")
+	ErrAllocationConflict = errors.New("ip and port are allocated on another node")
+	ErrPortsAllocated     = errors.New("every port in the range is already allocated")
 )
 
 // NodeService handles node operations
 type NodeService struct {
-	nodeRepo       repositories.NodeRepository
-	locationRepo   repositories.LocationRepository
-	allocationRepo repositories.AllocationRepository
-	serverRepo     repositories.ServerRepository
-	auditRepo      repositories.AuditLogRepository
+	nodeRepo         repositories.NodeRepository
+	locationRepo     repositories.LocationRepository
+	allocationRepo   repositories.AllocationRepository
+	serverRepo       repositories.ServerRepository
+	auditRepo        repositories.AuditLogRepository
+	notificationRepo repositories.NotificationRepository
+	nodeClient       NodeClient
 }
 
 // NewNodeService creates a new NodeService
@@ -36,13 +43,17 @@ func NewNodeService(
 	allocationRepo repositories.AllocationRepository,
 	serverRepo repositories.ServerRepository,
 	auditRepo repositories.AuditLogRepository,
+	notificationRepo repositories.NotificationRepository,
+	nodeClient NodeClient,
 ) *NodeService {
 	return &NodeService{
-		nodeRepo:       nodeRepo,
-		locationRepo:   locationRepo,
-		allocationRepo: allocationRepo,
-		serverRepo:     serverRepo,
-		auditRepo:      auditRepo,
+		nodeRepo:         nodeRepo,
+		locationRepo:     locationRepo,
+		allocationRepo:   allocationRepo,
+		serverRepo:       serverRepo,
+		auditRepo:        auditRepo,
+		notificationRepo: notificationRepo,
+		nodeClient:       nodeClient,
 	}
 }
 
@@ -117,13 +128,18 @@ func (s *NodeService) GetByLocation(ctx context.Context, locationID uuid.UUID) (
 	return s.nodeRepo.GetByLocationID(ctx, locationID)
 }
 
-// Update updates a node
-func (s *NodeService) Update(ctx context.Context, id uuid.UUID, req *CreateNodeRequest, updatedBy uuid.UUID) (*entities.Node, error) {
+// Update updates a node. version is the version the caller last read; the update
+// is rejected with repositories.ErrVersionConflict if the node changed since.
+func (s *NodeService) Update(ctx context.Context, id uuid.UUID, version int, req *CreateNodeRequest, updatedBy uuid.UUID) (*entities.Node, error) {
 	node, err := s.nodeRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrNodeNotFound
 	}
 
+	if node.Version != version {
+		return nil, repositories.ErrVersionConflict
+	}
+
 	node.Name = req.Name
 	node.Description = req.Description
 	node.LocationID = req.LocationID
@@ -137,6 +153,9 @@ func (s *NodeService) Update(ctx context.Context, id uuid.UUID, req *CreateNodeR
 	node.CPUTotal = req.CPUTotal
 
 	if err := s.nodeRepo.Update(ctx, node); err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update node: %w", err)
 	}
 
@@ -163,16 +182,122 @@ func (s *NodeService) Delete(ctx context.Context, id uuid.UUID, deletedBy uuid.U
 	return nil
 }
 
+// MaintenanceOptions controls how a node enters or leaves maintenance mode
+type MaintenanceOptions struct {
+	Drain          bool `json:"drain"`           // Stop running servers when entering maintenance
+	RestoreServers bool `json:"restore_servers"` // Restart drained servers when leaving maintenance
+}
+
 // SetMaintenanceMode enables/disables maintenance mode
-func (s *NodeService) SetMaintenanceMode(ctx context.Context, id uuid.UUID, maintenance bool, userID uuid.UUID) error {
+func (s *NodeService) SetMaintenanceMode(ctx context.Context, id uuid.UUID, maintenance bool, opts MaintenanceOptions, userID uuid.UUID) error {
+	node, err := s.nodeRepo.GetByID(ctx, id)
+	if err != nil {
+		return ErrNodeNotFound
+	}
+
+	// Flip the flag first so no new servers are placed while draining
 	if err := s.nodeRepo.SetMaintenanceMode(ctx, id, maintenance); err != nil {
 		return err
 	}
 
 	s.logAudit(ctx, userID, entities.AuditActionUpdate, "node", &id)
+
+	if maintenance && opts.Drain {
+		return s.drain(ctx, node)
+	}
+	if !maintenance {
+		return s.restore(ctx, node, opts.RestoreServers)
+	}
 	return nil
 }
 
+// drain gracefully stops every running server on a node and records them for restoration
+func (s *NodeService) drain(ctx context.Context, node *entities.Node) error {
+	servers, err := s.serverRepo.GetByNodeID(ctx, node.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, server := range servers {
+		if !server.IsRunning() {
+			continue
+		}
+
+		server.RestartAfterMaintenance = true
+		server.Status = entities.ServerStatusStopping
+		if err := s.serverRepo.Update(ctx, server); err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", server.ID, err))
+			continue
+		}
+
+		if err := s.nodeClient.StopServer(ctx, node.ID, server.ID); err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", server.ID, err))
+			continue
+		}
+
+		s.notifyOwner(ctx, server, node, "Server stopped for maintenance",
+			fmt.Sprintf("Your server %s was stopped because node %s entered maintenance.", server.Name, node.Name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// restore clears drain markers and optionally restarts the servers that were drained
+func (s *NodeService) restore(ctx context.Context, node *entities.Node, restart bool) error {
+	servers, err := s.serverRepo.GetByNodeID(ctx, node.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, server := range servers {
+		if !server.RestartAfterMaintenance {
+			continue
+		}
+
+		server.RestartAfterMaintenance = false
+		startable := restart && !server.Suspended
+		if startable {
+			server.Status = entities.ServerStatusStarting
+		}
+		if err := s.serverRepo.Update(ctx, server); err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", server.ID, err))
+			continue
+		}
+
+		if !startable {
+			s.notifyOwner(ctx, server, node, "Maintenance completed",
+				fmt.Sprintf("Node %s left maintenance. Your server %s can be started again.", node.Name, server.Name))
+			continue
+		}
+
+		if err := s.nodeClient.StartServer(ctx, node.ID, server.ID); err != nil {
+			_ = s.serverRepo.UpdateStatus(ctx, server.ID, entities.ServerStatusError)
+			errs = append(errs, fmt.Errorf("server %s: %w", server.ID, err))
+			continue
+		}
+
+		s.notifyOwner(ctx, server, node, "Server restarted after maintenance",
+			fmt.Sprintf("Node %s left maintenance and your server %s was started again.", node.Name, server.Name))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *NodeService) notifyOwner(ctx context.Context, server *entities.Server, node *entities.Node, title, message string) {
+	_ = s.notificationRepo.Create(ctx, &entities.Notification{
+		UserID:  server.OwnerID,
+		Type:    "node_maintenance",
+		Title:   title,
+		Message: message,
+		Data: map[string]interface{}{
+			"server_id": server.ID,
+			"node_id":   node.ID,
+		},
+	})
+}
+
 // UpdateOnlineStatus updates node online status
 func (s *NodeService) UpdateOnlineStatus(ctx context.Context, id uuid.UUID, isOnline bool) error {
 	return s.nodeRepo.UpdateOnlineStatus(ctx, id, isOnline)
@@ -253,6 +378,11 @@ type CreateAllocationRequest struct {
 	PortStart int       `json:"port_start" validate:"required,min=1,max=65535"`
 	PortEnd   int       `json:"port_end" validate:"required,min=1,max=65535,gtefield=PortStart"`
 	Alias     string    `json:"alias" validate:"max=255"`
+
+	// SeparateHosts allows ports of an IP that other nodes also allocate, for
+	// an IP that is not one shared address, such as a private one reused by
+	// nodes on different hosts
+	SeparateHosts bool `json:"separate_hosts"`
 }
 
 // CreateAllocations creates multiple allocations for a node
@@ -262,6 +392,12 @@ func (s *NodeService) CreateAllocations(ctx context.Context, req *CreateAllocati
 		return nil, ErrNodeNotFound
 	}
 
+	if !req.SeparateHosts {
+		if err := CheckAllocationConflict(ctx, s.allocationRepo, req.NodeID, req.IP, req.PortStart, req.PortEnd); err != nil {
+			return nil, err
+		}
+	}
+
 	var allocations []*entities.Allocation
 	for port := req.PortStart; port <= req.PortEnd; port++ {
 		// Check if port is available
@@ -283,7 +419,7 @@ func (s *NodeService) CreateAllocations(ctx context.Context, req *CreateAllocati
 	}
 
 	if len(allocations) == 0 {
-		return nil, errors.New("no available ports in range")
+		return nil, ErrPortsAllocated
 	}
 
 	if err := s.allocationRepo.CreateBatch(ctx, allocations); err != nil {
@@ -294,6 +430,31 @@ func (s *NodeService) CreateAllocations(ctx context.Context, req *CreateAllocati
 	return allocations, nil
 }
 
+// CheckAllocationConflict returns ErrAllocationConflict when a port from
+// portStart to portEnd of ip is allocated on a node other than nodeID. Nodes
+// behind one host share its public IP and the host binds each port only once,
+// so every path that persists allocations checks this first.
+func CheckAllocationConflict(ctx context.Context, allocations repositories.AllocationRepository, nodeID uuid.UUID, ip string, portStart, portEnd int) error {
+	conflicts, err := allocations.GetOnOtherNodes(ctx, nodeID, ip, portStart, portEnd)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return allocationConflict(conflicts)
+	}
+	return nil
+}
+
+// allocationConflict describes the allocations a new range overlaps
+func allocationConflict(conflicts []*entities.Allocation) error {
+	first := conflicts[0]
+	err := fmt.Errorf("%w: %s:%d is allocated on node %s", ErrAllocationConflict, first.IP, first.Port, first.NodeID)
+	if len(conflicts) > 1 {
+		err = fmt.Errorf("%w and %d more", err, len(conflicts)-1)
+	}
+	return err
+}
+
 // DeleteAllocation deletes an allocation
 func (s *NodeService) DeleteAllocation(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
 	allocation, err := s.allocationRepo.GetByID(ctx, id)
@@ -366,5 +527,7 @@ func (s *NodeService) logAudit(ctx context.Context, userID uuid.UUID, action ent
 		Resource:   resource,
 		ResourceID: resourceID,
 	}
-	_ = s.auditRepo.Create(ctx, log)
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", resource), zap.Error(err))
+	}
 }
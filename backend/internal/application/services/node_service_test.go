@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+func TestMaintenanceDrainAndRestore(t *testing.T) {
+	store := newFakeStore()
+	owner := addUser(store, "owner", 0)
+	node := &entities.Node{ID: uuid.New(), Name: "node-1"}
+	store.nodes[node.ID] = node
+
+	running := addServer(store, owner, 1024)
+	running.NodeID = node.ID
+	running.Status = entities.ServerStatusRunning
+	stopped := addServer(store, owner, 1024)
+	stopped.NodeID = node.ID
+	stopped.Status = entities.ServerStatusStopped
+
+	client := &fakeNodeClient{}
+	s := NewNodeService(fakeNodes{store: store}, nil, nil, fakeServers{store: store}, fakeAuditLogs{store: store}, fakeNotifications{store: store}, client)
+	ctx := context.Background()
+	admin := uuid.New()
+
+	if err := s.SetMaintenanceMode(ctx, node.ID, true, MaintenanceOptions{Drain: true}, admin); err != nil {
+		t.Fatalf("enter maintenance: %v", err)
+	}
+	if !store.nodes[node.ID].MaintenanceMode {
+		t.Error("node is not in maintenance")
+	}
+	if !slices.Equal(client.stopped, []uuid.UUID{running.ID}) {
+		t.Errorf("stopped %v, want only the running server %s", client.stopped, running.ID)
+	}
+	if !store.servers[running.ID].RestartAfterMaintenance {
+		t.Error("drained server was not recorded for restoration")
+	}
+	if store.servers[stopped.ID].RestartAfterMaintenance {
+		t.Error("stopped server was recorded for restoration")
+	}
+	if got := store.notified(owner.ID); len(got) != 1 {
+		t.Errorf("owner notifications = %v, want one", got)
+	}
+
+	if err := s.SetMaintenanceMode(ctx, node.ID, false, MaintenanceOptions{RestoreServers: true}, admin); err != nil {
+		t.Fatalf("exit maintenance: %v", err)
+	}
+	if store.nodes[node.ID].MaintenanceMode {
+		t.Error("node is still in maintenance")
+	}
+	if !slices.Equal(client.started, []uuid.UUID{running.ID}) {
+		t.Errorf("started %v, want only the drained server %s", client.started, running.ID)
+	}
+	if store.servers[running.ID].RestartAfterMaintenance {
+		t.Error("restored server is still marked for restoration")
+	}
+	if got := store.servers[running.ID].Status; got != entities.ServerStatusStarting {
+		t.Errorf("restored server status = %s, want %s", got, entities.ServerStatusStarting)
+	}
+}
+
+func TestMaintenanceWithoutDrainLeavesServersRunning(t *testing.T) {
+	store := newFakeStore()
+	owner := addUser(store, "owner", 0)
+	node := &entities.Node{ID: uuid.New()}
+	store.nodes[node.ID] = node
+	server := addServer(store, owner, 1024)
+	server.NodeID = node.ID
+	server.Status = entities.ServerStatusRunning
+
+	client := &fakeNodeClient{}
+	s := NewNodeService(fakeNodes{store: store}, nil, nil, fakeServers{store: store}, fakeAuditLogs{store: store}, fakeNotifications{store: store}, client)
+	if err := s.SetMaintenanceMode(context.Background(), node.ID, true, MaintenanceOptions{}, uuid.New()); err != nil {
+		t.Fatalf("enter maintenance: %v", err)
+	}
+	if len(client.stopped) != 0 || store.servers[server.ID].RestartAfterMaintenance {
+		t.Errorf("server was drained without the drain option: stopped %v", client.stopped)
+	}
+}
+
+func TestCreateAllocationsRejectsPortsOfAnotherNode(t *testing.T) {
+	store := newFakeStore()
+	first := &entities.Node{ID: uuid.New()}
+	second := &entities.Node{ID: uuid.New()}
+	store.nodes[first.ID] = first
+	store.nodes[second.ID] = second
+	s := NewNodeService(fakeNodes{store: store}, nil, fakeAllocations{store: store}, nil, fakeAuditLogs{}, nil, nil)
+	ctx := context.Background()
+	admin := uuid.New()
+
+	allocate := func(node *entities.Node, ip string, start, end int, separate bool) error {
+		_, err := s.CreateAllocations(ctx, &CreateAllocationRequest{NodeID: node.ID, IP: ip, PortStart: start, PortEnd: end, SeparateHosts: separate}, admin)
+		return err
+	}
+
+	if err := allocate(first, "203.0.113.10", 25565, 25570, false); err != nil {
+		t.Fatalf("first node: %v", err)
+	}
+	if err := allocate(second, "203.0.113.10", 25570, 25575, false); !errors.Is(err, ErrAllocationConflict) {
+		t.Errorf("overlapping range on another node = %v, want %v", err, ErrAllocationConflict)
+	}
+	if len(store.allocations) != 6 {
+		t.Errorf("%d allocations stored after the conflict, want 6", len(store.allocations))
+	}
+
+	if err := allocate(second, "203.0.113.11", 25565, 25570, false); err != nil {
+		t.Errorf("same ports on a distinct IP = %v", err)
+	}
+	if err := allocate(second, "10.0.0.5", 25565, 25565, false); err != nil {
+		t.Fatalf("private IP on the second node: %v", err)
+	}
+	if err := allocate(first, "10.0.0.5", 25565, 25565, true); err != nil {
+		t.Errorf("shared private IP on separate hosts = %v", err)
+	}
+	if err := allocate(first, "203.0.113.10", 25565, 25570, false); !errors.Is(err, ErrPortsAllocated) {
+		t.Errorf("range already allocated on the same node = %v, want %v", err, ErrPortsAllocated)
+	}
+}
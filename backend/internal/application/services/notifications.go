@@ -0,0 +1,19 @@
+package services
+
+import "github.com/aetherpanel/aether-panel/internal/domain/entities"
+
+// NotificationChannels returns whether a notification of the given type is
+// shown in-app and emailed to a user with prefs. The preference for the type
+// wins over the user's "*" preference; without either it is shown in-app only.
+func NotificationChannels(prefs []entities.NotificationPreference, notificationType string) (inApp, email bool) {
+	inApp = true
+	for _, p := range prefs {
+		switch p.Type {
+		case notificationType:
+			return p.InApp, p.Email
+		case "*":
+			inApp, email = p.InApp, p.Email
+		}
+	}
+	return inApp, email
+}
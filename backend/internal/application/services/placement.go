@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var ErrNoAvailableNode = errors.New("no node with sufficient capacity available")
+
+// PlacementStrategy decides the order in which candidate nodes are tried
+type PlacementStrategy string
+
+const (
+	PlacementMostFree   PlacementStrategy = "most_free"   // Spread load onto the emptiest node
+	PlacementBinpack    PlacementStrategy = "binpack"     // Fill the fullest node that still fits
+	PlacementRoundRobin PlacementStrategy = "round_robin" // Rotate through eligible nodes
+)
+
+// NodePlacer orders candidate nodes for new servers
+type NodePlacer struct {
+	strategy PlacementStrategy
+	cursor   uint64
+}
+
+// NewNodePlacer creates a new NodePlacer, defaulting to most_free
+func NewNodePlacer(strategy string) *NodePlacer {
+	s := PlacementStrategy(strategy)
+	switch s {
+	case PlacementMostFree, PlacementBinpack, PlacementRoundRobin:
+	default:
+		s = PlacementMostFree
+	}
+	return &NodePlacer{strategy: s}
+}
+
+// NodeFits reports whether a node can accept a server with the given resources
+func NodeFits(node *entities.Node, memory, disk int64) bool {
+	return node.IsOnline &&
+		!node.MaintenanceMode &&
+		node.AvailableMemory() >= memory &&
+		node.AvailableDisk() >= disk
+}
+
+// Order filters out nodes that cannot take the server and orders the rest by strategy
+func (p *NodePlacer) Order(nodes []*entities.Node, memory, disk int64) []*entities.Node {
+	eligible := make([]*entities.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if NodeFits(node, memory, disk) {
+			eligible = append(eligible, node)
+		}
+	}
+
+	if len(eligible) == 0 {
+		return eligible
+	}
+
+	switch p.strategy {
+	case PlacementBinpack:
+		sort.SliceStable(eligible, func(i, j int) bool {
+			return freeRatio(eligible[i]) < freeRatio(eligible[j])
+		})
+	case PlacementRoundRobin:
+		// Stable base order so rotation is deterministic across calls
+		sort.SliceStable(eligible, func(i, j int) bool {
+			return eligible[i].ID.String() < eligible[j].ID.String()
+		})
+		offset := int(atomic.AddUint64(&p.cursor, 1)-1) % len(eligible)
+		rotated := make([]*entities.Node, 0, len(eligible))
+		rotated = append(rotated, eligible[offset:]...)
+		eligible = append(rotated, eligible[:offset]...)
+	default:
+		sort.SliceStable(eligible, func(i, j int) bool {
+			return freeRatio(eligible[i]) > freeRatio(eligible[j])
+		})
+	}
+
+	return eligible
+}
+
+// freeRatio returns the share of a node's memory capacity that is still free
+func freeRatio(node *entities.Node) float64 {
+	capacity := node.MemoryTotal + (node.MemoryTotal * int64(node.MemoryOveralloc) / 100)
+	if capacity <= 0 {
+		return 0
+	}
+	return float64(node.AvailableMemory()) / float64(capacity)
+}
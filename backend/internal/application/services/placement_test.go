@@ -0,0 +1,99 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+func testNode(name string, allocated int64) *entities.Node {
+	return &entities.Node{
+		ID:              uuid.New(),
+		Name:            name,
+		IsOnline:        true,
+		MemoryTotal:     8192,
+		MemoryAllocated: allocated,
+		DiskTotal:       100000,
+	}
+}
+
+func nodeNames(nodes []*entities.Node) []string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name
+	}
+	return names
+}
+
+func TestNodePlacerOrder(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{"most_free", []string{"empty", "half", "busy"}},
+		{"binpack", []string{"busy", "half", "empty"}},
+		{"unknown", []string{"empty", "half", "busy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			nodes := []*entities.Node{testNode("half", 4096), testNode("busy", 6144), testNode("empty", 0)}
+			got := nodeNames(NewNodePlacer(tt.strategy).Order(nodes, 1024, 1024))
+			if len(got) != len(tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("order = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNodePlacerRoundRobinRotates(t *testing.T) {
+	nodes := []*entities.Node{testNode("a", 0), testNode("b", 0), testNode("c", 0)}
+	p := NewNodePlacer("round_robin")
+
+	seen := map[uuid.UUID]bool{}
+	for range nodes {
+		seen[p.Order(nodes, 1024, 1024)[0].ID] = true
+	}
+	if len(seen) != len(nodes) {
+		t.Errorf("first picks covered %d of %d nodes, want every node once", len(seen), len(nodes))
+	}
+	if first := p.Order(nodes, 1024, 1024)[0].ID; !seen[first] {
+		t.Error("rotation did not wrap around")
+	}
+}
+
+func TestNodePlacerExcludesUnfitNodes(t *testing.T) {
+	full := testNode("full", 8000)
+	maintenance := testNode("maintenance", 0)
+	maintenance.MaintenanceMode = true
+	offline := testNode("offline", 0)
+	offline.IsOnline = false
+	noDisk := testNode("no-disk", 0)
+	noDisk.DiskAllocated = noDisk.DiskTotal
+	fits := testNode("fits", 2048)
+
+	for _, strategy := range []string{"most_free", "binpack", "round_robin"} {
+		got := NewNodePlacer(strategy).Order([]*entities.Node{full, maintenance, offline, noDisk, fits}, 1024, 1024)
+		if len(got) != 1 || got[0] != fits {
+			t.Errorf("%s: order = %v, want only fits", strategy, nodeNames(got))
+		}
+	}
+
+	if got := NewNodePlacer("most_free").Order([]*entities.Node{full, maintenance, offline}, 1024, 1024); len(got) != 0 {
+		t.Errorf("order = %v, want no eligible node", nodeNames(got))
+	}
+}
+
+func TestNodePlacerHonoursOverallocation(t *testing.T) {
+	node := testNode("overalloc", 8000)
+	node.MemoryOveralloc = 50
+
+	if got := NewNodePlacer("most_free").Order([]*entities.Node{node}, 1024, 1024); len(got) != 1 {
+		t.Error("node with overallocation headroom excluded")
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var (
+	ErrPreferredAllocationUnavailable = errors.New("preferred allocation is not available")
+	ErrNoDedicatedIP                  = errors.New("no IP without other servers is available")
+)
+
+// AllocationPreferences narrow the allocations a new server may get. Zero
+// values leave the choice to SelectAllocations.
+type AllocationPreferences struct {
+	IP          string `json:"ip" validate:"omitempty,ip"`
+	Port        int    `json:"port" validate:"omitempty,min=1,max=65535"` // Primary port
+	DedicatedIP bool   `json:"dedicated_ip"`                              // No other server may use the IP
+}
+
+// SelectAllocations picks the allocations of a new server from the
+// allocations of its node: a free primary allocation followed by one for each
+// port of the egg, on the same IP at the port's offset. Primaries are tried by
+// ascending port, so if an offset port is taken the next complete free set is
+// used. Primaries that do not match prefs are skipped, and when none match the
+// returned error names the preference that could not be met. The protocols of
+// the returned allocations are set from the egg.
+func SelectAllocations(egg *entities.Egg, allocations []*entities.Allocation, prefs AllocationPreferences) ([]*entities.Allocation, error) {
+	free := make(map[string]*entities.Allocation, len(allocations))
+	usedIPs := make(map[string]bool)
+	var sorted []*entities.Allocation
+	for _, alloc := range allocations {
+		if alloc.ServerID != nil {
+			usedIPs[alloc.IP] = true
+			continue
+		}
+		free[allocationAddress(alloc.IP, alloc.Port)] = alloc
+		sorted = append(sorted, alloc)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	var ports []entities.EggPort
+	if egg != nil {
+		ports = egg.Ports
+	}
+
+	preferred, dedicated := false, false
+	for _, primary := range sorted {
+		if (prefs.IP != "" && primary.IP != prefs.IP) || (prefs.Port != 0 && primary.Port != prefs.Port) {
+			continue
+		}
+		preferred = true
+		if prefs.DedicatedIP && usedIPs[primary.IP] {
+			continue
+		}
+		dedicated = true
+
+		set := []*entities.Allocation{primary}
+		for _, port := range ports {
+			alloc, ok := free[allocationAddress(primary.IP, primary.Port+port.Offset)]
+			if !ok || port.Offset == 0 {
+				set = nil
+				break
+			}
+			set = append(set, alloc)
+		}
+		if set != nil {
+			ApplyAllocationProtocols(egg, primary, set)
+			return set, nil
+		}
+	}
+
+	switch {
+	case !preferred && (prefs.IP != "" || prefs.Port != 0):
+		return nil, ErrPreferredAllocationUnavailable
+	case !dedicated && prefs.DedicatedIP:
+		return nil, ErrNoDedicatedIP
+	}
+	return nil, ErrNoAvailableAllocation
+}
+
+// eggPortEnvironment returns the environment variables of an egg's ports,
+// found at their offsets from the primary allocation. Ports whose allocation
+// the server no longer has are left out.
+func eggPortEnvironment(egg *entities.Egg, primary *entities.Allocation, additional []*entities.Allocation) map[string]string {
+	env := make(map[string]string)
+	if egg == nil || primary == nil {
+		return env
+	}
+
+	assigned := make(map[string]bool, len(additional))
+	for _, alloc := range additional {
+		assigned[allocationAddress(alloc.IP, alloc.Port)] = true
+	}
+	for _, port := range egg.Ports {
+		target := primary.Port + port.Offset
+		if port.EnvVariable != "" && assigned[allocationAddress(primary.IP, target)] {
+			env[port.EnvVariable] = strconv.Itoa(target)
+		}
+	}
+	return env
+}
+
+// ApplyAllocationProtocols sets the protocol each of a server's allocations is
+// bound for: the egg's protocol for the primary allocation and, for the
+// allocation at each port's offset, that port's. All others, and those of
+// servers without an egg, bind both TCP and UDP.
+func ApplyAllocationProtocols(egg *entities.Egg, primary *entities.Allocation, allocations []*entities.Allocation) {
+	protocols := make(map[string]string)
+	if egg != nil && primary != nil {
+		protocols[allocationAddress(primary.IP, primary.Port)] = egg.Protocol
+		for _, port := range egg.Ports {
+			if port.Offset != 0 {
+				protocols[allocationAddress(primary.IP, primary.Port+port.Offset)] = port.Protocol
+			}
+		}
+	}
+
+	for _, alloc := range allocations {
+		alloc.Protocol = protocols[allocationAddress(alloc.IP, alloc.Port)]
+		if alloc.Protocol == "" {
+			alloc.Protocol = entities.ProtocolBoth
+		}
+	}
+}
+
+func allocationAddress(ip string, port int) string {
+	return ip + ":" + strconv.Itoa(port)
+}
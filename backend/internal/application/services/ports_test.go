@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// portsEgg is an egg with a query port one above the primary and an RCON port
+// ten above it
+var portsEgg = &entities.Egg{
+	Ports: []entities.EggPort{
+		{EnvVariable: "SERVER_QUERY_PORT", Offset: 1},
+		{EnvVariable: "RCON_PORT", Offset: 10, Protocol: entities.ProtocolTCP},
+	},
+}
+
+// pool returns free allocations on ip for each port
+func pool(ip string, ports ...int) []*entities.Allocation {
+	allocations := make([]*entities.Allocation, 0, len(ports))
+	for _, port := range ports {
+		allocations = append(allocations, &entities.Allocation{ID: uuid.New(), IP: ip, Port: port})
+	}
+	return allocations
+}
+
+// ports returns the ports of allocations in order
+func ports(allocations []*entities.Allocation) []int {
+	out := make([]int, 0, len(allocations))
+	for _, alloc := range allocations {
+		out = append(out, alloc.Port)
+	}
+	return out
+}
+
+func TestSelectAllocationsReservesOffsets(t *testing.T) {
+	allocations := pool("203.0.113.10", 25575, 25566, 25565, 25567)
+
+	set, err := SelectAllocations(portsEgg, allocations, AllocationPreferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ports(set), []int{25565, 25566, 25575}; !slices.Equal(got, want) {
+		t.Errorf("selected ports %v, want %v", got, want)
+	}
+	if set[2].Protocol != entities.ProtocolTCP || set[1].Protocol != entities.ProtocolBoth {
+		t.Errorf("protocols %s and %s, want the RCON port TCP only and the query port both", set[2].Protocol, set[1].Protocol)
+	}
+}
+
+func TestSelectAllocationsSkipsConflictingOffsets(t *testing.T) {
+	ip := "203.0.113.10"
+	allocations := pool(ip, 25565, 25566, 25575, 25600, 25601, 25610)
+	// The query port of the first set belongs to another server
+	taken := uuid.New()
+	allocations[1].ServerID = &taken
+
+	set, err := SelectAllocations(portsEgg, allocations, AllocationPreferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ports(set), []int{25600, 25601, 25610}; !slices.Equal(got, want) {
+		t.Errorf("selected ports %v, want the next complete free set %v", got, want)
+	}
+
+	// Offsets on another IP do not complete a set
+	if _, err := SelectAllocations(portsEgg, append(pool(ip, 25565), pool("203.0.113.11", 25566, 25575)...), AllocationPreferences{}); !errors.Is(err, ErrNoAvailableAllocation) {
+		t.Errorf("offsets only free on another IP = %v, want %v", err, ErrNoAvailableAllocation)
+	}
+}
+
+func TestEggPortEnvironment(t *testing.T) {
+	primary := &entities.Allocation{IP: "203.0.113.10", Port: 27015}
+	additional := pool("203.0.113.10", 27016, 27025)
+
+	env := eggPortEnvironment(portsEgg, primary, additional)
+	if want := map[string]string{"SERVER_QUERY_PORT": "27016", "RCON_PORT": "27025"}; !maps.Equal(env, want) {
+		t.Errorf("environment %v, want %v", env, want)
+	}
+
+	// A port whose allocation was removed is left out
+	env = eggPortEnvironment(portsEgg, primary, additional[:1])
+	if want := map[string]string{"SERVER_QUERY_PORT": "27016"}; !maps.Equal(env, want) {
+		t.Errorf("environment without the RCON allocation %v, want %v", env, want)
+	}
+
+	if env := eggPortEnvironment(nil, primary, additional); len(env) != 0 {
+		t.Errorf("environment without an egg %v, want none", env)
+	}
+}
+
+func TestSelectAllocationsPreferredPort(t *testing.T) {
+	allocations := append(pool("203.0.113.10", 25565, 25566, 25575, 25600, 25601, 25610), pool("203.0.113.11", 25600, 25601, 25610)...)
+
+	set, err := SelectAllocations(portsEgg, allocations, AllocationPreferences{Port: 25600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ports(set), []int{25600, 25601, 25610}; !slices.Equal(got, want) || set[0].IP != "203.0.113.10" {
+		t.Errorf("selected %s:%v, want 203.0.113.10:%v", set[0].IP, got, want)
+	}
+
+	set, err = SelectAllocations(portsEgg, allocations, AllocationPreferences{IP: "203.0.113.11", Port: 25600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alloc := range set {
+		if alloc.IP != "203.0.113.11" {
+			t.Errorf("allocation on %s, want the preferred IP", alloc.IP)
+		}
+	}
+}
+
+func TestSelectAllocationsDedicatedIP(t *testing.T) {
+	shared := pool("203.0.113.10", 25565, 25566, 25575, 25580)
+	other := uuid.New()
+	shared[3].ServerID = &other
+	allocations := append(shared, pool("203.0.113.11", 27015, 27016, 27025)...)
+
+	set, err := SelectAllocations(portsEgg, allocations, AllocationPreferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set[0].IP != "203.0.113.10" {
+		t.Errorf("without the preference the lowest free port is used, got %s:%d", set[0].IP, set[0].Port)
+	}
+
+	// An IP another server uses is skipped even though its ports are free
+	set, err = SelectAllocations(portsEgg, allocations, AllocationPreferences{DedicatedIP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ports(set), []int{27015, 27016, 27025}; !slices.Equal(got, want) || set[0].IP != "203.0.113.11" {
+		t.Errorf("dedicated IP selected %s:%v, want 203.0.113.11:%v", set[0].IP, got, want)
+	}
+}
+
+func TestSelectAllocationsUnsatisfiablePreferences(t *testing.T) {
+	shared := pool("203.0.113.10", 25565, 25566, 25575, 25580)
+	other := uuid.New()
+	shared[3].ServerID = &other
+	taken := pool("203.0.113.10", 25600)
+	taken[0].ServerID = &other
+	allocations := append(shared, taken...)
+
+	for name, tc := range map[string]struct {
+		prefs AllocationPreferences
+		err   error
+	}{
+		"port not on the node":    {AllocationPreferences{Port: 30000}, ErrPreferredAllocationUnavailable},
+		"port of another server":  {AllocationPreferences{Port: 25600}, ErrPreferredAllocationUnavailable},
+		"IP not on the node":      {AllocationPreferences{IP: "198.51.100.1"}, ErrPreferredAllocationUnavailable},
+		"every IP shared":         {AllocationPreferences{DedicatedIP: true}, ErrNoDedicatedIP},
+		"preferred port, shared":  {AllocationPreferences{Port: 25565, DedicatedIP: true}, ErrNoDedicatedIP},
+		"offsets of port missing": {AllocationPreferences{Port: 25566}, ErrNoAvailableAllocation},
+	} {
+		if _, err := SelectAllocations(portsEgg, allocations, tc.prefs); !errors.Is(err, tc.err) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPackageNotFound     = errors.New("package not found")
+	ErrPackageNotAllowed   = errors.New("package does not allow this game, egg or node")
+	ErrInvalidBillingCycle = errors.New("package is not offered with this billing cycle")
+)
+
+// ProvisioningService turns package orders into a paid subscription with a
+// server
+type ProvisioningService struct {
+	packageRepo repositories.PackageRepository
+	nodeRepo    repositories.NodeRepository
+	userRepo    repositories.UserRepository
+	eggRepo     repositories.EggRepository
+	auditRepo   repositories.AuditLogRepository
+	uow         repositories.UnitOfWork
+	servers     *ServerService
+	tax         *TaxCalculator
+}
+
+// NewProvisioningService creates a new ProvisioningService
+func NewProvisioningService(
+	packageRepo repositories.PackageRepository,
+	nodeRepo repositories.NodeRepository,
+	userRepo repositories.UserRepository,
+	eggRepo repositories.EggRepository,
+	auditRepo repositories.AuditLogRepository,
+	uow repositories.UnitOfWork,
+	servers *ServerService,
+	cfg *config.Config,
+) *ProvisioningService {
+	return &ProvisioningService{
+		packageRepo: packageRepo,
+		nodeRepo:    nodeRepo,
+		userRepo:    userRepo,
+		eggRepo:     eggRepo,
+		auditRepo:   auditRepo,
+		uow:         uow,
+		servers:     servers,
+		tax:         NewTaxCalculator(cfg.Billing),
+	}
+}
+
+// PackagePrice returns the price of a package for a billing cycle. Quarterly
+// and yearly billing are only offered when the package has a price for them.
+func PackagePrice(pkg *entities.Package, billingCycle string) (float64, error) {
+	switch billingCycle {
+	case "monthly":
+		return pkg.PriceMonthly, nil
+	case "quarterly":
+		if pkg.PriceQuarterly > 0 {
+			return pkg.PriceQuarterly, nil
+		}
+	case "yearly":
+		if pkg.PriceYearly > 0 {
+			return pkg.PriceYearly, nil
+		}
+	}
+	return 0, ErrInvalidBillingCycle
+}
+
+// OrderPackage subscribes a user to a package and provisions its server. The
+// first period and the setup fee are charged from the user's credits with a
+// paid invoice, and the charge, subscription and server are saved in one
+// transaction so a failure at any step leaves nothing behind. A nil
+// nodePreference places the server on one of the package's nodes.
+func (s *ProvisioningService) OrderPackage(ctx context.Context, userID, packageID uuid.UUID, billingCycle string, eggID, nodePreference uuid.UUID) (*entities.Subscription, error) {
+	pkg, err := s.packageRepo.GetByID(ctx, packageID)
+	if err != nil || !pkg.IsActive || pkg.DeletedAt != nil {
+		return nil, ErrPackageNotFound
+	}
+	price, err := PackagePrice(pkg, billingCycle)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.IsActive() {
+		return nil, ErrUserNotFound
+	}
+
+	egg, err := s.eggRepo.GetByID(ctx, eggID)
+	if err != nil {
+		return nil, ErrEggNotFound
+	}
+	if !allowedBy(pkg.AllowedGames, egg.GameID) || !allowedBy(pkg.AllowedEggs, egg.ID) {
+		return nil, ErrPackageNotAllowed
+	}
+
+	nodeID := nodePreference
+	if nodeID == uuid.Nil {
+		node, err := s.placeNode(ctx, pkg)
+		if err != nil {
+			return nil, err
+		}
+		nodeID = node.ID
+	} else if !allowedBy(pkg.AllowedNodes, nodeID) {
+		return nil, ErrPackageNotAllowed
+	}
+
+	plan, err := s.servers.planServer(ctx, &CreateServerRequest{
+		Name:        pkg.Name,
+		OwnerID:     userID,
+		NodeID:      nodeID,
+		GameID:      egg.GameID,
+		EggID:       egg.ID,
+		MemoryLimit: pkg.MemoryLimit,
+		DiskLimit:   pkg.DiskLimit,
+		CPULimit:    pkg.CPULimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	server := plan.server
+	server.DatabaseLimit = pkg.DatabaseLimit
+	server.AllocationLimit = pkg.AllocationLimit
+	server.BackupLimit = pkg.BackupLimit
+
+	now := time.Now()
+	sub := &entities.Subscription{
+		UserID:       userID,
+		PackageID:    pkg.ID,
+		BillingCycle: billingCycle,
+		Amount:       price,
+		Currency:     pkg.Currency,
+		Status:       entities.SubscriptionStatusActive,
+		AutoRenew:    true,
+		StartDate:    now,
+	}
+	sub.EndDate = sub.PeriodEnd(now)
+	sub.NextBillingDate = &sub.EndDate
+
+	// The server is only provisioned once the order is paid for
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		if err := repos.Subscriptions().Create(ctx, sub); err != nil {
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+		if err := s.charge(ctx, repos, user, pkg, sub, price+pkg.SetupFee, now); err != nil {
+			return err
+		}
+		if err := saveServer(ctx, repos, plan); err != nil {
+			return err
+		}
+		sub.ServerID = &server.ID
+		return repos.Subscriptions().Update(ctx, sub)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientCredits) {
+			return nil, ErrInsufficientCredits
+		}
+		return nil, err
+	}
+
+	sub.Package = pkg
+	sub.Server = server
+	s.logAudit(ctx, userID, sub)
+	return sub, nil
+}
+
+// charge takes the first payment of a new subscription from the user's
+// credits and records its transaction and paid invoice. Free orders are not
+// charged.
+func (s *ProvisioningService) charge(ctx context.Context, repos repositories.TxRepositories, user *entities.User, pkg *entities.Package, sub *entities.Subscription, amount float64, now time.Time) error {
+	if amount <= 0 {
+		return nil
+	}
+	tax := s.tax.Calculate(user, amount)
+
+	if err := repos.Users().UpdateCredits(ctx, user.ID, -tax.Total); err != nil {
+		return err
+	}
+	current, err := repos.Users().GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if current.Credits < 0 {
+		return ErrInsufficientCredits
+	}
+
+	if err := repos.Transactions().Create(ctx, &entities.Transaction{
+		UserID:        user.ID,
+		Type:          entities.TransactionTypeDebit,
+		Status:        entities.TransactionStatusCompleted,
+		Amount:        -tax.Total,
+		Currency:      sub.Currency,
+		Description:   fmt.Sprintf("Order of %s (%s)", pkg.Name, sub.BillingCycle),
+		Reference:     "ORD-" + sub.ID.String(),
+		PaymentMethod: entities.PaymentMethodInternal,
+		PaymentDetails: map[string]interface{}{
+			"subscription_id": sub.ID,
+			"package_id":      pkg.ID,
+		},
+		BalanceBefore: current.Credits + tax.Total,
+		BalanceAfter:  current.Credits,
+		ProcessedAt:   &now,
+	}); err != nil {
+		return err
+	}
+
+	number, err := repos.Invoices().GenerateNumber(ctx)
+	if err != nil {
+		return err
+	}
+	description := fmt.Sprintf("%s %s to %s", pkg.Name, now.Format("2006-01-02"), sub.EndDate.Format("2006-01-02"))
+	if pkg.SetupFee > 0 {
+		description += ", including setup fee"
+	}
+	invoice := &entities.Invoice{
+		InvoiceNumber:  number,
+		UserID:         user.ID,
+		SubscriptionID: &sub.ID,
+		Currency:       sub.Currency,
+		Status:         "paid",
+		IssueDate:      now,
+		DueDate:        now,
+		PaidAt:         &now,
+		Items: []entities.InvoiceItem{{
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   tax.Subtotal,
+			Total:       tax.Subtotal,
+		}},
+	}
+	tax.Apply(invoice)
+	return repos.Invoices().Create(ctx, invoice)
+}
+
+// placeNode selects one of a package's nodes with room for its server, any
+// node when the package does not restrict them
+func (s *ProvisioningService) placeNode(ctx context.Context, pkg *entities.Package) (*entities.Node, error) {
+	nodes, err := s.nodeRepo.GetAvailable(ctx, pkg.MemoryLimit, pkg.DiskLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*entities.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if allowedBy(pkg.AllowedNodes, node.ID) {
+			candidates = append(candidates, node)
+		}
+	}
+
+	ordered := s.servers.placer.Order(candidates, pkg.MemoryLimit, pkg.DiskLimit)
+	if len(ordered) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	return ordered[0], nil
+}
+
+// allowedBy reports whether id is in a package restriction, an empty one
+// allowing everything
+func allowedBy(allowed []uuid.UUID, id uuid.UUID) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ProvisioningService) logAudit(ctx context.Context, userID uuid.UUID, sub *entities.Subscription) {
+	err := s.auditRepo.Create(ctx, &entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditActionCreate,
+		Resource:    "subscription",
+		ResourceID:  &sub.ID,
+		Description: fmt.Sprintf("Ordered package %s (%s)", sub.Package.Name, sub.BillingCycle),
+		Metadata: map[string]interface{}{
+			"package_id": sub.PackageID,
+			"server_id":  sub.ServerID,
+		},
+	})
+	if err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", "subscription"), zap.Error(err))
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+)
+
+// orderFixture is a store with an online node with one free allocation, an
+// egg and a package allowing both
+type orderFixture struct {
+	store *fakeStore
+	node  *entities.Node
+	egg   *entities.Egg
+	pkg   *entities.Package
+}
+
+func newOrderFixture() *orderFixture {
+	store := newFakeStore()
+	node := &entities.Node{ID: uuid.New(), IsOnline: true, MemoryTotal: 8192, DiskTotal: 102400}
+	store.nodes[node.ID] = node
+	store.allocations = append(store.allocations, &entities.Allocation{ID: uuid.New(), NodeID: node.ID, IP: "203.0.113.10", Port: 25565})
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), DockerImages: []string{"ghcr.io/example/game:latest"}, StartupCommand: "./start.sh"}
+	store.eggs[egg.ID] = egg
+	pkg := &entities.Package{
+		ID:              uuid.New(),
+		Name:            "Starter",
+		PriceMonthly:    10,
+		SetupFee:        5,
+		Currency:        "USD",
+		MemoryLimit:     2048,
+		DiskLimit:       10240,
+		CPULimit:        100,
+		DatabaseLimit:   2,
+		AllocationLimit: 1,
+		BackupLimit:     3,
+		ServerLimit:     1,
+		AllowedEggs:     []uuid.UUID{egg.ID},
+		AllowedNodes:    []uuid.UUID{node.ID},
+		IsActive:        true,
+	}
+	store.packages[pkg.ID] = pkg
+	return &orderFixture{store: store, node: node, egg: egg, pkg: pkg}
+}
+
+// service returns a ProvisioningService on the fixture's store, planning
+// servers with allocations
+func (f *orderFixture) service(allocations repositories.AllocationRepository) *ProvisioningService {
+	cfg := &config.Config{}
+	servers := NewServerService(fakeServers{store: f.store}, fakeNodes{store: f.store}, allocations, nil,
+		fakeAuditLogs{store: f.store}, fakeActivityLogs{}, fakeQuotas{store: f.store}, fakeUsers{store: f.store},
+		fakeRoles{store: f.store}, fakeSubscriptions{store: f.store}, fakeEggs{store: f.store}, fakeEggVariables{},
+		fakeUnitOfWork{f.store}, fakeCommandHistory{}, &fakeNodeClient{}, cfg)
+	return NewProvisioningService(fakePackages{store: f.store}, fakeNodes{store: f.store}, fakeUsers{store: f.store},
+		fakeEggs{store: f.store}, fakeAuditLogs{store: f.store}, fakeUnitOfWork{f.store}, servers, cfg)
+}
+
+func TestOrderPackage(t *testing.T) {
+	f := newOrderFixture()
+	customer := addUser(f.store, "customer", 50)
+	s := f.service(fakeAllocations{store: f.store})
+	ctx := context.Background()
+
+	if _, err := s.OrderPackage(ctx, customer.ID, f.pkg.ID, "monthly", uuid.New(), uuid.Nil); !errors.Is(err, ErrEggNotFound) {
+		t.Errorf("unknown egg = %v, want %v", err, ErrEggNotFound)
+	}
+	if _, err := s.OrderPackage(ctx, customer.ID, f.pkg.ID, "monthly", f.egg.ID, uuid.New()); !errors.Is(err, ErrPackageNotAllowed) {
+		t.Errorf("node outside the package = %v, want %v", err, ErrPackageNotAllowed)
+	}
+	if _, err := s.OrderPackage(ctx, customer.ID, f.pkg.ID, "yearly", f.egg.ID, uuid.Nil); !errors.Is(err, ErrInvalidBillingCycle) {
+		t.Errorf("cycle without a price = %v, want %v", err, ErrInvalidBillingCycle)
+	}
+
+	sub, err := s.OrderPackage(ctx, customer.ID, f.pkg.ID, "monthly", f.egg.ID, uuid.Nil)
+	if err != nil {
+		t.Fatalf("OrderPackage: %v", err)
+	}
+
+	if got := f.store.users[customer.ID].Credits; got != 35 {
+		t.Errorf("credits = %v, want 35 after the first month and setup fee", got)
+	}
+	if len(f.store.transactions) != 1 || f.store.transactions[0].Amount != -15 {
+		t.Errorf("transactions = %+v, want one debit of 15", f.store.transactions)
+	}
+	if len(f.store.invoices) != 1 || f.store.invoices[0].Status != "paid" || f.store.invoices[0].SubscriptionID == nil || *f.store.invoices[0].SubscriptionID != sub.ID {
+		t.Errorf("invoices = %+v, want one paid invoice of the subscription", f.store.invoices)
+	}
+
+	stored, ok := f.store.subscriptions[sub.ID]
+	if !ok || stored.Status != entities.SubscriptionStatusActive || stored.ServerID == nil {
+		t.Fatalf("subscription = %+v, want an active subscription with a server", stored)
+	}
+	server, ok := f.store.servers[*stored.ServerID]
+	if !ok {
+		t.Fatal("subscription server was not created")
+	}
+	if server.OwnerID != customer.ID || server.NodeID != f.node.ID || server.EggID != f.egg.ID {
+		t.Errorf("server owner/node/egg = %s/%s/%s", server.OwnerID, server.NodeID, server.EggID)
+	}
+	if server.MemoryLimit != 2048 || server.DiskLimit != 10240 || server.CPULimit != 100 ||
+		server.DatabaseLimit != 2 || server.AllocationLimit != 1 || server.BackupLimit != 3 {
+		t.Errorf("server limits = %+v, want the package's", server)
+	}
+	if a := f.store.allocations[0]; a.ServerID == nil || *a.ServerID != server.ID || !a.IsPrimary {
+		t.Errorf("allocation = %+v, want it assigned as the server's primary", a)
+	}
+	if got := f.store.nodes[f.node.ID].MemoryAllocated; got != 2048 {
+		t.Errorf("node memory allocated = %d, want 2048", got)
+	}
+	if got := f.store.audited(customer.ID); len(got) != 1 || got[0] != entities.AuditActionCreate {
+		t.Errorf("audit = %v, want one create", got)
+	}
+}
+
+func TestOrderPackageInsufficientCredits(t *testing.T) {
+	f := newOrderFixture()
+	customer := addUser(f.store, "customer", 14)
+
+	_, err := f.service(fakeAllocations{store: f.store}).OrderPackage(context.Background(), customer.ID, f.pkg.ID, "monthly", f.egg.ID, uuid.Nil)
+	if !errors.Is(err, ErrInsufficientCredits) {
+		t.Fatalf("OrderPackage = %v, want %v", err, ErrInsufficientCredits)
+	}
+	if got := f.store.users[customer.ID].Credits; got != 14 {
+		t.Errorf("credits = %v, want 14", got)
+	}
+	if len(f.store.subscriptions) != 0 || len(f.store.servers) != 0 {
+		t.Errorf("order left %d subscriptions and %d servers", len(f.store.subscriptions), len(f.store.servers))
+	}
+}
+
+// takenAllocations hands out the node's allocations as free and then lets
+// another server take them, so assigning them fails after planning
+type takenAllocations struct {
+	fakeAllocations
+}
+
+func (f takenAllocations) GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Allocation, error) {
+	allocations, err := f.fakeAllocations.GetByNodeID(ctx, nodeID)
+	other := uuid.New()
+	for _, a := range f.store.allocations {
+		a.ServerID = &other
+	}
+	return allocations, err
+}
+
+func TestOrderPackageRollsBackWhenProvisioningFails(t *testing.T) {
+	f := newOrderFixture()
+	customer := addUser(f.store, "customer", 50)
+	s := f.service(takenAllocations{fakeAllocations{store: f.store}})
+
+	if _, err := s.OrderPackage(context.Background(), customer.ID, f.pkg.ID, "monthly", f.egg.ID, uuid.Nil); err == nil {
+		t.Fatal("OrderPackage succeeded with its allocation taken")
+	}
+
+	if got := f.store.users[customer.ID].Credits; got != 50 {
+		t.Errorf("credits = %v, want the charge rolled back to 50", got)
+	}
+	if len(f.store.transactions) != 0 || len(f.store.invoices) != 0 {
+		t.Errorf("order left %d transactions and %d invoices", len(f.store.transactions), len(f.store.invoices))
+	}
+	if len(f.store.subscriptions) != 0 || len(f.store.servers) != 0 {
+		t.Errorf("order left %d subscriptions and %d servers", len(f.store.subscriptions), len(f.store.servers))
+	}
+	if got := f.store.nodes[f.node.ID].MemoryAllocated; got != 0 {
+		t.Errorf("node memory allocated = %d, want 0", got)
+	}
+}
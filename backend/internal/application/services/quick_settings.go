@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var (
+	ErrInvalidQuickSettings = errors.New("invalid quick settings")
+	ErrQuickSettingNotFound = errors.New("quick setting not found")
+)
+
+// ValidateQuickSettings checks an egg's quick settings against its variables:
+// keys are unique, every setting sets one of the variables, toggles have
+// distinct on and off values and the options of selects pass the variable's
+// rules
+func ValidateQuickSettings(settings []entities.EggQuickSetting, vars []*entities.EggVariable) error {
+	byEnv := make(map[string]*entities.EggVariable, len(vars))
+	for _, v := range vars {
+		byEnv[v.EnvVariable] = v
+	}
+
+	keys := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		if s.Key == "" || s.Label == "" {
+			return fmt.Errorf("%w: every setting needs a key and a label", ErrInvalidQuickSettings)
+		}
+		if keys[s.Key] {
+			return fmt.Errorf("%w: %s is defined twice", ErrInvalidQuickSettings, s.Key)
+		}
+		keys[s.Key] = true
+
+		v, ok := byEnv[s.Variable]
+		if !ok {
+			return fmt.Errorf("%w: %s sets unknown variable %q", ErrInvalidQuickSettings, s.Key, s.Variable)
+		}
+
+		switch s.Type {
+		case entities.QuickSettingNumber, entities.QuickSettingText:
+		case entities.QuickSettingToggle:
+			if toggleValue(s, true) == toggleValue(s, false) {
+				return fmt.Errorf("%w: %s has the same on and off value", ErrInvalidQuickSettings, s.Key)
+			}
+		case entities.QuickSettingSelect:
+			if len(s.Options) == 0 {
+				return fmt.Errorf("%w: %s has no options", ErrInvalidQuickSettings, s.Key)
+			}
+			for _, o := range s.Options {
+				if err := ValidateVariableValue(v.Rules, o.Value); err != nil {
+					return fmt.Errorf("%w: %s option %q %s", ErrInvalidQuickSettings, s.Key, o.Value, err)
+				}
+			}
+		default:
+			return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidQuickSettings, s.Key, s.Type)
+		}
+	}
+	return nil
+}
+
+// QuickSettingValue is a quick setting of a server with its current value
+type QuickSettingValue struct {
+	entities.EggQuickSetting
+	Value    string `json:"value"` // "true" or "false" for toggles, the variable's value otherwise
+	Editable bool   `json:"editable"`
+}
+
+// VisibleQuickSettings returns the quick settings of the variables a user may
+// see, as VisibleServerVariables decides, with their current values
+func VisibleQuickSettings(settings []entities.EggQuickSetting, vars []*entities.EggVariable, env map[string]string, admin bool) []QuickSettingValue {
+	visible := make(map[string]ServerVariableValue, len(vars))
+	for _, v := range VisibleServerVariables(vars, env, admin) {
+		visible[v.EnvVariable] = v
+	}
+
+	values := make([]QuickSettingValue, 0, len(settings))
+	for _, s := range settings {
+		v, ok := visible[s.Variable]
+		if !ok {
+			continue
+		}
+		value := v.Value
+		if s.Type == entities.QuickSettingToggle {
+			value = strconv.FormatBool(value == toggleValue(s, true))
+		}
+		values = append(values, QuickSettingValue{EggQuickSetting: s, Value: value, Editable: v.Editable})
+	}
+	return values
+}
+
+// QuickSettingEdits turns values keyed by quick setting key into variable
+// edits keyed by environment variable name and merges them with the raw
+// variable edits, for ResolveVariableEdits to check like any other. Toggles
+// take true or false and selects one of their options' values. Setting a
+// variable to two different values is rejected.
+func QuickSettingEdits(settings []entities.EggQuickSetting, values map[string]string, variables map[string]string) (map[string]string, error) {
+	byKey := make(map[string]entities.EggQuickSetting, len(settings))
+	for _, s := range settings {
+		byKey[s.Key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	edits := make(map[string]string, len(variables)+len(values))
+	maps.Copy(edits, variables)
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrQuickSettingNotFound, key)
+		}
+
+		value := values[key]
+		switch s.Type {
+		case entities.QuickSettingToggle:
+			on, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, &VariableError{Variable: s.Variable, Message: "must be true or false"}
+			}
+			value = toggleValue(s, on)
+		case entities.QuickSettingSelect:
+			if !hasOption(s.Options, value) {
+				return nil, &VariableError{Variable: s.Variable, Message: "must be one of the setting's options"}
+			}
+		}
+
+		if previous, ok := edits[s.Variable]; ok && previous != value {
+			return nil, &VariableError{Variable: s.Variable, Message: "is set to different values"}
+		}
+		edits[s.Variable] = value
+	}
+	return edits, nil
+}
+
+// toggleValue returns the variable value of a toggle turned on or off
+func toggleValue(s entities.EggQuickSetting, on bool) string {
+	if on {
+		if s.On == "" {
+			return "true"
+		}
+		return s.On
+	}
+	if s.Off == "" {
+		return "false"
+	}
+	return s.Off
+}
+
+func hasOption(options []entities.QuickSettingOption, value string) bool {
+	for _, o := range options {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// survivalSettings are the quick settings of a survival game egg
+func survivalSettings() ([]entities.EggQuickSetting, []*entities.EggVariable) {
+	vars := []*entities.EggVariable{
+		{EnvVariable: "MAX_PLAYERS", DefaultValue: "20", Rules: "required|integer|max:100", UserViewable: true, UserEditable: true},
+		{EnvVariable: "PVP", DefaultValue: "0", Rules: "required|in:0,1", UserViewable: true, UserEditable: true, SortOrder: 1},
+		{EnvVariable: "DIFFICULTY", DefaultValue: "normal", Rules: "required|in:peaceful,easy,normal,hard", UserViewable: true, UserEditable: true, SortOrder: 2},
+	}
+	settings := []entities.EggQuickSetting{
+		{Key: "max_players", Label: "Max players", Type: entities.QuickSettingNumber, Variable: "MAX_PLAYERS"},
+		{Key: "pvp", Label: "PvP", Type: entities.QuickSettingToggle, Variable: "PVP", On: "1", Off: "0"},
+		{Key: "difficulty", Label: "Difficulty", Type: entities.QuickSettingSelect, Variable: "DIFFICULTY", Options: []entities.QuickSettingOption{
+			{Label: "Easy", Value: "easy"}, {Label: "Hard", Value: "hard"},
+		}},
+	}
+	return settings, vars
+}
+
+func TestQuickSettingEditsSetMaxPlayers(t *testing.T) {
+	settings, vars := survivalSettings()
+	if err := ValidateQuickSettings(settings, vars); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"MAX_PLAYERS": "20", "PVP": "0", "DIFFICULTY": "normal"}
+
+	edits, err := QuickSettingEdits(settings, map[string]string{"max_players": "64", "pvp": "true"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := ResolveVariableEdits(vars, env, edits, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, c := range changed {
+		got[c.Variable.EnvVariable] = c.Value
+	}
+	if len(got) != 2 || got["MAX_PLAYERS"] != "64" || got["PVP"] != "1" {
+		t.Errorf("changed %v, want MAX_PLAYERS 64 and PVP 1", got)
+	}
+
+	// Values are checked against the variable's rules
+	for _, value := range []string{"500", "lots"} {
+		edits, err := QuickSettingEdits(settings, map[string]string{"max_players": value}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ResolveVariableEdits(vars, env, edits, false)
+		var varErr *VariableError
+		if !errors.As(err, &varErr) || varErr.Variable != "MAX_PLAYERS" {
+			t.Errorf("max players %q = %v, want a MAX_PLAYERS variable error", value, err)
+		}
+	}
+
+	// A locked variable stays locked behind its setting
+	vars[0].UserEditable = false
+	if _, err := ResolveVariableEdits(vars, env, edits, false); !errors.Is(err, ErrVariableNotEditable) {
+		t.Errorf("locked max players = %v, want %v", err, ErrVariableNotEditable)
+	}
+}
+
+func TestQuickSettingEditsRejectBadValues(t *testing.T) {
+	settings, _ := survivalSettings()
+	var varErr *VariableError
+	for name, tc := range map[string]struct {
+		values, variables map[string]string
+		variable          string
+	}{
+		"toggle":      {map[string]string{"pvp": "sometimes"}, nil, "PVP"},
+		"select":      {map[string]string{"difficulty": "peaceful"}, nil, "DIFFICULTY"},
+		"conflicting": {map[string]string{"max_players": "64"}, map[string]string{"MAX_PLAYERS": "32"}, "MAX_PLAYERS"},
+	} {
+		if _, err := QuickSettingEdits(settings, tc.values, tc.variables); !errors.As(err, &varErr) || varErr.Variable != tc.variable {
+			t.Errorf("%s: %v, want a %s variable error", name, err, tc.variable)
+		}
+	}
+	if _, err := QuickSettingEdits(settings, map[string]string{"motd": "hi"}, nil); !errors.Is(err, ErrQuickSettingNotFound) {
+		t.Errorf("unknown setting = %v, want %v", err, ErrQuickSettingNotFound)
+	}
+}
+
+func TestVisibleQuickSettings(t *testing.T) {
+	settings, vars := survivalSettings()
+	vars[2].UserViewable = false
+
+	values := VisibleQuickSettings(settings, vars, map[string]string{"MAX_PLAYERS": "32", "PVP": "1"}, false)
+	if len(values) != 2 {
+		t.Fatalf("settings %+v, want the hidden difficulty left out", values)
+	}
+	if values[0].Key != "max_players" || values[0].Value != "32" || !values[0].Editable {
+		t.Errorf("max players %+v, want the editable stored value", values[0])
+	}
+	if values[1].Key != "pvp" || values[1].Value != "true" {
+		t.Errorf("pvp %+v, want the toggle on", values[1])
+	}
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotSubUser         = errors.New("user is not a sub-account of this reseller")
+	ErrQuotaExceeded      = errors.New("resource quota exceeded")
+	ErrQuotaAboveReseller = errors.New("quota exceeds the reseller's own limits")
+	ErrEmailInUse         = errors.New("email already in use")
+	ErrUsernameInUse      = errors.New("username already in use")
+	ErrUserHasServers     = errors.New("cannot delete user with active servers")
+)
+
+// maxResellerDepth bounds how deep nested reseller trees are walked
+const maxResellerDepth = 5
+
+// ResellerService handles reseller sub-account operations
+type ResellerService struct {
+	userRepo       repositories.UserRepository
+	roleRepo       repositories.RoleRepository
+	quotaRepo      repositories.UserQuotaRepository
+	serverRepo     repositories.ServerRepository
+	auditRepo      repositories.AuditLogRepository
+	uow            repositories.UnitOfWork
+	billingService *BillingService
+}
+
+// NewResellerService creates a new ResellerService
+func NewResellerService(
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	quotaRepo repositories.UserQuotaRepository,
+	serverRepo repositories.ServerRepository,
+	auditRepo repositories.AuditLogRepository,
+	uow repositories.UnitOfWork,
+	billingService *BillingService,
+) *ResellerService {
+	return &ResellerService{
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		quotaRepo:      quotaRepo,
+		serverRepo:     serverRepo,
+		auditRepo:      auditRepo,
+		uow:            uow,
+		billingService: billingService,
+	}
+}
+
+// CreateSubUserRequest represents a sub-user creation request
+type CreateSubUserRequest struct {
+	Email     string        `json:"email" validate:"required,email"`
+	Username  string        `json:"username" validate:"required,min=3,max=50,alphanum"`
+	Password  string        `json:"password" validate:"required,min=8"`
+	FirstName string        `json:"first_name" validate:"max=100"`
+	LastName  string        `json:"last_name" validate:"max=100"`
+	Credits   float64       `json:"credits" validate:"min=0"` // Initial credits taken from the reseller's balance
+	Quota     *QuotaRequest `json:"quota"`                    // Initial resource limits, none when nil
+}
+
+// QuotaRequest represents the limits a reseller sets for a sub-user
+type QuotaRequest struct {
+	MaxServers  int   `json:"max_servers" validate:"min=0"`
+	MemoryLimit int64 `json:"memory_limit" validate:"min=0"`
+	DiskLimit   int64 `json:"disk_limit" validate:"min=0"`
+	CPULimit    int   `json:"cpu_limit" validate:"min=0"`
+}
+
+// CreateSubUser creates a new user owned by a reseller. The user, their quota
+// and the initial credits are created together or not at all.
+func (s *ResellerService) CreateSubUser(ctx context.Context, resellerID uuid.UUID, req *CreateSubUserRequest) (*entities.User, error) {
+	if _, err := s.userRepo.GetByEmail(ctx, req.Email); err == nil {
+		return nil, ErrEmailInUse
+	}
+	if _, err := s.userRepo.GetByUsername(ctx, req.Username); err == nil {
+		return nil, ErrUsernameInUse
+	}
+
+	role, err := s.roleRepo.GetDefault(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default role: %w", err)
+	}
+
+	hash, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &entities.User{
+		ID:            uuid.New(),
+		Email:         req.Email,
+		Username:      req.Username,
+		PasswordHash:  hash,
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		Status:        entities.UserStatusActive,
+		EmailVerified: true,
+		RoleID:        role.ID,
+		ResellerID:    &resellerID,
+	}
+
+	var quota *entities.UserQuota
+	if req.Quota != nil {
+		if quota, err = s.newQuota(ctx, resellerID, user.ID, req.Quota); err != nil {
+			return nil, err
+		}
+	}
+
+	var reseller *entities.User
+	if req.Credits > 0 {
+		if reseller, err = s.userRepo.GetByID(ctx, resellerID); err != nil {
+			return nil, ErrUserNotFound
+		}
+		if err := s.billingService.checkTransferLimit(ctx, resellerID); err != nil {
+			return nil, err
+		}
+	}
+
+	var debit *entities.Transaction
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		if err := repos.Users().Create(ctx, user); err != nil {
+			return err
+		}
+		if quota != nil {
+			if err := repos.UserQuotas().Upsert(ctx, quota); err != nil {
+				return err
+			}
+		}
+		if reseller != nil {
+			debit, err = s.billingService.transfer(ctx, repos, reseller, user, req.Credits)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientCredits) {
+			return nil, ErrInsufficientCredits
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	s.logAudit(ctx, resellerID, entities.AuditActionCreate, "user", &user.ID)
+	if quota != nil {
+		s.logAudit(ctx, resellerID, entities.AuditActionUpdate, "user_quota", &user.ID)
+	}
+	if debit != nil {
+		s.billingService.auditTransfer(ctx, debit, user)
+	}
+	return user, nil
+}
+
+// GetSubUser retrieves a sub-user, ensuring it belongs to the reseller's tree
+func (s *ResellerService) GetSubUser(ctx context.Context, resellerID, userID uuid.UUID) (*entities.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	owns, err := s.OwnsUser(ctx, resellerID, user)
+	if err != nil {
+		return nil, err
+	}
+	if !owns {
+		return nil, ErrNotSubUser
+	}
+	return user, nil
+}
+
+// ListSubUsers retrieves every user in the reseller's sub-tree
+func (s *ResellerService) ListSubUsers(ctx context.Context, resellerID uuid.UUID) ([]*entities.User, error) {
+	var result []*entities.User
+	parents := []uuid.UUID{resellerID}
+
+	for depth := 0; depth < maxResellerDepth && len(parents) > 0; depth++ {
+		var next []uuid.UUID
+		for _, parentID := range parents {
+			children, err := s.userRepo.GetByResellerID(ctx, parentID)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				result = append(result, child)
+				next = append(next, child.ID)
+			}
+		}
+		parents = next
+	}
+
+	return result, nil
+}
+
+// TreeIDs returns the reseller's ID and the IDs of every user in their sub-tree
+func (s *ResellerService) TreeIDs(ctx context.Context, resellerID uuid.UUID) ([]uuid.UUID, error) {
+	users, err := s.ListSubUsers(ctx, resellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(users)+1)
+	ids = append(ids, resellerID)
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+// ListServers retrieves servers owned by the reseller or anyone in their sub-tree
+func (s *ResellerService) ListServers(ctx context.Context, resellerID uuid.UUID, params repositories.ListParams) ([]*entities.Server, int64, error) {
+	ownerIDs, err := s.TreeIDs(ctx, resellerID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.serverRepo.GetByOwnerIDs(ctx, ownerIDs, params)
+}
+
+// SetSubUserStatus activates or suspends a sub-user
+func (s *ResellerService) SetSubUserStatus(ctx context.Context, resellerID, userID uuid.UUID, status entities.UserStatus) error {
+	user, err := s.GetSubUser(ctx, resellerID, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Status = status
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, resellerID, entities.AuditActionUpdate, "user", &userID)
+	return nil
+}
+
+// DeleteSubUser deletes a sub-user that no longer owns any servers
+func (s *ResellerService) DeleteSubUser(ctx context.Context, resellerID, userID uuid.UUID) error {
+	if _, err := s.GetSubUser(ctx, resellerID, userID); err != nil {
+		return err
+	}
+
+	count, err := s.serverRepo.CountByOwnerID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserHasServers
+	}
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	_ = s.quotaRepo.Delete(ctx, userID)
+
+	s.logAudit(ctx, resellerID, entities.AuditActionDelete, "user", &userID)
+	return nil
+}
+
+// AllocateCredits moves credits from the reseller's balance to a direct sub-user
+func (s *ResellerService) AllocateCredits(ctx context.Context, resellerID, userID uuid.UUID, amount float64) (*entities.Transaction, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.ResellerID == nil || *user.ResellerID != resellerID {
+		return nil, ErrNotSubUser
+	}
+
+	return s.billingService.Transfer(ctx, resellerID, userID, amount)
+}
+
+// SetQuota sets the resource limits of a sub-user. When the reseller is itself
+// limited, the sub-user's limits may not exceed the reseller's.
+func (s *ResellerService) SetQuota(ctx context.Context, resellerID, userID uuid.UUID, req *QuotaRequest) (*entities.UserQuota, error) {
+	if _, err := s.GetSubUser(ctx, resellerID, userID); err != nil {
+		return nil, err
+	}
+
+	quota, err := s.newQuota(ctx, resellerID, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quotaRepo.Upsert(ctx, quota); err != nil {
+		return nil, fmt.Errorf("failed to save quota: %w", err)
+	}
+
+	s.logAudit(ctx, resellerID, entities.AuditActionUpdate, "user_quota", &userID)
+	return quota, nil
+}
+
+// newQuota builds a sub-user's quota set by the reseller, refusing limits
+// above the reseller's own
+func (s *ResellerService) newQuota(ctx context.Context, resellerID, userID uuid.UUID, req *QuotaRequest) (*entities.UserQuota, error) {
+	quota := &entities.UserQuota{
+		UserID:      userID,
+		MaxServers:  req.MaxServers,
+		MemoryLimit: req.MemoryLimit,
+		DiskLimit:   req.DiskLimit,
+		CPULimit:    req.CPULimit,
+		SetBy:       &resellerID,
+	}
+
+	if parent, err := s.quotaRepo.GetByUserID(ctx, resellerID); err == nil && parent != nil {
+		if !withinLimit(int64(quota.MaxServers), int64(parent.MaxServers)) ||
+			!withinLimit(quota.MemoryLimit, parent.MemoryLimit) ||
+			!withinLimit(quota.DiskLimit, parent.DiskLimit) ||
+			!withinLimit(int64(quota.CPULimit), int64(parent.CPULimit)) {
+			return nil, ErrQuotaAboveReseller
+		}
+	}
+	return quota, nil
+}
+
+// OwnsUser reports whether a user belongs to the reseller's sub-tree
+func (s *ResellerService) OwnsUser(ctx context.Context, resellerID uuid.UUID, user *entities.User) (bool, error) {
+	current := user
+	for depth := 0; depth < maxResellerDepth; depth++ {
+		if current.ResellerID == nil {
+			return false, nil
+		}
+		if *current.ResellerID == resellerID {
+			return true, nil
+		}
+
+		parent, err := s.userRepo.GetByID(ctx, *current.ResellerID)
+		if err != nil {
+			return false, nil
+		}
+		current = parent
+	}
+	return false, nil
+}
+
+func (s *ResellerService) logAudit(ctx context.Context, userID uuid.UUID, action entities.AuditAction, resource string, resourceID *uuid.UUID) {
+	log := &entities.AuditLog{
+		UserID:     &userID,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+	}
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", resource), zap.Error(err))
+	}
+}
+
+// withinLimit reports whether value respects limit, where zero means unlimited
+func withinLimit(value, limit int64) bool {
+	if limit == 0 {
+		return true
+	}
+	return value > 0 && value <= limit
+}
+
+// CheckQuota verifies that an owner can take on a new server with the given resources
+func CheckQuota(ctx context.Context, quotaRepo repositories.UserQuotaRepository, serverRepo repositories.ServerRepository, ownerID uuid.UUID, memory, disk int64, cpu int) error {
+	quota, err := quotaRepo.GetByUserID(ctx, ownerID)
+	if err != nil || quota == nil {
+		// No quota configured
+		return nil
+	}
+
+	servers, err := serverRepo.GetByOwnerID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	totalMemory, totalDisk, totalCPU := memory, disk, cpu
+	for _, server := range servers {
+		totalMemory += server.MemoryLimit
+		totalDisk += server.DiskLimit
+		totalCPU += server.CPULimit
+	}
+
+	if !quota.Allows(len(servers)+1, totalMemory, totalDisk, totalCPU) {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+)
+
+// resellerTree is a reseller with a sub-user and a sub-sub-user, next to an
+// unrelated reseller with a sub-user of their own
+type resellerTree struct {
+	reseller, sub, subSub, other, otherSub *entities.User
+}
+
+func newResellerTree(store *fakeStore) resellerTree {
+	user := func(name string, reseller *entities.User, credits float64) *entities.User {
+		u := &entities.User{ID: uuid.New(), Username: name, Credits: credits, Status: entities.UserStatusActive}
+		if reseller != nil {
+			u.ResellerID = &reseller.ID
+		}
+		store.users[u.ID] = u
+		return u
+	}
+
+	var t resellerTree
+	t.reseller = user("reseller", nil, 100)
+	t.sub = user("sub", t.reseller, 0)
+	t.subSub = user("subsub", t.sub, 0)
+	t.other = user("other", nil, 100)
+	t.otherSub = user("othersub", t.other, 0)
+	return t
+}
+
+func addServer(store *fakeStore, owner *entities.User, memory int64) *entities.Server {
+	server := &entities.Server{ID: uuid.New(), OwnerID: owner.ID, MemoryLimit: memory, DiskLimit: 1024, CPULimit: 100}
+	store.servers[server.ID] = server
+	return server
+}
+
+func newTestResellerService(store *fakeStore) *ResellerService {
+	cfg := &config.Config{Billing: config.BillingConfig{ResellerTransfersOwnOnly: true}}
+	uow := fakeUnitOfWork{store: store}
+	billing := NewBillingService(fakeTransactions{store: store}, fakeUsers{store: store}, fakeAuditLogs{store: store}, uow, cfg)
+	return NewResellerService(fakeUsers{store: store}, fakeRoles{store: store}, fakeQuotas{store: store}, fakeServers{store: store}, fakeAuditLogs{store: store}, uow, billing)
+}
+
+func TestResellerListServersScopedToSubTree(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	want := map[uuid.UUID]bool{
+		addServer(store, tree.reseller, 1024).ID: true,
+		addServer(store, tree.sub, 1024).ID:      true,
+		addServer(store, tree.subSub, 1024).ID:   true,
+	}
+	addServer(store, tree.other, 1024)
+	addServer(store, tree.otherSub, 1024)
+
+	s := newTestResellerService(store)
+	servers, total, err := s.ListServers(context.Background(), tree.reseller.ID, repositories.ListParams{Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("ListServers: %v", err)
+	}
+	if total != int64(len(want)) || len(servers) != len(want) {
+		t.Fatalf("got %d servers (total %d), want %d", len(servers), total, len(want))
+	}
+	for _, server := range servers {
+		if !want[server.ID] {
+			t.Errorf("server %s of owner %s listed outside the reseller's tree", server.ID, server.OwnerID)
+		}
+	}
+}
+
+func TestResellerGetSubUserScope(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	s := newTestResellerService(store)
+	ctx := context.Background()
+
+	for _, user := range []*entities.User{tree.sub, tree.subSub} {
+		if _, err := s.GetSubUser(ctx, tree.reseller.ID, user.ID); err != nil {
+			t.Errorf("GetSubUser(%s) = %v, want the sub-user", user.Username, err)
+		}
+	}
+	for _, user := range []*entities.User{tree.other, tree.otherSub} {
+		if _, err := s.GetSubUser(ctx, tree.reseller.ID, user.ID); !errors.Is(err, ErrNotSubUser) {
+			t.Errorf("GetSubUser(%s) = %v, want %v", user.Username, err, ErrNotSubUser)
+		}
+	}
+
+	// A sub-user's own sub-tree does not reach up to their reseller
+	if _, err := s.GetSubUser(ctx, tree.sub.ID, tree.reseller.ID); !errors.Is(err, ErrNotSubUser) {
+		t.Errorf("GetSubUser(reseller) from sub = %v, want %v", err, ErrNotSubUser)
+	}
+}
+
+func TestResellerAllocateCredits(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	s := newTestResellerService(store)
+	ctx := context.Background()
+
+	if _, err := s.AllocateCredits(ctx, tree.reseller.ID, tree.sub.ID, 40); err != nil {
+		t.Fatalf("AllocateCredits: %v", err)
+	}
+	if got := store.users[tree.reseller.ID].Credits; got != 60 {
+		t.Errorf("reseller credits = %.2f, want 60", got)
+	}
+	if got := store.users[tree.sub.ID].Credits; got != 40 {
+		t.Errorf("sub-user credits = %.2f, want 40", got)
+	}
+
+	// Only direct sub-users are funded by the reseller
+	for _, user := range []*entities.User{tree.subSub, tree.otherSub} {
+		if _, err := s.AllocateCredits(ctx, tree.reseller.ID, user.ID, 10); !errors.Is(err, ErrNotSubUser) {
+			t.Errorf("AllocateCredits(%s) = %v, want %v", user.Username, err, ErrNotSubUser)
+		}
+	}
+	if got := store.users[tree.reseller.ID].Credits; got != 60 {
+		t.Errorf("reseller credits = %.2f after rejected allocations, want 60", got)
+	}
+}
+
+func TestResellerCreateSubUser(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	role := &entities.Role{ID: uuid.New(), Name: "user", IsDefault: true}
+	store.roles[role.ID] = role
+	store.quotas[tree.reseller.ID] = &entities.UserQuota{UserID: tree.reseller.ID, MaxServers: 5}
+	s := newTestResellerService(store)
+	ctx := context.Background()
+
+	user, err := s.CreateSubUser(ctx, tree.reseller.ID, &CreateSubUserRequest{
+		Email:    "alex@example.com",
+		Username: "alex",
+		Password: "correct horse",
+		Credits:  30,
+		Quota:    &QuotaRequest{MaxServers: 2},
+	})
+	if err != nil {
+		t.Fatalf("CreateSubUser: %v", err)
+	}
+	if stored := store.users[user.ID]; stored == nil || *stored.ResellerID != tree.reseller.ID || stored.RoleID != role.ID || stored.Credits != 30 {
+		t.Errorf("stored user = %+v, want a funded sub-user of the reseller", stored)
+	}
+	if got := store.users[tree.reseller.ID].Credits; got != 70 {
+		t.Errorf("reseller credits = %.2f, want 70", got)
+	}
+	if quota := store.quotas[user.ID]; quota == nil || quota.MaxServers != 2 {
+		t.Errorf("stored quota = %+v, want 2 servers", quota)
+	}
+	if len(store.transactions) != 2 {
+		t.Errorf("recorded %d transactions, want both sides of the transfer", len(store.transactions))
+	}
+}
+
+func TestResellerCreateSubUserIsAtomic(t *testing.T) {
+	for name, req := range map[string]*CreateSubUserRequest{
+		"credits above the balance":  {Credits: 150, Quota: &QuotaRequest{MaxServers: 2}},
+		"quota above the reseller's": {Credits: 30, Quota: &QuotaRequest{MaxServers: 6}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newFakeStore()
+			tree := newResellerTree(store)
+			role := &entities.Role{ID: uuid.New(), Name: "user", IsDefault: true}
+			store.roles[role.ID] = role
+			store.quotas[tree.reseller.ID] = &entities.UserQuota{UserID: tree.reseller.ID, MaxServers: 5}
+			s := newTestResellerService(store)
+
+			req.Email, req.Username, req.Password = "alex@example.com", "alex", "correct horse"
+			user, err := s.CreateSubUser(context.Background(), tree.reseller.ID, req)
+			if err == nil || user != nil {
+				t.Fatalf("CreateSubUser = %v, %v, want no user and an error", user, err)
+			}
+			if _, err := (fakeUsers{store: store}).GetByUsername(context.Background(), "alex"); err == nil {
+				t.Error("the user was kept after the failure")
+			}
+			if len(store.quotas) != 1 || len(store.transactions) != 0 {
+				t.Errorf("kept %d quotas and %d transactions, want only the reseller's quota", len(store.quotas), len(store.transactions))
+			}
+			if got := store.users[tree.reseller.ID].Credits; got != 100 {
+				t.Errorf("reseller credits = %.2f, want 100", got)
+			}
+		})
+	}
+}
+
+func TestResellerSetQuota(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	store.quotas[tree.reseller.ID] = &entities.UserQuota{UserID: tree.reseller.ID, MaxServers: 5, MemoryLimit: 8192}
+	s := newTestResellerService(store)
+	ctx := context.Background()
+
+	if _, err := s.SetQuota(ctx, tree.reseller.ID, tree.sub.ID, &QuotaRequest{MaxServers: 2, MemoryLimit: 4096}); err != nil {
+		t.Fatalf("SetQuota within the reseller's limits: %v", err)
+	}
+	if quota := store.quotas[tree.sub.ID]; quota == nil || quota.MaxServers != 2 || *quota.SetBy != tree.reseller.ID {
+		t.Errorf("stored quota = %+v, want 2 servers set by the reseller", quota)
+	}
+
+	for name, req := range map[string]*QuotaRequest{
+		"above":     {MaxServers: 6, MemoryLimit: 4096},
+		"unlimited": {MaxServers: 2, MemoryLimit: 0},
+	} {
+		if _, err := s.SetQuota(ctx, tree.reseller.ID, tree.sub.ID, req); !errors.Is(err, ErrQuotaAboveReseller) {
+			t.Errorf("SetQuota %s = %v, want %v", name, err, ErrQuotaAboveReseller)
+		}
+	}
+
+	if _, err := s.SetQuota(ctx, tree.reseller.ID, tree.otherSub.ID, &QuotaRequest{MaxServers: 1}); !errors.Is(err, ErrNotSubUser) {
+		t.Errorf("SetQuota outside the tree = %v, want %v", err, ErrNotSubUser)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	addServer(store, tree.sub, 2048)
+	store.quotas[tree.sub.ID] = &entities.UserQuota{UserID: tree.sub.ID, MaxServers: 2, MemoryLimit: 4096}
+	quotas := fakeQuotas{store: store}
+	servers := fakeServers{store: store}
+	ctx := context.Background()
+
+	if err := CheckQuota(ctx, quotas, servers, tree.sub.ID, 2048, 1024, 100); err != nil {
+		t.Errorf("CheckQuota within limits = %v", err)
+	}
+	if err := CheckQuota(ctx, quotas, servers, tree.sub.ID, 3072, 1024, 100); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckQuota over memory = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	addServer(store, tree.sub, 1024)
+	if err := CheckQuota(ctx, quotas, servers, tree.sub.ID, 512, 1024, 100); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckQuota over server count = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	// Users without a quota are not limited
+	if err := CheckQuota(ctx, quotas, servers, tree.other.ID, 1<<20, 1<<20, 400); err != nil {
+		t.Errorf("CheckQuota without quota = %v", err)
+	}
+}
+
+func TestResellerTreeIDs(t *testing.T) {
+	store := newFakeStore()
+	tree := newResellerTree(store)
+	s := newTestResellerService(store)
+
+	ids, err := s.TreeIDs(context.Background(), tree.reseller.ID)
+	if err != nil {
+		t.Fatalf("TreeIDs: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != tree.reseller.ID || !slices.Contains(ids, tree.sub.ID) || !slices.Contains(ids, tree.subSub.ID) {
+		t.Errorf("tree = %v, want the reseller followed by %s and %s", ids, tree.sub.ID, tree.subSub.ID)
+	}
+}
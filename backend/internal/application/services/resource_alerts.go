@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// ErrInvalidResourceAlerts is returned for thresholds outside 0-100 or a
+// duration outside the allowed range
+var ErrInvalidResourceAlerts = errors.New("invalid resource alert thresholds")
+
+// Bounds of how long usage has to stay past a threshold, in seconds
+const (
+	MinAlertDuration = 60
+	MaxAlertDuration = 24 * 60 * 60
+)
+
+// ValidateResourceAlerts checks a server's alert thresholds. The duration only
+// matters, and is only checked, when a threshold is set.
+func ValidateResourceAlerts(alerts entities.ResourceAlerts) error {
+	if alerts.CPU < 0 || alerts.CPU > 100 || alerts.Memory < 0 || alerts.Memory > 100 {
+		return ErrInvalidResourceAlerts
+	}
+	if alerts.CPU == 0 && alerts.Memory == 0 {
+		return nil
+	}
+	if alerts.Duration < MinAlertDuration || alerts.Duration > MaxAlertDuration {
+		return ErrInvalidResourceAlerts
+	}
+	return nil
+}
+
+// AlertTransition is the outcome of evaluating a usage sample
+type AlertTransition int
+
+const (
+	AlertUnchanged AlertTransition = iota
+	AlertFired
+	AlertResolved
+)
+
+// AlertState tracks one resource of one server between samples
+type AlertState struct {
+	Firing bool      // An alert was raised and has not resolved yet
+	since  time.Time // When usage first crossed the line it has to stay past, zero if it has not
+}
+
+// Observe evaluates a usage sample, as a percentage, against a threshold. An
+// alert fires once usage has stayed at or above threshold for duration, and
+// resolves once it has stayed below threshold minus margin for as long. The
+// gap between the two lines keeps usage hovering around the threshold from
+// raising an alert on every crossing.
+func (s *AlertState) Observe(usage float64, threshold, margin int, duration time.Duration, now time.Time) AlertTransition {
+	crossed := usage >= float64(threshold)
+	if s.Firing {
+		crossed = usage < float64(threshold-margin)
+	}
+
+	if !crossed {
+		s.since = time.Time{}
+		return AlertUnchanged
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	if now.Sub(s.since) < duration {
+		return AlertUnchanged
+	}
+
+	s.since = time.Time{}
+	s.Firing = !s.Firing
+	if s.Firing {
+		return AlertFired
+	}
+	return AlertResolved
+}
+
+// CPUPercentOfLimit expresses CPU usage, where 100 is one core, as a
+// percentage of a server's CPU limit. Servers without a limit are measured
+// against one core.
+func CPUPercentOfLimit(usage float64, limit int) float64 {
+	if limit <= 0 {
+		limit = 100
+	}
+	return usage / float64(limit) * 100
+}
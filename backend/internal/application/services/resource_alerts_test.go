@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// observeAll feeds samples taken every interval from start to an AlertState
+// and returns the transitions with the sample index they happened at
+func observeAll(state *AlertState, samples []float64, start time.Time, interval time.Duration) map[int]AlertTransition {
+	transitions := make(map[int]AlertTransition)
+	for i, usage := range samples {
+		if t := state.Observe(usage, 90, 10, 5*time.Minute, start.Add(time.Duration(i)*interval)); t != AlertUnchanged {
+			transitions[i] = t
+		}
+	}
+	return transitions
+}
+
+// repeat returns n samples of usage
+func repeat(usage float64, n int) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = usage
+	}
+	return samples
+}
+
+func TestAlertFiresOnceUnderSustainedUsage(t *testing.T) {
+	var state AlertState
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// An hour at 95%, sampled every 10 seconds
+	transitions := observeAll(&state, repeat(95, 360), start, 10*time.Second)
+	if len(transitions) != 1 || transitions[30] != AlertFired {
+		t.Fatalf("transitions = %v, want a single fire after 5 minutes (sample 30)", transitions)
+	}
+	if !state.Firing {
+		t.Error("state not firing after the alert fired")
+	}
+}
+
+func TestAlertResolvesOnRecovery(t *testing.T) {
+	var state AlertState
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	observeAll(&state, repeat(95, 31), start, 10*time.Second)
+	if !state.Firing {
+		t.Fatal("alert did not fire")
+	}
+
+	// Between the threshold and the margin under it the alert keeps firing
+	if transitions := observeAll(&state, repeat(85, 60), start.Add(time.Hour), 10*time.Second); len(transitions) != 0 {
+		t.Fatalf("usage within the margin = %v, want the alert kept", transitions)
+	}
+
+	// Recovery has to last as long as the usage that fired the alert
+	transitions := observeAll(&state, repeat(40, 60), start.Add(2*time.Hour), 10*time.Second)
+	if len(transitions) != 1 || transitions[30] != AlertResolved {
+		t.Fatalf("transitions = %v, want a single resolve after 5 minutes (sample 30)", transitions)
+	}
+	if state.Firing {
+		t.Error("state still firing after the alert resolved")
+	}
+}
+
+func TestAlertIgnoresShortSpikes(t *testing.T) {
+	var state AlertState
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Four minutes high, one sample low, repeated for an hour
+	var samples []float64
+	for len(samples) < 360 {
+		samples = append(samples, repeat(95, 24)...)
+		samples = append(samples, 50)
+	}
+	if transitions := observeAll(&state, samples, start, 10*time.Second); len(transitions) != 0 {
+		t.Errorf("transitions = %v, want none for spikes shorter than the duration", transitions)
+	}
+}
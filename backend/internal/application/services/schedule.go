@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ErrInvalidSchedule is returned for a cron expression or timezone that cannot be evaluated
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// cronParser accepts standard five-field expressions and descriptors such as @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ScheduleLocation returns the zone a schedule's cron expression is read in:
+// the schedule's own IANA zone, or fallback when it has none
+func ScheduleLocation(tz string, fallback *time.Location) (*time.Location, error) {
+	if tz == "" {
+		return fallback, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, tz)
+	}
+	return loc, nil
+}
+
+// NextRun returns the first time after after that a cron expression fires,
+// reading the expression as wall clock time in loc. The result is in UTC, as
+// all stored times are.
+//
+// Across DST changes the wall clock decides: a time skipped when clocks go
+// forward does not fire that day, and a time repeated when they go back fires
+// once.
+func NextRun(expr string, loc *time.Location, after time.Time) (time.Time, error) {
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	next := schedule.Next(after.In(loc))
+	if _, wallClock := schedule.(*cron.SpecSchedule); wallClock {
+		for !next.IsZero() && repeated(next) {
+			next = schedule.Next(next)
+		}
+	}
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: %q never fires", ErrInvalidSchedule, expr)
+	}
+	return next.UTC(), nil
+}
+
+// repeated reports whether t is the second occurrence of its wall clock
+// time, as happens in the hour after clocks are turned back
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	earlier := t.Add(-time.Duration(before-offset) * time.Second)
+	return earlier.Format("2006-01-02 15:04") == t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := ScheduleLocation(name, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestNextRunInTimezone(t *testing.T) {
+	after := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for zone, want := range map[string]time.Time{
+		"":                 time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC),
+		"UTC":              time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC),
+		"America/New_York": time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC),
+		"Europe/Berlin":    time.Date(2026, 1, 10, 2, 0, 0, 0, time.UTC),
+		// Already past 03:00 there, so it runs the next day
+		"Asia/Kolkata": time.Date(2026, 1, 10, 21, 30, 0, 0, time.UTC),
+	} {
+		next, err := NextRun("0 3 * * *", mustLocation(t, zone), after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !next.Equal(want) || next.Location() != time.UTC {
+			t.Errorf("%q: next run %s, want %s", zone, next, want)
+		}
+	}
+
+	// A schedule without a zone of its own is read in the panel's
+	berlin := mustLocation(t, "Europe/Berlin")
+	if loc, err := ScheduleLocation("", berlin); err != nil || loc != berlin {
+		t.Errorf("fallback location %v (%v), want the panel's", loc, err)
+	}
+}
+
+func TestNextRunAcrossDST(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	for _, tc := range []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  []time.Time
+	}{
+		{
+			// Clocks go from 02:00 to 03:00 on March 8th, 2026
+			name:  "skipped time",
+			expr:  "30 2 * * *",
+			after: time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC),
+			want:  []time.Time{time.Date(2026, 3, 9, 6, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:  "wall clock kept after spring forward",
+			expr:  "0 4 * * *",
+			after: time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Clocks go from 02:00 back to 01:00 on November 1st, 2026
+			name:  "repeated time",
+			expr:  "30 1 * * *",
+			after: time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+				time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			after := tc.after
+			for _, want := range tc.want {
+				next, err := NextRun(tc.expr, newYork, after)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !next.Equal(want) {
+					t.Fatalf("next run after %s = %s, want %s", after, next, want)
+				}
+				after = next
+			}
+		})
+	}
+}
+
+func TestNextRunRejectsInvalidSchedules(t *testing.T) {
+	if _, err := ScheduleLocation("Mars/Olympus_Mons", time.UTC); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("unknown zone: %v, want ErrInvalidSchedule", err)
+	}
+	for _, expr := range []string{"", "every day", "61 * * * *", "0 0 30 2 *"} {
+		if _, err := NextRun(expr, time.UTC, time.Now()); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("%q: %v, want ErrInvalidSchedule", expr, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// ErrServerLimitReached is returned when a user already owns as many servers
+// as they may
+var ErrServerLimitReached = errors.New("server limit reached")
+
+// EffectiveServerLimit returns how many servers a user may own. The most
+// generous package among their active subscriptions applies, otherwise their
+// role's limit. 0 means unlimited.
+func EffectiveServerLimit(subscriptions []*entities.Subscription, role *entities.Role) int {
+	limit, subscribed := 0, false
+	for _, sub := range subscriptions {
+		if sub.Status != entities.SubscriptionStatusActive || sub.Package == nil {
+			continue
+		}
+		if sub.Package.ServerLimit == 0 {
+			return 0
+		}
+		subscribed = true
+		limit = max(limit, sub.Package.ServerLimit)
+	}
+	if subscribed {
+		return limit
+	}
+	if role != nil {
+		return role.ServerLimit
+	}
+	return 0
+}
+
+// CheckServerLimit checks that a user owning owned servers may create another
+func CheckServerLimit(owned int64, limit int) error {
+	if limit > 0 && owned >= int64(limit) {
+		return fmt.Errorf("%w: %d of %d servers in use", ErrServerLimitReached, owned, limit)
+	}
+	return nil
+}
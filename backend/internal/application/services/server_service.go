@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"sync"
 	"time"
 
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 var (
@@ -19,8 +24,38 @@ var (
 	ErrInsufficientResources = errors.New("insufficient resources on node")
 	ErrNoAvailableAllocation = errors.New("no available allocation")
 	ErrBackupLimitReached  = errors.New("backup limit reached")
+	ErrInvalidPowerAction  = errors.New("invalid power action")
+	ErrEggNotFound         = errors.New("egg not found")
+	ErrInvalidImage        = errors.New("docker image is not offered by the server's egg")
+	ErrBackupInProgress    = errors.New("server has a backup in progress")
+	ErrBackupNotFound      = errors.New("backup not found")
+	ErrBackupNotCompleted  = errors.New("backup has not completed")
+	ErrBackupEggMismatch   = errors.New("backup was taken with a different egg")
+	ErrBackupCorrupted     = errors.New("backup archive does not match its checksum")
+	ErrSafetyBackupFailed  = errors.New("safety backup failed, nothing was changed")
+	ErrCommandBlocked      = errors.New("command is blocked by the server's command policy")
+	ErrInvalidCPUSet       = errors.New("cpu set does not match the node's cores")
+	ErrInvalidSwap         = errors.New("swap exceeds what the node allows")
+	ErrAllocationNotFound  = errors.New("allocation not found")
 )
 
+// PowerAction represents a server power action
+type PowerAction string
+
+const (
+	PowerActionStart   PowerAction = "start"
+	PowerActionStop    PowerAction = "stop"
+	PowerActionRestart PowerAction = "restart"
+	PowerActionKill    PowerAction = "kill"
+)
+
+// BulkPowerResult is the outcome of a power action on a single server
+type BulkPowerResult struct {
+	ServerID uuid.UUID `json:"server_id"`
+	Success  bool      `json:"success"`
+	Error    string    `json:"error,omitempty"`
+}
+
 // ServerService handles server operations
 type ServerService struct {
 	serverRepo     repositories.ServerRepository
@@ -28,7 +63,29 @@ type ServerService struct {
 	allocationRepo repositories.AllocationRepository
 	backupRepo     repositories.BackupRepository
 	auditRepo      repositories.AuditLogRepository
+	activityRepo   repositories.ActivityLogRepository
+	quotaRepo      repositories.UserQuotaRepository
+	userRepo       repositories.UserRepository
+	roleRepo       repositories.RoleRepository
+	subRepo        repositories.SubscriptionRepository
+	eggRepo        repositories.EggRepository
+	eggVarRepo     repositories.EggVariableRepository
+	uow            repositories.UnitOfWork
+	history        CommandHistory
 	nodeClient     NodeClient
+	placer         *NodePlacer
+}
+
+// CommandHistory stores the console commands users recently sent to a server
+type CommandHistory interface {
+	Push(ctx context.Context, serverID uuid.UUID, userID uuid.UUID, command string) error
+	Recent(ctx context.Context, serverID uuid.UUID, userID uuid.UUID, limit int) ([]string, error)
+}
+
+// BackupArchive describes a server backup archive written by a node
+type BackupArchive struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
 }
 
 // NodeClient interface for communicating with node agents
@@ -39,22 +96,45 @@ type NodeClient interface {
 	KillServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error
 	GetServerStatus(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) (*ServerStats, error)
 	SendCommand(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, command string) error
-	CreateBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID) error
-	RestoreBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID) error
-	ReinstallServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error
+	CreateBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID) (*BackupArchive, error)
+	RestoreBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID, checksum string, wipeData bool) error
+	ReinstallServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, wipeData bool) error
+	UpdateServerImage(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, image string) error
+	UpdateServerBandwidth(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, in, out int64) error
+	UpdateServerStartup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, startup *Startup, allocations []*entities.Allocation) error
 }
 
 // ServerStats represents server resource usage
 type ServerStats struct {
-	CPUUsage    float64 `json:"cpu_usage"`
-	MemoryUsage int64   `json:"memory_usage"`
-	MemoryLimit int64   `json:"memory_limit"`
-	DiskUsage   int64   `json:"disk_usage"`
-	DiskLimit   int64   `json:"disk_limit"`
-	NetworkRx   int64   `json:"network_rx"`
-	NetworkTx   int64   `json:"network_tx"`
-	Uptime      int64   `json:"uptime"`
-	Status      string  `json:"status"`
+	CPUUsage      float64 `json:"cpu_usage"`
+	MemoryUsage   int64   `json:"memory_usage"`
+	MemoryLimit   int64   `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	DiskUsage     int64   `json:"disk_usage"`
+	DiskLimit     int64   `json:"disk_limit"`
+	NetworkRx     int64   `json:"network_rx"`
+	NetworkTx     int64   `json:"network_tx"`
+	Uptime        int64   `json:"uptime"`
+	Status        string  `json:"status"`
+	Health        string  `json:"health,omitempty"` // starting, healthy or unhealthy when the egg defines a healthcheck
+
+	OOMKill *OOMKill     `json:"oom_kill,omitempty"` // Set when the server was stopped for running out of memory
+	Crash   *CrashReport `json:"crash,omitempty"`    // Set when the server last crashed
+}
+
+// OOMKill describes a server stopped by the kernel for exceeding its memory limit
+type OOMKill struct {
+	KilledAt    time.Time `json:"killed_at"`
+	MemoryLimit int64     `json:"memory_limit"` // bytes
+	MemoryUsage int64     `json:"memory_usage"` // bytes, last sample before the kill
+}
+
+// CrashReport describes a server that exited without being stopped
+type CrashReport struct {
+	CrashedAt time.Time `json:"crashed_at"`
+	ExitCode  int       `json:"exit_code"`
+	Restarts  int       `json:"restarts"` // Automatic restarts within the recovery window
+	GaveUp    bool      `json:"gave_up"`  // The restart limit was reached and the server left stopped
 }
 
 // NewServerService creates a new ServerService
@@ -64,7 +144,17 @@ func NewServerService(
 	allocationRepo repositories.AllocationRepository,
 	backupRepo repositories.BackupRepository,
 	auditRepo repositories.AuditLogRepository,
+	activityRepo repositories.ActivityLogRepository,
+	quotaRepo repositories.UserQuotaRepository,
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	subRepo repositories.SubscriptionRepository,
+	eggRepo repositories.EggRepository,
+	eggVarRepo repositories.EggVariableRepository,
+	uow repositories.UnitOfWork,
+	history CommandHistory,
 	nodeClient NodeClient,
+	cfg *config.Config,
 ) *ServerService {
 	return &ServerService{
 		serverRepo:     serverRepo,
@@ -72,7 +162,17 @@ func NewServerService(
 		allocationRepo: allocationRepo,
 		backupRepo:     backupRepo,
 		auditRepo:      auditRepo,
+		activityRepo:   activityRepo,
+		quotaRepo:      quotaRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		subRepo:        subRepo,
+		eggRepo:        eggRepo,
+		eggVarRepo:     eggVarRepo,
+		uow:            uow,
+		history:        history,
 		nodeClient:     nodeClient,
+		placer:         NewNodePlacer(cfg.Placement.Strategy),
 	}
 }
 
@@ -81,24 +181,84 @@ type CreateServerRequest struct {
 	Name          string            `json:"name" validate:"required,min=1,max=100"`
 	Description   string            `json:"description" validate:"max=500"`
 	OwnerID       uuid.UUID         `json:"owner_id" validate:"required"`
-	NodeID        uuid.UUID         `json:"node_id" validate:"required"`
+	NodeID        uuid.UUID         `json:"node_id" validate:"required_unless=AutoPlace true"`
+	AutoPlace     bool              `json:"auto_place"`                                        // Pick a node automatically
+	LocationID    uuid.UUID         `json:"location_id" validate:"required_if=AutoPlace true"` // Location to place in when AutoPlace is set
 	GameID        uuid.UUID         `json:"game_id" validate:"required"`
 	EggID         uuid.UUID         `json:"egg_id" validate:"required"`
+	DockerImage   string            `json:"docker_image"` // One of the egg's images, defaults to the first
 	MemoryLimit   int64             `json:"memory_limit" validate:"required,min=128"`
+	SwapLimit     int64             `json:"swap_limit" validate:"min=-1"` // MB, -1 for unlimited
 	DiskLimit     int64             `json:"disk_limit" validate:"required,min=1024"`
 	CPULimit      int               `json:"cpu_limit" validate:"required,min=1,max=1000"`
-	Environment   map[string]string `json:"environment"`
+	CPUSet        string            `json:"cpu_set"`     // Cores to pin to, e.g. "0-3,8"; empty for quota only
+	NetworkMode   string            `json:"network_mode" validate:"omitempty,oneof=node isolated"`
+	NetworkIn     int64             `json:"network_in" validate:"omitempty,min=8192,max=1250000000"`  // bytes/s, 0 for unlimited
+	NetworkOut    int64             `json:"network_out" validate:"omitempty,min=8192,max=1250000000"` // bytes/s, 0 for unlimited
+	Environment   map[string]string `json:"environment"` // Overrides for egg variables, keyed by env variable
 	StartOnCreate bool              `json:"start_on_create"`
+
+	Allocation AllocationPreferences `json:"allocation"`
 }
 
 // Create creates a new server
 func (s *ServerService) Create(ctx context.Context, req *CreateServerRequest, createdBy uuid.UUID) (*entities.Server, error) {
+	plan, err := s.planServer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		return saveServer(ctx, repos, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Log audit
+	s.logAudit(ctx, createdBy, entities.AuditActionCreate, "server", &plan.server.ID)
+
+	return plan.server, nil
+}
+
+// serverPlan is a validated new server with the variables and allocations it
+// is saved with
+type serverPlan struct {
+	server      *entities.Server
+	variables   []ResolvedVariable
+	allocations []*entities.Allocation
+}
+
+// planServer checks a creation request against the node, the owner's limits
+// and the egg, and builds the server it describes without saving anything
+func (s *ServerService) planServer(ctx context.Context, req *CreateServerRequest) (*serverPlan, error) {
+	if req.AutoPlace {
+		node, err := s.placeNode(ctx, req.LocationID, req.MemoryLimit, req.DiskLimit)
+		if err != nil {
+			return nil, err
+		}
+		req.NodeID = node.ID
+	}
+
 	// Verify node exists and has capacity
 	node, err := s.nodeRepo.GetByID(ctx, req.NodeID)
 	if err != nil {
 		return nil, fmt.Errorf("node not found: %w", err)
 	}
 
+	if node.MaintenanceMode {
+		return nil, ErrNodeMaintenance
+	}
+
+	if err := s.CheckServerLimit(ctx, req.OwnerID); err != nil {
+		return nil, err
+	}
+
+	// Sub-users may not exceed the limits set by their reseller
+	if err := CheckQuota(ctx, s.quotaRepo, s.serverRepo, req.OwnerID, req.MemoryLimit, req.DiskLimit, req.CPULimit); err != nil {
+		return nil, err
+	}
+
 	if node.AvailableMemory() < req.MemoryLimit {
 		return nil, ErrInsufficientResources
 	}
@@ -107,11 +267,41 @@ func (s *ServerService) Create(ctx context.Context, req *CreateServerRequest, cr
 		return nil, ErrInsufficientResources
 	}
 
-	// Find available allocation
-	allocations, err := s.allocationRepo.GetAvailableByNodeID(ctx, req.NodeID)
-	if err != nil || len(allocations) == 0 {
+	if err := ValidateCPUSet(req.CPUSet, node); err != nil {
+		return nil, err
+	}
+	if err := ValidateSwap(req.SwapLimit, node); err != nil {
+		return nil, err
+	}
+
+	egg, err := s.eggRepo.GetByID(ctx, req.EggID)
+	if err != nil {
+		return nil, ErrEggNotFound
+	}
+	image, err := SelectEggImage(egg, req.DockerImage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Seed the environment from the egg's variables
+	eggVars, err := s.eggVarRepo.GetByEggID(ctx, req.EggID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load egg variables: %w", err)
+	}
+	variables, environment, err := ResolveEggVariables(eggVars, req.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find available allocations, including the egg's extra ports
+	nodeAllocations, err := s.allocationRepo.GetByNodeID(ctx, req.NodeID)
+	if err != nil {
 		return nil, ErrNoAvailableAllocation
 	}
+	allocations, err := SelectAllocations(egg, nodeAllocations, req.Allocation)
+	if err != nil {
+		return nil, err
+	}
 	allocation := allocations[0]
 
 	// Generate short UUID
@@ -128,34 +318,108 @@ func (s *ServerService) Create(ctx context.Context, req *CreateServerRequest, cr
 		AllocationID: allocation.ID,
 		GameID:       req.GameID,
 		EggID:        req.EggID,
+		EggVersion:   egg.Version,
+		DockerImage:  image,
+		StartupCmd:   egg.StartupCommand,
 		MemoryLimit:  req.MemoryLimit,
+		SwapLimit:    req.SwapLimit,
 		DiskLimit:    req.DiskLimit,
 		CPULimit:     req.CPULimit,
-		Environment:  req.Environment,
+		CPUSet:       req.CPUSet,
+		NetworkMode:  req.NetworkMode,
+		NetworkIn:    req.NetworkIn,
+		NetworkOut:   req.NetworkOut,
+		Environment:  environment,
 	}
 
-	if err := s.serverRepo.Create(ctx, server); err != nil {
-		return nil, fmt.Errorf("failed to create server: %w", err)
+	startup := NewStartupBuilder(egg, server, allocations, environment).Build()
+	server.StartupCmd = startup.Command
+	server.Environment = startup.Environment
+
+	return &serverPlan{server: server, variables: variables, allocations: allocations}, nil
+}
+
+// saveServer persists a planned server, its variables, allocations and node
+// usage with the repositories of a unit of work
+func saveServer(ctx context.Context, repos repositories.TxRepositories, plan *serverPlan) error {
+	server := plan.server
+	if err := repos.Servers().Create(ctx, server); err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
 	}
 
-	// Assign allocation to server
-	if err := s.allocationRepo.AssignToServer(ctx, allocation.ID, server.ID, true); err != nil {
-		return nil, fmt.Errorf("failed to assign allocation: %w", err)
+	for _, v := range plan.variables {
+		if err := repos.ServerVariables().Create(ctx, &entities.ServerVariable{
+			ServerID:      server.ID,
+			EggVariableID: v.Variable.ID,
+			Value:         v.Value,
+		}); err != nil {
+			return fmt.Errorf("failed to save server variable: %w", err)
+		}
 	}
 
-	// Update node allocated resources
-	if err := s.nodeRepo.UpdateResources(ctx, req.NodeID,
-		node.MemoryAllocated+req.MemoryLimit,
-		node.DiskAllocated+req.DiskLimit,
-		node.CPUAllocated+req.CPULimit,
-	); err != nil {
-		return nil, fmt.Errorf("failed to update node resources: %w", err)
+	// Assign allocations to server, the first one as primary
+	for i, alloc := range plan.allocations {
+		if err := repos.Allocations().AssignToServer(ctx, alloc.ID, server.ID, i == 0); err != nil {
+			return fmt.Errorf("failed to assign allocation: %w", err)
+		}
 	}
 
-	// Log audit
-	s.logAudit(ctx, createdBy, entities.AuditActionCreate, "server", &server.ID)
+	// Add the server to the node's allocated resources
+	if err := repos.Nodes().UpdateResources(ctx, server.NodeID, server.MemoryLimit, server.DiskLimit, server.CPULimit); err != nil {
+		return fmt.Errorf("failed to update node resources: %w", err)
+	}
+	return nil
+}
 
-	return server, nil
+// refreshStartup rebuilds the startup command and environment of a server and
+// pushes them to its node when they changed
+func (s *ServerService) refreshStartup(ctx context.Context, server *entities.Server) error {
+	egg, err := s.eggRepo.GetByID(ctx, server.EggID)
+	if err != nil {
+		return ErrEggNotFound
+	}
+
+	allocations, err := s.allocationRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load allocations: %w", err)
+	}
+
+	startup := NewStartupBuilder(egg, server, allocations, server.Environment).Build()
+	if startup.Command == server.StartupCmd && maps.Equal(startup.Environment, server.Environment) {
+		return nil
+	}
+
+	server.StartupCmd = startup.Command
+	server.Environment = startup.Environment
+	if err := s.serverRepo.Update(ctx, server); err != nil {
+		return err
+	}
+
+	if err := s.nodeClient.UpdateServerStartup(ctx, server.NodeID, server.ID, startup, allocations); err != nil {
+		return fmt.Errorf("failed to update startup on node: %w", err)
+	}
+	return nil
+}
+
+// placeNode selects a node in a location with room for a new server
+func (s *ServerService) placeNode(ctx context.Context, locationID uuid.UUID, memory, disk int64) (*entities.Node, error) {
+	nodes, err := s.nodeRepo.GetAvailable(ctx, memory, disk)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*entities.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.LocationID == locationID {
+			candidates = append(candidates, node)
+		}
+	}
+
+	ordered := s.placer.Order(candidates, memory, disk)
+	if len(ordered) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	return ordered[0], nil
 }
 
 // GetByID retrieves a server by ID
@@ -201,6 +465,11 @@ func (s *ServerService) Start(ctx context.Context, serverID uuid.UUID, userID uu
 		return ErrServerAlreadyRunning
 	}
 
+	// Allocations or variables may have changed since the last start
+	if err := s.refreshStartup(ctx, server); err != nil {
+		return err
+	}
+
 	// Update status
 	if err := s.serverRepo.UpdateStatus(ctx, serverID, entities.ServerStatusStarting); err != nil {
 		return err
@@ -212,12 +481,14 @@ func (s *ServerService) Start(ctx context.Context, serverID uuid.UUID, userID uu
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
-	// Update last started
+	// Update last started, the container now runs the current startup
 	now := time.Now()
 	server.LastStartedAt = &now
+	server.RestartRequired = false
 	_ = s.serverRepo.Update(ctx, server)
 
 	s.logAudit(ctx, userID, entities.AuditActionStart, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityServerStart, "")
 	return nil
 }
 
@@ -241,6 +512,7 @@ func (s *ServerService) Stop(ctx context.Context, serverID uuid.UUID, userID uui
 	}
 
 	s.logAudit(ctx, userID, entities.AuditActionStop, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityServerStop, "")
 	return nil
 }
 
@@ -264,6 +536,7 @@ func (s *ServerService) Restart(ctx context.Context, serverID uuid.UUID, userID
 	}
 
 	s.logAudit(ctx, userID, entities.AuditActionRestart, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityServerRestart, "")
 	return nil
 }
 
@@ -280,10 +553,43 @@ func (s *ServerService) Kill(ctx context.Context, serverID uuid.UUID, userID uui
 
 	_ = s.serverRepo.UpdateStatus(ctx, serverID, entities.ServerStatusStopped)
 	s.logAudit(ctx, userID, entities.AuditActionStop, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityServerKill, "")
 	return nil
 }
 
-// SendCommand sends a command to the server console
+// RunBulk runs fn for every server with at most concurrency calls in flight.
+// Results are returned in the same order as serverIDs.
+func RunBulk(ctx context.Context, serverIDs []uuid.UUID, concurrency int, fn func(context.Context, uuid.UUID) error) []BulkPowerResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]BulkPowerResult, len(serverIDs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, serverID := range serverIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, serverID uuid.UUID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = BulkPowerResult{ServerID: serverID, Success: true}
+			if err := fn(ctx, serverID); err != nil {
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}(i, serverID)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// SendCommand sends a command to the server console. Commands the server's
+// policy blocks are refused and audited unless the user is an admin or holds
+// entities.PermissionBypassCommandPolicy.
 func (s *ServerService) SendCommand(ctx context.Context, serverID uuid.UUID, command string, userID uuid.UUID) error {
 	server, err := s.serverRepo.GetByID(ctx, serverID)
 	if err != nil {
@@ -294,14 +600,28 @@ func (s *ServerService) SendCommand(ctx context.Context, serverID uuid.UUID, com
 		return ErrServerNotRunning
 	}
 
+	if !server.CommandPolicy.Allows(command) && !s.bypassesCommandPolicy(ctx, server, userID) {
+		s.logAuditWith(ctx, userID, entities.AuditActionCommandBlocked, "server", &serverID, map[string]interface{}{"command": command})
+		return ErrCommandBlocked
+	}
+
 	if err := s.nodeClient.SendCommand(ctx, server.NodeID, serverID, command); err != nil {
 		return fmt.Errorf("failed to send command: %w", err)
 	}
 
+	// History is a convenience, a failure to record it does not fail the command
+	_ = s.history.Push(ctx, serverID, userID, command)
+
 	s.logAudit(ctx, userID, entities.AuditActionCommand, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityConsoleCommand, command)
 	return nil
 }
 
+// CommandHistory returns the commands a user recently sent to a server, newest first
+func (s *ServerService) CommandHistory(ctx context.Context, serverID uuid.UUID, userID uuid.UUID, limit int) ([]string, error) {
+	return s.history.Recent(ctx, serverID, userID, limit)
+}
+
 // GetStats retrieves server resource statistics
 func (s *ServerService) GetStats(ctx context.Context, serverID uuid.UUID) (*ServerStats, error) {
 	server, err := s.serverRepo.GetByID(ctx, serverID)
@@ -342,7 +662,8 @@ func (s *ServerService) Unsuspend(ctx context.Context, serverID uuid.UUID, userI
 	return nil
 }
 
-// CreateBackup creates a server backup
+// CreateBackup backs up a server, returning once the node has written the
+// archive
 func (s *ServerService) CreateBackup(ctx context.Context, serverID uuid.UUID, name string, userID uuid.UUID) (*entities.Backup, error) {
 	server, err := s.serverRepo.GetByID(ctx, serverID)
 	if err != nil {
@@ -362,6 +683,7 @@ func (s *ServerService) CreateBackup(ctx context.Context, serverID uuid.UUID, na
 	// Create backup record
 	backup := &entities.Backup{
 		ServerID: serverID,
+		EggID:    &server.EggID,
 		Name:     name,
 		Status:   entities.BackupStatusPending,
 	}
@@ -370,23 +692,346 @@ func (s *ServerService) CreateBackup(ctx context.Context, serverID uuid.UUID, na
 		return nil, err
 	}
 
-	// Trigger backup on node
-	if err := s.nodeClient.CreateBackup(ctx, server.NodeID, serverID, backup.ID); err != nil {
+	archive, err := s.nodeClient.CreateBackup(ctx, server.NodeID, serverID, backup.ID)
+	if err != nil {
 		_ = s.backupRepo.UpdateStatus(ctx, backup.ID, entities.BackupStatusFailed)
 		return nil, fmt.Errorf("failed to create backup: %w", err)
 	}
 
+	now := time.Now()
+	backup.Status = entities.BackupStatusCompleted
+	backup.Size = archive.Size
+	backup.Checksum = archive.Checksum
+	backup.StoragePath = backup.ID.String() + ".tar.gz"
+	backup.CompletedAt = &now
+	if err := s.backupRepo.Update(ctx, backup); err != nil {
+		return nil, err
+	}
+
 	s.logAudit(ctx, userID, entities.AuditActionBackup, "server", &serverID)
+	s.logActivity(ctx, userID, serverID, entities.ActivityBackupCreate, backup.Name)
 	return backup, nil
 }
 
-// Reinstall reinstalls a server
-func (s *ServerService) Reinstall(ctx context.Context, serverID uuid.UUID, userID uuid.UUID) error {
+// RestoreBackup restores a completed backup onto its server. The node verifies
+// the archive checksum before touching any data and brings the server back to
+// the power state it had before the restore; an archive that fails the check
+// is flagged as corrupted. Backups taken while the server ran a different egg
+// are refused. With safetyBackup the server's current data is backed up first
+// and the restore only runs once that backup has completed.
+func (s *ServerService) RestoreBackup(ctx context.Context, serverID uuid.UUID, backupID uuid.UUID, userID uuid.UUID, wipeData, safetyBackup bool) error {
 	server, err := s.serverRepo.GetByID(ctx, serverID)
 	if err != nil {
 		return ErrServerNotFound
 	}
 
+	backup, err := s.backupRepo.GetByID(ctx, backupID)
+	if err != nil || backup.ServerID != serverID {
+		return ErrBackupNotFound
+	}
+
+	if backup.Status != entities.BackupStatusCompleted || backup.Checksum == "" {
+		return ErrBackupNotCompleted
+	}
+	if backup.EggID != nil && *backup.EggID != server.EggID {
+		return ErrBackupEggMismatch
+	}
+
+	metadata := map[string]interface{}{"backup_id": backupID}
+	if safetyBackup {
+		safety, err := s.safetyBackup(ctx, server, "restore", userID)
+		if err != nil {
+			return err
+		}
+		metadata["safety_backup_id"] = safety.ID
+	}
+
+	err = s.nodeClient.RestoreBackup(ctx, server.NodeID, serverID, backupID, backup.Checksum, wipeData)
+	if errors.Is(err, ErrBackupCorrupted) {
+		// Flag it so it is not offered again; the archive cannot be trusted
+		_ = s.backupRepo.UpdateStatus(ctx, backupID, entities.BackupStatusCorrupted)
+		return ErrBackupCorrupted
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	s.logAuditWith(ctx, userID, entities.AuditActionRestore, "server", &serverID, metadata)
+	s.logActivity(ctx, userID, serverID, entities.ActivityBackupRestore, backup.Name)
+	return nil
+}
+
+// safetyBackup backs up a server before a destructive action and waits for it
+// to complete. It counts against the server's backup limit, so a server at its
+// limit is refused with ErrBackupLimitReached until the owner frees a slot or
+// goes ahead without one.
+func (s *ServerService) safetyBackup(ctx context.Context, server *entities.Server, action string, userID uuid.UUID) (*entities.Backup, error) {
+	name := "Before " + action + " " + time.Now().UTC().Format("2006-01-02 15:04")
+	backup, err := s.CreateBackup(ctx, server.ID, name, userID)
+	if errors.Is(err, ErrBackupLimitReached) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Ctx(ctx).Warn("Safety backup failed", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return nil, ErrSafetyBackupFailed
+	}
+	return backup, nil
+}
+
+// bypassesCommandPolicy reports whether a user may send commands a server's
+// command policy blocks, as its owner or by their role
+func (s *ServerService) bypassesCommandPolicy(ctx context.Context, server *entities.Server, userID uuid.UUID) bool {
+	if server.OwnerID == userID {
+		return true
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	role, _ := s.roleRepo.GetByID(ctx, user.RoleID)
+	return role != nil && role.Grants(entities.PermissionBypassCommandPolicy)
+}
+
+// CheckServerLimit verifies that an owner may own another server under their
+// subscription or role. Admins have no limit.
+func (s *ServerService) CheckServerLimit(ctx context.Context, ownerID uuid.UUID) error {
+	owner, err := s.userRepo.GetByID(ctx, ownerID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	role, _ := s.roleRepo.GetByID(ctx, owner.RoleID)
+	if role != nil && role.Name == "admin" {
+		return nil
+	}
+
+	subscriptions, err := s.subRepo.GetByUserID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	owned, err := s.serverRepo.CountByOwnerID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	return CheckServerLimit(owned, EffectiveServerLimit(subscriptions, role))
+}
+
+// SelectEggImage returns the requested image if the egg offers it, or the egg's
+// first image when none was requested
+func SelectEggImage(egg *entities.Egg, image string) (string, error) {
+	if image == "" {
+		if len(egg.DockerImages) == 0 {
+			return "", ErrInvalidImage
+		}
+		return egg.DockerImages[0], nil
+	}
+
+	for _, offered := range egg.DockerImages {
+		if offered == image {
+			return image, nil
+		}
+	}
+	return "", ErrInvalidImage
+}
+
+// Update applies a partial update to a server. Resource changes are checked
+// against the node's capacity and moved onto its allocated totals in the same
+// transaction as the server, and a running server whose container settings
+// change is flagged RestartRequired.
+func (s *ServerService) Update(ctx context.Context, serverID uuid.UUID, update ServerUpdate, userID uuid.UUID) (*entities.Server, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, ErrServerNotFound
+	}
+	previous := *server
+
+	node, err := s.nodeRepo.GetByID(ctx, server.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("node not found: %w", err)
+	}
+	if update.CPUSet != nil {
+		if err := ValidateCPUSet(*update.CPUSet, node); err != nil {
+			return nil, err
+		}
+	}
+	if update.SwapLimit != nil {
+		if err := ValidateSwap(*update.SwapLimit, node); err != nil {
+			return nil, err
+		}
+	}
+
+	egg, err := s.eggRepo.GetByID(ctx, server.EggID)
+	if err != nil {
+		return nil, ErrEggNotFound
+	}
+	if update.DockerImage != nil && *update.DockerImage != server.DockerImage {
+		if _, err := SelectEggImage(egg, *update.DockerImage); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := update.Apply(server)
+	if len(changes) == 0 {
+		return server, nil
+	}
+
+	memory := server.MemoryLimit - previous.MemoryLimit
+	disk := server.DiskLimit - previous.DiskLimit
+	cpu := server.CPULimit - previous.CPULimit
+	resized := memory != 0 || disk != 0 || cpu != 0
+	if resized {
+		if err := CheckEggLimits(egg.Limits, int(server.MemoryLimit), int(server.DiskLimit), server.CPULimit); err != nil {
+			return nil, err
+		}
+	}
+	if NeedsRestart(changes) && server.IsRunning() {
+		server.RestartRequired = true
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		if resized {
+			current, err := repos.Nodes().GetByID(ctx, server.NodeID)
+			if err != nil {
+				return fmt.Errorf("node not found: %w", err)
+			}
+			if !ResizeFits(current, memory, disk, cpu) {
+				return ErrInsufficientResources
+			}
+			if err := repos.Nodes().UpdateResources(ctx, server.NodeID, memory, disk, cpu); err != nil {
+				return fmt.Errorf("failed to update node resources: %w", err)
+			}
+		}
+		return repos.Servers().Update(ctx, server)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := changes["docker_image"]; ok {
+		if err := s.nodeClient.UpdateServerImage(ctx, server.NodeID, serverID, server.DockerImage); err != nil {
+			return nil, fmt.Errorf("failed to update image on node: %w", err)
+		}
+	}
+	_, inChanged := changes["network_in"]
+	_, outChanged := changes["network_out"]
+	if inChanged || outChanged {
+		if err := s.nodeClient.UpdateServerBandwidth(ctx, server.NodeID, serverID, server.NetworkIn, server.NetworkOut); err != nil {
+			return nil, fmt.Errorf("failed to update bandwidth on node: %w", err)
+		}
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionUpdate, "server", &serverID)
+	return server, nil
+}
+
+// GetVariables returns the egg variables of a server the user may see
+func (s *ServerService) GetVariables(ctx context.Context, serverID uuid.UUID, admin bool) ([]ServerVariableValue, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, ErrServerNotFound
+	}
+
+	eggVars, err := s.eggVarRepo.GetByEggID(ctx, server.EggID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load egg variables: %w", err)
+	}
+	return VisibleServerVariables(eggVars, server.Environment, admin), nil
+}
+
+// UpdateVariables saves edits to a server's egg variables and pushes the new
+// startup to its node. A running server keeps its old environment until it
+// is started again and is flagged RestartRequired meanwhile.
+func (s *ServerService) UpdateVariables(ctx context.Context, serverID uuid.UUID, edits map[string]string, userID uuid.UUID, admin bool) ([]ServerVariableValue, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, ErrServerNotFound
+	}
+
+	egg, err := s.eggRepo.GetByID(ctx, server.EggID)
+	if err != nil {
+		return nil, ErrEggNotFound
+	}
+	eggVars, err := s.eggVarRepo.GetByEggID(ctx, server.EggID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load egg variables: %w", err)
+	}
+
+	changed, err := ResolveVariableEdits(eggVars, server.Environment, edits, admin)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return VisibleServerVariables(eggVars, server.Environment, admin), nil
+	}
+
+	allocations, err := s.allocationRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load allocations: %w", err)
+	}
+
+	environment := maps.Clone(server.Environment)
+	if environment == nil {
+		environment = make(map[string]string, len(changed))
+	}
+	for _, v := range changed {
+		environment[v.Variable.EnvVariable] = v.Value
+	}
+	startup := NewStartupBuilder(egg, server, allocations, environment).Build()
+	server.StartupCmd = startup.Command
+	server.Environment = startup.Environment
+	if server.IsRunning() {
+		server.RestartRequired = true
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		for _, v := range changed {
+			if err := repos.ServerVariables().Upsert(ctx, server.ID, v.Variable.ID, v.Value); err != nil {
+				return fmt.Errorf("failed to save server variable: %w", err)
+			}
+		}
+		return repos.Servers().Update(ctx, server)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.nodeClient.UpdateServerStartup(ctx, server.NodeID, server.ID, startup, allocations); err != nil {
+		return nil, fmt.Errorf("failed to update startup on node: %w", err)
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionUpdate, "server", &serverID)
+	return VisibleServerVariables(eggVars, server.Environment, admin), nil
+}
+
+// Reinstall reruns the install process of a server. When wipeData is set the
+// server's data volume is emptied first, otherwise existing files are kept.
+// Reinstalling is refused while a backup of the server is being taken. With
+// safetyBackup the server is backed up first and the reinstall only runs once
+// that backup has completed.
+func (s *ServerService) Reinstall(ctx context.Context, serverID uuid.UUID, userID uuid.UUID, wipeData, safetyBackup bool) error {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return ErrServerNotFound
+	}
+
+	backups, err := s.backupRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return fmt.Errorf("failed to check backups: %w", err)
+	}
+	for _, backup := range backups {
+		if backup.Status == entities.BackupStatusPending || backup.Status == entities.BackupStatusInProgress {
+			return ErrBackupInProgress
+		}
+	}
+
+	metadata := map[string]interface{}{"wipe_data": wipeData}
+	if safetyBackup {
+		safety, err := s.safetyBackup(ctx, server, "reinstall", userID)
+		if err != nil {
+			return err
+		}
+		metadata["safety_backup_id"] = safety.ID
+	}
+
 	// Stop if running
 	if server.IsRunning() {
 		_ = s.nodeClient.StopServer(ctx, server.NodeID, serverID)
@@ -396,11 +1041,17 @@ func (s *ServerService) Reinstall(ctx context.Context, serverID uuid.UUID, userI
 		return err
 	}
 
-	if err := s.nodeClient.ReinstallServer(ctx, server.NodeID, serverID); err != nil {
+	if err := s.nodeClient.ReinstallServer(ctx, server.NodeID, serverID, wipeData); err != nil {
+		_ = s.serverRepo.UpdateStatus(ctx, serverID, entities.ServerStatusError)
 		return fmt.Errorf("failed to reinstall server: %w", err)
 	}
 
-	s.logAudit(ctx, userID, entities.AuditActionInstall, "server", &serverID)
+	action, details := entities.AuditActionReinstall, ""
+	if wipeData {
+		action, details = entities.AuditActionReinstallWipe, "Data wiped"
+	}
+	s.logAuditWith(ctx, userID, action, "server", &serverID, metadata)
+	s.logActivity(ctx, userID, serverID, entities.ActivityServerReinstall, details)
 	return nil
 }
 
@@ -416,21 +1067,28 @@ func (s *ServerService) Delete(ctx context.Context, serverID uuid.UUID, userID u
 		_ = s.nodeClient.KillServer(ctx, server.NodeID, serverID)
 	}
 
-	// Free allocation
-	_ = s.allocationRepo.Unassign(ctx, server.AllocationID)
-
-	// Update node resources
-	node, _ := s.nodeRepo.GetByID(ctx, server.NodeID)
-	if node != nil {
-		_ = s.nodeRepo.UpdateResources(ctx, server.NodeID,
-			node.MemoryAllocated-server.MemoryLimit,
-			node.DiskAllocated-server.DiskLimit,
-			node.CPUAllocated-server.CPULimit,
-		)
-	}
-
-	// Delete server
-	if err := s.serverRepo.Delete(ctx, serverID); err != nil {
+	// Free the allocations, release node resources and delete the server together
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		allocations, err := repos.Allocations().GetByServerID(ctx, serverID)
+		if err != nil {
+			return fmt.Errorf("failed to load allocations: %w", err)
+		}
+		for _, alloc := range allocations {
+			if err := repos.Allocations().Unassign(ctx, alloc.ID); err != nil {
+				return fmt.Errorf("failed to free allocation: %w", err)
+			}
+		}
+
+		node, err := repos.Nodes().GetByID(ctx, server.NodeID)
+		if err == nil && node != nil {
+			if err := repos.Nodes().UpdateResources(ctx, server.NodeID, -server.MemoryLimit, -server.DiskLimit, -server.CPULimit); err != nil {
+				return fmt.Errorf("failed to update node resources: %w", err)
+			}
+		}
+
+		return repos.Servers().Delete(ctx, serverID)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -438,12 +1096,29 @@ func (s *ServerService) Delete(ctx context.Context, serverID uuid.UUID, userID u
 	return nil
 }
 
+// logActivity records an entry in a server's activity feed
+func (s *ServerService) logActivity(ctx context.Context, userID uuid.UUID, serverID uuid.UUID, action string, details string) {
+	_ = s.activityRepo.Create(ctx, &entities.ActivityLog{
+		UserID:   userID,
+		ServerID: &serverID,
+		Action:   action,
+		Details:  details,
+	})
+}
+
 func (s *ServerService) logAudit(ctx context.Context, userID uuid.UUID, action entities.AuditAction, resource string, resourceID *uuid.UUID) {
+	s.logAuditWith(ctx, userID, action, resource, resourceID, nil)
+}
+
+func (s *ServerService) logAuditWith(ctx context.Context, userID uuid.UUID, action entities.AuditAction, resource string, resourceID *uuid.UUID, metadata map[string]interface{}) {
 	log := &entities.AuditLog{
 		UserID:     &userID,
 		Action:     action,
 		Resource:   resource,
 		ResourceID: resourceID,
+		Metadata:   metadata,
+	}
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", resource), zap.Error(err))
 	}
-	_ = s.auditRepo.Create(ctx, log)
 }
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+func TestRunBulkPartialFailures(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	failing := map[uuid.UUID]error{ids[1]: ErrServerNotFound, ids[3]: ErrNodeOffline}
+
+	results := RunBulk(context.Background(), ids, 2, func(ctx context.Context, id uuid.UUID) error {
+		return failing[id]
+	})
+
+	if len(results) != len(ids) {
+		t.Fatalf("got %d results, want %d", len(results), len(ids))
+	}
+	for i, result := range results {
+		// Results keep the order of the requested servers
+		if result.ServerID != ids[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.ServerID, ids[i])
+		}
+		if err, failed := failing[ids[i]]; failed {
+			if result.Success || result.Error != err.Error() {
+				t.Errorf("result %d = %+v, want failure %q", i, result, err)
+			}
+		} else if !result.Success || result.Error != "" {
+			t.Errorf("result %d = %+v, want success", i, result)
+		}
+	}
+}
+
+func TestRunBulkConcurrencyLimit(t *testing.T) {
+	ids := make([]uuid.UUID, 20)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	var inFlight, peak, calls int32
+	results := RunBulk(context.Background(), ids, 3, func(ctx context.Context, id uuid.UUID) error {
+		atomic.AddInt32(&calls, 1)
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	})
+
+	if calls != int32(len(ids)) || len(results) != len(ids) {
+		t.Fatalf("ran %d calls with %d results, want %d", calls, len(results), len(ids))
+	}
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", peak)
+	}
+	if peak < 2 {
+		t.Errorf("peak concurrency = %d, calls did not run in parallel", peak)
+	}
+}
+
+func TestRunBulkDefaultsToSerial(t *testing.T) {
+	var inFlight, peak int32
+	RunBulk(context.Background(), []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}, 0, func(ctx context.Context, id uuid.UUID) error {
+		if n := atomic.AddInt32(&inFlight, 1); n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	})
+
+	if peak != 1 {
+		t.Errorf("peak concurrency = %d with no limit set, want 1", peak)
+	}
+}
+
+func TestSendCommandPolicy(t *testing.T) {
+	store := newFakeStore()
+	userRole := &entities.Role{ID: uuid.New(), Name: "user"}
+	bypassRole := &entities.Role{ID: uuid.New(), Name: "support", Permissions: []entities.Permission{{Name: entities.PermissionBypassCommandPolicy}}}
+	adminRole := &entities.Role{ID: uuid.New(), Name: "admin"}
+	for _, role := range []*entities.Role{userRole, bypassRole, adminRole} {
+		store.roles[role.ID] = role
+	}
+	owner := &entities.User{ID: uuid.New(), RoleID: userRole.ID}
+	subuser := &entities.User{ID: uuid.New(), RoleID: userRole.ID}
+	support := &entities.User{ID: uuid.New(), RoleID: bypassRole.ID}
+	admin := &entities.User{ID: uuid.New(), RoleID: adminRole.ID}
+	for _, user := range []*entities.User{owner, subuser, support, admin} {
+		store.users[user.ID] = user
+	}
+	server := &entities.Server{
+		ID:            uuid.New(),
+		OwnerID:       owner.ID,
+		Status:        entities.ServerStatusRunning,
+		CommandPolicy: entities.CommandPolicy{Mode: entities.CommandPolicyDeny, Prefixes: []string{"op"}},
+	}
+	store.servers[server.ID] = server
+
+	nodes := &fakeNodeClient{}
+	s := &ServerService{
+		serverRepo:   fakeServers{store: store},
+		userRepo:     fakeUsers{store: store},
+		roleRepo:     fakeRoles{store: store},
+		auditRepo:    fakeAuditLogs{store: store},
+		activityRepo: fakeActivityLogs{},
+		history:      fakeCommandHistory{},
+		nodeClient:   nodes,
+	}
+	ctx := context.Background()
+
+	// Other users are held to the policy
+	if err := s.SendCommand(ctx, server.ID, "minecraft:op Steve", subuser.ID); !errors.Is(err, ErrCommandBlocked) {
+		t.Fatalf("subuser op = %v, want %v", err, ErrCommandBlocked)
+	}
+	if got := store.audited(subuser.ID); len(got) != 1 || got[0] != entities.AuditActionCommandBlocked {
+		t.Errorf("subuser audit = %v, want one %s", got, entities.AuditActionCommandBlocked)
+	}
+	if err := s.SendCommand(ctx, server.ID, "say hi", subuser.ID); err != nil {
+		t.Errorf("subuser say = %v", err)
+	}
+
+	// The owner, admins and holders of the bypass permission are not
+	for name, user := range map[string]*entities.User{"owner": owner, "support": support, "admin": admin} {
+		if err := s.SendCommand(ctx, server.ID, "op Steve", user.ID); err != nil {
+			t.Errorf("op by %s = %v, want sent", name, err)
+		}
+		if got := store.audited(user.ID); slices.Contains(got, entities.AuditActionCommandBlocked) {
+			t.Errorf("%s audit = %v, want nothing blocked", name, got)
+		}
+	}
+	if want := []string{"say hi", "op Steve", "op Steve", "op Steve"}; !slices.Equal(nodes.commands, want) {
+		t.Errorf("sent %q, want %q", nodes.commands, want)
+	}
+}
+
+func TestUpdateAndDeleteMoveNodeResources(t *testing.T) {
+	store := newFakeStore()
+	owner := addUser(store, "owner", 0)
+	node := &entities.Node{ID: uuid.New(), MemoryTotal: 8192, DiskTotal: 102400, MemoryAllocated: 3072, DiskAllocated: 11264, CPUAllocated: 200}
+	store.nodes[node.ID] = node
+	egg := &entities.Egg{ID: uuid.New()}
+	store.eggs[egg.ID] = egg
+	// The node also runs a 1 GB server besides this one
+	server := addServer(store, owner, 2048)
+	server.NodeID, server.EggID, server.DiskLimit = node.ID, egg.ID, 10240
+	store.allocations = append(store.allocations, &entities.Allocation{ID: uuid.New(), NodeID: node.ID, ServerID: &server.ID, IsPrimary: true})
+
+	s := &ServerService{
+		serverRepo: fakeServers{store: store},
+		nodeRepo:   fakeNodes{store: store},
+		eggRepo:    fakeEggs{store: store},
+		auditRepo:  fakeAuditLogs{store: store},
+		uow:        fakeUnitOfWork{store: store},
+		nodeClient: &fakeNodeClient{},
+	}
+	ctx := context.Background()
+	resize := func(memory int64) error {
+		_, err := s.Update(ctx, server.ID, ServerUpdate{MemoryLimit: &memory}, owner.ID)
+		return err
+	}
+
+	if err := resize(8192); !errors.Is(err, ErrInsufficientResources) {
+		t.Errorf("resize beyond the node = %v, want %v", err, ErrInsufficientResources)
+	}
+	if err := resize(4096); err != nil {
+		t.Fatalf("resize within the node: %v", err)
+	}
+	if got := store.nodes[node.ID].MemoryAllocated; got != 5120 {
+		t.Errorf("node memory allocated = %d after the resize, want 5120", got)
+	}
+
+	if err := s.Delete(ctx, server.ID, owner.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := store.nodes[node.ID]; got.MemoryAllocated != 1024 || got.DiskAllocated != 1024 || got.CPUAllocated != 100 {
+		t.Errorf("node allocated = %d MB, %d MB disk, %d%% CPU after the delete, want the other server's 1024, 1024, 100",
+			got.MemoryAllocated, got.DiskAllocated, got.CPUAllocated)
+	}
+	if store.allocations[0].ServerID != nil {
+		t.Error("allocation of the deleted server was not freed")
+	}
+}
+
+func TestReinstallTakesSafetyBackupFirst(t *testing.T) {
+	store := newFakeStore()
+	owner := addUser(store, "owner", 0)
+	server := addServer(store, owner, 1024)
+	server.BackupLimit = 2
+
+	nodes := &fakeNodeClient{}
+	s := &ServerService{
+		serverRepo:   fakeServers{store: store},
+		backupRepo:   fakeBackups{store: store},
+		auditRepo:    fakeAuditLogs{store: store},
+		activityRepo: fakeActivityLogs{},
+		nodeClient:   nodes,
+	}
+	ctx := context.Background()
+
+	if err := s.Reinstall(ctx, server.ID, owner.ID, true, true); err != nil {
+		t.Fatalf("Reinstall: %v", err)
+	}
+	if want := []string{"backup", "reinstall wipe"}; !slices.Equal(nodes.calls, want) {
+		t.Errorf("node calls = %q, want %q", nodes.calls, want)
+	}
+	if len(store.backups) != 1 || store.backups[0].Status != entities.BackupStatusCompleted {
+		t.Fatalf("backups = %v, want one completed safety backup", store.backups)
+	}
+	if got := store.servers[server.ID].Status; got != entities.ServerStatusInstalling {
+		t.Errorf("server status = %s, want %s", got, entities.ServerStatusInstalling)
+	}
+
+	// A failed safety backup leaves the server untouched
+	nodes.calls, nodes.backupErr = nil, errors.New("disk full")
+	store.servers[server.ID].Status = entities.ServerStatusStopped
+	if err := s.Reinstall(ctx, server.ID, owner.ID, true, true); !errors.Is(err, ErrSafetyBackupFailed) {
+		t.Fatalf("Reinstall with a failing backup = %v, want %v", err, ErrSafetyBackupFailed)
+	}
+	if want := []string{"backup"}; !slices.Equal(nodes.calls, want) {
+		t.Errorf("node calls = %q, want only the backup", nodes.calls)
+	}
+	if got := store.servers[server.ID].Status; got != entities.ServerStatusStopped {
+		t.Errorf("server status = %s after the failed backup, want %s", got, entities.ServerStatusStopped)
+	}
+	if got := store.backups[1].Status; got != entities.BackupStatusFailed {
+		t.Errorf("safety backup status = %s, want %s", got, entities.BackupStatusFailed)
+	}
+}
+
+func TestSelectEggImage(t *testing.T) {
+	egg := &entities.Egg{DockerImages: []string{"ghcr.io/games/java:17", "ghcr.io/games/java:21"}}
+
+	if image, err := SelectEggImage(egg, ""); err != nil || image != "ghcr.io/games/java:17" {
+		t.Errorf("no image = %q, %v, want the egg's first image", image, err)
+	}
+	if image, err := SelectEggImage(egg, "ghcr.io/games/java:21"); err != nil || image != "ghcr.io/games/java:21" {
+		t.Errorf("offered image = %q, %v, want it selected", image, err)
+	}
+	for _, image := range []string{"ghcr.io/attacker/miner:latest", "ghcr.io/games/java:17 "} {
+		if _, err := SelectEggImage(egg, image); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("%q = %v, want %v", image, err, ErrInvalidImage)
+		}
+	}
+	if _, err := SelectEggImage(&entities.Egg{}, ""); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("egg without images = %v, want %v", err, ErrInvalidImage)
+	}
+}
+
+func TestCheckServerLimit(t *testing.T) {
+	store := newFakeStore()
+	userRole := &entities.Role{ID: uuid.New(), Name: "user", ServerLimit: 1}
+	adminRole := &entities.Role{ID: uuid.New(), Name: "admin", ServerLimit: 1}
+	store.roles[userRole.ID], store.roles[adminRole.ID] = userRole, adminRole
+	owner := addUser(store, "owner", 0)
+	owner.RoleID = userRole.ID
+	admin := addUser(store, "admin", 0)
+	admin.RoleID = adminRole.ID
+	addServer(store, owner, 1024)
+	addServer(store, admin, 1024)
+
+	s := &ServerService{
+		serverRepo: fakeServers{store: store},
+		userRepo:   fakeUsers{store: store},
+		roleRepo:   fakeRoles{store: store},
+		subRepo:    fakeSubscriptions{store: store},
+	}
+	ctx := context.Background()
+
+	if err := s.CheckServerLimit(ctx, owner.ID); !errors.Is(err, ErrServerLimitReached) {
+		t.Errorf("owner at the role's limit = %v, want %v", err, ErrServerLimitReached)
+	}
+	if err := s.CheckServerLimit(ctx, admin.ID); err != nil {
+		t.Errorf("admin = %v, want no limit", err)
+	}
+
+	// An active subscription's package replaces the role's limit
+	sub := &entities.Subscription{ID: uuid.New(), UserID: owner.ID, Status: entities.SubscriptionStatusActive, Package: &entities.Package{ServerLimit: 3}}
+	store.subscriptions[sub.ID] = sub
+	if err := s.CheckServerLimit(ctx, owner.ID); err != nil {
+		t.Errorf("owner under the package's limit = %v", err)
+	}
+	sub.Status = entities.SubscriptionStatusSuspended
+	if err := s.CheckServerLimit(ctx, owner.ID); !errors.Is(err, ErrServerLimitReached) {
+		t.Errorf("owner with a suspended subscription = %v, want %v", err, ErrServerLimitReached)
+	}
+}
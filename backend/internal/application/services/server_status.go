@@ -0,0 +1,36 @@
+package services
+
+import "github.com/aetherpanel/aether-panel/internal/domain/entities"
+
+// AgentStatus maps a status reported by an agent, a Docker container state or
+// one of the agent's own states, to a server status. Unknown states report
+// false.
+func AgentStatus(status string) (entities.ServerStatus, bool) {
+	switch status {
+	case "running":
+		return entities.ServerStatusRunning, true
+	case "restarting":
+		return entities.ServerStatusRestarting, true
+	case "created", "exited", "dead", "paused", "stopped":
+		return entities.ServerStatusStopped, true
+	case "installing":
+		return entities.ServerStatusInstalling, true
+	case "crashed", "error":
+		return entities.ServerStatusError, true
+	}
+	return "", false
+}
+
+// ReconcileStatus returns the status a server should have given what its
+// agent reports, and whether that differs from the stored one. Suspended
+// servers keep their status, it is not a container state.
+func ReconcileStatus(stored entities.ServerStatus, reported string) (entities.ServerStatus, bool) {
+	if stored == entities.ServerStatusSuspended {
+		return stored, false
+	}
+	actual, ok := AgentStatus(reported)
+	if !ok || actual == stored {
+		return stored, false
+	}
+	return actual, true
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// ServerUpdate is a partial update of a server's settings. Nil fields are left
+// as they are.
+type ServerUpdate struct {
+	Name        *string
+	Description *string
+	MemoryLimit *int64
+	SwapLimit   *int64
+	DiskLimit   *int64
+	CPULimit    *int
+	CPUSet      *string
+	DockerImage *string
+	NetworkIn   *int64
+	NetworkOut  *int64
+}
+
+// restartColumns are the server columns that only take effect once the
+// container is recreated. Bandwidth is applied to a running container and the
+// disk limit is enforced by the node as files are written.
+var restartColumns = []string{"memory_limit", "swap_limit", "cpu_limit", "cpu_set", "docker_image"}
+
+// Apply copies the fields that are set and differ from the server onto it, and
+// returns the changed columns with their new values
+func (u *ServerUpdate) Apply(server *entities.Server) map[string]interface{} {
+	changes := make(map[string]interface{})
+	setString(changes, "name", &server.Name, u.Name)
+	setString(changes, "description", &server.Description, u.Description)
+	setInt64(changes, "memory_limit", &server.MemoryLimit, u.MemoryLimit)
+	setInt64(changes, "swap_limit", &server.SwapLimit, u.SwapLimit)
+	setInt64(changes, "disk_limit", &server.DiskLimit, u.DiskLimit)
+	if u.CPULimit != nil && *u.CPULimit != server.CPULimit {
+		server.CPULimit = *u.CPULimit
+		changes["cpu_limit"] = server.CPULimit
+	}
+	setString(changes, "cpu_set", &server.CPUSet, u.CPUSet)
+	setString(changes, "docker_image", &server.DockerImage, u.DockerImage)
+	setInt64(changes, "network_in", &server.NetworkIn, u.NetworkIn)
+	setInt64(changes, "network_out", &server.NetworkOut, u.NetworkOut)
+	return changes
+}
+
+func setString(changes map[string]interface{}, column string, field, value *string) {
+	if value != nil && *value != *field {
+		*field = *value
+		changes[column] = *value
+	}
+}
+
+func setInt64(changes map[string]interface{}, column string, field, value *int64) {
+	if value != nil && *value != *field {
+		*field = *value
+		changes[column] = *value
+	}
+}
+
+// NeedsRestart reports whether any of the changed columns only take effect
+// once the server's container is recreated
+func NeedsRestart(changes map[string]interface{}) bool {
+	for _, column := range restartColumns {
+		if _, ok := changes[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ResizeFits reports whether a node has room for a server to grow by the given
+// amounts. Shrinking always fits, and CPU is only checked on nodes that report
+// a total.
+func ResizeFits(node *entities.Node, memory, disk int64, cpu int) bool {
+	if memory > 0 && node.AvailableMemory() < memory {
+		return false
+	}
+	if disk > 0 && node.AvailableDisk() < disk {
+		return false
+	}
+	if cpu > 0 && node.CPUTotal > 0 && node.CPUTotal-node.CPUAllocated < cpu {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// ServerWebhookPayload is the body posted to a server webhook
+type ServerWebhookPayload struct {
+	Event      string                 `json:"event"`
+	ServerID   uuid.UUID              `json:"server_id"`
+	ServerUUID string                 `json:"server_uuid"`
+	ServerName string                 `json:"server_name"`
+	Data       map[string]interface{} `json:"data"`
+	Timestamp  string                 `json:"timestamp"`
+}
+
+// BuildServerWebhookPayload renders the body posted to a server webhook for a
+// transition of server at the given time
+func BuildServerWebhookPayload(event string, server *entities.Server, data map[string]interface{}, at time.Time) ([]byte, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return json.Marshal(ServerWebhookPayload{
+		Event:      event,
+		ServerID:   server.ID,
+		ServerUUID: server.UUID,
+		ServerName: server.Name,
+		Data:       data,
+		Timestamp:  at.UTC().Format(time.RFC3339),
+	})
+}
+
+// ServerTransition returns the server webhook event for a server moving from
+// one status to another, or "" when the change is not a transition webhooks
+// subscribe to
+func ServerTransition(from, to entities.ServerStatus) string {
+	switch {
+	case from == to:
+		return ""
+	case to == entities.ServerStatusRunning:
+		return entities.ServerEventStarted
+	case to == entities.ServerStatusStopped:
+		return entities.ServerEventStopped
+	}
+	return ""
+}
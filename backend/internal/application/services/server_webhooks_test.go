@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+func TestServerTransition(t *testing.T) {
+	tests := []struct {
+		from, to entities.ServerStatus
+		want     string
+	}{
+		{entities.ServerStatusStarting, entities.ServerStatusRunning, entities.ServerEventStarted},
+		{entities.ServerStatusStopping, entities.ServerStatusStopped, entities.ServerEventStopped},
+		{entities.ServerStatusRunning, entities.ServerStatusRunning, ""},
+		{entities.ServerStatusStopped, entities.ServerStatusStarting, ""},
+	}
+	for _, tt := range tests {
+		if got := ServerTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("ServerTransition(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestServerWebhookStartDeliversSignedPayload(t *testing.T) {
+	server := &entities.Server{ID: uuid.New(), UUID: "abcd1234", Name: "survival"}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	body, err := BuildServerWebhookPayload(entities.ServerEventStarted, server, map[string]interface{}{"from": "starting"}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		received  ServerWebhookPayload
+		signature string
+		event     string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(WebhookSignatureHeader)
+		event = r.Header.Get(WebhookEventHeader)
+		if err := json.Unmarshal(raw, &received); err != nil {
+			t.Errorf("payload is not JSON: %v", err)
+		}
+		if want := "sha256=" + SignWebhookPayload("owner-secret", raw); signature != want {
+			t.Errorf("signature = %q, want %q", signature, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	cfg := testWebhookConfig().Webhooks
+	result := SendWebhook(context.Background(), receiver.Client(), cfg, receiver.URL, "owner-secret", entities.ServerEventStarted, body)
+	if result.Err != nil || result.Attempts != 1 {
+		t.Fatalf("result = %+v, want one successful attempt", result)
+	}
+	if signature == "" || event != entities.ServerEventStarted {
+		t.Errorf("headers: signature %q, event %q", signature, event)
+	}
+	if received.Event != entities.ServerEventStarted || received.ServerID != server.ID || received.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Errorf("payload = %+v", received)
+	}
+}
+
+func TestServerWebhookRetriesFailedDelivery(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	body, _ := BuildServerWebhookPayload(entities.ServerEventStarted, &entities.Server{ID: uuid.New()}, nil, time.Now())
+	cfg := testWebhookConfig().Webhooks
+	result := SendWebhook(context.Background(), receiver.Client(), cfg, receiver.URL, "", entities.ServerEventStarted, body)
+	if result.Err != nil || result.Attempts != 3 || result.StatusCode != http.StatusOK {
+		t.Errorf("result = %+v, want success on the third attempt", result)
+	}
+}
+
+func TestServerWebhookSubscribes(t *testing.T) {
+	webhook := &entities.ServerWebhook{Events: []string{entities.ServerEventStarted, entities.EventServerCrashed}}
+	if !webhook.Subscribes(entities.ServerEventStarted) || webhook.Subscribes(entities.ServerEventStopped) {
+		t.Errorf("subscriptions of %v wrong", webhook.Events)
+	}
+	all := &entities.ServerWebhook{Events: []string{"*"}}
+	if !all.Subscribes(entities.ServerEventBackupCompleted) {
+		t.Error("wildcard webhook not subscribed")
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+var startupPlaceholder = regexp.MustCompile(`\{\{\s*(?:env\.)?([A-Za-z0-9_]+)\s*\}\}`)
+
+// Startup is the rendered startup command and container environment of a server
+type Startup struct {
+	Command     string
+	Environment map[string]string
+	Healthcheck *entities.EggHealthcheck // From the egg, nil without one
+	Limits      ProcessLimits            // From the egg, zero fields use the panel default
+	ConfigFiles string                   // Egg config file rules as JSON, empty without any
+
+	// From the egg, a read-only root filesystem with the Tmpfs paths writable
+	ReadOnlyRootfs bool
+	Tmpfs          []string
+	Capabilities   []string // From the egg, added back to the node's base set
+
+	// From the egg, the container's entrypoint and how the command runs:
+	// through Shell (/bin/bash when empty) or, with DirectExec, without one
+	Entrypoint []string
+	Shell      string
+	DirectExec bool
+}
+
+// ProcessLimits caps the processes and open files of a server's container.
+// Zero fields are left to the default.
+type ProcessLimits struct {
+	Pids   int64 `json:"pids"`
+	NoFile int64 `json:"nofile"`
+	NProc  int64 `json:"nproc"`
+}
+
+// Or returns l with its zero fields taken from defaults
+func (l ProcessLimits) Or(defaults ProcessLimits) ProcessLimits {
+	if l.Pids == 0 {
+		l.Pids = defaults.Pids
+	}
+	if l.NoFile == 0 {
+		l.NoFile = defaults.NoFile
+	}
+	if l.NProc == 0 {
+		l.NProc = defaults.NProc
+	}
+	return l
+}
+
+// StartupBuilder renders a server's startup command from its egg and builds
+// the complete container environment from the server's resolved variables,
+// its allocations and its limits
+type StartupBuilder struct {
+	egg         *entities.Egg
+	server      *entities.Server
+	allocations []*entities.Allocation
+	variables   map[string]string
+}
+
+// NewStartupBuilder creates a new StartupBuilder. variables holds the resolved
+// egg variables and user overrides keyed by environment variable name.
+func NewStartupBuilder(egg *entities.Egg, server *entities.Server, allocations []*entities.Allocation, variables map[string]string) *StartupBuilder {
+	return &StartupBuilder{
+		egg:         egg,
+		server:      server,
+		allocations: allocations,
+		variables:   variables,
+	}
+}
+
+// Build returns the startup of the server. System values such as SERVER_PORT,
+// SERVER_MEMORY and the egg's port variables take precedence over variables
+// of the same name.
+// Placeholders without a value are left in the command untouched.
+func (b *StartupBuilder) Build() *Startup {
+	env := make(map[string]string, len(b.variables)+8)
+	for k, v := range b.variables {
+		env[k] = v
+	}
+
+	env["SERVER_UUID"] = b.server.UUID
+	env["SERVER_MEMORY"] = strconv.FormatInt(b.server.MemoryLimit, 10)
+
+	primary, additional := b.splitAllocations()
+	if primary != nil {
+		env["SERVER_IP"] = primary.IP
+		env["SERVER_PORT"] = strconv.Itoa(primary.Port)
+	}
+
+	ports := make([]string, 0, len(additional))
+	for i, alloc := range additional {
+		port := strconv.Itoa(alloc.Port)
+		env["SERVER_PORT_"+strconv.Itoa(i+1)] = port
+		ports = append(ports, port)
+	}
+	env["ADDITIONAL_PORTS"] = strings.Join(ports, ",")
+	for k, v := range eggPortEnvironment(b.egg, primary, additional) {
+		env[k] = v
+	}
+
+	template := b.server.StartupCmd
+	if b.egg != nil && b.egg.StartupCommand != "" {
+		template = b.egg.StartupCommand
+	}
+
+	command := startupPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := startupPlaceholder.FindStringSubmatch(match)[1]
+		if value, ok := env[name]; ok {
+			return value
+		}
+		return match
+	})
+	env["STARTUP"] = command
+
+	startup := &Startup{Command: command, Environment: env}
+	if b.egg != nil {
+		startup.Healthcheck = b.egg.Healthcheck
+		startup.Limits = ProcessLimits{Pids: b.egg.PidsLimit, NoFile: b.egg.NoFile, NProc: b.egg.NProc}
+		startup.ConfigFiles = b.egg.ConfigFiles
+		startup.ReadOnlyRootfs = b.egg.ReadOnlyRootfs
+		startup.Tmpfs = b.egg.Tmpfs
+		startup.Capabilities = b.egg.Capabilities
+		startup.Entrypoint = b.egg.Entrypoint
+		startup.Shell = b.egg.Shell
+		startup.DirectExec = b.egg.DirectExec
+	}
+	return startup
+}
+
+// splitAllocations returns the primary allocation and the remaining ones
+// ordered by port. Without a flagged primary, the server's own allocation or
+// else the lowest port is used.
+func (b *StartupBuilder) splitAllocations() (*entities.Allocation, []*entities.Allocation) {
+	if len(b.allocations) == 0 {
+		return nil, nil
+	}
+
+	sorted := make([]*entities.Allocation, len(b.allocations))
+	copy(sorted, b.allocations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	primary := 0
+	for i, alloc := range sorted {
+		if alloc.IsPrimary {
+			primary = i
+			break
+		}
+		if alloc.ID == b.server.AllocationID {
+			primary = i
+		}
+	}
+
+	additional := make([]*entities.Allocation, 0, len(sorted)-1)
+	additional = append(additional, sorted[:primary]...)
+	additional = append(additional, sorted[primary+1:]...)
+	return sorted[primary], additional
+}
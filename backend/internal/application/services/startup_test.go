@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+func TestStartupBuilderExposesEveryAllocation(t *testing.T) {
+	egg := &entities.Egg{
+		StartupCommand: "java -Xmx{{SERVER_MEMORY}}M -jar server.jar --port {{SERVER_PORT}} --query {{ env.QUERY_PORT }} --world {{WORLD}} {{UNKNOWN}}",
+		Ports:          []entities.EggPort{{EnvVariable: "QUERY_PORT", Offset: 1}},
+	}
+	primary := &entities.Allocation{ID: uuid.New(), IP: "203.0.113.10", Port: 25565}
+	query := &entities.Allocation{ID: uuid.New(), IP: "203.0.113.10", Port: 25566}
+	server := &entities.Server{UUID: "a1b2c3", MemoryLimit: 2048, AllocationID: primary.ID}
+
+	// The primary is the server's allocation regardless of order
+	startup := NewStartupBuilder(egg, server, []*entities.Allocation{query, primary}, map[string]string{
+		"WORLD":       "survival",
+		"SERVER_PORT": "1", // System values win over variables
+	}).Build()
+
+	for name, want := range map[string]string{
+		"SERVER_IP":        "203.0.113.10",
+		"SERVER_PORT":      "25565",
+		"SERVER_PORT_1":    "25566",
+		"ADDITIONAL_PORTS": "25566",
+		"QUERY_PORT":       "25566",
+		"SERVER_MEMORY":    "2048",
+		"SERVER_UUID":      "a1b2c3",
+		"WORLD":            "survival",
+	} {
+		if got := startup.Environment[name]; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	want := "java -Xmx2048M -jar server.jar --port 25565 --query 25566 --world survival {{UNKNOWN}}"
+	if startup.Command != want {
+		t.Errorf("command %q, want %q", startup.Command, want)
+	}
+	if startup.Environment["STARTUP"] != want {
+		t.Errorf("STARTUP = %q, want the rendered command", startup.Environment["STARTUP"])
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// SubscriptionService renews, suspends and expires subscriptions. It is meant
+// to be run once per subscription interval, daily by default.
+type SubscriptionService struct {
+	subscriptionRepo repositories.SubscriptionRepository
+	serverRepo       repositories.ServerRepository
+	auditRepo        repositories.AuditLogRepository
+	notificationRepo repositories.NotificationRepository
+	uow              repositories.UnitOfWork
+	nodeClient       NodeClient
+	tax              *TaxCalculator
+	config           config.BillingConfig
+}
+
+// NewSubscriptionService creates a new SubscriptionService
+func NewSubscriptionService(
+	subscriptionRepo repositories.SubscriptionRepository,
+	serverRepo repositories.ServerRepository,
+	auditRepo repositories.AuditLogRepository,
+	notificationRepo repositories.NotificationRepository,
+	uow repositories.UnitOfWork,
+	nodeClient NodeClient,
+	cfg *config.Config,
+) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		serverRepo:       serverRepo,
+		auditRepo:        auditRepo,
+		notificationRepo: notificationRepo,
+		uow:              uow,
+		nodeClient:       nodeClient,
+		tax:              NewTaxCalculator(cfg.Billing),
+		config:           cfg.Billing,
+	}
+}
+
+// Start processes subscriptions every SubscriptionInterval until the context
+// is cancelled. An interval of 0 disables processing.
+func (s *SubscriptionService) Start(ctx context.Context) {
+	if s.config.SubscriptionInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SubscriptionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.ProcessSubscriptions(ctx, now); err != nil {
+				logger.Ctx(ctx).Error("Failed to process subscriptions", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ProcessSubscriptions runs one pass of the subscription lifecycle:
+//   - users are notified once when a subscription renews or ends within the renewal notice
+//   - auto-renewing subscriptions that are due are charged from credits and renewed
+//   - unpaid subscriptions past their grace period are suspended with their server
+//   - cancelled subscriptions past their end date are expired and their server suspended
+func (s *SubscriptionService) ProcessSubscriptions(ctx context.Context, now time.Time) error {
+	var errs []error
+
+	expiring, err := s.subscriptionRepo.GetExpiring(ctx, now.Add(s.config.RenewalNotice))
+	if err != nil {
+		return err
+	}
+	for _, sub := range expiring {
+		if s.dueForNotice(sub, now) {
+			s.notifyUpcoming(ctx, sub)
+		}
+		if !sub.AutoRenew || sub.NextBillingDate == nil || sub.NextBillingDate.After(now) {
+			continue
+		}
+		if err := s.renew(ctx, sub, now); err != nil {
+			if errors.Is(err, ErrInsufficientCredits) {
+				// Renewal is retried every run of the grace period, the user is told once
+				if sub.PaymentFailedAt != nil {
+					continue
+				}
+				if err := s.subscriptionRepo.MarkPaymentFailed(ctx, sub.ID, now); err != nil {
+					errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+					continue
+				}
+				s.notify(ctx, sub, "subscription_payment_failed", "Subscription renewal failed",
+					fmt.Sprintf("We could not renew your subscription for %.2f %s because your balance is too low. Add credits before %s to avoid suspension.",
+						sub.Amount, sub.Currency, sub.GraceEndsAt().Format("January 2, 2006")))
+				continue
+			}
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+		}
+	}
+
+	expired, err := s.subscriptionRepo.GetExpired(ctx)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, sub := range expired {
+		var err error
+		switch {
+		case sub.Status == entities.SubscriptionStatusCancelled || !sub.AutoRenew:
+			err = s.expire(ctx, sub)
+		case sub.Status == entities.SubscriptionStatusActive && now.After(sub.GraceEndsAt()):
+			err = s.suspend(ctx, sub)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// renew charges a subscription's amount plus tax from the user's credits,
+// records a paid invoice and extends the subscription by one billing cycle
+func (s *SubscriptionService) renew(ctx context.Context, sub *entities.Subscription, now time.Time) error {
+	newEndDate := sub.PeriodEnd(sub.EndDate)
+	reference := "SUB-" + sub.ID.String() + "-" + sub.EndDate.Format("20060102")
+
+	var charged float64
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repositories.TxRepositories) error {
+		user, err := repos.Users().GetByID(ctx, sub.UserID)
+		if err != nil {
+			return err
+		}
+		tax := s.tax.Calculate(user, sub.Amount)
+		charged = tax.Total
+
+		if err := repos.Users().UpdateCredits(ctx, sub.UserID, -tax.Total); err != nil {
+			return err
+		}
+		if user, err = repos.Users().GetByID(ctx, sub.UserID); err != nil {
+			return err
+		}
+		if user.Credits < 0 {
+			return ErrInsufficientCredits
+		}
+
+		if err := repos.Transactions().Create(ctx, &entities.Transaction{
+			UserID:        sub.UserID,
+			Type:          entities.TransactionTypeDebit,
+			Status:        entities.TransactionStatusCompleted,
+			Amount:        -tax.Total,
+			Currency:      sub.Currency,
+			Description:   fmt.Sprintf("Subscription renewal (%s)", sub.BillingCycle),
+			Reference:     reference,
+			PaymentMethod: entities.PaymentMethodInternal,
+			PaymentDetails: map[string]interface{}{
+				"subscription_id": sub.ID,
+			},
+			BalanceBefore: user.Credits + tax.Total,
+			BalanceAfter:  user.Credits,
+			ProcessedAt:   &now,
+		}); err != nil {
+			return err
+		}
+
+		number, err := repos.Invoices().GenerateNumber(ctx)
+		if err != nil {
+			return err
+		}
+		description := fmt.Sprintf("Subscription %s to %s", sub.EndDate.Format("2006-01-02"), newEndDate.Format("2006-01-02"))
+		invoice := &entities.Invoice{
+			InvoiceNumber:  number,
+			UserID:         sub.UserID,
+			SubscriptionID: &sub.ID,
+			Currency:       sub.Currency,
+			Status:         "paid",
+			IssueDate:      now,
+			DueDate:        now,
+			PaidAt:         &now,
+			Items: []entities.InvoiceItem{{
+				Description: description,
+				Quantity:    1,
+				UnitPrice:   tax.Subtotal,
+				Total:       tax.Subtotal,
+			}},
+		}
+		tax.Apply(invoice)
+		if err := repos.Invoices().Create(ctx, invoice); err != nil {
+			return err
+		}
+
+		return repos.Subscriptions().Renew(ctx, sub.ID, newEndDate)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientCredits) {
+			return ErrInsufficientCredits
+		}
+		return fmt.Errorf("failed to renew subscription: %w", err)
+	}
+
+	s.logAudit(ctx, sub, fmt.Sprintf("Renewed subscription until %s for %.2f %s", newEndDate.Format("2006-01-02"), charged, sub.Currency))
+	s.notify(ctx, sub, "subscription_renewed", "Subscription renewed",
+		fmt.Sprintf("Your subscription was renewed until %s and %.2f %s was charged from your credits.",
+			newEndDate.Format("January 2, 2006"), charged, sub.Currency))
+	return nil
+}
+
+// suspend suspends an unpaid subscription and its server
+func (s *SubscriptionService) suspend(ctx context.Context, sub *entities.Subscription) error {
+	if err := s.subscriptionRepo.Suspend(ctx, sub.ID); err != nil {
+		return err
+	}
+	err := s.suspendServer(ctx, sub, "Subscription payment overdue")
+
+	s.logAudit(ctx, sub, "Suspended subscription after the grace period")
+	s.notify(ctx, sub, "subscription_suspended", "Subscription suspended",
+		"Your subscription was not paid within the grace period and has been suspended together with its server. Add credits and contact support to restore it.")
+	return err
+}
+
+// expire ends a subscription that will not renew and suspends its server
+func (s *SubscriptionService) expire(ctx context.Context, sub *entities.Subscription) error {
+	sub.Status = entities.SubscriptionStatusExpired
+	sub.NextBillingDate = nil
+	if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+		return err
+	}
+	err := s.suspendServer(ctx, sub, "Subscription expired")
+
+	s.logAudit(ctx, sub, "Expired subscription")
+	s.notify(ctx, sub, "subscription_expired", "Subscription expired",
+		"Your subscription has ended and its server has been suspended.")
+	return err
+}
+
+// suspendServer stops and suspends the server of a subscription, if it has one
+func (s *SubscriptionService) suspendServer(ctx context.Context, sub *entities.Subscription, reason string) error {
+	if sub.ServerID == nil {
+		return nil
+	}
+
+	server, err := s.serverRepo.GetByID(ctx, *sub.ServerID)
+	if err != nil {
+		return ErrServerNotFound
+	}
+	if server.Suspended {
+		return nil
+	}
+
+	if server.IsRunning() {
+		_ = s.nodeClient.StopServer(ctx, server.NodeID, server.ID)
+	}
+	return s.serverRepo.Suspend(ctx, server.ID, reason)
+}
+
+// dueForNotice reports whether the renewal notice window opened during the
+// last interval, so each billing period is announced only once
+func (s *SubscriptionService) dueForNotice(sub *entities.Subscription, now time.Time) bool {
+	if sub.Status != entities.SubscriptionStatusActive {
+		return false
+	}
+	noticeAt := sub.EndDate.Add(-s.config.RenewalNotice)
+	return !noticeAt.After(now) && now.Sub(noticeAt) < s.config.SubscriptionInterval
+}
+
+func (s *SubscriptionService) notifyUpcoming(ctx context.Context, sub *entities.Subscription) {
+	date := sub.EndDate.Format("January 2, 2006")
+	if sub.AutoRenew {
+		s.notify(ctx, sub, "subscription_renewal_upcoming", "Subscription renews soon",
+			fmt.Sprintf("Your subscription renews on %s for %.2f %s. Make sure you have enough credits.", date, sub.Amount, sub.Currency))
+		return
+	}
+	s.notify(ctx, sub, "subscription_ending", "Subscription ends soon",
+		fmt.Sprintf("Your subscription ends on %s and will not renew. Its server will be suspended then.", date))
+}
+
+func (s *SubscriptionService) notify(ctx context.Context, sub *entities.Subscription, kind, title, message string) {
+	data := map[string]interface{}{
+		"subscription_id": sub.ID,
+		"end_date":        sub.EndDate,
+	}
+	if sub.ServerID != nil {
+		data["server_id"] = *sub.ServerID
+	}
+
+	_ = s.notificationRepo.Create(ctx, &entities.Notification{
+		UserID:  sub.UserID,
+		Type:    kind,
+		Title:   title,
+		Message: message,
+		Data:    data,
+	})
+}
+
+func (s *SubscriptionService) logAudit(ctx context.Context, sub *entities.Subscription, description string) {
+	err := s.auditRepo.Create(ctx, &entities.AuditLog{
+		Action:      entities.AuditActionUpdate,
+		Resource:    "subscription",
+		ResourceID:  &sub.ID,
+		Description: description,
+		Metadata: map[string]interface{}{
+			"user_id": sub.UserID,
+			"status":  sub.Status,
+		},
+	})
+	if err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", "subscription"), zap.Error(err))
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+)
+
+// newTestSubscription adds a user with credits and a running server with an
+// auto-renewing monthly subscription ending at end
+func newTestSubscription(store *fakeStore, credits float64, end time.Time) *entities.Subscription {
+	user := &entities.User{ID: uuid.New(), Username: "player", Credits: credits, Status: entities.UserStatusActive}
+	server := &entities.Server{ID: uuid.New(), NodeID: uuid.New(), OwnerID: user.ID, Status: entities.ServerStatusRunning}
+	sub := &entities.Subscription{
+		ID:              uuid.New(),
+		UserID:          user.ID,
+		ServerID:        &server.ID,
+		BillingCycle:    "monthly",
+		Amount:          10,
+		Currency:        "USD",
+		Status:          entities.SubscriptionStatusActive,
+		AutoRenew:       true,
+		EndDate:         end,
+		NextBillingDate: &end,
+		GracePeriodDays: 3,
+	}
+	store.users[user.ID] = user
+	store.servers[server.ID] = server
+	store.subscriptions[sub.ID] = sub
+	return sub
+}
+
+func newTestSubscriptionService(store *fakeStore, nodes *fakeNodeClient) *SubscriptionService {
+	cfg := &config.Config{Billing: config.BillingConfig{
+		SubscriptionInterval: 24 * time.Hour,
+		RenewalNotice:        72 * time.Hour,
+	}}
+	return NewSubscriptionService(
+		fakeSubscriptions{store: store},
+		fakeServers{store: store},
+		fakeAuditLogs{},
+		fakeNotifications{store: store},
+		fakeUnitOfWork{store: store},
+		nodes,
+		cfg,
+	)
+}
+
+func TestProcessSubscriptionsRenews(t *testing.T) {
+	store := newFakeStore()
+	now := time.Now()
+	end := now.Add(-time.Hour)
+	sub := newTestSubscription(store, 25, end)
+
+	s := newTestSubscriptionService(store, &fakeNodeClient{})
+	if err := s.ProcessSubscriptions(context.Background(), now); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+
+	if credits := store.users[sub.UserID].Credits; credits != 15 {
+		t.Errorf("credits = %.2f, want 15", credits)
+	}
+	if len(store.transactions) != 1 || store.transactions[0].Amount != -10 {
+		t.Errorf("transactions = %+v, want one debit of 10", store.transactions)
+	}
+	if len(store.invoices) != 1 || store.invoices[0].Status != "paid" {
+		t.Errorf("invoices = %+v, want one paid invoice", store.invoices)
+	}
+	if want := end.AddDate(0, 1, 0); !sub.EndDate.Equal(want) {
+		t.Errorf("end date = %v, want %v", sub.EndDate, want)
+	}
+	if !slices.Contains(store.notified(sub.UserID), "subscription_renewed") {
+		t.Errorf("notifications = %v, want a renewal notice", store.notified(sub.UserID))
+	}
+}
+
+func TestProcessSubscriptionsInsufficientBalance(t *testing.T) {
+	store := newFakeStore()
+	now := time.Now()
+	end := now.Add(-time.Hour)
+	sub := newTestSubscription(store, 5, end)
+	nodes := &fakeNodeClient{}
+
+	s := newTestSubscriptionService(store, nodes)
+	if err := s.ProcessSubscriptions(context.Background(), now); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+
+	// Nothing is charged or recorded, and the subscription waits out its grace period
+	if credits := store.users[sub.UserID].Credits; credits != 5 {
+		t.Errorf("credits = %.2f, want 5", credits)
+	}
+	if len(store.transactions) != 0 || len(store.invoices) != 0 {
+		t.Errorf("recorded %d transactions and %d invoices for a failed renewal", len(store.transactions), len(store.invoices))
+	}
+	if current := store.subscriptions[sub.ID]; current.Status != entities.SubscriptionStatusActive || !current.EndDate.Equal(end) {
+		t.Errorf("subscription = %s until %v, want it unchanged", current.Status, current.EndDate)
+	}
+	if store.servers[*sub.ServerID].Suspended || len(nodes.stopped) != 0 {
+		t.Error("server suspended within the grace period")
+	}
+	if !slices.Contains(store.notified(sub.UserID), "subscription_payment_failed") {
+		t.Errorf("notifications = %v, want a payment failure notice", store.notified(sub.UserID))
+	}
+}
+
+func TestProcessSubscriptionsNotifiesPaymentFailureOnce(t *testing.T) {
+	store := newFakeStore()
+	now := time.Now()
+	sub := newTestSubscription(store, 5, now.Add(-time.Hour))
+	s := newTestSubscriptionService(store, &fakeNodeClient{})
+	ctx := context.Background()
+
+	failures := func() int {
+		count := 0
+		for _, kind := range store.notified(sub.UserID) {
+			if kind == "subscription_payment_failed" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Every run of the grace period retries the renewal
+	for run := 0; run < 3; run++ {
+		if err := s.ProcessSubscriptions(ctx, now.Add(time.Duration(run)*24*time.Hour)); err != nil {
+			t.Fatalf("ProcessSubscriptions run %d: %v", run+1, err)
+		}
+	}
+	if n := failures(); n != 1 {
+		t.Errorf("sent %d payment failure notices over the grace period, want 1", n)
+	}
+
+	// A later period that fails again is notified again
+	store.users[sub.UserID].Credits = 10
+	if err := s.ProcessSubscriptions(ctx, now.Add(48*time.Hour)); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+	if renewed := store.subscriptions[sub.ID]; renewed.PaymentFailedAt != nil {
+		t.Error("the failed payment was kept after the renewal")
+	}
+	if err := s.ProcessSubscriptions(ctx, store.subscriptions[sub.ID].EndDate.Add(time.Hour)); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+	if n := failures(); n != 2 {
+		t.Errorf("sent %d payment failure notices over two periods, want 2", n)
+	}
+}
+
+func TestProcessSubscriptionsSuspendsAfterGracePeriod(t *testing.T) {
+	store := newFakeStore()
+	now := time.Now()
+	sub := newTestSubscription(store, 0, now.AddDate(0, 0, -4))
+	nodes := &fakeNodeClient{}
+
+	s := newTestSubscriptionService(store, nodes)
+	if err := s.ProcessSubscriptions(context.Background(), now); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+
+	if status := store.subscriptions[sub.ID].Status; status != entities.SubscriptionStatusSuspended {
+		t.Errorf("status = %s, want %s", status, entities.SubscriptionStatusSuspended)
+	}
+	if server := store.servers[*sub.ServerID]; !server.Suspended {
+		t.Error("server not suspended after the grace period")
+	}
+	if len(nodes.stopped) != 1 || nodes.stopped[0] != *sub.ServerID {
+		t.Errorf("stopped = %v, want the subscription's server", nodes.stopped)
+	}
+	if !slices.Contains(store.notified(sub.UserID), "subscription_suspended") {
+		t.Errorf("notifications = %v, want a suspension notice", store.notified(sub.UserID))
+	}
+}
+
+func TestProcessSubscriptionsExpiresCancelled(t *testing.T) {
+	store := newFakeStore()
+	now := time.Now()
+	sub := newTestSubscription(store, 100, now.Add(-time.Hour))
+	sub.Status = entities.SubscriptionStatusCancelled
+	sub.AutoRenew = false
+
+	s := newTestSubscriptionService(store, &fakeNodeClient{})
+	if err := s.ProcessSubscriptions(context.Background(), now); err != nil {
+		t.Fatalf("ProcessSubscriptions: %v", err)
+	}
+
+	expired := store.subscriptions[sub.ID]
+	if expired.Status != entities.SubscriptionStatusExpired || expired.NextBillingDate != nil {
+		t.Errorf("subscription = %s, next billing %v, want expired without a next billing date", expired.Status, expired.NextBillingDate)
+	}
+	if !store.servers[*sub.ServerID].Suspended {
+		t.Error("server of an expired subscription not suspended")
+	}
+	if credits := store.users[sub.UserID].Credits; credits != 100 {
+		t.Errorf("credits = %.2f, want 100, a cancelled subscription is not charged", credits)
+	}
+}
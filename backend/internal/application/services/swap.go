@@ -0,0 +1,28 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// UnlimitedSwap lets a server use as much swap as the host has
+const UnlimitedSwap = -1
+
+// ValidateSwap checks a server's swap in MB against the swap its node allows
+// per server. 0 gives the server no swap beyond its memory.
+func ValidateSwap(swap int64, node *entities.Node) error {
+	if swap < UnlimitedSwap {
+		return fmt.Errorf("%w: %d MB", ErrInvalidSwap, swap)
+	}
+	if node.SwapMax == UnlimitedSwap {
+		return nil
+	}
+	if swap == UnlimitedSwap {
+		return fmt.Errorf("%w: node does not allow unlimited swap", ErrInvalidSwap)
+	}
+	if swap > node.SwapMax {
+		return fmt.Errorf("%w: %d MB exceeds the node's %d MB", ErrInvalidSwap, swap, node.SwapMax)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxServerTags is the most tags a server may carry
+	MaxServerTags = 10
+	// MaxTagLength is the longest tag allowed, in characters
+	MaxTagLength = 32
+)
+
+var (
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTooManyTags = errors.New("too many tags")
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// NormalizeTag lowercases a tag and joins its words with dashes, so
+// "Game Night" and "game-night" are the same tag
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if tag == "" || len(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+	return tag, nil
+}
+
+// MergeTags adds tags to a server's existing ones, keeping their order and
+// dropping duplicates. It fails when a tag is invalid or the server would
+// carry more than MaxServerTags.
+func MergeTags(existing, added []string) ([]string, error) {
+	tags := make([]string, 0, len(existing)+len(added))
+	seen := make(map[string]bool, len(existing)+len(added))
+	for _, tag := range existing {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, raw := range added {
+		tag, err := NormalizeTag(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxServerTags {
+		return nil, fmt.Errorf("%w: a server may have at most %d", ErrTooManyTags, MaxServerTags)
+	}
+	return tags, nil
+}
+
+// RemoveTag returns tags without tag, compared after normalizing
+func RemoveTag(tags []string, tag string) []string {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return tags
+	}
+	kept := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t != tag {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"math"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+)
+
+// Reasons a customer pays no tax
+const (
+	TaxExemptUser    = "exempt"
+	TaxReverseCharge = "reverse_charge"
+)
+
+// TaxResult is the tax applied to an amount for a customer
+type TaxResult struct {
+	Subtotal     float64 // Amount before tax
+	Tax          float64
+	Total        float64
+	Rate         float64 // Percent
+	Inclusive    bool
+	Country      string
+	Region       string
+	ExemptReason string
+}
+
+// TaxCalculator applies the configured tax rates to invoice amounts
+type TaxCalculator struct {
+	config        config.TaxConfig
+	sellerCountry string
+}
+
+// NewTaxCalculator creates a new TaxCalculator
+func NewTaxCalculator(cfg config.BillingConfig) *TaxCalculator {
+	return &TaxCalculator{
+		config:        cfg.Tax,
+		sellerCountry: strings.ToLower(cfg.Company.Country),
+	}
+}
+
+// Calculate returns the tax on amount for a customer. With exclusive pricing
+// tax is added on top of amount, with inclusive pricing it is taken out of it.
+func (t *TaxCalculator) Calculate(customer *entities.User, amount float64) TaxResult {
+	result := TaxResult{
+		Subtotal:  amount,
+		Total:     amount,
+		Inclusive: t.config.Inclusive,
+		Country:   strings.ToUpper(customer.Country),
+		Region:    customer.Region,
+	}
+	if !t.config.Enabled {
+		return result
+	}
+
+	switch {
+	case customer.TaxExempt:
+		result.ExemptReason = TaxExemptUser
+	case t.config.ReverseCharge && customer.VATNumber != "" && customer.Country != "" &&
+		!strings.EqualFold(customer.Country, t.sellerCountry):
+		result.ExemptReason = TaxReverseCharge
+	}
+	if result.ExemptReason != "" {
+		return result
+	}
+
+	result.Rate = t.rate(customer.Country, customer.Region)
+	if t.config.Inclusive {
+		result.Subtotal = roundMoney(amount / (1 + result.Rate/100))
+		result.Tax = roundMoney(amount - result.Subtotal)
+	} else {
+		result.Tax = roundMoney(amount * result.Rate / 100)
+		result.Total = roundMoney(amount + result.Tax)
+	}
+	return result
+}
+
+// rate returns the tax rate of a region, falling back to its country and then
+// the default rate. Config keys are lower case, as viper reads them.
+func (t *TaxCalculator) rate(country, region string) float64 {
+	country = strings.ToLower(country)
+	if region != "" {
+		if rate, ok := t.config.Rates[country+"-"+strings.ToLower(region)]; ok {
+			return rate
+		}
+	}
+	if rate, ok := t.config.Rates[country]; ok {
+		return rate
+	}
+	return t.config.DefaultRate
+}
+
+// Apply sets the amounts of an invoice and records the applied tax in its metadata
+func (r TaxResult) Apply(invoice *entities.Invoice) {
+	invoice.Subtotal = r.Subtotal
+	invoice.Tax = r.Tax
+	invoice.Total = r.Total
+	if invoice.Metadata == nil {
+		invoice.Metadata = map[string]interface{}{}
+	}
+	invoice.Metadata["tax"] = map[string]interface{}{
+		"rate":          r.Rate,
+		"inclusive":     r.Inclusive,
+		"country":       r.Country,
+		"region":        r.Region,
+		"exempt_reason": r.ExemptReason,
+	}
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
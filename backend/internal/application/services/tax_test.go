@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+)
+
+func newTestTaxCalculator(inclusive bool) *TaxCalculator {
+	return NewTaxCalculator(config.BillingConfig{
+		Company: config.CompanyConfig{Country: "DE"},
+		Tax: config.TaxConfig{
+			Enabled:       true,
+			Inclusive:     inclusive,
+			DefaultRate:   20,
+			Rates:         map[string]float64{"de": 19, "us": 0, "us-ca": 7.25},
+			ReverseCharge: true,
+		},
+	})
+}
+
+func TestTaxExclusiveAndInclusive(t *testing.T) {
+	customer := &entities.User{Country: "de"}
+
+	exclusive := newTestTaxCalculator(false).Calculate(customer, 100)
+	if exclusive.Subtotal != 100 || exclusive.Tax != 19 || exclusive.Total != 119 || exclusive.Inclusive {
+		t.Errorf("exclusive = %+v, want 19.00 added to 100.00", exclusive)
+	}
+
+	// The price already holds the tax: 119.00 is 100.00 plus 19%
+	inclusive := newTestTaxCalculator(true).Calculate(customer, 119)
+	if inclusive.Subtotal != 100 || inclusive.Tax != 19 || inclusive.Total != 119 || !inclusive.Inclusive {
+		t.Errorf("inclusive = %+v, want 19.00 taken out of 119.00", inclusive)
+	}
+
+	// Amounts are rounded to cents and still add up
+	odd := newTestTaxCalculator(true).Calculate(customer, 9.99)
+	if odd.Subtotal != 8.39 || odd.Tax != 1.6 || roundMoney(odd.Subtotal+odd.Tax) != odd.Total {
+		t.Errorf("inclusive 9.99 = %+v, want 8.39 and 1.60", odd)
+	}
+}
+
+func TestTaxRatesByCountry(t *testing.T) {
+	calculator := newTestTaxCalculator(false)
+	for name, tc := range map[string]struct {
+		customer entities.User
+		rate     float64
+		exempt   string
+	}{
+		"country":                  {entities.User{Country: "DE"}, 19, ""},
+		"region":                   {entities.User{Country: "US", Region: "CA"}, 7.25, ""},
+		"region without a rate":    {entities.User{Country: "US", Region: "TX"}, 0, ""},
+		"country without a rate":   {entities.User{Country: "FR"}, 20, ""},
+		"domestic VAT number":      {entities.User{Country: "DE", VATNumber: "DE123"}, 19, ""},
+		"foreign VAT number":       {entities.User{Country: "FR", VATNumber: "FR123"}, 0, TaxReverseCharge},
+		"exempt":                   {entities.User{Country: "DE", TaxExempt: true}, 0, TaxExemptUser},
+		"no country and no region": {entities.User{}, 20, ""},
+	} {
+		result := calculator.Calculate(&tc.customer, 50)
+		if result.Rate != tc.rate || result.ExemptReason != tc.exempt {
+			t.Errorf("%s: rate %v exempt %q, want %v %q", name, result.Rate, result.ExemptReason, tc.rate, tc.exempt)
+		}
+		if want := roundMoney(50 * tc.rate / 100); result.Tax != want || result.Total != 50+want {
+			t.Errorf("%s: tax %v total %v, want %v on 50.00", name, result.Tax, result.Total, want)
+		}
+	}
+
+	disabled := NewTaxCalculator(config.BillingConfig{Tax: config.TaxConfig{DefaultRate: 20}})
+	if result := disabled.Calculate(&entities.User{Country: "DE"}, 50); result.Tax != 0 || result.Total != 50 {
+		t.Errorf("disabled tax = %+v, want none", result)
+	}
+}
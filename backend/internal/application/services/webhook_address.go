@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookAddressNotPublic is returned for webhook URLs that are or resolve to
+// an address inside the panel's network
+var ErrWebhookAddressNotPublic = errors.New("webhook URL must resolve to a public address")
+
+// nonPublicPrefixes are the ranges webhooks may not reach besides loopback,
+// private, link-local, multicast and unspecified addresses
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64 of any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64
+}
+
+// IsPublicAddress reports whether webhooks may be delivered to ip. Loopback,
+// private, link-local (which holds cloud metadata endpoints), CGNAT and other
+// special-purpose addresses are refused.
+func IsPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckWebhookURL refuses a webhook URL whose host is, or resolves to, an
+// address IsPublicAddress refuses
+func CheckWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ErrWebhookAddressNotPublic
+	}
+
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if !IsPublicAddress(ip) {
+			return ErrWebhookAddressNotPublic
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return ErrWebhookAddressNotPublic
+	}
+	for _, ip := range addrs {
+		if !IsPublicAddress(ip) {
+			return ErrWebhookAddressNotPublic
+		}
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient creates an HTTP client for delivering webhooks to user
+// supplied URLs. It only connects to addresses IsPublicAddress allows, checked
+// on the address actually dialed so a host re-resolving to an internal address
+// after CheckWebhookURL accepted it is still refused. Proxies are not used, as
+// they would dial on the client's behalf.
+func NewWebhookHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !IsPublicAddress(addrPort.Addr()) {
+				return ErrWebhookAddressNotPublic
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"fe80::1", false},
+		{"fd00:ec2::254", false}, // Private IPv6, as used for metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false}, // IPv4-mapped loopback
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false}, // NAT64 of the metadata address
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	ctx := context.Background()
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://10.0.0.5/hook",
+		"http:///hook",
+	} {
+		if err := CheckWebhookURL(ctx, url); !errors.Is(err, ErrWebhookAddressNotPublic) {
+			t.Errorf("CheckWebhookURL(%s) = %v, want %v", url, err, ErrWebhookAddressNotPublic)
+		}
+	}
+	if err := CheckWebhookURL(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckWebhookURL(public address) = %v, want nil", err)
+	}
+}
+
+func TestWebhookHTTPClientRefusesInternalAddresses(t *testing.T) {
+	var hits int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// The receiver listens on loopback, as a host re-resolving to an internal
+	// address after its URL was accepted would
+	cfg := config.WebhookConfig{MaxRetries: 0, RetryBackoff: time.Millisecond}
+	result := SendWebhook(context.Background(), NewWebhookHTTPClient(time.Second), cfg, receiver.URL, "", "server.started", []byte(`{}`))
+	if !errors.Is(result.Err, ErrWebhookAddressNotPublic) {
+		t.Errorf("delivery error = %v, want %v", result.Err, ErrWebhookAddressNotPublic)
+	}
+	if hits != 0 {
+		t.Errorf("receiver was reached %d times", hits)
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWebhookNotFound        = errors.New("webhook not found")
+	ErrWebhookDeliveryFailed  = errors.New("webhook delivery failed")
+	ErrWebhookInvalidTemplate = errors.New("invalid webhook template")
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Aether-Signature"
+	WebhookEventHeader     = "X-Aether-Event"
+)
+
+// defaultWebhookTemplates maps event types to their default message template
+var defaultWebhookTemplates = map[string]string{
+	entities.EventNodeOffline:   `Node{{with index .Data "node_name"}} {{.}}{{end}} is offline. {{.Message}}`,
+	entities.EventServerCrashed: `Server{{with index .Data "server_name"}} {{.}}{{end}} crashed. {{.Message}}`,
+	entities.EventBackupFailed:  `Backup{{with index .Data "backup_name"}} {{.}}{{end}} failed. {{.Message}}`,
+	entities.EventResourceHigh:  `High resource usage detected. {{.Message}}`,
+	entities.EventResourceOK:    `Resource usage is back to normal. {{.Message}}`,
+	entities.EventServerOOM:     `Server{{with index .Data "server_name"}} {{.}}{{end}} ran out of memory. {{.Message}}`,
+	entities.EventNodeCert:      `Node{{with index .Data "node_name"}} {{.}}{{end}} has a certificate problem. {{.Message}}`,
+	entities.EventBackupCorrupt: `Backup{{with index .Data "backup_name"}} {{.}}{{end}} is corrupted. {{.Message}}`,
+}
+
+// webhookTitles maps event types to a human readable title
+var webhookTitles = map[string]string{
+	entities.EventNodeOffline:   "Node Offline",
+	entities.EventServerCrashed: "Server Crashed",
+	entities.EventBackupFailed:  "Backup Failed",
+	entities.EventResourceHigh:  "High Resource Usage",
+	entities.EventResourceOK:    "Resource Usage Recovered",
+	entities.EventServerOOM:     "Server Out of Memory",
+	entities.EventNodeCert:      "Node Certificate",
+	entities.EventBackupCorrupt: "Backup Corrupted",
+}
+
+// severityColors maps event severity to an RGB color
+var severityColors = map[string]int{
+	"info":     0x3b82f6,
+	"warning":  0xf59e0b,
+	"error":    0xef4444,
+	"critical": 0x991b1b,
+}
+
+// WebhookService delivers system events to registered webhooks. Events passed
+// to Notify are queued and delivered by a fixed number of workers, started by
+// Start and drained by Shutdown.
+type WebhookService struct {
+	webhookRepo repositories.WebhookRepository
+	httpClient  *http.Client
+	config      config.WebhookConfig
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan entities.SystemEvent
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(webhookRepo repositories.WebhookRepository, cfg *config.Config) *WebhookService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		httpClient:  &http.Client{Timeout: cfg.Webhooks.Timeout},
+		config:      cfg.Webhooks,
+		queue:       make(chan entities.SystemEvent, max(cfg.Webhooks.QueueSize, 1)),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// WebhookMessageData is the data passed to webhook message templates
+type WebhookMessageData struct {
+	EventType string
+	Severity  string
+	Message   string
+	Data      map[string]interface{}
+	Timestamp time.Time
+}
+
+// Dispatch delivers an event to every active webhook subscribed to its type
+func (s *WebhookService) Dispatch(ctx context.Context, event *entities.SystemEvent) error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	webhooks, err := s.webhookRepo.GetActiveByEvent(ctx, event.EventType)
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.EventType) {
+			continue
+		}
+
+		if err := s.Deliver(ctx, webhook, event); err != nil {
+			_ = s.webhookRepo.RecordFailure(ctx, webhook.ID, err.Error())
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.ID, err))
+			continue
+		}
+		_ = s.webhookRepo.RecordDelivery(ctx, webhook.ID, time.Now())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Notify queues an event for the workers, so webhook retries never hold up
+// whoever created the event. Events are dropped when the queue is full or the
+// service is shutting down.
+func (s *WebhookService) Notify(event entities.SystemEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		logger.Ctx(s.ctx).Warn("Webhook queue full, dropping system event", zap.String("event", event.EventType))
+	}
+}
+
+// Start starts the workers delivering queued events
+func (s *WebhookService) Start() {
+	for i := 0; i < max(s.config.Workers, 1); i++ {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			for event := range s.queue {
+				if err := s.Dispatch(s.ctx, &event); err != nil {
+					logger.Ctx(s.ctx).Warn("Failed to deliver system event to webhooks",
+						zap.String("event", event.EventType),
+						zap.Error(err),
+					)
+				}
+			}
+		}()
+	}
+}
+
+// Shutdown stops queueing events and waits for the workers to deliver the
+// queued ones. When ctx is done first, deliveries in flight are cancelled.
+func (s *WebhookService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("webhook deliveries cut off: %w", ctx.Err())
+	}
+}
+
+// Deliver sends a single event to a webhook, retrying with exponential backoff
+func (s *WebhookService) Deliver(ctx context.Context, webhook *entities.Webhook, event *entities.SystemEvent) error {
+	body, err := s.BuildPayload(webhook, event)
+	if err != nil {
+		return err
+	}
+
+	result := SendWebhook(ctx, s.httpClient, s.config, webhook.URL, webhook.Secret, event.EventType, body)
+	return result.Err
+}
+
+// WebhookResult is the outcome of sending a payload to a webhook
+type WebhookResult struct {
+	Attempts   int
+	StatusCode int // Of the last attempt, 0 if no response was received
+	Err        error
+}
+
+// SendWebhook posts a payload to a webhook URL, signed with secret when one
+// is set, retrying failures with exponential backoff
+func SendWebhook(ctx context.Context, client *http.Client, cfg config.WebhookConfig, url, secret, eventType string, body []byte) WebhookResult {
+	var result WebhookResult
+	backoff := cfg.RetryBackoff
+	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return result
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		result.Attempts++
+		status, err := postWebhook(ctx, client, url, secret, eventType, body)
+		result.StatusCode = status
+		if err == nil && status < 300 {
+			result.Err = nil
+			return result
+		}
+
+		if err != nil {
+			result.Err = err
+			continue
+		}
+
+		result.Err = fmt.Errorf("%w: status %d", ErrWebhookDeliveryFailed, status)
+
+		// Client errors other than rate limiting will not succeed on retry
+		if status < 500 && status != http.StatusTooManyRequests {
+			return result
+		}
+	}
+
+	return result
+}
+
+// BuildPayload renders the provider specific request body for an event
+func (s *WebhookService) BuildPayload(webhook *entities.Webhook, event *entities.SystemEvent) ([]byte, error) {
+	message, err := renderWebhookMessage(webhook, event)
+	if err != nil {
+		return nil, err
+	}
+
+	title := webhookTitles[event.EventType]
+	if title == "" {
+		title = event.EventType
+	}
+
+	severity := event.Severity
+	if severity == "" {
+		severity = "info"
+	}
+
+	timestamp := event.CreatedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var payload interface{}
+	switch webhook.Provider {
+	case entities.WebhookProviderDiscord:
+		fields := make([]map[string]interface{}, 0, len(event.Data))
+		for k, v := range event.Data {
+			fields = append(fields, map[string]interface{}{
+				"name":   k,
+				"value":  fmt.Sprint(v),
+				"inline": true,
+			})
+		}
+		payload = map[string]interface{}{
+			"username": s.config.Username,
+			"embeds": []map[string]interface{}{
+				{
+					"title":       title,
+					"description": message,
+					"color":       severityColors[severity],
+					"timestamp":   timestamp.UTC().Format(time.RFC3339),
+					"fields":      fields,
+					"footer":      map[string]string{"text": event.EventType},
+				},
+			},
+		}
+	case entities.WebhookProviderSlack:
+		fields := make([]map[string]interface{}, 0, len(event.Data))
+		for k, v := range event.Data {
+			fields = append(fields, map[string]interface{}{
+				"title": k,
+				"value": fmt.Sprint(v),
+				"short": true,
+			})
+		}
+		payload = map[string]interface{}{
+			"username": s.config.Username,
+			"text":     title,
+			"attachments": []map[string]interface{}{
+				{
+					"color":  fmt.Sprintf("#%06x", severityColors[severity]),
+					"title":  title,
+					"text":   message,
+					"fields": fields,
+					"footer": event.EventType,
+					"ts":     timestamp.Unix(),
+				},
+			},
+		}
+	default:
+		payload = map[string]interface{}{
+			"event":     event.EventType,
+			"severity":  severity,
+			"title":     title,
+			"message":   message,
+			"node_id":   event.NodeID,
+			"server_id": event.ServerID,
+			"data":      event.Data,
+			"timestamp": timestamp.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// postWebhook sends the payload to a webhook URL and returns the response status
+func postWebhook(ctx context.Context, client *http.Client, url, secret, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, eventType)
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of a payload
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateWebhookTemplate checks that a message template parses
+func ValidateWebhookTemplate(tmpl string) error {
+	if _, err := template.New("webhook").Option("missingkey=zero").Parse(tmpl); err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookInvalidTemplate, err)
+	}
+	return nil
+}
+
+// renderWebhookMessage renders the message body for an event
+func renderWebhookMessage(webhook *entities.Webhook, event *entities.SystemEvent) (string, error) {
+	text := webhook.Template
+	if text == "" {
+		text = defaultWebhookTemplates[event.EventType]
+	}
+	if text == "" {
+		return event.Message, nil
+	}
+
+	tmpl, err := template.New("webhook").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrWebhookInvalidTemplate, err)
+	}
+
+	data := WebhookMessageData{
+		EventType: event.EventType,
+		Severity:  event.Severity,
+		Message:   event.Message,
+		Data:      event.Data,
+		Timestamp: event.CreatedAt,
+	}
+	if data.Data == nil {
+		data.Data = map[string]interface{}{}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrWebhookInvalidTemplate, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+)
+
+// fakeWebhooks serves webhooks from memory and records delivery outcomes
+type fakeWebhooks struct {
+	repositories.WebhookRepository
+	webhooks []*entities.Webhook
+
+	mu        sync.Mutex
+	delivered []uuid.UUID
+	failed    []uuid.UUID
+}
+
+func (f *fakeWebhooks) GetActiveByEvent(ctx context.Context, eventType string) ([]*entities.Webhook, error) {
+	return f.webhooks, nil
+}
+
+func (f *fakeWebhooks) RecordDelivery(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeWebhooks) RecordFailure(ctx context.Context, id uuid.UUID, errMsg string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+func testWebhookConfig() *config.Config {
+	return &config.Config{Webhooks: config.WebhookConfig{
+		Enabled:      true,
+		Timeout:      time.Second,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+		Username:     "Aether Panel",
+	}}
+}
+
+func TestBuildPayloadDiscord(t *testing.T) {
+	s := NewWebhookService(&fakeWebhooks{}, testWebhookConfig())
+	webhook := &entities.Webhook{Provider: entities.WebhookProviderDiscord}
+	event := &entities.SystemEvent{
+		EventType: entities.EventNodeOffline,
+		Severity:  "critical",
+		Message:   "No heartbeat for 2 minutes.",
+		Data:      map[string]interface{}{"node_name": "de-1"},
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	body, err := s.BuildPayload(webhook, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Username string `json:"username"`
+		Embeds   []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+			Timestamp   string `json:"timestamp"`
+			Fields      []struct {
+				Name   string `json:"name"`
+				Value  string `json:"value"`
+				Inline bool   `json:"inline"`
+			} `json:"fields"`
+			Footer struct {
+				Text string `json:"text"`
+			} `json:"footer"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Username != "Aether Panel" {
+		t.Errorf("username = %q", payload.Username)
+	}
+	if len(payload.Embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(payload.Embeds))
+	}
+	embed := payload.Embeds[0]
+	if embed.Title != "Node Offline" {
+		t.Errorf("title = %q", embed.Title)
+	}
+	if embed.Description != "Node de-1 is offline. No heartbeat for 2 minutes." {
+		t.Errorf("description = %q", embed.Description)
+	}
+	if embed.Color != severityColors["critical"] {
+		t.Errorf("color = %#x, want %#x", embed.Color, severityColors["critical"])
+	}
+	if embed.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Errorf("timestamp = %q", embed.Timestamp)
+	}
+	if embed.Footer.Text != entities.EventNodeOffline {
+		t.Errorf("footer = %q", embed.Footer.Text)
+	}
+	if len(embed.Fields) != 1 || embed.Fields[0].Name != "node_name" || embed.Fields[0].Value != "de-1" || !embed.Fields[0].Inline {
+		t.Errorf("fields = %+v", embed.Fields)
+	}
+}
+
+func TestSendWebhookRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get(WebhookSignatureHeader) == "" {
+			t.Error("retried request is not signed")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testWebhookConfig().Webhooks
+	result := SendWebhook(context.Background(), srv.Client(), cfg, srv.URL, "secret", entities.EventServerCrashed, []byte(`{}`))
+	if result.Err != nil {
+		t.Fatalf("SendWebhook: %v", result.Err)
+	}
+	if result.Attempts != 3 || result.StatusCode != http.StatusNoContent {
+		t.Errorf("attempts = %d, status = %d, want 3 attempts ending in 204", result.Attempts, result.StatusCode)
+	}
+}
+
+func TestSendWebhookGivesUp(t *testing.T) {
+	for _, tc := range []struct {
+		status   int
+		attempts int
+	}{
+		{http.StatusInternalServerError, 4}, // MaxRetries + 1
+		{http.StatusBadRequest, 1},
+	} {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(tc.status)
+		}))
+
+		cfg := testWebhookConfig().Webhooks
+		result := SendWebhook(context.Background(), srv.Client(), cfg, srv.URL, "", entities.EventServerCrashed, []byte(`{}`))
+		srv.Close()
+
+		if !errors.Is(result.Err, ErrWebhookDeliveryFailed) {
+			t.Errorf("status %d: err = %v, want %v", tc.status, result.Err, ErrWebhookDeliveryFailed)
+		}
+		if result.Attempts != tc.attempts || int(calls.Load()) != tc.attempts {
+			t.Errorf("status %d: %d attempts, %d calls, want %d", tc.status, result.Attempts, calls.Load(), tc.attempts)
+		}
+	}
+}
+
+func TestDispatchRecordsOutcomes(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer broken.Close()
+
+	repo := &fakeWebhooks{webhooks: []*entities.Webhook{
+		{ID: uuid.New(), URL: ok.URL, Events: []string{entities.EventBackupFailed}},
+		{ID: uuid.New(), URL: broken.URL, Events: []string{"*"}},
+		{ID: uuid.New(), URL: ok.URL, Events: []string{entities.EventNodeOffline}},
+	}}
+	s := NewWebhookService(repo, testWebhookConfig())
+
+	err := s.Dispatch(context.Background(), &entities.SystemEvent{EventType: entities.EventBackupFailed, Message: "disk full"})
+	if !errors.Is(err, ErrWebhookDeliveryFailed) {
+		t.Errorf("Dispatch = %v, want %v", err, ErrWebhookDeliveryFailed)
+	}
+	if len(repo.delivered) != 1 || repo.delivered[0] != repo.webhooks[0].ID {
+		t.Errorf("delivered = %v, want only the subscribed webhook", repo.delivered)
+	}
+	if len(repo.failed) != 1 || repo.failed[0] != repo.webhooks[1].ID {
+		t.Errorf("failed = %v, want only the broken webhook", repo.failed)
+	}
+}
+
+func TestNotifyQueuesEventsUntilShutdown(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	cfg := testWebhookConfig()
+	cfg.Webhooks.Workers, cfg.Webhooks.QueueSize = 2, 2
+	repo := &fakeWebhooks{webhooks: []*entities.Webhook{{ID: uuid.New(), URL: server.URL, Events: []string{"*"}}}}
+	s := NewWebhookService(repo, cfg)
+
+	// The queue holds two events, the third is dropped
+	for i := 0; i < 3; i++ {
+		s.Notify(entities.SystemEvent{EventType: entities.EventNodeOffline})
+	}
+	s.Start()
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if got := received.Load(); got != 2 {
+		t.Errorf("delivered %d events, want the 2 queued", got)
+	}
+
+	s.Notify(entities.SystemEvent{EventType: entities.EventNodeOffline})
+	if got := received.Load(); got != 2 {
+		t.Errorf("delivered %d events after the shutdown, want 2", got)
+	}
+}
+
+func TestShutdownCancelsDeliveriesPastTheDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := testWebhookConfig()
+	cfg.Webhooks.Timeout = time.Minute
+	repo := &fakeWebhooks{webhooks: []*entities.Webhook{{ID: uuid.New(), URL: server.URL, Events: []string{"*"}}}}
+	s := NewWebhookService(repo, cfg)
+	s.Start()
+	s.Notify(entities.SystemEvent{EventType: entities.EventNodeOffline})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Shutdown took %s, want it to give up at the deadline", elapsed)
+	}
+	s.workers.Wait()
+}
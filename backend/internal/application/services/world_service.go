@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrWorldNotFound           = errors.New("world not found")
+	ErrWorldBackupNotFound     = errors.New("world backup not found")
+	ErrWorldBackupNotCompleted = errors.New("world backup has not completed")
+	ErrServerMustBeStopped     = errors.New("server must be stopped first")
+	ErrWorldNodeFailed         = errors.New("node failed the world operation")
+)
+
+// WorldInfo is a world folder as reported by a node
+type WorldInfo struct {
+	FolderName string     `json:"folder_name"`
+	Name       string     `json:"name"`
+	Dimension  string     `json:"dimension"`
+	Seed       string     `json:"seed"`
+	GameMode   string     `json:"game_mode"`
+	Difficulty string     `json:"difficulty"`
+	Size       int64      `json:"size"`
+	LastPlayed *time.Time `json:"last_played"`
+}
+
+// WorldArchive describes a world backup archive written by a node
+type WorldArchive struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// WorldClient is the part of the node agent API used to manage worlds
+type WorldClient interface {
+	ListWorlds(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) ([]WorldInfo, error)
+	BackupWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID) (*WorldArchive, error)
+	RestoreWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID, checksum string) error
+	DeleteWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string) error
+}
+
+// ServerNotifier delivers transitions of a server to its webhooks
+type ServerNotifier interface {
+	Notify(server *entities.Server, event string, data map[string]interface{})
+}
+
+// WorldService manages the Minecraft worlds of servers through their node
+type WorldService struct {
+	worldRepo  repositories.WorldRepository
+	backupRepo repositories.WorldBackupRepository
+	serverRepo repositories.ServerRepository
+	auditRepo  repositories.AuditLogRepository
+	client     WorldClient
+	notifier   ServerNotifier
+}
+
+// NewWorldService creates a new WorldService
+func NewWorldService(
+	worldRepo repositories.WorldRepository,
+	backupRepo repositories.WorldBackupRepository,
+	serverRepo repositories.ServerRepository,
+	auditRepo repositories.AuditLogRepository,
+	client WorldClient,
+	notifier ServerNotifier,
+) *WorldService {
+	return &WorldService{
+		worldRepo:  worldRepo,
+		backupRepo: backupRepo,
+		serverRepo: serverRepo,
+		auditRepo:  auditRepo,
+		client:     client,
+		notifier:   notifier,
+	}
+}
+
+// List scans the server's data directory for worlds and brings the stored
+// worlds in line with it. Worlds whose folder disappeared are removed.
+func (s *WorldService) List(ctx context.Context, serverID uuid.UUID) ([]*entities.World, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, ErrServerNotFound
+	}
+
+	found, err := s.client.ListWorlds(ctx, server.NodeID, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWorldNodeFailed, err)
+	}
+
+	stored, err := s.worldRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	byFolder := make(map[string]*entities.World, len(stored))
+	for _, w := range stored {
+		byFolder[w.FolderName] = w
+	}
+
+	worlds := make([]*entities.World, 0, len(found))
+	for _, info := range found {
+		world, exists := byFolder[info.FolderName]
+		if !exists {
+			world = &entities.World{ServerID: serverID, FolderName: info.FolderName}
+		}
+		delete(byFolder, info.FolderName)
+
+		world.Name = info.Name
+		world.Dimension = info.Dimension
+		world.Seed = info.Seed
+		world.GameMode = info.GameMode
+		world.Difficulty = info.Difficulty
+		world.Size = info.Size
+		world.LastPlayed = info.LastPlayed
+
+		if exists {
+			err = s.worldRepo.Update(ctx, world)
+		} else {
+			err = s.worldRepo.Create(ctx, world)
+		}
+		if err != nil {
+			return nil, err
+		}
+		worlds = append(worlds, world)
+	}
+
+	for _, gone := range byFolder {
+		_ = s.worldRepo.Delete(ctx, gone.ID)
+	}
+
+	return worlds, nil
+}
+
+// Get returns one of a server's worlds
+func (s *WorldService) Get(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID) (*entities.World, error) {
+	_, world, err := s.getWorld(ctx, serverID, worldID)
+	return world, err
+}
+
+// CreateBackup archives a world on its node and records the archive's size
+// and checksum
+func (s *WorldService) CreateBackup(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID, name string, userID uuid.UUID) (*entities.WorldBackup, error) {
+	server, world, err := s.getWorld(ctx, serverID, worldID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = world.Name + " " + time.Now().UTC().Format("2006-01-02 15:04")
+	}
+	backup, err := s.backupWorld(ctx, server, world, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionBackup, world, nil)
+	return backup, nil
+}
+
+// backupWorld archives a world on its node and records the backup, notifying
+// the server's webhooks either way
+func (s *WorldService) backupWorld(ctx context.Context, server *entities.Server, world *entities.World, name string) (*entities.WorldBackup, error) {
+	backup := &entities.WorldBackup{
+		WorldID: world.ID,
+		Name:    name,
+		Status:  entities.BackupStatusInProgress,
+	}
+	if err := s.backupRepo.Create(ctx, backup); err != nil {
+		return nil, err
+	}
+
+	archive, err := s.client.BackupWorld(ctx, server.NodeID, server.ID, world.FolderName, backup.ID)
+	if err != nil {
+		backup.Status = entities.BackupStatusFailed
+		_ = s.backupRepo.Update(ctx, backup)
+		s.notifier.Notify(server, entities.EventBackupFailed, worldBackupData(world, backup))
+		return nil, fmt.Errorf("%w: %w", ErrWorldNodeFailed, err)
+	}
+
+	now := time.Now()
+	backup.Status = entities.BackupStatusCompleted
+	backup.Size = archive.Size
+	backup.Checksum = archive.Checksum
+	backup.StoragePath = "worlds/" + backup.ID.String() + ".tar.gz"
+	backup.CompletedAt = &now
+	if err := s.backupRepo.Update(ctx, backup); err != nil {
+		return nil, err
+	}
+
+	s.notifier.Notify(server, entities.ServerEventBackupCompleted, worldBackupData(world, backup))
+	return backup, nil
+}
+
+// ListBackups returns the backups of a world
+func (s *WorldService) ListBackups(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID) ([]*entities.WorldBackup, error) {
+	if _, _, err := s.getWorld(ctx, serverID, worldID); err != nil {
+		return nil, err
+	}
+	return s.backupRepo.GetByWorldID(ctx, worldID)
+}
+
+// RestoreBackup replaces a world with a completed backup. The node verifies
+// the archive checksum and refuses while the server is running; an archive
+// that fails the check is flagged as corrupted. With safetyBackup the world is
+// backed up first and the restore only runs once that backup has completed.
+func (s *WorldService) RestoreBackup(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID, backupID uuid.UUID, userID uuid.UUID, safetyBackup bool) error {
+	server, world, err := s.getWorld(ctx, serverID, worldID)
+	if err != nil {
+		return err
+	}
+
+	backup, err := s.backupRepo.GetByID(ctx, backupID)
+	if err != nil || backup.WorldID != worldID {
+		return ErrWorldBackupNotFound
+	}
+	if backup.Status != entities.BackupStatusCompleted || backup.Checksum == "" {
+		return ErrWorldBackupNotCompleted
+	}
+	if server.IsRunning() {
+		return ErrServerMustBeStopped
+	}
+
+	metadata := map[string]interface{}{"backup_id": backupID}
+	if safetyBackup {
+		name := "Before restore " + time.Now().UTC().Format("2006-01-02 15:04")
+		safety, err := s.backupWorld(ctx, server, world, name)
+		if err != nil {
+			logger.Ctx(ctx).Warn("Safety backup failed", zap.String("world_id", worldID.String()), zap.Error(err))
+			return ErrSafetyBackupFailed
+		}
+		metadata["safety_backup_id"] = safety.ID
+	}
+
+	err = s.client.RestoreWorld(ctx, server.NodeID, serverID, world.FolderName, backupID, backup.Checksum)
+	if errors.Is(err, ErrBackupCorrupted) {
+		// Flag it so it is not offered again; the archive cannot be trusted
+		backup.Status = entities.BackupStatusCorrupted
+		_ = s.backupRepo.Update(ctx, backup)
+		return ErrBackupCorrupted
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWorldNodeFailed, err)
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionRestore, world, metadata)
+	return nil
+}
+
+// Delete removes a world folder from the server. Its backups are kept.
+func (s *WorldService) Delete(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID, userID uuid.UUID) error {
+	server, world, err := s.getWorld(ctx, serverID, worldID)
+	if err != nil {
+		return err
+	}
+	if server.IsRunning() {
+		return ErrServerMustBeStopped
+	}
+
+	if err := s.client.DeleteWorld(ctx, server.NodeID, serverID, world.FolderName); err != nil {
+		return fmt.Errorf("%w: %w", ErrWorldNodeFailed, err)
+	}
+	if err := s.worldRepo.Delete(ctx, worldID); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, userID, entities.AuditActionDelete, world, nil)
+	return nil
+}
+
+// getWorld loads a server and one of its worlds
+func (s *WorldService) getWorld(ctx context.Context, serverID uuid.UUID, worldID uuid.UUID) (*entities.Server, *entities.World, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, nil, ErrServerNotFound
+	}
+	world, err := s.worldRepo.GetByID(ctx, worldID)
+	if err != nil || world.ServerID != serverID {
+		return nil, nil, ErrWorldNotFound
+	}
+	return server, world, nil
+}
+
+// logAudit records an action on a world, with extra metadata merged into the
+// world's own
+func (s *WorldService) logAudit(ctx context.Context, userID uuid.UUID, action entities.AuditAction, world *entities.World, extra map[string]interface{}) {
+	metadata := map[string]interface{}{"server_id": world.ServerID, "folder": world.FolderName}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	log := &entities.AuditLog{
+		UserID:     &userID,
+		Action:     action,
+		Resource:   "world",
+		ResourceID: &world.ID,
+		Metadata:   metadata,
+	}
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.Ctx(ctx).Warn("Failed to write audit log", zap.String("resource", "world"), zap.Error(err))
+	}
+}
+
+// worldBackupData is the webhook data of a world backup transition
+func worldBackupData(world *entities.World, backup *entities.WorldBackup) map[string]interface{} {
+	return map[string]interface{}{
+		"backup_id":   backup.ID,
+		"backup_name": backup.Name,
+		"world":       world.Name,
+		"size":        backup.Size,
+	}
+}
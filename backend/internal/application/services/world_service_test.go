@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeWorlds keeps worlds in memory
+type fakeWorlds struct {
+	repositories.WorldRepository
+	worlds map[uuid.UUID]*entities.World
+}
+
+func (f fakeWorlds) Create(ctx context.Context, world *entities.World) error {
+	world.ID = uuid.New()
+	f.worlds[world.ID] = world
+	return nil
+}
+
+func (f fakeWorlds) GetByID(ctx context.Context, id uuid.UUID) (*entities.World, error) {
+	if world, ok := f.worlds[id]; ok {
+		copied := *world
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeWorlds) Update(ctx context.Context, world *entities.World) error {
+	f.worlds[world.ID] = world
+	return nil
+}
+
+func (f fakeWorlds) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(f.worlds, id)
+	return nil
+}
+
+func (f fakeWorlds) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.World, error) {
+	var worlds []*entities.World
+	for _, world := range f.worlds {
+		if world.ServerID == serverID {
+			copied := *world
+			worlds = append(worlds, &copied)
+		}
+	}
+	return worlds, nil
+}
+
+// fakeWorldBackups keeps world backups in memory
+type fakeWorldBackups struct {
+	repositories.WorldBackupRepository
+	backups map[uuid.UUID]*entities.WorldBackup
+}
+
+func (f fakeWorldBackups) Create(ctx context.Context, backup *entities.WorldBackup) error {
+	backup.ID = uuid.New()
+	copied := *backup
+	f.backups[backup.ID] = &copied
+	return nil
+}
+
+func (f fakeWorldBackups) GetByID(ctx context.Context, id uuid.UUID) (*entities.WorldBackup, error) {
+	if backup, ok := f.backups[id]; ok {
+		copied := *backup
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeWorldBackups) Update(ctx context.Context, backup *entities.WorldBackup) error {
+	copied := *backup
+	f.backups[backup.ID] = &copied
+	return nil
+}
+
+// fakeWorldClient serves the worlds on a node and records what it was asked to do
+type fakeWorldClient struct {
+	found      []WorldInfo
+	backupErr  error
+	restoreErr error
+	backedUp   []uuid.UUID
+	restored   []uuid.UUID
+}
+
+func (f *fakeWorldClient) ListWorlds(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) ([]WorldInfo, error) {
+	return f.found, nil
+}
+
+func (f *fakeWorldClient) BackupWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID) (*WorldArchive, error) {
+	if f.backupErr != nil {
+		return nil, f.backupErr
+	}
+	f.backedUp = append(f.backedUp, backupID)
+	return &WorldArchive{Size: 1024, Checksum: "abc123"}, nil
+}
+
+func (f *fakeWorldClient) RestoreWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID, checksum string) error {
+	if f.restoreErr != nil {
+		return f.restoreErr
+	}
+	f.restored = append(f.restored, backupID)
+	return nil
+}
+
+func (f *fakeWorldClient) DeleteWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string) error {
+	return nil
+}
+
+// fakeNotifier records the webhook events sent
+type fakeNotifier struct {
+	events []string
+}
+
+func (f *fakeNotifier) Notify(server *entities.Server, event string, data map[string]interface{}) {
+	f.events = append(f.events, event)
+}
+
+type worldTest struct {
+	service  *WorldService
+	store    *fakeStore
+	worlds   fakeWorlds
+	backups  fakeWorldBackups
+	client   *fakeWorldClient
+	notifier *fakeNotifier
+	server   *entities.Server
+	world    *entities.World
+	userID   uuid.UUID
+}
+
+func newWorldTest() *worldTest {
+	store := newFakeStore()
+	wt := &worldTest{
+		store:    store,
+		worlds:   fakeWorlds{worlds: map[uuid.UUID]*entities.World{}},
+		backups:  fakeWorldBackups{backups: map[uuid.UUID]*entities.WorldBackup{}},
+		client:   &fakeWorldClient{},
+		notifier: &fakeNotifier{},
+		userID:   uuid.New(),
+	}
+	wt.server = &entities.Server{ID: uuid.New(), NodeID: uuid.New(), Status: entities.ServerStatusStopped}
+	store.servers[wt.server.ID] = wt.server
+	wt.world = &entities.World{ServerID: wt.server.ID, Name: "Survival", FolderName: "world"}
+	_ = wt.worlds.Create(context.Background(), wt.world)
+
+	wt.service = NewWorldService(wt.worlds, wt.backups, fakeServers{store: store}, fakeAuditLogs{store: store}, wt.client, wt.notifier)
+	return wt
+}
+
+// completedBackup stores a completed backup of the world
+func (wt *worldTest) completedBackup() *entities.WorldBackup {
+	backup := &entities.WorldBackup{WorldID: wt.world.ID, Name: "nightly", Status: entities.BackupStatusCompleted, Checksum: "abc123"}
+	_ = wt.backups.Create(context.Background(), backup)
+	return backup
+}
+
+func TestWorldListSyncsStoredWorlds(t *testing.T) {
+	wt := newWorldTest()
+	gone := &entities.World{ServerID: wt.server.ID, Name: "Old", FolderName: "old"}
+	_ = wt.worlds.Create(context.Background(), gone)
+	wt.client.found = []WorldInfo{
+		{FolderName: "world", Name: "Survival", Size: 2048},
+		{FolderName: "world_nether", Name: "Survival", Dimension: "nether"},
+	}
+
+	worlds, err := wt.service.List(context.Background(), wt.server.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 2 || worlds[0].ID != wt.world.ID || worlds[0].Size != 2048 {
+		t.Errorf("listed %+v, want the stored world updated and the nether added", worlds)
+	}
+	if _, ok := wt.worlds.worlds[gone.ID]; ok {
+		t.Error("world whose folder disappeared is still stored")
+	}
+}
+
+func TestWorldCreateBackupNotifies(t *testing.T) {
+	wt := newWorldTest()
+	ctx := context.Background()
+
+	backup, err := wt.service.CreateBackup(ctx, wt.server.ID, wt.world.ID, "", wt.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Status != entities.BackupStatusCompleted || backup.Checksum != "abc123" {
+		t.Errorf("backup = %+v, want it completed with the archive checksum", backup)
+	}
+	if got := wt.store.audited(wt.userID); len(got) != 1 || got[0] != entities.AuditActionBackup {
+		t.Errorf("audited %v, want a backup", got)
+	}
+
+	wt.client.backupErr = errors.New("disk full")
+	if _, err := wt.service.CreateBackup(ctx, wt.server.ID, wt.world.ID, "", wt.userID); !errors.Is(err, ErrWorldNodeFailed) {
+		t.Errorf("CreateBackup on a failing node = %v, want %v", err, ErrWorldNodeFailed)
+	}
+	want := []string{entities.ServerEventBackupCompleted, entities.EventBackupFailed}
+	if len(wt.notifier.events) != 2 || wt.notifier.events[0] != want[0] || wt.notifier.events[1] != want[1] {
+		t.Errorf("notified %v, want %v", wt.notifier.events, want)
+	}
+}
+
+func TestWorldRestoreTakesSafetyBackupFirst(t *testing.T) {
+	wt := newWorldTest()
+	backup := wt.completedBackup()
+
+	if err := wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(wt.client.backedUp) != 1 || len(wt.client.restored) != 1 || wt.client.restored[0] != backup.ID {
+		t.Fatalf("backed up %v and restored %v, want a safety backup and the restore", wt.client.backedUp, wt.client.restored)
+	}
+
+	entry := wt.store.audits[len(wt.store.audits)-1]
+	if entry.Action != entities.AuditActionRestore || entry.Metadata["backup_id"] != backup.ID ||
+		entry.Metadata["safety_backup_id"] != wt.client.backedUp[0] || entry.Metadata["folder"] != "world" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
+
+func TestWorldRestoreAbandonedWhenSafetyBackupFails(t *testing.T) {
+	wt := newWorldTest()
+	backup := wt.completedBackup()
+	wt.client.backupErr = errors.New("disk full")
+
+	err := wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, true)
+	if !errors.Is(err, ErrSafetyBackupFailed) {
+		t.Fatalf("RestoreBackup = %v, want %v", err, ErrSafetyBackupFailed)
+	}
+	if len(wt.client.restored) != 0 {
+		t.Error("world was restored without its safety backup")
+	}
+
+	// Without a safety backup the restore goes ahead
+	if err := wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(wt.client.restored) != 1 {
+		t.Error("world was not restored")
+	}
+}
+
+func TestWorldRestoreFlagsCorruptedBackup(t *testing.T) {
+	wt := newWorldTest()
+	backup := wt.completedBackup()
+	wt.client.restoreErr = errors.Join(ErrBackupCorrupted, errors.New("checksum mismatch"))
+
+	err := wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, false)
+	if !errors.Is(err, ErrBackupCorrupted) {
+		t.Fatalf("RestoreBackup = %v, want %v", err, ErrBackupCorrupted)
+	}
+	if status := wt.backups.backups[backup.ID].Status; status != entities.BackupStatusCorrupted {
+		t.Errorf("backup status = %s, want %s", status, entities.BackupStatusCorrupted)
+	}
+
+	// A corrupted backup is no longer offered for restoring
+	wt.client.restoreErr = nil
+	err = wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, false)
+	if !errors.Is(err, ErrWorldBackupNotCompleted) {
+		t.Errorf("RestoreBackup of a corrupted backup = %v, want %v", err, ErrWorldBackupNotCompleted)
+	}
+}
+
+func TestWorldRestoreRequiresStoppedServer(t *testing.T) {
+	wt := newWorldTest()
+	backup := wt.completedBackup()
+	wt.server.Status = entities.ServerStatusRunning
+
+	err := wt.service.RestoreBackup(context.Background(), wt.server.ID, wt.world.ID, backup.ID, wt.userID, true)
+	if !errors.Is(err, ErrServerMustBeStopped) {
+		t.Fatalf("RestoreBackup = %v, want %v", err, ErrServerMustBeStopped)
+	}
+	if len(wt.client.backedUp) != 0 || len(wt.client.restored) != 0 {
+		t.Error("node was asked to act on a running server")
+	}
+}
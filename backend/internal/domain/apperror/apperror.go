@@ -0,0 +1,38 @@
+package apperror
+
+import "net/http"
+
+// Error is an error meant for API clients. Code is a stable machine readable
+// identifier such as "server.not_found", Message is safe to show to users.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error // Underlying cause, never sent to clients
+}
+
+// New creates a new Error
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Internal is returned to clients for errors that have no public meaning
+var Internal = New(http.StatusInternalServerError, "internal", "Internal Server Error")
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e carrying err as its cause
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
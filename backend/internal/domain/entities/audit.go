@@ -22,6 +22,17 @@ const (
 	AuditActionRestore AuditAction = "restore"
 	AuditActionInstall AuditAction = "install"
 	AuditActionCommand AuditAction = "command"
+
+	AuditActionCommandBlocked AuditAction = "command_blocked" // Refused by the server's command policy
+
+	AuditActionImpersonate AuditAction = "impersonate" // Support staff acting as a user
+
+	AuditActionReinstall     AuditAction = "reinstall"      // Install rerun on existing files
+	AuditActionReinstallWipe AuditAction = "reinstall_wipe" // Data wiped before reinstalling
+
+	AuditActionDownload AuditAction = "download"
+
+	AuditActionAccessDenied AuditAction = "access_denied" // Refused by a permission, role or ownership check
 )
 
 // AuditLog represents an audit log entry
@@ -62,6 +73,22 @@ func (ActivityLog) TableName() string {
 	return "activity_logs"
 }
 
+// Server activities shown to users in a server's activity feed
+const (
+	ActivityServerStart     = "server.start"
+	ActivityServerStop      = "server.stop"
+	ActivityServerRestart   = "server.restart"
+	ActivityServerKill      = "server.kill"
+	ActivityServerReinstall = "server.reinstall"
+	ActivityConsoleCommand  = "server.console.command"
+	ActivityBackupCreate    = "server.backup.create"
+	ActivityBackupRestore   = "server.backup.restore"
+	ActivityFileDelete      = "server.file.delete"
+	ActivityDatabaseCreate  = "server.database.create"
+	ActivityDatabaseRotate  = "server.database.rotate-password"
+	ActivityDatabaseDelete  = "server.database.delete"
+)
+
 // SystemEvent represents system-level events
 type SystemEvent struct {
 	ID        uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -15,6 +15,7 @@ const (
 	BackupStatusCompleted  BackupStatus = "completed"
 	BackupStatusFailed     BackupStatus = "failed"
 	BackupStatusDeleted    BackupStatus = "deleted"
+	BackupStatusCorrupted  BackupStatus = "corrupted" // Archive no longer matches its checksum
 )
 
 // Backup represents a server backup
@@ -22,6 +23,7 @@ type Backup struct {
 	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	ServerID    uuid.UUID    `json:"server_id" gorm:"type:uuid;not null;index"`
 	Server      *Server      `json:"server,omitempty" gorm:"foreignKey:ServerID"`
+	EggID       *uuid.UUID   `json:"egg_id" gorm:"type:uuid"` // Egg of the server when the backup was taken
 	Name        string       `json:"name" gorm:"not null;size:100"`
 	Status      BackupStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
 	Checksum    string       `json:"checksum" gorm:"size:64"` // SHA-256
@@ -32,6 +34,7 @@ type Backup struct {
 	ScheduleID  *uuid.UUID   `json:"schedule_id" gorm:"type:uuid"`
 	ErrorMsg    string       `json:"error_msg" gorm:"size:500"`
 	CompletedAt *time.Time   `json:"completed_at"`
+	VerifiedAt  *time.Time   `json:"verified_at"` // Last integrity scan that found the archive intact
 	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt   *time.Time   `json:"deleted_at" gorm:"index"`
@@ -49,6 +52,7 @@ type BackupSchedule struct {
 	Server          *Server    `json:"server,omitempty" gorm:"foreignKey:ServerID"`
 	Name            string     `json:"name" gorm:"not null;size:100"`
 	CronExpression  string     `json:"cron_expression" gorm:"not null;size:50"`
+	Timezone        string     `json:"timezone" gorm:"size:50"` // IANA zone the expression is read in, empty for the panel's
 	RetentionCount  int        `json:"retention_count" gorm:"default:3"`
 	IsActive        bool       `json:"is_active" gorm:"default:true"`
 	LastRunAt       *time.Time `json:"last_run_at"`
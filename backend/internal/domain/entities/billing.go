@@ -89,12 +89,12 @@ type Package struct {
 	ServerLimit     int       `json:"server_limit" gorm:"default:1"`
 
 	// Game restrictions
-	AllowedGames    []uuid.UUID `json:"allowed_games" gorm:"type:jsonb"`
-	AllowedEggs     []uuid.UUID `json:"allowed_eggs" gorm:"type:jsonb"`
-	AllowedNodes    []uuid.UUID `json:"allowed_nodes" gorm:"type:jsonb"`
+	AllowedGames    []uuid.UUID `json:"allowed_games" gorm:"type:jsonb;serializer:json"`
+	AllowedEggs     []uuid.UUID `json:"allowed_eggs" gorm:"type:jsonb;serializer:json"`
+	AllowedNodes    []uuid.UUID `json:"allowed_nodes" gorm:"type:jsonb;serializer:json"`
 
 	// Display
-	Features        []string  `json:"features" gorm:"type:jsonb"`
+	Features        []string  `json:"features" gorm:"type:jsonb;serializer:json"`
 	Badge           string    `json:"badge" gorm:"size:50"` // e.g., "Popular", "Best Value"
 	Color           string    `json:"color" gorm:"size:7"`
 	SortOrder       int       `json:"sort_order" gorm:"default:0"`
@@ -111,6 +111,14 @@ func (Package) TableName() string {
 	return "packages"
 }
 
+// Subscription statuses
+const (
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusSuspended = "suspended"
+	SubscriptionStatusCancelled = "cancelled"
+	SubscriptionStatusExpired   = "expired"
+)
+
 // Subscription represents a user's subscription to a package
 type Subscription struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -139,6 +147,7 @@ type Subscription struct {
 	
 	// Grace period
 	GracePeriodDays int        `json:"grace_period_days" gorm:"default:3"`
+	PaymentFailedAt *time.Time `json:"payment_failed_at"` // First failed renewal of the period, cleared on renewal
 	
 	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
@@ -154,6 +163,23 @@ func (s *Subscription) IsExpired() bool {
 	return time.Now().After(s.EndDate)
 }
 
+// GraceEndsAt returns when an unpaid subscription gets suspended
+func (s *Subscription) GraceEndsAt() time.Time {
+	return s.EndDate.AddDate(0, 0, s.GracePeriodDays)
+}
+
+// PeriodEnd returns the end of a billing period starting at from
+func (s *Subscription) PeriodEnd(from time.Time) time.Time {
+	switch s.BillingCycle {
+	case "quarterly":
+		return from.AddDate(0, 3, 0)
+	case "yearly":
+		return from.AddDate(1, 0, 0)
+	default:
+		return from.AddDate(0, 1, 0)
+	}
+}
+
 // Invoice represents a billing invoice
 type Invoice struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -183,6 +209,9 @@ type Invoice struct {
 	
 	// Notes
 	Notes           string     `json:"notes" gorm:"type:text"`
+
+	// Metadata records how the invoice was computed, such as the applied tax rate
+	Metadata        map[string]interface{} `json:"metadata" gorm:"type:jsonb;default:'{}'"`
 	
 	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
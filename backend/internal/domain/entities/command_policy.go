@@ -0,0 +1,62 @@
+package entities
+
+import "strings"
+
+// Command policy modes
+const (
+	CommandPolicyAllow = "allow" // Only commands matching a prefix are sent
+	CommandPolicyDeny  = "deny"  // Commands matching a prefix are blocked
+)
+
+// PermissionBypassCommandPolicy lets a user send commands a server's command
+// policy blocks. Admins hold it implicitly.
+const PermissionBypassCommandPolicy = "servers.console.unrestricted"
+
+// CommandPolicy restricts the console commands a server accepts from users
+// without admin rights or PermissionBypassCommandPolicy. An empty mode lets
+// every command through.
+type CommandPolicy struct {
+	Mode     string   `json:"mode"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// Allows reports whether the policy lets a command through. Prefixes match
+// whole words without regard to case, a leading slash or a namespace, so "op"
+// matches "/OP Steve" and "minecraft:op Steve" but not "options".
+func (p CommandPolicy) Allows(command string) bool {
+	switch p.Mode {
+	case CommandPolicyAllow:
+		return p.matches(command)
+	case CommandPolicyDeny:
+		return !p.matches(command)
+	}
+	return true
+}
+
+func (p CommandPolicy) matches(command string) bool {
+	command = normalizeCommand(command)
+	for _, prefix := range p.Prefixes {
+		prefix = normalizeCommand(prefix)
+		if prefix == "" {
+			continue
+		}
+		if command == prefix || strings.HasPrefix(command, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCommand lowercases a command and strips its leading slash and the
+// namespace of its first word, as in "minecraft:op"
+func normalizeCommand(command string) string {
+	command = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(command), "/"))
+	name, args, _ := strings.Cut(command, " ")
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if args == "" {
+		return name
+	}
+	return name + " " + args
+}
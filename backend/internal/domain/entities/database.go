@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DatabaseHost is a MySQL or MariaDB server that server databases are created on
+type DatabaseHost struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name              string     `json:"name" gorm:"not null;size:100"`
+	Host              string     `json:"host" gorm:"not null;size:255"`
+	Port              int        `json:"port" gorm:"not null;default:3306"`
+	Username          string     `json:"username" gorm:"not null;size:100"` // Needs CREATE USER and GRANT OPTION
+	PasswordEncrypted string     `json:"-" gorm:"type:text;not null"`
+	NodeID            *uuid.UUID `json:"node_id" gorm:"type:uuid;index"` // Preferred by servers on this node
+	Node              *Node      `json:"node,omitempty" gorm:"foreignKey:NodeID"`
+	MaxDatabases      int        `json:"max_databases" gorm:"default:0"` // 0 for unlimited
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for DatabaseHost
+func (DatabaseHost) TableName() string {
+	return "database_hosts"
+}
+
+// ServerDatabase is a database and its scoped user created for a server
+type ServerDatabase struct {
+	ID                uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ServerID          uuid.UUID     `json:"server_id" gorm:"type:uuid;not null;index"`
+	Server            *Server       `json:"server,omitempty" gorm:"foreignKey:ServerID"`
+	DatabaseHostID    uuid.UUID     `json:"database_host_id" gorm:"type:uuid;not null;index"`
+	DatabaseHost      *DatabaseHost `json:"database_host,omitempty" gorm:"foreignKey:DatabaseHostID"`
+	Database          string        `json:"database" gorm:"not null;size:64"`
+	Username          string        `json:"username" gorm:"not null;size:32"`
+	Remote            string        `json:"remote" gorm:"not null;size:255;default:'%'"` // Hosts the user may connect from
+	PasswordEncrypted string        `json:"-" gorm:"type:text;not null"`
+	CreatedAt         time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for ServerDatabase
+func (ServerDatabase) TableName() string {
+	return "server_databases"
+}
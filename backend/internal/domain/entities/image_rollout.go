@@ -0,0 +1,79 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Image rollout modes
+const (
+	RolloutNextRestart = "next_restart" // Servers move to the image on their next start or restart
+	RolloutImmediate   = "immediate"    // Running servers are restarted onto the image, staggered
+)
+
+// Image rollout states
+const (
+	RolloutPending  = "pending"
+	RolloutPulling  = "pulling"
+	RolloutRunning  = "running"
+	RolloutFinished = "finished"
+	RolloutFailed   = "failed"
+)
+
+// Image rollout server states
+const (
+	RolloutServerPending    = "pending"
+	RolloutServerScheduled  = "scheduled" // Switched, waiting for the server's next start
+	RolloutServerUpgraded   = "upgraded"
+	RolloutServerRolledBack = "rolled_back"
+	RolloutServerFailed     = "failed" // Not upgraded, or the rollback failed too
+)
+
+// ImageRollout moves the servers of an egg onto a new Docker image. The image
+// is pulled on their nodes first, then each server is recreated on it and
+// rolled back to its previous image if it does not come up.
+type ImageRollout struct {
+	ID             uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EggID          uuid.UUID            `json:"egg_id" gorm:"type:uuid;not null;index"`
+	Image          string               `json:"image" gorm:"size:255;not null"`
+	Mode           string               `json:"mode" gorm:"size:20;not null"`
+	StaggerSeconds int                  `json:"stagger_seconds" gorm:"default:0"` // Delay between immediate upgrades
+	Concurrency    int                  `json:"concurrency" gorm:"default:0"`     // Upgrades in flight at once, 0 for the panel default
+	Status         string               `json:"status" gorm:"size:20;default:'pending'"`
+	Error          string               `json:"error,omitempty" gorm:"size:500"`
+	CreatedBy      *uuid.UUID           `json:"created_by" gorm:"type:uuid"`
+	Servers        []ImageRolloutServer `json:"servers,omitempty" gorm:"foreignKey:RolloutID"`
+	FinishedAt     *time.Time           `json:"finished_at"`
+	CreatedAt      time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for ImageRollout
+func (ImageRollout) TableName() string {
+	return "image_rollouts"
+}
+
+// Active reports whether the rollout is still pulling or upgrading servers
+func (r *ImageRollout) Active() bool {
+	return r.Status == RolloutPending || r.Status == RolloutPulling || r.Status == RolloutRunning
+}
+
+// ImageRolloutServer is the progress of one server in an image rollout
+type ImageRolloutServer struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RolloutID     uuid.UUID  `json:"rollout_id" gorm:"type:uuid;not null;index"`
+	ServerID      uuid.UUID  `json:"server_id" gorm:"type:uuid;not null;index"`
+	NodeID        uuid.UUID  `json:"node_id" gorm:"type:uuid;not null"`
+	PreviousImage string     `json:"previous_image" gorm:"size:255"`
+	Status        string     `json:"status" gorm:"size:20;default:'pending'"`
+	Error         string     `json:"error,omitempty" gorm:"size:500"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for ImageRolloutServer
+func (ImageRolloutServer) TableName() string {
+	return "image_rollout_servers"
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types sent to users
+const (
+	NotificationServerCrashed     = "server_crashed"
+	NotificationServerOOM         = "server_oom"
+	NotificationResourceHigh      = "resource_high"
+	NotificationResourceRecovered = "resource_recovered"
+)
+
+// NotificationTypes lists the notification types a user can set preferences for
+var NotificationTypes = []string{
+	NotificationServerCrashed,
+	NotificationServerOOM,
+	NotificationResourceHigh,
+	NotificationResourceRecovered,
+}
+
+// NotificationPreference is how a user wants notifications of one type
+// delivered. Type "*" applies to every type without a preference of its own;
+// without either, notifications are shown in-app only.
+type NotificationPreference struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_notification_preference"`
+	Type      string    `json:"type" gorm:"size:50;not null;uniqueIndex:idx_notification_preference"`
+	InApp     bool      `json:"in_app" gorm:"default:true"`
+	Email     bool      `json:"email" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for NotificationPreference
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
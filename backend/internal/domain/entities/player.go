@@ -65,6 +65,14 @@ func (PlayerStats) TableName() string {
 	return "player_stats"
 }
 
+// Stat types shown on leaderboards
+const (
+	StatTypeKills       = "kills"
+	StatTypeDeaths      = "deaths"
+	StatTypePlayTime    = "playtime" // Seconds, tracked on Player.PlayTime
+	StatTypeBlocksMined = "blocks_mined"
+)
+
 // ChatLog represents a chat message log
 type ChatLog struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
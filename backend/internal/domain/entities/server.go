@@ -30,6 +30,25 @@ type Server struct {
 	Suspended       bool         `json:"suspended" gorm:"default:false"`
 	SuspendedReason string       `json:"suspended_reason" gorm:"size:500"`
 
+	// Set when the server was stopped by a node drain and should come back afterwards
+	RestartAfterMaintenance bool `json:"restart_after_maintenance" gorm:"default:false"`
+
+	// Set when startup changes reached the node while the server was running;
+	// they apply once the server is started again
+	RestartRequired bool `json:"restart_required" gorm:"default:false"`
+
+	// Crash recovery: restart after a crash up to CrashRecoveryMaxRestarts
+	// times within CrashRecoveryWindow, then leave it stopped and tell the owner
+	CrashRecoveryEnabled     bool `json:"crash_recovery_enabled" gorm:"default:false"`
+	CrashRecoveryMaxRestarts int  `json:"crash_recovery_max_restarts" gorm:"default:3"`
+	CrashRecoveryWindow      int  `json:"crash_recovery_window" gorm:"default:600"` // seconds
+
+	// Send console output as plain text, without colour and cursor escapes
+	ConsoleStripANSI bool `json:"console_strip_ansi" gorm:"default:false"`
+
+	// Tell the owner when usage stays high
+	ResourceAlerts ResourceAlerts `json:"resource_alerts" gorm:"type:jsonb;serializer:json;default:'{}'"`
+
 	// Ownership
 	OwnerID uuid.UUID `json:"owner_id" gorm:"type:uuid;not null;index"`
 	Owner   *User     `json:"owner,omitempty" gorm:"foreignKey:OwnerID"`
@@ -48,11 +67,17 @@ type Server struct {
 	DockerImage string    `json:"docker_image" gorm:"size:255"`
 	StartupCmd  string    `json:"startup_cmd" gorm:"type:text"`
 
+	// Egg version the startup command and image were last built from. Set
+	// EggOutdated when a later version of the egg would build them differently.
+	EggVersion  int  `json:"egg_version" gorm:"not null;default:1"`
+	EggOutdated bool `json:"egg_outdated" gorm:"default:false;index"`
+
 	// Resource Limits
 	MemoryLimit    int64 `json:"memory_limit" gorm:"default:1024"`     // MB
-	SwapLimit      int64 `json:"swap_limit" gorm:"default:0"`          // MB
+	SwapLimit      int64 `json:"swap_limit" gorm:"default:0"`          // MB beyond memory, 0 = none, -1 = unlimited
 	DiskLimit      int64 `json:"disk_limit" gorm:"default:10240"`      // MB
 	CPULimit       int   `json:"cpu_limit" gorm:"default:100"`         // Percentage (100 = 1 core)
+	CPUSet         string `json:"cpu_set" gorm:"size:255"`             // Pinned cores, e.g. "0-3,8"; empty for quota only
 	IOWeight       int   `json:"io_weight" gorm:"default:500"`         // 10-1000
 	NetworkIn      int64 `json:"network_in" gorm:"default:0"`          // Bytes/s, 0 = unlimited
 	NetworkOut     int64 `json:"network_out" gorm:"default:0"`         // Bytes/s, 0 = unlimited
@@ -63,10 +88,26 @@ type Server struct {
 	// Environment Variables (stored as JSON)
 	Environment map[string]string `json:"environment" gorm:"type:jsonb;default:'{}'"`
 
+	// Lowercase labels for organizing and filtering servers
+	Tags []string `json:"tags" gorm:"type:jsonb;serializer:json;default:'[]';index:idx_servers_tags,type:gin"`
+
+	// Extra host paths mounted into the container, within its node's allowed mounts
+	Mounts []Mount `json:"mounts" gorm:"type:jsonb;serializer:json;default:'[]'"`
+
+	// Console commands users other than the owner and admins may send
+	CommandPolicy CommandPolicy `json:"command_policy" gorm:"type:jsonb;serializer:json;default:'{}'"`
+
+	// Network: "node" shares the node's network, "isolated" gives the server its
+	// own; empty uses the node default
+	NetworkMode string `json:"network_mode" gorm:"size:20"`
+
 	// Container Info
 	ContainerID   string `json:"container_id" gorm:"size:100"`
 	InternalID    string `json:"internal_id" gorm:"size:100"` // Docker container name
 
+	// Optimistic locking, incremented on every update
+	Version int `json:"version" gorm:"not null;default:1"`
+
 	// Timestamps
 	InstalledAt   *time.Time `json:"installed_at"`
 	LastStartedAt *time.Time `json:"last_started_at"`
@@ -90,6 +131,11 @@ func (s *Server) CanStart() bool {
 	return s.Status == ServerStatusStopped && !s.Suspended
 }
 
+// ManageableBy checks if a user may manage the server, as its owner or an admin
+func (s *Server) ManageableBy(userID uuid.UUID, isAdmin bool) bool {
+	return isAdmin || s.OwnerID == userID
+}
+
 // Node represents a physical or virtual machine running the agent
 type Node struct {
 	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -109,6 +155,7 @@ type Node struct {
 	DiskTotal        int64 `json:"disk_total" gorm:"default:0"`        // MB
 	DiskAllocated    int64 `json:"disk_allocated" gorm:"default:0"`    // MB
 	DiskOveralloc    int   `json:"disk_overalloc" gorm:"default:0"`    // Percentage
+	SwapMax          int64 `json:"swap_max" gorm:"default:0"`          // MB of swap per server, 0 for none, -1 for unlimited
 	CPUTotal         int   `json:"cpu_total" gorm:"default:0"`         // Percentage (100 = 1 core)
 	CPUAllocated     int   `json:"cpu_allocated" gorm:"default:0"`     // Percentage
 
@@ -116,16 +163,58 @@ type Node struct {
 	IsOnline        bool       `json:"is_online" gorm:"default:false"`
 	LastCheckedAt   *time.Time `json:"last_checked_at"`
 	MaintenanceMode bool       `json:"maintenance_mode" gorm:"default:false"`
+	ImageWarmup     bool       `json:"image_warmup" gorm:"default:false"` // Pre-pull server images in the background
+	UploadSize      int        `json:"upload_size" gorm:"default:100"`    // MB per upload, capped by the panel's body limit
+
+	// Host paths, and everything below them, that servers on the node may
+	// mount. The node enforces its own copy from its configuration file.
+	AllowedMounts []string `json:"allowed_mounts" gorm:"type:jsonb;serializer:json;default:'[]'"`
+
+	// TLS certificate of https nodes, checked with the node's health
+	TLSInsecure   bool       `json:"tls_insecure" gorm:"default:false"` // Skip certificate verification, for development nodes only
+	CertStatus    CertStatus `json:"cert_status" gorm:"size:20"`        // Empty for http nodes
+	CertExpiresAt *time.Time `json:"cert_expires_at"`
+	CertError     string     `json:"cert_error,omitempty" gorm:"size:500"`
 	
 	// System Info (populated by agent)
 	SystemInfo map[string]interface{} `json:"system_info" gorm:"type:jsonb;default:'{}'"`
 
+	// Optimistic locking, incremented on every update
+	Version int `json:"version" gorm:"not null;default:1"`
+
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt *time.Time `json:"deleted_at" gorm:"index"`
 }
 
+// ResourceAlerts are a server's thresholds for sustained high usage. A
+// threshold of 0 turns off the alert for that resource.
+type ResourceAlerts struct {
+	CPU      int `json:"cpu"`      // Percentage of the server's CPU limit
+	Memory   int `json:"memory"`   // Percentage of the server's memory limit
+	Duration int `json:"duration"` // Seconds usage has to stay past a threshold, or back under it, before alerting or resolving
+}
+
+// Mount is a host path mounted into a server's container
+type Mount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// CertStatus is the state of a node's TLS certificate
+type CertStatus string
+
+const (
+	CertStatusValid     CertStatus = "valid"
+	CertStatusExpiring  CertStatus = "expiring"  // Valid, but expires within the warning period
+	CertStatusExpired   CertStatus = "expired"
+	CertStatusUntrusted CertStatus = "untrusted" // Self-signed or signed by an unknown authority
+	CertStatusInvalid   CertStatus = "invalid"   // Wrong hostname or otherwise failing verification
+	CertStatusUnknown   CertStatus = "unknown"   // The handshake failed before a certificate was seen
+)
+
 // TableName returns the table name for Node
 func (Node) TableName() string {
 	return "nodes"
@@ -143,6 +232,33 @@ func (n *Node) AvailableDisk() int64 {
 	return maxDisk - n.DiskAllocated
 }
 
+// EffectiveUploadSize returns the node's upload limit in MB, bounded by the
+// panel-wide body limit
+func (n *Node) EffectiveUploadSize(bodyLimit int) int {
+	if n.UploadSize <= 0 || (bodyLimit > 0 && n.UploadSize > bodyLimit) {
+		return bodyLimit
+	}
+	return n.UploadSize
+}
+
+// RegistryCredential holds credentials for a private Docker registry. Credentials
+// without a node apply to every node.
+type RegistryCredential struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	NodeID            *uuid.UUID `json:"node_id" gorm:"type:uuid;index"`
+	Node              *Node      `json:"node,omitempty" gorm:"foreignKey:NodeID"`
+	Host              string     `json:"host" gorm:"not null;size:255"` // e.g. ghcr.io, docker.io for Docker Hub
+	Username          string     `json:"username" gorm:"not null;size:255"`
+	PasswordEncrypted string     `json:"-" gorm:"type:text;not null"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for RegistryCredential
+func (RegistryCredential) TableName() string {
+	return "registry_credentials"
+}
+
 // Location represents a physical location/datacenter
 type Location struct {
 	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -174,10 +290,18 @@ type Allocation struct {
 	ServerID  *uuid.UUID `json:"server_id" gorm:"type:uuid;index"`
 	Server    *Server    `json:"server,omitempty" gorm:"foreignKey:ServerID"`
 	IsPrimary bool       `json:"is_primary" gorm:"default:false"`
+	Protocol  string     `json:"protocol" gorm:"size:4;not null;default:'both'"` // tcp, udp or both, from the egg of its server
 	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// Protocols an allocation is bound for
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolBoth = "both"
+)
+
 // TableName returns the table name for Allocation
 func (Allocation) TableName() string {
 	return "allocations"
@@ -214,7 +338,7 @@ type Egg struct {
 	Name            string    `json:"name" gorm:"not null;size:100"`
 	Description     string    `json:"description" gorm:"type:text"`
 	Author          string    `json:"author" gorm:"size:100"`
-	DockerImages    []string  `json:"docker_images" gorm:"type:jsonb"`
+	DockerImages    []string  `json:"docker_images" gorm:"type:jsonb;serializer:json"`
 	StartupCommand  string    `json:"startup_command" gorm:"type:text"`
 	ConfigFiles     string    `json:"config_files" gorm:"type:jsonb"`
 	ConfigStartup   string    `json:"config_startup" gorm:"type:jsonb"`
@@ -224,8 +348,23 @@ type Egg struct {
 	InstallContainer string   `json:"install_container" gorm:"size:255"`
 	InstallEntrypoint string  `json:"install_entrypoint" gorm:"size:255"`
 	Variables       []EggVariable `json:"variables,omitempty" gorm:"foreignKey:EggID"`
+	Ports           []EggPort `json:"ports,omitempty" gorm:"type:jsonb;serializer:json"`
+	Protocol        string    `json:"protocol" gorm:"size:4;not null;default:'both'"` // Protocol of the primary port: tcp, udp or both
+	Healthcheck     *EggHealthcheck `json:"healthcheck,omitempty" gorm:"type:jsonb;serializer:json"`
+	PidsLimit       int64     `json:"pids_limit" gorm:"default:0"`           // Processes and threads per server, 0 for the panel default
+	NoFile          int64     `json:"nofile" gorm:"column:nofile;default:0"` // Open files per process, 0 for the panel default
+	NProc           int64     `json:"nproc" gorm:"column:nproc;default:0"`   // Processes per user, 0 for the panel default
+	ReadOnlyRootfs  bool      `json:"read_only_rootfs" gorm:"default:false"` // Only the data volume and Tmpfs paths are writable
+	Tmpfs           []string  `json:"tmpfs" gorm:"type:jsonb;serializer:json"` // Writable in-memory paths of read-only servers, e.g. /tmp
+	Capabilities    []string  `json:"capabilities" gorm:"type:jsonb;serializer:json"` // Added back on top of the node's base set, all others are dropped
+	Entrypoint      []string  `json:"entrypoint" gorm:"type:jsonb;serializer:json"` // Replaces the image's entrypoint, empty keeps it
+	Shell           string    `json:"shell" gorm:"size:100"` // Shell the startup runs in, /bin/bash when empty
+	DirectExec      bool      `json:"direct_exec" gorm:"default:false"` // Run the startup's arguments without a shell
+	Limits          EggLimits `json:"limits" gorm:"type:jsonb;serializer:json;default:'{}'"` // Resource bounds for the egg's servers
+	QuickSettings   []EggQuickSetting `json:"quick_settings" gorm:"type:jsonb;serializer:json"` // Friendly fields for common variables
 	IsActive        bool      `json:"is_active" gorm:"default:true"`
 	SortOrder       int       `json:"sort_order" gorm:"default:0"`
+	Version         int       `json:"version" gorm:"not null;default:1"` // Incremented when the startup command or images change
 	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -235,6 +374,68 @@ func (Egg) TableName() string {
 	return "eggs"
 }
 
+// EggPort is an extra port servers of an egg need, such as a query or RCON
+// port. It is reserved at a fixed offset from the primary port and exposed to
+// the server under EnvVariable.
+type EggPort struct {
+	EnvVariable string `json:"env_variable"`
+	Offset      int    `json:"offset"`
+	Protocol    string `json:"protocol,omitempty"` // tcp, udp or both; empty for both
+}
+
+// Quick setting types
+const (
+	QuickSettingNumber = "number"
+	QuickSettingText   = "text"
+	QuickSettingToggle = "toggle"
+	QuickSettingSelect = "select"
+)
+
+// EggQuickSetting is a friendly field for a common egg variable, such as max
+// players or PvP, that is set without editing the raw variable. Values are
+// still checked against the variable's rules.
+type EggQuickSetting struct {
+	Key      string               `json:"key"` // e.g. max_players
+	Label    string               `json:"label"`
+	Type     string               `json:"type"`              // number, text, toggle or select
+	Variable string               `json:"variable"`          // Environment variable of the egg variable it sets
+	On       string               `json:"on,omitempty"`      // Variable value of an enabled toggle, "true" when empty
+	Off      string               `json:"off,omitempty"`     // Variable value of a disabled toggle, "false" when empty
+	Options  []QuickSettingOption `json:"options,omitempty"` // Choices of a select
+}
+
+// QuickSettingOption is a choice of a select quick setting
+type QuickSettingOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"` // Variable value
+}
+
+// EggLimits are the resources servers of an egg may be given. Servers
+// created without a resource get its recommended value.
+type EggLimits struct {
+	Memory ResourceBounds `json:"memory"` // MB
+	Disk   ResourceBounds `json:"disk"`   // MB
+	CPU    ResourceBounds `json:"cpu"`    // Percentage (100 = 1 core)
+}
+
+// ResourceBounds limits a single resource. Zero leaves a bound unset.
+type ResourceBounds struct {
+	Min         int `json:"min"`
+	Recommended int `json:"recommended"`
+	Max         int `json:"max"`
+}
+
+// EggHealthcheck is a command run inside a server's container to tell whether
+// the game is actually serving. Servers report starting, healthy or unhealthy
+// while it is set.
+type EggHealthcheck struct {
+	Test        string `json:"test"`         // Shell command, exit code 0 is healthy
+	Interval    int    `json:"interval"`     // seconds
+	Timeout     int    `json:"timeout"`      // seconds
+	Retries     int    `json:"retries"`      // Consecutive failures before unhealthy
+	StartPeriod int    `json:"start_period"` // seconds of failures ignored after start
+}
+
 // EggVariable represents a configurable variable for an egg
 type EggVariable struct {
 	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -245,7 +446,9 @@ type EggVariable struct {
 	DefaultValue string    `json:"default_value" gorm:"size:500"`
 	UserViewable bool      `json:"user_viewable" gorm:"default:true"`
 	UserEditable bool      `json:"user_editable" gorm:"default:true"`
-	Rules        string    `json:"rules" gorm:"size:500"` // Validation rules
+	Rules        string    `json:"rules" gorm:"size:500"`         // Validation rules
+	DependsOn    string    `json:"depends_on" gorm:"size:100"`    // Env variable this one applies with, empty to always apply
+	DependsValue string    `json:"depends_value" gorm:"size:500"` // Comma separated values of DependsOn it applies with
 	SortOrder    int       `json:"sort_order" gorm:"default:0"`
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
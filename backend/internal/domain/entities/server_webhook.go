@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Server state transitions a server webhook can subscribe to
+const (
+	ServerEventStarted         = "server.started"
+	ServerEventStopped         = "server.stopped"
+	ServerEventBackupCompleted = "backup.completed"
+)
+
+// ServerWebhookEvents lists all transitions a server webhook can subscribe to
+var ServerWebhookEvents = []string{
+	ServerEventStarted,
+	ServerEventStopped,
+	EventServerCrashed,
+	ServerEventBackupCompleted,
+	EventBackupFailed,
+}
+
+// ServerWebhook is a webhook a server's owner registered to trigger external
+// automation on the state transitions of that server. Unlike admin webhooks it
+// always posts the generic JSON payload.
+type ServerWebhook struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ServerID  uuid.UUID `json:"server_id" gorm:"type:uuid;not null;index"`
+	URL       string    `json:"url" gorm:"not null;size:500"`
+	Secret    string    `json:"-" gorm:"size:100"` // HMAC-SHA256 signing secret
+	Events    []string  `json:"events" gorm:"type:jsonb;serializer:json"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for ServerWebhook
+func (ServerWebhook) TableName() string {
+	return "server_webhooks"
+}
+
+// Subscribes checks if the webhook is subscribed to a transition
+func (w *ServerWebhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerWebhookDelivery records one delivery of a transition to a server
+// webhook, retries included
+type ServerWebhookDelivery struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WebhookID  uuid.UUID `json:"webhook_id" gorm:"type:uuid;not null;index"`
+	ServerID   uuid.UUID `json:"server_id" gorm:"type:uuid;not null;index"`
+	Event      string    `json:"event" gorm:"size:50;not null"`
+	Success    bool      `json:"success"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code"` // Of the last attempt, 0 if no response was received
+	Error      string    `json:"error" gorm:"size:500"`
+	Duration   int64     `json:"duration"` // Milliseconds, retries included
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName returns the table name for ServerWebhookDelivery
+func (ServerWebhookDelivery) TableName() string {
+	return "server_webhook_deliveries"
+}
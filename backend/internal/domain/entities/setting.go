@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SettingType is the type of a setting's value
+type SettingType string
+
+const (
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeInt    SettingType = "int"
+	SettingTypeString SettingType = "string"
+)
+
+// Setting is a panel setting changed at runtime by admins. Values are stored
+// as text and parsed according to Type.
+type Setting struct {
+	Key       string      `json:"key" gorm:"primary_key;size:100"`
+	Type      SettingType `json:"type" gorm:"type:varchar(10);not null"`
+	Value     string      `json:"value" gorm:"type:text;not null"`
+	UpdatedBy *uuid.UUID  `json:"updated_by" gorm:"type:uuid"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for Setting
+func (Setting) TableName() string {
+	return "settings"
+}
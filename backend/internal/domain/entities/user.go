@@ -38,10 +38,15 @@ type User struct {
 	LockedUntil       *time.Time `json:"locked_until"`
 	PasswordChangedAt *time.Time `json:"password_changed_at"`
 	Credits           float64    `json:"credits" gorm:"type:decimal(12,2);default:0"`
+	Country           string     `json:"country" gorm:"size:2"`     // ISO 3166-1 alpha-2, used for tax
+	Region            string     `json:"region" gorm:"size:50"`     // State or province, for regional tax rates
+	VATNumber         string     `json:"vat_number" gorm:"size:50"` // Business VAT number, enables reverse charge
+	TaxExempt         bool       `json:"tax_exempt" gorm:"default:false"`
 	RoleID            uuid.UUID  `json:"role_id" gorm:"type:uuid"`
 	Role              *Role      `json:"role,omitempty" gorm:"foreignKey:RoleID"`
 	ResellerID        *uuid.UUID `json:"reseller_id" gorm:"type:uuid"`
 	Reseller          *User      `json:"reseller,omitempty" gorm:"foreignKey:ResellerID"`
+	Version           int        `json:"version" gorm:"not null;default:1"` // Optimistic locking
 	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt         *time.Time `json:"deleted_at" gorm:"index"`
@@ -73,6 +78,43 @@ func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+// UserQuota represents resource limits a reseller sets for one of their sub-users.
+// A zero limit means unlimited.
+type UserQuota struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;uniqueIndex;not null"`
+	User        *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	MaxServers  int        `json:"max_servers" gorm:"default:0"`
+	MemoryLimit int64      `json:"memory_limit" gorm:"default:0"` // MB across all servers
+	DiskLimit   int64      `json:"disk_limit" gorm:"default:0"`   // MB across all servers
+	CPULimit    int        `json:"cpu_limit" gorm:"default:0"`    // Percentage across all servers
+	SetBy       *uuid.UUID `json:"set_by" gorm:"type:uuid"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for UserQuota
+func (UserQuota) TableName() string {
+	return "user_quotas"
+}
+
+// Allows checks whether adding the requested resources to the current usage stays within the quota
+func (q *UserQuota) Allows(servers int, memory, disk int64, cpu int) bool {
+	if q.MaxServers > 0 && servers > q.MaxServers {
+		return false
+	}
+	if q.MemoryLimit > 0 && memory > q.MemoryLimit {
+		return false
+	}
+	if q.DiskLimit > 0 && disk > q.DiskLimit {
+		return false
+	}
+	if q.CPULimit > 0 && cpu > q.CPULimit {
+		return false
+	}
+	return true
+}
+
 // Role represents a user role with permissions
 type Role struct {
 	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -83,6 +125,7 @@ type Role struct {
 	IsSystem    bool         `json:"is_system" gorm:"default:false"`
 	IsDefault   bool         `json:"is_default" gorm:"default:false"`
 	Priority    int          `json:"priority" gorm:"default:0"`
+	ServerLimit int          `json:"server_limit" gorm:"default:0"` // Servers a member may own without a subscription, 0 for unlimited
 	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions;"`
 	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
@@ -93,6 +136,20 @@ func (Role) TableName() string {
 	return "roles"
 }
 
+// Grants reports whether the role carries a permission. Admins hold every
+// permission.
+func (r *Role) Grants(permission string) bool {
+	if r.Name == "admin" {
+		return true
+	}
+	for _, p := range r.Permissions {
+		if p.Name == permission || p.Name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // Permission represents a system permission
 type Permission struct {
 	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -133,6 +190,21 @@ func (s *Session) IsValid() bool {
 	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
 }
 
+// UsedRefreshToken is a refresh token that was rotated out of a session.
+// Refresh tokens are single use, so presenting one again outside the retry
+// grace window means it was copied and the session is revoked.
+type UsedRefreshToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SessionID uuid.UUID `json:"session_id" gorm:"type:uuid;not null;index"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null;size:64"` // SHA-256 hex
+	UsedAt    time.Time `json:"used_at"`
+}
+
+// TableName returns the table name for UsedRefreshToken
+func (UsedRefreshToken) TableName() string {
+	return "used_refresh_tokens"
+}
+
 // APIKey represents an API key for programmatic access
 type APIKey struct {
 	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -0,0 +1,77 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookProvider represents the chat platform a webhook delivers to
+type WebhookProvider string
+
+const (
+	WebhookProviderDiscord WebhookProvider = "discord"
+	WebhookProviderSlack   WebhookProvider = "slack"
+	WebhookProviderGeneric WebhookProvider = "generic"
+)
+
+// System event types that can be delivered to webhooks
+const (
+	EventNodeOffline   = "node.offline"
+	EventServerCrashed = "server.crashed"
+	EventBackupFailed  = "backup.failed"
+	EventResourceHigh  = "resource.high"
+	EventResourceOK    = "resource.recovered"
+	EventServerOOM     = "server.oom"
+	EventStatusDrift   = "server.status_drift"
+	EventUnhealthy     = "server.unhealthy"
+	EventNodeCert      = "node.certificate"
+	EventBackupCorrupt = "backup.corrupted"
+)
+
+// WebhookEventTypes lists all event types a webhook can subscribe to
+var WebhookEventTypes = []string{
+	EventNodeOffline,
+	EventServerCrashed,
+	EventBackupFailed,
+	EventResourceHigh,
+	EventResourceOK,
+	EventServerOOM,
+	EventStatusDrift,
+	EventUnhealthy,
+	EventNodeCert,
+	EventBackupCorrupt,
+}
+
+// Webhook represents an outbound webhook registered by an administrator
+type Webhook struct {
+	ID              uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name            string          `json:"name" gorm:"not null;size:100"`
+	URL             string          `json:"url" gorm:"not null;size:500"`
+	Provider        WebhookProvider `json:"provider" gorm:"type:varchar(20);default:'generic'"`
+	Secret          string          `json:"-" gorm:"size:100"` // HMAC-SHA256 signing secret
+	Events          []string        `json:"events" gorm:"type:jsonb;serializer:json"`
+	Template        string          `json:"template" gorm:"type:text"` // Optional message template override
+	IsActive        bool            `json:"is_active" gorm:"default:true"`
+	FailureCount    int             `json:"failure_count" gorm:"default:0"`
+	LastError       string          `json:"last_error" gorm:"size:500"`
+	LastDeliveredAt *time.Time      `json:"last_delivered_at"`
+	CreatedBy       *uuid.UUID      `json:"created_by" gorm:"type:uuid"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName returns the table name for Webhook
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes checks if the webhook is subscribed to an event type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -17,7 +17,7 @@ type AuditLogRepository interface {
 	GetByResource(ctx context.Context, resource string, resourceID uuid.UUID) ([]*entities.AuditLog, error)
 	GetByAction(ctx context.Context, action entities.AuditAction, params ListParams) ([]*entities.AuditLog, int64, error)
 	GetByDateRange(ctx context.Context, start, end time.Time, params ListParams) ([]*entities.AuditLog, int64, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // ActivityLogRepository defines the interface for activity log data access
@@ -26,7 +26,7 @@ type ActivityLogRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID, params ListParams) ([]*entities.ActivityLog, int64, error)
 	GetByServerID(ctx context.Context, serverID uuid.UUID, params ListParams) ([]*entities.ActivityLog, int64, error)
 	GetRecent(ctx context.Context, limit int) ([]*entities.ActivityLog, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // SystemEventRepository defines the interface for system event data access
@@ -38,7 +38,7 @@ type SystemEventRepository interface {
 	GetByServerID(ctx context.Context, serverID uuid.UUID, params ListParams) ([]*entities.SystemEvent, int64, error)
 	GetBySeverity(ctx context.Context, severity string, params ListParams) ([]*entities.SystemEvent, int64, error)
 	GetRecent(ctx context.Context, limit int) ([]*entities.SystemEvent, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // NotificationRepository defines the interface for notification data access
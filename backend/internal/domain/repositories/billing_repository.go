@@ -2,12 +2,16 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/google/uuid"
 )
 
+// ErrInsufficientCredits is returned when a user's balance cannot cover a debit
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
 // TransactionRepository defines the interface for transaction data access
 type TransactionRepository interface {
 	Create(ctx context.Context, tx *entities.Transaction) error
@@ -17,6 +21,7 @@ type TransactionRepository interface {
 	GetByReference(ctx context.Context, reference string) (*entities.Transaction, error)
 	GetByDateRange(ctx context.Context, start, end time.Time) ([]*entities.Transaction, error)
 	SumByUserID(ctx context.Context, userID uuid.UUID, txType entities.TransactionType) (float64, error)
+	CountOutgoingTransfers(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error)
 }
 
 // PackageRepository defines the interface for package data access
@@ -39,11 +44,16 @@ type SubscriptionRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Subscription, error)
 	GetByServerID(ctx context.Context, serverID uuid.UUID) (*entities.Subscription, error)
 	GetActive(ctx context.Context) ([]*entities.Subscription, error)
+	// GetExpiring returns active subscriptions ending before the given time
 	GetExpiring(ctx context.Context, before time.Time) ([]*entities.Subscription, error)
+	// GetExpired returns active and cancelled subscriptions whose end date has passed
 	GetExpired(ctx context.Context) ([]*entities.Subscription, error)
 	Cancel(ctx context.Context, id uuid.UUID) error
 	Suspend(ctx context.Context, id uuid.UUID) error
+	// Renew reactivates a subscription and moves its end and next billing date to newEndDate
 	Renew(ctx context.Context, id uuid.UUID, newEndDate time.Time) error
+	// MarkPaymentFailed records when a renewal first failed for lack of credits
+	MarkPaymentFailed(ctx context.Context, id uuid.UUID, at time.Time) error
 }
 
 // InvoiceRepository defines the interface for invoice data access
@@ -51,7 +51,7 @@ type ChatLogRepository interface {
 	GetByServerID(ctx context.Context, serverID uuid.UUID, params ListParams) ([]*entities.ChatLog, int64, error)
 	GetByPlayerID(ctx context.Context, playerID uuid.UUID, params ListParams) ([]*entities.ChatLog, int64, error)
 	Search(ctx context.Context, serverID uuid.UUID, query string, start, end time.Time) ([]*entities.ChatLog, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // CommandLogRepository defines the interface for command log data access
@@ -60,7 +60,7 @@ type CommandLogRepository interface {
 	GetByServerID(ctx context.Context, serverID uuid.UUID, params ListParams) ([]*entities.CommandLog, int64, error)
 	GetByPlayerID(ctx context.Context, playerID uuid.UUID, params ListParams) ([]*entities.CommandLog, int64, error)
 	Search(ctx context.Context, serverID uuid.UUID, query string) ([]*entities.CommandLog, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // DeathLogRepository defines the interface for death log data access
@@ -70,5 +70,5 @@ type DeathLogRepository interface {
 	GetByPlayerID(ctx context.Context, playerID uuid.UUID, params ListParams) ([]*entities.DeathLog, int64, error)
 	GetByKillerID(ctx context.Context, killerID uuid.UUID, params ListParams) ([]*entities.DeathLog, int64, error)
 	CountByPlayerID(ctx context.Context, playerID uuid.UUID) (int64, error)
-	DeleteOlderThan(ctx context.Context, before time.Time) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
@@ -2,20 +2,31 @@ package repositories
 
 import (
 	"context"
+	"errors"
 
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/google/uuid"
 )
 
+// ErrVersionConflict is returned by Update when the record was modified since it
+// was read. Update implementations only write a row whose version still matches
+// the entity's Version and increment it on success.
+var ErrVersionConflict = errors.New("record was modified by another request")
+
 // ServerRepository defines the interface for server data access
 type ServerRepository interface {
 	Create(ctx context.Context, server *entities.Server) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.Server, error)
 	GetByUUID(ctx context.Context, uuid string) (*entities.Server, error)
 	Update(ctx context.Context, server *entities.Server) error
+	// Delete soft deletes a server. Lookups and lists skip it from then on,
+	// except List with WithTrashed set.
 	Delete(ctx context.Context, id uuid.UUID) error
+	// Purge permanently removes a server, soft deleted or not
+	Purge(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, params ListParams) ([]*entities.Server, int64, error)
 	GetByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*entities.Server, error)
+	GetByOwnerIDs(ctx context.Context, ownerIDs []uuid.UUID, params ListParams) ([]*entities.Server, int64, error)
 	GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Server, error)
 	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.ServerStatus) error
 	UpdateContainerID(ctx context.Context, id uuid.UUID, containerID string) error
@@ -34,6 +45,8 @@ type NodeRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, params ListParams) ([]*entities.Node, int64, error)
 	GetByLocationID(ctx context.Context, locationID uuid.UUID) ([]*entities.Node, error)
+	// GetAvailable returns online nodes outside maintenance whose free memory and
+	// disk, including overallocation, cover the requested amounts
 	GetAvailable(ctx context.Context, memoryRequired, diskRequired int64) ([]*entities.Node, error)
 	UpdateOnlineStatus(ctx context.Context, id uuid.UUID, isOnline bool) error
 	UpdateResources(ctx context.Context, id uuid.UUID, memoryAlloc, diskAlloc int64, cpuAlloc int) error
@@ -63,6 +76,9 @@ type AllocationRepository interface {
 	AssignToServer(ctx context.Context, id uuid.UUID, serverID uuid.UUID, isPrimary bool) error
 	Unassign(ctx context.Context, id uuid.UUID) error
 	IsPortAvailable(ctx context.Context, nodeID uuid.UUID, ip string, port int) (bool, error)
+	// GetOnOtherNodes returns the allocations of ip with a port from portStart
+	// to portEnd on nodes other than nodeID
+	GetOnOtherNodes(ctx context.Context, nodeID uuid.UUID, ip string, portStart, portEnd int) ([]*entities.Allocation, error)
 }
 
 // GameRepository defines the interface for game data access
@@ -105,3 +121,25 @@ type ServerVariableRepository interface {
 	GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerVariable, error)
 	Upsert(ctx context.Context, serverID, eggVariableID uuid.UUID, value string) error
 }
+
+// DatabaseHostRepository defines the interface for database host data access
+type DatabaseHostRepository interface {
+	Create(ctx context.Context, host *entities.DatabaseHost) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.DatabaseHost, error)
+	Update(ctx context.Context, host *entities.DatabaseHost) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context) ([]*entities.DatabaseHost, error)
+	// GetForNode returns the hosts usable by servers on a node, the node's own
+	// hosts first, skipping hosts that reached their database limit
+	GetForNode(ctx context.Context, nodeID uuid.UUID) ([]*entities.DatabaseHost, error)
+}
+
+// ServerDatabaseRepository defines the interface for server database data access
+type ServerDatabaseRepository interface {
+	Create(ctx context.Context, database *entities.ServerDatabase) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.ServerDatabase, error)
+	Update(ctx context.Context, database *entities.ServerDatabase) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerDatabase, error)
+	CountByServerID(ctx context.Context, serverID uuid.UUID) (int64, error)
+}
@@ -0,0 +1,23 @@
+package repositories
+
+import "context"
+
+// TxRepositories provides repositories bound to a single database transaction
+type TxRepositories interface {
+	Users() UserRepository
+	UserQuotas() UserQuotaRepository
+	Servers() ServerRepository
+	Nodes() NodeRepository
+	Allocations() AllocationRepository
+	ServerVariables() ServerVariableRepository
+	Transactions() TransactionRepository
+	Subscriptions() SubscriptionRepository
+	Invoices() InvoiceRepository
+}
+
+// UnitOfWork runs a set of writes atomically
+type UnitOfWork interface {
+	// Do runs fn inside a transaction. The transaction is committed if fn
+	// returns nil and rolled back if it returns an error or panics.
+	Do(ctx context.Context, fn func(ctx context.Context, repos TxRepositories) error) error
+}
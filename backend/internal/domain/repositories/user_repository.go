@@ -27,13 +27,17 @@ type UserRepository interface {
 	// Delete soft deletes a user
 	Delete(ctx context.Context, id uuid.UUID) error
 	
-	// List retrieves users with pagination and filters
+	// Purge permanently removes a user, soft deleted or not
+	Purge(ctx context.Context, id uuid.UUID) error
+	
+	// List retrieves users with pagination and filters, including soft
+	// deleted users only when params.WithTrashed is set
 	List(ctx context.Context, params ListParams) ([]*entities.User, int64, error)
 	
 	// GetByResellerID retrieves users by reseller ID
 	GetByResellerID(ctx context.Context, resellerID uuid.UUID) ([]*entities.User, error)
 	
-	// UpdateCredits updates user credits
+	// UpdateCredits atomically adds amount, which may be negative, to a user's credits
 	UpdateCredits(ctx context.Context, id uuid.UUID, amount float64) error
 	
 	// IncrementFailedLogin increments failed login count
@@ -46,6 +50,13 @@ type UserRepository interface {
 	UpdateLastLogin(ctx context.Context, id uuid.UUID, ip string) error
 }
 
+// UserQuotaRepository defines the interface for user quota data access
+type UserQuotaRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*entities.UserQuota, error)
+	Upsert(ctx context.Context, quota *entities.UserQuota) error
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
 // RoleRepository defines the interface for role data access
 type RoleRepository interface {
 	Create(ctx context.Context, role *entities.Role) error
@@ -73,6 +84,7 @@ type PermissionRepository interface {
 // SessionRepository defines the interface for session data access
 type SessionRepository interface {
 	Create(ctx context.Context, session *entities.Session) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Session, error)
 	GetByToken(ctx context.Context, token string) (*entities.Session, error)
 	GetByRefreshToken(ctx context.Context, refreshToken string) (*entities.Session, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Session, error)
@@ -80,6 +92,10 @@ type SessionRepository interface {
 	Revoke(ctx context.Context, id uuid.UUID) error
 	RevokeAllByUserID(ctx context.Context, userID uuid.UUID) error
 	DeleteExpired(ctx context.Context) error
+	// MarkRefreshTokenUsed records a rotated refresh token. It fails if the
+	// token hash was already recorded, so only one refresh can use a token.
+	MarkRefreshTokenUsed(ctx context.Context, used *entities.UsedRefreshToken) error
+	GetUsedRefreshToken(ctx context.Context, tokenHash string) (*entities.UsedRefreshToken, error)
 }
 
 // APIKeyRepository defines the interface for API key data access
@@ -101,15 +117,65 @@ type ListParams struct {
 	SortDir  string // asc, desc
 	Search   string
 	Filters  map[string]interface{}
+
+	// WithTrashed includes soft deleted rows, for admin recovery views
+	WithTrashed bool
 }
 
 // DefaultListParams returns default list parameters
 func DefaultListParams() ListParams {
 	return ListParams{
 		Page:     1,
-		PageSize: 20,
+		PageSize: DefaultPageSize,
 		SortBy:   "created_at",
 		SortDir:  "desc",
 		Filters:  make(map[string]interface{}),
 	}
 }
+
+// Page size bounds applied by Clamp
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Clamp brings Page and PageSize within bounds. A page below 1 becomes 1, a
+// page size below 1 becomes DefaultPageSize and one above maxPageSize is cut
+// down to it; maxPageSize <= 0 means MaxPageSize.
+func (p ListParams) Clamp(maxPageSize int) ListParams {
+	if maxPageSize <= 0 {
+		maxPageSize = MaxPageSize
+	}
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.PageSize < 1 {
+		p.PageSize = DefaultPageSize
+	}
+	if p.PageSize > maxPageSize {
+		p.PageSize = maxPageSize
+	}
+	return p
+}
+
+// Offset returns the number of rows before the page
+func (p ListParams) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// PageMeta describes one page of a list response
+type PageMeta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+}
+
+// NewPageMeta returns the meta of a page of params given the total row count
+func NewPageMeta(params ListParams, total int64) PageMeta {
+	meta := PageMeta{Page: params.Page, PageSize: params.PageSize, Total: total}
+	if params.PageSize > 0 {
+		meta.TotalPages = (total + int64(params.PageSize) - 1) / int64(params.PageSize)
+	}
+	return meta
+}
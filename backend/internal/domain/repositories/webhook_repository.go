@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// WebhookRepository defines the interface for webhook data access
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entities.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error)
+	Update(ctx context.Context, webhook *entities.Webhook) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, params ListParams) ([]*entities.Webhook, int64, error)
+	GetActiveByEvent(ctx context.Context, eventType string) ([]*entities.Webhook, error)
+	RecordDelivery(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error
+	RecordFailure(ctx context.Context, id uuid.UUID, errMsg string) error
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// BackupScanner catches silently corrupted backups before a restore does. It
+// has nodes re-hash a sample of completed backups, least recently verified
+// first, and flags the ones that no longer match their checksum.
+type BackupScanner struct {
+	client *Client
+	db     *gorm.DB
+	config config.AgentConfig
+	logger *zap.Logger
+}
+
+// NewBackupScanner creates a new BackupScanner
+func NewBackupScanner(client *Client, db *gorm.DB, cfg config.AgentConfig, log *zap.Logger) *BackupScanner {
+	return &BackupScanner{
+		client: client,
+		db:     db,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Start scans a sample of backups every interval until the context is cancelled
+func (s *BackupScanner) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.BackupScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scan(ctx)
+		}
+	}
+}
+
+func (s *BackupScanner) scan(ctx context.Context) {
+	var backups []entities.Backup
+	if err := s.db.WithContext(ctx).
+		Preload("Server").
+		Joins("JOIN servers ON servers.id = backups.server_id").
+		Joins("JOIN nodes ON nodes.id = servers.node_id").
+		Where("backups.status = ? AND backups.checksum <> '' AND backups.deleted_at IS NULL", entities.BackupStatusCompleted).
+		Where("nodes.is_online = ? AND nodes.deleted_at IS NULL", true).
+		Order("backups.verified_at ASC NULLS FIRST").
+		Limit(s.config.BackupScanSample).
+		Find(&backups).Error; err != nil {
+		s.logger.Warn("Failed to load backups for integrity scan", zap.Error(err))
+		return
+	}
+
+	corrupted := 0
+	for i := range backups {
+		err := s.VerifyBackup(ctx, &backups[i])
+		switch {
+		case errors.Is(err, services.ErrBackupCorrupted):
+			corrupted++
+		case err != nil:
+			s.logger.Debug("Failed to verify backup", zap.String("backup_id", backups[i].ID.String()), zap.Error(err))
+		}
+	}
+	if corrupted > 0 {
+		s.logger.Warn("Integrity scan found corrupted backups", zap.Int("backups", corrupted), zap.Int("scanned", len(backups)))
+	}
+}
+
+// VerifyBackup has the node of a backup re-hash its archive. An intact backup
+// records when it was verified; a corrupted one is flagged and
+// services.ErrBackupCorrupted returned. The backup's Server must be loaded.
+func (s *BackupScanner) VerifyBackup(ctx context.Context, backup *entities.Backup) error {
+	err := s.client.VerifyBackup(ctx, backup.Server.NodeID, backup.ID, backup.Checksum)
+	if errors.Is(err, services.ErrBackupCorrupted) {
+		if markErr := s.MarkCorrupted(ctx, backup, backup.Server); markErr != nil {
+			return markErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	backup.VerifiedAt = &now
+	return s.db.WithContext(ctx).Model(&entities.Backup{}).
+		Where("id = ?", backup.ID).
+		UpdateColumn("verified_at", now).Error
+}
+
+// MarkCorrupted flags a backup whose archive failed verification, so it is
+// no longer offered for restore or download, and raises a system event for
+// admins. A backup already flagged is left alone.
+func (s *BackupScanner) MarkCorrupted(ctx context.Context, backup *entities.Backup, server *entities.Server) error {
+	const reason = "Archive no longer matches its checksum"
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entities.Backup{}).
+			Where("id = ? AND status = ?", backup.ID, entities.BackupStatusCompleted).
+			Updates(map[string]interface{}{
+				"status":    entities.BackupStatusCorrupted,
+				"error_msg": reason,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		backup.Status = entities.BackupStatusCorrupted
+		backup.ErrorMsg = reason
+
+		s.logger.Warn("Backup failed checksum verification",
+			zap.String("backup_id", backup.ID.String()),
+			zap.String("server_id", server.ID.String()),
+		)
+		return tx.Create(&entities.SystemEvent{
+			NodeID:    &server.NodeID,
+			ServerID:  &server.ID,
+			EventType: entities.EventBackupCorrupt,
+			Severity:  "error",
+			Message:   reason,
+			Data: map[string]interface{}{
+				"backup_id":   backup.ID,
+				"backup_name": backup.Name,
+				"server_id":   server.ID,
+				"server_name": server.Name,
+				"checksum":    backup.Checksum,
+			},
+		}).Error
+	})
+}
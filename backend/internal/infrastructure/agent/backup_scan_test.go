@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestBackupScanFlagsCorruptedArchives(t *testing.T) {
+	db := dbtest.Open(t, &entities.Node{}, &entities.Server{}, &entities.Backup{}, &entities.SystemEvent{})
+
+	// The node finds one archive no longer matching its checksum
+	corruptID := uuid.New()
+	var mu sync.Mutex
+	var verified []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/backups/"), "/verify")
+		mu.Lock()
+		verified = append(verified, id)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if id == corruptID.String() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "backup checksum mismatch"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort, IsOnline: true}
+	server := &entities.Server{ID: uuid.New(), UUID: "game", Name: "game", NodeID: node.ID, OwnerID: uuid.New()}
+	backup := func(id uuid.UUID, name string, status entities.BackupStatus) *entities.Backup {
+		return &entities.Backup{ID: id, ServerID: server.ID, Name: name, Status: status, Checksum: strings.Repeat("a", 64)}
+	}
+	intact := backup(uuid.New(), "intact", entities.BackupStatusCompleted)
+	corrupt := backup(corruptID, "corrupt", entities.BackupStatusCompleted)
+	// Only completed backups have an archive worth checking
+	failed := backup(uuid.New(), "failed", entities.BackupStatusFailed)
+	for _, row := range []interface{}{node, server, intact, corrupt, failed} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.AgentConfig{RequestTimeout: 5 * time.Second, BackupScanSample: 10}
+	scanner := NewBackupScanner(NewClient(cfg, db), db, cfg, zap.NewNop())
+	scanner.scan(context.Background())
+	// Flagged backups are not offered again, so the second pass raises nothing
+	scanner.scan(context.Background())
+
+	type state struct {
+		Status     entities.BackupStatus
+		ErrorMsg   string
+		VerifiedAt *time.Time
+	}
+	stored := func(b *entities.Backup) state {
+		t.Helper()
+		var s state
+		if err := db.Model(&entities.Backup{}).Select("status", "error_msg", "verified_at").Where("id = ?", b.ID).Scan(&s).Error; err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if s := stored(intact); s.Status != entities.BackupStatusCompleted || s.VerifiedAt == nil {
+		t.Errorf("intact backup %+v, want completed and verified", s)
+	}
+	if s := stored(corrupt); s.Status != entities.BackupStatusCorrupted || s.ErrorMsg == "" || s.VerifiedAt != nil {
+		t.Errorf("corrupt backup %+v, want flagged corrupted", s)
+	}
+	if s := stored(failed); s.Status != entities.BackupStatusFailed || s.VerifiedAt != nil {
+		t.Errorf("failed backup %+v, want it left alone", s)
+	}
+	for _, id := range verified {
+		if id == failed.ID.String() {
+			t.Error("the failed backup was sent for verification")
+		}
+	}
+
+	var events []struct {
+		ServerID  string
+		EventType string
+		Severity  string
+	}
+	if err := db.Model(&entities.SystemEvent{}).Select("server_id", "event_type", "severity").Scan(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ServerID != server.ID.String() || events[0].EventType != entities.EventBackupCorrupt || events[0].Severity != "error" {
+		t.Errorf("events %+v, want one corrupted backup error", events)
+	}
+}
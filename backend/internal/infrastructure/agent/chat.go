@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// chatCursorKey returns the Redis key holding the last chat sequence number
+// stored for a server
+func chatCursorKey(serverID string) string {
+	return "chat:cursor:" + serverID
+}
+
+// ChatCollector polls agents for the chat of running servers and stores it as
+// chat logs. Old chat logs are purged by database.RetentionPurger.
+type ChatCollector struct {
+	client *Client
+	db     *gorm.DB
+	rdb    *redis.Client
+	config config.ChatConfig
+	logger *zap.Logger
+}
+
+// NewChatCollector creates a new ChatCollector
+func NewChatCollector(client *Client, db *gorm.DB, rdb *redis.Client, cfg config.ChatConfig, log *zap.Logger) *ChatCollector {
+	return &ChatCollector{
+		client: client,
+		db:     db,
+		rdb:    rdb,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Start polls agents until the context is cancelled
+func (c *ChatCollector) Start(ctx context.Context) {
+	poll := time.NewTicker(c.config.PollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			c.collect(ctx)
+		}
+	}
+}
+
+// collect stores the new chat messages of every running server
+func (c *ChatCollector) collect(ctx context.Context) {
+	var servers []entities.Server
+	if err := c.db.WithContext(ctx).
+		Where("status = ?", entities.ServerStatusRunning).
+		Find(&servers).Error; err != nil {
+		c.logger.Warn("Failed to load servers for chat collection", zap.Error(err))
+		return
+	}
+
+	for _, server := range servers {
+		serverID := server.ID.String()
+
+		var cursor int64
+		if value, err := c.rdb.Get(ctx, chatCursorKey(serverID)); err == nil {
+			cursor, _ = strconv.ParseInt(value, 10, 64)
+		}
+
+		messages, err := c.client.GetChat(ctx, server.NodeID, server.ID, cursor)
+		if err != nil {
+			c.logger.Debug("Failed to fetch server chat", zap.String("server_id", serverID), zap.Error(err))
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		logs := make([]entities.ChatLog, 0, len(messages))
+		for _, msg := range messages {
+			logs = append(logs, entities.ChatLog{
+				ServerID:  server.ID,
+				PlayerID:  c.playerID(ctx, server.ID, msg.Username),
+				Username:  msg.Username,
+				Message:   msg.Message,
+				Channel:   "global",
+				CreatedAt: msg.SentAt,
+			})
+		}
+		if err := c.db.WithContext(ctx).Create(&logs).Error; err != nil {
+			c.logger.Warn("Failed to store chat logs", zap.String("server_id", serverID), zap.Error(err))
+			continue
+		}
+
+		last := messages[len(messages)-1].Seq
+		if err := c.rdb.Set(ctx, chatCursorKey(serverID), strconv.FormatInt(last, 10), 0); err != nil {
+			c.logger.Warn("Failed to save chat cursor", zap.String("server_id", serverID), zap.Error(err))
+		}
+	}
+}
+
+// playerID returns the known player of a server with the given name, if any
+func (c *ChatCollector) playerID(ctx context.Context, serverID uuid.UUID, username string) *uuid.UUID {
+	var player entities.Player
+	if err := c.db.WithContext(ctx).Select("id").
+		Where("server_id = ? AND username = ?", serverID, username).
+		First(&player).Error; err != nil {
+		return nil
+	}
+	return &player.ID
+}
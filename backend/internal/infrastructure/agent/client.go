@@ -0,0 +1,650 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Client communicates with node agents over their HTTP API
+type Client struct {
+	db             *gorm.DB
+	httpClient     *http.Client
+	insecureClient *http.Client // For nodes whose certificates are not verified
+	config         config.AgentConfig
+}
+
+// operation classes an agent call's timeout is chosen by
+type operation int
+
+const (
+	opQuery operation = iota
+	opPower
+	opUpdate
+	opInstall
+	opBackup
+	opPull
+)
+
+// timeout returns the deadline of an operation class, falling back to the
+// request timeout when the class has none
+func (c *Client) timeout(op operation) time.Duration {
+	var d time.Duration
+	switch op {
+	case opQuery:
+		d = c.config.Timeouts.Query
+	case opPower:
+		d = c.config.Timeouts.Power
+	case opUpdate:
+		d = c.config.Timeouts.Update
+	case opInstall:
+		d = c.config.Timeouts.Install
+	case opBackup:
+		d = c.config.Timeouts.Backup
+	case opPull:
+		d = c.config.Timeouts.Pull
+	}
+	if d <= 0 {
+		d = c.config.RequestTimeout
+	}
+	return d
+}
+
+// NewClient creates a new agent client
+func NewClient(cfg config.AgentConfig, db *gorm.DB) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	insecure := http.DefaultTransport.(*http.Transport).Clone()
+	insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+	// Deadlines come from the request context, per operation class
+	return &Client{
+		db: db,
+		httpClient: &http.Client{
+			Transport: transport,
+		},
+		insecureClient: &http.Client{
+			Transport: insecure,
+		},
+		config: cfg,
+	}
+}
+
+// httpFor returns the HTTP client for a node, which skips certificate
+// verification only for nodes explicitly marked insecure
+func (c *Client) httpFor(node *entities.Node) *http.Client {
+	if node.TLSInsecure {
+		return c.insecureClient
+	}
+	return c.httpClient
+}
+
+// Error represents an error response returned by an agent
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("agent returned %d: %s", e.StatusCode, e.Message)
+}
+
+// agentStats mirrors the stats payload returned by the agent
+type agentStats struct {
+	Status        string  `json:"status"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	DiskUsage     uint64  `json:"disk_usage"`
+	DiskLimit     uint64  `json:"disk_limit"`
+	NetworkRx     uint64  `json:"network_rx"`
+	NetworkTx     uint64  `json:"network_tx"`
+	Uptime        int64   `json:"uptime"`
+	Health        string  `json:"health"`
+
+	OOMKill *struct {
+		KilledAt    time.Time `json:"killed_at"`
+		MemoryLimit uint64    `json:"memory_limit"`
+		MemoryUsage uint64    `json:"memory_usage"`
+	} `json:"oom_kill"`
+	Crash *services.CrashReport `json:"crash"`
+}
+
+// StartServer starts a server on its node
+func (c *Client) StartServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	return c.do(ctx, opPower, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/power/start", nil, nil)
+}
+
+// StopServer gracefully stops a server on its node
+func (c *Client) StopServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	return c.do(ctx, opPower, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/power/stop", nil, nil)
+}
+
+// RestartServer restarts a server on its node
+func (c *Client) RestartServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	return c.do(ctx, opPower, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/power/restart", nil, nil)
+}
+
+// KillServer forcefully stops a server on its node
+func (c *Client) KillServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) error {
+	return c.do(ctx, opPower, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/power/kill", nil, nil)
+}
+
+// GetServerStatus retrieves the latest resource usage of a server
+func (c *Client) GetServerStatus(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) (*services.ServerStats, error) {
+	var raw *agentStats
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/servers/"+serverID.String()+"/stats", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	// The agent has not collected a sample yet
+	if raw == nil {
+		return &services.ServerStats{}, nil
+	}
+
+	stats := &services.ServerStats{
+		CPUUsage:      raw.CPUPercent,
+		MemoryUsage:   int64(raw.MemoryUsage),
+		MemoryLimit:   int64(raw.MemoryLimit),
+		MemoryPercent: raw.MemoryPercent,
+		DiskUsage:     int64(raw.DiskUsage),
+		DiskLimit:     int64(raw.DiskLimit),
+		NetworkRx:     int64(raw.NetworkRx),
+		NetworkTx:     int64(raw.NetworkTx),
+		Uptime:        raw.Uptime,
+		Status:        raw.Status,
+		Health:        raw.Health,
+	}
+	if raw.OOMKill != nil {
+		stats.OOMKill = &services.OOMKill{
+			KilledAt:    raw.OOMKill.KilledAt,
+			MemoryLimit: int64(raw.OOMKill.MemoryLimit),
+			MemoryUsage: int64(raw.OOMKill.MemoryUsage),
+		}
+	}
+	stats.Crash = raw.Crash
+	if stats.MemoryPercent == 0 && stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	return stats, nil
+}
+
+// SendCommand sends a console command to a server
+func (c *Client) SendCommand(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, command string) error {
+	body := map[string]string{"command": command}
+	return c.do(ctx, opPower, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/command", body, nil)
+}
+
+// PortBinding is a server port binding as agents report and accept it
+type PortBinding struct {
+	IP        string `json:"ip"`
+	Port      int    `json:"port"`
+	IsPrimary bool   `json:"is_primary"`
+	Protocol  string `json:"protocol,omitempty"` // tcp, udp or both
+}
+
+// DiscoveredContainer is an Aether-managed container reported by an agent
+type DiscoveredContainer struct {
+	ServerID    string            `json:"server_id"`
+	UUID        string            `json:"uuid"`
+	ContainerID string            `json:"container_id"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	State       string            `json:"state"`
+	StartupCmd  string            `json:"startup_cmd"`
+	Environment map[string]string `json:"environment"`
+	MemoryLimit int64             `json:"memory_limit"` // MB
+	CPULimit    int               `json:"cpu_limit"`    // percentage
+	Allocations []PortBinding     `json:"allocations"`
+	Labels      map[string]string `json:"labels"`
+	Tracked     bool              `json:"tracked"`
+}
+
+// DiscoverServers lists the Aether-managed containers present on a node
+func (c *Client) DiscoverServers(ctx context.Context, nodeID uuid.UUID) ([]DiscoveredContainer, error) {
+	var resp struct {
+		Servers []DiscoveredContainer `json:"servers"`
+	}
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/servers/discover", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Servers, nil
+}
+
+// Orphan is a container or data directory a node holds for a server the panel
+// no longer has
+type Orphan struct {
+	Kind        string `json:"kind"` // container, data
+	UUID        string `json:"uuid"`
+	ServerID    string `json:"server_id,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Size        uint64 `json:"size,omitempty"` // Bytes, data directories only
+	Removed     bool   `json:"removed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CleanOrphans lists the orphans on a node, those not belonging to any of the
+// known server UUIDs, and removes them unless dryRun is set
+func (c *Client) CleanOrphans(ctx context.Context, nodeID uuid.UUID, known []string, dryRun bool) ([]Orphan, error) {
+	body := map[string]interface{}{"known": known, "dry_run": dryRun}
+	var resp struct {
+		Orphans []Orphan `json:"orphans"`
+	}
+	if err := c.do(ctx, opUpdate, nodeID, http.MethodPost, "/api/servers/orphans", body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Orphans, nil
+}
+
+// GetServerStatuses returns the live status of every server on a node, keyed
+// by server ID
+func (c *Client) GetServerStatuses(ctx context.Context, nodeID uuid.UUID) (map[string]string, error) {
+	var resp struct {
+		Statuses map[string]string `json:"statuses"`
+	}
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/servers/statuses", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Statuses, nil
+}
+
+// SystemInfo is a node's live capacity and usage as its agent reports it
+type SystemInfo struct {
+	CPUCores     int     `json:"cpu_cores"`
+	Load         float64 `json:"load"` // 1 minute load average
+	MemoryMB     int64   `json:"memory_mb"`
+	MemoryUsedMB int64   `json:"memory_used_mb"`
+	DiskMB       int64   `json:"disk_mb"`
+	DiskUsedMB   int64   `json:"disk_used_mb"`
+}
+
+// GetSystemInfo returns the live capacity and usage of a node
+func (c *Client) GetSystemInfo(ctx context.Context, nodeID uuid.UUID) (*SystemInfo, error) {
+	var info SystemInfo
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/system", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Ping checks that a node's agent is reachable and reports itself healthy
+func (c *Client) Ping(ctx context.Context, nodeID uuid.UUID) error {
+	return c.do(ctx, opQuery, nodeID, http.MethodGet, "/health", nil, nil)
+}
+
+// RegistryAuth is a decrypted registry credential pushed to an agent
+type RegistryAuth struct {
+	Host     string `json:"host"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SyncRegistries replaces the registry credentials an agent uses for image pulls
+func (c *Client) SyncRegistries(ctx context.Context, nodeID uuid.UUID, registries []RegistryAuth) error {
+	body := map[string]interface{}{"registries": registries}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/registries", body, nil)
+}
+
+// SyncUploadLimit sets the largest upload, in MB, an agent accepts for its servers
+func (c *Client) SyncUploadLimit(ctx context.Context, nodeID uuid.UUID, maxUploadSize int) error {
+	body := map[string]int{"max_upload_size": maxUploadSize}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/limits", body, nil)
+}
+
+// ListImages returns the images present on a node, as repository:tag references
+func (c *Client) ListImages(ctx context.Context, nodeID uuid.UUID) ([]string, error) {
+	var resp struct {
+		Images []string `json:"images"`
+	}
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/images", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Images, nil
+}
+
+// PullImage pulls an image onto a node using the registry credentials it was given
+func (c *Client) PullImage(ctx context.Context, nodeID uuid.UUID, image string) error {
+	body := map[string]string{"image": image}
+	return c.do(ctx, opPull, nodeID, http.MethodPost, "/api/images/pull", body, nil)
+}
+
+// ChatMessage is a chat line a node read from a server's console
+type ChatMessage struct {
+	Seq      int64     `json:"seq"`
+	Username string    `json:"username"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// GetChat returns the chat messages a node buffered for a server after the given sequence number
+func (c *Client) GetChat(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, after int64) ([]ChatMessage, error) {
+	var resp struct {
+		Messages []ChatMessage `json:"messages"`
+	}
+	path := "/api/servers/" + serverID.String() + "/chat?after=" + strconv.FormatInt(after, 10)
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// CreateBackup backs up a server's data directory, returning once the node
+// has written the archive
+func (c *Client) CreateBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID) (*services.BackupArchive, error) {
+	body := map[string]string{"backup_id": backupID.String()}
+	var archive services.BackupArchive
+	if err := c.do(ctx, opBackup, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/backups", body, &archive); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// RestoreBackup restores a server from a backup. The node refuses archives
+// whose SHA-256 does not match checksum with services.ErrBackupCorrupted.
+func (c *Client) RestoreBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID, checksum string, wipeData bool) error {
+	body := map[string]interface{}{"checksum": checksum, "wipe_data": wipeData}
+	return corrupted(c.do(ctx, opBackup, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/backups/"+backupID.String()+"/restore", body, nil))
+}
+
+// VerifyBackup has a node recompute the checksum of a stored backup archive.
+// An archive that no longer matches returns services.ErrBackupCorrupted.
+func (c *Client) VerifyBackup(ctx context.Context, nodeID uuid.UUID, backupID uuid.UUID, checksum string) error {
+	body := map[string]string{"checksum": checksum}
+	return corrupted(c.do(ctx, opBackup, nodeID, http.MethodPost, "/api/backups/"+backupID.String()+"/verify", body, nil))
+}
+
+// DownloadBackup streams a backup archive from its node, which verifies it
+// against checksum first. The caller must close the returned reader.
+func (c *Client) DownloadBackup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, backupID uuid.UUID, checksum string) (io.ReadCloser, int64, error) {
+	path := "/api/servers/" + serverID.String() + "/backups/" + backupID.String() + "/download?checksum=" + url.QueryEscape(checksum)
+	resp, err := c.send(ctx, opBackup, nodeID, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, corrupted(err)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// corrupted translates a node's checksum mismatch response into
+// services.ErrBackupCorrupted
+func corrupted(err error) error {
+	var agentErr *Error
+	if errors.As(err, &agentErr) && agentErr.StatusCode == http.StatusUnprocessableEntity {
+		return fmt.Errorf("%w: %s", services.ErrBackupCorrupted, agentErr.Message)
+	}
+	return err
+}
+
+// UpdateServerImage changes a server's image; the agent pulls it on next start
+func (c *Client) UpdateServerImage(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, image string) error {
+	body := map[string]string{"image": image}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/image", body, nil)
+}
+
+// UpdateServerStartup replaces a server's startup command, environment,
+// allocations, healthcheck, process limits, config file rules, root filesystem
+// mode, capabilities and container command; the node recreates the container
+// on next start.
+// Limits the egg leaves unset are the panel's.
+func (c *Client) UpdateServerStartup(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, startup *services.Startup, allocations []*entities.Allocation) error {
+	allocs := make([]PortBinding, 0, len(allocations))
+	for _, a := range allocations {
+		allocs = append(allocs, PortBinding{IP: a.IP, Port: a.Port, IsPrimary: a.IsPrimary, Protocol: a.Protocol})
+	}
+	body := map[string]interface{}{
+		"startup_cmd": startup.Command,
+		"environment": startup.Environment,
+		"allocations": allocs,
+		"healthcheck": startup.Healthcheck,
+		"process_limits": startup.Limits.Or(services.ProcessLimits{
+			Pids:   c.config.PidsLimit,
+			NoFile: c.config.NoFile,
+			NProc:  c.config.NProc,
+		}),
+		"read_only_rootfs": startup.ReadOnlyRootfs,
+		"tmpfs":            startup.Tmpfs,
+		"capabilities":     startup.Capabilities,
+		"entrypoint":       startup.Entrypoint,
+		"shell":            startup.Shell,
+		"direct_exec":      startup.DirectExec,
+	}
+	if startup.ConfigFiles != "" {
+		body["config_files"] = json.RawMessage(startup.ConfigFiles)
+	}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/startup", body, nil)
+}
+
+// UpdateServerBandwidth replaces a server's bandwidth limits in bytes/s, 0 for
+// unlimited; a running server is capped right away
+func (c *Client) UpdateServerBandwidth(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, in, out int64) error {
+	body := map[string]int64{"network_in": in, "network_out": out}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/bandwidth", body, nil)
+}
+
+// CrashRecoveryPolicy is a server's crash recovery policy as the agent takes it
+type CrashRecoveryPolicy struct {
+	Enabled     bool `json:"enabled"`
+	MaxRestarts int  `json:"max_restarts"`
+	Window      int  `json:"window"` // seconds
+}
+
+// UpdateCrashRecovery pushes a server's crash recovery policy to its node
+func (c *Client) UpdateCrashRecovery(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, policy CrashRecoveryPolicy) error {
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/crash-recovery", policy, nil)
+}
+
+// UpdateServerMounts replaces the extra host paths mounted into a server. The
+// node checks them against its own allowed mounts and applies them on the
+// next start.
+func (c *Client) UpdateServerMounts(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, mounts []entities.Mount) error {
+	body := map[string]interface{}{"mounts": mounts}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/mounts", body, nil)
+}
+
+// UpdateConsoleOutput sets whether a server's node strips colour and cursor
+// escapes from its console output
+func (c *Client) UpdateConsoleOutput(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, stripANSI bool) error {
+	body := map[string]bool{"strip_ansi": stripANSI}
+	return c.do(ctx, opUpdate, nodeID, http.MethodPut, "/api/servers/"+serverID.String()+"/console", body, nil)
+}
+
+// ReinstallServer reruns the install process of a server, emptying its data
+// volume first when wipeData is set
+func (c *Client) ReinstallServer(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, wipeData bool) error {
+	body := map[string]bool{"wipe_data": wipeData}
+	return c.do(ctx, opInstall, nodeID, http.MethodPost, "/api/servers/"+serverID.String()+"/reinstall", body, nil)
+}
+
+// ListWorlds returns the Minecraft worlds found in a server's data directory
+func (c *Client) ListWorlds(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID) ([]services.WorldInfo, error) {
+	var resp struct {
+		Worlds []services.WorldInfo `json:"worlds"`
+	}
+	if err := c.do(ctx, opQuery, nodeID, http.MethodGet, "/api/servers/"+serverID.String()+"/worlds", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Worlds, nil
+}
+
+// BackupWorld archives a world folder on the node
+func (c *Client) BackupWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID) (*services.WorldArchive, error) {
+	body := map[string]string{"backup_id": backupID.String()}
+	var archive services.WorldArchive
+	if err := c.do(ctx, opBackup, nodeID, http.MethodPost, worldPath(serverID, folder)+"/backups", body, &archive); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// RestoreWorld replaces a world folder with a backup. The node refuses
+// archives whose SHA-256 does not match checksum.
+func (c *Client) RestoreWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string, backupID uuid.UUID, checksum string) error {
+	body := map[string]string{"checksum": checksum}
+	return corrupted(c.do(ctx, opBackup, nodeID, http.MethodPost, worldPath(serverID, folder)+"/backups/"+backupID.String()+"/restore", body, nil))
+}
+
+// DeleteWorld removes a world folder on the node
+func (c *Client) DeleteWorld(ctx context.Context, nodeID uuid.UUID, serverID uuid.UUID, folder string) error {
+	return c.do(ctx, opUpdate, nodeID, http.MethodDelete, worldPath(serverID, folder), nil, nil)
+}
+
+// worldPath returns the agent API path of a server's world folder
+func worldPath(serverID uuid.UUID, folder string) string {
+	return "/api/servers/" + serverID.String() + "/worlds/" + url.PathEscape(folder)
+}
+
+// do performs an authenticated request against a node agent, bounded by the
+// timeout of its operation class. Cancelling ctx aborts the request.
+func (c *Client) do(ctx context.Context, op operation, nodeID uuid.UUID, method, path string, body interface{}, dest interface{}) error {
+	resp, err := c.send(ctx, op, nodeID, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if dest != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+			return fmt.Errorf("failed to decode agent response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// send performs an authenticated request against a node agent and returns
+// its successful response. The deadline and trace span of the request end
+// when the response body is closed.
+func (c *Client) send(ctx context.Context, op operation, nodeID uuid.UUID, method, path string, body interface{}) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout(op))
+
+	var node entities.Node
+	if err := c.db.WithContext(ctx).Where("id = ?", nodeID).First(&node).Error; err != nil {
+		cancel()
+		return nil, services.ErrNodeNotFound
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "agent "+method+" "+routeOf(path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("node.id", nodeID.String()),
+		),
+	)
+	done := func() {
+		span.End()
+		cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, BaseURL(&node)+path, reader)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	tracing.InjectHeaders(ctx, req.Header)
+	req.Header.Set("Authorization", "Bearer "+node.DaemonToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpFor(&node).Do(req)
+	if err != nil {
+		defer done()
+		span.RecordError(err)
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			span.SetStatus(codes.Error, "node timed out")
+			return nil, fmt.Errorf("%w: %s %s after %s", services.ErrNodeTimeout, method, routeOf(path), c.timeout(op))
+		case errors.Is(ctx.Err(), context.Canceled):
+			span.SetStatus(codes.Error, "request cancelled")
+			return nil, ctx.Err()
+		}
+		span.SetStatus(codes.Error, "node unreachable")
+		return nil, fmt.Errorf("%w: %v", services.ErrNodeOffline, err)
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	if resp.StatusCode >= 300 {
+		defer done()
+		defer resp.Body.Close()
+
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errBody)
+		return nil, &Error{StatusCode: resp.StatusCode, Message: errBody.Error}
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: done}
+	return resp, nil
+}
+
+// releasingBody runs release once the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// BaseURL returns the base URL of a node's agent API
+func BaseURL(node *entities.Node) string {
+	return fmt.Sprintf("%s://%s:%d", node.Scheme, node.FQDN, node.DaemonPort)
+}
+
+// routeOf drops the query and replaces UUIDs and world folders in an agent path
+// with placeholders so span names stay low-cardinality
+func routeOf(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if _, err := uuid.Parse(p); err == nil {
+			parts[i] = ":id"
+		} else if i > 0 && parts[i-1] == "worlds" {
+			parts[i] = ":world"
+		}
+	}
+	return strings.Join(parts, "/")
+}
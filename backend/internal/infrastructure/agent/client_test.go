@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+)
+
+// newSlowAgent returns a client for a node whose agent answers after delay,
+// and a channel receiving true for each request the client abandoned first
+func newSlowAgent(t *testing.T, cfg config.AgentConfig, delay time.Duration) (*Client, uuid.UUID, <-chan bool) {
+	t.Helper()
+	aborted := make(chan bool, 10)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			aborted <- false
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status":"running"}`))
+		case <-r.Context().Done():
+			aborted <- true
+		}
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	db := dbtest.Open(t, &entities.Node{})
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(cfg, db), node.ID, aborted
+}
+
+// wasAborted reports whether the agent saw its request abandoned
+func wasAborted(t *testing.T, aborted <-chan bool) bool {
+	t.Helper()
+	select {
+	case v := <-aborted:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent never finished the request")
+		return false
+	}
+}
+
+func TestAgentCallsAbortAtTheirDeadline(t *testing.T) {
+	cfg := config.AgentConfig{
+		RequestTimeout: 5 * time.Second,
+		Timeouts:       config.AgentTimeouts{Power: 50 * time.Millisecond},
+	}
+	client, nodeID, aborted := newSlowAgent(t, cfg, 300*time.Millisecond)
+
+	started := time.Now()
+	err := client.StartServer(context.Background(), nodeID, uuid.New())
+	if !errors.Is(err, services.ErrNodeTimeout) {
+		t.Fatalf("slow power action = %v, want %v", err, services.ErrNodeTimeout)
+	}
+	if elapsed := time.Since(started); elapsed > 250*time.Millisecond {
+		t.Errorf("returned after %s, want at the 50ms power deadline", elapsed)
+	}
+	if !wasAborted(t, aborted) {
+		t.Error("the agent request outlived its deadline")
+	}
+
+	// Queries have no timeout of their own and get the request timeout
+	if _, err := client.GetServerStatus(context.Background(), nodeID, uuid.New()); err != nil {
+		t.Errorf("query = %v, want it to wait out the agent", err)
+	}
+	if wasAborted(t, aborted) {
+		t.Error("query aborted")
+	}
+}
+
+func TestAgentCallsFollowCallerCancellation(t *testing.T) {
+	client, nodeID, aborted := newSlowAgent(t, config.AgentConfig{RequestTimeout: 5 * time.Second}, 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := client.StopServer(ctx, nodeID, uuid.New())
+	if !errors.Is(err, context.Canceled) || errors.Is(err, services.ErrNodeTimeout) {
+		t.Errorf("cancelled call = %v, want %v", err, context.Canceled)
+	}
+	if !wasAborted(t, aborted) {
+		t.Error("the agent request kept running after the caller gave up")
+	}
+
+	// A caller deadline shorter than the operation's is a timeout as well
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.StopServer(ctx, nodeID, uuid.New()); !errors.Is(err, services.ErrNodeTimeout) {
+		t.Errorf("call past the caller deadline = %v, want %v", err, services.ErrNodeTimeout)
+	}
+	if !wasAborted(t, aborted) {
+		t.Error("the agent request outlived the caller deadline")
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// reportCrash marks a server whose node gave up restarting it after repeated
+// crashes as errored, records a system event and tells the owner. Servers
+// already in the error state were reported before and are skipped.
+func (c *StatsCollector) reportCrash(ctx context.Context, server *entities.Server, crash *services.CrashReport) {
+	data := map[string]interface{}{
+		"server_id":   server.ID,
+		"server_name": server.Name,
+		"exit_code":   crash.ExitCode,
+		"restarts":    crash.Restarts,
+		"crashed_at":  crash.CrashedAt,
+	}
+
+	var notification *entities.Notification
+	var delivery NotificationDelivery
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entities.Server{}).
+			Where("id = ? AND status <> ?", server.ID, entities.ServerStatusError).
+			Update("status", entities.ServerStatusError)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&entities.SystemEvent{
+			NodeID:    &server.NodeID,
+			ServerID:  &server.ID,
+			EventType: entities.EventServerCrashed,
+			Severity:  "error",
+			Message:   fmt.Sprintf("Server %s crashed %d times within its recovery window and was left stopped", server.Name, crash.Restarts+1),
+			Data:      data,
+		}).Error; err != nil {
+			return err
+		}
+
+		notification = &entities.Notification{
+			UserID: server.OwnerID,
+			Type:   entities.NotificationServerCrashed,
+			Title:  "Server keeps crashing",
+			Message: fmt.Sprintf("Your server %s crashed again after %d automatic restarts and was left stopped. Check its console for the cause before starting it.",
+				server.Name, crash.Restarts),
+			Data: data,
+		}
+		var err error
+		delivery, err = c.notify.Create(tx, notification)
+		return err
+	})
+	if err != nil {
+		c.logger.Warn("Failed to report server crash", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return
+	}
+	if notification != nil {
+		c.notify.Deliver(ctx, server.ID, notification, delivery)
+		c.hooks.Notify(server, entities.EventServerCrashed, data)
+	}
+
+	c.logger.Info("Server crash recovery gave up",
+		zap.String("server_id", server.ID.String()),
+		zap.Int("exit_code", crash.ExitCode),
+		zap.Int("restarts", crash.Restarts),
+	)
+}
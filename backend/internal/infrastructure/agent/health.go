@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CertCheck is the result of verifying a node's TLS certificate
+type CertCheck struct {
+	Status    entities.CertStatus `json:"status"`
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// NodeHealthChecker polls nodes, recording whether they are online and, for
+// https nodes, the state of their TLS certificate
+type NodeHealthChecker struct {
+	client *Client
+	db     *gorm.DB
+	config config.AgentConfig
+	logger *zap.Logger
+}
+
+// NewNodeHealthChecker creates a new NodeHealthChecker
+func NewNodeHealthChecker(client *Client, db *gorm.DB, cfg config.AgentConfig, log *zap.Logger) *NodeHealthChecker {
+	return &NodeHealthChecker{
+		client: client,
+		db:     db,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Start checks every node right away and then every interval until the
+// context is cancelled
+func (h *NodeHealthChecker) Start(ctx context.Context) {
+	ticker := time.NewTicker(h.config.HealthInterval)
+	defer ticker.Stop()
+
+	h.checkAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkAll(ctx)
+		}
+	}
+}
+
+func (h *NodeHealthChecker) checkAll(ctx context.Context) {
+	var nodes []entities.Node
+	if err := h.db.WithContext(ctx).Where("deleted_at IS NULL").Find(&nodes).Error; err != nil {
+		h.logger.Warn("Failed to load nodes for health checks", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(node *entities.Node) {
+			defer wg.Done()
+			if err := h.CheckNode(ctx, node); err != nil {
+				h.logger.Warn("Failed to record node health", zap.String("node_id", node.ID.String()), zap.Error(err))
+			}
+		}(&nodes[i])
+	}
+	wg.Wait()
+}
+
+// CheckNode polls a node and stores its online and certificate status. A
+// certificate that turns expiring, expired or fails verification raises a
+// system event once, when the status changes.
+func (h *NodeHealthChecker) CheckNode(ctx context.Context, node *entities.Node) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"is_online":       h.client.Ping(ctx, node.ID) == nil,
+		"last_checked_at": now,
+		"cert_status":     "",
+		"cert_expires_at": nil,
+		"cert_error":      "",
+	}
+
+	var cert *CertCheck
+	if node.Scheme == "https" {
+		checkCtx, cancel := context.WithTimeout(ctx, h.client.timeout(opQuery))
+		check := CheckCertificate(checkCtx, node.FQDN, node.DaemonPort, h.config.CertWarnBefore)
+		cancel()
+
+		cert = &check
+		updates["cert_status"] = check.Status
+		updates["cert_expires_at"] = check.ExpiresAt
+		updates["cert_error"] = truncate(check.Error, 500)
+	}
+
+	return h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Node{}).Where("id = ?", node.ID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		if cert == nil || cert.Status == node.CertStatus || !h.warns(node, cert.Status) {
+			return nil
+		}
+
+		severity := "error"
+		if cert.Status == entities.CertStatusExpiring {
+			severity = "warning"
+		}
+		h.logger.Warn("Node certificate needs attention",
+			zap.String("node_id", node.ID.String()),
+			zap.String("status", string(cert.Status)),
+			zap.String("error", cert.Error),
+		)
+		return tx.Create(&entities.SystemEvent{
+			NodeID:    &node.ID,
+			EventType: entities.EventNodeCert,
+			Severity:  severity,
+			Message:   certMessage(cert),
+			Data: map[string]interface{}{
+				"node_id":    node.ID,
+				"node_name":  node.Name,
+				"status":     cert.Status,
+				"expires_at": cert.ExpiresAt,
+				"error":      cert.Error,
+			},
+		}).Error
+	})
+}
+
+// warns reports whether a certificate status deserves an event. Nodes marked
+// insecure are expected to fail verification, so only expiry is reported.
+func (h *NodeHealthChecker) warns(node *entities.Node, status entities.CertStatus) bool {
+	switch status {
+	case entities.CertStatusExpiring, entities.CertStatusExpired:
+		return true
+	case entities.CertStatusUntrusted, entities.CertStatusInvalid:
+		return !node.TLSInsecure
+	}
+	return false
+}
+
+func certMessage(cert *CertCheck) string {
+	switch cert.Status {
+	case entities.CertStatusExpiring:
+		return fmt.Sprintf("Certificate expires on %s", cert.ExpiresAt.Format(time.DateOnly))
+	case entities.CertStatusExpired:
+		return fmt.Sprintf("Certificate expired on %s", cert.ExpiresAt.Format(time.DateOnly))
+	}
+	return "Certificate failed verification: " + cert.Error
+}
+
+// certRoots are the authorities node certificates are verified against, the
+// system roots when nil
+var certRoots *x509.CertPool
+
+// CheckCertificate connects to a node's agent and verifies the certificate it
+// presents against the system roots and the node's hostname. The certificate
+// is inspected even when verification fails, so expiry is reported for
+// self-signed certificates too.
+func CheckCertificate(ctx context.Context, host string, port int, warnBefore time.Duration) CertCheck {
+	dialer := &tls.Dialer{
+		// Verified below, so that a failing certificate can still be inspected
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return CertCheck{Status: entities.CertStatusUnknown, Error: err.Error()}
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return CertCheck{Status: entities.CertStatusUnknown, Error: "no certificate presented"}
+	}
+
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	now := time.Now()
+	check := CertCheck{ExpiresAt: &leaf.NotAfter}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         certRoots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+
+	var unknownAuthority x509.UnknownAuthorityError
+	switch {
+	case now.After(leaf.NotAfter):
+		check.Status = entities.CertStatusExpired
+	case errors.As(err, &unknownAuthority):
+		check.Status = entities.CertStatusUntrusted
+		check.Error = err.Error()
+	case err != nil:
+		check.Status = entities.CertStatusInvalid
+		check.Error = err.Error()
+	case leaf.NotAfter.Sub(now) < warnBefore:
+		check.Status = entities.CertStatusExpiring
+	default:
+		check.Status = entities.CertStatusValid
+	}
+	return check
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+)
+
+// issueCert issues a certificate for 127.0.0.1 valid until notAfter, signed
+// by parent or self-signed when parent is nil
+func issueCert(t *testing.T, parent *tls.Certificate, notAfter time.Time, isCA bool) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "node-1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// serveTLS starts an agent presenting cert and returns its port
+func serveTLS(t *testing.T, cert tls.Certificate) int {
+	t.Helper()
+	daemon := httptest.NewUnstartedServer(http.NotFoundHandler())
+	daemon.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	daemon.StartTLS()
+	t.Cleanup(daemon.Close)
+	_, port, err := net.SplitHostPort(daemon.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+func TestCheckCertificate(t *testing.T) {
+	ca := issueCert(t, nil, time.Now().Add(365*24*time.Hour), true)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	certRoots = roots
+	t.Cleanup(func() { certRoots = nil })
+
+	const warnBefore = 14 * 24 * time.Hour
+	for _, tc := range []struct {
+		name string
+		cert tls.Certificate
+		want entities.CertStatus
+	}{
+		{"valid", issueCert(t, &ca, time.Now().Add(90*24*time.Hour), false), entities.CertStatusValid},
+		{"expiring", issueCert(t, &ca, time.Now().Add(7*24*time.Hour), false), entities.CertStatusExpiring},
+		{"expired", issueCert(t, &ca, time.Now().Add(-time.Hour), false), entities.CertStatusExpired},
+		{"self-signed", issueCert(t, nil, time.Now().Add(90*24*time.Hour), false), entities.CertStatusUntrusted},
+	} {
+		check := CheckCertificate(context.Background(), "127.0.0.1", serveTLS(t, tc.cert), warnBefore)
+		if check.Status != tc.want {
+			t.Errorf("%s certificate = %s (%s), want %s", tc.name, check.Status, check.Error, tc.want)
+		}
+		if check.ExpiresAt == nil || !check.ExpiresAt.Equal(tc.cert.Leaf.NotAfter) {
+			t.Errorf("%s certificate expires at %v, want %v", tc.name, check.ExpiresAt, tc.cert.Leaf.NotAfter)
+		}
+		if (check.Error != "") != (tc.want == entities.CertStatusUntrusted) {
+			t.Errorf("%s certificate error %q", tc.name, check.Error)
+		}
+	}
+
+	// A node that cannot be reached has no certificate to judge
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	if check := CheckCertificate(context.Background(), "127.0.0.1", port, warnBefore); check.Status != entities.CertStatusUnknown || check.Error == "" {
+		t.Errorf("unreachable node = %+v, want unknown with the dial error", check)
+	}
+}
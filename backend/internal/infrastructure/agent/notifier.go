@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/mail"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NotificationDelivery is where a notification goes once it is created
+type NotificationDelivery struct {
+	InApp bool // Stored, and published to the sockets following its server
+	Email bool
+}
+
+// Notifier sends users notifications on the channels their notification
+// preferences ask for
+type Notifier struct {
+	db     *gorm.DB
+	rdb    *redis.Client
+	mailer *mail.Mailer
+	logger *zap.Logger
+}
+
+// NewNotifier creates a new Notifier
+func NewNotifier(db *gorm.DB, rdb *redis.Client, mailer *mail.Mailer, log *zap.Logger) *Notifier {
+	return &Notifier{
+		db:     db,
+		rdb:    rdb,
+		mailer: mailer,
+		logger: log,
+	}
+}
+
+// Create stores a notification through tx unless its user turned the type
+// off in-app, and returns where it is to be delivered. Call Deliver once tx
+// commits.
+func (n *Notifier) Create(tx *gorm.DB, notification *entities.Notification) (NotificationDelivery, error) {
+	var prefs []entities.NotificationPreference
+	if err := tx.Where("user_id = ? AND type IN ?", notification.UserID, []string{notification.Type, "*"}).Find(&prefs).Error; err != nil {
+		return NotificationDelivery{}, err
+	}
+
+	var delivery NotificationDelivery
+	delivery.InApp, delivery.Email = services.NotificationChannels(prefs, notification.Type)
+	if !delivery.InApp {
+		return delivery, nil
+	}
+	return delivery, tx.Create(notification).Error
+}
+
+// Deliver publishes a created notification about a server to the sockets
+// following it and emails it in the background, as delivery says
+func (n *Notifier) Deliver(ctx context.Context, serverID uuid.UUID, notification *entities.Notification, delivery NotificationDelivery) {
+	if delivery.InApp {
+		n.publish(ctx, serverID, notification)
+	}
+	if delivery.Email {
+		go n.email(context.Background(), *notification)
+	}
+}
+
+func (n *Notifier) publish(ctx context.Context, serverID uuid.UUID, notification *entities.Notification) {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return
+	}
+	if err := n.rdb.Publish(ctx, EventsKey(serverID.String()), payload); err != nil {
+		n.logger.Debug("Failed to publish notification", zap.String("server_id", serverID.String()), zap.Error(err))
+	}
+}
+
+func (n *Notifier) email(ctx context.Context, notification entities.Notification) {
+	var user entities.User
+	if err := n.db.WithContext(ctx).Select("id", "email").Where("id = ?", notification.UserID).First(&user).Error; err != nil {
+		n.logger.Warn("Failed to load notification recipient", zap.String("user_id", notification.UserID.String()), zap.Error(err))
+		return
+	}
+	if err := n.mailer.Send(ctx, user.Email, notification.Title, notification.Message); err != nil {
+		n.logger.Warn("Failed to email notification",
+			zap.String("user_id", notification.UserID.String()),
+			zap.String("type", notification.Type),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestNotifierFollowsPreferences(t *testing.T) {
+	db := dbtest.Open(t, &entities.Notification{}, &entities.NotificationPreference{})
+	notifier := NewNotifier(db, nil, nil, zap.NewNop())
+
+	picky, other := uuid.New(), uuid.New()
+	for _, pref := range []entities.NotificationPreference{
+		{ID: uuid.New(), UserID: picky, Type: entities.NotificationServerOOM, InApp: false, Email: true},
+		{ID: uuid.New(), UserID: picky, Type: "*", InApp: true, Email: true},
+	} {
+		// Create writes the true in_app default in place of false
+		inApp := pref.InApp
+		if err := db.Create(&pref).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&pref).Update("in_app", inApp).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		user   uuid.UUID
+		typ    string
+		want   NotificationDelivery
+		stored bool
+	}{
+		{"turned off in-app", picky, entities.NotificationServerOOM, NotificationDelivery{InApp: false, Email: true}, false},
+		{"user default", picky, entities.NotificationServerCrashed, NotificationDelivery{InApp: true, Email: true}, true},
+		{"no preferences", other, entities.NotificationServerOOM, NotificationDelivery{InApp: true}, true},
+	} {
+		notification := &entities.Notification{ID: uuid.New(), UserID: tc.user, Type: tc.typ, Title: tc.name}
+		delivery, err := notifier.Create(db, notification)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delivery != tc.want {
+			t.Errorf("%s: delivery %+v, want %+v", tc.name, delivery, tc.want)
+		}
+		var count int64
+		if err := db.Model(&entities.Notification{}).Where("id = ?", notification.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if (count == 1) != tc.stored {
+			t.Errorf("%s: stored %d notifications, want stored %v", tc.name, count, tc.stored)
+		}
+	}
+}
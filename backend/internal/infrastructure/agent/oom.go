@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// reportOOM marks a server killed for running out of memory as errored, records
+// a system event and tells the owner to raise the memory limit. Servers already
+// in the error state were reported before and are skipped.
+func (c *StatsCollector) reportOOM(ctx context.Context, server *entities.Server, oom *services.OOMKill) {
+	const mb = 1024 * 1024
+
+	data := map[string]interface{}{
+		"server_id":    server.ID,
+		"server_name":  server.Name,
+		"memory_limit": server.MemoryLimit, // MB
+		"memory_usage": oom.MemoryUsage / mb,
+		"killed_at":    oom.KilledAt,
+	}
+
+	var notification *entities.Notification
+	var delivery NotificationDelivery
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entities.Server{}).
+			Where("id = ? AND status <> ?", server.ID, entities.ServerStatusError).
+			Update("status", entities.ServerStatusError)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&entities.SystemEvent{
+			NodeID:    &server.NodeID,
+			ServerID:  &server.ID,
+			EventType: entities.EventServerOOM,
+			Severity:  "warning",
+			Message:   fmt.Sprintf("Server %s was stopped after exceeding its %d MB memory limit", server.Name, server.MemoryLimit),
+			Data:      data,
+		}).Error; err != nil {
+			return err
+		}
+
+		notification = &entities.Notification{
+			UserID: server.OwnerID,
+			Type:   entities.NotificationServerOOM,
+			Title:  "Server ran out of memory",
+			Message: fmt.Sprintf("Your server %s used all of its %d MB of memory and was stopped. Increase its memory limit to keep it from crashing again.",
+				server.Name, server.MemoryLimit),
+			Data: data,
+		}
+		var err error
+		delivery, err = c.notify.Create(tx, notification)
+		return err
+	})
+	if err != nil {
+		c.logger.Warn("Failed to report out of memory kill", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return
+	}
+	if notification != nil {
+		c.notify.Deliver(ctx, server.ID, notification, delivery)
+	}
+
+	c.logger.Info("Server killed for running out of memory",
+		zap.String("server_id", server.ID.String()),
+		zap.Int64("memory_limit", server.MemoryLimit),
+		zap.Int64("memory_usage", oom.MemoryUsage/mb),
+	)
+}
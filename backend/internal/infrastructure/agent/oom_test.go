@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// newTestCollector returns a StatsCollector on db and an in-memory Redis
+func newTestCollector(t *testing.T, db *gorm.DB) *StatsCollector {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return NewStatsCollector(nil, db, rdb, nil, NewNotifier(db, rdb, nil, zap.NewNop()), config.AgentConfig{}, zap.NewNop())
+}
+
+func TestReportOOMRecordsEventAndNotifiesOwner(t *testing.T) {
+	db := dbtest.Open(t, &entities.Server{}, &entities.SystemEvent{}, &entities.Notification{}, &entities.NotificationPreference{})
+	c := newTestCollector(t, db)
+
+	server := &entities.Server{ID: uuid.New(), Name: "survival", NodeID: uuid.New(), OwnerID: uuid.New(), MemoryLimit: 2048, Status: entities.ServerStatusRunning}
+	if err := db.Create(server).Error; err != nil {
+		t.Fatal(err)
+	}
+	oom := &services.OOMKill{KilledAt: time.Now(), MemoryLimit: 2048 << 20, MemoryUsage: 2040 << 20}
+
+	// The kill is seen on every poll until the server is started again, and
+	// reported once
+	c.reportOOM(context.Background(), server, oom)
+	c.reportOOM(context.Background(), server, oom)
+
+	var status entities.ServerStatus
+	if err := db.Model(&entities.Server{}).Select("status").Where("id = ?", server.ID).Scan(&status).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status != entities.ServerStatusError {
+		t.Errorf("status = %s, want %s", status, entities.ServerStatusError)
+	}
+
+	var events []struct {
+		EventType string
+		Severity  string
+		Data      string
+	}
+	if err := db.Model(&entities.SystemEvent{}).Where("server_id = ?", server.ID).Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != entities.EventServerOOM || events[0].Severity != "warning" {
+		t.Fatalf("system events %+v, want one out of memory warning", events)
+	}
+	var data struct {
+		MemoryLimit int64 `json:"memory_limit"`
+		MemoryUsage int64 `json:"memory_usage"`
+	}
+	if err := json.Unmarshal([]byte(events[0].Data), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.MemoryLimit != 2048 || data.MemoryUsage != 2040 {
+		t.Errorf("event data %+v, want the limit and last usage in MB", data)
+	}
+
+	var notifications []struct {
+		UserID uuid.UUID
+		Type   string
+	}
+	if err := db.Model(&entities.Notification{}).Find(&notifications).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || notifications[0].UserID != server.OwnerID || notifications[0].Type != entities.NotificationServerOOM {
+		t.Errorf("notifications %+v, want one out of memory notification for the owner", notifications)
+	}
+}
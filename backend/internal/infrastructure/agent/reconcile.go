@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrNotOnNode is returned when a server's node does not know about it
+var ErrNotOnNode = errors.New("server not found on its node")
+
+// StatusDrift is a server whose stored status was corrected from its node
+type StatusDrift struct {
+	ServerID uuid.UUID             `json:"server_id"`
+	From     entities.ServerStatus `json:"from"`
+	To       entities.ServerStatus `json:"to"`
+}
+
+// StatusReconciler corrects stored server statuses that no longer match what
+// their nodes report, such as after a missed event or an optimistic update
+type StatusReconciler struct {
+	client *Client
+	db     *gorm.DB
+	hooks  *ServerWebhooks
+	config config.AgentConfig
+	logger *zap.Logger
+}
+
+// NewStatusReconciler creates a new StatusReconciler
+func NewStatusReconciler(client *Client, db *gorm.DB, hooks *ServerWebhooks, cfg config.AgentConfig, log *zap.Logger) *StatusReconciler {
+	return &StatusReconciler{
+		client: client,
+		db:     db,
+		hooks:  hooks,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Start reconciles the servers of online nodes until the context is cancelled
+func (r *StatusReconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcileAll(ctx)
+		}
+	}
+}
+
+func (r *StatusReconciler) reconcileAll(ctx context.Context) {
+	var nodes []entities.Node
+	if err := r.db.WithContext(ctx).
+		Where("is_online = ? AND deleted_at IS NULL", true).
+		Find(&nodes).Error; err != nil {
+		r.logger.Warn("Failed to load nodes for status reconciliation", zap.Error(err))
+		return
+	}
+
+	for _, node := range nodes {
+		drifts, err := r.ReconcileNode(ctx, node.ID)
+		if err != nil {
+			r.logger.Debug("Failed to reconcile node", zap.String("node_id", node.ID.String()), zap.Error(err))
+			continue
+		}
+		if len(drifts) > 0 {
+			r.logger.Info("Corrected server statuses", zap.String("node_id", node.ID.String()), zap.Int("servers", len(drifts)))
+		}
+	}
+}
+
+// ReconcileNode corrects the servers of a node. Servers changed within the
+// grace period are skipped, as a power action may still be under way.
+func (r *StatusReconciler) ReconcileNode(ctx context.Context, nodeID uuid.UUID) ([]StatusDrift, error) {
+	statuses, err := r.client.GetServerStatuses(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []entities.Server
+	if err := r.db.WithContext(ctx).
+		Where("node_id = ? AND updated_at < ?", nodeID, time.Now().Add(-r.config.ReconcileGrace)).
+		Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	var drifts []StatusDrift
+	for i := range servers {
+		reported, ok := statuses[servers[i].ID.String()]
+		if !ok {
+			continue
+		}
+		drift, err := r.apply(ctx, &servers[i], reported)
+		if err != nil {
+			return drifts, err
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+	return drifts, nil
+}
+
+// ReconcileServer corrects a single server right away. It returns nil when
+// the stored status already matched.
+func (r *StatusReconciler) ReconcileServer(ctx context.Context, server *entities.Server) (*StatusDrift, error) {
+	statuses, err := r.client.GetServerStatuses(ctx, server.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	reported, ok := statuses[server.ID.String()]
+	if !ok {
+		return nil, ErrNotOnNode
+	}
+	return r.apply(ctx, server, reported)
+}
+
+// apply stores the reported status of a server and records the drift. The
+// update only goes through while the stored status is unchanged, so a power
+// action racing the reconciler wins.
+func (r *StatusReconciler) apply(ctx context.Context, server *entities.Server, reported string) (*StatusDrift, error) {
+	status, drifted := services.ReconcileStatus(server.Status, reported)
+	if !drifted {
+		return nil, nil
+	}
+
+	drift := &StatusDrift{ServerID: server.ID, From: server.Status, To: status}
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entities.Server{}).
+			Where("id = ? AND status = ?", server.ID, server.Status).
+			Update("status", status)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+
+		return tx.Create(&entities.SystemEvent{
+			NodeID:    &server.NodeID,
+			ServerID:  &server.ID,
+			EventType: entities.EventStatusDrift,
+			Severity:  "warning",
+			Message:   fmt.Sprintf("Server %s was stored as %s but its node reports %s", server.Name, drift.From, reported),
+			Data: map[string]interface{}{
+				"server_id":   server.ID,
+				"server_name": server.Name,
+				"from":        drift.From,
+				"to":          drift.To,
+				"reported":    reported,
+			},
+		}).Error
+	})
+	if err != nil || !applied {
+		return nil, err
+	}
+
+	server.Status = status
+	if event := services.ServerTransition(drift.From, status); event != "" {
+		r.hooks.Notify(server, event, map[string]interface{}{"from": drift.From, "to": status})
+	}
+	return drift, nil
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestReconcileCorrectsStoppedServer(t *testing.T) {
+	db := dbtest.Open(t, &entities.Node{}, &entities.Server{}, &entities.SystemEvent{}, &entities.ServerWebhook{})
+
+	statuses := map[string]string{}
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"statuses": statuses})
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	settled := time.Now().Add(-time.Hour)
+	server := func(name string, status entities.ServerStatus, reported string, updatedAt time.Time) *entities.Server {
+		s := &entities.Server{ID: uuid.New(), UUID: name, Name: name, NodeID: node.ID, OwnerID: uuid.New(), Status: status}
+		if err := db.Create(s).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(s).UpdateColumn("updated_at", updatedAt).Error; err != nil {
+			t.Fatal(err)
+		}
+		if reported != "" {
+			statuses[s.ID.String()] = reported
+		}
+		return s
+	}
+	stopped := server("stopped", entities.ServerStatusRunning, "exited", settled)
+	running := server("running", entities.ServerStatusRunning, "running", settled)
+	// Just started from the panel, the container may not be up yet
+	starting := server("starting", entities.ServerStatusRunning, "exited", time.Now())
+	suspended := server("suspended", entities.ServerStatusSuspended, "exited", settled)
+	missing := server("missing", entities.ServerStatusRunning, "", settled)
+
+	cfg := config.AgentConfig{RequestTimeout: 5 * time.Second, ReconcileGrace: time.Minute}
+	reconciler := NewStatusReconciler(NewClient(cfg, db), db, NewServerWebhooks(db, config.WebhookConfig{}, zap.NewNop()), cfg, zap.NewNop())
+
+	drifts, err := reconciler.ReconcileNode(context.Background(), node.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 1 || drifts[0] != (StatusDrift{ServerID: stopped.ID, From: entities.ServerStatusRunning, To: entities.ServerStatusStopped}) {
+		t.Errorf("drifts %+v, want the stopped server corrected", drifts)
+	}
+
+	for s, want := range map[*entities.Server]entities.ServerStatus{
+		stopped:   entities.ServerStatusStopped,
+		running:   entities.ServerStatusRunning,
+		starting:  entities.ServerStatusRunning,
+		suspended: entities.ServerStatusSuspended,
+		missing:   entities.ServerStatusRunning,
+	} {
+		var status entities.ServerStatus
+		if err := db.Model(&entities.Server{}).Select("status").Where("id = ?", s.ID).Scan(&status).Error; err != nil {
+			t.Fatal(err)
+		}
+		if status != want {
+			t.Errorf("%s = %s, want %s", s.Name, status, want)
+		}
+	}
+
+	var events []struct {
+		ServerID  string
+		EventType string
+	}
+	if err := db.Model(&entities.SystemEvent{}).Select("server_id", "event_type").Scan(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ServerID != stopped.ID.String() || events[0].EventType != entities.EventStatusDrift {
+		t.Errorf("events %+v, want one drift of the stopped server", events)
+	}
+}
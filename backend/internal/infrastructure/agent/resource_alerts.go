@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// resourceAlerts is the alert state of one server's resources
+type resourceAlerts struct {
+	cpu    services.AlertState
+	memory services.AlertState
+}
+
+// checkResourceAlerts evaluates a server's stats against its alert
+// thresholds, reporting alerts that fire or resolve
+func (c *StatsCollector) checkResourceAlerts(ctx context.Context, server *entities.Server, stats *services.ServerStats) {
+	thresholds := server.ResourceAlerts
+	serverID := server.ID.String()
+	if thresholds.CPU == 0 && thresholds.Memory == 0 {
+		delete(c.alerts, serverID)
+		return
+	}
+
+	state := c.alerts[serverID]
+	if state == nil {
+		state = &resourceAlerts{}
+		c.alerts[serverID] = state
+	}
+
+	now := time.Now()
+	duration := time.Duration(thresholds.Duration) * time.Second
+	if thresholds.CPU > 0 {
+		usage := services.CPUPercentOfLimit(stats.CPUUsage, server.CPULimit)
+		if t := state.cpu.Observe(usage, thresholds.CPU, c.config.AlertMargin, duration, now); t != services.AlertUnchanged {
+			c.reportResourceAlert(ctx, server, "CPU", usage, thresholds.CPU, t)
+		}
+	} else {
+		state.cpu = services.AlertState{}
+	}
+	if thresholds.Memory > 0 {
+		if t := state.memory.Observe(stats.MemoryPercent, thresholds.Memory, c.config.AlertMargin, duration, now); t != services.AlertUnchanged {
+			c.reportResourceAlert(ctx, server, "memory", stats.MemoryPercent, thresholds.Memory, t)
+		}
+	} else {
+		state.memory = services.AlertState{}
+	}
+}
+
+// reportResourceAlert records a system event and notifies the owner of a
+// server that an alert fired or resolved
+func (c *StatsCollector) reportResourceAlert(ctx context.Context, server *entities.Server, resource string, usage float64, threshold int, transition services.AlertTransition) {
+	duration := time.Duration(server.ResourceAlerts.Duration) * time.Second
+	data := map[string]interface{}{
+		"server_id":   server.ID,
+		"server_name": server.Name,
+		"resource":    resource,
+		"usage":       usage,
+		"threshold":   threshold,
+		"duration":    server.ResourceAlerts.Duration,
+	}
+
+	event := &entities.SystemEvent{
+		NodeID:    &server.NodeID,
+		ServerID:  &server.ID,
+		EventType: entities.EventResourceHigh,
+		Severity:  "warning",
+		Message:   fmt.Sprintf("Server %s has used over %d%% %s for %s", server.Name, threshold, resource, duration),
+		Data:      data,
+	}
+	notification := &entities.Notification{
+		UserID:  server.OwnerID,
+		Type:    entities.NotificationResourceHigh,
+		Title:   fmt.Sprintf("Server %s usage is high", resource),
+		Message: fmt.Sprintf("Your server %s has used over %d%% of its %s for %s. Consider raising its limit.", server.Name, threshold, resource, duration),
+		Data:    data,
+	}
+	if transition == services.AlertResolved {
+		event.EventType = entities.EventResourceOK
+		event.Severity = "info"
+		event.Message = fmt.Sprintf("Server %s %s usage is back under %d%%", server.Name, resource, threshold)
+		notification.Type = entities.NotificationResourceRecovered
+		notification.Title = fmt.Sprintf("Server %s usage is back to normal", resource)
+		notification.Message = fmt.Sprintf("Your server %s is using %.0f%% of its %s again.", server.Name, usage, resource)
+	}
+
+	var delivery NotificationDelivery
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		var err error
+		delivery, err = c.notify.Create(tx, notification)
+		return err
+	})
+	if err != nil {
+		c.logger.Warn("Failed to report resource alert", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return
+	}
+	c.notify.Deliver(ctx, server.ID, notification, delivery)
+
+	c.logger.Info("Server resource alert",
+		zap.String("server_id", server.ID.String()),
+		zap.String("resource", resource),
+		zap.String("event", event.EventType),
+		zap.Float64("usage", usage),
+	)
+}
@@ -0,0 +1,308 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultRolloutSettle is how long a server recreated on a new image is given
+// to come up before its health is checked, unless configured
+const defaultRolloutSettle = 30 * time.Second
+
+// ImageRollouts moves the servers of an egg onto a new image: the image is
+// pulled on their nodes, then each server is recreated on it and rolled back
+// to its previous image if it does not come up
+type ImageRollouts struct {
+	client *Client
+	db     *gorm.DB
+	config config.AgentConfig
+	logger *zap.Logger
+}
+
+// NewImageRollouts creates a new ImageRollouts
+func NewImageRollouts(client *Client, db *gorm.DB, cfg config.AgentConfig, log *zap.Logger) *ImageRollouts {
+	return &ImageRollouts{
+		client: client,
+		db:     db,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Run carries out a rollout. Servers on nodes that fail to pull the image are
+// left on their current one. Immediate rollouts restart running servers onto
+// the image, starting one every StaggerSeconds with at most Concurrency in
+// flight; stopped servers and next restart rollouts are switched and verified
+// by Confirm when the server next starts.
+func (r *ImageRollouts) Run(ctx context.Context, rolloutID uuid.UUID) error {
+	var rollout entities.ImageRollout
+	if err := r.db.WithContext(ctx).Preload("Servers").Where("id = ?", rolloutID).First(&rollout).Error; err != nil {
+		return err
+	}
+
+	r.setStatus(ctx, &rollout, entities.RolloutPulling, nil)
+	pending := r.pull(ctx, &rollout)
+	r.setStatus(ctx, &rollout, entities.RolloutRunning, nil)
+
+	concurrency := rollout.Concurrency
+	if concurrency <= 0 {
+		concurrency = r.config.BulkConcurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	stagger := time.Duration(rollout.StaggerSeconds) * time.Second
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, entry := range pending {
+		if i > 0 && stagger > 0 && rollout.Mode == entities.RolloutImmediate {
+			select {
+			case <-ctx.Done():
+			case <-time.After(stagger):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(entry *entities.ImageRolloutServer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.upgrade(ctx, &rollout, entry)
+		}(entry)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		r.setStatus(context.Background(), &rollout, entities.RolloutFailed, err)
+		return err
+	}
+	r.setStatus(ctx, &rollout, entities.RolloutFinished, nil)
+	return nil
+}
+
+// FailInterrupted marks the rollouts and server upgrades left in progress by
+// a previous run of the panel as failed, so they no longer block new rollouts.
+// Servers already switched keep their new image; the previous one is kept on
+// their entry.
+func (r *ImageRollouts) FailInterrupted(ctx context.Context) {
+	interrupted := func(status string) map[string]interface{} {
+		return map[string]interface{}{
+			"status":      status,
+			"error":       "Interrupted by a panel restart",
+			"finished_at": time.Now(),
+		}
+	}
+	if err := r.db.WithContext(ctx).Model(&entities.ImageRolloutServer{}).
+		Where("status = ?", entities.RolloutServerPending).
+		Updates(interrupted(entities.RolloutServerFailed)).Error; err != nil {
+		r.logger.Warn("Failed to fail interrupted rollout servers", zap.Error(err))
+	}
+	if err := r.db.WithContext(ctx).Model(&entities.ImageRollout{}).
+		Where("status IN ?", []string{entities.RolloutPending, entities.RolloutPulling, entities.RolloutRunning}).
+		Updates(interrupted(entities.RolloutFailed)).Error; err != nil {
+		r.logger.Warn("Failed to fail interrupted rollouts", zap.Error(err))
+	}
+}
+
+// pull pulls the rollout's image on every node it touches and returns the
+// servers whose node has it. The servers of nodes that fail are marked failed.
+func (r *ImageRollouts) pull(ctx context.Context, rollout *entities.ImageRollout) []*entities.ImageRolloutServer {
+	byNode := make(map[uuid.UUID][]*entities.ImageRolloutServer)
+	for i := range rollout.Servers {
+		entry := &rollout.Servers[i]
+		byNode[entry.NodeID] = append(byNode[entry.NodeID], entry)
+	}
+
+	concurrency := r.config.WarmupConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	pending := make([]*entities.ImageRolloutServer, 0, len(rollout.Servers))
+
+	for nodeID, entries := range byNode {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(nodeID uuid.UUID, entries []*entities.ImageRolloutServer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := r.client.PullImage(ctx, nodeID, rollout.Image); err != nil {
+				r.logger.Warn("Failed to pull rollout image",
+					zap.String("node_id", nodeID.String()),
+					zap.String("image", rollout.Image),
+					zap.Error(err),
+				)
+				for _, entry := range entries {
+					r.finish(ctx, entry, entities.RolloutServerFailed, fmt.Errorf("pull image: %w", err))
+				}
+				return
+			}
+
+			mu.Lock()
+			pending = append(pending, entries...)
+			mu.Unlock()
+		}(nodeID, entries)
+	}
+	wg.Wait()
+	return pending
+}
+
+// upgrade switches one server to the rollout's image, restarting it onto the
+// image right away when the rollout is immediate and the server is running
+func (r *ImageRollouts) upgrade(ctx context.Context, rollout *entities.ImageRollout, entry *entities.ImageRolloutServer) {
+	var server entities.Server
+	if err := r.db.WithContext(ctx).Where("id = ?", entry.ServerID).First(&server).Error; err != nil {
+		r.finish(ctx, entry, entities.RolloutServerFailed, services.ErrServerNotFound)
+		return
+	}
+
+	if err := r.switchImage(ctx, &server, rollout.Image); err != nil {
+		r.finish(ctx, entry, entities.RolloutServerFailed, err)
+		return
+	}
+	if rollout.Mode != entities.RolloutImmediate || !server.IsRunning() {
+		r.finish(ctx, entry, entities.RolloutServerScheduled, nil)
+		return
+	}
+
+	if err := r.client.RestartServer(ctx, server.NodeID, server.ID); err != nil {
+		r.rollback(ctx, &server, entry, err)
+		return
+	}
+	r.verify(ctx, &server, entry)
+}
+
+// Confirm verifies a server that was started or restarted while switched to
+// a rollout's image, rolling it back if it does not come up. Servers without
+// a scheduled rollout are left alone.
+func (r *ImageRollouts) Confirm(ctx context.Context, serverID uuid.UUID) {
+	var entry entities.ImageRolloutServer
+	if err := r.db.WithContext(ctx).
+		Where("server_id = ? AND status = ?", serverID, entities.RolloutServerScheduled).
+		Order("created_at DESC").First(&entry).Error; err != nil {
+		return
+	}
+
+	// Claim the entry so a second start does not verify it twice
+	claim := r.db.WithContext(ctx).Model(&entry).
+		Where("status = ?", entities.RolloutServerScheduled).
+		Update("status", entities.RolloutServerPending)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var server entities.Server
+	if err := r.db.WithContext(ctx).Where("id = ?", serverID).First(&server).Error; err != nil {
+		r.finish(ctx, &entry, entities.RolloutServerFailed, services.ErrServerNotFound)
+		return
+	}
+	r.verify(ctx, &server, &entry)
+}
+
+// verify waits for a server recreated on the new image to settle and rolls it
+// back unless it is healthy
+func (r *ImageRollouts) verify(ctx context.Context, server *entities.Server, entry *entities.ImageRolloutServer) {
+	settle := r.config.RolloutSettle
+	if settle <= 0 {
+		settle = defaultRolloutSettle
+	}
+	select {
+	case <-ctx.Done():
+		r.finish(context.Background(), entry, entities.RolloutServerFailed, ctx.Err())
+		return
+	case <-time.After(settle):
+	}
+
+	stats, err := r.client.GetServerStatus(ctx, server.NodeID, server.ID)
+	if err == nil {
+		err = services.CheckRolloutHealth(stats)
+	}
+	if err != nil {
+		r.rollback(ctx, server, entry, err)
+		return
+	}
+	r.finish(ctx, entry, entities.RolloutServerUpgraded, nil)
+}
+
+// rollback moves a server that failed to upgrade back to its previous image
+// and restarts it
+func (r *ImageRollouts) rollback(ctx context.Context, server *entities.Server, entry *entities.ImageRolloutServer, cause error) {
+	r.logger.Warn("Rolling back server image",
+		zap.String("server_id", server.ID.String()),
+		zap.String("image", entry.PreviousImage),
+		zap.Error(cause),
+	)
+
+	err := r.switchImage(ctx, server, entry.PreviousImage)
+	if err == nil {
+		err = r.client.RestartServer(ctx, server.NodeID, server.ID)
+	}
+	if err != nil {
+		r.finish(ctx, entry, entities.RolloutServerFailed, fmt.Errorf("%v; rollback failed: %w", cause, err))
+		return
+	}
+	r.finish(ctx, entry, entities.RolloutServerRolledBack, cause)
+}
+
+// switchImage points a server at an image, on its node and in the database.
+// The node recreates the container on its next start or restart.
+func (r *ImageRollouts) switchImage(ctx context.Context, server *entities.Server, image string) error {
+	if err := r.client.UpdateServerImage(ctx, server.NodeID, server.ID, image); err != nil {
+		return err
+	}
+	server.DockerImage = image
+	return r.db.WithContext(ctx).Model(server).Update("docker_image", image).Error
+}
+
+func (r *ImageRollouts) finish(ctx context.Context, entry *entities.ImageRolloutServer, status string, err error) {
+	entry.Status = status
+	entry.Error = ""
+	if err != nil {
+		entry.Error = truncate(err.Error(), 500)
+	}
+	updates := map[string]interface{}{
+		"status": entry.Status,
+		"error":  entry.Error,
+	}
+	if status != entities.RolloutServerScheduled {
+		now := time.Now()
+		entry.FinishedAt = &now
+		updates["finished_at"] = entry.FinishedAt
+	}
+	if err := r.db.WithContext(ctx).Model(entry).Updates(updates).Error; err != nil {
+		r.logger.Warn("Failed to record rollout progress", zap.String("server_id", entry.ServerID.String()), zap.Error(err))
+	}
+}
+
+func (r *ImageRollouts) setStatus(ctx context.Context, rollout *entities.ImageRollout, status string, err error) {
+	rollout.Status = status
+	updates := map[string]interface{}{"status": status}
+	if err != nil {
+		rollout.Error = truncate(err.Error(), 500)
+		updates["error"] = rollout.Error
+	}
+	if !rollout.Active() {
+		now := time.Now()
+		rollout.FinishedAt = &now
+		updates["finished_at"] = rollout.FinishedAt
+	}
+	if err := r.db.WithContext(ctx).Model(rollout).Updates(updates).Error; err != nil {
+		r.logger.Warn("Failed to record rollout status", zap.String("rollout_id", rollout.ID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ServerWebhooks delivers the state transitions of a server to the webhooks
+// its owner registered for it, and logs every delivery for the owner to see
+type ServerWebhooks struct {
+	db         *gorm.DB
+	httpClient *http.Client
+	config     config.WebhookConfig
+	logger     *zap.Logger
+}
+
+// NewServerWebhooks creates a new ServerWebhooks. Owners choose the URLs, so
+// deliveries only ever connect to public addresses.
+func NewServerWebhooks(db *gorm.DB, cfg config.WebhookConfig, log *zap.Logger) *ServerWebhooks {
+	return &ServerWebhooks{
+		db:         db,
+		httpClient: services.NewWebhookHTTPClient(cfg.Timeout),
+		config:     cfg,
+		logger:     log,
+	}
+}
+
+// Notify delivers a transition of a server to its subscribed webhooks in the
+// background, so retries never hold up the caller
+func (w *ServerWebhooks) Notify(server *entities.Server, event string, data map[string]interface{}) {
+	at := time.Now()
+	go w.deliver(context.Background(), *server, event, data, at)
+}
+
+func (w *ServerWebhooks) deliver(ctx context.Context, server entities.Server, event string, data map[string]interface{}, at time.Time) {
+	var webhooks []entities.ServerWebhook
+	if err := w.db.WithContext(ctx).Where("server_id = ? AND is_active = ?", server.ID, true).Find(&webhooks).Error; err != nil {
+		w.logger.Warn("Failed to load server webhooks", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return
+	}
+
+	var body []byte
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = services.BuildServerWebhookPayload(event, &server, data, at); err != nil {
+				w.logger.Warn("Failed to build server webhook payload", zap.String("event", event), zap.Error(err))
+				return
+			}
+		}
+
+		started := time.Now()
+		result := services.SendWebhook(ctx, w.httpClient, w.config, webhook.URL, webhook.Secret, event, body)
+		delivery := &entities.ServerWebhookDelivery{
+			WebhookID:  webhook.ID,
+			ServerID:   server.ID,
+			Event:      event,
+			Success:    result.Err == nil,
+			Attempts:   result.Attempts,
+			StatusCode: result.StatusCode,
+			Duration:   time.Since(started).Milliseconds(),
+		}
+		if result.Err != nil {
+			delivery.Error = truncate(result.Err.Error(), 500)
+			w.logger.Debug("Server webhook delivery failed",
+				zap.String("webhook_id", webhook.ID.String()),
+				zap.String("event", event),
+				zap.Error(result.Err),
+			)
+		}
+		if err := w.db.WithContext(ctx).Create(delivery).Error; err != nil {
+			w.logger.Warn("Failed to log server webhook delivery", zap.String("webhook_id", webhook.ID.String()), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StatsKey returns the Redis key and Pub/Sub channel for a server's stats
+func StatsKey(serverID string) string {
+	return "stats:" + serverID
+}
+
+// StoppedStats is the sample published for a server that is no longer active,
+// so stats subscribers see it halt
+const StoppedStats = `{"status":"stopped"}`
+
+// EventsKey returns the Pub/Sub channel for notifications about a server
+func EventsKey(serverID string) string {
+	return "events:" + serverID
+}
+
+// StatsCollector polls agents for the stats of active servers and caches them
+type StatsCollector struct {
+	client *Client
+	db     *gorm.DB
+	rdb    *redis.Client
+	hooks  *ServerWebhooks
+	notify *Notifier
+	config config.AgentConfig
+	logger *zap.Logger
+	last   map[string]string
+	health map[string]string // Last reported container health per server
+	alerts map[string]*resourceAlerts
+}
+
+// NewStatsCollector creates a new StatsCollector
+func NewStatsCollector(client *Client, db *gorm.DB, rdb *redis.Client, hooks *ServerWebhooks, notifier *Notifier, cfg config.AgentConfig, log *zap.Logger) *StatsCollector {
+	return &StatsCollector{
+		client: client,
+		db:     db,
+		rdb:    rdb,
+		hooks:  hooks,
+		notify: notifier,
+		config: cfg,
+		logger: log,
+		last:   make(map[string]string),
+		health: make(map[string]string),
+		alerts: make(map[string]*resourceAlerts),
+	}
+}
+
+// Start polls agents until the context is cancelled
+func (c *StatsCollector) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.config.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.collect(ctx)
+		}
+	}
+}
+
+// collect fetches and publishes stats for every running server
+func (c *StatsCollector) collect(ctx context.Context) {
+	var servers []entities.Server
+	if err := c.db.WithContext(ctx).
+		Where("status IN ? AND deleted_at IS NULL", []entities.ServerStatus{entities.ServerStatusRunning, entities.ServerStatusStarting, entities.ServerStatusStopping}).
+		Find(&servers).Error; err != nil {
+		c.logger.Warn("Failed to load servers for stats collection", zap.Error(err))
+		return
+	}
+
+	seen := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		serverID := server.ID.String()
+		seen[serverID] = true
+
+		stats, err := c.client.GetServerStatus(ctx, server.NodeID, server.ID)
+		if err != nil {
+			c.logger.Debug("Failed to fetch server stats", zap.String("server_id", serverID), zap.Error(err))
+			continue
+		}
+
+		if stats.OOMKill != nil {
+			c.reportOOM(ctx, &server, stats.OOMKill)
+		}
+		if stats.Crash != nil && stats.Crash.GaveUp {
+			c.reportCrash(ctx, &server, stats.Crash)
+		}
+		if stats.Health == HealthUnhealthy && c.health[serverID] != HealthUnhealthy {
+			c.reportUnhealthy(ctx, &server, c.health[serverID])
+		}
+		c.health[serverID] = stats.Health
+		// Usage while a server shuts down says nothing about its limits
+		if server.Status == entities.ServerStatusStopping {
+			delete(c.alerts, serverID)
+		} else {
+			c.checkResourceAlerts(ctx, &server, stats)
+		}
+
+		data, err := json.Marshal(stats)
+		if err != nil {
+			continue
+		}
+		payload := string(data)
+
+		if err := c.rdb.Set(ctx, StatsKey(serverID), payload, c.config.StatsTTL); err != nil {
+			c.logger.Warn("Failed to cache server stats", zap.String("server_id", serverID), zap.Error(err))
+		}
+
+		// Only notify subscribers when the sample actually changed
+		if c.last[serverID] == payload {
+			continue
+		}
+		c.last[serverID] = payload
+
+		if err := c.rdb.Publish(ctx, StatsKey(serverID), payload); err != nil {
+			c.logger.Warn("Failed to publish server stats", zap.String("server_id", serverID), zap.Error(err))
+		}
+	}
+
+	// Drop samples and alert state of servers that are no longer active,
+	// stopped or deleted, telling their subscribers they stopped
+	for serverID := range c.last {
+		if !seen[serverID] {
+			if err := c.rdb.Set(ctx, StatsKey(serverID), StoppedStats, c.config.StatsTTL); err != nil {
+				c.logger.Warn("Failed to cache server stats", zap.String("server_id", serverID), zap.Error(err))
+			}
+			if err := c.rdb.Publish(ctx, StatsKey(serverID), StoppedStats); err != nil {
+				c.logger.Warn("Failed to publish server stats", zap.String("server_id", serverID), zap.Error(err))
+			}
+			delete(c.last, serverID)
+			delete(c.health, serverID)
+		}
+	}
+	for serverID := range c.alerts {
+		if !seen[serverID] {
+			delete(c.alerts, serverID)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"go.uber.org/zap"
+)
+
+// HealthUnhealthy is the container health of a server failing its healthcheck
+const HealthUnhealthy = "unhealthy"
+
+// reportUnhealthy records a system event for a running server whose egg
+// healthcheck started failing. The server is left running, an unhealthy game
+// may still recover on its own.
+func (c *StatsCollector) reportUnhealthy(ctx context.Context, server *entities.Server, previous string) {
+	err := c.db.WithContext(ctx).Create(&entities.SystemEvent{
+		NodeID:    &server.NodeID,
+		ServerID:  &server.ID,
+		EventType: entities.EventUnhealthy,
+		Severity:  "warning",
+		Message:   fmt.Sprintf("Server %s is failing its healthcheck", server.Name),
+		Data: map[string]interface{}{
+			"server_id":   server.ID,
+			"server_name": server.Name,
+			"previous":    previous,
+		},
+	}).Error
+	if err != nil {
+		c.logger.Warn("Failed to record unhealthy server", zap.String("server_id", server.ID.String()), zap.Error(err))
+		return
+	}
+
+	c.logger.Info("Server is unhealthy", zap.String("server_id", server.ID.String()))
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Image pull policies for warmup
+const (
+	PullIfNotPresent = "if_not_present"
+	PullAlways       = "always"
+)
+
+// Warmup states
+const (
+	WarmupRunning  = "running"
+	WarmupFinished = "finished"
+	WarmupFailed   = "failed"
+)
+
+// ErrWarmupRunning is returned when a node is already warming up
+var ErrWarmupRunning = errors.New("image warmup already running")
+
+// warmupLockTTL bounds how long a crashed warmup can block the next one
+const warmupLockTTL = time.Hour
+
+// WarmupKey returns the Redis key of a node's warmup progress
+func WarmupKey(nodeID string) string {
+	return "warmup:" + nodeID
+}
+
+func warmupLockKey(nodeID string) string {
+	return "warmup:lock:" + nodeID
+}
+
+// WarmupProgress is the progress of a node's image warmup
+type WarmupProgress struct {
+	Status     string            `json:"status"`
+	Images     int               `json:"images"`  // Images the node's servers use
+	Pending    int               `json:"pending"` // Images selected for pulling
+	Pulled     int               `json:"pulled"`
+	Failed     map[string]string `json:"failed,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// ImageWarmer pre-pulls the images of a node's servers so their first start
+// does not wait on a download
+type ImageWarmer struct {
+	client *Client
+	db     *gorm.DB
+	rdb    *redis.Client
+	config config.AgentConfig
+	logger *zap.Logger
+}
+
+// NewImageWarmer creates a new ImageWarmer
+func NewImageWarmer(client *Client, db *gorm.DB, rdb *redis.Client, cfg config.AgentConfig, log *zap.Logger) *ImageWarmer {
+	return &ImageWarmer{
+		client: client,
+		db:     db,
+		rdb:    rdb,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Start warms up every opted-in node on the configured interval until the
+// context is cancelled
+func (w *ImageWarmer) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.WarmupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.warmAll(ctx)
+		}
+	}
+}
+
+func (w *ImageWarmer) warmAll(ctx context.Context) {
+	var nodes []entities.Node
+	if err := w.db.WithContext(ctx).
+		Where("image_warmup = ? AND is_online = ? AND deleted_at IS NULL", true, true).
+		Find(&nodes).Error; err != nil {
+		w.logger.Warn("Failed to load nodes for image warmup", zap.Error(err))
+		return
+	}
+
+	for _, node := range nodes {
+		if err := w.Warm(ctx, node.ID); err != nil && !errors.Is(err, ErrWarmupRunning) {
+			w.logger.Warn("Image warmup failed", zap.String("node_id", node.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// Warm pulls the images of the servers on a node, skipping the ones already
+// present unless the pull policy is "always". At most WarmupConcurrency pulls
+// run at once. Progress is kept under WarmupKey while it runs.
+func (w *ImageWarmer) Warm(ctx context.Context, nodeID uuid.UUID) error {
+	lock := warmupLockKey(nodeID.String())
+	acquired, err := w.rdb.Underlying().SetNX(ctx, lock, 1, warmupLockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrWarmupRunning
+	}
+	defer w.rdb.Delete(context.Background(), lock)
+
+	progress := &WarmupProgress{Status: WarmupRunning, StartedAt: time.Now()}
+	var mu sync.Mutex
+	report := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = w.rdb.SetJSON(ctx, WarmupKey(nodeID.String()), progress, 0)
+	}
+
+	fail := func(err error) error {
+		now := time.Now()
+		progress.Status = WarmupFailed
+		progress.Error = err.Error()
+		progress.FinishedAt = &now
+		report()
+		return err
+	}
+
+	var images []string
+	if err := w.db.WithContext(ctx).Model(&entities.Server{}).
+		Where("node_id = ? AND docker_image <> ''", nodeID).
+		Distinct().Pluck("docker_image", &images).Error; err != nil {
+		return fail(err)
+	}
+	progress.Images = len(images)
+
+	pending := images
+	if w.config.PullPolicy != PullAlways {
+		present, err := w.client.ListImages(ctx, nodeID)
+		if err != nil {
+			return fail(err)
+		}
+		pending = MissingImages(images, present)
+	}
+	progress.Pending = len(pending)
+	report()
+
+	concurrency := w.config.WarmupConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, image := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(image string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := w.client.PullImage(ctx, nodeID, image)
+
+			mu.Lock()
+			if err != nil {
+				if progress.Failed == nil {
+					progress.Failed = make(map[string]string)
+				}
+				progress.Failed[image] = err.Error()
+			} else {
+				progress.Pulled++
+			}
+			mu.Unlock()
+			report()
+		}(image)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	progress.Status = WarmupFinished
+	progress.FinishedAt = &now
+	report()
+	return nil
+}
+
+// MissingImages returns the images that are not in present. References
+// without a tag are compared as ":latest", as Docker resolves them.
+func MissingImages(images, present []string) []string {
+	have := make(map[string]bool, len(present))
+	for _, image := range present {
+		have[normalizeImage(image)] = true
+	}
+
+	missing := make([]string, 0, len(images))
+	for _, image := range images {
+		if !have[normalizeImage(image)] {
+			missing = append(missing, image)
+		}
+	}
+	return missing
+}
+
+// normalizeImage adds the implicit latest tag to a reference without tag or digest
+func normalizeImage(image string) string {
+	name := image[strings.LastIndexByte(image, '/')+1:]
+	if strings.ContainsAny(name, ":@") {
+		return image
+	}
+	return image + ":latest"
+}
+
+// Running reports whether a warmup of the node is in progress
+func (w *ImageWarmer) Running(ctx context.Context, nodeID uuid.UUID) (bool, error) {
+	return w.rdb.Exists(ctx, warmupLockKey(nodeID.String()))
+}
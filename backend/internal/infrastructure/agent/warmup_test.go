@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// warmupAgent is a node agent with present images, recording the pulls it
+// is asked for and how many ran at once
+type warmupAgent struct {
+	present []string
+
+	mu       sync.Mutex
+	pulled   []string
+	inFlight int
+	peak     int
+}
+
+func (a *warmupAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/images":
+		_ = json.NewEncoder(w).Encode(map[string][]string{"images": a.present})
+	case "/api/images/pull":
+		var req struct {
+			Image string `json:"image"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		a.mu.Lock()
+		a.pulled = append(a.pulled, req.Image)
+		a.inFlight++
+		a.peak = max(a.peak, a.inFlight)
+		a.mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		a.mu.Lock()
+		a.inFlight--
+		a.mu.Unlock()
+		_, _ = w.Write([]byte(`{"success":true}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newTestWarmer returns an ImageWarmer for a node served by agent, whose
+// servers use images
+func newTestWarmer(t *testing.T, agent *warmupAgent, cfg config.AgentConfig, images ...string) (*ImageWarmer, *redis.Client, uuid.UUID) {
+	t.Helper()
+	db := dbtest.Open(t, &entities.Node{}, &entities.Server{})
+
+	daemon := httptest.NewServer(agent)
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort, ImageWarmup: true}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, image := range images {
+		server := &entities.Server{ID: uuid.New(), UUID: "server-" + strconv.Itoa(i), Name: "server-" + strconv.Itoa(i), NodeID: node.ID, OwnerID: uuid.New(), DockerImage: image}
+		if err := db.Create(server).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A server on another node
+	if err := db.Create(&entities.Server{ID: uuid.New(), UUID: "elsewhere", Name: "elsewhere", NodeID: uuid.New(), OwnerID: uuid.New(), DockerImage: "other:1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisPort, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: redisPort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg.RequestTimeout = 5 * time.Second
+	return NewImageWarmer(NewClient(cfg, db), db, rdb, cfg, zap.NewNop()), rdb, node.ID
+}
+
+func TestWarmupPullsOnlyMissingImages(t *testing.T) {
+	agent := &warmupAgent{present: []string{"ghcr.io/example/game:1", "itzg/minecraft-server:latest"}}
+	cfg := config.AgentConfig{PullPolicy: PullIfNotPresent, WarmupConcurrency: 2}
+	warmer, rdb, nodeID := newTestWarmer(t, agent, cfg,
+		"ghcr.io/example/game:1", "ghcr.io/example/game:1", "itzg/minecraft-server", "ghcr.io/example/game:2", "steamcmd/steamcmd")
+
+	if err := warmer.Warm(context.Background(), nodeID); err != nil {
+		t.Fatal(err)
+	}
+
+	// An untagged reference is the latest tag the node already has
+	slices.Sort(agent.pulled)
+	if want := []string{"ghcr.io/example/game:2", "steamcmd/steamcmd"}; !slices.Equal(agent.pulled, want) {
+		t.Errorf("pulled %q, want only the missing %q", agent.pulled, want)
+	}
+
+	var progress WarmupProgress
+	if err := rdb.GetJSON(context.Background(), WarmupKey(nodeID.String()), &progress); err != nil {
+		t.Fatal(err)
+	}
+	if progress.Status != WarmupFinished || progress.Images != 4 || progress.Pending != 2 || progress.Pulled != 2 || len(progress.Failed) != 0 {
+		t.Errorf("progress %+v, want 2 of 4 images pulled", progress)
+	}
+	if running, _ := warmer.Running(context.Background(), nodeID); running {
+		t.Error("warmup still marked running")
+	}
+}
+
+func TestWarmupBoundsConcurrentPulls(t *testing.T) {
+	images := []string{"game:1", "game:2", "game:3", "game:4", "game:5", "game:6", "game:7"}
+	for _, concurrency := range []int{1, 3} {
+		agent := &warmupAgent{}
+		cfg := config.AgentConfig{PullPolicy: PullAlways, WarmupConcurrency: concurrency}
+		warmer, _, nodeID := newTestWarmer(t, agent, cfg, images...)
+
+		if err := warmer.Warm(context.Background(), nodeID); err != nil {
+			t.Fatal(err)
+		}
+		if len(agent.pulled) != len(images) {
+			t.Errorf("concurrency %d: pulled %q, want every image", concurrency, agent.pulled)
+		}
+		if agent.peak != concurrency {
+			t.Errorf("concurrency %d: %d pulls ran at once", concurrency, agent.peak)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -10,15 +12,23 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Security SecurityConfig `mapstructure:"security"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	Mail     MailConfig     `mapstructure:"mail"`
-	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	App       AppConfig       `mapstructure:"app"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Mail      MailConfig      `mapstructure:"mail"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Webhooks  WebhookConfig   `mapstructure:"webhooks"`
+	Agents    AgentConfig     `mapstructure:"agents"`
+	Billing   BillingConfig   `mapstructure:"billing"`
+	Placement PlacementConfig `mapstructure:"placement"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Console   ConsoleConfig   `mapstructure:"console"`
+	Chat      ChatConfig      `mapstructure:"chat"`
+	Retention RetentionConfig `mapstructure:"retention"`
 }
 
 // AppConfig holds application-specific configuration
@@ -28,7 +38,27 @@ type AppConfig struct {
 	Environment string `mapstructure:"environment"` // development, staging, production
 	Debug       bool   `mapstructure:"debug"`
 	URL         string `mapstructure:"url"`
-	Timezone    string `mapstructure:"timezone"`
+	Timezone    string `mapstructure:"timezone"` // IANA zone schedules without their own are read in
+
+	location *time.Location
+}
+
+// Location returns the panel's timezone. Times are stored and returned in
+// UTC, the zone only gives wall clock schedules their meaning.
+func (c *AppConfig) Location() *time.Location {
+	if c.location == nil {
+		return time.UTC
+	}
+	return c.location
+}
+
+func (c *AppConfig) resolveLocation() error {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid app timezone %q: %w", c.Timezone, err)
+	}
+	c.location = loc
+	return nil
 }
 
 // ServerConfig holds HTTP server configuration
@@ -47,7 +77,7 @@ type ServerConfig struct {
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
 	Enabled          bool     `mapstructure:"enabled"`
-	AllowOrigins     []string `mapstructure:"allow_origins"`
+	AllowOrigins     []string `mapstructure:"allow_origins"` // Exact origins or subdomain patterns like https://*.example.com; defaults to app.url
 	AllowMethods     []string `mapstructure:"allow_methods"`
 	AllowHeaders     []string `mapstructure:"allow_headers"`
 	ExposeHeaders    []string `mapstructure:"expose_headers"`
@@ -55,6 +85,20 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// validate rejects a wildcard origin together with credentials, which browsers
+// refuse and which would let any site make authenticated requests
+func (c CORSConfig) validate() error {
+	if !c.Enabled || !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			return errors.New("server.cors.allow_origins cannot contain \"*\" when allow_credentials is enabled, list the allowed origins instead")
+		}
+	}
+	return nil
+}
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Host            string        `mapstructure:"host"`
@@ -98,15 +142,72 @@ func (c RedisConfig) Addr() string {
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret           string        `mapstructure:"secret"`
+	Secret           string        `mapstructure:"secret"`      // Verifies tokens without a kid, and signs when no keys are set
+	Keys             []JWTKey      `mapstructure:"keys"`        // Signing keys by kid, for rotation
+	CurrentKey       string        `mapstructure:"current_key"` // Kid of the key new tokens are signed with
 	AccessExpiry     time.Duration `mapstructure:"access_expiry"`
 	RefreshExpiry    time.Duration `mapstructure:"refresh_expiry"`
+	RefreshReuseGrace time.Duration `mapstructure:"refresh_reuse_grace"` // How long a rotated refresh token may be retried
 	Issuer           string        `mapstructure:"issuer"`
 	Audience         string        `mapstructure:"audience"`
 	CookieName       string        `mapstructure:"cookie_name"`
 	CookieSecure     bool          `mapstructure:"cookie_secure"`
 	CookieHTTPOnly   bool          `mapstructure:"cookie_http_only"`
 	CookieSameSite   string        `mapstructure:"cookie_same_site"`
+	ImpersonationExpiry time.Duration `mapstructure:"impersonation_expiry"` // Lifetime of support impersonation tokens
+}
+
+// JWTKey is a JWT signing key. To rotate, add a new key and make it current;
+// tokens signed with the old one keep verifying until they expire, after
+// which the old key can be retired or removed.
+type JWTKey struct {
+	ID         string `mapstructure:"id"`
+	Secret     string `mapstructure:"secret"`
+	SecretFile string `mapstructure:"secret_file"` // Read the secret from a file, e.g. a mounted secret
+	Retired    bool   `mapstructure:"retired"`     // Tokens signed with a retired key are rejected
+}
+
+// LegacyJWTKeyID is the kid of the plain jwt.secret, which also verifies
+// tokens issued before key IDs were introduced
+const LegacyJWTKeyID = "default"
+
+// resolveKeys reads key secrets from their files and checks that the current
+// key can sign
+func (c *JWTConfig) resolveKeys() error {
+	if len(c.Keys) == 0 {
+		c.CurrentKey = LegacyJWTKeyID
+		return nil
+	}
+
+	ids := make(map[string]bool, len(c.Keys))
+	for i := range c.Keys {
+		key := &c.Keys[i]
+		if key.ID == "" {
+			return fmt.Errorf("jwt key %d has no id", i)
+		}
+		if ids[key.ID] {
+			return fmt.Errorf("jwt key %q is defined twice", key.ID)
+		}
+		ids[key.ID] = true
+
+		if key.SecretFile != "" {
+			data, err := os.ReadFile(key.SecretFile)
+			if err != nil {
+				return fmt.Errorf("failed to read jwt key %q: %w", key.ID, err)
+			}
+			key.Secret = strings.TrimSpace(string(data))
+		}
+		if key.Secret == "" {
+			return fmt.Errorf("jwt key %q has no secret", key.ID)
+		}
+		if key.ID == c.CurrentKey && key.Retired {
+			return fmt.Errorf("jwt current key %q is retired", key.ID)
+		}
+	}
+	if !ids[c.CurrentKey] {
+		return fmt.Errorf("jwt current key %q is not defined", c.CurrentKey)
+	}
+	return nil
 }
 
 // SecurityConfig holds security configuration
@@ -123,6 +224,8 @@ type SecurityConfig struct {
 	RateLimitRequests    int           `mapstructure:"rate_limit_requests"`
 	RateLimitDuration    time.Duration `mapstructure:"rate_limit_duration"`
 	EncryptionKey        string        `mapstructure:"encryption_key"` // 32 bytes for AES-256
+	RequireEmailVerification bool          `mapstructure:"require_email_verification"` // New accounts stay pending until their email is verified
+	EmailVerificationExpiry  time.Duration `mapstructure:"email_verification_expiry"`
 }
 
 // StorageConfig holds storage configuration
@@ -170,6 +273,128 @@ type MetricsConfig struct {
 	Prometheus bool   `mapstructure:"prometheus"`
 }
 
+// WebhookConfig holds outbound webhook delivery configuration
+type WebhookConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxRetries   int           `mapstructure:"max_retries"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"` // Initial backoff, doubled per attempt
+	Username     string        `mapstructure:"username"`      // Display name used in chat payloads
+	Workers      int           `mapstructure:"workers"`       // Parallel deliveries of system events
+	QueueSize    int           `mapstructure:"queue_size"`    // Events waiting for a worker before new ones are dropped
+}
+
+// AgentConfig holds node agent communication configuration
+type AgentConfig struct {
+	RequestTimeout     time.Duration `mapstructure:"request_timeout"` // Default for calls whose class has no timeout
+	Timeouts           AgentTimeouts `mapstructure:"timeouts"`
+	Insecure           bool          `mapstructure:"insecure"` // Skip TLS verification for self-signed agents
+	StatsInterval      time.Duration `mapstructure:"stats_interval"`
+	StatsTTL           time.Duration `mapstructure:"stats_ttl"`
+	BulkConcurrency    int           `mapstructure:"bulk_concurrency"`     // Max parallel agent calls for bulk actions
+	PullPolicy         string        `mapstructure:"pull_policy"`          // "if_not_present" or "always", for image warmup
+	WarmupInterval     time.Duration `mapstructure:"warmup_interval"`      // How often images are pre-pulled on opted-in nodes
+	WarmupConcurrency  int           `mapstructure:"warmup_concurrency"`   // Max parallel image pulls per node
+	RolloutSettle      time.Duration `mapstructure:"rollout_settle"`       // How long a server moved to a new image has to come up before its health is checked
+	ReconcileInterval  time.Duration `mapstructure:"reconcile_interval"`   // How often stored server statuses are checked against nodes
+	ReconcileGrace     time.Duration `mapstructure:"reconcile_grace"`      // Servers changed more recently are skipped
+	HealthInterval     time.Duration `mapstructure:"health_interval"`      // How often nodes are polled for health and certificate status
+	CertWarnBefore     time.Duration `mapstructure:"cert_warn_before"`     // Warn when a node certificate expires within this
+	BackupScanInterval time.Duration `mapstructure:"backup_scan_interval"` // How often stored backups are checked against their checksums
+	BackupScanSample   int           `mapstructure:"backup_scan_sample"`   // Backups verified per scan, least recently verified first
+	AlertMargin        int           `mapstructure:"alert_margin"`         // Percentage points usage has to drop under a threshold before its alert resolves
+	PidsLimit          int64         `mapstructure:"pids_limit"`           // Processes and threads per server container, unless its egg sets one
+	NoFile             int64         `mapstructure:"nofile"`               // Open files per server process, unless its egg sets one
+	NProc              int64         `mapstructure:"nproc"`                // Processes per container user, 0 leaves it to the node
+}
+
+// AgentTimeouts bounds agent calls by operation class, so a hung agent
+// cannot hold a request open
+type AgentTimeouts struct {
+	Query   time.Duration `mapstructure:"query"`   // Stats, chat, listings
+	Power   time.Duration `mapstructure:"power"`   // Power actions and console commands
+	Update  time.Duration `mapstructure:"update"`  // Pushing startup, image, registries and limits
+	Install time.Duration `mapstructure:"install"` // Reinstalls
+	Backup  time.Duration `mapstructure:"backup"`  // Creating and restoring backups
+	Pull    time.Duration `mapstructure:"pull"`    // Image pulls
+}
+
+// BillingConfig holds billing and credit configuration
+type BillingConfig struct {
+	ResellerTransfersOwnOnly bool          `mapstructure:"reseller_transfers_own_only"` // Resellers may only transfer to their sub-accounts
+	TransferLimit            int           `mapstructure:"transfer_limit"`              // Max transfers per user per window
+	TransferWindow           time.Duration `mapstructure:"transfer_window"`
+	SubscriptionInterval     time.Duration `mapstructure:"subscription_interval"` // How often subscriptions are renewed, suspended and expired
+	RenewalNotice            time.Duration `mapstructure:"renewal_notice"`        // How long before renewal users are notified
+	InvoiceCacheTTL          time.Duration `mapstructure:"invoice_cache_ttl"`     // How long rendered invoice PDFs are cached
+	Company                  CompanyConfig `mapstructure:"company"`
+	Tax                      TaxConfig     `mapstructure:"tax"`
+}
+
+// TaxConfig holds tax rates applied to invoices
+type TaxConfig struct {
+	Enabled       bool               `mapstructure:"enabled"`
+	Inclusive     bool               `mapstructure:"inclusive"`      // Prices already include tax
+	DefaultRate   float64            `mapstructure:"default_rate"`   // Percent, for countries without a rate
+	Rates         map[string]float64 `mapstructure:"rates"`          // Percent by country ("de") or country and region ("us-ca")
+	ReverseCharge bool               `mapstructure:"reverse_charge"` // Foreign customers with a VAT number pay no tax
+}
+
+// CompanyConfig holds the seller details printed on invoices
+type CompanyConfig struct {
+	Name    string `mapstructure:"name"`
+	Address string `mapstructure:"address"` // One line per address line
+	Email   string `mapstructure:"email"`
+	Website string `mapstructure:"website"`
+	TaxID   string `mapstructure:"tax_id"`
+	Country string `mapstructure:"country"` // ISO 3166-1 alpha-2, customers here are never reverse charged
+}
+
+// PlacementConfig holds automatic node placement configuration
+type PlacementConfig struct {
+	Strategy string `mapstructure:"strategy"` // most_free, binpack, round_robin
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"` // OTLP/HTTP collector host:port
+	Insecure    bool    `mapstructure:"insecure"` // Send to the collector without TLS
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRate  float64 `mapstructure:"sample_rate"` // Fraction of new traces recorded, 0-1
+}
+
+// ConsoleConfig holds limits for console WebSocket connections
+type ConsoleConfig struct {
+	InputRate        float64       `mapstructure:"input_rate"`         // Commands per second allowed per connection
+	InputBurst       int           `mapstructure:"input_burst"`        // Commands that may be sent at once before throttling
+	MaxCommandLength int           `mapstructure:"max_command_length"` // Bytes
+	OutputBuffer     int           `mapstructure:"output_buffer"`      // Lines queued for a slow client before dropping
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	MaxViolations    int           `mapstructure:"max_violations"` // Rejected commands before the connection is closed
+	HistorySize      int           `mapstructure:"history_size"`   // Commands remembered per user and server
+	HistoryTTL       time.Duration `mapstructure:"history_ttl"`
+}
+
+// ChatConfig holds settings for collecting in-game chat from node agents
+type ChatConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// RetentionConfig holds how long each log type is kept before it is purged.
+// A retention of 0 keeps logs forever; retentions shorter than Floor are
+// ignored so a typo cannot wipe a table.
+type RetentionConfig struct {
+	Interval          time.Duration `mapstructure:"interval"`
+	Floor             time.Duration `mapstructure:"floor"`
+	ChatLogs          time.Duration `mapstructure:"chat_logs"`
+	CommandLogs       time.Duration `mapstructure:"command_logs"`
+	DeathLogs         time.Duration `mapstructure:"death_logs"`
+	AuditLogs         time.Duration `mapstructure:"audit_logs"`
+	WebhookDeliveries time.Duration `mapstructure:"webhook_deliveries"`
+	Notifications     time.Duration `mapstructure:"notifications"` // Read notifications only, unread ones are kept
+}
+
 // Load loads configuration from file and environment
 func Load() (*Config, error) {
 	v := viper.New()
@@ -204,6 +429,20 @@ func Load() (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := cfg.JWT.resolveKeys(); err != nil {
+		return nil, err
+	}
+	if err := cfg.App.resolveLocation(); err != nil {
+		return nil, err
+	}
+
+	// Each environment serves its own panel URL unless told otherwise
+	if len(cfg.Server.CORS.AllowOrigins) == 0 {
+		cfg.Server.CORS.AllowOrigins = []string{strings.TrimRight(cfg.App.URL, "/")}
+	}
+	if err := cfg.Server.CORS.validate(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
@@ -227,7 +466,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.shutdown_timeout", "30s")
 	v.SetDefault("server.body_limit", 50)
 	v.SetDefault("server.cors.enabled", true)
-	v.SetDefault("server.cors.allow_origins", []string{"*"})
+	v.SetDefault("server.cors.allow_origins", []string{})
 	v.SetDefault("server.cors.allow_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
 	v.SetDefault("server.cors.allow_headers", []string{"Origin", "Content-Type", "Accept", "Authorization"})
 	v.SetDefault("server.cors.allow_credentials", true)
@@ -260,12 +499,14 @@ func setDefaults(v *viper.Viper) {
 	// JWT defaults
 	v.SetDefault("jwt.access_expiry", "15m")
 	v.SetDefault("jwt.refresh_expiry", "7d")
+	v.SetDefault("jwt.refresh_reuse_grace", "30s")
 	v.SetDefault("jwt.issuer", "aether-panel")
 	v.SetDefault("jwt.audience", "aether-panel")
 	v.SetDefault("jwt.cookie_name", "aether_token")
 	v.SetDefault("jwt.cookie_secure", false)
 	v.SetDefault("jwt.cookie_http_only", true)
 	v.SetDefault("jwt.cookie_same_site", "lax")
+	v.SetDefault("jwt.impersonation_expiry", "30m")
 
 	// Security defaults
 	v.SetDefault("security.password_min_length", 8)
@@ -279,6 +520,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("security.two_factor_issuer", "Aether Panel")
 	v.SetDefault("security.rate_limit_requests", 100)
 	v.SetDefault("security.rate_limit_duration", "1m")
+	v.SetDefault("security.require_email_verification", false)
+	v.SetDefault("security.email_verification_expiry", "24h")
 
 	// Storage defaults
 	v.SetDefault("storage.driver", "local")
@@ -298,4 +541,85 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("metrics.path", "/metrics")
 	v.SetDefault("metrics.port", 9090)
 	v.SetDefault("metrics.prometheus", true)
+
+	// Webhook defaults
+	v.SetDefault("webhooks.enabled", true)
+	v.SetDefault("webhooks.timeout", "10s")
+	v.SetDefault("webhooks.max_retries", 3)
+	v.SetDefault("webhooks.retry_backoff", "2s")
+	v.SetDefault("webhooks.username", "Aether Panel")
+	v.SetDefault("webhooks.workers", 4)
+	v.SetDefault("webhooks.queue_size", 256)
+
+	// Agent defaults
+	v.SetDefault("agents.request_timeout", "15s")
+	v.SetDefault("agents.timeouts.query", "10s")
+	v.SetDefault("agents.timeouts.power", "30s")
+	v.SetDefault("agents.timeouts.update", "15s")
+	v.SetDefault("agents.timeouts.install", "1m")
+	v.SetDefault("agents.timeouts.backup", "10m")
+	v.SetDefault("agents.timeouts.pull", "15m")
+	v.SetDefault("agents.insecure", false)
+	v.SetDefault("agents.stats_interval", "2s")
+	v.SetDefault("agents.stats_ttl", "30s")
+	v.SetDefault("agents.bulk_concurrency", 10)
+	v.SetDefault("agents.pull_policy", "if_not_present")
+	v.SetDefault("agents.warmup_interval", "6h")
+	v.SetDefault("agents.warmup_concurrency", 2)
+	v.SetDefault("agents.rollout_settle", "30s")
+	v.SetDefault("agents.reconcile_interval", "1m")
+	v.SetDefault("agents.reconcile_grace", "30s")
+	v.SetDefault("agents.health_interval", "30s")
+	v.SetDefault("agents.cert_warn_before", "336h")
+	v.SetDefault("agents.backup_scan_interval", "6h")
+	v.SetDefault("agents.backup_scan_sample", 20)
+	v.SetDefault("agents.alert_margin", 10)
+	v.SetDefault("agents.pids_limit", 1024)
+	v.SetDefault("agents.nofile", 65536)
+	v.SetDefault("agents.nproc", 0)
+
+	// Billing defaults
+	v.SetDefault("billing.reseller_transfers_own_only", true)
+	v.SetDefault("billing.transfer_limit", 5)
+	v.SetDefault("billing.transfer_window", "1h")
+	v.SetDefault("billing.subscription_interval", "24h")
+	v.SetDefault("billing.renewal_notice", "72h")
+	v.SetDefault("billing.invoice_cache_ttl", "24h")
+	v.SetDefault("billing.company.name", "Aether Panel")
+	v.SetDefault("billing.tax.enabled", false)
+	v.SetDefault("billing.tax.inclusive", false)
+	v.SetDefault("billing.tax.default_rate", 0)
+	v.SetDefault("billing.tax.reverse_charge", true)
+
+	// Placement defaults
+	v.SetDefault("placement.strategy", "most_free")
+
+	// Tracing defaults
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "aether-panel")
+	v.SetDefault("tracing.sample_rate", 1.0)
+
+	v.SetDefault("console.input_rate", 5.0)
+	v.SetDefault("console.input_burst", 10)
+	v.SetDefault("console.max_command_length", 1024)
+	v.SetDefault("console.output_buffer", 256)
+	v.SetDefault("console.write_timeout", "10s")
+	v.SetDefault("console.max_violations", 20)
+	v.SetDefault("console.history_size", 50)
+	v.SetDefault("console.history_ttl", "720h")
+
+	// Chat defaults
+	v.SetDefault("chat.poll_interval", "5s")
+
+	// Log retention defaults
+	v.SetDefault("retention.interval", "1h")
+	v.SetDefault("retention.floor", "24h")
+	v.SetDefault("retention.chat_logs", "2160h")
+	v.SetDefault("retention.command_logs", "2160h")
+	v.SetDefault("retention.death_logs", "2160h")
+	v.SetDefault("retention.audit_logs", "8760h")
+	v.SetDefault("retention.webhook_deliveries", "720h")
+	v.SetDefault("retention.notifications", "720h")
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCORSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    CORSConfig
+		wantErr bool
+	}{
+		{"wildcard with credentials", CORSConfig{Enabled: true, AllowCredentials: true, AllowOrigins: []string{"https://panel.example.com", "*"}}, true},
+		{"wildcard without credentials", CORSConfig{Enabled: true, AllowOrigins: []string{"*"}}, false},
+		{"wildcard while disabled", CORSConfig{AllowCredentials: true, AllowOrigins: []string{"*"}}, false},
+		{"listed origins with credentials", CORSConfig{Enabled: true, AllowCredentials: true, AllowOrigins: []string{"https://*.example.com"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cors.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// loadFile runs Load on a config file with the given contents. The refresh
+// expiry is set as in config.example.yaml.
+func loadFile(t *testing.T, contents string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	contents = "jwt:\n  refresh_expiry: 168h\n" + contents
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AETHER_CONFIG_PATH", dir)
+	return Load()
+}
+
+func TestLoadRefusesWildcardOriginsWithCredentials(t *testing.T) {
+	_, err := loadFile(t, `
+server:
+  cors:
+    allow_origins: ["*"]
+    allow_credentials: true
+`)
+	if err == nil || !strings.Contains(err.Error(), "allow_origins") {
+		t.Fatalf("Load() = %v, want the wildcard origin refused", err)
+	}
+}
+
+func TestLoadDefaultsOriginsToAppURL(t *testing.T) {
+	cfg, err := loadFile(t, `
+app:
+  url: https://panel.example.com/
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Server.CORS.AllowOrigins; len(got) != 1 || got[0] != "https://panel.example.com" {
+		t.Errorf("allow_origins = %q, want the app URL", got)
+	}
+}
+
+func TestValidateRequiresJWTSecret(t *testing.T) {
+	for _, tc := range []struct {
+		secret, want string
+	}{
+		{"", "jwt.secret is required unless jwt.keys are set"},
+		{"too-short", "jwt.secret must be at least 32 characters, got 9"},
+		{"0123456789abcdef0123456789abcdef", ""},
+	} {
+		cfg, err := loadFile(t, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.JWT.Secret = tc.secret
+		err = cfg.Validate()
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("secret %q: Validate() = %v, want the defaults accepted", tc.secret, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "\n"+tc.want) {
+			t.Errorf("secret %q: Validate() = %v, want %q", tc.secret, err, tc.want)
+		}
+	}
+}
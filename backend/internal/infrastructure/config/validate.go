@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// minSecretLength is the shortest JWT signing secret accepted, 256 bits for
+// HS256
+const minSecretLength = 32
+
+// encryptionKeyLength is the length of the AES-256 encryption key in bytes
+const encryptionKeyLength = 32
+
+// problems collects every configuration problem so they are reported at once
+type problems []error
+
+func (p *problems) addf(format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf(format, args...))
+}
+
+func (p *problems) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		p.addf("%s must be one of %s, got %q", key, strings.Join(allowed, ", "), value)
+	}
+}
+
+func (p *problems) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		p.addf("%s must be between 1 and 65535, got %d", key, port)
+	}
+}
+
+func (p *problems) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		p.addf("%s is required", key)
+	}
+}
+
+// Validate checks the configuration for missing secrets, unknown enum values
+// and out of range numbers, so a bad config stops the panel at startup rather
+// than failing confusingly at runtime. The returned error lists every problem
+// found, one per line.
+func (c *Config) Validate() error {
+	var p problems
+
+	p.oneOf("app.environment", c.App.Environment, "development", "staging", "production")
+	p.required("app.url", c.App.URL)
+
+	p.port("server.port", c.Server.Port)
+	if c.Server.BodyLimit < 1 {
+		p.addf("server.body_limit must be at least 1 MB, got %d", c.Server.BodyLimit)
+	}
+
+	p.required("database.host", c.Database.Host)
+	p.port("database.port", c.Database.Port)
+	p.required("database.user", c.Database.User)
+	p.required("database.name", c.Database.Name)
+	p.oneOf("database.ssl_mode", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	p.oneOf("database.log_level", c.Database.LogLevel, "silent", "error", "warn", "info")
+	if c.Database.MaxOpenConns < 1 {
+		p.addf("database.max_open_conns must be at least 1, got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		p.addf("database.max_idle_conns must be between 0 and database.max_open_conns, got %d", c.Database.MaxIdleConns)
+	}
+
+	p.required("redis.host", c.Redis.Host)
+	p.port("redis.port", c.Redis.Port)
+	if c.Redis.DB < 0 {
+		p.addf("redis.db must not be negative, got %d", c.Redis.DB)
+	}
+	if c.Redis.PoolSize < 1 {
+		p.addf("redis.pool_size must be at least 1, got %d", c.Redis.PoolSize)
+	}
+
+	// Keys are resolved by Load, so their secrets are read by now
+	if len(c.JWT.Keys) == 0 {
+		if c.JWT.Secret == "" {
+			p.addf("jwt.secret is required unless jwt.keys are set")
+		} else if len(c.JWT.Secret) < minSecretLength {
+			p.addf("jwt.secret must be at least %d characters, got %d", minSecretLength, len(c.JWT.Secret))
+		}
+	}
+	for _, key := range c.JWT.Keys {
+		if key.Secret != "" && len(key.Secret) < minSecretLength {
+			p.addf("jwt key %q secret must be at least %d characters, got %d", key.ID, minSecretLength, len(key.Secret))
+		}
+	}
+	if c.JWT.AccessExpiry <= 0 {
+		p.addf("jwt.access_expiry must be positive")
+	}
+	if c.JWT.RefreshExpiry <= 0 {
+		p.addf("jwt.refresh_expiry must be positive")
+	}
+	p.oneOf("jwt.cookie_same_site", strings.ToLower(c.JWT.CookieSameSite), "strict", "lax", "none")
+
+	// Registry and database host passwords are encrypted with the key
+	switch {
+	case c.Security.EncryptionKey == "" && c.App.Environment == "production":
+		p.addf("security.encryption_key is required in production")
+	case c.Security.EncryptionKey != "" && len(c.Security.EncryptionKey) != encryptionKeyLength:
+		p.addf("security.encryption_key must be exactly %d bytes, got %d", encryptionKeyLength, len(c.Security.EncryptionKey))
+	}
+	if c.Security.PasswordMinLength < 1 {
+		p.addf("security.password_min_length must be at least 1, got %d", c.Security.PasswordMinLength)
+	}
+	if c.Security.RateLimitRequests < 1 {
+		p.addf("security.rate_limit_requests must be at least 1, got %d", c.Security.RateLimitRequests)
+	}
+	if c.Security.RateLimitDuration <= 0 {
+		p.addf("security.rate_limit_duration must be positive")
+	}
+
+	p.oneOf("storage.driver", c.Storage.Driver, "local", "s3")
+	if c.Storage.Driver == "s3" {
+		p.required("storage.s3.bucket", c.Storage.S3.Bucket)
+		p.required("storage.s3.access_key_id", c.Storage.S3.AccessKeyID)
+		p.required("storage.s3.secret_access_key", c.Storage.S3.SecretAccessKey)
+	}
+
+	p.oneOf("mail.driver", c.Mail.Driver, "smtp", "sendgrid", "mailgun")
+	p.oneOf("mail.encryption", c.Mail.Encryption, "tls", "ssl", "none")
+	if c.Mail.Host != "" {
+		p.port("mail.port", c.Mail.Port)
+	}
+
+	if c.Metrics.Enabled {
+		p.port("metrics.port", c.Metrics.Port)
+	}
+	if c.Webhooks.MaxRetries < 0 {
+		p.addf("webhooks.max_retries must not be negative, got %d", c.Webhooks.MaxRetries)
+	}
+
+	p.oneOf("agents.pull_policy", c.Agents.PullPolicy, "if_not_present", "always")
+	if c.Agents.StatsInterval <= 0 {
+		p.addf("agents.stats_interval must be positive")
+	}
+	if c.Agents.BulkConcurrency < 1 {
+		p.addf("agents.bulk_concurrency must be at least 1, got %d", c.Agents.BulkConcurrency)
+	}
+
+	p.oneOf("placement.strategy", c.Placement.Strategy, "most_free", "binpack", "round_robin")
+
+	if c.Tracing.SampleRate < 0 || c.Tracing.SampleRate > 1 {
+		p.addf("tracing.sample_rate must be between 0 and 1, got %g", c.Tracing.SampleRate)
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(p...))
+}
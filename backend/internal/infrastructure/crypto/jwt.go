@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"errors"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey        = errors.New("token signed with an unknown key")
+	ErrRetiredKey        = errors.New("token signed with a retired key")
+	ErrUnexpectedSigning = errors.New("unexpected signing method")
+)
+
+// KeyRing signs JWTs with the current key and verifies them against any key
+// that is not retired, selected by the token's kid header
+type KeyRing struct {
+	current string
+	keys    map[string][]byte
+	retired map[string]bool
+}
+
+// NewKeyRing creates a KeyRing from the JWT configuration. The plain secret is
+// registered under LegacyJWTKeyID unless a key already uses that ID.
+func NewKeyRing(cfg config.JWTConfig) *KeyRing {
+	k := &KeyRing{
+		current: cfg.CurrentKey,
+		keys:    make(map[string][]byte, len(cfg.Keys)+1),
+		retired: make(map[string]bool),
+	}
+	if cfg.Secret != "" {
+		k.keys[config.LegacyJWTKeyID] = []byte(cfg.Secret)
+	}
+	for _, key := range cfg.Keys {
+		k.keys[key.ID] = []byte(key.Secret)
+		if key.Retired {
+			k.retired[key.ID] = true
+		}
+	}
+	return k
+}
+
+// Sign signs claims with the current key, naming it in the kid header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.current
+	return token.SignedString(k.keys[k.current])
+}
+
+// Keyfunc returns the key a token was signed with, for jwt.Parse. Tokens
+// without a kid predate key rotation and are checked against the legacy key.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, ErrUnexpectedSigning
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = config.LegacyJWTKeyID
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if k.retired[kid] {
+		return nil, ErrRetiredKey
+	}
+	return key, nil
+}
@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+}
+
+// verify parses a token with ring, returning the error of its key lookup
+func verify(ring *KeyRing, token string) error {
+	_, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, ring.Keyfunc)
+	return err
+}
+
+func TestKeyRingVerifiesPreviousKeyDuringOverlap(t *testing.T) {
+	before := NewKeyRing(config.JWTConfig{
+		Secret:     "legacy-secret",
+		Keys:       []config.JWTKey{{ID: "2026-01", Secret: "january-secret"}},
+		CurrentKey: "2026-01",
+	})
+	oldToken, err := before.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A token from before rotation was introduced has no kid
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new key is made current, the old one stays for the overlap
+	after := NewKeyRing(config.JWTConfig{
+		Secret: "legacy-secret",
+		Keys: []config.JWTKey{
+			{ID: "2026-01", Secret: "january-secret"},
+			{ID: "2026-02", Secret: "february-secret"},
+		},
+		CurrentKey: "2026-02",
+	})
+	newToken, err := after.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"previous key": oldToken, "current key": newToken, "no kid": legacyToken} {
+		if err := verify(after, token); err != nil {
+			t.Errorf("%s: %v, want it verified", name, err)
+		}
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != "2026-02" {
+		t.Errorf("new token kid = %v, want the current key", kid)
+	}
+	if err := verify(before, newToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of a key the ring lacks = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyRingRejectsRetiredKeys(t *testing.T) {
+	before := NewKeyRing(config.JWTConfig{
+		Keys:       []config.JWTKey{{ID: "2026-01", Secret: "january-secret"}},
+		CurrentKey: "2026-01",
+	})
+	oldToken, err := before.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring := NewKeyRing(config.JWTConfig{
+		Keys: []config.JWTKey{
+			{ID: "2026-01", Secret: "january-secret", Retired: true},
+			{ID: "2026-02", Secret: "february-secret"},
+		},
+		CurrentKey: "2026-02",
+	})
+	if err := verify(ring, oldToken); !errors.Is(err, ErrRetiredKey) {
+		t.Errorf("token of a retired key = %v, want %v", err, ErrRetiredKey)
+	}
+
+	// A token claiming the current kid but signed with the retired secret
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = "2026-02"
+	token, err := forged.SignedString([]byte("january-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(ring, token); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("token with a mismatched kid = %v, want an invalid signature", err)
+	}
+
+	// Signing methods other than HMAC are refused before any key is used
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(ring, none); !errors.Is(err, ErrUnexpectedSigning) {
+		t.Errorf("unsigned token = %v, want %v", err, ErrUnexpectedSigning)
+	}
+}
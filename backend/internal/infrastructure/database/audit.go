@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository implements repositories.AuditLogRepository
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, log *entities.AuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+func (r *AuditLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.AuditLog, error) {
+	var log entities.AuditLog
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&log).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+func (r *AuditLogRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.AuditLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.AuditLog{}), params)
+}
+
+func (r *AuditLogRepository) GetByUserID(ctx context.Context, userID uuid.UUID, params repositories.ListParams) ([]*entities.AuditLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.AuditLog{}).Where("user_id = ?", userID), params)
+}
+
+func (r *AuditLogRepository) GetByResource(ctx context.Context, resource string, resourceID uuid.UUID) ([]*entities.AuditLog, error) {
+	var logs []*entities.AuditLog
+	err := r.db.WithContext(ctx).
+		Where("resource = ? AND resource_id = ?", resource, resourceID).
+		Order("created_at DESC").
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *AuditLogRepository) GetByAction(ctx context.Context, action entities.AuditAction, params repositories.ListParams) ([]*entities.AuditLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.AuditLog{}).Where("action = ?", action), params)
+}
+
+func (r *AuditLogRepository) GetByDateRange(ctx context.Context, start, end time.Time, params repositories.ListParams) ([]*entities.AuditLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.AuditLog{}).Where("created_at BETWEEN ? AND ?", start, end), params)
+}
+
+func (r *AuditLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *AuditLogRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.AuditLog, int64, error) {
+	logs := make([]*entities.AuditLog, 0, params.PageSize)
+	total, err := Paginate(query, params, "created_at DESC", &logs)
+	return logs, total, err
+}
+
+// NotificationRepository implements repositories.NotificationRepository
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, notification *entities.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Notification, error) {
+	var notification entities.Notification
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&notification).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (r *NotificationRepository) GetByUserID(ctx context.Context, userID uuid.UUID, params repositories.ListParams) ([]*entities.Notification, int64, error) {
+	notifications := make([]*entities.Notification, 0, params.PageSize)
+	query := r.db.WithContext(ctx).Model(&entities.Notification{}).Where("user_id = ?", userID)
+	total, err := Paginate(query, params, "created_at DESC", &notifications)
+	return notifications, total, err
+}
+
+func (r *NotificationRepository) GetUnreadByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Notification, error) {
+	var notifications []*entities.Notification
+	err := r.db.WithContext(ctx).Where("user_id = ? AND is_read = ?", userID, false).Order("created_at DESC").Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *NotificationRepository) MarkAsRead(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Notification{}).
+		Where("id = ? AND is_read = ?", id, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()}).Error
+}
+
+func (r *NotificationRepository) MarkAllAsRead(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()}).Error
+}
+
+func (r *NotificationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Notification{}).Error
+}
+
+func (r *NotificationRepository) DeleteAllByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.Notification{}).Error
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&count).Error
+	return count, err
+}
+
+// ActivityLogRepository implements repositories.ActivityLogRepository
+type ActivityLogRepository struct {
+	db *gorm.DB
+}
+
+// NewActivityLogRepository creates a new ActivityLogRepository
+func NewActivityLogRepository(db *gorm.DB) *ActivityLogRepository {
+	return &ActivityLogRepository{db: db}
+}
+
+func (r *ActivityLogRepository) Create(ctx context.Context, log *entities.ActivityLog) error {
+	return r.db.WithContext(ctx).Omit("User").Create(log).Error
+}
+
+func (r *ActivityLogRepository) GetByUserID(ctx context.Context, userID uuid.UUID, params repositories.ListParams) ([]*entities.ActivityLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.ActivityLog{}).Where("user_id = ?", userID), params)
+}
+
+func (r *ActivityLogRepository) GetByServerID(ctx context.Context, serverID uuid.UUID, params repositories.ListParams) ([]*entities.ActivityLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.ActivityLog{}).Where("server_id = ?", serverID), params)
+}
+
+func (r *ActivityLogRepository) GetRecent(ctx context.Context, limit int) ([]*entities.ActivityLog, error) {
+	var logs []*entities.ActivityLog
+	err := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func (r *ActivityLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.ActivityLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ActivityLogRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.ActivityLog, int64, error) {
+	logs := make([]*entities.ActivityLog, 0, params.PageSize)
+	total, err := Paginate(query, params, "created_at DESC", &logs)
+	return logs, total, err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BackupRepository implements repositories.BackupRepository
+type BackupRepository struct {
+	db *gorm.DB
+}
+
+// NewBackupRepository creates a new BackupRepository
+func NewBackupRepository(db *gorm.DB) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+func (r *BackupRepository) Create(ctx context.Context, backup *entities.Backup) error {
+	return r.db.WithContext(ctx).Omit("Server").Create(backup).Error
+}
+
+func (r *BackupRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Backup, error) {
+	var backup entities.Backup
+	if err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("id = ?", id).First(&backup).Error; err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+func (r *BackupRepository) Update(ctx context.Context, backup *entities.Backup) error {
+	return r.db.WithContext(ctx).Omit("Server").Save(backup).Error
+}
+
+func (r *BackupRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return SoftDelete(r.db.WithContext(ctx), &entities.Backup{}, id)
+}
+
+func (r *BackupRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.Backup, error) {
+	var backups []*entities.Backup
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("server_id = ?", serverID).Order("created_at DESC").Find(&backups).Error
+	return backups, err
+}
+
+func (r *BackupRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.BackupStatus) error {
+	return r.db.WithContext(ctx).Model(&entities.Backup{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// CountByServerID counts the backups that take up one of a server's backup
+// slots, which failed backups do not
+func (r *BackupRepository) CountByServerID(ctx context.Context, serverID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Backup{}).Scopes(NotTrashed).
+		Where("server_id = ? AND status <> ?", serverID, entities.BackupStatusFailed).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *BackupRepository) GetOldestByServerID(ctx context.Context, serverID uuid.UUID, limit int) ([]*entities.Backup, error) {
+	var backups []*entities.Backup
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).
+		Where("server_id = ? AND is_locked = ?", serverID, false).
+		Order("created_at").
+		Limit(limit).
+		Find(&backups).Error
+	return backups, err
+}
+
+func (r *BackupRepository) Lock(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Backup{}).Where("id = ?", id).Update("is_locked", true).Error
+}
+
+func (r *BackupRepository) Unlock(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Backup{}).Where("id = ?", id).Update("is_locked", false).Error
+}
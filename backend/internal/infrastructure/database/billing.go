@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransactionRepository implements repositories.TransactionRepository
+type TransactionRepository struct {
+	db *gorm.DB
+}
+
+// NewTransactionRepository creates a new TransactionRepository
+func NewTransactionRepository(db *gorm.DB) *TransactionRepository {
+	return &TransactionRepository{db: db}
+}
+
+func (r *TransactionRepository) Create(ctx context.Context, tx *entities.Transaction) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(tx).Error
+}
+
+func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Transaction, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *TransactionRepository) GetByReference(ctx context.Context, reference string) (*entities.Transaction, error) {
+	return r.first(ctx, "reference = ?", reference)
+}
+
+func (r *TransactionRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Transaction, error) {
+	var tx entities.Transaction
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&tx).Error; err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *TransactionRepository) Update(ctx context.Context, tx *entities.Transaction) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(tx).Error
+}
+
+func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID, params repositories.ListParams) ([]*entities.Transaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Transaction{}).Where("user_id = ?", userID)
+	for _, key := range []string{"type", "status"} {
+		if value, ok := params.Filters[key]; ok {
+			query = query.Where(key+" = ?", value)
+		}
+	}
+
+	transactions := make([]*entities.Transaction, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "created_at", "amount"), &transactions)
+	return transactions, total, err
+}
+
+func (r *TransactionRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*entities.Transaction, error) {
+	var transactions []*entities.Transaction
+	err := r.db.WithContext(ctx).Where("created_at BETWEEN ? AND ?", start, end).Order("created_at").Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *TransactionRepository) SumByUserID(ctx context.Context, userID uuid.UUID, txType entities.TransactionType) (float64, error) {
+	var sum float64
+	err := r.db.WithContext(ctx).Model(&entities.Transaction{}).
+		Where("user_id = ? AND type = ? AND status = ?", userID, txType, entities.TransactionStatusCompleted).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (r *TransactionRepository) CountOutgoingTransfers(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Transaction{}).
+		Where("user_id = ? AND type = ? AND amount < 0 AND created_at >= ?", userID, entities.TransactionTypeTransfer, since).
+		Count(&count).Error
+	return count, err
+}
+
+// SubscriptionRepository implements repositories.SubscriptionRepository
+type SubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionRepository creates a new SubscriptionRepository
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+func (r *SubscriptionRepository) Create(ctx context.Context, sub *entities.Subscription) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(sub).Error
+}
+
+func (r *SubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Subscription, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *SubscriptionRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) (*entities.Subscription, error) {
+	return r.first(ctx, "server_id = ?", serverID)
+}
+
+func (r *SubscriptionRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Subscription, error) {
+	var sub entities.Subscription
+	if err := r.db.WithContext(ctx).Where(query, args...).Order("created_at DESC").First(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *SubscriptionRepository) Update(ctx context.Context, sub *entities.Subscription) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(sub).Error
+}
+
+// GetByUserID returns a user's subscriptions with their packages, which hold
+// the limits the subscriptions grant
+func (r *SubscriptionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Subscription, error) {
+	var subs []*entities.Subscription
+	err := r.db.WithContext(ctx).Preload("Package").Where("user_id = ?", userID).Order("end_date").Find(&subs).Error
+	return subs, err
+}
+
+func (r *SubscriptionRepository) GetActive(ctx context.Context) ([]*entities.Subscription, error) {
+	return r.find(ctx, "status = ?", entities.SubscriptionStatusActive)
+}
+
+func (r *SubscriptionRepository) GetExpiring(ctx context.Context, before time.Time) ([]*entities.Subscription, error) {
+	return r.find(ctx, "status = ? AND end_date < ?", entities.SubscriptionStatusActive, before)
+}
+
+func (r *SubscriptionRepository) GetExpired(ctx context.Context) ([]*entities.Subscription, error) {
+	return r.find(ctx, "status IN ? AND end_date < ?",
+		[]string{entities.SubscriptionStatusActive, entities.SubscriptionStatusCancelled}, time.Now())
+}
+
+func (r *SubscriptionRepository) find(ctx context.Context, query string, args ...interface{}) ([]*entities.Subscription, error) {
+	var subs []*entities.Subscription
+	err := r.db.WithContext(ctx).Where(query, args...).Order("end_date").Find(&subs).Error
+	return subs, err
+}
+
+func (r *SubscriptionRepository) Cancel(ctx context.Context, id uuid.UUID) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"status":            entities.SubscriptionStatusCancelled,
+		"cancelled_at":      time.Now(),
+		"next_billing_date": nil,
+	})
+}
+
+func (r *SubscriptionRepository) Suspend(ctx context.Context, id uuid.UUID) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"status":       entities.SubscriptionStatusSuspended,
+		"suspended_at": time.Now(),
+	})
+}
+
+func (r *SubscriptionRepository) Renew(ctx context.Context, id uuid.UUID, newEndDate time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"status":            entities.SubscriptionStatusActive,
+		"end_date":          newEndDate,
+		"next_billing_date": newEndDate,
+		"suspended_at":      nil,
+		"payment_failed_at": nil,
+	})
+}
+
+func (r *SubscriptionRepository) MarkPaymentFailed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{"payment_failed_at": at})
+}
+
+func (r *SubscriptionRepository) update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&entities.Subscription{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// InvoiceRepository implements repositories.InvoiceRepository
+type InvoiceRepository struct {
+	db *gorm.DB
+}
+
+// NewInvoiceRepository creates a new InvoiceRepository
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// Create creates an invoice together with its items
+func (r *InvoiceRepository) Create(ctx context.Context, invoice *entities.Invoice) error {
+	return r.db.WithContext(ctx).Omit("User", "Subscription").Create(invoice).Error
+}
+
+func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Invoice, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *InvoiceRepository) GetByNumber(ctx context.Context, number string) (*entities.Invoice, error) {
+	return r.first(ctx, "invoice_number = ?", number)
+}
+
+func (r *InvoiceRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Invoice, error) {
+	var invoice entities.Invoice
+	if err := r.db.WithContext(ctx).Preload("Items").Where(query, args...).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *InvoiceRepository) Update(ctx context.Context, invoice *entities.Invoice) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(invoice).Error
+}
+
+func (r *InvoiceRepository) GetByUserID(ctx context.Context, userID uuid.UUID, params repositories.ListParams) ([]*entities.Invoice, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Invoice{}).Where("user_id = ?", userID)
+	if value, ok := params.Filters["status"]; ok {
+		query = query.Where("status = ?", value)
+	}
+
+	invoices := make([]*entities.Invoice, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "issue_date", "due_date", "total"), &invoices, preload("Items"))
+	return invoices, total, err
+}
+
+func (r *InvoiceRepository) GetOverdue(ctx context.Context) ([]*entities.Invoice, error) {
+	var invoices []*entities.Invoice
+	err := r.db.WithContext(ctx).Where("status = ? AND due_date < ?", "pending", time.Now()).Order("due_date").Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *InvoiceRepository) MarkPaid(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&entities.Invoice{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": "paid", "paid_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GenerateNumber returns the next invoice number of the current month, as
+// INV-YYYYMM-NNNNN. Two concurrent invoices may draw the same number, the
+// unique index on invoice_number rejects the second.
+func (r *InvoiceRepository) GenerateNumber(ctx context.Context) (string, error) {
+	prefix := "INV-" + time.Now().UTC().Format("200601") + "-"
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entities.Invoice{}).Where("invoice_number LIKE ?", prefix+"%").Count(&count).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%05d", prefix, count+1), nil
+}
+
+// PackageRepository implements repositories.PackageRepository
+type PackageRepository struct {
+	db *gorm.DB
+}
+
+// NewPackageRepository creates a new PackageRepository
+func NewPackageRepository(db *gorm.DB) *PackageRepository {
+	return &PackageRepository{db: db}
+}
+
+func (r *PackageRepository) Create(ctx context.Context, pkg *entities.Package) error {
+	return r.db.WithContext(ctx).Create(pkg).Error
+}
+
+func (r *PackageRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Package, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *PackageRepository) GetBySlug(ctx context.Context, slug string) (*entities.Package, error) {
+	return r.first(ctx, "slug = ?", slug)
+}
+
+func (r *PackageRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Package, error) {
+	var pkg entities.Package
+	if err := r.db.WithContext(ctx).Scopes(NotTrashed).Where(query, args...).First(&pkg).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (r *PackageRepository) Update(ctx context.Context, pkg *entities.Package) error {
+	return r.db.WithContext(ctx).Save(pkg).Error
+}
+
+func (r *PackageRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return SoftDelete(r.db.WithContext(ctx), &entities.Package{}, id)
+}
+
+func (r *PackageRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.Package, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Package{}).Scopes(Trashed(params))
+	if value, ok := params.Filters["category"]; ok {
+		query = query.Where("category = ?", value)
+	}
+
+	packages := make([]*entities.Package, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "sort_order", "name", "price_monthly", "created_at"), &packages)
+	return packages, total, err
+}
+
+// GetActive returns the active packages shown in the store, in display order
+func (r *PackageRepository) GetActive(ctx context.Context) ([]*entities.Package, error) {
+	return r.find(ctx, "is_active = ? AND is_hidden = ?", true, false)
+}
+
+func (r *PackageRepository) GetByCategory(ctx context.Context, category string) ([]*entities.Package, error) {
+	return r.find(ctx, "category = ? AND is_active = ? AND is_hidden = ?", category, true, false)
+}
+
+func (r *PackageRepository) find(ctx context.Context, query string, args ...interface{}) ([]*entities.Package, error) {
+	var packages []*entities.Package
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where(query, args...).Order("sort_order, name").Find(&packages).Error
+	return packages, err
+}
@@ -0,0 +1,137 @@
+// Package dbtest opens SQLite databases standing in for Postgres in tests
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// jsonbDriver is the SQLite driver, storing the maps entities keep in Postgres
+// jsonb columns without a serializer as JSON and nil maps as NULL. Queries
+// using ILIKE, which SQLite lacks, run with its LIKE, case-insensitive already.
+type jsonbDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *jsonbDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return jsonbConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type jsonbConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (jsonbConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value := reflect.ValueOf(nv.Value)
+	if value.Kind() != reflect.Map {
+		return driver.ErrSkip
+	}
+	if value.IsNil() {
+		nv.Value = nil
+		return nil
+	}
+	data, err := json.Marshal(nv.Value)
+	nv.Value = string(data)
+	return err
+}
+
+func (c jsonbConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.SQLiteConn.QueryContext(ctx, strings.ReplaceAll(query, " ILIKE ", " LIKE "), args)
+}
+
+func init() {
+	sql.Register("sqlite3_jsonb", &jsonbDriver{})
+}
+
+// Open opens a SQLite database with the tables of models. The
+// Postgres-only parts of the entities, defaults such as gen_random_uuid() and
+// GIN indexes, are dropped. Rows created without a UUID primary key are given
+// a random one instead.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "panel.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite3_jsonb", DSN: dsn}, &gorm.Config{
+		Logger:                           logger.Discard,
+		IgnoreRelationshipsWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if strings.Contains(field.DefaultValue, "(") || (field.FieldType.Kind() == reflect.Map && field.Serializer == nil) {
+				field.HasDefaultValue, field.DefaultValue, field.DefaultValueInterface = false, "", nil
+			}
+			if strings.Contains(field.TagSettings["INDEX"], "type:") {
+				delete(field.TagSettings, "INDEX")
+			}
+		}
+		// Nor are the dropped defaults read back after an insert
+		returned := stmt.Schema.FieldsWithDefaultDBValue[:0]
+		for _, field := range stmt.Schema.FieldsWithDefaultDBValue {
+			if field.HasDefaultValue {
+				returned = append(returned, field)
+			}
+		}
+		stmt.Schema.FieldsWithDefaultDBValue = returned
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("test:uuid_ids", assignUUIDs); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// assignUUIDs sets a random UUID primary key on rows being created without one
+func assignUUIDs(tx *gorm.DB) {
+	if tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || field.FieldType != reflect.TypeOf(uuid.UUID{}) {
+		return
+	}
+
+	ctx, rv := tx.Statement.Context, tx.Statement.ReflectValue
+	assign := func(row reflect.Value) {
+		if _, zero := field.ValueOf(ctx, row); zero {
+			_ = field.Set(ctx, row, uuid.New())
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			assign(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
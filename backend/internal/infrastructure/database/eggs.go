@@ -0,0 +1,328 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultEgg is a seeded egg together with the name of the game it belongs to
+type defaultEgg struct {
+	Game string
+	Egg  entities.Egg
+}
+
+// seedDefaultEggs creates the default eggs and their variables. Eggs and
+// variables that already exist are left as they are, so admin edits survive
+// a reseed.
+func seedDefaultEggs(db *gorm.DB) error {
+	for _, d := range getDefaultEggs() {
+		var game entities.Game
+		if err := db.Where("name = ?", d.Game).First(&game).Error; err != nil {
+			return fmt.Errorf("failed to find game %s: %w", d.Game, err)
+		}
+
+		egg := d.Egg
+		variables := egg.Variables
+		egg.Variables = nil
+		egg.GameID = game.ID
+		if err := db.Where(entities.Egg{GameID: game.ID, Name: egg.Name}).FirstOrCreate(&egg).Error; err != nil {
+			return fmt.Errorf("failed to seed egg %s: %w", egg.Name, err)
+		}
+
+		for i, v := range variables {
+			var existing entities.EggVariable
+			err := db.Where("egg_id = ? AND env_variable = ?", egg.ID, v.EnvVariable).First(&existing).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to find variable %s of egg %s: %w", v.EnvVariable, egg.Name, err)
+			}
+
+			v.ID = uuid.New()
+			v.EggID = egg.ID
+			v.SortOrder = i
+			viewable, editable := v.UserViewable, v.UserEditable
+			if err := db.Create(&v).Error; err != nil {
+				return fmt.Errorf("failed to seed variable %s of egg %s: %w", v.EnvVariable, egg.Name, err)
+			}
+			// Create writes the true column defaults in place of false, so
+			// hidden and locked variables are updated after it
+			if !viewable || !editable {
+				if err := db.Model(&v).Select("user_viewable", "user_editable").
+					Updates(entities.EggVariable{UserViewable: viewable, UserEditable: editable}).Error; err != nil {
+					return fmt.Errorf("failed to seed variable %s of egg %s: %w", v.EnvVariable, egg.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// getDefaultEggs returns the eggs seeded for the default games
+func getDefaultEggs() []defaultEgg {
+	return []defaultEgg{
+		{
+			Game: "Minecraft Java",
+			Egg: entities.Egg{
+				Name:           "Paper",
+				Description:    "High performance Minecraft Java server with plugin support",
+				Author:         "Aether Panel",
+				DockerImages:   []string{"ghcr.io/pterodactyl/yolks:java_21", "ghcr.io/pterodactyl/yolks:java_17"},
+				StartupCommand: "java -Xms128M -XX:MaxRAMPercentage=95.0 -Dterminal.jline=false -Dterminal.ansi=true -jar {{SERVER_JARFILE}}",
+				ConfigFiles:    `{"server.properties": {"parser": "properties", "find": {"server-ip": "0.0.0.0", "server-port": "{{server.build.default.port}}"}}}`,
+				ConfigStartup:  `{"done": ")! For help, type "}`,
+				ConfigStop:     "stop",
+				ConfigLogs:     "{}",
+				InstallScript: `#!/bin/ash
+cd /mnt/server
+BUILD=$(curl -s https://api.papermc.io/v2/projects/paper/versions/${MINECRAFT_VERSION}/builds | jq -r '.builds | map(select(.channel == "default") | .build) | .[-1]')
+curl -o ${SERVER_JARFILE} https://api.papermc.io/v2/projects/paper/versions/${MINECRAFT_VERSION}/builds/${BUILD}/downloads/paper-${MINECRAFT_VERSION}-${BUILD}.jar
+echo "eula=true" > eula.txt`,
+				InstallContainer:  "ghcr.io/pterodactyl/installers:alpine",
+				InstallEntrypoint: "ash",
+				Limits:            entities.EggLimits{Memory: entities.ResourceBounds{Min: 1024, Recommended: 2048}, Disk: entities.ResourceBounds{Min: 2048, Recommended: 10240}, CPU: entities.ResourceBounds{Recommended: 100}},
+				IsActive:          true,
+				Variables: []entities.EggVariable{
+					{Name: "Minecraft Version", Description: "Minecraft version to install", EnvVariable: "MINECRAFT_VERSION", DefaultValue: "1.21.1", UserViewable: true, UserEditable: true, Rules: "required|string|max:20"},
+					{Name: "Server Jar File", Description: "Name of the server jar to run", EnvVariable: "SERVER_JARFILE", DefaultValue: "server.jar", UserViewable: true, UserEditable: true, Rules: "required|string|max:50"},
+				},
+			},
+		},
+		{
+			Game: "CS2",
+			Egg: entities.Egg{
+				Name:           "Counter-Strike 2",
+				Description:    "Counter-Strike 2 dedicated server installed through SteamCMD",
+				Author:         "Aether Panel",
+				DockerImages:   []string{"ghcr.io/pterodactyl/games:source"},
+				StartupCommand: "./game/bin/linuxsteamrt64/cs2 -dedicated -ip 0.0.0.0 -port {{SERVER_PORT}} -maxplayers {{MAX_PLAYERS}} +map {{SRCDS_MAP}} +sv_setsteamaccount {{STEAM_ACC}}",
+				ConfigFiles:    "{}",
+				ConfigStartup:  `{"done": "Connection to Steam servers successful"}`,
+				ConfigStop:     "quit",
+				ConfigLogs:     "{}",
+				InstallScript: `#!/bin/bash
+mkdir -p /mnt/server/steamcmd && cd /mnt/server/steamcmd
+curl -sSL https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz | tar -xz
+./steamcmd.sh +force_install_dir /mnt/server +login anonymous +app_update ${SRCDS_APPID} validate +quit`,
+				InstallContainer:  "ghcr.io/pterodactyl/installers:debian",
+				InstallEntrypoint: "bash",
+				Limits:            entities.EggLimits{Memory: entities.ResourceBounds{Min: 2048, Recommended: 4096}, Disk: entities.ResourceBounds{Min: 40960, Recommended: 61440}, CPU: entities.ResourceBounds{Recommended: 200}},
+				IsActive:          true,
+				Variables: []entities.EggVariable{
+					{Name: "Steam App ID", Description: "Steam app of the dedicated server", EnvVariable: "SRCDS_APPID", DefaultValue: "730", UserViewable: false, UserEditable: false, Rules: "required|integer|in:730"},
+					{Name: "Default Map", Description: "Map loaded when the server starts", EnvVariable: "SRCDS_MAP", DefaultValue: "de_dust2", UserViewable: true, UserEditable: true, Rules: "required|string|max:50"},
+					{Name: "Max Players", Description: "Player slots", EnvVariable: "MAX_PLAYERS", DefaultValue: "10", UserViewable: true, UserEditable: true, Rules: "required|integer|min:1|max:64"},
+					{Name: "Game Server Login Token", Description: "Steam game server login token, required for the server to be listed", EnvVariable: "STEAM_ACC", DefaultValue: "", UserViewable: true, UserEditable: true, Rules: "string|max:32"},
+				},
+			},
+		},
+		{
+			Game: "Rust",
+			Egg: entities.Egg{
+				Name:           "Rust",
+				Description:    "Rust dedicated server installed through SteamCMD",
+				Author:         "Aether Panel",
+				DockerImages:   []string{"ghcr.io/pterodactyl/games:rust"},
+				StartupCommand: `./RustDedicated -batchmode +server.port {{SERVER_PORT}} +server.queryport {{QUERY_PORT}} +server.identity "rust" +rcon.port {{RCON_PORT}} +rcon.web true +server.hostname "{{HOSTNAME}}" +server.level "{{LEVEL}}" +server.worldsize {{WORLD_SIZE}} +server.maxplayers {{MAX_PLAYERS}} +rcon.password "{{RCON_PASS}}"`,
+				ConfigFiles:    "{}",
+				ConfigStartup:  `{"done": "Server startup complete"}`,
+				ConfigStop:     "quit",
+				ConfigLogs:     "{}",
+				InstallScript: `#!/bin/bash
+mkdir -p /mnt/server/steamcmd && cd /mnt/server/steamcmd
+curl -sSL https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz | tar -xz
+./steamcmd.sh +force_install_dir /mnt/server +login anonymous +app_update 258550 validate +quit`,
+				InstallContainer:  "ghcr.io/pterodactyl/installers:debian",
+				InstallEntrypoint: "bash",
+				Ports: []entities.EggPort{
+					{EnvVariable: "QUERY_PORT", Offset: 1},
+					{EnvVariable: "RCON_PORT", Offset: 2},
+				},
+				Limits:   entities.EggLimits{Memory: entities.ResourceBounds{Min: 8192, Recommended: 12288}, Disk: entities.ResourceBounds{Min: 20480, Recommended: 30720}, CPU: entities.ResourceBounds{Recommended: 200}},
+				IsActive: true,
+				Variables: []entities.EggVariable{
+					{Name: "Server Name", Description: "Name shown in the server browser", EnvVariable: "HOSTNAME", DefaultValue: "A Rust Server", UserViewable: true, UserEditable: true, Rules: "required|string|max:60"},
+					{Name: "Level", Description: "World type to generate", EnvVariable: "LEVEL", DefaultValue: "Procedural Map", UserViewable: true, UserEditable: true, Rules: "required|in:Procedural Map,Barren,HapisIsland,SavasIsland"},
+					{Name: "World Size", Description: "World size in meters", EnvVariable: "WORLD_SIZE", DefaultValue: "3000", UserViewable: true, UserEditable: true, Rules: "required|integer|min:1000|max:6000"},
+					{Name: "Max Players", Description: "Player slots", EnvVariable: "MAX_PLAYERS", DefaultValue: "40", UserViewable: true, UserEditable: true, Rules: "required|integer|min:1|max:500"},
+					{Name: "RCON Password", Description: "Password of the web RCON", EnvVariable: "RCON_PASS", DefaultValue: "", UserViewable: true, UserEditable: true, Rules: "required|string|min:8|max:64"},
+				},
+			},
+		},
+		{
+			Game: "ARK: Survival Evolved",
+			Egg: entities.Egg{
+				Name:           "ARK: Survival Evolved",
+				Description:    "ARK: Survival Evolved dedicated server installed through SteamCMD",
+				Author:         "Aether Panel",
+				DockerImages:   []string{"ghcr.io/pterodactyl/games:source"},
+				StartupCommand: `./ShooterGame/Binaries/Linux/ShooterGameServer {{SERVER_MAP}}?listen?SessionName="{{SESSION_NAME}}"?ServerPassword={{ARK_PASSWORD}}?ServerAdminPassword={{ARK_ADMIN_PASSWORD}}?Port={{SERVER_PORT}}?QueryPort={{QUERY_PORT}}?MaxPlayers={{MAX_PLAYERS}} -server -log`,
+				ConfigFiles:    "{}",
+				ConfigStartup:  `{"done": "Waiting commands for 127.0.0.1"}`,
+				ConfigStop:     "^C",
+				ConfigLogs:     "{}",
+				InstallScript: `#!/bin/bash
+mkdir -p /mnt/server/steamcmd && cd /mnt/server/steamcmd
+curl -sSL https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz | tar -xz
+./steamcmd.sh +force_install_dir /mnt/server +login anonymous +app_update 376030 validate +quit`,
+				InstallContainer:  "ghcr.io/pterodactyl/installers:debian",
+				InstallEntrypoint: "bash",
+				Ports: []entities.EggPort{
+					{EnvVariable: "QUERY_PORT", Offset: 1},
+				},
+				Limits:   entities.EggLimits{Memory: entities.ResourceBounds{Min: 6144, Recommended: 8192}, Disk: entities.ResourceBounds{Min: 20480, Recommended: 30720}, CPU: entities.ResourceBounds{Recommended: 200}},
+				IsActive: true,
+				Variables: []entities.EggVariable{
+					{Name: "Map", Description: "Map the server runs", EnvVariable: "SERVER_MAP", DefaultValue: "TheIsland", UserViewable: true, UserEditable: true, Rules: "required|string|max:30"},
+					{Name: "Session Name", Description: "Name shown in the server browser", EnvVariable: "SESSION_NAME", DefaultValue: "An ARK Server", UserViewable: true, UserEditable: true, Rules: "required|string|max:60"},
+					{Name: "Server Password", Description: "Password players need to join, empty for none", EnvVariable: "ARK_PASSWORD", DefaultValue: "", UserViewable: true, UserEditable: true, Rules: "string|max:64"},
+					{Name: "Admin Password", Description: "Password for admin commands", EnvVariable: "ARK_ADMIN_PASSWORD", DefaultValue: "", UserViewable: true, UserEditable: true, Rules: "required|string|min:8|max:64"},
+					{Name: "Max Players", Description: "Player slots", EnvVariable: "MAX_PLAYERS", DefaultValue: "20", UserViewable: true, UserEditable: true, Rules: "required|integer|min:1|max:200"},
+				},
+			},
+		},
+		{
+			Game: "Valheim",
+			Egg: entities.Egg{
+				Name:           "Valheim",
+				Description:    "Valheim dedicated server installed through SteamCMD",
+				Author:         "Aether Panel",
+				DockerImages:   []string{"ghcr.io/pterodactyl/games:source"},
+				StartupCommand: `./valheim_server.x86_64 -nographics -batchmode -name "{{SERVER_NAME}}" -port {{SERVER_PORT}} -world "{{WORLD_NAME}}" -password "{{PASSWORD}}" -public {{PUBLIC}}`,
+				ConfigFiles:    "{}",
+				ConfigStartup:  `{"done": "Game server connected"}`,
+				ConfigStop:     "^C",
+				ConfigLogs:     "{}",
+				InstallScript: `#!/bin/bash
+mkdir -p /mnt/server/steamcmd && cd /mnt/server/steamcmd
+curl -sSL https://steamcdn-a.akamaihd.net/client/installer/steamcmd_linux.tar.gz | tar -xz
+./steamcmd.sh +force_install_dir /mnt/server +login anonymous +app_update 896660 validate +quit`,
+				InstallContainer:  "ghcr.io/pterodactyl/installers:debian",
+				InstallEntrypoint: "bash",
+				// Valheim also listens on the port after the game port
+				Ports: []entities.EggPort{
+					{EnvVariable: "QUERY_PORT", Offset: 1},
+				},
+				Limits:   entities.EggLimits{Memory: entities.ResourceBounds{Min: 2048, Recommended: 4096}, Disk: entities.ResourceBounds{Min: 2048, Recommended: 5120}, CPU: entities.ResourceBounds{Recommended: 200}},
+				IsActive: true,
+				Variables: []entities.EggVariable{
+					{Name: "Server Name", Description: "Name shown in the server browser", EnvVariable: "SERVER_NAME", DefaultValue: "A Valheim Server", UserViewable: true, UserEditable: true, Rules: "required|string|max:60"},
+					{Name: "World Name", Description: "World save to load or create", EnvVariable: "WORLD_NAME", DefaultValue: "Dedicated", UserViewable: true, UserEditable: true, Rules: "required|string|max:30"},
+					{Name: "Password", Description: "Password players need to join, at least 5 characters", EnvVariable: "PASSWORD", DefaultValue: "", UserViewable: true, UserEditable: true, Rules: "required|string|min:5|max:64"},
+					{Name: "Public", Description: "List the server in the server browser", EnvVariable: "PUBLIC", DefaultValue: "1", UserViewable: true, UserEditable: true, Rules: "required|boolean"},
+				},
+			},
+		},
+	}
+}
+
+// EggRepository implements repositories.EggRepository
+type EggRepository struct {
+	db *gorm.DB
+}
+
+// NewEggRepository creates a new EggRepository
+func NewEggRepository(db *gorm.DB) *EggRepository {
+	return &EggRepository{db: db}
+}
+
+func (r *EggRepository) Create(ctx context.Context, egg *entities.Egg) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(egg).Error
+}
+
+func (r *EggRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Egg, error) {
+	var egg entities.Egg
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&egg).Error; err != nil {
+		return nil, err
+	}
+	return &egg, nil
+}
+
+func (r *EggRepository) Update(ctx context.Context, egg *entities.Egg) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(egg).Error
+}
+
+func (r *EggRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Egg{}).Error
+}
+
+func (r *EggRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.Egg, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Egg{})
+	if params.Search != "" {
+		query = query.Where("name ILIKE ?", "%"+params.Search+"%")
+	}
+	if value, ok := params.Filters["game_id"]; ok {
+		query = query.Where("game_id = ?", value)
+	}
+
+	eggs := make([]*entities.Egg, 0, params.PageSize)
+	total, err := Paginate(query, params, "sort_order, name", &eggs)
+	return eggs, total, err
+}
+
+func (r *EggRepository) GetByGameID(ctx context.Context, gameID uuid.UUID) ([]*entities.Egg, error) {
+	var eggs []*entities.Egg
+	err := r.db.WithContext(ctx).Where("game_id = ?", gameID).Order("sort_order, name").Find(&eggs).Error
+	return eggs, err
+}
+
+func (r *EggRepository) GetActive(ctx context.Context) ([]*entities.Egg, error) {
+	var eggs []*entities.Egg
+	err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("sort_order, name").Find(&eggs).Error
+	return eggs, err
+}
+
+// EggVariableRepository implements repositories.EggVariableRepository
+type EggVariableRepository struct {
+	db *gorm.DB
+}
+
+// NewEggVariableRepository creates a new EggVariableRepository
+func NewEggVariableRepository(db *gorm.DB) *EggVariableRepository {
+	return &EggVariableRepository{db: db}
+}
+
+// Create writes the true column defaults in place of false flags, so hidden
+// and locked variables are updated after it
+func (r *EggVariableRepository) Create(ctx context.Context, variable *entities.EggVariable) error {
+	viewable, editable := variable.UserViewable, variable.UserEditable
+	if err := r.db.WithContext(ctx).Create(variable).Error; err != nil {
+		return err
+	}
+	variable.UserViewable, variable.UserEditable = viewable, editable
+	if viewable && editable {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(variable).Select("user_viewable", "user_editable").
+		Updates(entities.EggVariable{UserViewable: viewable, UserEditable: editable}).Error
+}
+
+func (r *EggVariableRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.EggVariable, error) {
+	var variable entities.EggVariable
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&variable).Error; err != nil {
+		return nil, err
+	}
+	return &variable, nil
+}
+
+func (r *EggVariableRepository) Update(ctx context.Context, variable *entities.EggVariable) error {
+	return r.db.WithContext(ctx).Save(variable).Error
+}
+
+func (r *EggVariableRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.EggVariable{}).Error
+}
+
+func (r *EggVariableRepository) GetByEggID(ctx context.Context, eggID uuid.UUID) ([]*entities.EggVariable, error) {
+	var variables []*entities.EggVariable
+	err := r.db.WithContext(ctx).Where("egg_id = ?", eggID).Order("sort_order").Find(&variables).Error
+	return variables, err
+}
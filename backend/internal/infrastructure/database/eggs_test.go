@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// openSeedDB opens a database with the tables SeedDefaultData writes
+func openSeedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &entities.Permission{}, &entities.Role{}, &entities.Game{}, &entities.Egg{}, &entities.EggVariable{})
+	// Relationships are not migrated, so the join table of roles is made here
+	if err := db.Exec("CREATE TABLE role_permissions (role_id TEXT, permission_id TEXT, PRIMARY KEY (role_id, permission_id))").Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seededEgg is an egg as stored by the seed
+type seededEgg struct {
+	ID             string
+	Game           string
+	StartupCommand string
+}
+
+// seededEggs returns the stored eggs by name and the variables of each egg
+func seededEggs(t *testing.T, db *gorm.DB) (map[string]seededEgg, map[string][]entities.EggVariable) {
+	t.Helper()
+	var rows []struct {
+		ID             string
+		Name           string
+		Game           string
+		StartupCommand string
+	}
+	if err := db.Table("eggs").
+		Select("eggs.id, eggs.name, games.name AS game, eggs.startup_command").
+		Joins("JOIN games ON games.id = eggs.game_id").
+		Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	eggs := make(map[string]seededEgg, len(rows))
+	variables := make(map[string][]entities.EggVariable, len(rows))
+	for _, row := range rows {
+		eggs[row.Name] = seededEgg{ID: row.ID, Game: row.Game, StartupCommand: row.StartupCommand}
+		var vars []entities.EggVariable
+		if err := db.Where("egg_id = ?", row.ID).Order("sort_order").Find(&vars).Error; err != nil {
+			t.Fatal(err)
+		}
+		variables[row.Name] = vars
+	}
+	return eggs, variables
+}
+
+func TestSeedDefaultEggs(t *testing.T) {
+	db := openSeedDB(t)
+	if err := SeedDefaultData(db); err != nil {
+		t.Fatal(err)
+	}
+
+	eggs, variables := seededEggs(t, db)
+	want := map[string]struct {
+		game      string
+		variables []string
+	}{
+		"Paper":                 {"Minecraft Java", []string{"MINECRAFT_VERSION", "SERVER_JARFILE"}},
+		"Counter-Strike 2":      {"CS2", []string{"SRCDS_APPID", "SRCDS_MAP", "MAX_PLAYERS", "STEAM_ACC"}},
+		"Rust":                  {"Rust", []string{"HOSTNAME", "LEVEL", "WORLD_SIZE", "MAX_PLAYERS", "RCON_PASS"}},
+		"ARK: Survival Evolved": {"ARK: Survival Evolved", []string{"SERVER_MAP", "SESSION_NAME", "ARK_PASSWORD", "ARK_ADMIN_PASSWORD", "MAX_PLAYERS"}},
+		"Valheim":               {"Valheim", []string{"SERVER_NAME", "WORLD_NAME", "PASSWORD", "PUBLIC"}},
+	}
+	if len(eggs) != len(want) {
+		t.Errorf("seeded %d eggs, want %d", len(eggs), len(want))
+	}
+	for name, w := range want {
+		egg, ok := eggs[name]
+		if !ok {
+			t.Errorf("egg %s not seeded", name)
+			continue
+		}
+		if egg.Game != w.game {
+			t.Errorf("egg %s belongs to %s, want %s", name, egg.Game, w.game)
+		}
+		var names []string
+		for _, v := range variables[name] {
+			names = append(names, v.EnvVariable)
+		}
+		if !slices.Equal(names, w.variables) {
+			t.Errorf("variables of %s = %v, want %v", name, names, w.variables)
+		}
+	}
+
+	// Every default game has an egg to create servers from
+	var games []string
+	if err := db.Model(&entities.Game{}).Pluck("name", &games).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range games {
+		found := false
+		for _, egg := range eggs {
+			found = found || egg.Game == game
+		}
+		if !found {
+			t.Errorf("game %s has no egg", game)
+		}
+	}
+
+	// Hidden variables keep their flags instead of the column defaults
+	for _, v := range variables["Counter-Strike 2"] {
+		if v.EnvVariable == "SRCDS_APPID" && (v.UserViewable || v.UserEditable) {
+			t.Errorf("SRCDS_APPID viewable %v editable %v, want hidden", v.UserViewable, v.UserEditable)
+		}
+	}
+
+	ark := eggs["ARK: Survival Evolved"].StartupCommand
+	if !strings.HasPrefix(ark, "./ShooterGame/Binaries/Linux/ShooterGameServer ") || strings.Contains(ark, "entities") {
+		t.Errorf("ARK startup %q, want it to run ShooterGameServer", ark)
+	}
+}
+
+func TestSeedDefaultEggsKeepsAdminEdits(t *testing.T) {
+	db := openSeedDB(t)
+	if err := SeedDefaultData(db); err != nil {
+		t.Fatal(err)
+	}
+	eggs, _ := seededEggs(t, db)
+	paper := eggs["Paper"].ID
+
+	if err := db.Model(&entities.EggVariable{}).Where("egg_id = ? AND env_variable = ?", paper, "MINECRAFT_VERSION").
+		Update("default_value", "1.20.4").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Where("egg_id = ? AND env_variable = ?", paper, "SERVER_JARFILE").Delete(&entities.EggVariable{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SeedDefaultData(db); err != nil {
+		t.Fatal(err)
+	}
+	reseeded, variables := seededEggs(t, db)
+	if len(reseeded) != len(eggs) {
+		t.Errorf("%d eggs after reseeding, want %d", len(reseeded), len(eggs))
+	}
+	values := map[string]string{}
+	for _, v := range variables["Paper"] {
+		values[v.EnvVariable] = v.DefaultValue
+	}
+	if len(variables["Paper"]) != 2 || values["MINECRAFT_VERSION"] != "1.20.4" || values["SERVER_JARFILE"] != "server.jar" {
+		t.Errorf("Paper variables after reseeding %v, want the edit kept and the removed variable restored", values)
+	}
+}
+
+func TestCreateEggVariableKeepsFlags(t *testing.T) {
+	db := dbtest.Open(t, &entities.EggVariable{})
+	repo := NewEggVariableRepository(db)
+	eggID := uuid.New()
+
+	for _, want := range []entities.EggVariable{
+		{ID: uuid.New(), EggID: eggID, Name: "App ID", EnvVariable: "APP_ID"},
+		{ID: uuid.New(), EggID: eggID, Name: "Map", EnvVariable: "MAP", UserViewable: true},
+		{ID: uuid.New(), EggID: eggID, Name: "Players", EnvVariable: "PLAYERS", UserViewable: true, UserEditable: true},
+	} {
+		variable := want
+		if err := repo.Create(context.Background(), &variable); err != nil {
+			t.Fatal(err)
+		}
+		if variable.UserViewable != want.UserViewable || variable.UserEditable != want.UserEditable {
+			t.Errorf("%s after Create viewable %v editable %v, want %v and %v", want.EnvVariable,
+				variable.UserViewable, variable.UserEditable, want.UserViewable, want.UserEditable)
+		}
+		stored, err := repo.GetByID(context.Background(), want.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.UserViewable != want.UserViewable || stored.UserEditable != want.UserEditable {
+			t.Errorf("%s stored viewable %v editable %v, want %v and %v", want.EnvVariable,
+				stored.UserViewable, stored.UserEditable, want.UserViewable, want.UserEditable)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// feedChannels maps the tables whose new rows are published to their channel
+var feedChannels = map[string]string{
+	"system_events": redis.ChannelSystemEvents,
+	"audit_logs":    redis.ChannelAuditLogs,
+}
+
+// PublishCreated publishes every system event and audit log created through
+// db to its Pub/Sub channel, for the live admin feed. Rows created inside a
+// transaction are published when they are inserted, before it commits.
+func PublishCreated(db *gorm.DB, rdb *redis.Client, log *zap.Logger) error {
+	return db.Callback().Create().After("gorm:create").Register("aether:publish_created", func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Schema == nil {
+			return
+		}
+		channel, ok := feedChannels[tx.Statement.Schema.Table]
+		if !ok {
+			return
+		}
+
+		eachCreated(tx, func(row reflect.Value) {
+			payload, err := json.Marshal(row.Interface())
+			if err != nil {
+				return
+			}
+			if err := rdb.Publish(tx.Statement.Context, channel, payload); err != nil {
+				log.Debug("Failed to publish created row", zap.String("channel", channel), zap.Error(err))
+			}
+		})
+	})
+}
+
+// NotifyCreatedEvents calls notify with every system event created through
+// db once it is committed. Events created inside a transaction are held until
+// the transaction commits and dropped when it rolls back, so db's connection
+// pool is wrapped to see the commits. notify runs on the committing
+// goroutine, so it should not block.
+func NotifyCreatedEvents(db *gorm.DB, notify func(event entities.SystemEvent)) error {
+	pool := &eventPool{ConnPool: db.ConnPool, notify: notify}
+	db.ConnPool, db.Statement.ConnPool = pool, pool
+
+	return db.Callback().Create().After("gorm:create").Register("aether:notify_created_events", func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Schema == nil || tx.Statement.Schema.Table != "system_events" {
+			return
+		}
+		eachCreated(tx, func(row reflect.Value) {
+			event, ok := row.Interface().(entities.SystemEvent)
+			if !ok {
+				return
+			}
+			if etx, ok := tx.Statement.ConnPool.(*eventTx); ok {
+				etx.hold(event)
+				return
+			}
+			notify(event)
+		})
+	})
+}
+
+// eventPool is a connection pool whose transactions hold the system events
+// created in them until they commit
+type eventPool struct {
+	gorm.ConnPool
+	notify func(event entities.SystemEvent)
+}
+
+func (p *eventPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	beginner, ok := p.ConnPool.(gorm.TxBeginner)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &eventTx{Tx: tx, pool: p}, nil
+}
+
+// GetDBConn returns the wrapped *sql.DB, for gorm.DB.DB
+func (p *eventPool) GetDBConn() (*sql.DB, error) {
+	if conn, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return conn.GetDBConn()
+	}
+	conn, _ := p.ConnPool.(*sql.DB)
+	return conn, nil
+}
+
+// eventTx is a transaction of an eventPool
+type eventTx struct {
+	*sql.Tx
+	pool *eventPool
+
+	mu     sync.Mutex
+	events []entities.SystemEvent
+}
+
+func (t *eventTx) hold(event entities.SystemEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+// Commit commits the transaction and notifies its events
+func (t *eventTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	events := t.events
+	t.events = nil
+	t.mu.Unlock()
+
+	for _, event := range events {
+		t.pool.notify(event)
+	}
+	return nil
+}
+
+// GetDBConn returns the *sql.DB of the pool, for gorm.DB.DB
+func (t *eventTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.GetDBConn()
+}
+
+// eachCreated calls fn with every row a create statement inserted
+func eachCreated(tx *gorm.DB, fn func(row reflect.Value)) {
+	rows := reflect.Indirect(tx.Statement.ReflectValue)
+	switch rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			fn(reflect.Indirect(rows.Index(i)))
+		}
+	case reflect.Struct:
+		fn(rows)
+	}
+}
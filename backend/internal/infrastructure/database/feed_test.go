@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newEventTestDB opens a SQLite database with a system_events table whose
+// committed events are recorded in notified
+func newEventTestDB(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "events.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := db.Exec("CREATE TABLE system_events (id TEXT PRIMARY KEY, node_id TEXT, server_id TEXT, event_type TEXT, severity TEXT, message TEXT, data TEXT, created_at DATETIME)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	notified := &[]string{}
+	if err := NotifyCreatedEvents(db, func(event entities.SystemEvent) {
+		*notified = append(*notified, event.Message)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return db, notified
+}
+
+// createEvent creates a system event with message through db
+func createEvent(t *testing.T, db *gorm.DB, message string) {
+	t.Helper()
+	event := &entities.SystemEvent{ID: uuid.New(), EventType: entities.EventNodeOffline, Message: message}
+	if err := db.Omit("Data").Create(event).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyCreatedEventsAfterCommit(t *testing.T) {
+	db, notified := newEventTestDB(t)
+
+	createEvent(t, db, "outside")
+	if !slices.Equal(*notified, []string{"outside"}) {
+		t.Fatalf("notified %q, want the event created outside a transaction", *notified)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		createEvent(t, tx, "committed")
+		if len(*notified) != 1 {
+			t.Errorf("notified %q before the commit", *notified)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(*notified, []string{"outside", "committed"}) {
+		t.Errorf("notified %q, want the committed event after the commit", *notified)
+	}
+
+	errRollback := errors.New("notification failed")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		createEvent(t, tx, "rolled back")
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Transaction = %v, want %v", err, errRollback)
+	}
+	if !slices.Equal(*notified, []string{"outside", "committed"}) {
+		t.Errorf("notified %q, want nothing of the rolled back transaction", *notified)
+	}
+
+	var count int64
+	db.Model(&entities.SystemEvent{}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d events stored, want 2", count)
+	}
+	if _, err := db.DB(); err != nil {
+		t.Errorf("DB() = %v after wrapping the pool", err)
+	}
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/go-sql-driver/mysql"
+)
+
+// identifierPattern limits database and user names to characters that are safe
+// to quote in MySQL statements
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// MySQLProvisioner creates and drops server databases on MySQL or MariaDB hosts
+type MySQLProvisioner struct {
+	timeout time.Duration
+}
+
+// NewMySQLProvisioner creates a new MySQLProvisioner
+func NewMySQLProvisioner() *MySQLProvisioner {
+	return &MySQLProvisioner{timeout: 10 * time.Second}
+}
+
+// CreateDatabase creates a database and a user with full access to it only.
+// Anything created is dropped again when a later step fails.
+func (p *MySQLProvisioner) CreateDatabase(ctx context.Context, host services.DatabaseHostCredentials, database, username, password, remote string) (err error) {
+	if !identifierPattern.MatchString(database) || !identifierPattern.MatchString(username) {
+		return fmt.Errorf("invalid database or user name")
+	}
+
+	db, err := p.open(host)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "CREATE DATABASE `"+database+"`"); err != nil {
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_, _ = db.ExecContext(ctx, "DROP DATABASE IF EXISTS `"+database+"`")
+		}
+	}()
+
+	if _, err = db.ExecContext(ctx, "CREATE USER ?@? IDENTIFIED BY ?", username, remote, password); err != nil {
+		return fmt.Errorf("failed to create database user: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_, _ = db.ExecContext(ctx, "DROP USER IF EXISTS ?@?", username, remote)
+		}
+	}()
+
+	if _, err = db.ExecContext(ctx, "GRANT ALL PRIVILEGES ON `"+database+"`.* TO ?@?", username, remote); err != nil {
+		return fmt.Errorf("failed to grant database access: %w", err)
+	}
+	return nil
+}
+
+// RotatePassword sets a new password for a database user
+func (p *MySQLProvisioner) RotatePassword(ctx context.Context, host services.DatabaseHostCredentials, username, remote, password string) error {
+	db, err := p.open(host)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "ALTER USER ?@? IDENTIFIED BY ?", username, remote, password); err != nil {
+		return fmt.Errorf("failed to change database password: %w", err)
+	}
+	return nil
+}
+
+// DropDatabase drops a database and its user. Either may already be gone.
+func (p *MySQLProvisioner) DropDatabase(ctx context.Context, host services.DatabaseHostCredentials, database, username, remote string) error {
+	if !identifierPattern.MatchString(database) {
+		return fmt.Errorf("invalid database name")
+	}
+
+	db, err := p.open(host)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "DROP DATABASE IF EXISTS `"+database+"`"); err != nil {
+		return fmt.Errorf("failed to drop database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "DROP USER IF EXISTS ?@?", username, remote); err != nil {
+		return fmt.Errorf("failed to drop database user: %w", err)
+	}
+	return nil
+}
+
+// open connects to a database host as its administrative user
+func (p *MySQLProvisioner) open(host services.DatabaseHostCredentials) (*sql.DB, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = host.Username
+	cfg.Passwd = host.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
+	cfg.Timeout = p.timeout
+	cfg.ReadTimeout = p.timeout
+	cfg.WriteTimeout = p.timeout
+	// Account statements cannot be prepared, so arguments are escaped client side
+	cfg.InterpolateParams = true
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database host: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
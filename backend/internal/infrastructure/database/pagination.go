@@ -0,0 +1,26 @@
+package database
+
+import (
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// Paginate loads one page of query into dest, ordered by order, and returns
+// the total number of rows the query matches. The count runs on the query
+// before ordering and paging, so both see the same filters. scopes, such as
+// preloads, only apply to the page query.
+func Paginate[T any](query *gorm.DB, params repositories.ListParams, order string, dest *[]T, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	page := query.Session(&gorm.Session{}).Scopes(scopes...)
+	if order != "" {
+		page = page.Order(order)
+	}
+	if err := page.Offset(params.Offset()).Limit(params.PageSize).Find(dest).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// searchPattern matches text containing query, with LIKE wildcards in query
+// taken literally
+func searchPattern(query string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query) + "%"
+}
+
+// ChatLogRepository implements repositories.ChatLogRepository
+type ChatLogRepository struct {
+	db *gorm.DB
+}
+
+// NewChatLogRepository creates a new ChatLogRepository
+func NewChatLogRepository(db *gorm.DB) *ChatLogRepository {
+	return &ChatLogRepository{db: db}
+}
+
+func (r *ChatLogRepository) Create(ctx context.Context, log *entities.ChatLog) error {
+	return r.db.WithContext(ctx).Omit("Player").Create(log).Error
+}
+
+func (r *ChatLogRepository) GetByServerID(ctx context.Context, serverID uuid.UUID, params repositories.ListParams) ([]*entities.ChatLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.ChatLog{}).Where("server_id = ?", serverID), params)
+}
+
+func (r *ChatLogRepository) GetByPlayerID(ctx context.Context, playerID uuid.UUID, params repositories.ListParams) ([]*entities.ChatLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.ChatLog{}).Where("player_id = ?", playerID), params)
+}
+
+// Search returns a server's chat messages containing query sent between start
+// and end, newest first
+func (r *ChatLogRepository) Search(ctx context.Context, serverID uuid.UUID, query string, start, end time.Time) ([]*entities.ChatLog, error) {
+	var logs []*entities.ChatLog
+	err := r.db.WithContext(ctx).
+		Where("server_id = ? AND message ILIKE ? AND created_at BETWEEN ? AND ?", serverID, searchPattern(query), start, end).
+		Order("created_at DESC").
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *ChatLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.ChatLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ChatLogRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.ChatLog, int64, error) {
+	logs := make([]*entities.ChatLog, 0, params.PageSize)
+	total, err := Paginate(query, params, "created_at DESC", &logs)
+	return logs, total, err
+}
+
+// CommandLogRepository implements repositories.CommandLogRepository
+type CommandLogRepository struct {
+	db *gorm.DB
+}
+
+// NewCommandLogRepository creates a new CommandLogRepository
+func NewCommandLogRepository(db *gorm.DB) *CommandLogRepository {
+	return &CommandLogRepository{db: db}
+}
+
+func (r *CommandLogRepository) Create(ctx context.Context, log *entities.CommandLog) error {
+	return r.db.WithContext(ctx).Omit("Player").Create(log).Error
+}
+
+func (r *CommandLogRepository) GetByServerID(ctx context.Context, serverID uuid.UUID, params repositories.ListParams) ([]*entities.CommandLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.CommandLog{}).Where("server_id = ?", serverID), params)
+}
+
+func (r *CommandLogRepository) GetByPlayerID(ctx context.Context, playerID uuid.UUID, params repositories.ListParams) ([]*entities.CommandLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.CommandLog{}).Where("player_id = ?", playerID), params)
+}
+
+// Search returns a server's commands containing query, newest first
+func (r *CommandLogRepository) Search(ctx context.Context, serverID uuid.UUID, query string) ([]*entities.CommandLog, error) {
+	var logs []*entities.CommandLog
+	err := r.db.WithContext(ctx).
+		Where("server_id = ? AND command ILIKE ?", serverID, searchPattern(query)).
+		Order("created_at DESC").
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *CommandLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.CommandLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *CommandLogRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.CommandLog, int64, error) {
+	logs := make([]*entities.CommandLog, 0, params.PageSize)
+	total, err := Paginate(query, params, "created_at DESC", &logs)
+	return logs, total, err
+}
+
+// DeathLogRepository implements repositories.DeathLogRepository
+type DeathLogRepository struct {
+	db *gorm.DB
+}
+
+// NewDeathLogRepository creates a new DeathLogRepository
+func NewDeathLogRepository(db *gorm.DB) *DeathLogRepository {
+	return &DeathLogRepository{db: db}
+}
+
+func (r *DeathLogRepository) Create(ctx context.Context, log *entities.DeathLog) error {
+	return r.db.WithContext(ctx).Omit("Player", "Killer").Create(log).Error
+}
+
+func (r *DeathLogRepository) GetByServerID(ctx context.Context, serverID uuid.UUID, params repositories.ListParams) ([]*entities.DeathLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.DeathLog{}).Where("server_id = ?", serverID), params)
+}
+
+func (r *DeathLogRepository) GetByPlayerID(ctx context.Context, playerID uuid.UUID, params repositories.ListParams) ([]*entities.DeathLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.DeathLog{}).Where("player_id = ?", playerID), params)
+}
+
+func (r *DeathLogRepository) GetByKillerID(ctx context.Context, killerID uuid.UUID, params repositories.ListParams) ([]*entities.DeathLog, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.DeathLog{}).Where("killer_id = ?", killerID), params)
+}
+
+func (r *DeathLogRepository) CountByPlayerID(ctx context.Context, playerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.DeathLog{}).Where("player_id = ?", playerID).Count(&count).Error
+	return count, err
+}
+
+func (r *DeathLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.DeathLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *DeathLogRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.DeathLog, int64, error) {
+	logs := make([]*entities.DeathLog, 0, params.PageSize)
+	total, err := Paginate(query, params, "created_at DESC", &logs)
+	return logs, total, err
+}
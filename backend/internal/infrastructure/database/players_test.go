@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+)
+
+func TestChatLogDeleteOlderThan(t *testing.T) {
+	db := dbtest.Open(t, &entities.ChatLog{})
+	repo := NewChatLogRepository(db)
+	ctx := context.Background()
+
+	serverID := uuid.New()
+	now := time.Now()
+	for _, age := range []time.Duration{30 * 24 * time.Hour, 8 * 24 * time.Hour, time.Hour} {
+		if err := repo.Create(ctx, &entities.ChatLog{ServerID: serverID, Username: "Steve", Message: "hi", CreatedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := repo.DeleteOlderThan(ctx, now.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d messages, want the two older than a week", removed)
+	}
+	logs, total, err := repo.GetByServerID(ctx, serverID, repositories.ListParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(logs) != 1 || now.Sub(logs[0].CreatedAt) > 2*time.Hour {
+		t.Errorf("%d messages left, want only the recent one", total)
+	}
+}
@@ -32,6 +32,10 @@ func NewPostgresConnection(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		Logger:                                   logger.Default.LogMode(logLevel),
 		DisableForeignKeyConstraintWhenMigrating: false,
 		PrepareStmt:                              true,
+		// Timestamps are stored and returned in UTC, clients convert them
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
 	}
 
 	// Open connection
@@ -66,17 +70,20 @@ func AutoMigrate(db *gorm.DB) error {
 	db.Exec("SET FOREIGN_KEY_CHECKS = 0")
 	defer db.Exec("SET FOREIGN_KEY_CHECKS = 1")
 	
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		// User & Auth
 		&entities.User{},
 		&entities.Role{},
 		&entities.Permission{},
 		&entities.Session{},
+		&entities.UsedRefreshToken{},
 		&entities.APIKey{},
+		&entities.UserQuota{},
 
 		// Core entities first (without dependencies)
 		&entities.Location{},
 		&entities.Node{},
+		&entities.RegistryCredential{},
 		&entities.Game{},
 		&entities.Egg{},
 		&entities.EggVariable{},
@@ -92,6 +99,12 @@ func AutoMigrate(db *gorm.DB) error {
 		&entities.BackupSchedule{},
 		&entities.Snapshot{},
 		&entities.ServerTransfer{},
+		&entities.ImageRollout{},
+		&entities.ImageRolloutServer{},
+
+		// Server databases
+		&entities.DatabaseHost{},
+		&entities.ServerDatabase{},
 
 		// Billing
 		&entities.Transaction{},
@@ -116,12 +129,43 @@ func AutoMigrate(db *gorm.DB) error {
 		&entities.CommandLog{},
 		&entities.DeathLog{},
 
+		// Settings
+		&entities.Setting{},
+
 		// Audit & Logs
 		&entities.AuditLog{},
 		&entities.ActivityLog{},
 		&entities.SystemEvent{},
 		&entities.Notification{},
+		&entities.NotificationPreference{},
+		&entities.Webhook{},
+		&entities.ServerWebhook{},
+		&entities.ServerWebhookDelivery{},
 	)
+	if err != nil {
+		return err
+	}
+
+	return createSearchIndexes(db)
+}
+
+// createSearchIndexes adds the trigram indexes that back substring search
+func createSearchIndexes(db *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_servers_name_trgm ON servers USING gin (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_servers_uuid_trgm ON servers USING gin (uuid gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_nodes_name_trgm ON nodes USING gin (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_nodes_fqdn_trgm ON nodes USING gin (fqdn gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)",
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+	}
+	return nil
 }
 
 // SeedDefaultData seeds default data into the database
@@ -165,6 +209,11 @@ func SeedDefaultData(db *gorm.DB) error {
 		}
 	}
 
+	// Seed default eggs
+	if err := seedDefaultEggs(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -184,9 +233,11 @@ func getDefaultPermissions() []entities.Permission {
 		{Name: "servers.update", DisplayName: "Update Servers", Category: "servers", CreatedAt: now},
 		{Name: "servers.delete", DisplayName: "Delete Servers", Category: "servers", CreatedAt: now},
 		{Name: "servers.console", DisplayName: "Access Console", Category: "servers", CreatedAt: now},
+		{Name: "servers.console.unrestricted", DisplayName: "Bypass Command Policies", Category: "servers", CreatedAt: now},
 		{Name: "servers.files", DisplayName: "Manage Files", Category: "servers", CreatedAt: now},
 		{Name: "servers.power", DisplayName: "Power Actions", Category: "servers", CreatedAt: now},
 		{Name: "servers.backup", DisplayName: "Manage Backups", Category: "servers", CreatedAt: now},
+		{Name: "servers.databases", DisplayName: "Manage Databases", Category: "servers", CreatedAt: now},
 
 		// Node permissions
 		{Name: "nodes.view", DisplayName: "View Nodes", Category: "nodes", CreatedAt: now},
@@ -242,16 +293,15 @@ func getDefaultRoles() []entities.Role {
 	}
 }
 
-// getDefaultGames returns default supported games
+// getDefaultGames returns default supported games, each with an egg from
+// getDefaultEggs
 func getDefaultGames() []entities.Game {
 	now := time.Now()
 	return []entities.Game{
 		{Name: "Minecraft Java", Description: "Minecraft Java Edition", Category: "minecraft", SortOrder: 1, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "Minecraft Bedrock", Description: "Minecraft Bedrock Edition", Category: "minecraft", SortOrder: 2, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "Rust", Description: "Rust Survival Game", Category: "survival", SortOrder: 3, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "ARK: Survival Evolved", Description: "ARK Survival Game", Category: "survival", SortOrder: 4, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "Valheim", Description: "Valheim Viking Survival", Category: "survival", SortOrder: 5, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "CS2", Description: "Counter-Strike 2", Category: "fps", SortOrder: 6, IsActive: true, CreatedAt: now, UpdatedAt: now},
-		{Name: "Terraria", Description: "Terraria Sandbox", Category: "sandbox", SortOrder: 7, IsActive: true, CreatedAt: now, UpdatedAt: now},
+		{Name: "Rust", Description: "Rust Survival Game", Category: "survival", SortOrder: 2, IsActive: true, CreatedAt: now, UpdatedAt: now},
+		{Name: "ARK: Survival Evolved", Description: "ARK Survival Game", Category: "survival", SortOrder: 3, IsActive: true, CreatedAt: now, UpdatedAt: now},
+		{Name: "Valheim", Description: "Valheim Viking Survival", Category: "survival", SortOrder: 4, IsActive: true, CreatedAt: now, UpdatedAt: now},
+		{Name: "CS2", Description: "Counter-Strike 2", Category: "fps", SortOrder: 5, IsActive: true, CreatedAt: now, UpdatedAt: now},
 	}
 }
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// minRetentionFloor is the shortest floor honoured, whatever is configured
+const minRetentionFloor = time.Hour
+
+// Read notifications are purged in batches with a pause in between, as users
+// may hold many and each delete would otherwise lock the table for long
+const (
+	notificationPurgeBatch = 1000
+	notificationPurgePause = 100 * time.Millisecond
+)
+
+// logPurger deletes the rows of a log type older than a cutoff, returning
+// how many were removed
+type logPurger interface {
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+// webhookDeliveries purges webhook delivery logs, which have no repository of
+// their own
+type webhookDeliveries struct {
+	db *gorm.DB
+}
+
+func (w webhookDeliveries) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := w.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entities.ServerWebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// retainedLog is a log type purged after its retention
+type retainedLog struct {
+	name string
+	repo logPurger
+	keep time.Duration
+}
+
+// RetentionPurger periodically deletes logs older than their configured
+// retention
+type RetentionPurger struct {
+	db     *gorm.DB
+	config config.RetentionConfig
+	logs   []retainedLog
+	logger *zap.Logger
+}
+
+// NewRetentionPurger creates a new RetentionPurger
+func NewRetentionPurger(db *gorm.DB, cfg config.RetentionConfig, log *zap.Logger) *RetentionPurger {
+	return &RetentionPurger{
+		db:     db,
+		config: cfg,
+		logs: []retainedLog{
+			{"chat_logs", NewChatLogRepository(db), cfg.ChatLogs},
+			{"command_logs", NewCommandLogRepository(db), cfg.CommandLogs},
+			{"death_logs", NewDeathLogRepository(db), cfg.DeathLogs},
+			{"audit_logs", NewAuditLogRepository(db), cfg.AuditLogs},
+			{"webhook_deliveries", webhookDeliveries{db}, cfg.WebhookDeliveries},
+		},
+		logger: log,
+	}
+}
+
+// Start purges old logs every interval until the context is cancelled. An
+// interval of 0 disables purging.
+func (p *RetentionPurger) Start(ctx context.Context) {
+	if p.config.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Purge(ctx)
+		}
+	}
+}
+
+// Purge deletes the logs past their retention and returns the rows removed
+// per log type. Log types kept forever or with an invalid retention are
+// skipped.
+func (p *RetentionPurger) Purge(ctx context.Context) map[string]int64 {
+	removed := make(map[string]int64, len(p.logs)+1)
+	for _, l := range p.logs {
+		before, ok := p.cutoff(l.name, l.keep)
+		if !ok {
+			continue
+		}
+
+		count, err := l.repo.DeleteOlderThan(ctx, before)
+		if err != nil {
+			p.logger.Warn("Failed to purge old logs", zap.String("log", l.name), zap.Error(err))
+			continue
+		}
+		removed[l.name] = count
+		if count > 0 {
+			p.logger.Info("Purged old logs",
+				zap.String("log", l.name),
+				zap.Int64("count", count),
+				zap.Time("before", before),
+			)
+		}
+	}
+
+	if before, ok := p.cutoff("notifications", p.config.Notifications); ok {
+		removed["notifications"] = p.purgeNotifications(ctx, before)
+	}
+	return removed
+}
+
+// purgeNotifications deletes read notifications created before the cutoff,
+// a batch at a time, and returns how many were removed. Unread notifications
+// are kept however old they are.
+func (p *RetentionPurger) purgeNotifications(ctx context.Context, before time.Time) int64 {
+	var total int64
+	for {
+		batch := p.db.Model(&entities.Notification{}).Select("id").
+			Where("is_read = ? AND created_at < ?", true, before).
+			Limit(notificationPurgeBatch)
+		res := p.db.WithContext(ctx).Where("id IN (?)", batch).Delete(&entities.Notification{})
+		if res.Error != nil {
+			p.logger.Warn("Failed to purge old logs", zap.String("log", "notifications"), zap.Error(res.Error))
+			break
+		}
+		total += res.RowsAffected
+		if res.RowsAffected < notificationPurgeBatch {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return total
+		case <-time.After(notificationPurgePause):
+		}
+	}
+
+	if total > 0 {
+		p.logger.Info("Purged old logs",
+			zap.String("log", "notifications"),
+			zap.Int64("count", total),
+			zap.Time("before", before),
+		)
+	}
+	return total
+}
+
+// cutoff returns the time before which logs kept for keep are deleted. It
+// reports false for 0, which keeps logs forever, and for retentions below the
+// floor.
+func (p *RetentionPurger) cutoff(name string, keep time.Duration) (time.Time, bool) {
+	if keep == 0 {
+		return time.Time{}, false
+	}
+
+	floor := p.config.Floor
+	if floor < minRetentionFloor {
+		floor = minRetentionFloor
+	}
+	if keep < floor {
+		p.logger.Warn("Ignoring log retention below the floor",
+			zap.String("log", name),
+			zap.Duration("retention", keep),
+			zap.Duration("floor", floor),
+		)
+		return time.Time{}, false
+	}
+	return time.Now().Add(-keep), true
+}
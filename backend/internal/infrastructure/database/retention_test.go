@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var retainedTables = []string{"chat_logs", "command_logs", "death_logs", "audit_logs"}
+
+// newRetentionTestDB opens a SQLite database with the retained log tables,
+// each holding a row from ten days ago and one from an hour ago
+func newRetentionTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "logs.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	now := time.Now()
+	for _, table := range retainedTables {
+		if err := db.Exec("CREATE TABLE " + table + " (id TEXT PRIMARY KEY, created_at DATETIME)").Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, age := range []time.Duration{240 * time.Hour, time.Hour} {
+			if err := db.Exec("INSERT INTO "+table+" (id, created_at) VALUES (?, ?)", uuid.NewString(), now.Add(-age)).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return db
+}
+
+func countRows(t *testing.T, db *gorm.DB, table string) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Table(table).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestPurgeRemovesOnlyOlderRows(t *testing.T) {
+	db := newRetentionTestDB(t)
+	purger := NewRetentionPurger(db, config.RetentionConfig{
+		Floor:       24 * time.Hour,
+		ChatLogs:    72 * time.Hour,
+		CommandLogs: 72 * time.Hour,
+		DeathLogs:   72 * time.Hour,
+		AuditLogs:   72 * time.Hour,
+	}, zap.NewNop())
+
+	removed := purger.Purge(context.Background())
+	for _, table := range retainedTables {
+		if removed[table] != 1 {
+			t.Errorf("%s: removed %d rows, want the one older than the retention", table, removed[table])
+		}
+		if count := countRows(t, db, table); count != 1 {
+			t.Errorf("%s: %d rows left, want the recent one", table, count)
+		}
+	}
+}
+
+func TestPurgeIgnoresZeroAndInvalidRetention(t *testing.T) {
+	db := newRetentionTestDB(t)
+	// With no floor configured, the minimum floor applies
+	purger := NewRetentionPurger(db, config.RetentionConfig{
+		ChatLogs:    0, // Kept forever
+		CommandLogs: 30 * time.Minute,
+		DeathLogs:   -72 * time.Hour,
+	}, zap.NewNop())
+
+	removed := purger.Purge(context.Background())
+	for _, table := range retainedTables {
+		if _, ok := removed[table]; ok {
+			t.Errorf("%s was purged, want it skipped", table)
+		}
+		if count := countRows(t, db, table); count != 2 {
+			t.Errorf("%s: %d rows left, want both kept", table, count)
+		}
+	}
+}
+
+func TestPurgeRemovesOldReadNotifications(t *testing.T) {
+	db := dbtest.Open(t, &entities.Notification{})
+	now := time.Now()
+	notification := func(title string, read bool, age time.Duration) {
+		t.Helper()
+		if err := db.Create(&entities.Notification{ID: uuid.New(), UserID: uuid.New(), Type: entities.NotificationServerCrashed, Title: title}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&entities.Notification{}).Where("title = ?", title).
+			UpdateColumns(map[string]interface{}{"is_read": read, "created_at": now.Add(-age)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	notification("old read", true, 240*time.Hour)
+	notification("old unread", false, 240*time.Hour)
+	notification("recent read", true, time.Hour)
+
+	purger := NewRetentionPurger(db, config.RetentionConfig{Floor: 24 * time.Hour, Notifications: 72 * time.Hour}, zap.NewNop())
+	removed := purger.Purge(context.Background())
+	if removed["notifications"] != 1 {
+		t.Errorf("removed %d notifications, want 1", removed["notifications"])
+	}
+	var titles []string
+	if err := db.Model(&entities.Notification{}).Order("title").Pluck("title", &titles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(titles, []string{"old unread", "recent read"}) {
+		t.Errorf("kept %v, want unread and recent notifications", titles)
+	}
+}
@@ -0,0 +1,537 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ServerRepository implements repositories.ServerRepository
+type ServerRepository struct {
+	db *gorm.DB
+}
+
+// NewServerRepository creates a new ServerRepository
+func NewServerRepository(db *gorm.DB) *ServerRepository {
+	return &ServerRepository{db: db}
+}
+
+func (r *ServerRepository) Create(ctx context.Context, server *entities.Server) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(server).Error
+}
+
+func (r *ServerRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Server, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *ServerRepository) GetByUUID(ctx context.Context, uuid string) (*entities.Server, error) {
+	return r.first(ctx, "uuid = ?", uuid)
+}
+
+func (r *ServerRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Server, error) {
+	var server entities.Server
+	if err := r.db.WithContext(ctx).Scopes(NotTrashed).Where(query, args...).First(&server).Error; err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// Update writes the server if it was not changed since it was read, see
+// repositories.ErrVersionConflict
+func (r *ServerRepository) Update(ctx context.Context, server *entities.Server) error {
+	return updateVersioned(r.db.WithContext(ctx), server, &server.Version)
+}
+
+func (r *ServerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return SoftDelete(r.db.WithContext(ctx), &entities.Server{}, id)
+}
+
+func (r *ServerRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	return Purge(r.db.WithContext(ctx), &entities.Server{}, id)
+}
+
+func (r *ServerRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.Server, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.Server{}), params)
+}
+
+func (r *ServerRepository) GetByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]*entities.Server, error) {
+	var servers []*entities.Server
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("owner_id = ?", ownerID).Order("created_at").Find(&servers).Error
+	return servers, err
+}
+
+func (r *ServerRepository) GetByOwnerIDs(ctx context.Context, ownerIDs []uuid.UUID, params repositories.ListParams) ([]*entities.Server, int64, error) {
+	return r.page(r.db.WithContext(ctx).Model(&entities.Server{}).Where("owner_id IN ?", ownerIDs), params)
+}
+
+func (r *ServerRepository) GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Server, error) {
+	var servers []*entities.Server
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("node_id = ?", nodeID).Order("created_at").Find(&servers).Error
+	return servers, err
+}
+
+func (r *ServerRepository) page(query *gorm.DB, params repositories.ListParams) ([]*entities.Server, int64, error) {
+	query = query.Scopes(Trashed(params))
+	if params.Search != "" {
+		search := "%" + params.Search + "%"
+		query = query.Where("name ILIKE ? OR uuid ILIKE ?", search, search)
+	}
+	for _, key := range []string{"status", "node_id", "owner_id", "egg_id"} {
+		if value, ok := params.Filters[key]; ok {
+			query = query.Where(key+" = ?", value)
+		}
+	}
+
+	servers := make([]*entities.Server, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "created_at", "name", "status"), &servers)
+	return servers, total, err
+}
+
+func (r *ServerRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.ServerStatus) error {
+	return r.update(ctx, id, map[string]interface{}{"status": status})
+}
+
+func (r *ServerRepository) UpdateContainerID(ctx context.Context, id uuid.UUID, containerID string) error {
+	return r.update(ctx, id, map[string]interface{}{"container_id": containerID})
+}
+
+func (r *ServerRepository) Suspend(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"suspended":        true,
+		"suspended_reason": reason,
+		"status":           entities.ServerStatusSuspended,
+	})
+}
+
+func (r *ServerRepository) Unsuspend(ctx context.Context, id uuid.UUID) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"suspended":        false,
+		"suspended_reason": "",
+		"status":           entities.ServerStatusStopped,
+	})
+}
+
+func (r *ServerRepository) CountByNodeID(ctx context.Context, nodeID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Server{}).Scopes(NotTrashed).Where("node_id = ?", nodeID).Count(&count).Error
+	return count, err
+}
+
+func (r *ServerRepository) CountByOwnerID(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Server{}).Scopes(NotTrashed).Where("owner_id = ?", ownerID).Count(&count).Error
+	return count, err
+}
+
+// update changes columns of a server and bumps its version, so a concurrent
+// Update of a copy read before fails instead of reverting the change
+func (r *ServerRepository) update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := r.db.WithContext(ctx).Model(&entities.Server{}).Scopes(NotTrashed).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// NodeRepository implements repositories.NodeRepository
+type NodeRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeRepository creates a new NodeRepository
+func NewNodeRepository(db *gorm.DB) *NodeRepository {
+	return &NodeRepository{db: db}
+}
+
+func (r *NodeRepository) Create(ctx context.Context, node *entities.Node) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(node).Error
+}
+
+func (r *NodeRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Node, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *NodeRepository) GetByName(ctx context.Context, name string) (*entities.Node, error) {
+	return r.first(ctx, "name = ?", name)
+}
+
+func (r *NodeRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Node, error) {
+	var node entities.Node
+	if err := r.db.WithContext(ctx).Scopes(NotTrashed).Where(query, args...).First(&node).Error; err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// Update writes the node if it was not changed since it was read, see
+// repositories.ErrVersionConflict
+func (r *NodeRepository) Update(ctx context.Context, node *entities.Node) error {
+	return updateVersioned(r.db.WithContext(ctx), node, &node.Version)
+}
+
+func (r *NodeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return SoftDelete(r.db.WithContext(ctx), &entities.Node{}, id)
+}
+
+func (r *NodeRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.Node, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Node{}).Scopes(Trashed(params))
+	if params.Search != "" {
+		search := "%" + params.Search + "%"
+		query = query.Where("name ILIKE ? OR fqdn ILIKE ?", search, search)
+	}
+	if value, ok := params.Filters["location_id"]; ok {
+		query = query.Where("location_id = ?", value)
+	}
+
+	nodes := make([]*entities.Node, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "created_at", "name"), &nodes, preload("Location"))
+	return nodes, total, err
+}
+
+func (r *NodeRepository) GetByLocationID(ctx context.Context, locationID uuid.UUID) ([]*entities.Node, error) {
+	var nodes []*entities.Node
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("location_id = ?", locationID).Order("name").Find(&nodes).Error
+	return nodes, err
+}
+
+func (r *NodeRepository) GetAvailable(ctx context.Context, memoryRequired, diskRequired int64) ([]*entities.Node, error) {
+	var nodes []*entities.Node
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).
+		Where("is_online = ? AND maintenance_mode = ?", true, false).
+		Where("memory_total + memory_total * memory_overalloc / 100 - memory_allocated >= ?", memoryRequired).
+		Where("disk_total + disk_total * disk_overalloc / 100 - disk_allocated >= ?", diskRequired).
+		Order("name").
+		Find(&nodes).Error
+	return nodes, err
+}
+
+func (r *NodeRepository) UpdateOnlineStatus(ctx context.Context, id uuid.UUID, isOnline bool) error {
+	return r.update(ctx, id, map[string]interface{}{"is_online": isOnline, "last_checked_at": time.Now()})
+}
+
+// UpdateResources adds to the resources allocated on a node; negative
+// amounts release them
+func (r *NodeRepository) UpdateResources(ctx context.Context, id uuid.UUID, memoryAlloc, diskAlloc int64, cpuAlloc int) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"memory_allocated": gorm.Expr("memory_allocated + ?", memoryAlloc),
+		"disk_allocated":   gorm.Expr("disk_allocated + ?", diskAlloc),
+		"cpu_allocated":    gorm.Expr("cpu_allocated + ?", cpuAlloc),
+	})
+}
+
+func (r *NodeRepository) SetMaintenanceMode(ctx context.Context, id uuid.UUID, maintenance bool) error {
+	return r.update(ctx, id, map[string]interface{}{"maintenance_mode": maintenance})
+}
+
+func (r *NodeRepository) update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := r.db.WithContext(ctx).Model(&entities.Node{}).Scopes(NotTrashed).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// LocationRepository implements repositories.LocationRepository
+type LocationRepository struct {
+	db *gorm.DB
+}
+
+// NewLocationRepository creates a new LocationRepository
+func NewLocationRepository(db *gorm.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+func (r *LocationRepository) Create(ctx context.Context, location *entities.Location) error {
+	return r.db.WithContext(ctx).Create(location).Error
+}
+
+func (r *LocationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Location, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *LocationRepository) GetByShortCode(ctx context.Context, shortCode string) (*entities.Location, error) {
+	return r.first(ctx, "short_code = ?", shortCode)
+}
+
+func (r *LocationRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Location, error) {
+	var location entities.Location
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&location).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *LocationRepository) Update(ctx context.Context, location *entities.Location) error {
+	return r.db.WithContext(ctx).Save(location).Error
+}
+
+func (r *LocationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Location{}).Error
+}
+
+func (r *LocationRepository) List(ctx context.Context) ([]*entities.Location, error) {
+	var locations []*entities.Location
+	err := r.db.WithContext(ctx).Order("name").Find(&locations).Error
+	return locations, err
+}
+
+// AllocationRepository implements repositories.AllocationRepository
+type AllocationRepository struct {
+	db *gorm.DB
+}
+
+// NewAllocationRepository creates a new AllocationRepository
+func NewAllocationRepository(db *gorm.DB) *AllocationRepository {
+	return &AllocationRepository{db: db}
+}
+
+func (r *AllocationRepository) Create(ctx context.Context, allocation *entities.Allocation) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(allocation).Error
+}
+
+func (r *AllocationRepository) CreateBatch(ctx context.Context, allocations []*entities.Allocation) error {
+	if len(allocations) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).CreateInBatches(allocations, 500).Error
+}
+
+func (r *AllocationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Allocation, error) {
+	var allocation entities.Allocation
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&allocation).Error; err != nil {
+		return nil, err
+	}
+	return &allocation, nil
+}
+
+func (r *AllocationRepository) Update(ctx context.Context, allocation *entities.Allocation) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(allocation).Error
+}
+
+func (r *AllocationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Allocation{}).Error
+}
+
+func (r *AllocationRepository) GetByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Allocation, error) {
+	return r.find(ctx, "node_id = ?", nodeID)
+}
+
+func (r *AllocationRepository) GetAvailableByNodeID(ctx context.Context, nodeID uuid.UUID) ([]*entities.Allocation, error) {
+	return r.find(ctx, "node_id = ? AND server_id IS NULL", nodeID)
+}
+
+func (r *AllocationRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	err := r.db.WithContext(ctx).Where("server_id = ?", serverID).Order("is_primary DESC, port").Find(&allocations).Error
+	return allocations, err
+}
+
+func (r *AllocationRepository) find(ctx context.Context, query string, args ...interface{}) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	err := r.db.WithContext(ctx).Where(query, args...).Order("ip, port").Find(&allocations).Error
+	return allocations, err
+}
+
+// AssignToServer assigns a free allocation to a server. It fails with
+// gorm.ErrRecordNotFound when the allocation was taken in the meantime.
+func (r *AllocationRepository) AssignToServer(ctx context.Context, id uuid.UUID, serverID uuid.UUID, isPrimary bool) error {
+	result := r.db.WithContext(ctx).Model(&entities.Allocation{}).
+		Where("id = ? AND server_id IS NULL", id).
+		Updates(map[string]interface{}{"server_id": serverID, "is_primary": isPrimary})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *AllocationRepository) Unassign(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Allocation{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"server_id": nil, "is_primary": false}).Error
+}
+
+func (r *AllocationRepository) IsPortAvailable(ctx context.Context, nodeID uuid.UUID, ip string, port int) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Allocation{}).
+		Where("node_id = ? AND ip = ? AND port = ?", nodeID, ip, port).
+		Count(&count).Error
+	return count == 0, err
+}
+
+func (r *AllocationRepository) GetOnOtherNodes(ctx context.Context, nodeID uuid.UUID, ip string, portStart, portEnd int) ([]*entities.Allocation, error) {
+	var allocations []*entities.Allocation
+	err := r.db.WithContext(ctx).
+		Where("node_id <> ? AND ip = ? AND port BETWEEN ? AND ?", nodeID, ip, portStart, portEnd).
+		Order("port").
+		Find(&allocations).Error
+	return allocations, err
+}
+
+// ServerVariableRepository implements repositories.ServerVariableRepository
+type ServerVariableRepository struct {
+	db *gorm.DB
+}
+
+// NewServerVariableRepository creates a new ServerVariableRepository
+func NewServerVariableRepository(db *gorm.DB) *ServerVariableRepository {
+	return &ServerVariableRepository{db: db}
+}
+
+func (r *ServerVariableRepository) Create(ctx context.Context, variable *entities.ServerVariable) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(variable).Error
+}
+
+func (r *ServerVariableRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.ServerVariable, error) {
+	var variable entities.ServerVariable
+	if err := r.db.WithContext(ctx).Preload("EggVariable").Where("id = ?", id).First(&variable).Error; err != nil {
+		return nil, err
+	}
+	return &variable, nil
+}
+
+func (r *ServerVariableRepository) Update(ctx context.Context, variable *entities.ServerVariable) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(variable).Error
+}
+
+func (r *ServerVariableRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.ServerVariable{}).Error
+}
+
+func (r *ServerVariableRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerVariable, error) {
+	var variables []*entities.ServerVariable
+	err := r.db.WithContext(ctx).Preload("EggVariable").Where("server_id = ?", serverID).Find(&variables).Error
+	return variables, err
+}
+
+// Upsert sets the value of a server's variable, creating it if the server
+// has none for the egg variable yet
+func (r *ServerVariableRepository) Upsert(ctx context.Context, serverID, eggVariableID uuid.UUID, value string) error {
+	result := r.db.WithContext(ctx).Model(&entities.ServerVariable{}).
+		Where("server_id = ? AND egg_variable_id = ?", serverID, eggVariableID).
+		Update("value", value)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return r.Create(ctx, &entities.ServerVariable{ServerID: serverID, EggVariableID: eggVariableID, Value: value})
+}
+
+// updateVersioned writes all columns of model if its stored version still
+// equals *version, incrementing it on success
+func updateVersioned(db *gorm.DB, model interface{}, version *int) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(model).Where("version = ?", expected).Select("*").Omit("created_at", clause.Associations).Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return repositories.ErrVersionConflict
+	}
+	return nil
+}
+
+// DatabaseHostRepository implements repositories.DatabaseHostRepository
+type DatabaseHostRepository struct {
+	db *gorm.DB
+}
+
+// NewDatabaseHostRepository creates a new DatabaseHostRepository
+func NewDatabaseHostRepository(db *gorm.DB) *DatabaseHostRepository {
+	return &DatabaseHostRepository{db: db}
+}
+
+func (r *DatabaseHostRepository) Create(ctx context.Context, host *entities.DatabaseHost) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(host).Error
+}
+
+func (r *DatabaseHostRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.DatabaseHost, error) {
+	var host entities.DatabaseHost
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&host).Error; err != nil {
+		return nil, err
+	}
+	return &host, nil
+}
+
+func (r *DatabaseHostRepository) Update(ctx context.Context, host *entities.DatabaseHost) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(host).Error
+}
+
+func (r *DatabaseHostRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.DatabaseHost{}).Error
+}
+
+func (r *DatabaseHostRepository) List(ctx context.Context) ([]*entities.DatabaseHost, error) {
+	var hosts []*entities.DatabaseHost
+	err := r.db.WithContext(ctx).Order("name").Find(&hosts).Error
+	return hosts, err
+}
+
+func (r *DatabaseHostRepository) GetForNode(ctx context.Context, nodeID uuid.UUID) ([]*entities.DatabaseHost, error) {
+	var hosts []*entities.DatabaseHost
+	err := r.db.WithContext(ctx).
+		Where("node_id = ? OR node_id IS NULL", nodeID).
+		Where("max_databases = 0 OR max_databases > (SELECT COUNT(*) FROM server_databases WHERE database_host_id = database_hosts.id)").
+		Order("node_id IS NULL, name").
+		Find(&hosts).Error
+	return hosts, err
+}
+
+// ServerDatabaseRepository implements repositories.ServerDatabaseRepository
+type ServerDatabaseRepository struct {
+	db *gorm.DB
+}
+
+// NewServerDatabaseRepository creates a new ServerDatabaseRepository
+func NewServerDatabaseRepository(db *gorm.DB) *ServerDatabaseRepository {
+	return &ServerDatabaseRepository{db: db}
+}
+
+func (r *ServerDatabaseRepository) Create(ctx context.Context, database *entities.ServerDatabase) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(database).Error
+}
+
+func (r *ServerDatabaseRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.ServerDatabase, error) {
+	var database entities.ServerDatabase
+	if err := r.db.WithContext(ctx).Preload("DatabaseHost").Where("id = ?", id).First(&database).Error; err != nil {
+		return nil, err
+	}
+	return &database, nil
+}
+
+func (r *ServerDatabaseRepository) Update(ctx context.Context, database *entities.ServerDatabase) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(database).Error
+}
+
+func (r *ServerDatabaseRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.ServerDatabase{}).Error
+}
+
+func (r *ServerDatabaseRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.ServerDatabase, error) {
+	var databases []*entities.ServerDatabase
+	err := r.db.WithContext(ctx).Preload("DatabaseHost").Where("server_id = ?", serverID).Order("created_at").Find(&databases).Error
+	return databases, err
+}
+
+func (r *ServerDatabaseRepository) CountByServerID(ctx context.Context, serverID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.ServerDatabase{}).Where("server_id = ?", serverID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Runtime settings
+const (
+	SettingRegistrationEnabled = "registration_enabled"
+	SettingDefaultBackupLimit  = "default_backup_limit"
+	SettingMaintenanceMessage  = "maintenance_message"
+	SettingSafetyBackups       = "safety_backups"
+)
+
+// settingsCacheKey holds every stored setting, shared by all panel instances
+const settingsCacheKey = "settings:all"
+
+// settingsCacheTTL bounds how long an instance can serve a value changed
+// without going through Settings
+const settingsCacheTTL = 5 * time.Minute
+
+// ErrUnknownSetting is returned for a key that is not a defined setting
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// SettingDefinition describes a setting admins may change
+type SettingDefinition struct {
+	Type        entities.SettingType `json:"type"`
+	Default     string               `json:"default"`
+	Description string               `json:"description"`
+	Min         int                  `json:"min,omitempty"` // Int settings only
+	Max         int                  `json:"max,omitempty"` // Max value of ints, max length of strings
+}
+
+var settingDefinitions = map[string]SettingDefinition{
+	SettingRegistrationEnabled: {
+		Type:        entities.SettingTypeBool,
+		Default:     "true",
+		Description: "Allow new users to register",
+	},
+	SettingDefaultBackupLimit: {
+		Type:        entities.SettingTypeInt,
+		Default:     "2",
+		Description: "Backup limit given to new servers",
+		Min:         1, // Zero would be replaced by the column default on insert
+		Max:         100,
+	},
+	SettingMaintenanceMessage: {
+		Type:        entities.SettingTypeString,
+		Default:     "The panel is undergoing maintenance, changes are temporarily disabled",
+		Description: "Message shown when maintenance mode is enabled without one",
+		Max:         500,
+	},
+	SettingSafetyBackups: {
+		Type:        entities.SettingTypeBool,
+		Default:     "false",
+		Description: "Back up servers before reinstalls and restores unless the request opts out",
+	},
+}
+
+// SettingValueError is returned when a value does not fit its setting
+type SettingValueError struct {
+	Key     string
+	Message string
+}
+
+func (e *SettingValueError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// SettingValue is a setting with its effective value
+type SettingValue struct {
+	Key        string            `json:"key"`
+	Value      interface{}       `json:"value"`
+	Default    interface{}       `json:"default"`
+	IsDefault  bool              `json:"is_default"`
+	UpdatedAt  *time.Time        `json:"updated_at,omitempty"`
+	UpdatedBy  *uuid.UUID        `json:"updated_by,omitempty"`
+	Definition SettingDefinition `json:"definition"`
+}
+
+// Settings reads and writes runtime settings. Reads are served from a Redis
+// copy of the settings table that writes invalidate, so a change applies on
+// every panel instance without a restart.
+type Settings struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+// NewSettings creates a new Settings
+func NewSettings(db *gorm.DB, rdb *redis.Client) *Settings {
+	return &Settings{db: db, redis: rdb}
+}
+
+// stored returns the stored settings by key, from the cache when possible
+func (s *Settings) stored(ctx context.Context) (map[string]entities.Setting, error) {
+	var settings map[string]entities.Setting
+	if err := s.redis.GetJSON(ctx, settingsCacheKey, &settings); err == nil {
+		return settings, nil
+	}
+
+	var rows []entities.Setting
+	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	settings = make(map[string]entities.Setting, len(rows))
+	for _, row := range rows {
+		settings[row.Key] = row
+	}
+	_ = s.redis.SetJSON(ctx, settingsCacheKey, settings, settingsCacheTTL)
+	return settings, nil
+}
+
+// raw returns the text value of a setting, falling back to its default when
+// it was never set or cannot be read
+func (s *Settings) raw(ctx context.Context, key string) string {
+	def := settingDefinitions[key]
+	settings, err := s.stored(ctx)
+	if err != nil {
+		return def.Default
+	}
+	if setting, ok := settings[key]; ok && setting.Type == def.Type {
+		return setting.Value
+	}
+	return def.Default
+}
+
+// Bool returns the value of a bool setting
+func (s *Settings) Bool(ctx context.Context, key string) bool {
+	v, _ := strconv.ParseBool(s.raw(ctx, key))
+	return v
+}
+
+// Int returns the value of an int setting
+func (s *Settings) Int(ctx context.Context, key string) int {
+	v, _ := strconv.Atoi(s.raw(ctx, key))
+	return v
+}
+
+// String returns the value of a string setting
+func (s *Settings) String(ctx context.Context, key string) string {
+	return s.raw(ctx, key)
+}
+
+// All returns every defined setting with its effective value, sorted by key
+func (s *Settings) All(ctx context.Context) ([]SettingValue, error) {
+	settings, err := s.stored(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]SettingValue, 0, len(settingDefinitions))
+	for key, def := range settingDefinitions {
+		value := SettingValue{
+			Key:        key,
+			Value:      typedSetting(def.Type, def.Default),
+			Default:    typedSetting(def.Type, def.Default),
+			IsDefault:  true,
+			Definition: def,
+		}
+		if setting, ok := settings[key]; ok && setting.Type == def.Type {
+			updatedAt := setting.UpdatedAt
+			value.Value = typedSetting(def.Type, setting.Value)
+			value.IsDefault = false
+			value.UpdatedAt = &updatedAt
+			value.UpdatedBy = setting.UpdatedBy
+		}
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Key < values[j].Key
+	})
+	return values, nil
+}
+
+// Set stores a setting from its JSON value, which must match the setting's
+// type and bounds. The cache is dropped so every instance reads the change.
+func (s *Settings) Set(ctx context.Context, key string, value json.RawMessage, updatedBy uuid.UUID) error {
+	def, ok := settingDefinitions[key]
+	if !ok {
+		return ErrUnknownSetting
+	}
+
+	if len(value) == 0 || string(value) == "null" {
+		return &SettingValueError{Key: key, Message: "is required"}
+	}
+	text, err := parseSetting(key, def, value)
+	if err != nil {
+		return err
+	}
+
+	setting := entities.Setting{Key: key, Type: def.Type, Value: text, UpdatedBy: &updatedBy}
+	if err := s.db.WithContext(ctx).Save(&setting).Error; err != nil {
+		return err
+	}
+	return s.redis.Delete(ctx, settingsCacheKey)
+}
+
+// parseSetting checks a JSON value against a definition and returns its text form
+func parseSetting(key string, def SettingDefinition, value json.RawMessage) (string, error) {
+	switch def.Type {
+	case entities.SettingTypeBool:
+		var v bool
+		if err := json.Unmarshal(value, &v); err != nil {
+			return "", &SettingValueError{Key: key, Message: "must be a boolean"}
+		}
+		return strconv.FormatBool(v), nil
+
+	case entities.SettingTypeInt:
+		var v int
+		if err := json.Unmarshal(value, &v); err != nil {
+			return "", &SettingValueError{Key: key, Message: "must be an integer"}
+		}
+		if v < def.Min || v > def.Max {
+			return "", &SettingValueError{Key: key, Message: fmt.Sprintf("must be between %d and %d", def.Min, def.Max)}
+		}
+		return strconv.Itoa(v), nil
+
+	default:
+		var v string
+		if err := json.Unmarshal(value, &v); err != nil {
+			return "", &SettingValueError{Key: key, Message: "must be a string"}
+		}
+		if def.Max > 0 && len(v) > def.Max {
+			return "", &SettingValueError{Key: key, Message: fmt.Sprintf("must be at most %d characters", def.Max)}
+		}
+		return v, nil
+	}
+}
+
+// typedSetting converts a setting's text value for JSON output
+func typedSetting(t entities.SettingType, text string) interface{} {
+	switch t {
+	case entities.SettingTypeBool:
+		v, _ := strconv.ParseBool(text)
+		return v
+	case entities.SettingTypeInt:
+		v, _ := strconv.Atoi(text)
+		return v
+	}
+	return text
+}
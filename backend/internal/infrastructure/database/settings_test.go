@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// newTestSettings returns two panel instances' Settings sharing a database
+// and a Redis
+func newTestSettings(t *testing.T) (*Settings, *Settings, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &entities.Setting{})
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return NewSettings(db, rdb), NewSettings(db, rdb), db
+}
+
+func TestSettingsTakeEffectLive(t *testing.T) {
+	first, second, _ := newTestSettings(t)
+	ctx := context.Background()
+	admin := uuid.New()
+
+	// Defaults apply until a setting is stored, and are now cached
+	if !first.Bool(ctx, SettingRegistrationEnabled) || first.Int(ctx, SettingDefaultBackupLimit) != 2 {
+		t.Fatal("defaults not applied")
+	}
+
+	for key, value := range map[string]string{
+		SettingRegistrationEnabled: `false`,
+		SettingDefaultBackupLimit:  `5`,
+		SettingMaintenanceMessage:  `"Back at noon"`,
+	} {
+		if err := second.Set(ctx, key, json.RawMessage(value), admin); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+
+	// The other instance reads the changes without a restart
+	if first.Bool(ctx, SettingRegistrationEnabled) {
+		t.Error("registration still enabled")
+	}
+	if got := first.Int(ctx, SettingDefaultBackupLimit); got != 5 {
+		t.Errorf("backup limit = %d, want 5", got)
+	}
+	if got := first.String(ctx, SettingMaintenanceMessage); got != "Back at noon" {
+		t.Errorf("maintenance message = %q", got)
+	}
+
+	values, err := first.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range values {
+		if value.Key == SettingDefaultBackupLimit {
+			if value.Value != 5 || value.Default != 2 || value.IsDefault || value.UpdatedBy == nil || *value.UpdatedBy != admin {
+				t.Errorf("listed %+v, want 5 set by the admin", value)
+			}
+		}
+		if value.Key == SettingSafetyBackups && !value.IsDefault {
+			t.Errorf("%s listed as changed", value.Key)
+		}
+	}
+}
+
+func TestSettingsRejectInvalidValues(t *testing.T) {
+	settings, _, db := newTestSettings(t)
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		key   string
+		value string
+	}{
+		"string for a bool":   {SettingRegistrationEnabled, `"false"`},
+		"number for a bool":   {SettingRegistrationEnabled, `0`},
+		"fraction for an int": {SettingDefaultBackupLimit, `2.5`},
+		"string for an int":   {SettingDefaultBackupLimit, `"5"`},
+		"int below its min":   {SettingDefaultBackupLimit, `0`},
+		"int above its max":   {SettingDefaultBackupLimit, `101`},
+		"bool for a string":   {SettingMaintenanceMessage, `true`},
+		"missing value":       {SettingMaintenanceMessage, `null`},
+	} {
+		var valueErr *SettingValueError
+		if err := settings.Set(ctx, tc.key, json.RawMessage(tc.value), uuid.New()); !errors.As(err, &valueErr) || valueErr.Key != tc.key {
+			t.Errorf("%s: err = %v, want a value error for %s", name, err, tc.key)
+		}
+	}
+	if err := settings.Set(ctx, "debug_mode", json.RawMessage(`true`), uuid.New()); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("unknown setting = %v, want %v", err, ErrUnknownSetting)
+	}
+
+	var stored int64
+	if err := db.Model(&entities.Setting{}).Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("%d settings stored, want none", stored)
+	}
+	if got := settings.Int(ctx, SettingDefaultBackupLimit); got != 2 {
+		t.Errorf("backup limit = %d, want the default", got)
+	}
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// DeletedAt fields are plain timestamps rather than gorm.DeletedAt, so GORM
+// does not filter soft deleted rows itself. Repositories apply these helpers
+// to every query on such a table.
+
+// NotTrashed scopes a query to rows that are not soft deleted
+func NotTrashed(tx *gorm.DB) *gorm.DB {
+	return tx.Where("deleted_at IS NULL")
+}
+
+// Trashed scopes a list query to the rows params asks for: rows that are not
+// soft deleted, or all rows when params.WithTrashed is set
+func Trashed(params repositories.ListParams) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if params.WithTrashed {
+			return tx
+		}
+		return NotTrashed(tx)
+	}
+}
+
+// SoftDelete marks the row of model with the given id as deleted. Deleting a
+// row that is already soft deleted keeps its original deletion time.
+func SoftDelete(db *gorm.DB, model interface{}, id interface{}) error {
+	result := db.Model(model).Scopes(NotTrashed).Where("id = ?", id).Update("deleted_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes the row of model with the given id, whether soft
+// deleted or not
+func Purge(db *gorm.DB, model interface{}, id interface{}) error {
+	result := db.Where("id = ?", id).Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestTrashedUsers(t *testing.T) {
+	db := dbtest.Open(t, &entities.Role{}, &entities.User{})
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	users := map[string]*entities.User{}
+	for _, name := range []string{"kept", "trashed", "purged"} {
+		user := &entities.User{ID: uuid.New(), Email: name + "@example.com", Username: name, PasswordHash: "hash"}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+	}
+	for _, name := range []string{"trashed", "purged"} {
+		if err := repo.Delete(ctx, users[name].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Delete(ctx, users["trashed"].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("deleting a trashed user again: %v, want not found", err)
+	}
+
+	listed := func(params repositories.ListParams) []string {
+		t.Helper()
+		params.Page, params.PageSize, params.SortBy, params.SortDir = 1, 10, "username", "asc"
+		found, total, err := repo.List(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(found))
+		for _, u := range found {
+			names = append(names, u.Username)
+		}
+		if total != int64(len(names)) {
+			t.Errorf("total %d for %v", total, names)
+		}
+		return names
+	}
+
+	if got := listed(repositories.ListParams{}); len(got) != 1 || got[0] != "kept" {
+		t.Errorf("listed %v, want trashed users left out", got)
+	}
+	if _, err := repo.GetByID(ctx, users["trashed"].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("looking up a trashed user: %v, want not found", err)
+	}
+	if got := listed(repositories.ListParams{WithTrashed: true}); len(got) != 3 {
+		t.Errorf("listed %v with trashed, want every user", got)
+	}
+
+	if err := repo.Purge(ctx, users["purged"].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := listed(repositories.ListParams{WithTrashed: true}); len(got) != 2 || got[0] != "kept" || got[1] != "trashed" {
+		t.Errorf("listed %v with trashed after purging, want the purged user gone", got)
+	}
+	// Users that were never trashed can be purged as well
+	if err := repo.Purge(ctx, users["kept"].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Purge(ctx, users["kept"].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("purging a purged user: %v, want not found", err)
+	}
+}
+
+func TestTrashedServers(t *testing.T) {
+	db := dbtest.Open(t, &entities.Server{})
+	repo := NewServerRepository(db)
+	ctx := context.Background()
+	owner := uuid.New()
+
+	var ids []uuid.UUID
+	for _, name := range []string{"kept", "trashed"} {
+		server := &entities.Server{ID: uuid.New(), UUID: name, Name: name, OwnerID: owner, NodeID: uuid.New()}
+		if err := db.Create(server).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, server.ID)
+	}
+	if err := repo.Delete(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := repo.CountByOwnerID(ctx, owner); err != nil || count != 1 {
+		t.Errorf("owner has %d servers (%v), want the trashed one left out", count, err)
+	}
+	var stored int64
+	if err := db.Model(&entities.Server{}).Count(&stored).Error; err != nil || stored != 2 {
+		t.Errorf("%d rows stored (%v), want the trashed server kept", stored, err)
+	}
+
+	if err := repo.Purge(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&entities.Server{}).Count(&stored).Error; err != nil || stored != 1 {
+		t.Errorf("%d rows stored (%v), want the purged server removed", stored, err)
+	}
+}
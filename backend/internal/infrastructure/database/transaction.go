@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// WithTransaction runs fn inside a GORM transaction. The transaction is rolled
+// back when fn returns an error or panics; a panic is returned as an error.
+func WithTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("transaction panicked: %v", r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback().Error; rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RepositoryFactory builds repositories that operate on the given transaction
+type RepositoryFactory func(tx *gorm.DB) repositories.TxRepositories
+
+// UnitOfWork implements repositories.UnitOfWork on top of GORM transactions
+type UnitOfWork struct {
+	db      *gorm.DB
+	factory RepositoryFactory
+}
+
+// NewUnitOfWork creates a new UnitOfWork
+func NewUnitOfWork(db *gorm.DB, factory RepositoryFactory) *UnitOfWork {
+	return &UnitOfWork{db: db, factory: factory}
+}
+
+// Do runs fn with transaction-scoped repositories
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repositories.TxRepositories) error) error {
+	return WithTransaction(ctx, u.db, func(tx *gorm.DB) error {
+		return fn(ctx, u.factory(tx))
+	})
+}
+
+// txRepositories implements repositories.TxRepositories with the GORM
+// repositories of one transaction
+type txRepositories struct {
+	tx *gorm.DB
+}
+
+// NewTxRepositories is the RepositoryFactory of the GORM repositories
+func NewTxRepositories(tx *gorm.DB) repositories.TxRepositories {
+	return txRepositories{tx: tx}
+}
+
+func (r txRepositories) Users() repositories.UserRepository {
+	return NewUserRepository(r.tx)
+}
+
+func (r txRepositories) UserQuotas() repositories.UserQuotaRepository {
+	return NewUserQuotaRepository(r.tx)
+}
+
+func (r txRepositories) Servers() repositories.ServerRepository {
+	return NewServerRepository(r.tx)
+}
+
+func (r txRepositories) Nodes() repositories.NodeRepository {
+	return NewNodeRepository(r.tx)
+}
+
+func (r txRepositories) Allocations() repositories.AllocationRepository {
+	return NewAllocationRepository(r.tx)
+}
+
+func (r txRepositories) ServerVariables() repositories.ServerVariableRepository {
+	return NewServerVariableRepository(r.tx)
+}
+
+func (r txRepositories) Transactions() repositories.TransactionRepository {
+	return NewTransactionRepository(r.tx)
+}
+
+func (r txRepositories) Subscriptions() repositories.SubscriptionRepository {
+	return NewSubscriptionRepository(r.tx)
+}
+
+func (r txRepositories) Invoices() repositories.InvoiceRepository {
+	return NewInvoiceRepository(r.tx)
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ledger is the state behind txTestDriver: statements executed in a
+// transaction stay pending until it commits
+type ledger struct {
+	mu        sync.Mutex
+	committed []string
+	pending   []string
+	rollbacks int
+}
+
+type txTestDriver struct{ ledger *ledger }
+
+func (d txTestDriver) Open(name string) (driver.Conn, error) {
+	return &txTestConn{ledger: d.ledger}, nil
+}
+
+type txTestConn struct {
+	ledger *ledger
+	inTx   bool
+}
+
+func (c *txTestConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *txTestConn) Close() error { return nil }
+
+func (c *txTestConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txTestConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *txTestConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.ledger.mu.Lock()
+	defer c.ledger.mu.Unlock()
+	if c.inTx {
+		c.ledger.pending = append(c.ledger.pending, query)
+	} else {
+		c.ledger.committed = append(c.ledger.committed, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *txTestConn) Commit() error {
+	c.ledger.mu.Lock()
+	defer c.ledger.mu.Unlock()
+	c.ledger.committed = append(c.ledger.committed, c.ledger.pending...)
+	c.ledger.pending = nil
+	c.inTx = false
+	return nil
+}
+
+func (c *txTestConn) Rollback() error {
+	c.ledger.mu.Lock()
+	defer c.ledger.mu.Unlock()
+	c.ledger.pending = nil
+	c.ledger.rollbacks++
+	c.inTx = false
+	return nil
+}
+
+// newLedgerDB opens a GORM database whose writes land in a ledger
+func newLedgerDB(t *testing.T) (*gorm.DB, *ledger) {
+	t.Helper()
+	l := &ledger{}
+	name := "txtest-" + strings.ReplaceAll(t.Name(), "/", "-")
+	sql.Register(name, txTestDriver{ledger: l})
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, l
+}
+
+func TestWithTransactionCommits(t *testing.T) {
+	db, l := newLedgerDB(t)
+
+	err := WithTransaction(context.Background(), db, func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET credits = credits - 5").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE users SET credits = credits + 5").Error
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction = %v", err)
+	}
+	if len(l.committed) != 2 || l.rollbacks != 0 {
+		t.Errorf("committed %d writes with %d rollbacks, want 2 and 0", len(l.committed), l.rollbacks)
+	}
+}
+
+func TestWithTransactionRollsBackOnError(t *testing.T) {
+	db, l := newLedgerDB(t)
+	errTransfer := errors.New("recipient not found")
+
+	err := WithTransaction(context.Background(), db, func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET credits = credits - 5").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO transactions DEFAULT VALUES").Error; err != nil {
+			return err
+		}
+		return errTransfer
+	})
+	if !errors.Is(err, errTransfer) {
+		t.Fatalf("WithTransaction = %v, want %v", err, errTransfer)
+	}
+	if len(l.committed) != 0 || len(l.pending) != 0 {
+		t.Errorf("kept writes %q %q after the error, want none", l.committed, l.pending)
+	}
+	if l.rollbacks != 1 {
+		t.Errorf("rolled back %d times, want 1", l.rollbacks)
+	}
+}
+
+func TestWithTransactionRecoversPanic(t *testing.T) {
+	db, l := newLedgerDB(t)
+
+	err := WithTransaction(context.Background(), db, func(tx *gorm.DB) error {
+		tx.Exec("UPDATE servers SET status = 'installing'")
+		panic("allocation list is empty")
+	})
+	if err == nil || !strings.Contains(err.Error(), "transaction panicked") {
+		t.Fatalf("WithTransaction = %v, want the recovered panic", err)
+	}
+	if len(l.committed) != 0 || l.rollbacks != 1 {
+		t.Errorf("committed %d writes with %d rollbacks, want 0 and 1", len(l.committed), l.rollbacks)
+	}
+}
+
+func TestUnitOfWorkScopesRepositoriesToTransaction(t *testing.T) {
+	db, l := newLedgerDB(t)
+	errCreate := errors.New("allocation taken")
+
+	var scoped *gorm.DB
+	uow := NewUnitOfWork(db, func(tx *gorm.DB) repositories.TxRepositories {
+		scoped = tx
+		return NewTxRepositories(tx)
+	})
+	err := uow.Do(context.Background(), func(ctx context.Context, repos repositories.TxRepositories) error {
+		if err := scoped.Exec("INSERT INTO servers DEFAULT VALUES").Error; err != nil {
+			return err
+		}
+		return errCreate
+	})
+	if !errors.Is(err, errCreate) {
+		t.Fatalf("Do = %v, want %v", err, errCreate)
+	}
+	if scoped == db {
+		t.Error("repositories were built on the database, not the transaction")
+	}
+	if len(l.committed) != 0 || l.rollbacks != 1 {
+		t.Errorf("committed %d writes with %d rollbacks, want 0 and 1", len(l.committed), l.rollbacks)
+	}
+}
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRepository implements repositories.UserRepository
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.User, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	return r.first(ctx, "LOWER(email) = LOWER(?)", email)
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entities.User, error) {
+	return r.first(ctx, "LOWER(username) = LOWER(?)", username)
+}
+
+func (r *UserRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.User, error) {
+	var user entities.User
+	if err := r.db.WithContext(ctx).Scopes(NotTrashed).Preload("Role").Where(query, args...).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *entities.User) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(user).Error
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return SoftDelete(r.db.WithContext(ctx), &entities.User{}, id)
+}
+
+func (r *UserRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	return Purge(r.db.WithContext(ctx), &entities.User{}, id)
+}
+
+func (r *UserRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.User{}).Scopes(Trashed(params))
+	if params.Search != "" {
+		search := "%" + params.Search + "%"
+		query = query.Where("email ILIKE ? OR username ILIKE ?", search, search)
+	}
+	for _, key := range []string{"status", "role_id", "reseller_id"} {
+		if value, ok := params.Filters[key]; ok {
+			query = query.Where(key+" = ?", value)
+		}
+	}
+
+	users := make([]*entities.User, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "created_at", "email", "username", "last_login_at"), &users, preload("Role"))
+	return users, total, err
+}
+
+func (r *UserRepository) GetByResellerID(ctx context.Context, resellerID uuid.UUID) ([]*entities.User, error) {
+	var users []*entities.User
+	err := r.db.WithContext(ctx).Scopes(NotTrashed).Where("reseller_id = ?", resellerID).Order("created_at").Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) UpdateCredits(ctx context.Context, id uuid.UUID, amount float64) error {
+	return r.update(ctx, id, map[string]interface{}{"credits": gorm.Expr("credits + ?", amount)})
+}
+
+func (r *UserRepository) IncrementFailedLogin(ctx context.Context, id uuid.UUID) error {
+	return r.update(ctx, id, map[string]interface{}{"failed_login_count": gorm.Expr("failed_login_count + 1")})
+}
+
+func (r *UserRepository) ResetFailedLogin(ctx context.Context, id uuid.UUID) error {
+	return r.update(ctx, id, map[string]interface{}{"failed_login_count": 0, "locked_until": nil})
+}
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID, ip string) error {
+	return r.update(ctx, id, map[string]interface{}{"last_login_at": time.Now(), "last_login_ip": ip})
+}
+
+func (r *UserRepository) update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&entities.User{}).Scopes(NotTrashed).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SessionRepository implements repositories.SessionRepository
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *entities.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Session, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *SessionRepository) GetByToken(ctx context.Context, token string) (*entities.Session, error) {
+	return r.first(ctx, "token = ?", token)
+}
+
+func (r *SessionRepository) GetByRefreshToken(ctx context.Context, refreshToken string) (*entities.Session, error) {
+	return r.first(ctx, "refresh_token = ?", refreshToken)
+}
+
+func (r *SessionRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Session, error) {
+	var session entities.Session
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.Session, error) {
+	var sessions []*entities.Session
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_activity DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) Update(ctx context.Context, session *entities.Session) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(session).Error
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *SessionRepository) RevokeAllByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&entities.Session{}).Error
+}
+
+func (r *SessionRepository) MarkRefreshTokenUsed(ctx context.Context, used *entities.UsedRefreshToken) error {
+	return r.db.WithContext(ctx).Create(used).Error
+}
+
+func (r *SessionRepository) GetUsedRefreshToken(ctx context.Context, tokenHash string) (*entities.UsedRefreshToken, error) {
+	var used entities.UsedRefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&used).Error; err != nil {
+		return nil, err
+	}
+	return &used, nil
+}
+
+// UserQuotaRepository implements repositories.UserQuotaRepository
+type UserQuotaRepository struct {
+	db *gorm.DB
+}
+
+// NewUserQuotaRepository creates a new UserQuotaRepository
+func NewUserQuotaRepository(db *gorm.DB) *UserQuotaRepository {
+	return &UserQuotaRepository{db: db}
+}
+
+func (r *UserQuotaRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*entities.UserQuota, error) {
+	var quota entities.UserQuota
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&quota).Error; err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// Upsert creates the user's quota or replaces its limits
+func (r *UserQuotaRepository) Upsert(ctx context.Context, quota *entities.UserQuota) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_servers", "memory_limit", "disk_limit", "cpu_limit", "set_by", "updated_at"}),
+	}).Create(quota).Error
+}
+
+func (r *UserQuotaRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.UserQuota{}).Error
+}
+
+// RoleRepository implements repositories.RoleRepository
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new RoleRepository
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+func (r *RoleRepository) Create(ctx context.Context, role *entities.Role) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(role).Error
+}
+
+func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Role, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *RoleRepository) GetByName(ctx context.Context, name string) (*entities.Role, error) {
+	return r.first(ctx, "name = ?", name)
+}
+
+func (r *RoleRepository) GetDefault(ctx context.Context) (*entities.Role, error) {
+	return r.first(ctx, "is_default = ?", true)
+}
+
+func (r *RoleRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.Role, error) {
+	var role entities.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where(query, args...).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) Update(ctx context.Context, role *entities.Role) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(role).Error
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Role{}).Error
+}
+
+func (r *RoleRepository) List(ctx context.Context) ([]*entities.Role, error) {
+	var roles []*entities.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepository) AssignPermissions(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Role{ID: roleID}).Association("Permissions").Append(permissionsByID(permissionIDs))
+}
+
+func (r *RoleRepository) RemovePermissions(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&entities.Role{ID: roleID}).Association("Permissions").Delete(permissionsByID(permissionIDs))
+}
+
+// permissionsByID returns permission stubs carrying only their IDs, enough
+// for association changes
+func permissionsByID(ids []uuid.UUID) []entities.Permission {
+	permissions := make([]entities.Permission, len(ids))
+	for i, id := range ids {
+		permissions[i].ID = id
+	}
+	return permissions
+}
+
+// listOrder returns the ORDER BY clause for params, sorting by params.SortBy
+// when it is one of the allowed columns and by the first of them otherwise
+func listOrder(params repositories.ListParams, allowed ...string) string {
+	column := allowed[0]
+	for _, a := range allowed {
+		if params.SortBy == a {
+			column = a
+		}
+	}
+	if params.SortDir == "asc" {
+		return column + " ASC"
+	}
+	return column + " DESC"
+}
+
+// preload returns a scope preloading an association
+func preload(association string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload(association)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository implements repositories.WebhookRepository
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *entities.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error) {
+	var webhook entities.Webhook
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *entities.Webhook) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(webhook).Error
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Webhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context, params repositories.ListParams) ([]*entities.Webhook, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Webhook{})
+	if params.Search != "" {
+		search := "%" + params.Search + "%"
+		query = query.Where("name ILIKE ? OR url ILIKE ?", search, search)
+	}
+
+	webhooks := make([]*entities.Webhook, 0, params.PageSize)
+	total, err := Paginate(query, params, listOrder(params, "created_at", "name"), &webhooks)
+	return webhooks, total, err
+}
+
+// GetActiveByEvent returns the active webhooks subscribed to an event type,
+// directly or through the "*" wildcard
+func (r *WebhookRepository) GetActiveByEvent(ctx context.Context, eventType string) ([]*entities.Webhook, error) {
+	subscribed, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []*entities.Webhook
+	err = r.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Where("events @> ? OR events @> ?", string(subscribed), `["*"]`).
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entities.Webhook{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_delivered_at": deliveredAt,
+		"failure_count":     0,
+		"last_error":        "",
+	}).Error
+}
+
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id uuid.UUID, errMsg string) error {
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+	return r.db.WithContext(ctx).Model(&entities.Webhook{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failure_count": gorm.Expr("failure_count + 1"),
+		"last_error":    errMsg,
+	}).Error
+}
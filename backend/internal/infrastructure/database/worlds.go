@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WorldRepository implements repositories.WorldRepository
+type WorldRepository struct {
+	db *gorm.DB
+}
+
+// NewWorldRepository creates a new WorldRepository
+func NewWorldRepository(db *gorm.DB) *WorldRepository {
+	return &WorldRepository{db: db}
+}
+
+func (r *WorldRepository) Create(ctx context.Context, world *entities.World) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(world).Error
+}
+
+func (r *WorldRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.World, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *WorldRepository) GetByName(ctx context.Context, serverID uuid.UUID, name string) (*entities.World, error) {
+	return r.first(ctx, "server_id = ? AND name = ?", serverID, name)
+}
+
+func (r *WorldRepository) first(ctx context.Context, query string, args ...interface{}) (*entities.World, error) {
+	var world entities.World
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&world).Error; err != nil {
+		return nil, err
+	}
+	return &world, nil
+}
+
+func (r *WorldRepository) Update(ctx context.Context, world *entities.World) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(world).Error
+}
+
+func (r *WorldRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.World{}).Error
+}
+
+func (r *WorldRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*entities.World, error) {
+	var worlds []*entities.World
+	err := r.db.WithContext(ctx).Where("server_id = ?", serverID).Find(&worlds).Error
+	return worlds, err
+}
+
+// WorldBackupRepository implements repositories.WorldBackupRepository
+type WorldBackupRepository struct {
+	db *gorm.DB
+}
+
+// NewWorldBackupRepository creates a new WorldBackupRepository
+func NewWorldBackupRepository(db *gorm.DB) *WorldBackupRepository {
+	return &WorldBackupRepository{db: db}
+}
+
+func (r *WorldBackupRepository) Create(ctx context.Context, backup *entities.WorldBackup) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(backup).Error
+}
+
+func (r *WorldBackupRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.WorldBackup, error) {
+	var backup entities.WorldBackup
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&backup).Error; err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+func (r *WorldBackupRepository) Update(ctx context.Context, backup *entities.WorldBackup) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(backup).Error
+}
+
+func (r *WorldBackupRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.WorldBackup{}).Error
+}
+
+func (r *WorldBackupRepository) GetByWorldID(ctx context.Context, worldID uuid.UUID) ([]*entities.WorldBackup, error) {
+	var backups []*entities.WorldBackup
+	err := r.db.WithContext(ctx).Where("world_id = ?", worldID).Order("created_at DESC").Find(&backups).Error
+	return backups, err
+}
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying fields, which every logger derived
+// from it with Ctx or FromContext includes
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	carried, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	merged := make([]zap.Field, 0, len(carried)+len(fields))
+	merged = append(merged, carried...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns base with the fields carried by ctx, such as the
+// request and user IDs of the request ctx belongs to
+func FromContext(ctx context.Context, base *zap.Logger) *zap.Logger {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	if len(fields) == 0 {
+		return base
+	}
+	return base.With(fields...)
+}
+
+// Ctx returns the global logger with the fields carried by ctx
+func Ctx(ctx context.Context) *zap.Logger {
+	return FromContext(ctx, zap.L())
+}
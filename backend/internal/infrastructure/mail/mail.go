@@ -0,0 +1,111 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+)
+
+// ErrNotConfigured is returned when no mail host is set
+var ErrNotConfigured = errors.New("mail is not configured")
+
+// ErrUnsupportedDriver is returned for mail drivers other than smtp
+var ErrUnsupportedDriver = errors.New("unsupported mail driver")
+
+// Mailer sends plain text emails over SMTP
+type Mailer struct {
+	config  config.MailConfig
+	timeout time.Duration
+}
+
+// NewMailer creates a new Mailer
+func NewMailer(cfg config.MailConfig) *Mailer {
+	return &Mailer{config: cfg, timeout: 15 * time.Second}
+}
+
+// Send sends a plain text email to a single recipient
+func (m *Mailer) Send(ctx context.Context, to, subject, body string) error {
+	if m.config.Driver != "smtp" {
+		return ErrUnsupportedDriver
+	}
+	if m.config.Host == "" {
+		return ErrNotConfigured
+	}
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("mail header contains a line break")
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+
+	var conn net.Conn
+	var err error
+	if m.config.Encryption == "ssl" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(m.timeout))
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.config.Encryption == "tls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("mail authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.config.FromEmail); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s <%s>\r\n", m.config.FromName, m.config.FromEmail)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// CommandHistory keeps the most recent console commands of each user per
+// server in a capped Redis list, newest first
+type CommandHistory struct {
+	client *Client
+	size   int
+	ttl    time.Duration
+}
+
+// NewCommandHistory creates a new CommandHistory keeping up to size commands
+// per user and server. Histories expire after ttl without a new command.
+func NewCommandHistory(client *Client, size int, ttl time.Duration) *CommandHistory {
+	return &CommandHistory{client: client, size: size, ttl: ttl}
+}
+
+func commandHistoryKey(serverID, userID uuid.UUID) string {
+	return BuildKey(PrefixConsole, "history", serverID.String(), userID.String())
+}
+
+// Push records a command. A command identical to the previous one is not
+// stored again.
+func (h *CommandHistory) Push(ctx context.Context, serverID, userID uuid.UUID, command string) error {
+	key := commandHistoryKey(serverID, userID)
+
+	last, err := h.client.rdb.LIndex(ctx, key, 0).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := h.client.rdb.TxPipeline()
+	if err == redis.Nil || last != command {
+		pipe.LPush(ctx, key, command)
+		pipe.LTrim(ctx, key, 0, int64(h.size-1))
+	}
+	if h.ttl > 0 {
+		pipe.Expire(ctx, key, h.ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Recent returns up to limit commands, newest first
+func (h *CommandHistory) Recent(ctx context.Context, serverID, userID uuid.UUID, limit int) ([]string, error) {
+	if limit <= 0 || limit > h.size {
+		limit = h.size
+	}
+	return h.client.rdb.LRange(ctx, commandHistoryKey(serverID, userID), 0, int64(limit-1)).Result()
+}
@@ -188,6 +188,13 @@ const (
 	PrefixLock        = "lock:"
 	PrefixConsole     = "console:"
 	PrefixMetrics     = "metrics:"
+	PrefixDenial      = "denied:"
+)
+
+// Pub/Sub channels announcing newly created records
+const (
+	ChannelSystemEvents = "feed:system_events"
+	ChannelAuditLogs    = "feed:audit_logs"
 )
 
 // BuildKey builds a cache key with prefix
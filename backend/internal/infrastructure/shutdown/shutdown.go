@@ -0,0 +1,144 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned for work refused because the panel is shutting down
+var ErrShuttingDown = errors.New("panel is shutting down")
+
+// Operation is a long running operation in flight, such as a backup
+type Operation struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Coordinator tracks long operations and open sockets so the panel can shut
+// down without cutting them off. Once draining, new operations and sockets
+// are refused, open sockets are closed and running operations are waited for.
+type Coordinator struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   uint64
+	ops      map[uint64]Operation
+	sockets  map[uint64]func()
+	running  sync.WaitGroup
+}
+
+// New creates a new Coordinator
+func New() *Coordinator {
+	return &Coordinator{
+		ops:     make(map[uint64]Operation),
+		sockets: make(map[uint64]func()),
+	}
+}
+
+// Begin registers a long operation. The returned done must be called when it
+// finishes. While draining it returns ErrShuttingDown.
+func (c *Coordinator) Begin(kind, name string) (done func(), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining {
+		return nil, ErrShuttingDown
+	}
+
+	c.nextID++
+	id := c.nextID
+	c.ops[id] = Operation{Kind: kind, Name: name, StartedAt: time.Now()}
+	c.running.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.ops, id)
+			c.mu.Unlock()
+			c.running.Done()
+		})
+	}, nil
+}
+
+// Socket registers an open socket with the function that closes it cleanly.
+// The returned remove must be called when the socket closes on its own.
+// While draining it returns ErrShuttingDown.
+func (c *Coordinator) Socket(closeFn func()) (remove func(), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining {
+		return nil, ErrShuttingDown
+	}
+
+	c.nextID++
+	id := c.nextID
+	c.sockets[id] = closeFn
+	return func() {
+		c.mu.Lock()
+		delete(c.sockets, id)
+		c.mu.Unlock()
+	}, nil
+}
+
+// Draining reports whether shutdown has begun
+func (c *Coordinator) Draining() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.draining
+}
+
+// Operations returns the operations in flight
+func (c *Coordinator) Operations() []Operation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ops := make([]Operation, 0, len(c.ops))
+	for _, op := range c.ops {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Drain stops accepting operations and sockets and closes the open sockets.
+// It returns the number of sockets closed.
+func (c *Coordinator) Drain() int {
+	c.mu.Lock()
+	c.draining = true
+	closers := make([]func(), 0, len(c.sockets))
+	for id, closeFn := range c.sockets {
+		closers = append(closers, closeFn)
+		delete(c.sockets, id)
+	}
+	c.mu.Unlock()
+
+	for _, closeFn := range closers {
+		closeFn()
+	}
+	return len(closers)
+}
+
+// Wait blocks until the running operations finished or ctx is done, in which
+// case the error names the operations that were still running
+func (c *Coordinator) Wait(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	ops := c.Operations()
+	names := make([]string, 0, len(ops))
+	for _, op := range ops {
+		names = append(names, op.Kind+" "+op.Name)
+	}
+	return fmt.Errorf("%d operations still running: %s", len(ops), strings.Join(names, ", "))
+}
@@ -0,0 +1,83 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForRunningOperation(t *testing.T) {
+	c := New()
+	done, err := c.Begin("backup", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Drain()
+	if _, err := c.Begin("restore", "world"); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("operation begun while draining: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		done()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	started := time.Now()
+	if err := c.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if time.Since(started) < 50*time.Millisecond {
+		t.Error("Wait returned before the operation finished")
+	}
+	if ops := c.Operations(); len(ops) != 0 {
+		t.Errorf("operations %v after finishing, want none", ops)
+	}
+}
+
+func TestWaitGivesUpAtTimeout(t *testing.T) {
+	c := New()
+	done, err := c.Begin("backup", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	c.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = c.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "backup world") {
+		t.Errorf("Wait = %v, want the running backup named", err)
+	}
+	if time.Since(started) > time.Second {
+		t.Errorf("Wait took %s past its timeout", time.Since(started))
+	}
+}
+
+func TestDrainClosesSockets(t *testing.T) {
+	c := New()
+	closed := 0
+	if _, err := c.Socket(func() { closed++ }); err != nil {
+		t.Fatal(err)
+	}
+	// A socket that closed on its own is not closed again
+	remove, err := c.Socket(func() { t.Error("a removed socket was closed") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove()
+
+	if n := c.Drain(); n != 1 || closed != 1 {
+		t.Errorf("closed %d sockets (%d calls), want 1", n, closed)
+	}
+	if !c.Draining() {
+		t.Error("not draining after Drain")
+	}
+	if _, err := c.Socket(func() {}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("socket registered while draining: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/websocket/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// handleConsoleWebSocket handles WebSocket connections for server console.
+// Input is refused without console access, held to the server's command
+// policy and rate limited per connection. Output is queued in a bounded buffer
+// so a slow client only loses its own oldest lines.
+func handleConsoleWebSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client, db *gorm.DB, log *zap.Logger) {
+	serverID := c.Params("serverId")
+	limits := cfg.Console
+	mayConsole, _ := c.Locals(socketConsoleKey).(bool)
+	commander, _ := c.Locals(socketCommanderKey).(*socketCommander)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribe to console channel
+	pubsub := rdb.Subscribe(ctx, redis.PrefixConsole+serverID)
+	defer pubsub.Close()
+
+	out := make(chan string, max(limits.OutputBuffer, 1))
+	var dropped atomic.Int64
+
+	// Relay messages from Redis into the bounded buffer, dropping the oldest
+	// queued line when the client is not keeping up
+	go func() {
+		for msg := range pubsub.Channel() {
+			select {
+			case out <- msg.Payload:
+				continue
+			default:
+			}
+			select {
+			case <-out:
+				dropped.Add(1)
+			default:
+			}
+			select {
+			case out <- msg.Payload:
+			default:
+				dropped.Add(1)
+			}
+		}
+	}()
+
+	// Write queued messages to the client. This is the only goroutine writing
+	// data frames to the connection.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload := <-out:
+				if limits.WriteTimeout > 0 {
+					_ = c.SetWriteDeadline(time.Now().Add(limits.WriteTimeout))
+				}
+				if err := c.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+					cancel()
+					_ = c.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	// Frames far above the command limit are rejected by the connection itself
+	if limits.MaxCommandLength > 0 {
+		c.SetReadLimit(int64(limits.MaxCommandLength) * 4)
+	}
+
+	limiter := newInputLimiter(limits.InputRate, limits.InputBurst)
+	violations := 0
+
+	// Read messages from client and publish to Redis
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		if !mayConsole || commander == nil {
+			select {
+			case out <- "[Aether] Console access denied":
+			default:
+			}
+			continue
+		}
+		if err := commander.check(serverID, string(msg), func(entry *entities.AuditLog) { db.Create(entry) }); err != nil {
+			select {
+			case out <- "[Aether] " + err.Error():
+			default:
+			}
+			continue
+		}
+
+		var reason string
+		switch {
+		case limits.MaxCommandLength > 0 && len(msg) > limits.MaxCommandLength:
+			reason = fmt.Sprintf("command exceeds %d bytes", limits.MaxCommandLength)
+		case !limiter.Allow():
+			reason = "too many commands, slow down"
+		}
+
+		if reason == "" {
+			_ = rdb.Publish(ctx, redis.PrefixConsole+serverID+":input", string(msg))
+			continue
+		}
+
+		violations++
+		if limits.MaxViolations > 0 && violations >= limits.MaxViolations {
+			log.Warn("Closing abusive console connection",
+				zap.String("server_id", serverID),
+				zap.String("remote_addr", c.RemoteAddr().String()),
+				zap.Int("violations", violations),
+			)
+			_ = c.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "console input limit exceeded"),
+				time.Now().Add(time.Second))
+			break
+		}
+
+		select {
+		case out <- "[Aether] Command rejected: " + reason:
+		default:
+		}
+	}
+
+	// The connection is reused once the handler returns, so stop the writer
+	// first. Closing unblocks a write stuck on a stalled client.
+	cancel()
+	_ = c.Close()
+	<-written
+
+	if n := dropped.Load(); n > 0 {
+		log.Debug("Dropped console output for slow client",
+			zap.String("server_id", serverID),
+			zap.Int64("dropped", n),
+		)
+	}
+}
+
+// inputLimiter is a token bucket limiting commands sent on one connection
+type inputLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newInputLimiter creates a limiter that allows rate commands per second with
+// bursts of up to burst commands. A non-positive rate disables limiting.
+func newInputLimiter(rate float64, burst int) *inputLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &inputLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow reports whether another command may be sent now
+func (l *inputLimiter) Allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// consoleTest serves the console socket of one server on a local port
+type consoleTest struct {
+	url    string
+	redis  *miniredis.Miniredis
+	rdb    *redis.Client
+	server *entities.Server
+}
+
+func newConsoleTest(t *testing.T, limits config.ConsoleConfig) *consoleTest {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	server := &entities.Server{ID: uuid.New(), OwnerID: uuid.New()}
+	load := func(id string) (*entities.Server, error) { return server, nil }
+	cfg := &config.Config{Console: limits}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws/console/:serverId", socketUser(server.OwnerID, "user", "servers.console"), socketAccess(load),
+		websocket.New(func(c *websocket.Conn) {
+			handleConsoleWebSocket(c, cfg, rdb, nil, zap.NewNop())
+		}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return &consoleTest{
+		url:    "ws://" + ln.Addr().String() + "/ws/console/" + server.ID.String(),
+		redis:  mr,
+		rdb:    rdb,
+		server: server,
+	}
+}
+
+func (ct *consoleTest) dial(t *testing.T) *fastws.Conn {
+	t.Helper()
+	conn, _, err := fastws.DefaultDialer.Dial(ct.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// subscribe returns the channel the console publishes input for the server to
+func (ct *consoleTest) subscribe(t *testing.T, suffix string) <-chan string {
+	t.Helper()
+	pubsub := ct.rdb.Subscribe(context.Background(), redis.PrefixConsole+ct.server.ID.String()+suffix)
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pubsub.Close() })
+
+	messages := make(chan string, 100)
+	go func() {
+		for msg := range pubsub.Channel() {
+			messages <- msg.Payload
+		}
+	}()
+	return messages
+}
+
+// waitSubscribed waits until n console connections listen for output
+func (ct *consoleTest) waitSubscribed(t *testing.T, n int) {
+	t.Helper()
+	channel := redis.PrefixConsole + ct.server.ID.String()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if ct.redis.PubSubNumSub(channel)[channel] >= n {
+			return
+		}
+	}
+	t.Fatalf("fewer than %d console connections subscribed", n)
+}
+
+func readText(t *testing.T, conn *fastws.Conn) string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(msg)
+}
+
+func TestConsoleThrottlesInput(t *testing.T) {
+	ct := newConsoleTest(t, config.ConsoleConfig{InputRate: 0.01, InputBurst: 3, MaxCommandLength: 32, OutputBuffer: 16})
+	input := ct.subscribe(t, ":input")
+	conn := ct.dial(t)
+
+	for i := 0; i < 5; i++ {
+		if err := conn.WriteMessage(fastws.TextMessage, []byte("say "+strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if got := readText(t, conn); got != "[Aether] Command rejected: too many commands, slow down" {
+			t.Errorf("reply %d = %q, want the throttling notice", i, got)
+		}
+	}
+	if err := conn.WriteMessage(fastws.TextMessage, []byte("say "+strings.Repeat("a", 40))); err != nil {
+		t.Fatal(err)
+	}
+	if got := readText(t, conn); !strings.Contains(got, "command exceeds 32 bytes") {
+		t.Errorf("reply to a long command = %q", got)
+	}
+
+	var published []string
+	for len(published) < 3 {
+		select {
+		case cmd := <-input:
+			published = append(published, cmd)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("published %q, want the first 3 commands", published)
+		}
+	}
+	select {
+	case cmd := <-input:
+		t.Errorf("published throttled command %q", cmd)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if strings.Join(published, ",") != "say 0,say 1,say 2" {
+		t.Errorf("published %q, want the first 3 commands", published)
+	}
+}
+
+func TestConsoleClosesAbusiveConnections(t *testing.T) {
+	ct := newConsoleTest(t, config.ConsoleConfig{InputRate: 0.01, InputBurst: 1, OutputBuffer: 16, MaxViolations: 2})
+	conn := ct.dial(t)
+
+	for i := 0; i < 3; i++ {
+		if err := conn.WriteMessage(fastws.TextMessage, []byte("say spam")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !fastws.IsCloseError(err, fastws.ClosePolicyViolation) {
+			t.Errorf("read error = %v, want a policy violation close", err)
+		}
+		return
+	}
+}
+
+func TestConsoleStalledReaderDoesNotBlockOthers(t *testing.T) {
+	ct := newConsoleTest(t, config.ConsoleConfig{OutputBuffer: 8, WriteTimeout: time.Second})
+	ct.dial(t) // never reads
+	reader := ct.dial(t)
+	ct.waitSubscribed(t, 2)
+
+	// Far more output than the stalled client's buffers hold
+	line := strings.Repeat("x", 64*1024)
+	const lines = 256
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < lines; i++ {
+			_ = ct.rdb.Publish(context.Background(), redis.PrefixConsole+ct.server.ID.String(), line)
+		}
+		_ = ct.rdb.Publish(context.Background(), redis.PrefixConsole+ct.server.ID.String(), "last")
+	}()
+
+	for {
+		if readText(t, reader) == "last" {
+			break
+		}
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing console output blocked on the stalled client")
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/shutdown"
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+func TestDrainSendsCloseFrames(t *testing.T) {
+	ops := shutdown.New()
+	served := make(chan struct{}, 1)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", websocket.New(drainable(ops, func(c *websocket.Conn) {
+		served <- struct{}{}
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	url := "ws://" + ln.Addr().String() + "/ws"
+
+	// closeCode reads from conn until the server closes it
+	closeCode := func(conn *fastws.Conn) int {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := conn.ReadMessage()
+		var closeErr *fastws.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("read %v, want a close frame", err)
+		}
+		return closeErr.Code
+	}
+
+	conn, _, err := fastws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the socket was never served")
+	}
+
+	if n := ops.Drain(); n != 1 {
+		t.Errorf("drained %d sockets, want 1", n)
+	}
+	if code := closeCode(conn); code != fastws.CloseGoingAway {
+		t.Errorf("close code %d, want going away", code)
+	}
+
+	// Sockets opened while draining are told to come back later
+	late, _, err := fastws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer late.Close()
+	if code := closeCode(late); code != fastws.CloseTryAgainLater {
+		t.Errorf("close code %d, want try again later", code)
+	}
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/apperror"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/shutdown"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// serviceErrors maps service sentinel errors to the error reported to clients
+var serviceErrors = map[error]*apperror.Error{
+	// Auth
+	services.ErrInvalidCredentials: apperror.New(http.StatusUnauthorized, "auth.invalid_credentials", "Invalid credentials"),
+	services.ErrAccountLocked:      apperror.New(http.StatusForbidden, "auth.account_locked", "Account is locked"),
+	services.ErrAccountInactive:    apperror.New(http.StatusForbidden, "auth.account_inactive", "Account is not active"),
+	services.ErrInvalidToken:       apperror.New(http.StatusUnauthorized, "auth.invalid_token", "Invalid token"),
+	services.ErrTokenExpired:       apperror.New(http.StatusUnauthorized, "auth.token_expired", "Token has expired"),
+	services.ErrRefreshTokenReused: apperror.New(http.StatusUnauthorized, "auth.refresh_token_reused", "Refresh token was already used, the session has been revoked"),
+	services.ErrInvalid2FACode:     apperror.New(http.StatusUnauthorized, "auth.invalid_2fa_code", "Invalid 2FA code"),
+	services.ErrEmailNotVerified:   apperror.New(http.StatusForbidden, "auth.email_not_verified", "Email not verified"),
+
+	// Billing
+	services.ErrInvalidAmount:       apperror.New(http.StatusBadRequest, "billing.invalid_amount", "Amount must be greater than zero"),
+	services.ErrSelfTransfer:        apperror.New(http.StatusBadRequest, "billing.self_transfer", "Cannot transfer credits to yourself"),
+	services.ErrTransferNotAllowed:  apperror.New(http.StatusForbidden, "billing.transfer_not_allowed", "Transfer to this account is not allowed"),
+	services.ErrTransferRateLimited: apperror.New(http.StatusTooManyRequests, "billing.transfer_rate_limited", "Too many transfers, try again later"),
+	services.ErrUserNotFound:        apperror.New(http.StatusNotFound, "user.not_found", "User not found"),
+	services.ErrInvoiceNotFound:     apperror.New(http.StatusNotFound, "billing.invoice_not_found", "Invoice not found"),
+	services.ErrInvoiceAccessDenied: apperror.New(http.StatusForbidden, "billing.invoice_access_denied", "Access denied"),
+	services.ErrInsufficientCredits: apperror.New(http.StatusPaymentRequired, "billing.insufficient_credits", "Insufficient credits"),
+	services.ErrPackageNotFound:     apperror.New(http.StatusNotFound, "billing.package_not_found", "Package not found"),
+	services.ErrPackageNotAllowed:   apperror.New(http.StatusForbidden, "billing.package_not_allowed", "Package does not allow this game, egg or node"),
+	services.ErrInvalidBillingCycle: apperror.New(http.StatusBadRequest, "billing.invalid_billing_cycle", "Package is not offered with this billing cycle"),
+
+	// Nodes
+	services.ErrNodeNotFound:       apperror.New(http.StatusNotFound, "node.not_found", "Node not found"),
+	services.ErrNodeOffline:        apperror.New(http.StatusServiceUnavailable, "node.offline", "Node is offline"),
+	services.ErrNodeTimeout:        apperror.New(http.StatusGatewayTimeout, "node.timeout", "Node did not respond in time"),
+	services.ErrNodeMaintenance:    apperror.New(http.StatusServiceUnavailable, "node.maintenance", "Node is in maintenance mode"),
+	services.ErrLocationNotFound:   apperror.New(http.StatusNotFound, "location.not_found", "Location not found"),
+	services.ErrNoAvailableNode:    apperror.New(http.StatusConflict, "node.none_available", "No node with sufficient capacity available"),
+	services.ErrAllocationConflict: apperror.New(http.StatusConflict, "allocation.conflict", "IP and port are already allocated on another node"),
+	services.ErrPortsAllocated:     apperror.New(http.StatusConflict, "allocation.ports_allocated", "Every port in the range is already allocated"),
+
+	// Resellers
+	services.ErrNotSubUser:         apperror.New(http.StatusForbidden, "reseller.not_sub_user", "User is not a sub-account of this reseller"),
+	services.ErrQuotaExceeded:      apperror.New(http.StatusForbidden, "reseller.quota_exceeded", "Resource quota exceeded"),
+	services.ErrQuotaAboveReseller: apperror.New(http.StatusBadRequest, "reseller.quota_above_reseller", "Quota exceeds the reseller's own limits"),
+	services.ErrEmailInUse:         apperror.New(http.StatusConflict, "user.email_in_use", "Email already in use"),
+	services.ErrUsernameInUse:      apperror.New(http.StatusConflict, "user.username_in_use", "Username already in use"),
+	services.ErrUserHasServers:     apperror.New(http.StatusConflict, "user.has_servers", "Cannot delete user with active servers"),
+
+	// Servers
+	services.ErrServerNotFound:                 apperror.New(http.StatusNotFound, "server.not_found", "Server not found"),
+	services.ErrServerSuspended:                apperror.New(http.StatusForbidden, "server.suspended", "Server is suspended"),
+	services.ErrServerAlreadyRunning:           apperror.New(http.StatusConflict, "server.already_running", "Server is already running"),
+	services.ErrServerNotRunning:               apperror.New(http.StatusConflict, "server.not_running", "Server is not running"),
+	services.ErrInsufficientResources:          apperror.New(http.StatusConflict, "server.insufficient_resources", "Insufficient resources on node"),
+	services.ErrNoAvailableAllocation:          apperror.New(http.StatusConflict, "server.no_available_allocation", "No available allocation"),
+	services.ErrPreferredAllocationUnavailable: apperror.New(http.StatusConflict, "server.preferred_allocation_unavailable", "The preferred IP and port are not available on this node"),
+	services.ErrNoDedicatedIP:                  apperror.New(http.StatusConflict, "server.no_dedicated_ip", "No IP on this node is free of other servers"),
+	services.ErrBackupLimitReached:             apperror.New(http.StatusConflict, "server.backup_limit_reached", "Backup limit reached"),
+	services.ErrInvalidPowerAction:             apperror.New(http.StatusBadRequest, "server.invalid_power_action", "Invalid power action"),
+	services.ErrEggNotFound:                    apperror.New(http.StatusNotFound, "egg.not_found", "Egg not found"),
+	services.ErrInvalidImage:                   apperror.New(http.StatusBadRequest, "server.invalid_image", "Docker image is not offered by the server's egg"),
+	services.ErrBackupInProgress:               apperror.New(http.StatusConflict, "server.backup_in_progress", "Server has a backup in progress"),
+	services.ErrBackupNotFound:                 apperror.New(http.StatusNotFound, "backup.not_found", "Backup not found"),
+	services.ErrBackupNotCompleted:             apperror.New(http.StatusConflict, "backup.not_completed", "Backup has not completed"),
+	services.ErrSafetyBackupFailed:             apperror.New(http.StatusBadGateway, "backup.safety_failed", "Safety backup failed, nothing was changed"),
+	services.ErrCommandBlocked:                 apperror.New(http.StatusForbidden, "server.command_blocked", "Command is blocked by the server's command policy"),
+	services.ErrBackupEggMismatch:              apperror.New(http.StatusConflict, "backup.egg_mismatch", "Backup was taken with a different egg"),
+	services.ErrInvalidMount:                   apperror.New(http.StatusBadRequest, "server.invalid_mount", "Mount paths must be absolute, and the target may not be / or overlap /home/container"),
+	services.ErrMountNotAllowed:                apperror.New(http.StatusUnprocessableEntity, "server.mount_not_allowed", "Mount source is outside the node's allowed mounts"),
+	services.ErrMountTargetInUse:               apperror.New(http.StatusConflict, "server.mount_target_in_use", "A mount already uses this target"),
+	services.ErrMountNotFound:                  apperror.New(http.StatusNotFound, "server.mount_not_found", "Mount not found"),
+	services.ErrInvalidAllowedPath:             apperror.New(http.StatusBadRequest, "node.invalid_allowed_mounts", "Allowed mounts must be absolute paths other than /"),
+	services.ErrInvalidResourceAlerts:          apperror.New(http.StatusBadRequest, "server.invalid_resource_alerts", "Alert thresholds must be 0-100%, with a duration of 1 minute to 1 day"),
+	services.ErrBackupCorrupted:                apperror.New(http.StatusUnprocessableEntity, "backup.corrupted", "Backup is corrupted, its archive no longer matches its checksum"),
+	services.ErrInvalidCPUSet:                  apperror.New(http.StatusBadRequest, "server.invalid_cpu_set", "CPU set does not match the node's cores"),
+	services.ErrInvalidSwap:                    apperror.New(http.StatusBadRequest, "server.invalid_swap", "Swap exceeds what the node allows"),
+	services.ErrAllocationNotFound:             apperror.New(http.StatusNotFound, "allocation.not_found", "Allocation not found"),
+	services.ErrVariableNotFound:               apperror.New(http.StatusNotFound, "variable.not_found", "Variable not found"),
+	services.ErrVariableNotEditable:            apperror.New(http.StatusForbidden, "variable.not_editable", "Variable is not editable"),
+	services.ErrQuickSettingNotFound:           apperror.New(http.StatusNotFound, "variable.quick_setting_not_found", "Quick setting not found"),
+	services.ErrInvalidTag:                     apperror.New(http.StatusBadRequest, "server.invalid_tag", "Tags may only contain letters, numbers, dashes, underscores, dots and colons, up to 32 characters"),
+	services.ErrTooManyTags:                    apperror.New(http.StatusBadRequest, "server.too_many_tags", "A server may have at most 10 tags"),
+	services.ErrServerLimitReached:             apperror.New(http.StatusForbidden, "server.limit_reached", "Server limit reached, upgrade your package to create more servers"),
+
+	// Databases
+	services.ErrDatabaseLimitReached:    apperror.New(http.StatusConflict, "database.limit_reached", "Database limit reached"),
+	services.ErrDatabaseNotFound:        apperror.New(http.StatusNotFound, "database.not_found", "Database not found"),
+	services.ErrDatabaseExists:          apperror.New(http.StatusConflict, "database.exists", "Database already exists"),
+	services.ErrDatabaseHostNotFound:    apperror.New(http.StatusNotFound, "database_host.not_found", "Database host not found"),
+	services.ErrNoDatabaseHost:          apperror.New(http.StatusConflict, "database_host.none_available", "No database host available"),
+	services.ErrDatabaseHostUnavailable: apperror.New(http.StatusBadGateway, "database_host.unavailable", "Database host failed the request"),
+	services.ErrInvalidDatabaseName:     apperror.New(http.StatusBadRequest, "database.invalid_name", "Database name may only contain letters, numbers and underscores"),
+	services.ErrInvalidDatabaseRemote:   apperror.New(http.StatusBadRequest, "database.invalid_remote", "Invalid database remote"),
+	crypto.ErrInvalidKey:                apperror.New(http.StatusInternalServerError, "security.invalid_encryption_key", "Encryption key is not configured correctly"),
+
+	// Worlds
+	services.ErrWorldNotFound:           apperror.New(http.StatusNotFound, "world.not_found", "World not found"),
+	services.ErrWorldBackupNotFound:     apperror.New(http.StatusNotFound, "world.backup_not_found", "World backup not found"),
+	services.ErrWorldBackupNotCompleted: apperror.New(http.StatusConflict, "world.backup_not_completed", "World backup has not completed"),
+	services.ErrServerMustBeStopped:     apperror.New(http.StatusConflict, "server.must_be_stopped", "Server must be stopped first"),
+	services.ErrWorldNodeFailed:         apperror.New(http.StatusBadGateway, "world.node_failed", "Node failed the world operation"),
+
+	// Webhooks
+	services.ErrWebhookNotFound:        apperror.New(http.StatusNotFound, "webhook.not_found", "Webhook not found"),
+	services.ErrWebhookDeliveryFailed:  apperror.New(http.StatusBadGateway, "webhook.delivery_failed", "Webhook delivery failed"),
+	services.ErrWebhookInvalidTemplate: apperror.New(http.StatusBadRequest, "webhook.invalid_template", "Invalid webhook template"),
+
+	// Shutdown
+	shutdown.ErrShuttingDown: apperror.New(http.StatusServiceUnavailable, "server.shutting_down", "Panel is shutting down, try again shortly"),
+}
+
+// resolveError finds the client facing error for err. Errors that are neither
+// an *apperror.Error, a known service error nor a *fiber.Error become a generic
+// internal error so no implementation detail leaks to clients.
+func resolveError(err error) *apperror.Error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	for sentinel, mapped := range serviceErrors {
+		if errors.Is(err, sentinel) {
+			return mapped.Wrap(err)
+		}
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return apperror.New(fiberErr.Code, requestErrorCode(fiberErr.Code), fiberErr.Message).Wrap(err)
+	}
+
+	return apperror.Internal.Wrap(err)
+}
+
+// requestErrorCode returns the error code for errors raised by fiber itself
+func requestErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "request.bad_request"
+	case http.StatusUnauthorized:
+		return "request.unauthorized"
+	case http.StatusForbidden:
+		return "request.forbidden"
+	case http.StatusNotFound:
+		return "request.not_found"
+	case http.StatusMethodNotAllowed:
+		return "request.method_not_allowed"
+	case http.StatusRequestTimeout:
+		return "request.timeout"
+	case http.StatusRequestEntityTooLarge:
+		return "request.too_large"
+	case http.StatusTooManyRequests:
+		return "request.rate_limited"
+	}
+	if status >= http.StatusInternalServerError {
+		return apperror.Internal.Code
+	}
+	return "request.failed"
+}
+
+// errorHandler renders every error returned by a handler in the same shape
+func errorHandler(log *zap.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		appErr := resolveError(err)
+		if appErr.Status >= http.StatusInternalServerError {
+			logger.FromContext(c.UserContext(), log).Error("Request failed",
+				zap.String("method", c.Method()),
+				zap.String("path", c.Path()),
+				zap.String("code", appErr.Code),
+				zap.Error(err),
+			)
+		}
+
+		return c.Status(appErr.Status).JSON(fiber.Map{
+			"error":      appErr.Message,
+			"code":       appErr.Code,
+			"status":     appErr.Status,
+			"success":    false,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+}
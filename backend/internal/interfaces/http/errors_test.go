@@ -0,0 +1,66 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/apperror"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type errorBody struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Success bool   `json:"success"`
+}
+
+// renderError returns the response errorHandler gives for a handler failing
+// with err
+func renderError(t *testing.T, err error) (int, errorBody) {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler(zap.NewNop())})
+	app.Get("/", func(c *fiber.Ctx) error { return err })
+
+	resp, testErr := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if testErr != nil {
+		t.Fatal(testErr)
+	}
+	defer resp.Body.Close()
+	var body errorBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestErrorHandlerMapsServiceErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"sentinel":         {services.ErrInsufficientResources, http.StatusConflict, "server.insufficient_resources"},
+		"wrapped sentinel": {fmt.Errorf("create server: %w", services.ErrServerNotFound), http.StatusNotFound, "server.not_found"},
+		"app error":        {apperror.New(http.StatusTeapot, "test.teapot", "Short and stout"), http.StatusTeapot, "test.teapot"},
+		"fiber error":      {fiber.ErrMethodNotAllowed, http.StatusMethodNotAllowed, "request.method_not_allowed"},
+	} {
+		status, body := renderError(t, tc.err)
+		if status != tc.status || body.Status != tc.status || body.Code != tc.code || body.Success {
+			t.Errorf("%s: %d %+v, want %d with code %s", name, status, body, tc.status, tc.code)
+		}
+	}
+}
+
+func TestErrorHandlerHidesUnknownErrors(t *testing.T) {
+	status, body := renderError(t, errors.New("pq: connection refused to 10.0.0.5"))
+	if status != http.StatusInternalServerError || body.Code != apperror.Internal.Code || body.Error != apperror.Internal.Message {
+		t.Errorf("unknown error = %d %+v, want the generic internal error", status, body)
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/websocket/v2"
+)
+
+// Frame types of the admin feed
+const (
+	feedSystemEvent = "system_event"
+	feedAuditLog    = "audit_log"
+)
+
+// feedFilter limits the admin feed to the system events of some severities
+// and the audit logs of some resources. Empty lists let everything through.
+type feedFilter struct {
+	severities map[string]bool
+	resources  map[string]bool
+}
+
+// handleFeedSocket streams system events and audit logs to an admin as they
+// are created. ?severity=warning,error and ?resource=server,node narrow the
+// feed; a filter applies only to the records it names.
+func handleFeedSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client) {
+	filter := feedFilter{
+		severities: splitSet(c.Query("severity")),
+		resources:  splitSet(c.Query("resource")),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubsub := rdb.Subscribe(ctx, redis.ChannelSystemEvents, redis.ChannelAuditLogs)
+	defer pubsub.Close()
+
+	// Detect client disconnects
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			frameType := feedSystemEvent
+			if msg.Channel == redis.ChannelAuditLogs {
+				frameType = feedAuditLog
+			}
+			if !filter.allows(frameType, msg.Payload) {
+				continue
+			}
+
+			payload, err := json.Marshal(socketFrame{Type: frameType, Data: json.RawMessage(msg.Payload)})
+			if err != nil {
+				continue
+			}
+			if cfg.Console.WriteTimeout > 0 {
+				_ = c.SetWriteDeadline(time.Now().Add(cfg.Console.WriteTimeout))
+			}
+			if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allows reports whether a record passes the filter
+func (f feedFilter) allows(frameType, payload string) bool {
+	var record struct {
+		Severity string `json:"severity"`
+		Resource string `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(payload), &record); err != nil {
+		return false
+	}
+
+	switch frameType {
+	case feedSystemEvent:
+		return len(f.severities) == 0 || f.severities[record.Severity]
+	case feedAuditLog:
+		return len(f.resources) == 0 || f.resources[record.Resource]
+	}
+	return false
+}
+
+// splitSet returns the non-empty values of a comma separated list
+func splitSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"go.uber.org/zap"
+)
+
+func TestFeedSocketStreamsFilteredRecords(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	db := dbtest.Open(t, &entities.SystemEvent{}, &entities.AuditLog{})
+	if err := database.PublishCreated(db, rdb, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws/events", websocket.New(func(c *websocket.Conn) {
+		handleFeedSocket(c, cfg, rdb)
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	conn, _, err := fastws.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/ws/events?severity=error,critical&resource=server", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		subs := mr.PubSubNumSub(redis.ChannelSystemEvents, redis.ChannelAuditLogs)
+		if subs[redis.ChannelSystemEvents] == 1 && subs[redis.ChannelAuditLogs] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriptions %v, want both feed channels", subs)
+		}
+	}
+
+	// Records the filters exclude are created first, so the frames read next
+	// show they were skipped
+	for _, row := range []interface{}{
+		&entities.SystemEvent{EventType: entities.EventStatusDrift, Severity: "warning", Message: "drifted"},
+		&entities.AuditLog{Action: entities.AuditActionUpdate, Resource: "node", Description: "node renamed"},
+		&entities.SystemEvent{EventType: entities.EventNodeCert, Severity: "error", Message: "certificate expired"},
+		&entities.AuditLog{Action: entities.AuditActionUpdate, Resource: "server", Description: "server renamed"},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var frames []string
+	for range 2 {
+		var frame struct {
+			Type string `json:"type"`
+			Data struct {
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(readText(t, conn)), &frame); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame.Type+": "+frame.Data.Message+frame.Data.Description)
+	}
+	if frames[0] != "system_event: certificate expired" || frames[1] != "audit_log: server renamed" {
+		t.Errorf("frames %q, want the error event and the server audit log", frames)
+	}
+}
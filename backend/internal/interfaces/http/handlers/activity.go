@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// activityDescriptions are the feed texts of server activities
+var activityDescriptions = map[string]string{
+	entities.ActivityServerStart:     "Started the server",
+	entities.ActivityServerStop:      "Stopped the server",
+	entities.ActivityServerRestart:   "Restarted the server",
+	entities.ActivityServerKill:      "Killed the server",
+	entities.ActivityServerReinstall: "Reinstalled the server",
+	entities.ActivityConsoleCommand:  "Ran a console command",
+	entities.ActivityBackupCreate:    "Created a backup",
+	entities.ActivityBackupRestore:   "Restored a backup",
+	entities.ActivityFileDelete:      "Deleted files",
+	entities.ActivityDatabaseCreate:  "Created a database",
+	entities.ActivityDatabaseRotate:  "Changed a database password",
+	entities.ActivityDatabaseDelete:  "Deleted a database",
+}
+
+// ActivityActor is the user behind an activity
+type ActivityActor struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Avatar   string    `json:"avatar"`
+}
+
+// ActivityEntry is one item of a server's activity feed
+type ActivityEntry struct {
+	ID          uuid.UUID      `json:"id"`
+	Action      string         `json:"action"`
+	Description string         `json:"description"`
+	Details     string         `json:"details,omitempty"`
+	Actor       *ActivityActor `json:"actor"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// GetServerActivity returns the activity feed of a server, newest first. Unlike
+// audit logs it is meant for the server's users and leaves out IP addresses.
+func (h *Handler) GetServerActivity(c *fiber.Ctx) error {
+	params := pageParams(c, 25, 100)
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	db := h.db.Model(&entities.ActivityLog{}).Where("server_id = ?", server.ID)
+
+	var logs []entities.ActivityLog
+	total, err := database.Paginate(db, params, "created_at DESC", &logs, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("User")
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch activity",
+		})
+	}
+
+	entries := make([]ActivityEntry, 0, len(logs))
+	for _, log := range logs {
+		entry := ActivityEntry{
+			ID:          log.ID,
+			Action:      log.Action,
+			Description: activityDescriptions[log.Action],
+			Details:     log.Details,
+			CreatedAt:   log.CreatedAt,
+		}
+		if entry.Description == "" {
+			entry.Description = log.Action
+		}
+		if log.User != nil {
+			entry.Actor = &ActivityActor{ID: log.User.ID, Username: log.User.Username, Avatar: log.User.Avatar}
+		}
+		entries = append(entries, entry)
+	}
+
+	return c.JSON(paginated(entries, params, total))
+}
+
+// recordActivity adds an entry to a server's activity feed for the current user
+func (h *Handler) recordActivity(c *fiber.Ctx, serverID uuid.UUID, action, details string) {
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.ActivityLog{
+		UserID:    userID,
+		ServerID:  &serverID,
+		Action:    action,
+		Details:   details,
+		IPAddress: c.IP(),
+	})
+}
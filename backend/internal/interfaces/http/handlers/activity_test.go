@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestPowerActionAppearsInActivityFeed(t *testing.T) {
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.User{}, &entities.ActivityLog{})
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	owner := &entities.User{ID: uuid.New(), Email: "alex@example.com", Username: "alex", Status: entities.UserStatusActive}
+	stranger := &entities.User{ID: uuid.New(), Email: "sam@example.com", Username: "sam", Status: entities.UserStatusActive}
+	server := &entities.Server{ID: uuid.New(), Name: "survival", NodeID: node.ID, EggID: uuid.New(), OwnerID: owner.ID, Status: entities.ServerStatusRunning}
+	// Activity of another server stays out of the feed
+	otherServer := uuid.New()
+	other := &entities.ActivityLog{ID: uuid.New(), UserID: stranger.ID, ServerID: &otherServer, Action: entities.ActivityServerKill}
+	for _, row := range []interface{}{node, owner, stranger, server, other} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, agent: agent.NewClient(cfg.Agents, db)}
+	caller := owner.ID
+	var handlerErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		handlerErr = err
+		return fiber.DefaultErrorHandler(c, err)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, caller)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Post("/servers/:id/stop", h.StopServer)
+	app.Get("/servers/:id/activity", h.GetServerActivity)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/servers/"+server.ID.String()+"/stop", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stop = %d, %v", resp.StatusCode, handlerErr)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+server.ID.String()+"/activity", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Data []ActivityEntry `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&feed)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(feed.Data) != 1 {
+		t.Fatalf("feed = %d %+v, want the stop alone", resp.StatusCode, feed.Data)
+	}
+	entry := feed.Data[0]
+	if entry.Action != entities.ActivityServerStop || entry.Description != "Stopped the server" || entry.CreatedAt.IsZero() {
+		t.Errorf("entry %+v, want the stop with its description and time", entry)
+	}
+	if entry.Actor == nil || entry.Actor.ID != owner.ID || entry.Actor.Username != "alex" {
+		t.Errorf("actor %+v, want alex", entry.Actor)
+	}
+
+	// Only the server's owners see its feed
+	caller = stranger.ID
+	handlerErr = nil
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+server.ID.String()+"/activity", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	var fiberErr *fiber.Error
+	if resp.StatusCode != http.StatusForbidden || !errors.As(handlerErr, &fiberErr) {
+		t.Errorf("feed of another user's server = %d, %v, want access denied", resp.StatusCode, handlerErr)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UpdateAllocationRequest struct {
+	Alias string `json:"alias" validate:"max=255"`
+	Notes string `json:"notes" validate:"max=500"`
+}
+
+type SetPrimaryAllocationRequest struct {
+	Force bool `json:"force"` // Restart a running server so the new port applies immediately
+}
+
+// CreateNodeAllocations creates allocations for a range of ports on a node
+func (h *Handler) CreateNodeAllocations(c *fiber.Ctx) error {
+	nodeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return services.ErrNodeNotFound
+	}
+
+	var req services.CreateAllocationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.NodeID = nodeID
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	allocations, err := h.nodes.CreateAllocations(c.UserContext(), &req, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": allocations,
+	})
+}
+
+// UpdateAllocation edits the alias and notes of an allocation
+func (h *Handler) UpdateAllocation(c *fiber.Ctx) error {
+	var req UpdateAllocationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var allocation entities.Allocation
+	if err := h.db.Where("id = ?", c.Params("id")).First(&allocation).Error; err != nil {
+		return services.ErrAllocationNotFound
+	}
+
+	// Server owners may label their own allocations, everything else is for admins
+	if !middleware.IsAdmin(c) {
+		userID, _ := middleware.GetUserID(c)
+		var owned int64
+		if allocation.ServerID != nil {
+			h.db.Model(&entities.Server{}).Where("id = ? AND owner_id = ?", *allocation.ServerID, userID).Count(&owned)
+		}
+		if owned == 0 {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"error": "Access denied",
+			})
+		}
+	}
+
+	allocation.Alias = req.Alias
+	allocation.Notes = req.Notes
+	if err := h.db.Model(&allocation).Select("alias", "notes").Updates(&allocation).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update allocation",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": allocation,
+	})
+}
+
+// SetPrimaryAllocation makes one of a server's allocations its primary one.
+// The node picks up the new SERVER_PORT on the next start, or right away when
+// forced, which restarts a running server.
+func (h *Handler) SetPrimaryAllocation(c *fiber.Ctx) error {
+	var req SetPrimaryAllocationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	var allocation entities.Allocation
+	if err := h.db.Where("id = ? AND server_id = ?", c.Params("allocId"), server.ID).First(&allocation).Error; err != nil {
+		return services.ErrAllocationNotFound
+	}
+
+	var egg entities.Egg
+	if err := h.db.Preload("Variables").Where("id = ?", server.EggID).First(&egg).Error; err != nil {
+		return services.ErrEggNotFound
+	}
+
+	// Exactly one primary per server: clear the others and flag the new one together
+	var allocations []*entities.Allocation
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Allocation{}).
+			Where("server_id = ? AND id <> ?", server.ID, allocation.ID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&allocation).Update("is_primary", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", server.ID).Find(&allocations).Error; err != nil {
+			return err
+		}
+
+		server.AllocationID = allocation.ID
+		startup := services.NewStartupBuilder(&egg, server, allocations, server.Environment).Build()
+		server.StartupCmd = startup.Command
+		server.Environment = startup.Environment
+		return tx.Model(server).Updates(map[string]interface{}{
+			"allocation_id": server.AllocationID,
+			"startup_cmd":   server.StartupCmd,
+			"environment":   server.Environment,
+		}).Error
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change primary allocation",
+		})
+	}
+
+	ctx := c.UserContext()
+	startup := &services.Startup{Command: server.StartupCmd, Environment: server.Environment}
+	if err := h.agent.UpdateServerStartup(ctx, server.NodeID, server.ID, startup, allocations); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Primary allocation changed but the node could not be updated",
+		})
+	}
+
+	// A restart keeps the old container, stopping and starting recreates it
+	restarted := false
+	if req.Force && server.IsRunning() {
+		if err := h.agent.StopServer(ctx, server.NodeID, server.ID); err != nil {
+			return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+				"error": "Failed to stop server on node",
+			})
+		}
+		if err := h.agent.StartServer(ctx, server.NodeID, server.ID); err != nil {
+			h.db.Model(server).Update("status", entities.ServerStatusError)
+			return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+				"error": "Failed to start server on node",
+			})
+		}
+		restarted = true
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"primary_allocation": allocation.ID, "forced": req.Force},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data":      allocation,
+		"restarted": restarted,
+	})
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// allocationTest is a stopped server with allocations on ports 25565, its
+// primary, 25566 and 25567, next to another server's primary allocation.
+// Requests are made as caller, the owner of the server unless changed.
+type allocationTest struct {
+	db          *gorm.DB
+	app         *fiber.App
+	server      *entities.Server
+	allocations []*entities.Allocation
+	other       *entities.Allocation
+	caller      uuid.UUID
+	err         error
+
+	mu            sync.Mutex
+	agentRequests []string
+}
+
+func newAllocationTest(t *testing.T) *allocationTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.Egg{}, &entities.EggVariable{}, &entities.Allocation{}, &entities.AuditLog{})
+	at := &allocationTest{db: db}
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		at.mu.Lock()
+		at.agentRequests = append(at.agentRequests, r.Method+" "+r.URL.Path)
+		at.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "game", StartupCommand: "./start --port {{SERVER_PORT}}"}
+	at.server = &entities.Server{ID: uuid.New(), Name: "survival", NodeID: node.ID, EggID: egg.ID, OwnerID: uuid.New(), Status: entities.ServerStatusStopped}
+	for _, row := range []interface{}{node, egg} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, port := range []int{25565, 25566, 25567} {
+		at.allocations = append(at.allocations, &entities.Allocation{ID: uuid.New(), NodeID: node.ID, IP: "203.0.113.10", Port: port, ServerID: &at.server.ID, IsPrimary: i == 0})
+	}
+	at.server.AllocationID = at.allocations[0].ID
+	otherServer := uuid.New()
+	at.other = &entities.Allocation{ID: uuid.New(), NodeID: node.ID, IP: "203.0.113.10", Port: 27015, ServerID: &otherServer, IsPrimary: true}
+	for _, row := range []interface{}{at.server, at.allocations, at.other} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, validator: middleware.NewValidator(), agent: agent.NewClient(cfg.Agents, db)}
+	at.caller = at.server.OwnerID
+	at.app = fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		at.err = err
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	at.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, at.caller)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	at.app.Put("/allocations/:id", h.UpdateAllocation)
+	at.app.Post("/servers/:id/allocations/:allocId/primary", h.SetPrimaryAllocation)
+	return at
+}
+
+// request sends body to path, returning the status
+func (at *allocationTest) request(t *testing.T, method, path string, body fiber.Map) int {
+	t.Helper()
+	at.err = nil
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := at.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// primaries returns the ports of the primary allocations of the test server
+// and whether the other server's allocation is still its primary
+func (at *allocationTest) primaries(t *testing.T) ([]int, bool) {
+	t.Helper()
+	var ports []int
+	if err := at.db.Model(&entities.Allocation{}).Where("server_id = ? AND is_primary", at.server.ID).Pluck("port", &ports).Error; err != nil {
+		t.Fatal(err)
+	}
+	var other entities.Allocation
+	if err := at.db.First(&other, "id = ?", at.other.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ports, other.IsPrimary
+}
+
+func TestSetPrimaryAllocationKeepsOnePrimary(t *testing.T) {
+	at := newAllocationTest(t)
+	base := "/servers/" + at.server.ID.String() + "/allocations/"
+
+	// Allocations of other servers cannot be made primary
+	at.request(t, http.MethodPost, base+at.other.ID.String()+"/primary", nil)
+	if !errors.Is(at.err, services.ErrAllocationNotFound) {
+		t.Errorf("another server's allocation = %v, want %v", at.err, services.ErrAllocationNotFound)
+	}
+
+	if status := at.request(t, http.MethodPost, base+at.allocations[2].ID.String()+"/primary", nil); status != http.StatusOK {
+		t.Fatalf("set primary = %d, %v", status, at.err)
+	}
+	ports, otherPrimary := at.primaries(t)
+	if len(ports) != 1 || ports[0] != 25567 {
+		t.Errorf("primary ports %v, want only 25567", ports)
+	}
+	if !otherPrimary {
+		t.Error("the other server lost its primary allocation")
+	}
+
+	var row struct {
+		AllocationID uuid.UUID
+		StartupCmd   string
+		Environment  string
+	}
+	if err := at.db.Model(&entities.Server{}).Select("allocation_id", "startup_cmd", "environment").Where("id = ?", at.server.ID).Scan(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	var env map[string]string
+	if err := json.Unmarshal([]byte(row.Environment), &env); err != nil {
+		t.Fatal(err)
+	}
+	if row.AllocationID != at.allocations[2].ID || env["SERVER_PORT"] != "25567" || row.StartupCmd != "./start --port 25567" {
+		t.Errorf("server on %s with SERVER_PORT %s running %q, want the new primary", row.AllocationID, env["SERVER_PORT"], row.StartupCmd)
+	}
+
+	// The node is told, and a stopped server is not started
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if len(at.agentRequests) != 1 || !strings.HasSuffix(at.agentRequests[0], "/startup") {
+		t.Errorf("agent requests %v, want only the startup update", at.agentRequests)
+	}
+}
+
+func TestUpdateAllocationAlias(t *testing.T) {
+	at := newAllocationTest(t)
+	path := "/allocations/" + at.allocations[1].ID.String()
+
+	if status := at.request(t, http.MethodPut, path, fiber.Map{"alias": "query", "notes": "Steam query port"}); status != http.StatusOK {
+		t.Fatalf("owner update = %d, %v", status, at.err)
+	}
+	var allocation entities.Allocation
+	if err := at.db.First(&allocation, "id = ?", at.allocations[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if allocation.Alias != "query" || allocation.Notes != "Steam query port" || allocation.IsPrimary || allocation.Port != 25566 {
+		t.Errorf("allocation %+v, want only the alias and notes changed", allocation)
+	}
+
+	if status := at.request(t, http.MethodPut, path, fiber.Map{"alias": strings.Repeat("a", 256)}); status != http.StatusBadRequest {
+		t.Errorf("overlong alias = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	at.caller = uuid.New()
+	if status := at.request(t, http.MethodPut, path, fiber.Map{"alias": "mine"}); status != http.StatusForbidden {
+		t.Errorf("update by another user = %d, want %d", status, http.StatusForbidden)
+	}
+	if status := at.request(t, http.MethodPut, "/allocations/"+at.other.ID.String(), fiber.Map{"alias": "mine"}); status != http.StatusForbidden {
+		t.Errorf("update of another server's allocation = %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/mail"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
 	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -11,17 +15,30 @@ import (
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	config *config.Config
-	db     *gorm.DB
-	redis  *redis.Client
+	config    *config.Config
+	db        *gorm.DB
+	redis     *redis.Client
+	settings  *database.Settings
+	validator *middleware.Validator
+	mailer    *mail.Mailer
+	auth      *services.AuthService
 }
 
 // NewAuthHandler creates a new AuthHandler
 func NewAuthHandler(cfg *config.Config, db *gorm.DB, rdb *redis.Client) *AuthHandler {
 	return &AuthHandler{
-		config: cfg,
-		db:     db,
-		redis:  rdb,
+		config:    cfg,
+		db:        db,
+		redis:     rdb,
+		settings:  database.NewSettings(db, rdb),
+		validator: middleware.NewValidator(),
+		mailer:    mail.NewMailer(cfg.Mail),
+		auth: services.NewAuthService(
+			database.NewUserRepository(db),
+			database.NewSessionRepository(db),
+			database.NewAuditLogRepository(db),
+			cfg,
+		),
 	}
 }
 
@@ -50,12 +67,22 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: Implement actual login logic using AuthService
-	// This is a placeholder response
-	return c.JSON(fiber.Map{
-		"message": "Login endpoint",
-		"email":   req.Email,
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	resp, err := h.auth.Login(c.UserContext(), &services.LoginRequest{
+		Email:     req.Email,
+		Password:  req.Password,
+		TwoFACode: req.TwoFACode,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
 	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(resp)
 }
 
 // Register handles user registration
@@ -67,10 +94,86 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: Implement actual registration logic
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Registration successful",
+	ctx := c.UserContext()
+	if !h.settings.Bool(ctx, database.SettingRegistrationEnabled) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Registration is disabled",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var existing int64
+	if err := h.db.Model(&entities.User{}).
+		Where("LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)", req.Email, req.Username).
+		Count(&existing).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Registration failed",
+		})
+	}
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Email or username is already taken",
+		})
+	}
+
+	var role entities.Role
+	if err := h.db.Where("is_default = ?", true).First(&role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "No default role is configured",
+		})
+	}
+
+	hash, err := services.HashPassword(req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Registration failed",
+		})
+	}
+
+	verify := h.config.Security.RequireEmailVerification
+	user := entities.User{
+		Email:        req.Email,
+		Username:     req.Username,
+		PasswordHash: hash,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Status:       entities.UserStatusActive,
+		RoleID:       role.ID,
+	}
+	if verify {
+		user.Status = entities.UserStatusPending
+	}
+	if err := h.db.Create(&user).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Registration failed",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:     &user.ID,
+		Action:     entities.AuditActionCreate,
+		Resource:   "user",
+		ResourceID: &user.ID,
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
 	})
+
+	resp := fiber.Map{
+		"message":               "Registration successful",
+		"verification_required": verify,
+	}
+	if verify {
+		if err := h.sendVerificationEmail(ctx, &user); err != nil {
+			resp["message"] = "Registration successful, but the verification email could not be sent"
+		} else {
+			resp["message"] = "Registration successful, check your email to verify your account"
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
 // RefreshToken handles token refresh
@@ -84,10 +187,16 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: Implement token refresh logic
-	return c.JSON(fiber.Map{
-		"message": "Token refreshed",
-	})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	tokens, err := h.auth.RefreshToken(c.UserContext(), req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(tokens)
 }
 
 // Logout handles user logout
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+)
+
+// authTest serves the registration, verification and login endpoints
+type authTest struct {
+	db  *gorm.DB
+	rdb *redis.Client
+	h   *AuthHandler
+	app *fiber.App
+}
+
+func newAuthTest(t *testing.T) *authTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Role{}, &entities.User{}, &entities.Session{}, &entities.AuditLog{}, &entities.Setting{})
+	if err := db.Create(&entities.Role{ID: uuid.New(), Name: "user", IsDefault: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			Keys:         []config.JWTKey{{ID: "test", Secret: "test-secret"}},
+			CurrentKey:   "test",
+			AccessExpiry: 15 * time.Minute,
+		},
+		Security: config.SecurityConfig{RequireEmailVerification: true, MaxLoginAttempts: 5, EmailVerificationExpiry: time.Hour},
+	}
+	at := &authTest{db: db, rdb: rdb, h: NewAuthHandler(cfg, db, rdb)}
+	at.app = fiber.New()
+	at.app.Post("/register", at.h.Register)
+	at.app.Post("/verify-email", at.h.VerifyEmail)
+	at.app.Post("/login", at.h.Login)
+	return at
+}
+
+// post sends body to path, returning the status and the decoded response
+func (at *authTest) post(t *testing.T, path string, body fiber.Map) (int, fiber.Map) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := at.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded fiber.Map
+	_ = json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+var testRegistration = fiber.Map{"email": "new@example.com", "username": "newcomer", "password": "correct horse"}
+
+func TestRegistrationClosed(t *testing.T) {
+	at := newAuthTest(t)
+	if err := at.db.Create(&entities.Setting{Key: database.SettingRegistrationEnabled, Type: entities.SettingTypeBool, Value: "false"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if status, resp := at.post(t, "/register", testRegistration); status != http.StatusForbidden {
+		t.Errorf("register while closed = %d %v, want %d", status, resp, http.StatusForbidden)
+	}
+	var count int64
+	at.db.Model(&entities.User{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d users created while registration was closed", count)
+	}
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	at := newAuthTest(t)
+	ctx := context.Background()
+
+	status, resp := at.post(t, "/register", testRegistration)
+	if status != http.StatusCreated || resp["verification_required"] != true {
+		t.Fatalf("register = %d %v, want %d requiring verification", status, resp, http.StatusCreated)
+	}
+	var user entities.User
+	if err := at.db.First(&user, "username = ?", "newcomer").Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != entities.UserStatusPending || user.EmailVerified {
+		t.Fatalf("registered user status %s, verified %v, want pending and unverified", user.Status, user.EmailVerified)
+	}
+
+	login := &services.LoginRequest{Email: "new@example.com", Password: "correct horse"}
+	if _, err := at.h.auth.Login(ctx, login); !errors.Is(err, services.ErrEmailNotVerified) {
+		t.Errorf("login before verification = %v, want %v", err, services.ErrEmailNotVerified)
+	}
+
+	// The mailed token, stored as the registration stores it
+	token := strings.Repeat("ab", 32)
+	if err := at.rdb.Set(ctx, emailVerificationKey(token), user.ID.String(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, resp := at.post(t, "/verify-email", fiber.Map{"token": strings.Repeat("cd", 32)}); status != http.StatusBadRequest {
+		t.Errorf("unknown token = %d %v, want %d", status, resp, http.StatusBadRequest)
+	}
+	if status, resp := at.post(t, "/verify-email", fiber.Map{"token": token}); status != http.StatusOK {
+		t.Fatalf("verify = %d %v, want %d", status, resp, http.StatusOK)
+	}
+	at.db.First(&user, "id = ?", user.ID)
+	if user.Status != entities.UserStatusActive || !user.EmailVerified || user.EmailVerifiedAt == nil {
+		t.Errorf("verified user status %s, verified %v at %v, want active and verified", user.Status, user.EmailVerified, user.EmailVerifiedAt)
+	}
+	if status, _ := at.post(t, "/verify-email", fiber.Map{"token": token}); status != http.StatusBadRequest {
+		t.Errorf("reused token = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, resp = at.post(t, "/login", fiber.Map{"email": "new@example.com", "password": "correct horse"})
+	if status != http.StatusOK {
+		t.Fatalf("login after verification = %d %v, want %d", status, resp, http.StatusOK)
+	}
+	if tokens, _ := resp["tokens"].(map[string]interface{}); tokens == nil || tokens["access_token"] == "" {
+		t.Errorf("login response %v has no tokens", resp)
+	}
+}
+
+func TestLoginLogsCarryRequestID(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	at := newAuthTest(t)
+	hash, err := services.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &entities.User{ID: uuid.New(), Email: "alex@example.com", Username: "alex", PasswordHash: hash, Status: entities.UserStatusActive, EmailVerified: true}
+	if err := at.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The audit write of the login fails, which the service logs
+	if err := at.db.Migrator().DropTable(&entities.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{ContextKey: middleware.RequestIDKey}), middleware.RequestLogging())
+	app.Post("/login", at.h.Login)
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"alex@example.com","password":"correct horse"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	id := resp.Header.Get(fiber.HeaderXRequestID)
+	if resp.StatusCode != http.StatusOK || id == "" {
+		t.Fatalf("login = %d with request ID %q", resp.StatusCode, id)
+	}
+
+	entries := logs.FilterMessage("Failed to write audit log").All()
+	if len(entries) != 1 || entries[0].ContextMap()["request_id"] != id {
+		t.Errorf("logged %v, want the audit failure tied to request %s", entries, id)
+	}
+}
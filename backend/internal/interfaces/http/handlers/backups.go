@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DownloadBackup streams a backup archive from its node. The node verifies the
+// archive against its checksum first; a mismatch flags the backup as
+// corrupted instead of handing out a broken archive.
+func (h *Handler) DownloadBackup(c *fiber.Ctx) error {
+	server, backup, err := h.findBackup(c)
+	if err != nil {
+		return err
+	}
+
+	body, size, err := h.agent.DownloadBackup(c.UserContext(), server.NodeID, server.ID, backup.ID, backup.Checksum)
+	if errors.Is(err, services.ErrBackupCorrupted) {
+		if err := h.backups.MarkCorrupted(c.UserContext(), backup, server); err != nil {
+			return err
+		}
+		return services.ErrBackupCorrupted
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to download backup from node",
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionDownload,
+		Resource:   "backup",
+		ResourceID: &backup.ID,
+		Metadata:   map[string]interface{}{"server_id": server.ID, "name": backup.Name},
+		IPAddress:  c.IP(),
+	})
+
+	c.Set("Content-Type", "application/gzip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backup.Name+".tar.gz"))
+	return c.SendStream(body, int(size))
+}
+
+// VerifyBackup has a backup's node re-hash its archive right away, rather
+// than waiting for the integrity scan to reach it
+func (h *Handler) VerifyBackup(c *fiber.Ctx) error {
+	_, backup, err := h.findBackup(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.backups.VerifyBackup(c.UserContext(), backup); err != nil {
+		if errors.Is(err, services.ErrBackupCorrupted) {
+			return services.ErrBackupCorrupted
+		}
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to verify backup on node",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": backup,
+	})
+}
+
+type RestoreBackupRequest struct {
+	WipeData     bool  `json:"wipe_data"`     // Empty the data directory before extracting
+	SafetyBackup *bool `json:"safety_backup"` // Back up the server first, omit for the panel setting
+}
+
+// RestoreBackup restores a completed backup onto its server. The node checks
+// the archive against its checksum before touching any data and brings the
+// server back to the power state it had before.
+func (h *Handler) RestoreBackup(c *fiber.Ctx) error {
+	var req RestoreBackupRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+	backupID, err := uuid.Parse(c.Params("backupId"))
+	if err != nil {
+		return services.ErrBackupNotFound
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.UserContext()
+	if err := h.servers.RestoreBackup(ctx, server.ID, backupID, userID, req.WipeData, h.wantsSafetyBackup(c, req.SafetyBackup)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Backup restored",
+	})
+}
+
+// wantsSafetyBackup reports whether a destructive action should back the
+// server up first: as the request asks, or as the panel setting says when the
+// request leaves it out
+func (h *Handler) wantsSafetyBackup(c *fiber.Ctx, requested *bool) bool {
+	if requested != nil {
+		return *requested
+	}
+	return h.settings.Bool(c.UserContext(), database.SettingSafetyBackups)
+}
+
+// backupData is the webhook data of a server backup transition
+func backupData(backup *entities.Backup) map[string]interface{} {
+	return map[string]interface{}{
+		"backup_id":   backup.ID,
+		"backup_name": backup.Name,
+		"size":        backup.Size,
+	}
+}
+
+// findBackup loads the completed backup named by the request, along with its
+// server, checking that the caller may access it
+func (h *Handler) findBackup(c *fiber.Ctx) (*entities.Server, *entities.Backup, error) {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var backup entities.Backup
+	if err := h.db.Scopes(database.NotTrashed).
+		Where("id = ? AND server_id = ?", c.Params("backupId"), server.ID).
+		First(&backup).Error; err != nil {
+		return nil, nil, services.ErrBackupNotFound
+	}
+	switch {
+	case backup.Status == entities.BackupStatusCorrupted:
+		return nil, nil, services.ErrBackupCorrupted
+	case backup.Status != entities.BackupStatusCompleted || backup.Checksum == "":
+		return nil, nil, services.ErrBackupNotCompleted
+	}
+
+	backup.Server = server
+	return server, &backup, nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ChatLogQuery struct {
+	Q    string `query:"q" validate:"max=200"`
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// GetChatLogs searches the stored chat of a server by message text and time range, newest first
+func (h *Handler) GetChatLogs(c *fiber.Ctx) error {
+	var query ChatLogQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+	if fields := h.validator.Validate(query); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+	params := pageParams(c, 50, 200)
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	db := h.db.Model(&entities.ChatLog{}).Where("server_id = ?", server.ID)
+	if query.Q != "" {
+		// Escape LIKE wildcards so the query matches literally
+		pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query.Q)
+		db = db.Where("message ILIKE ?", "%"+pattern+"%")
+	}
+	if from, err := time.Parse(time.RFC3339, query.From); err == nil {
+		db = db.Where("created_at >= ?", from)
+	}
+	if to, err := time.Parse(time.RFC3339, query.To); err == nil {
+		db = db.Where("created_at <= ?", to)
+	}
+
+	logs := make([]entities.ChatLog, 0, params.PageSize)
+	total, err := database.Paginate(db, params, "created_at DESC", &logs)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search chat logs",
+		})
+	}
+
+	return c.JSON(paginated(logs, params, total))
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestGetChatLogsSearch(t *testing.T) {
+	db := newTestDB(t, &entities.Server{}, &entities.ChatLog{})
+	server := &entities.Server{ID: uuid.New(), Name: "survival", NodeID: uuid.New(), OwnerID: uuid.New()}
+	if err := db.Create(server).Error; err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, log := range []*entities.ChatLog{
+		{ServerID: server.ID, Username: "Steve", Message: "anyone seen my Diamonds?", CreatedAt: day},
+		{ServerID: server.ID, Username: "Alex", Message: "diamonds are at spawn", CreatedAt: day.Add(24 * time.Hour)},
+		{ServerID: server.ID, Username: "Alex", Message: "good night", CreatedAt: day.Add(48 * time.Hour)},
+		{ServerID: uuid.New(), Username: "Eve", Message: "diamonds elsewhere", CreatedAt: day},
+	} {
+		if err := db.Create(log).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{cfg: &config.Config{}, db: db, validator: middleware.NewValidator()}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, server.OwnerID)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Get("/servers/:id/chat", h.GetChatLogs)
+
+	for name, tc := range map[string]struct {
+		query url.Values
+		want  []string
+	}{
+		"everything":         {url.Values{}, []string{"good night", "diamonds are at spawn", "anyone seen my Diamonds?"}},
+		"substring":          {url.Values{"q": {"diamond"}}, []string{"diamonds are at spawn", "anyone seen my Diamonds?"}},
+		"no match":           {url.Values{"q": {"emerald"}}, []string{}},
+		"from":               {url.Values{"from": {day.Add(time.Hour).Format(time.RFC3339)}}, []string{"good night", "diamonds are at spawn"}},
+		"to":                 {url.Values{"to": {day.Add(24 * time.Hour).Format(time.RFC3339)}}, []string{"diamonds are at spawn", "anyone seen my Diamonds?"}},
+		"substring in range": {url.Values{"q": {"DIAMONDS"}, "from": {day.Add(time.Hour).Format(time.RFC3339)}, "to": {day.Add(36 * time.Hour).Format(time.RFC3339)}}, []string{"diamonds are at spawn"}},
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+server.ID.String()+"/chat?"+tc.query.Encode(), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Data []entities.ChatLog `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, %v", name, resp.StatusCode, err)
+			continue
+		}
+		got := make([]string, 0, len(body.Data))
+		for _, log := range body.Data {
+			got = append(got, log.Message)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: messages %q, want %q", name, got, tc.want)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+server.ID.String()+"/chat?from=yesterday", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid from = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UpdateCommandPolicyRequest struct {
+	Mode     string   `json:"mode" validate:"omitempty,oneof=allow deny"` // Empty allows every command
+	Prefixes []string `json:"prefixes" validate:"max=100,dive,required,max=100"`
+}
+
+// UpdateCommandPolicy replaces the console commands a server accepts from
+// users other than its owner and admins, as an allowlist or a denylist of
+// command prefixes. Blocked commands are refused and audited.
+func (h *Handler) UpdateCommandPolicy(c *fiber.Ctx) error {
+	var req UpdateCommandPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	policy := entities.CommandPolicy{Mode: req.Mode, Prefixes: req.Prefixes}
+	if policy.Prefixes == nil {
+		policy.Prefixes = []string{}
+	}
+	if err := h.db.Model(server).Update("command_policy", policy).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update command policy",
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"command_policy": policy},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": policy,
+	})
+}
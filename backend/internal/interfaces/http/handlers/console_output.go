@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UpdateConsoleOutputRequest struct {
+	StripANSI bool `json:"strip_ansi"`
+}
+
+// UpdateConsoleOutput sets whether a server's console keeps its colours or is
+// sent as plain text. Output is always cleaned of invalid UTF-8 and stray
+// control characters by the node either way.
+func (h *Handler) UpdateConsoleOutput(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	req := UpdateConsoleOutputRequest{StripANSI: server.ConsoleStripANSI}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.db.Model(server).Update("console_strip_ansi", req.StripANSI).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update console output",
+		})
+	}
+
+	if err := h.agent.UpdateConsoleOutput(c.UserContext(), server.NodeID, server.ID, req.StripANSI); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Console output saved but the node could not be updated",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"console_strip_ansi": req.StripANSI},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": req,
+	})
+}
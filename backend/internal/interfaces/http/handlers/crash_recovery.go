@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UpdateCrashRecoveryRequest struct {
+	Enabled     bool `json:"enabled"`
+	MaxRestarts int  `json:"max_restarts" validate:"min=1,max=10"`
+	Window      int  `json:"window" validate:"min=60,max=86400"` // seconds
+}
+
+// UpdateCrashRecovery sets whether a server is restarted after crashing, and
+// how often within a window before it is left stopped and its owner told.
+// Stops through power actions or the console never count as crashes.
+func (h *Handler) UpdateCrashRecovery(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	req := UpdateCrashRecoveryRequest{
+		Enabled:     server.CrashRecoveryEnabled,
+		MaxRestarts: server.CrashRecoveryMaxRestarts,
+		Window:      server.CrashRecoveryWindow,
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	if err := h.db.Model(server).Updates(map[string]interface{}{
+		"crash_recovery_enabled":      req.Enabled,
+		"crash_recovery_max_restarts": req.MaxRestarts,
+		"crash_recovery_window":       req.Window,
+	}).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update crash recovery",
+		})
+	}
+
+	policy := agent.CrashRecoveryPolicy{Enabled: req.Enabled, MaxRestarts: req.MaxRestarts, Window: req.Window}
+	if err := h.agent.UpdateCrashRecovery(c.UserContext(), server.NodeID, server.ID, policy); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Crash recovery saved but the node could not be updated",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"crash_recovery": policy},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": policy,
+	})
+}
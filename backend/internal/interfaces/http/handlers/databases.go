@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CreateDatabaseHostRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Host         string `json:"host" validate:"required,max=255"`
+	Port         int    `json:"port" validate:"omitempty,min=1,max=65535"` // Defaults to 3306
+	Username     string `json:"username" validate:"required,max=100"`
+	Password     string `json:"password" validate:"required"`
+	NodeID       string `json:"node_id" validate:"omitempty,uuid"` // Empty serves every node
+	MaxDatabases int    `json:"max_databases" validate:"min=0"`
+}
+
+type CreateServerDatabaseRequest struct {
+	Name   string `json:"name" validate:"required,max=48"`
+	Remote string `json:"remote" validate:"max=255"` // Hosts allowed to connect, defaults to %
+}
+
+// GetDatabaseHosts returns the configured database hosts
+func (h *Handler) GetDatabaseHosts(c *fiber.Ctx) error {
+	var hosts []entities.DatabaseHost
+	if err := h.db.Order("name").Find(&hosts).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch database hosts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": hosts,
+	})
+}
+
+// CreateDatabaseHost stores a database host with its encrypted admin password
+func (h *Handler) CreateDatabaseHost(c *fiber.Ctx) error {
+	var req CreateDatabaseHostRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	secrets, err := crypto.NewCipher(h.cfg.Security.EncryptionKey)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Encryption key is not configured correctly",
+		})
+	}
+	encrypted, err := secrets.Encrypt(req.Password)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to encrypt password",
+		})
+	}
+
+	host := entities.DatabaseHost{
+		Name:              req.Name,
+		Host:              req.Host,
+		Port:              req.Port,
+		Username:          req.Username,
+		PasswordEncrypted: encrypted,
+		MaxDatabases:      req.MaxDatabases,
+	}
+	if host.Port == 0 {
+		host.Port = 3306
+	}
+	if req.NodeID != "" {
+		nodeID := uuid.MustParse(req.NodeID)
+		var node entities.Node
+		if err := h.db.Where("id = ?", nodeID).First(&node).Error; err != nil {
+			return services.ErrNodeNotFound
+		}
+		host.NodeID = &nodeID
+	}
+
+	if err := h.db.Create(&host).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create database host",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": host,
+	})
+}
+
+// DeleteDatabaseHost removes a database host that no longer holds databases
+func (h *Handler) DeleteDatabaseHost(c *fiber.Ctx) error {
+	var host entities.DatabaseHost
+	if err := h.db.Where("id = ?", c.Params("id")).First(&host).Error; err != nil {
+		return services.ErrDatabaseHostNotFound
+	}
+
+	var count int64
+	h.db.Model(&entities.ServerDatabase{}).Where("database_host_id = ?", host.ID).Count(&count)
+	if count > 0 {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Database host still has databases",
+		})
+	}
+
+	if err := h.db.Delete(&host).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete database host",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// GetServerDatabases returns the databases of a server
+func (h *Handler) GetServerDatabases(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	databases, err := h.databases.List(c.UserContext(), server.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch databases",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": databases,
+	})
+}
+
+// CreateServerDatabase provisions a database and user for a server within its database limit
+func (h *Handler) CreateServerDatabase(c *fiber.Ctx) error {
+	var req CreateServerDatabaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	database, err := h.databases.Create(c.UserContext(), server.ID, req.Name, req.Remote, userID)
+	if err != nil {
+		return err
+	}
+
+	h.recordActivity(c, database.ServerID, entities.ActivityDatabaseCreate, database.Database)
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": database,
+	})
+}
+
+// RotateServerDatabasePassword gives a database user a new random password
+func (h *Handler) RotateServerDatabasePassword(c *fiber.Ctx) error {
+	server, databaseID, err := h.serverDatabaseParams(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	database, err := h.databases.RotatePassword(c.UserContext(), server.ID, databaseID, userID)
+	if err != nil {
+		return err
+	}
+
+	h.recordActivity(c, database.ServerID, entities.ActivityDatabaseRotate, database.Database)
+
+	return c.JSON(fiber.Map{
+		"data": database,
+	})
+}
+
+// DeleteServerDatabase drops a database and its user
+func (h *Handler) DeleteServerDatabase(c *fiber.Ctx) error {
+	server, databaseID, err := h.serverDatabaseParams(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	database, err := h.databases.Delete(c.UserContext(), server.ID, databaseID, userID)
+	if err != nil {
+		return err
+	}
+
+	h.recordActivity(c, database.ServerID, entities.ActivityDatabaseDelete, database.Database)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// serverDatabaseParams returns the server and database ID named by the request
+func (h *Handler) serverDatabaseParams(c *fiber.Ctx) (*entities.Server, uuid.UUID, error) {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	databaseID, err := uuid.Parse(c.Params("databaseId"))
+	if err != nil {
+		return nil, uuid.Nil, services.ErrDatabaseNotFound
+	}
+	return server, databaseID, nil
+}
+
+// configuredSecrets encrypts with the configured key, checked on each use so a
+// missing key fails the requests needing it rather than the panel's startup
+type configuredSecrets string
+
+func (key configuredSecrets) Encrypt(plaintext string) (string, error) {
+	secrets, err := crypto.NewCipher(string(key))
+	if err != nil {
+		return "", err
+	}
+	return secrets.Encrypt(plaintext)
+}
+
+func (key configuredSecrets) Decrypt(ciphertext string) (string, error) {
+	secrets, err := crypto.NewCipher(string(key))
+	if err != nil {
+		return "", err
+	}
+	return secrets.Decrypt(ciphertext)
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/apperror"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UpdateEggRequest struct {
+	Name           string   `json:"name" validate:"required,min=1,max=100"`
+	Description    string   `json:"description" validate:"max=2000"`
+	StartupCommand string   `json:"startup_command" validate:"required,max=4000"`
+	DockerImages   []string `json:"docker_images" validate:"required,min=1,dive,required,max=255"`
+
+	// Protocol the primary port of the egg's servers is bound for, both when
+	// empty. Applied to existing servers when the egg is reapplied.
+	Protocol string `json:"protocol" validate:"omitempty,oneof=tcp udp both"`
+
+	// Omit to remove the egg's healthcheck
+	Healthcheck *EggHealthcheckRequest `json:"healthcheck"`
+
+	// Container caps for the egg's servers, 0 for the panel default
+	PidsLimit int64 `json:"pids_limit" validate:"min=0,max=1000000"`
+	NoFile    int64 `json:"nofile" validate:"min=0,max=1048576"`
+	NProc     int64 `json:"nproc" validate:"min=0,max=1000000"`
+
+	// Run the egg's servers with a read-only root filesystem, only the data
+	// volume and the Tmpfs paths (e.g. /tmp) stay writable. Off by default
+	// as most images write outside the data volume.
+	ReadOnlyRootfs bool     `json:"read_only_rootfs"`
+	Tmpfs          []string `json:"tmpfs" validate:"max=10,dive,required,startswith=/,ne=/,max=255"`
+
+	// Capabilities the egg's servers keep, all others are dropped. Nodes
+	// refuse those outside their allowlist.
+	Capabilities []string `json:"capabilities" validate:"max=20,dive,required,max=32,uppercase"`
+
+	// How the egg's servers run their startup: through Shell (/bin/bash when
+	// empty) or, with DirectExec, split into arguments and run without a
+	// shell. Entrypoint replaces the image's own, for images that need it.
+	Entrypoint []string `json:"entrypoint" validate:"max=10,dive,required,max=255"`
+	Shell      string   `json:"shell" validate:"omitempty,startswith=/,max=100"`
+	DirectExec bool     `json:"direct_exec"`
+
+	// Resource bounds for the egg's servers, checked when they are created or
+	// resized
+	Limits entities.EggLimits `json:"limits"`
+
+	// Friendly fields for common variables, such as max players or PvP,
+	// checked against the egg's variables
+	QuickSettings []entities.EggQuickSetting `json:"quick_settings" validate:"max=30"`
+}
+
+type EggHealthcheckRequest struct {
+	Test        string `json:"test" validate:"required,max=1000"`
+	Interval    int    `json:"interval" validate:"min=1,max=3600"`     // seconds
+	Timeout     int    `json:"timeout" validate:"min=1,max=600"`       // seconds
+	Retries     int    `json:"retries" validate:"min=1,max=20"`        // failures before unhealthy
+	StartPeriod int    `json:"start_period" validate:"min=0,max=3600"` // seconds
+}
+
+type ReapplyEggRequest struct {
+	ServerIDs []string `json:"server_ids" validate:"required,min=1,max=100,dive,uuid"`
+	Confirm   bool     `json:"confirm"` // Without it the changes are only previewed
+}
+
+// EggReapplyPreview is what reapplying an egg would change on a server
+type EggReapplyPreview struct {
+	ServerID       uuid.UUID `json:"server_id"`
+	Name           string    `json:"name"`
+	StartupCmd     string    `json:"startup_cmd"`
+	NewStartupCmd  string    `json:"new_startup_cmd"`
+	DockerImage    string    `json:"docker_image"`
+	NewDockerImage string    `json:"new_docker_image"`
+}
+
+// UpdateEgg changes an egg. A new startup command or image list bumps the
+// egg's version and flags the servers it would build differently as outdated;
+// they keep running as they are until the egg is reapplied to them. A changed
+// healthcheck, process limit, root filesystem mode or capability reaches
+// servers the same way, with their next startup push.
+func (h *Handler) UpdateEgg(c *fiber.Ctx) error {
+	var req UpdateEggRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+	if err := services.ValidateEggLimits(req.Limits); err != nil {
+		return apperror.New(http.StatusBadRequest, "egg.invalid_limits", err.Error())
+	}
+
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", c.Params("id")).First(&egg).Error; err != nil {
+		return services.ErrEggNotFound
+	}
+	var eggVars []*entities.EggVariable
+	if err := h.db.Where("egg_id = ?", egg.ID).Find(&eggVars).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch variables",
+		})
+	}
+	if err := services.ValidateQuickSettings(req.QuickSettings, eggVars); err != nil {
+		return apperror.New(http.StatusBadRequest, "egg.invalid_quick_settings", err.Error())
+	}
+
+	old := egg
+	egg.Name = req.Name
+	egg.Description = req.Description
+	egg.StartupCommand = req.StartupCommand
+	egg.DockerImages = req.DockerImages
+	egg.Protocol = req.Protocol
+	if egg.Protocol == "" {
+		egg.Protocol = entities.ProtocolBoth
+	}
+	egg.Healthcheck = nil
+	if hc := req.Healthcheck; hc != nil {
+		egg.Healthcheck = &entities.EggHealthcheck{
+			Test:        hc.Test,
+			Interval:    hc.Interval,
+			Timeout:     hc.Timeout,
+			Retries:     hc.Retries,
+			StartPeriod: hc.StartPeriod,
+		}
+	}
+	egg.PidsLimit = req.PidsLimit
+	egg.NoFile = req.NoFile
+	egg.NProc = req.NProc
+	egg.ReadOnlyRootfs = req.ReadOnlyRootfs
+	egg.Tmpfs = req.Tmpfs
+	egg.Capabilities = req.Capabilities
+	egg.Entrypoint = req.Entrypoint
+	egg.Shell = req.Shell
+	egg.DirectExec = req.DirectExec
+	egg.Limits = req.Limits
+	egg.QuickSettings = req.QuickSettings
+	changed := services.EggChanged(&old, &egg)
+	if changed {
+		egg.Version++
+	}
+
+	if err := h.db.Model(&egg).Select("name", "description", "startup_command", "docker_images", "protocol", "healthcheck", "pids_limit", "nofile", "nproc", "read_only_rootfs", "tmpfs", "capabilities", "entrypoint", "shell", "direct_exec", "limits", "quick_settings", "version").Updates(&egg).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update egg",
+		})
+	}
+
+	outdated := 0
+	if changed {
+		var err error
+		if outdated, err = h.flagOutdatedServers(&egg); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Egg updated but its servers could not be checked",
+			})
+		}
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditActionUpdate,
+		Resource:    "egg",
+		ResourceID:  &egg.ID,
+		Description: "Updated egg " + egg.Name,
+		Metadata:    map[string]interface{}{"version": egg.Version, "outdated_servers": outdated},
+		IPAddress:   c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data":             egg,
+		"outdated_servers": outdated,
+	})
+}
+
+// flagOutdatedServers checks every server of an egg against its current
+// version. Servers it would build differently are flagged outdated, the rest
+// are moved to the new version. It returns the number of outdated servers.
+func (h *Handler) flagOutdatedServers(egg *entities.Egg) (int, error) {
+	var servers []*entities.Server
+	if err := h.db.Where("egg_id = ?", egg.ID).Find(&servers).Error; err != nil {
+		return 0, err
+	}
+	if len(servers) == 0 {
+		return 0, nil
+	}
+
+	var eggVars []*entities.EggVariable
+	if err := h.db.Where("egg_id = ?", egg.ID).Find(&eggVars).Error; err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, len(servers))
+	for i, server := range servers {
+		ids[i] = server.ID
+	}
+	var allocations []*entities.Allocation
+	if err := h.db.Where("server_id IN ?", ids).Find(&allocations).Error; err != nil {
+		return 0, err
+	}
+	byServer := make(map[uuid.UUID][]*entities.Allocation, len(servers))
+	for _, alloc := range allocations {
+		if alloc.ServerID != nil {
+			byServer[*alloc.ServerID] = append(byServer[*alloc.ServerID], alloc)
+		}
+	}
+
+	var outdated, current []uuid.UUID
+	for _, server := range servers {
+		if services.EggOutdated(egg, eggVars, server, byServer[server.ID]) {
+			outdated = append(outdated, server.ID)
+		} else {
+			current = append(current, server.ID)
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if len(outdated) > 0 {
+			if err := tx.Model(&entities.Server{}).Where("id IN ?", outdated).
+				Update("egg_outdated", true).Error; err != nil {
+				return err
+			}
+		}
+		if len(current) > 0 {
+			return tx.Model(&entities.Server{}).Where("id IN ?", current).Updates(map[string]interface{}{
+				"egg_version":  egg.Version,
+				"egg_outdated": false,
+			}).Error
+		}
+		return nil
+	})
+	return len(outdated), err
+}
+
+// GetOutdatedEggServers returns a page of the servers an egg would now build differently
+func (h *Handler) GetOutdatedEggServers(c *fiber.Ctx) error {
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", c.Params("id")).First(&egg).Error; err != nil {
+		return services.ErrEggNotFound
+	}
+
+	params := pageParams(c, 50, 100)
+	servers := make([]entities.Server, 0, params.PageSize)
+	query := h.db.Model(&entities.Server{}).Where("egg_id = ? AND egg_outdated = ?", egg.ID, true)
+	total, err := database.Paginate(query, params, "name", &servers, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Node")
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch servers",
+		})
+	}
+
+	return c.JSON(paginated(servers, params, total))
+}
+
+// ReapplyEgg rebuilds the startup command, image and port protocols of
+// selected servers from the current version of their egg, keeping their
+// variable values. Without
+// confirm it only returns what would change. Running servers pick up the
+// changes on their next start.
+func (h *Handler) ReapplyEgg(c *fiber.Ctx) error {
+	var req ReapplyEggRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", c.Params("id")).First(&egg).Error; err != nil {
+		return services.ErrEggNotFound
+	}
+	var eggVars []*entities.EggVariable
+	if err := h.db.Where("egg_id = ?", egg.ID).Find(&eggVars).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch variables",
+		})
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.ServerIDs))
+	for _, raw := range req.ServerIDs {
+		ids = append(ids, uuid.MustParse(raw))
+	}
+
+	if !req.Confirm {
+		previews := make([]EggReapplyPreview, 0, len(ids))
+		for _, id := range ids {
+			server, allocations, err := h.eggServer(c.UserContext(), egg.ID, id)
+			if err != nil {
+				continue
+			}
+			startup, image := services.ReapplyEgg(&egg, eggVars, server, allocations)
+			previews = append(previews, EggReapplyPreview{
+				ServerID:       server.ID,
+				Name:           server.Name,
+				StartupCmd:     server.StartupCmd,
+				NewStartupCmd:  startup.Command,
+				DockerImage:    server.DockerImage,
+				NewDockerImage: image,
+			})
+		}
+		return c.JSON(fiber.Map{
+			"confirm": false,
+			"data":    previews,
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	ip := c.IP()
+	results := services.RunBulk(c.UserContext(), ids, h.cfg.Agents.BulkConcurrency, func(ctx context.Context, id uuid.UUID) error {
+		server, allocations, err := h.eggServer(ctx, egg.ID, id)
+		if err != nil {
+			return err
+		}
+
+		startup, image := services.ReapplyEgg(&egg, eggVars, server, allocations)
+		imageChanged := image != server.DockerImage
+		server.StartupCmd = startup.Command
+		server.Environment = startup.Environment
+		server.DockerImage = image
+		if server.IsRunning() {
+			server.RestartRequired = true
+		}
+
+		if err := h.reapplyProtocols(ctx, &egg, server, allocations); err != nil {
+			return err
+		}
+		if err := h.db.Model(server).Updates(map[string]interface{}{
+			"startup_cmd":      server.StartupCmd,
+			"environment":      server.Environment,
+			"docker_image":     server.DockerImage,
+			"restart_required": server.RestartRequired,
+			"egg_version":      egg.Version,
+			"egg_outdated":     false,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := h.agent.UpdateServerStartup(ctx, server.NodeID, server.ID, startup, allocations); err != nil {
+			return err
+		}
+		if imageChanged {
+			if err := h.agent.UpdateServerImage(ctx, server.NodeID, server.ID, image); err != nil {
+				return err
+			}
+		}
+
+		h.db.Create(&entities.AuditLog{
+			UserID:      &userID,
+			Action:      entities.AuditActionUpdate,
+			Resource:    "server",
+			ResourceID:  &server.ID,
+			Description: "Reapplied egg " + egg.Name,
+			Metadata:    map[string]interface{}{"egg_id": egg.ID, "egg_version": egg.Version},
+			IPAddress:   ip,
+		})
+		return nil
+	})
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"confirm":   true,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"data":      results,
+	})
+}
+
+// reapplyProtocols sets the protocols of a server's allocations to those its
+// egg asks for
+func (h *Handler) reapplyProtocols(ctx context.Context, egg *entities.Egg, server *entities.Server, allocations []*entities.Allocation) error {
+	var primary *entities.Allocation
+	for _, alloc := range allocations {
+		if alloc.ID == server.AllocationID {
+			primary = alloc
+		}
+	}
+
+	services.ApplyAllocationProtocols(egg, primary, allocations)
+	for _, alloc := range allocations {
+		if err := h.db.WithContext(ctx).Model(alloc).Update("protocol", alloc.Protocol).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eggServer loads a server built from an egg along with its allocations
+func (h *Handler) eggServer(ctx context.Context, eggID, serverID uuid.UUID) (*entities.Server, []*entities.Allocation, error) {
+	var server entities.Server
+	if err := h.db.WithContext(ctx).Where("id = ? AND egg_id = ?", serverID, eggID).First(&server).Error; err != nil {
+		return nil, nil, services.ErrServerNotFound
+	}
+	var allocations []*entities.Allocation
+	if err := h.db.WithContext(ctx).Where("server_id = ?", server.ID).Find(&allocations).Error; err != nil {
+		return nil, nil, err
+	}
+	return &server, allocations, nil
+}
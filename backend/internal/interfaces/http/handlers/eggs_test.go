@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestUpdateEggFlagsAndReappliesServers(t *testing.T) {
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.Egg{}, &entities.EggVariable{}, &entities.Allocation{}, &entities.AuditLog{})
+
+	var mu sync.Mutex
+	var pushed []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pushed = append(pushed, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "game", StartupCommand: "./run --world {{WORLD}}", DockerImages: []string{"game:1"}, Version: 1}
+	otherEgg := &entities.Egg{ID: uuid.New(), GameID: egg.GameID, Name: "other", StartupCommand: "./run --world {{WORLD}}", DockerImages: []string{"game:1"}, Version: 1}
+	variable := &entities.EggVariable{ID: uuid.New(), EggID: egg.ID, Name: "World", EnvVariable: "WORLD", DefaultValue: "survival"}
+	server := func(name, eggID, startup string) *entities.Server {
+		return &entities.Server{
+			ID: uuid.New(), UUID: name, Name: name, NodeID: node.ID, EggID: uuid.MustParse(eggID), OwnerID: uuid.New(),
+			StartupCmd: startup, DockerImage: "game:1", EggVersion: 1, Status: entities.ServerStatusStopped,
+		}
+	}
+	stale := server("stale", egg.ID.String(), "./run --world survival")
+	// Already started the way the next version does, by hand
+	ahead := server("ahead", egg.ID.String(), "./run --world survival --nogui")
+	unrelated := server("unrelated", otherEgg.ID.String(), "./run --world survival")
+	for _, row := range []interface{}{node, egg, otherEgg, variable, stale, ahead, unrelated} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, validator: middleware.NewValidator(), agent: agent.NewClient(cfg.Agents, db)}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, uuid.New())
+		c.Locals(middleware.RoleNameKey, "admin")
+		return c.Next()
+	})
+	app.Put("/admin/eggs/:id", h.UpdateEgg)
+	app.Post("/admin/eggs/:id/reapply", h.ReapplyEgg)
+	request := func(method, path string, body fiber.Map) map[string]json.RawMessage {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s = %d %s", method, path, resp.StatusCode, decoded["error"])
+		}
+		return decoded
+	}
+	type state struct {
+		StartupCmd  string
+		EggVersion  int
+		EggOutdated bool
+	}
+	stored := func(id uuid.UUID) state {
+		t.Helper()
+		var s state
+		if err := db.Model(&entities.Server{}).Select("startup_cmd", "egg_version", "egg_outdated").Where("id = ?", id).Scan(&s).Error; err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	body := request(http.MethodPut, "/admin/eggs/"+egg.ID.String(), fiber.Map{
+		"name": "game", "startup_command": "./run --world {{WORLD}} --nogui", "docker_images": []string{"game:1"},
+	})
+	if string(body["outdated_servers"]) != "1" {
+		t.Errorf("outdated servers = %s, want 1", body["outdated_servers"])
+	}
+	for s, want := range map[*entities.Server]state{
+		stale:     {"./run --world survival", 1, true},
+		ahead:     {"./run --world survival --nogui", 2, false},
+		unrelated: {"./run --world survival", 1, false},
+	} {
+		if got := stored(s.ID); got != want {
+			t.Errorf("%s after the update = %+v, want %+v", s.Name, got, want)
+		}
+	}
+
+	// A preview changes nothing
+	request(http.MethodPost, "/admin/eggs/"+egg.ID.String()+"/reapply", fiber.Map{"server_ids": []string{stale.ID.String()}})
+	if got := stored(stale.ID); !got.EggOutdated || len(pushed) != 0 {
+		t.Errorf("preview changed the server (%+v) or pushed %v", got, pushed)
+	}
+
+	body = request(http.MethodPost, "/admin/eggs/"+egg.ID.String()+"/reapply", fiber.Map{"server_ids": []string{stale.ID.String()}, "confirm": true})
+	if string(body["succeeded"]) != "1" {
+		t.Fatalf("reapply = %s", body["data"])
+	}
+	if got, want := stored(stale.ID), (state{"./run --world survival --nogui", 2, false}); got != want {
+		t.Errorf("stale after reapplying = %+v, want %+v", got, want)
+	}
+	if len(pushed) != 1 || !strings.HasSuffix(pushed[0], "/servers/"+stale.ID.String()+"/startup") {
+		t.Errorf("agent calls %v, want the new startup pushed", pushed)
+	}
+}
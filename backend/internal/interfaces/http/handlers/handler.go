@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"errors"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
-	"github.com/go-playground/validator/v10"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/shutdown"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -12,15 +20,158 @@ type Handler struct {
 	cfg       *config.Config
 	db        *gorm.DB
 	redis     *redis.Client
-	validator *validator.Validate
+	validator *middleware.Validator
+	agent     *agent.Client
+	warmer    *agent.ImageWarmer
+	rollouts  *agent.ImageRollouts
+	reconcile *agent.StatusReconciler
+	hooks     *agent.ServerWebhooks
+	health    *agent.NodeHealthChecker
+	backups   *agent.BackupScanner
+	history   *redis.CommandHistory
+	databases *services.DatabaseService
+	invoices  *services.InvoiceService
+	nodes     *services.NodeService
+	servers   *services.ServerService
+	settings  *database.Settings
+	ops       *shutdown.Coordinator
+	placer    *services.NodePlacer
+	resellers *services.ResellerService
+	worlds    *services.WorldService
+
+	provisioning *services.ProvisioningService
+	maintenance  *middleware.Maintenance
 }
 
 // NewHandler creates a new handler instance
-func NewHandler(cfg *config.Config, db *gorm.DB, redis *redis.Client) *Handler {
+func NewHandler(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logger, ops *shutdown.Coordinator) *Handler {
+	agentClient := agent.NewClient(cfg.Agents, db)
+	settings := database.NewSettings(db, rdb)
+	hooks := agent.NewServerWebhooks(db, cfg.Webhooks, log)
+	uow := database.NewUnitOfWork(db, database.NewTxRepositories)
+	history := redis.NewCommandHistory(rdb, cfg.Console.HistorySize, cfg.Console.HistoryTTL)
+	billing := services.NewBillingService(
+		database.NewTransactionRepository(db),
+		database.NewUserRepository(db),
+		database.NewAuditLogRepository(db),
+		uow,
+		cfg,
+	)
+	servers := services.NewServerService(
+		database.NewServerRepository(db),
+		database.NewNodeRepository(db),
+		database.NewAllocationRepository(db),
+		database.NewBackupRepository(db),
+		database.NewAuditLogRepository(db),
+		database.NewActivityLogRepository(db),
+		database.NewUserQuotaRepository(db),
+		database.NewUserRepository(db),
+		database.NewRoleRepository(db),
+		database.NewSubscriptionRepository(db),
+		database.NewEggRepository(db),
+		database.NewEggVariableRepository(db),
+		uow,
+		history,
+		agentClient,
+		cfg,
+	)
 	return &Handler{
 		cfg:       cfg,
 		db:        db,
-		redis:     redis,
-		validator: validator.New(),
+		redis:     rdb,
+		validator: middleware.NewValidator(),
+		agent:     agentClient,
+		warmer:    agent.NewImageWarmer(agentClient, db, rdb, cfg.Agents, log),
+		rollouts:  agent.NewImageRollouts(agentClient, db, cfg.Agents, log),
+		reconcile: agent.NewStatusReconciler(agentClient, db, hooks, cfg.Agents, log),
+		hooks:     hooks,
+		health:    agent.NewNodeHealthChecker(agentClient, db, cfg.Agents, log),
+		backups:   agent.NewBackupScanner(agentClient, db, cfg.Agents, log),
+		history:   history,
+		databases: services.NewDatabaseService(
+			database.NewServerDatabaseRepository(db),
+			database.NewDatabaseHostRepository(db),
+			database.NewServerRepository(db),
+			database.NewAuditLogRepository(db),
+			database.NewMySQLProvisioner(),
+			configuredSecrets(cfg.Security.EncryptionKey),
+		),
+		invoices: services.NewInvoiceService(
+			database.NewInvoiceRepository(db),
+			database.NewUserRepository(db),
+			rdb,
+			cfg,
+		),
+		nodes: services.NewNodeService(
+			database.NewNodeRepository(db),
+			database.NewLocationRepository(db),
+			database.NewAllocationRepository(db),
+			database.NewServerRepository(db),
+			database.NewAuditLogRepository(db),
+			database.NewNotificationRepository(db),
+			agentClient,
+		),
+		servers:  servers,
+		settings: settings,
+		ops:      ops,
+		placer:   services.NewNodePlacer(cfg.Placement.Strategy),
+		resellers: services.NewResellerService(
+			database.NewUserRepository(db),
+			database.NewRoleRepository(db),
+			database.NewUserQuotaRepository(db),
+			database.NewServerRepository(db),
+			database.NewAuditLogRepository(db),
+			uow,
+			billing,
+		),
+
+		worlds: services.NewWorldService(
+			database.NewWorldRepository(db),
+			database.NewWorldBackupRepository(db),
+			database.NewServerRepository(db),
+			database.NewAuditLogRepository(db),
+			agentClient,
+			hooks,
+		),
+
+		provisioning: services.NewProvisioningService(
+			database.NewPackageRepository(db),
+			database.NewNodeRepository(db),
+			database.NewUserRepository(db),
+			database.NewEggRepository(db),
+			database.NewAuditLogRepository(db),
+			uow,
+			servers,
+			cfg,
+		),
+		maintenance: middleware.NewMaintenance(rdb, settings),
+	}
+}
+
+// errVersionConflict is returned when an optimistic update finds a newer version
+var errVersionConflict = errors.New("version conflict")
+
+// saveVersioned writes all fields of model only if its stored version still
+// equals expected, bumping the version on success
+func saveVersioned(db *gorm.DB, model interface{}, version *int, expected int) error {
+	*version = expected + 1
+
+	result := db.Model(model).Where("version = ?", expected).Select("*").Omit("created_at").Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return errVersionConflict
+	}
+	return nil
+}
+
+// versionConflict writes the standard response for a stale update
+func versionConflict(c *fiber.Ctx, current int) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":           "Resource was modified by another request, refetch and try again",
+		"current_version": current,
+	})
 }
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database/dbtest"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a SQLite database with the tables of models
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	return dbtest.Open(t, models...)
+}
+
+// writeConcurrently bumps the version of a row right before the next update
+// runs, like another request saving between a handler's read and its write. It
+// commits on its own connection, so a handler rolling back keeps the write.
+func writeConcurrently(t *testing.T, db *gorm.DB, table string, id uuid.UUID) {
+	t.Helper()
+	var once sync.Once
+	err := db.Callback().Update().Before("gorm:update").Register("test:concurrent_write", func(tx *gorm.DB) {
+		once.Do(func() {
+			if err := db.Exec("UPDATE "+table+" SET version = version + 1 WHERE id = ?", id).Error; err != nil {
+				t.Error(err)
+			}
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// versionTest holds a node running a server and a user, all at known versions.
+// Requests are made as the user, the owner of the server, unless caller is set.
+type versionTest struct {
+	db     *gorm.DB
+	app    *fiber.App
+	node   *entities.Node
+	server *entities.Server
+	user   *entities.User
+	caller uuid.UUID
+	err    error
+}
+
+func newVersionTest(t *testing.T) *versionTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Location{}, &entities.Node{}, &entities.Egg{}, &entities.Server{}, &entities.User{})
+	vt := &versionTest{db: db}
+
+	location := &entities.Location{ID: uuid.New(), ShortCode: "eu", Name: "Europe"}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "Paper"}
+	vt.node = &entities.Node{
+		ID: uuid.New(), Name: "node-1", LocationID: location.ID, FQDN: "node-1.example.com", Scheme: "https",
+		DaemonPort: 8443, MemoryTotal: 16384, DiskTotal: 102400, UploadSize: 100, Version: 3,
+	}
+	vt.user = &entities.User{ID: uuid.New(), Email: "alex@example.com", Username: "alex", Status: entities.UserStatusActive, Version: 4}
+	vt.server = &entities.Server{
+		ID: uuid.New(), Name: "survival", NodeID: vt.node.ID, EggID: egg.ID, OwnerID: vt.user.ID,
+		MemoryLimit: 2048, DiskLimit: 10240, CPULimit: 100, Status: entities.ServerStatusStopped, Version: 2,
+	}
+	for _, row := range []interface{}{location, egg, vt.node, vt.user, vt.server} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, db: db, validator: middleware.NewValidator()}
+	users := &UserHandler{config: cfg, db: db, validator: middleware.NewValidator()}
+
+	vt.caller = vt.user.ID
+	vt.app = fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		vt.err = err
+		code := http.StatusInternalServerError
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			code = fe.Code
+		}
+		return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+	}})
+	vt.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, vt.caller)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	vt.app.Put("/servers/:id", h.UpdateServer)
+	vt.app.Put("/nodes/:id", h.UpdateNode)
+	vt.app.Put("/users/:id", users.Update)
+	return vt
+}
+
+// put sends body to path, returning the status and the decoded response
+func (vt *versionTest) put(t *testing.T, path string, body fiber.Map) (int, fiber.Map) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(data))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := vt.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded fiber.Map
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, decoded
+}
+
+// nodeUpdate is an update of the test node read at version
+func (vt *versionTest) nodeUpdate(name string, version int) fiber.Map {
+	return fiber.Map{
+		"name":               name,
+		"location_id":        vt.node.LocationID,
+		"fqdn":               vt.node.FQDN,
+		"scheme":             vt.node.Scheme,
+		"memory":             vt.node.MemoryTotal,
+		"disk":               vt.node.DiskTotal,
+		"upload_size":        vt.node.UploadSize,
+		"daemon_listen_port": vt.node.DaemonPort,
+		"daemon_sftp_port":   2022,
+		"version":            version,
+	}
+}
+
+// storedVersion returns the version of a row of model
+func (vt *versionTest) storedVersion(t *testing.T, model interface{}, id uuid.UUID) int {
+	t.Helper()
+	var version int
+	if err := vt.db.Model(model).Select("version").Where("id = ?", id).Scan(&version).Error; err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestStaleUpdatesAreRejected(t *testing.T) {
+	vt := newVersionTest(t)
+
+	for name, tc := range map[string]struct {
+		path    string
+		body    fiber.Map
+		current int
+	}{
+		"server": {"/servers/" + vt.server.ID.String(), fiber.Map{"name": "creative", "version": 1}, 2},
+		"node":   {"/nodes/" + vt.node.ID.String(), vt.nodeUpdate("node-renamed", 2), 3},
+		"user":   {"/users/" + vt.user.ID.String(), fiber.Map{"first_name": "Alex", "version": 3}, 4},
+	} {
+		status, resp := vt.put(t, tc.path, tc.body)
+		if status != http.StatusConflict {
+			t.Errorf("stale %s update = %d, want %d", name, status, http.StatusConflict)
+			continue
+		}
+		if current, _ := resp["current_version"].(float64); int(current) != tc.current {
+			t.Errorf("stale %s update reported version %v, want %d", name, resp["current_version"], tc.current)
+		}
+	}
+
+	var server entities.Server
+	var node entities.Node
+	var user entities.User
+	vt.db.First(&server, "id = ?", vt.server.ID)
+	vt.db.First(&node, "id = ?", vt.node.ID)
+	vt.db.First(&user, "id = ?", vt.user.ID)
+	if server.Name != "survival" || node.Name != "node-1" || user.FirstName != "" {
+		t.Errorf("stale updates were written: server %q, node %q, user %q", server.Name, node.Name, user.FirstName)
+	}
+}
+
+func TestFreshUpdatesSucceed(t *testing.T) {
+	vt := newVersionTest(t)
+
+	for name, tc := range map[string]struct {
+		path  string
+		body  fiber.Map
+		model interface{}
+		id    uuid.UUID
+		want  int
+	}{
+		"server": {"/servers/" + vt.server.ID.String(), fiber.Map{"name": "creative", "version": 2}, &entities.Server{}, vt.server.ID, 3},
+		"node":   {"/nodes/" + vt.node.ID.String(), vt.nodeUpdate("node-renamed", 3), &entities.Node{}, vt.node.ID, 4},
+		"user":   {"/users/" + vt.user.ID.String(), fiber.Map{"first_name": "Alex", "version": 4}, &entities.User{}, vt.user.ID, 5},
+	} {
+		if status, resp := vt.put(t, tc.path, tc.body); status != http.StatusOK {
+			t.Errorf("fresh %s update = %d %v, want %d", name, status, resp, http.StatusOK)
+			continue
+		}
+		if version := vt.storedVersion(t, tc.model, tc.id); version != tc.want {
+			t.Errorf("%s version = %d after the update, want %d", name, version, tc.want)
+		}
+	}
+
+	var server entities.Server
+	vt.db.First(&server, "id = ?", vt.server.ID)
+	if server.Name != "creative" {
+		t.Errorf("server name = %q, want the update applied", server.Name)
+	}
+}
+
+// TestUpdatesRacingAWriteAreRejected saves another change between each
+// handler's version check and its write, which only the conditional update
+// can catch
+func TestUpdatesRacingAWriteAreRejected(t *testing.T) {
+	for name, tc := range map[string]struct {
+		table   string
+		request func(vt *versionTest) (string, fiber.Map)
+		id      func(vt *versionTest) uuid.UUID
+		current int
+	}{
+		"server": {
+			"servers",
+			func(vt *versionTest) (string, fiber.Map) {
+				return "/servers/" + vt.server.ID.String(), fiber.Map{"name": "creative", "version": 2}
+			},
+			func(vt *versionTest) uuid.UUID { return vt.server.ID },
+			3,
+		},
+		"node": {
+			"nodes",
+			func(vt *versionTest) (string, fiber.Map) {
+				return "/nodes/" + vt.node.ID.String(), vt.nodeUpdate("node-renamed", 3)
+			},
+			func(vt *versionTest) uuid.UUID { return vt.node.ID },
+			4,
+		},
+		"user": {
+			"users",
+			func(vt *versionTest) (string, fiber.Map) {
+				return "/users/" + vt.user.ID.String(), fiber.Map{"first_name": "Alex", "version": 4}
+			},
+			func(vt *versionTest) uuid.UUID { return vt.user.ID },
+			5,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vt := newVersionTest(t)
+			writeConcurrently(t, vt.db, tc.table, tc.id(vt))
+
+			path, body := tc.request(vt)
+			status, resp := vt.put(t, path, body)
+			if status != http.StatusConflict {
+				t.Fatalf("update racing a write = %d %v, want %d", status, resp, http.StatusConflict)
+			}
+			if current, _ := resp["current_version"].(float64); int(current) != tc.current {
+				t.Errorf("reported version %v, want %d", resp["current_version"], tc.current)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CreateImageRolloutRequest struct {
+	Image          string   `json:"image" validate:"required,max=255"`
+	Mode           string   `json:"mode" validate:"required,oneof=next_restart immediate"`
+	FromImage      string   `json:"from_image" validate:"max=255"`             // Only servers on this image, all others when empty
+	ServerIDs      []string `json:"server_ids" validate:"max=1000,dive,uuid"`  // Only these servers, all of the egg's when empty
+	StaggerSeconds int      `json:"stagger_seconds" validate:"min=0,max=3600"` // Delay between immediate upgrades
+	Concurrency    int      `json:"concurrency" validate:"min=0,max=50"`       // 0 for the panel's bulk concurrency
+}
+
+// CreateImageRollout moves the servers of an egg onto one of its images. The
+// image is pulled on their nodes first; servers are then switched to it on
+// their next restart, or restarted onto it right away with a stagger, and
+// rolled back to their previous image if they do not come up. Progress is
+// available from GetImageRollout.
+func (h *Handler) CreateImageRollout(c *fiber.Ctx) error {
+	var req CreateImageRolloutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", c.Params("id")).First(&egg).Error; err != nil {
+		return services.ErrEggNotFound
+	}
+	if !slices.Contains(egg.DockerImages, req.Image) {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "The egg does not offer this image",
+		})
+	}
+
+	var active int64
+	h.db.Model(&entities.ImageRollout{}).
+		Where("egg_id = ? AND status IN ?", egg.ID, []string{entities.RolloutPending, entities.RolloutPulling, entities.RolloutRunning}).
+		Count(&active)
+	if active > 0 {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "An image rollout is already running for this egg",
+		})
+	}
+
+	query := h.db.Model(&entities.Server{}).Scopes(database.NotTrashed).
+		Where("egg_id = ? AND docker_image <> ?", egg.ID, req.Image)
+	if req.FromImage != "" {
+		query = query.Where("docker_image = ?", req.FromImage)
+	}
+	if len(req.ServerIDs) > 0 {
+		query = query.Where("id IN ?", req.ServerIDs)
+	}
+	var servers []entities.Server
+	if err := query.Select("id", "node_id", "docker_image").Order("name").Find(&servers).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch servers",
+		})
+	}
+	if len(servers) == 0 {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "No servers to move to this image",
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	rollout := entities.ImageRollout{
+		EggID:          egg.ID,
+		Image:          req.Image,
+		Mode:           req.Mode,
+		StaggerSeconds: req.StaggerSeconds,
+		Concurrency:    req.Concurrency,
+		Status:         entities.RolloutPending,
+		CreatedBy:      &userID,
+	}
+	serverIDs := make([]uuid.UUID, 0, len(servers))
+	for _, server := range servers {
+		serverIDs = append(serverIDs, server.ID)
+		rollout.Servers = append(rollout.Servers, entities.ImageRolloutServer{
+			ServerID:      server.ID,
+			NodeID:        server.NodeID,
+			PreviousImage: server.DockerImage,
+			Status:        entities.RolloutServerPending,
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// A server still waiting on an earlier rollout moves to this one instead
+		if err := tx.Model(&entities.ImageRolloutServer{}).
+			Where("server_id IN ? AND status = ?", serverIDs, entities.RolloutServerScheduled).
+			Updates(map[string]interface{}{
+				"status":      entities.RolloutServerFailed,
+				"error":       "Superseded by a newer rollout",
+				"finished_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rollout).Error
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create image rollout",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditActionUpdate,
+		Resource:    "egg",
+		ResourceID:  &egg.ID,
+		Description: "Started image rollout of " + egg.Name + " to " + req.Image,
+		Metadata: map[string]interface{}{
+			"rollout_id": rollout.ID,
+			"image":      req.Image,
+			"mode":       req.Mode,
+			"servers":    len(servers),
+		},
+		IPAddress: c.IP(),
+	})
+
+	h.startRollout(rollout.ID)
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"data": rollout,
+	})
+}
+
+// GetImageRollouts returns a page of an egg's image rollouts, newest first
+func (h *Handler) GetImageRollouts(c *fiber.Ctx) error {
+	params := pageParams(c, 25, 100)
+
+	rollouts := make([]entities.ImageRollout, 0, params.PageSize)
+	query := h.db.Model(&entities.ImageRollout{}).Where("egg_id = ?", c.Params("id"))
+	total, err := database.Paginate(query, params, "created_at DESC", &rollouts)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch image rollouts",
+		})
+	}
+
+	return c.JSON(paginated(rollouts, params, total))
+}
+
+// GetImageRollout returns an image rollout with the progress of each server
+func (h *Handler) GetImageRollout(c *fiber.Ctx) error {
+	var rollout entities.ImageRollout
+	if err := h.db.Preload("Servers").
+		Where("id = ? AND egg_id = ?", c.Params("rollout"), c.Params("id")).
+		First(&rollout).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Image rollout not found",
+		})
+	}
+
+	counts := make(map[string]int)
+	for _, s := range rollout.Servers {
+		counts[s.Status]++
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   rollout,
+		"counts": counts,
+	})
+}
+
+// startRollout carries out an image rollout in the background, outliving the request
+func (h *Handler) startRollout(rolloutID uuid.UUID) {
+	go func() {
+		_ = h.rollouts.Run(context.Background(), rolloutID)
+	}()
+}
+
+// confirmRollout verifies in the background a server started onto an image
+// a rollout switched it to, rolling it back if it does not come up
+func (h *Handler) confirmRollout(serverID uuid.UUID) {
+	go h.rollouts.Confirm(context.Background(), serverID)
+}
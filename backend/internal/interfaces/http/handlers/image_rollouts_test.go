@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeNodeAgent is a node agent whose servers run whatever image they are
+// given. Servers listed as failing on an image report exited while on it.
+type fakeNodeAgent struct {
+	mu       sync.Mutex
+	failing  map[string]string // Server ID to the image it fails on
+	images   map[string]string
+	restarts map[string][]time.Time
+	starts   map[string]int
+}
+
+func newFakeNodeAgent() *fakeNodeAgent {
+	return &fakeNodeAgent{
+		failing:  make(map[string]string),
+		images:   make(map[string]string),
+		restarts: make(map[string][]time.Time),
+		starts:   make(map[string]int),
+	}
+}
+
+func (f *fakeNodeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/servers/"), "/")
+	switch {
+	case r.URL.Path == "/api/images/pull":
+	case len(parts) == 2 && parts[1] == "image":
+		var body struct {
+			Image string `json:"image"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.images[parts[0]] = body.Image
+	case len(parts) == 3 && parts[2] == "restart":
+		f.restarts[parts[0]] = append(f.restarts[parts[0]], time.Now())
+	case len(parts) == 3 && parts[2] == "start":
+		f.starts[parts[0]]++
+	case len(parts) == 2 && parts[1] == "stats":
+		status := "running"
+		if image, ok := f.failing[parts[0]]; ok && f.images[parts[0]] == image {
+			status = "exited"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+	_, _ = w.Write([]byte("{}"))
+}
+
+// rolloutTest is a node served by a fakeNodeAgent, with its servers on game:1
+type rolloutTest struct {
+	db       *gorm.DB
+	cfg      *config.Config
+	agent    *fakeNodeAgent
+	node     *entities.Node
+	servers  []*entities.Server
+	rollouts *agent.ImageRollouts
+	client   *agent.Client
+}
+
+func newRolloutTest(t *testing.T, servers int, status entities.ServerStatus) *rolloutTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.ImageRollout{}, &entities.ImageRolloutServer{}, &entities.ActivityLog{})
+	rt := &rolloutTest{db: db, agent: newFakeNodeAgent()}
+
+	daemon := httptest.NewServer(rt.agent)
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	rt.node = &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort, MemoryTotal: 65536, DiskTotal: 1048576}
+	if err := db.Create(rt.node).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < servers; i++ {
+		server := &entities.Server{
+			ID: uuid.New(), UUID: "server-" + strconv.Itoa(i), Name: "server-" + strconv.Itoa(i), NodeID: rt.node.ID, EggID: uuid.New(), OwnerID: uuid.New(),
+			MemoryLimit: 1024, DiskLimit: 10240, CPULimit: 100, DockerImage: "game:1", Status: status, Version: 1,
+		}
+		if err := db.Create(server).Error; err != nil {
+			t.Fatal(err)
+		}
+		rt.servers = append(rt.servers, server)
+	}
+
+	rt.cfg = &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second, RolloutSettle: 10 * time.Millisecond, WarmupConcurrency: 1}}
+	rt.client = agent.NewClient(rt.cfg.Agents, db)
+	rt.rollouts = agent.NewImageRollouts(rt.client, db, rt.cfg.Agents, zap.NewNop())
+	return rt
+}
+
+// rollout stores a rollout of every test server to game:2
+func (rt *rolloutTest) rollout(t *testing.T, mode string, stagger int) *entities.ImageRollout {
+	t.Helper()
+	rollout := &entities.ImageRollout{ID: uuid.New(), EggID: uuid.New(), Image: "game:2", Mode: mode, StaggerSeconds: stagger, Concurrency: len(rt.servers), Status: entities.RolloutPending}
+	for _, server := range rt.servers {
+		rollout.Servers = append(rollout.Servers, entities.ImageRolloutServer{
+			ID: uuid.New(), ServerID: server.ID, NodeID: server.NodeID, PreviousImage: server.DockerImage, Status: entities.RolloutServerPending,
+		})
+	}
+	if err := rt.db.Create(rollout).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rollout
+}
+
+// entryStatus returns the rollout status of a server
+func (rt *rolloutTest) entryStatus(t *testing.T, serverID uuid.UUID) string {
+	t.Helper()
+	var entry entities.ImageRolloutServer
+	if err := rt.db.Where("server_id = ?", serverID).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	return entry.Status
+}
+
+// image returns the image of a server stored in the panel
+func (rt *rolloutTest) image(t *testing.T, serverID uuid.UUID) string {
+	t.Helper()
+	var server entities.Server
+	if err := rt.db.First(&server, "id = ?", serverID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return server.DockerImage
+}
+
+func TestStaggeredRolloutRollsBackFailingServer(t *testing.T) {
+	rt := newRolloutTest(t, 3, entities.ServerStatusRunning)
+	failing := rt.servers[1]
+	rt.agent.failing[failing.ID.String()] = "game:2"
+	rollout := rt.rollout(t, entities.RolloutImmediate, 1)
+
+	if err := rt.rollouts.Run(context.Background(), rollout.ID); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, server := range rt.servers {
+		want, image := entities.RolloutServerUpgraded, "game:2"
+		if server == failing {
+			want, image = entities.RolloutServerRolledBack, "game:1"
+		}
+		if got := rt.entryStatus(t, server.ID); got != want {
+			t.Errorf("%s rollout status = %s, want %s", server.Name, got, want)
+		}
+		if got := rt.image(t, server.ID); got != image {
+			t.Errorf("%s image = %s, want %s", server.Name, got, image)
+		}
+		if got := rt.agent.images[server.ID.String()]; got != image {
+			t.Errorf("%s image on the node = %s, want %s", server.Name, got, image)
+		}
+	}
+	if got := len(rt.agent.restarts[failing.ID.String()]); got != 2 {
+		t.Errorf("failing server restarted %d times, want onto the image and back", got)
+	}
+
+	// Upgrades start one stagger apart
+	for i := 1; i < len(rt.servers); i++ {
+		previous := rt.agent.restarts[rt.servers[i-1].ID.String()][0]
+		current := rt.agent.restarts[rt.servers[i].ID.String()][0]
+		if gap := current.Sub(previous); gap < 900*time.Millisecond {
+			t.Errorf("%s restarted %s after %s, want a 1s stagger", rt.servers[i].Name, gap, rt.servers[i-1].Name)
+		}
+	}
+
+	var stored entities.ImageRollout
+	rt.db.First(&stored, "id = ?", rollout.ID)
+	if stored.Status != entities.RolloutFinished {
+		t.Errorf("rollout status = %s, want %s", stored.Status, entities.RolloutFinished)
+	}
+}
+
+func TestStartServerConfirmsScheduledRollout(t *testing.T) {
+	rt := newRolloutTest(t, 2, entities.ServerStatusStopped)
+	failing, healthy := rt.servers[0], rt.servers[1]
+	rt.agent.failing[failing.ID.String()] = "game:2"
+	rollout := rt.rollout(t, entities.RolloutNextRestart, 0)
+
+	if err := rt.rollouts.Run(context.Background(), rollout.ID); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, server := range rt.servers {
+		if got := rt.entryStatus(t, server.ID); got != entities.RolloutServerScheduled {
+			t.Fatalf("%s rollout status = %s before its start, want %s", server.Name, got, entities.RolloutServerScheduled)
+		}
+	}
+
+	h := &Handler{cfg: rt.cfg, db: rt.db, agent: rt.client, rollouts: rt.rollouts}
+	app := fiber.New()
+	var caller uuid.UUID
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, caller)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Post("/servers/:id/start", h.StartServer)
+	app.Post("/servers/:id/restart", h.RestartServer)
+
+	for path, server := range map[string]*entities.Server{"start": failing, "restart": healthy} {
+		caller = server.OwnerID
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/servers/"+server.ID.String()+"/"+path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s = %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	// The rollout is confirmed in the background
+	want := map[uuid.UUID]string{failing.ID: entities.RolloutServerRolledBack, healthy.ID: entities.RolloutServerUpgraded}
+	deadline := time.Now().Add(5 * time.Second)
+	for id, status := range want {
+		for rt.entryStatus(t, id) != status && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := rt.entryStatus(t, id); got != status {
+			t.Errorf("rollout status of %s = %s, want %s", id, got, status)
+		}
+	}
+	if got := rt.image(t, failing.ID); got != "game:1" {
+		t.Errorf("failing server image = %s, want it rolled back to game:1", got)
+	}
+	if got := rt.image(t, healthy.ID); got != "game:2" {
+		t.Errorf("healthy server image = %s, want game:2", got)
+	}
+	if rt.agent.starts[failing.ID.String()] != 1 {
+		t.Errorf("failing server started %d times, want 1", rt.agent.starts[failing.ID.String()])
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Impersonate issues a short-lived token that lets an admin act as another
+// user. Requests made with it carry the user's permissions and are audited
+// under both identities.
+func (h *UserHandler) Impersonate(c *fiber.Ctx) error {
+	adminID, _ := middleware.GetUserID(c)
+	if _, ok := middleware.GetImpersonatorID(c); ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not allowed while impersonating a user",
+		})
+	}
+
+	var user entities.User
+	if err := h.db.Preload("Role").Where("id = ?", c.Params("id")).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if user.ID == adminID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot impersonate yourself",
+		})
+	}
+	if user.Role != nil && user.Role.Name == "admin" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Cannot impersonate an admin",
+		})
+	}
+	if !user.IsActive() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "User is not active",
+		})
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(h.config.JWT.ImpersonationExpiry)
+	tokenID := uuid.New().String()
+
+	claims := &middleware.Claims{
+		UserID:         user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		RoleID:         user.RoleID,
+		ImpersonatorID: &adminID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    h.config.JWT.Issuer,
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{h.config.JWT.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	}
+	if user.Role != nil {
+		claims.RoleName = user.Role.Name
+	}
+
+	token, err := h.keys.Sign(claims)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue token",
+		})
+	}
+
+	// The session is what keeps the token valid, so it can be revoked early
+	ctx := c.UserContext()
+	if err := h.redis.Set(ctx, middleware.ImpersonationKey(tokenID), adminID.String(), h.config.JWT.ImpersonationExpiry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start impersonation",
+		})
+	}
+	_ = h.redis.SAdd(ctx, middleware.ImpersonationUserKey(user.ID), tokenID)
+	_ = h.redis.Expire(ctx, middleware.ImpersonationUserKey(user.ID), h.config.JWT.ImpersonationExpiry)
+
+	h.db.Create(&entities.AuditLog{
+		UserID:      &adminID,
+		Action:      entities.AuditActionImpersonate,
+		Resource:    "user",
+		ResourceID:  &user.ID,
+		Description: fmt.Sprintf("Started impersonating %s", user.Username),
+		Metadata:    map[string]interface{}{"token_id": tokenID, "expires_at": expiresAt},
+		IPAddress:   c.IP(),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_at":   expiresAt,
+		"user_id":      user.ID,
+	})
+}
+
+// RevokeImpersonation ends every impersonation session of a user
+func (h *UserHandler) RevokeImpersonation(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := middleware.RevokeImpersonations(c.UserContext(), h.redis, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke impersonation",
+		})
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:      &adminID,
+		Action:      entities.AuditActionImpersonate,
+		Resource:    "user",
+		ResourceID:  &userID,
+		Description: "Revoked impersonation sessions",
+		IPAddress:   c.IP(),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
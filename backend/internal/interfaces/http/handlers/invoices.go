@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DownloadInvoice returns an invoice as a PDF to its owner or a billing admin
+func (h *Handler) DownloadInvoice(c *fiber.Ctx) error {
+	invoiceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return services.ErrInvoiceNotFound
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	invoice, pdf, err := h.invoices.Download(c.UserContext(), invoiceID, userID, middleware.HasPermission(c, "billing.manage"))
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, services.InvoiceFilename(invoice)))
+	return c.Send(pdf)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// pdfText returns the page content of a PDF with its streams inflated
+func pdfText(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var text strings.Builder
+	for rest := pdf; ; {
+		start := bytes.Index(rest, []byte("stream\n"))
+		if start < 0 {
+			return text.String()
+		}
+		rest = rest[start+len("stream\n"):]
+		end := bytes.Index(rest, []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated PDF stream")
+		}
+		if r, err := zlib.NewReader(bytes.NewReader(rest[:end])); err == nil {
+			data, _ := io.ReadAll(r)
+			text.Write(data)
+		} else {
+			text.Write(rest[:end])
+		}
+		rest = rest[end+len("\nendstream"):]
+	}
+}
+
+func TestDownloadInvoice(t *testing.T) {
+	db := newTestDB(t, &entities.User{}, &entities.Invoice{}, &entities.InvoiceItem{})
+	owner := &entities.User{ID: uuid.New(), Email: "alex@example.com", Username: "alex", FirstName: "Alex", LastName: "Doe"}
+	invoice := &entities.Invoice{
+		ID: uuid.New(), InvoiceNumber: "INV-2026-0042", UserID: owner.ID,
+		Subtotal: 100, Discount: 10, Tax: 17.1, Total: 107.1, Currency: "EUR", Status: "paid",
+		IssueDate: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		Items: []entities.InvoiceItem{
+			{Description: "Game server, 4 GB", Quantity: 2, UnitPrice: 40, Total: 80},
+			{Description: "Extra backups", Quantity: 1, UnitPrice: 20, Total: 20},
+		},
+	}
+	for _, row := range []interface{}{owner, invoice} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{Billing: config.BillingConfig{Company: config.CompanyConfig{Name: "Aether Hosting", TaxID: "DE123456789"}, InvoiceCacheTTL: time.Hour}}
+	h := &Handler{invoices: services.NewInvoiceService(database.NewInvoiceRepository(db), database.NewUserRepository(db), rdb, cfg)}
+
+	// download fetches the invoice as caller holding permissions
+	var handlerErr error
+	download := func(caller uuid.UUID, permissions ...string) (int, []byte) {
+		handlerErr = nil
+		app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+			handlerErr = err
+			return c.SendStatus(http.StatusForbidden)
+		}})
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.UserIDKey, caller)
+			c.Locals(middleware.RoleNameKey, "user")
+			c.Locals(middleware.PermissionsKey, permissions)
+			return c.Next()
+		})
+		app.Get("/invoices/:id/pdf", h.DownloadInvoice)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/invoices/"+invoice.ID.String()+"/pdf", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, pdf := download(owner.ID)
+	if status != http.StatusOK || !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatalf("owner download = %d, %v", status, handlerErr)
+	}
+	text := pdfText(t, pdf)
+	for _, want := range []string{
+		"(Number: INV-2026-0042)", "(Aether Hosting)", "(Tax ID: DE123456789)", "(Alex Doe)",
+		"(Game server, 4 GB)", "(40.00 EUR)", "(80.00 EUR)",
+		"(Subtotal)", "(100.00 EUR)", "(Discount)", "(-10.00 EUR)", "(Tax)", "(17.10 EUR)", "(Total)", "(107.10 EUR)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("invoice PDF lacks %s", want)
+		}
+	}
+	if !mr.Exists(services.InvoicePDFKey(invoice)) {
+		t.Error("the rendered invoice was not cached")
+	}
+
+	if status, _ := download(owner.ID); status != http.StatusOK {
+		t.Errorf("cached download = %d, %v", status, handlerErr)
+	}
+	if status, _ := download(uuid.New(), "billing.manage"); status != http.StatusOK {
+		t.Errorf("billing admin download = %d, %v", status, handlerErr)
+	}
+	for name, permissions := range map[string][]string{"another user": nil, "a billing viewer": {"billing.view"}} {
+		if status, body := download(uuid.New(), permissions...); !errors.Is(handlerErr, services.ErrInvoiceAccessDenied) || bytes.HasPrefix(body, []byte("%PDF-")) {
+			t.Errorf("download by %s = %d, %v, want %v", name, status, handlerErr, services.ErrInvoiceAccessDenied)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// leaderboardTTL is how long a leaderboard is served from cache
+const leaderboardTTL = 30 * time.Second
+
+type LeaderboardQuery struct {
+	Stat  string `query:"stat" validate:"required,oneof=kills deaths playtime blocks_mined"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// LeaderboardEntry is a player's position on a leaderboard
+type LeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	PlayerID    uuid.UUID `json:"player_id"`
+	UUID        string    `json:"uuid"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	SkinURL     string    `json:"skin_url"`
+	IsOnline    bool      `json:"is_online"`
+	Value       int64     `json:"value"`
+}
+
+// GetLeaderboard returns the top players of a server for a stat type
+func (h *Handler) GetLeaderboard(c *fiber.Ctx) error {
+	query := LeaderboardQuery{Limit: 10}
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+	if fields := h.validator.Validate(query); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	cacheKey := redis.BuildKey(redis.PrefixServer, server.ID.String(), "leaderboard", query.Stat, strconv.Itoa(query.Limit))
+
+	var entries []LeaderboardEntry
+	if err := h.redis.GetJSON(ctx, cacheKey, &entries); err == nil {
+		return c.JSON(fiber.Map{
+			"data": entries,
+		})
+	}
+
+	// Play time is kept on the player itself, every other stat in player_stats
+	db := h.db.WithContext(ctx).Table("players")
+	if query.Stat == entities.StatTypePlayTime {
+		db = db.Select("players.id AS player_id, players.uuid, players.username, players.display_name, players.skin_url, players.is_online, players.play_time AS value").
+			Where("players.server_id = ? AND players.play_time > 0", server.ID).
+			Order("players.play_time DESC")
+	} else {
+		db = db.Select("players.id AS player_id, players.uuid, players.username, players.display_name, players.skin_url, players.is_online, player_stats.stat_value AS value").
+			Joins("JOIN player_stats ON player_stats.player_id = players.id").
+			Where("players.server_id = ? AND player_stats.stat_type = ?", server.ID, query.Stat).
+			Order("player_stats.stat_value DESC")
+	}
+
+	entries = make([]LeaderboardEntry, 0, query.Limit)
+	if err := db.Order("players.username ASC").Limit(query.Limit).Scan(&entries).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch leaderboard",
+		})
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	_ = h.redis.SetJSON(ctx, cacheKey, entries, leaderboardTTL)
+
+	return c.JSON(fiber.Map{
+		"data": entries,
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// newLeaderboardTest returns an app serving the leaderboard of a server whose
+// players bob, alice and carol have 12, 5 and 5 kills and 300, 100 and 0
+// seconds of play time. A player of another server tops both.
+func newLeaderboardTest(t *testing.T) (*fiber.App, *entities.Server) {
+	t.Helper()
+	db := newTestDB(t, &entities.Server{}, &entities.Player{}, &entities.PlayerStats{})
+	server := &entities.Server{ID: uuid.New(), Name: "survival", NodeID: uuid.New(), OwnerID: uuid.New()}
+	if err := db.Create(server).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		server   uuid.UUID
+		username string
+		kills    int64
+		playTime int64
+	}{
+		{server.ID, "alice", 5, 100},
+		{server.ID, "bob", 12, 300},
+		{server.ID, "carol", 5, 0},
+		{uuid.New(), "dave", 50, 9000},
+	} {
+		player := &entities.Player{ID: uuid.New(), ServerID: p.server, Username: p.username, PlayTime: p.playTime}
+		if err := db.Create(player).Error; err != nil {
+			t.Fatal(err)
+		}
+		for stat, value := range map[string]int64{entities.StatTypeKills: p.kills, entities.StatTypeDeaths: 1} {
+			if err := db.Create(&entities.PlayerStats{PlayerID: player.ID, StatType: stat, StatValue: value}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	h := &Handler{cfg: &config.Config{}, db: db, redis: rdb, validator: middleware.NewValidator()}
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		t.Errorf("unexpected error %v", err)
+		return c.SendStatus(http.StatusInternalServerError)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, server.OwnerID)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Get("/servers/:id/leaderboard", h.GetLeaderboard)
+	return app, server
+}
+
+// leaderboard requests the leaderboard with query, returning the status and
+// the entries
+func leaderboard(t *testing.T, app *fiber.App, server *entities.Server, query string) (int, []LeaderboardEntry) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+server.ID.String()+"/leaderboard?"+query, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data []LeaderboardEntry `json:"data"`
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, body.Data
+}
+
+func TestLeaderboardOrdering(t *testing.T) {
+	app, server := newLeaderboardTest(t)
+
+	for query, want := range map[string][]string{
+		"stat=kills":         {"bob:12", "alice:5", "carol:5"}, // Ties by name
+		"stat=playtime":      {"bob:300", "alice:100"},         // No play time, no rank
+		"stat=kills&limit=1": {"bob:12"},
+		"stat=blocks_mined":  {},
+	} {
+		status, entries := leaderboard(t, app, server, query)
+		if status != http.StatusOK {
+			t.Errorf("%s: status = %d", query, status)
+			continue
+		}
+		got := make([]string, 0, len(entries))
+		for i, e := range entries {
+			got = append(got, e.Username+":"+strconv.FormatInt(e.Value, 10))
+			if e.Rank != i+1 {
+				t.Errorf("%s: %s ranked %d, want %d", query, e.Username, e.Rank, i+1)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: leaderboard %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestLeaderboardValidatesStatType(t *testing.T) {
+	app, server := newLeaderboardTest(t)
+
+	for _, query := range []string{"", "stat=score", "stat=KILLS", "stat=kills&limit=500", "stat=kills&limit=-1"} {
+		if status, _ := leaderboard(t, app, server, query); status != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
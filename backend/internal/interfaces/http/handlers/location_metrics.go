@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// locationMetricsTTL is how long location metrics are served from cache
+const locationMetricsTTL = 15 * time.Second
+
+// ResourceRollup is a resource summed over the nodes of a location
+type ResourceRollup struct {
+	Total     int64 `json:"total"`     // Configured capacity of all nodes
+	Allocated int64 `json:"allocated"` // Limits of the servers placed on them
+	Used      int64 `json:"used"`      // Live use of the nodes that reported it
+}
+
+// LocationMetrics is the capacity and usage of a location's nodes
+type LocationMetrics struct {
+	LocationID     uuid.UUID      `json:"location_id"`
+	ShortCode      string         `json:"short_code"`
+	Name           string         `json:"name"`
+	Nodes          int            `json:"nodes"`
+	NodesOnline    int            `json:"nodes_online"`
+	NodesReporting int            `json:"nodes_reporting"` // Online nodes whose agent answered
+	Servers        int64          `json:"servers"`
+	Memory         ResourceRollup `json:"memory"` // MB
+	Disk           ResourceRollup `json:"disk"`   // MB
+	CPU            ResourceRollup `json:"cpu"`    // Percentage (100 = 1 core)
+}
+
+// nodeUsage is the sum of the limits of a node's servers
+type nodeUsage struct {
+	NodeID  uuid.UUID
+	Servers int64
+	Memory  int64
+	Disk    int64
+	CPU     int64
+}
+
+// GetLocationMetrics returns the capacity and usage of every location
+func (h *Handler) GetLocationMetrics(c *fiber.Ctx) error {
+	metrics, err := h.locationMetrics(c.UserContext())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch location metrics",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": metrics,
+	})
+}
+
+// GetLocationMetricsByID returns the capacity and usage of one location
+func (h *Handler) GetLocationMetricsByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Location not found",
+		})
+	}
+
+	metrics, err := h.locationMetrics(c.UserContext())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch location metrics",
+		})
+	}
+
+	for _, m := range metrics {
+		if m.LocationID == id {
+			return c.JSON(fiber.Map{
+				"data": m,
+			})
+		}
+	}
+	return c.Status(http.StatusNotFound).JSON(fiber.Map{
+		"error": "Location not found",
+	})
+}
+
+// locationMetrics returns the metrics of every location, from cache when they
+// were computed recently
+func (h *Handler) locationMetrics(ctx context.Context) ([]LocationMetrics, error) {
+	cacheKey := redis.BuildKey(redis.PrefixMetrics, "locations")
+
+	var metrics []LocationMetrics
+	if err := h.redis.GetJSON(ctx, cacheKey, &metrics); err == nil {
+		return metrics, nil
+	}
+
+	var locations []entities.Location
+	if err := h.db.WithContext(ctx).Order("short_code").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	var nodes []entities.Node
+	if err := h.db.WithContext(ctx).Scopes(database.NotTrashed).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+
+	var usage []nodeUsage
+	if err := h.db.WithContext(ctx).Model(&entities.Server{}).Scopes(database.NotTrashed).
+		Select("node_id, COUNT(*) AS servers, COALESCE(SUM(memory_limit), 0) AS memory, COALESCE(SUM(disk_limit), 0) AS disk, COALESCE(SUM(cpu_limit), 0) AS cpu").
+		Group("node_id").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	usageByNode := make(map[uuid.UUID]nodeUsage, len(usage))
+	for _, u := range usage {
+		usageByNode[u.NodeID] = u
+	}
+
+	metrics = aggregateLocationMetrics(locations, nodes, usageByNode, h.liveSystemInfo(ctx, nodes))
+
+	_ = h.redis.SetJSON(ctx, cacheKey, metrics, locationMetricsTTL)
+
+	return metrics, nil
+}
+
+// liveSystemInfo asks the agents of the online nodes for their usage. Nodes
+// whose agent does not answer are left out.
+func (h *Handler) liveSystemInfo(ctx context.Context, nodes []entities.Node) map[uuid.UUID]*agent.SystemInfo {
+	live := make(map[uuid.UUID]*agent.SystemInfo, len(nodes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, node := range nodes {
+		if !node.IsOnline {
+			continue
+		}
+		wg.Add(1)
+		go func(nodeID uuid.UUID) {
+			defer wg.Done()
+
+			info, err := h.agent.GetSystemInfo(ctx, nodeID)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			live[nodeID] = info
+			mu.Unlock()
+		}(node.ID)
+	}
+	wg.Wait()
+
+	return live
+}
+
+// aggregateLocationMetrics sums node capacity, the limits of their servers and
+// the live usage of the nodes in live per location. Offline nodes count
+// towards capacity and allocation but have no live usage.
+func aggregateLocationMetrics(locations []entities.Location, nodes []entities.Node, usage map[uuid.UUID]nodeUsage, live map[uuid.UUID]*agent.SystemInfo) []LocationMetrics {
+	metrics := make([]LocationMetrics, len(locations))
+	index := make(map[uuid.UUID]int, len(locations))
+	for i, location := range locations {
+		metrics[i] = LocationMetrics{
+			LocationID: location.ID,
+			ShortCode:  location.ShortCode,
+			Name:       location.Name,
+		}
+		index[location.ID] = i
+	}
+
+	for _, node := range nodes {
+		i, ok := index[node.LocationID]
+		if !ok {
+			continue
+		}
+		m := &metrics[i]
+
+		m.Nodes++
+		m.Memory.Total += node.MemoryTotal
+		m.Disk.Total += node.DiskTotal
+		m.CPU.Total += int64(node.CPUTotal)
+
+		u := usage[node.ID]
+		m.Servers += u.Servers
+		m.Memory.Allocated += u.Memory
+		m.Disk.Allocated += u.Disk
+		m.CPU.Allocated += u.CPU
+
+		if !node.IsOnline {
+			continue
+		}
+		m.NodesOnline++
+
+		info, ok := live[node.ID]
+		if !ok {
+			continue
+		}
+		m.NodesReporting++
+		m.Memory.Used += info.MemoryUsedMB
+		m.Disk.Used += info.DiskUsedMB
+		m.CPU.Used += int64(info.Load * 100)
+	}
+
+	return metrics
+}
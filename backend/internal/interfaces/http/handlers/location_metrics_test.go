@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestLocationMetricsWithOfflineNode(t *testing.T) {
+	db := newTestDB(t, &entities.Location{}, &entities.Node{}, &entities.Server{})
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	var queried atomic.Int32
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queried.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(agent.SystemInfo{CPUCores: 8, Load: 1.5, MemoryMB: 16384, MemoryUsedMB: 6000, DiskMB: 200000, DiskUsedMB: 50000})
+	}))
+	t.Cleanup(daemon.Close)
+	host, daemonPort, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(daemonPort)
+
+	eu := &entities.Location{ID: uuid.New(), ShortCode: "eu", Name: "Europe"}
+	us := &entities.Location{ID: uuid.New(), ShortCode: "us", Name: "United States"}
+	node := func(name string, online bool, memory, disk int64, cpu int) *entities.Node {
+		return &entities.Node{ID: uuid.New(), Name: name, LocationID: eu.ID, FQDN: host, Scheme: "http", DaemonPort: portNumber,
+			IsOnline: online, MemoryTotal: memory, DiskTotal: disk, CPUTotal: cpu}
+	}
+	online := node("online", true, 16384, 200000, 800)
+	// Its agent would answer, but offline nodes are not asked
+	offline := node("offline", false, 8192, 100000, 400)
+	server := func(name string, node *entities.Node, memory, disk int64, cpu int) *entities.Server {
+		return &entities.Server{ID: uuid.New(), UUID: name, Name: name, NodeID: node.ID, OwnerID: uuid.New(),
+			MemoryLimit: memory, DiskLimit: disk, CPULimit: cpu}
+	}
+	rows := []interface{}{eu, us, online, offline,
+		server("a", online, 4096, 20000, 200), server("b", online, 2048, 10000, 100), server("c", offline, 1024, 5000, 50)}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, redis: rdb, agent: agent.NewClient(cfg.Agents, db)}
+	app := fiber.New()
+	app.Get("/locations/metrics", h.GetLocationMetrics)
+	get := func() []LocationMetrics {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/locations/metrics", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Data []LocationMetrics `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Data
+	}
+
+	metrics := get()
+	want := []LocationMetrics{
+		{
+			LocationID: eu.ID, ShortCode: "eu", Name: "Europe",
+			Nodes: 2, NodesOnline: 1, NodesReporting: 1, Servers: 3,
+			// Capacity and allocation count every node, live use only the online one
+			Memory: ResourceRollup{Total: 24576, Allocated: 7168, Used: 6000},
+			Disk:   ResourceRollup{Total: 300000, Allocated: 35000, Used: 50000},
+			CPU:    ResourceRollup{Total: 1200, Allocated: 350, Used: 150},
+		},
+		{LocationID: us.ID, ShortCode: "us", Name: "United States"},
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("metrics for %d locations, want %d", len(metrics), len(want))
+	}
+	for i := range want {
+		if metrics[i] != want[i] {
+			t.Errorf("%s = %+v, want %+v", want[i].ShortCode, metrics[i], want[i])
+		}
+	}
+	if n := queried.Load(); n != 1 {
+		t.Errorf("asked %d agents, want only the online node's", n)
+	}
+
+	// Served from cache until it expires
+	get()
+	if n := queried.Load(); n != 1 {
+		t.Errorf("asked agents %d times, want the cached metrics reused", n)
+	}
+}
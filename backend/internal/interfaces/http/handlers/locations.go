@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -42,11 +43,8 @@ func (h *Handler) CreateLocation(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
 	}
 
 	// Check if short code already exists
@@ -101,11 +99,8 @@ func (h *Handler) UpdateLocation(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
 	}
 
 	var location entities.Location
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UpdateMaintenanceRequest struct {
+	Enabled      bool     `json:"enabled"`
+	Message      string   `json:"message" validate:"max=500"`
+	RetryAfter   int      `json:"retry_after" validate:"min=0,max=86400"`
+	AllowedUsers []string `json:"allowed_users" validate:"omitempty,max=100,dive,uuid"`
+}
+
+// GetMaintenance returns the panel maintenance state
+func (h *Handler) GetMaintenance(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"data": h.maintenance.State(c.UserContext()),
+	})
+}
+
+// UpdateMaintenance enables or disables panel maintenance mode
+func (h *Handler) UpdateMaintenance(c *fiber.Ctx) error {
+	var req UpdateMaintenanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.UserContext()
+
+	if !req.Enabled {
+		if err := h.maintenance.Disable(ctx); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to disable maintenance mode",
+			})
+		}
+	} else {
+		now := time.Now()
+		state := &middleware.MaintenanceState{
+			Message:    req.Message,
+			RetryAfter: req.RetryAfter,
+			StartedAt:  &now,
+			StartedBy:  &userID,
+		}
+		for _, id := range req.AllowedUsers {
+			state.AllowedUsers = append(state.AllowedUsers, uuid.MustParse(id))
+		}
+		if err := h.maintenance.Enable(ctx, state); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to enable maintenance mode",
+			})
+		}
+	}
+
+	description := "Disabled maintenance mode"
+	if req.Enabled {
+		description = "Enabled maintenance mode"
+	}
+	h.db.Create(&entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditActionUpdate,
+		Resource:    "maintenance",
+		Description: description,
+		IPAddress:   c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": h.maintenance.State(ctx),
+	})
+}
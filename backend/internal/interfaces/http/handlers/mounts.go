@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AttachMountRequest struct {
+	Source   string `json:"source" validate:"required,max=255"`
+	Target   string `json:"target" validate:"required,max=255"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// AttachServerMount mounts a host path from the node's allowed mounts into a
+// server. Mounts are fixed when the container is created, so the server picks
+// it up on its next start.
+func (h *Handler) AttachServerMount(c *fiber.Ctx) error {
+	var req AttachMountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var server entities.Server
+	if err := h.db.Preload("Node").Where("id = ?", c.Params("id")).First(&server).Error; err != nil {
+		return services.ErrServerNotFound
+	}
+	if server.Node == nil {
+		return services.ErrNodeNotFound
+	}
+
+	mount, err := services.CheckMount(server.Node.AllowedMounts, entities.Mount{
+		Source:   req.Source,
+		Target:   req.Target,
+		ReadOnly: req.ReadOnly,
+	})
+	if err != nil {
+		return err
+	}
+	for _, existing := range server.Mounts {
+		if existing.Target == mount.Target {
+			return services.ErrMountTargetInUse
+		}
+	}
+
+	mounts := append(append([]entities.Mount{}, server.Mounts...), mount)
+	return h.saveMounts(c, &server, mounts, fiber.Map{"attached": mount})
+}
+
+// DetachServerMount removes the mount with the target given as a query
+// parameter from a server, on its next start
+func (h *Handler) DetachServerMount(c *fiber.Ctx) error {
+	var server entities.Server
+	if err := h.db.Where("id = ?", c.Params("id")).First(&server).Error; err != nil {
+		return services.ErrServerNotFound
+	}
+
+	target := path.Clean(c.Query("target"))
+	mounts := make([]entities.Mount, 0, len(server.Mounts))
+	for _, mount := range server.Mounts {
+		if mount.Target != target {
+			mounts = append(mounts, mount)
+		}
+	}
+	if len(mounts) == len(server.Mounts) {
+		return services.ErrMountNotFound
+	}
+
+	return h.saveMounts(c, &server, mounts, fiber.Map{"detached": target})
+}
+
+// saveMounts pushes a server's new mounts to its node and stores them once the
+// node has accepted them, as the node may allow less than the panel thinks
+func (h *Handler) saveMounts(c *fiber.Ctx, server *entities.Server, mounts []entities.Mount, metadata map[string]interface{}) error {
+	err := h.agent.UpdateServerMounts(c.UserContext(), server.NodeID, server.ID, mounts)
+	var agentErr *agent.Error
+	if errors.As(err, &agentErr) && agentErr.StatusCode == http.StatusUnprocessableEntity {
+		return services.ErrMountNotAllowed
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to update mounts on node",
+		})
+	}
+
+	if err := h.db.Model(server).Update("mounts", mounts).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Mounts updated on the node but could not be saved",
+		})
+	}
+	server.Mounts = mounts
+
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   metadata,
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": mounts,
+	})
+}
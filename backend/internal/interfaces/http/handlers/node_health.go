@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CheckNodeHealth polls a node right away, such as after renewing its
+// certificate, and returns the node with its updated online and certificate
+// status
+func (h *Handler) CheckNodeHealth(c *fiber.Ctx) error {
+	var node entities.Node
+	if err := h.db.Where("id = ? AND deleted_at IS NULL", c.Params("id")).First(&node).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Node not found",
+		})
+	}
+
+	if err := h.health.CheckNode(c.UserContext(), &node); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check node health",
+		})
+	}
+
+	h.db.Preload("Location").First(&node, "id = ?", node.ID)
+	return c.JSON(fiber.Map{
+		"data": node,
+	})
+}
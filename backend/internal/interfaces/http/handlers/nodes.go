@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/aetherpanel/aether-panel/internal/application/services"
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CreateNodeRequest struct {
@@ -21,9 +31,13 @@ type CreateNodeRequest struct {
 	MemoryOverallocate   int    `json:"memory_overallocate" validate:"min=0,max=500"`
 	Disk                 int    `json:"disk" validate:"required,min=1024"`
 	DiskOverallocate     int    `json:"disk_overallocate" validate:"min=0,max=500"`
+	SwapMax              int64  `json:"swap_max" validate:"min=-1"` // MB per server, -1 for unlimited
 	UploadSize           int    `json:"upload_size" validate:"min=1,max=1000"`
 	DaemonListenPort     int    `json:"daemon_listen_port" validate:"required,min=1024,max=65535"`
 	DaemonSftpPort       int    `json:"daemon_sftp_port" validate:"required,min=1024,max=65535"`
+	ImageWarmup          bool   `json:"image_warmup"`
+	TLSInsecure          bool   `json:"tls_insecure"` // Skip certificate verification, for development nodes only
+	AllowedMounts        []string `json:"allowed_mounts" validate:"max=50,dive,required,max=255"` // Host paths servers may mount
 }
 
 type UpdateNodeRequest struct {
@@ -34,13 +48,19 @@ type UpdateNodeRequest struct {
 	Scheme               string `json:"scheme" validate:"required,oneof=http https"`
 	BehindProxy          bool   `json:"behind_proxy"`
 	MaintenanceMode      bool   `json:"maintenance_mode"`
+	services.MaintenanceOptions // How servers are drained and restored when MaintenanceMode changes
 	Memory               int    `json:"memory" validate:"required,min=128"`
 	MemoryOverallocate   int    `json:"memory_overallocate" validate:"min=0,max=500"`
 	Disk                 int    `json:"disk" validate:"required,min=1024"`
 	DiskOverallocate     int    `json:"disk_overallocate" validate:"min=0,max=500"`
+	SwapMax              int64  `json:"swap_max" validate:"min=-1"` // MB per server, -1 for unlimited
 	UploadSize           int    `json:"upload_size" validate:"min=1,max=1000"`
 	DaemonListenPort     int    `json:"daemon_listen_port" validate:"required,min=1024,max=65535"`
 	DaemonSftpPort       int    `json:"daemon_sftp_port" validate:"required,min=1024,max=65535"`
+	ImageWarmup          bool   `json:"image_warmup"`
+	TLSInsecure          bool   `json:"tls_insecure"`
+	AllowedMounts        []string `json:"allowed_mounts" validate:"max=50,dive,required,max=255"`
+	Version              int    `json:"version" validate:"required,min=1"` // Version the client last read
 }
 
 // generateToken generates a secure random token
@@ -52,11 +72,15 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// GetNodes returns all nodes
+// GetNodes returns a page of nodes
 func (h *Handler) GetNodes(c *fiber.Ctx) error {
-	var nodes []entities.Node
-	
-	if err := h.db.Preload("Location").Find(&nodes).Error; err != nil {
+	params := pageParams(c, 50, repositories.MaxPageSize)
+	nodes := make([]entities.Node, 0, params.PageSize)
+
+	total, err := database.Paginate(h.db.Model(&entities.Node{}), params, "name", &nodes, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Location")
+	})
+	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch nodes",
 		})
@@ -69,9 +93,7 @@ func (h *Handler) GetNodes(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(fiber.Map{
-		"data": nodes,
-	})
+	return c.JSON(paginated(nodes, params, total))
 }
 
 // CreateNode creates a new node
@@ -84,11 +106,13 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	allowedMounts, err := services.ValidateAllowedMounts(req.AllowedMounts)
+	if err != nil {
+		return err
 	}
 
 	// Check if location exists
@@ -129,9 +153,14 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 		MemoryOveralloc:  req.MemoryOverallocate,
 		DiskTotal:        int64(req.Disk),
 		DiskOveralloc:    req.DiskOverallocate,
+		SwapMax:          req.SwapMax,
 		CPUTotal:         100, // Default 1 core
 		IsOnline:         false,
 		MaintenanceMode:  req.BehindProxy, // Use BehindProxy as maintenance mode for now
+		ImageWarmup:      req.ImageWarmup,
+		UploadSize:       req.UploadSize,
+		TLSInsecure:      req.TLSInsecure,
+		AllowedMounts:    allowedMounts,
 	}
 
 	if err := h.db.Create(&node).Error; err != nil {
@@ -143,9 +172,15 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 	// Load location for response
 	h.db.Preload("Location").First(&node, "id = ?", node.ID)
 
-	return c.Status(http.StatusCreated).JSON(fiber.Map{
-		"data": node,
-	})
+	resp := fiber.Map{
+		"data":            node,
+		"max_upload_size": node.EffectiveUploadSize(h.cfg.Server.BodyLimit),
+	}
+	if err := h.agent.SyncUploadLimit(c.UserContext(), node.ID, node.EffectiveUploadSize(h.cfg.Server.BodyLimit)); err != nil {
+		resp["sync_error"] = err.Error()
+	}
+
+	return c.Status(http.StatusCreated).JSON(resp)
 }
 
 // GetNode returns a specific node
@@ -176,11 +211,8 @@ func (h *Handler) UpdateNode(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
 	}
 
 	var node entities.Node
@@ -190,6 +222,15 @@ func (h *Handler) UpdateNode(c *fiber.Ctx) error {
 		})
 	}
 
+	if node.Version != req.Version {
+		return versionConflict(c, node.Version)
+	}
+
+	allowedMounts, err := services.ValidateAllowedMounts(req.AllowedMounts)
+	if err != nil {
+		return err
+	}
+
 	// Check if location exists
 	var location entities.Location
 	if err := h.db.Where("id = ?", req.LocationID).First(&location).Error; err != nil {
@@ -214,25 +255,60 @@ func (h *Handler) UpdateNode(c *fiber.Ctx) error {
 	node.LocationID = locationUUID
 	node.FQDN = req.FQDN
 	node.Scheme = req.Scheme
-	node.MaintenanceMode = req.MaintenanceMode
+	maintenanceChanged := req.MaintenanceMode != node.MaintenanceMode
 	node.MemoryTotal = int64(req.Memory)
 	node.MemoryOveralloc = req.MemoryOverallocate
 	node.DiskTotal = int64(req.Disk)
 	node.DiskOveralloc = req.DiskOverallocate
+	node.SwapMax = req.SwapMax
 	node.DaemonPort = req.DaemonListenPort
-
-	if err := h.db.Save(&node).Error; err != nil {
+	warmupEnabled := req.ImageWarmup && !node.ImageWarmup
+	node.ImageWarmup = req.ImageWarmup
+	uploadSizeChanged := req.UploadSize != node.UploadSize
+	node.UploadSize = req.UploadSize
+	node.TLSInsecure = req.TLSInsecure
+	node.AllowedMounts = allowedMounts
+
+	if err := saveVersioned(h.db, &node, &node.Version, req.Version); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			var current entities.Node
+			h.db.Select("version").Where("id = ?", node.ID).First(&current)
+			return versionConflict(c, current.Version)
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update node",
 		})
 	}
 
+	if warmupEnabled {
+		h.startWarmup(node.ID)
+	}
+
+	// Maintenance goes through the node service so running servers are
+	// drained on entry and restored on exit
+	var maintenanceErr error
+	if maintenanceChanged {
+		userID, _ := middleware.GetUserID(c)
+		maintenanceErr = h.nodes.SetMaintenanceMode(c.UserContext(), node.ID, req.MaintenanceMode, req.MaintenanceOptions, userID)
+	}
+
 	// Load location for response
 	h.db.Preload("Location").First(&node, "id = ?", node.ID)
 
-	return c.JSON(fiber.Map{
-		"data": node,
-	})
+	resp := fiber.Map{
+		"data":            node,
+		"max_upload_size": node.EffectiveUploadSize(h.cfg.Server.BodyLimit),
+	}
+	if maintenanceErr != nil {
+		resp["maintenance_error"] = maintenanceErr.Error()
+	}
+	if uploadSizeChanged {
+		if err := h.agent.SyncUploadLimit(c.UserContext(), node.ID, node.EffectiveUploadSize(h.cfg.Server.BodyLimit)); err != nil {
+			resp["sync_error"] = err.Error()
+		}
+	}
+
+	return c.JSON(resp)
 }
 
 // DeleteNode deletes a node
@@ -300,9 +376,379 @@ func (h *Handler) GetNodeConfiguration(c *fiber.Ctx) error {
 				"bind_port": 2022, // Default SFTP port
 			},
 		},
-		"allowed_mounts": []string{},
+		"uploads": fiber.Map{
+			"max_upload_size": node.EffectiveUploadSize(h.cfg.Server.BodyLimit), // MB
+		},
+		"allowed_mounts": nodeAllowedMounts(node),
 		"remote":         c.BaseURL(),
 	}
 
 	return c.JSON(config)
 }
+
+// nodeAllowedMounts returns the allowed mounts of a node, never nil so the
+// node configuration always lists them
+func nodeAllowedMounts(node entities.Node) []string {
+	if node.AllowedMounts == nil {
+		return []string{}
+	}
+	return node.AllowedMounts
+}
+
+type ImportNodeServersRequest struct {
+	OwnerID string   `json:"owner_id" validate:"required,uuid"`
+	UUIDs   []string `json:"uuids" validate:"omitempty,dive,required"` // Restrict the import to these containers
+	DryRun  bool     `json:"dry_run"`
+}
+
+// ImportResult reports what happened to a single discovered container
+type ImportResult struct {
+	UUID        string     `json:"uuid"`
+	ContainerID string     `json:"container_id"`
+	Name        string     `json:"name"`
+	Image       string     `json:"image"`
+	Result      string     `json:"result"` // imported, reconciled, unmatched, failed
+	ServerID    *uuid.UUID `json:"server_id,omitempty"`
+	EggID       *uuid.UUID `json:"egg_id,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+const (
+	importResultImported   = "imported"
+	importResultReconciled = "reconciled"
+	importResultUnmatched  = "unmatched"
+	importResultFailed     = "failed"
+)
+
+// ImportNodeServers creates server records for Aether-managed containers that
+// already run on a node. Containers are matched to existing servers by their
+// UUID label; known servers only have their status reconciled and containers
+// whose image does not belong to any egg are reported as unmatched.
+func (h *Handler) ImportNodeServers(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req ImportNodeServersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var node entities.Node
+	if err := h.db.Where("id = ?", id).First(&node).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Node not found",
+		})
+	}
+
+	ownerID := uuid.MustParse(req.OwnerID)
+	var owner entities.User
+	if err := h.db.Where("id = ?", ownerID).First(&owner).Error; err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Owner not found",
+		})
+	}
+
+	containers, err := h.agent.DiscoverServers(c.UserContext(), node.ID)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to discover containers on node",
+		})
+	}
+
+	var eggs []entities.Egg
+	if err := h.db.Preload("Variables").Where("is_active = ?", true).Find(&eggs).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch eggs",
+		})
+	}
+
+	wanted := make(map[string]bool, len(req.UUIDs))
+	for _, u := range req.UUIDs {
+		wanted[u] = true
+	}
+
+	results := make([]ImportResult, 0, len(containers))
+	counts := make(map[string]int)
+	for _, container := range containers {
+		if len(wanted) > 0 && !wanted[container.UUID] {
+			continue
+		}
+
+		result := h.importContainer(c.UserContext(), &node, ownerID, eggs, container, req.DryRun)
+		counts[result.Result]++
+		results = append(results, result)
+	}
+
+	if !req.DryRun && counts[importResultImported] > 0 {
+		if node.ImageWarmup {
+			h.startWarmup(node.ID)
+		}
+
+		userID, _ := middleware.GetUserID(c)
+		h.db.Create(&entities.AuditLog{
+			UserID:      &userID,
+			Action:      entities.AuditActionCreate,
+			Resource:    "server",
+			Description: fmt.Sprintf("Imported %d servers from node %s", counts[importResultImported], node.Name),
+			Metadata: map[string]interface{}{
+				"node_id":    node.ID,
+				"owner_id":   ownerID,
+				"imported":   counts[importResultImported],
+				"reconciled": counts[importResultReconciled],
+				"unmatched":  counts[importResultUnmatched],
+				"failed":     counts[importResultFailed],
+			},
+			IPAddress: c.IP(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run":    req.DryRun,
+		"imported":   counts[importResultImported],
+		"reconciled": counts[importResultReconciled],
+		"unmatched":  counts[importResultUnmatched],
+		"failed":     counts[importResultFailed],
+		"data":       results,
+	})
+}
+
+// importContainer imports or reconciles a single discovered container. An
+// imported server gets the defaults and passes the checks of a created one:
+// the egg's recommended limits for what the container does not report, its
+// egg variables resolved from the container's environment, and room on the
+// node.
+func (h *Handler) importContainer(ctx context.Context, node *entities.Node, ownerID uuid.UUID, eggs []entities.Egg, container agent.DiscoveredContainer, dryRun bool) ImportResult {
+	result := ImportResult{
+		UUID:        container.UUID,
+		ContainerID: container.ContainerID,
+		Name:        container.Name,
+		Image:       container.Image,
+		Status:      string(containerServerStatus(container.State)),
+	}
+
+	if container.UUID == "" {
+		result.Result = importResultFailed
+		result.Error = "container has no aether.server.uuid label"
+		return result
+	}
+
+	// Known servers only get their container and status brought up to date
+	var existing entities.Server
+	if err := h.db.Select("id", "node_id").Where("uuid = ?", container.UUID).First(&existing).Error; err == nil {
+		result.Result = importResultReconciled
+		result.ServerID = &existing.ID
+		if existing.NodeID != node.ID {
+			result.Result = importResultFailed
+			result.Error = "server with this UUID belongs to another node"
+			return result
+		}
+		if !dryRun {
+			h.db.Model(&existing).Updates(map[string]interface{}{
+				"status":       containerServerStatus(container.State),
+				"container_id": container.ContainerID,
+				"internal_id":  container.Name,
+			})
+		}
+		return result
+	}
+
+	egg := matchEggByImage(eggs, container.Image)
+	if egg == nil {
+		result.Result = importResultUnmatched
+		result.Error = "no egg uses this docker image"
+		return result
+	}
+	result.EggID = &egg.ID
+
+	if len(container.Allocations) == 0 {
+		result.Result = importResultFailed
+		result.Error = "container has no port bindings"
+		return result
+	}
+
+	fail := func(err error) ImportResult {
+		result.Result = importResultFailed
+		result.ServerID = nil
+		result.Error = err.Error()
+		return result
+	}
+
+	memory, disk, cpu := int(container.MemoryLimit), 0, container.CPULimit
+	if err := services.ApplyEggLimits(egg.Limits, &memory, &disk, &cpu); err != nil {
+		return fail(err)
+	}
+	eggVars := make([]*entities.EggVariable, len(egg.Variables))
+	for i := range egg.Variables {
+		eggVars[i] = &egg.Variables[i]
+	}
+	variables, environment, err := services.ResolveEggVariables(eggVars, container.Environment)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Reuse the agent's server ID so the agent keeps tracking the same server
+	serverID, err := uuid.Parse(container.ServerID)
+	if err != nil {
+		serverID = uuid.New()
+	}
+	result.ServerID = &serverID
+
+	if dryRun {
+		if _, err := nodeCapacity(h.db, node, int64(memory), int64(disk)); err != nil {
+			return fail(err)
+		}
+		result.Result = importResultImported
+		return result
+	}
+
+	server := entities.Server{
+		ID:          serverID,
+		UUID:        container.UUID,
+		Name:        container.Name,
+		Description: "Imported from node " + node.Name,
+		Status:      containerServerStatus(container.State),
+		OwnerID:     ownerID,
+		NodeID:      node.ID,
+		GameID:      egg.GameID,
+		EggID:       egg.ID,
+		EggVersion:  egg.Version,
+		DockerImage: container.Image,
+		StartupCmd:  container.StartupCmd,
+		MemoryLimit: int64(memory),
+		DiskLimit:   int64(disk),
+		CPULimit:    cpu,
+		BackupLimit: h.settings.Int(ctx, database.SettingDefaultBackupLimit),
+		Environment: environment,
+		ContainerID: container.ContainerID,
+		InternalID:  container.Name,
+		Tags:        []string{},
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Checked with the node locked, as the servers imported before this
+		// one already take up room
+		var current entities.Node
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", node.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if _, err := nodeCapacity(tx, &current, server.MemoryLimit, server.DiskLimit); err != nil {
+			return err
+		}
+
+		var allocations []*entities.Allocation
+		for _, a := range container.Allocations {
+			allocation, err := claimAllocation(tx, node.ID, a)
+			if err != nil {
+				return err
+			}
+			if a.IsPrimary || server.AllocationID == uuid.Nil {
+				server.AllocationID = allocation.ID
+			}
+			allocations = append(allocations, allocation)
+		}
+
+		if err := tx.Create(&server).Error; err != nil {
+			return err
+		}
+		if err := createServerVariables(tx, server.ID, variables); err != nil {
+			return err
+		}
+
+		for _, allocation := range allocations {
+			if err := tx.Model(allocation).Updates(map[string]interface{}{
+				"server_id":  server.ID,
+				"is_primary": allocation.ID == server.AllocationID,
+				"protocol":   allocation.Protocol,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Result = importResultImported
+	return result
+}
+
+// claimAllocation finds or creates the allocation for a discovered port binding
+func claimAllocation(tx *gorm.DB, nodeID uuid.UUID, binding agent.PortBinding) (*entities.Allocation, error) {
+	ip := binding.IP
+	if ip == "" {
+		ip = "0.0.0.0"
+	}
+
+	var allocation entities.Allocation
+	err := tx.Where("node_id = ? AND ip = ? AND port = ?", nodeID, ip, binding.Port).First(&allocation).Error
+	if err == nil {
+		if allocation.ServerID != nil {
+			return nil, fmt.Errorf("port %s:%d is already assigned to another server", ip, binding.Port)
+		}
+		allocation.Protocol = bindingProtocol(binding)
+		return &allocation, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := services.CheckAllocationConflict(tx.Statement.Context, database.NewAllocationRepository(tx), nodeID, ip, binding.Port, binding.Port); err != nil {
+		return nil, err
+	}
+
+	allocation = entities.Allocation{
+		NodeID:   nodeID,
+		IP:       ip,
+		Port:     binding.Port,
+		Protocol: bindingProtocol(binding),
+	}
+	if err := tx.Create(&allocation).Error; err != nil {
+		return nil, err
+	}
+	return &allocation, nil
+}
+
+// bindingProtocol returns the protocol of a discovered port binding, both for
+// agents that do not report it
+func bindingProtocol(binding agent.PortBinding) string {
+	if binding.Protocol == "" {
+		return entities.ProtocolBoth
+	}
+	return binding.Protocol
+}
+
+// matchEggByImage returns the first egg offering the given docker image
+func matchEggByImage(eggs []entities.Egg, image string) *entities.Egg {
+	for i := range eggs {
+		for _, candidate := range eggs[i].DockerImages {
+			if candidate == image {
+				return &eggs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// containerServerStatus maps a docker container state onto a server status
+func containerServerStatus(state string) entities.ServerStatus {
+	switch state {
+	case "running":
+		return entities.ServerStatusRunning
+	case "restarting":
+		return entities.ServerStatusRestarting
+	case "removing":
+		return entities.ServerStatusStopping
+	case "dead":
+		return entities.ServerStatusError
+	default:
+		return entities.ServerStatusStopped
+	}
+}
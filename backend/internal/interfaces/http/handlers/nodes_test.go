@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestClaimAllocationRejectsPortsOfAnotherNode(t *testing.T) {
+	db := newTestDB(t, &entities.Allocation{})
+	first, second := uuid.New(), uuid.New()
+	taken := &entities.Allocation{ID: uuid.New(), NodeID: first, IP: "203.0.113.10", Port: 25565}
+	if err := db.Create(taken).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := claimAllocation(db, second, agent.PortBinding{IP: "203.0.113.10", Port: 25565}); !errors.Is(err, services.ErrAllocationConflict) {
+		t.Errorf("same IP and port on another node = %v, want %v", err, services.ErrAllocationConflict)
+	}
+
+	allocation, err := claimAllocation(db, second, agent.PortBinding{IP: "203.0.113.11", Port: 25565})
+	if err != nil {
+		t.Fatalf("distinct IP = %v", err)
+	}
+	if allocation.NodeID != second || allocation.Port != 25565 {
+		t.Errorf("claimed %+v, want port 25565 on the second node", allocation)
+	}
+
+	claimed, err := claimAllocation(db, first, agent.PortBinding{IP: "203.0.113.10", Port: 25565})
+	if err != nil {
+		t.Fatalf("existing allocation of the same node = %v", err)
+	}
+	if claimed.ID != taken.ID {
+		t.Errorf("claimed %s, want the existing allocation %s", claimed.ID, taken.ID)
+	}
+
+	var count int64
+	db.Model(&entities.Allocation{}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d allocations stored, want 2", count)
+	}
+}
+
+// importTest is a node whose agent reports containers for ImportNodeServers,
+// with one egg offering the image game:1
+type importTest struct {
+	db         *gorm.DB
+	node       *entities.Node
+	egg        *entities.Egg
+	owner      *entities.User
+	containers []agent.DiscoveredContainer
+	app        *fiber.App
+}
+
+func newImportTest(t *testing.T) *importTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.Egg{}, &entities.EggVariable{}, &entities.ServerVariable{},
+		&entities.Allocation{}, &entities.User{}, &entities.AuditLog{}, &entities.Setting{})
+	it := &importTest{db: db}
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/servers/discover" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"servers": it.containers})
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	it.node = &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort, MemoryTotal: 4096, DiskTotal: 20480}
+	it.egg = &entities.Egg{
+		ID: uuid.New(), GameID: uuid.New(), Name: "game", DockerImages: []string{"game:1"}, IsActive: true, Version: 1,
+		Limits:    entities.EggLimits{Memory: entities.ResourceBounds{Recommended: 1024}, Disk: entities.ResourceBounds{Recommended: 5120}, CPU: entities.ResourceBounds{Recommended: 100}},
+		Variables: []entities.EggVariable{{ID: uuid.New(), Name: "Max players", EnvVariable: "MAX_PLAYERS", DefaultValue: "20", Rules: "required|integer|max:100"}},
+	}
+	it.owner = &entities.User{ID: uuid.New(), Username: "owner", Email: "owner@example.com"}
+	for _, row := range []interface{}{it.node, it.egg, it.owner} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr := miniredis.RunT(t)
+	redisPort, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: redisPort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, validator: middleware.NewValidator(), agent: agent.NewClient(cfg.Agents, db), settings: database.NewSettings(db, rdb)}
+	it.app = fiber.New()
+	it.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, uuid.New())
+		c.Locals(middleware.RoleNameKey, "admin")
+		return c.Next()
+	})
+	it.app.Post("/nodes/:id/import", h.ImportNodeServers)
+	return it
+}
+
+// container returns a discovered container of the game egg
+func (it *importTest) container(name string, memory int64, port int) agent.DiscoveredContainer {
+	return agent.DiscoveredContainer{
+		ServerID: uuid.New().String(), UUID: "uuid-" + name, ContainerID: "container-" + name, Name: name, Image: "game:1", State: "running",
+		MemoryLimit: memory, CPULimit: 100, Environment: map[string]string{"MAX_PLAYERS": "50"},
+		Allocations: []agent.PortBinding{{IP: "203.0.113.10", Port: port, IsPrimary: true}},
+	}
+}
+
+// run imports every discovered container and returns the result of each by name
+func (it *importTest) run(t *testing.T, dryRun bool) map[string]ImportResult {
+	t.Helper()
+	body, _ := json.Marshal(fiber.Map{"owner_id": it.owner.ID, "dry_run": dryRun})
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+it.node.ID.String()+"/import", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := it.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("import = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var out struct {
+		Data []ImportResult `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]ImportResult, len(out.Data))
+	for _, result := range out.Data {
+		results[result.Name] = result
+	}
+	return results
+}
+
+func TestImportNodeServers(t *testing.T) {
+	it := newImportTest(t)
+	unmatched := it.container("modded", 1024, 25566)
+	unmatched.Image = "other:1"
+	it.containers = []agent.DiscoveredContainer{
+		it.container("survival", 1024, 25565),
+		unmatched,
+		// Does not fit the 4 GB node
+		it.container("huge", 8192, 25567),
+	}
+
+	want := map[string]string{"survival": importResultImported, "modded": importResultUnmatched, "huge": importResultFailed}
+	for name, result := range it.run(t, true) {
+		if result.Result != want[name] {
+			t.Errorf("dry run %s = %s (%s), want %s", name, result.Result, result.Error, want[name])
+		}
+	}
+	var count int64
+	it.db.Model(&entities.Server{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run stored %d servers", count)
+	}
+
+	for name, result := range it.run(t, false) {
+		if result.Result != want[name] {
+			t.Errorf("%s = %s (%s), want %s", name, result.Result, result.Error, want[name])
+		}
+	}
+
+	var server entities.Server
+	if err := it.db.Omit("Environment").First(&server, "uuid = ?", "uuid-survival").Error; err != nil {
+		t.Fatalf("imported server: %v", err)
+	}
+	var environment string
+	it.db.Model(&server).Pluck("environment", &environment)
+	if err := json.Unmarshal([]byte(environment), &server.Environment); err != nil {
+		t.Fatal(err)
+	}
+	if server.MemoryLimit != 1024 || server.DiskLimit != 5120 || server.BackupLimit != 2 {
+		t.Errorf("imported limits = %d MB, %d MB disk, %d backups, want 1024, the egg's 5120 and the default 2",
+			server.MemoryLimit, server.DiskLimit, server.BackupLimit)
+	}
+	if server.OwnerID != it.owner.ID || server.EggID != it.egg.ID || server.Environment["MAX_PLAYERS"] != "50" {
+		t.Errorf("imported server = owner %s, egg %s, environment %v", server.OwnerID, server.EggID, server.Environment)
+	}
+	var variable entities.ServerVariable
+	if err := it.db.First(&variable, "server_id = ?", server.ID).Error; err != nil || variable.Value != "50" {
+		t.Errorf("server variable = %q, %v, want the container's 50", variable.Value, err)
+	}
+	var allocation entities.Allocation
+	if err := it.db.First(&allocation, "id = ?", server.AllocationID).Error; err != nil || allocation.Port != 25565 || allocation.ServerID == nil {
+		t.Errorf("primary allocation = %+v, %v, want port 25565 assigned", allocation, err)
+	}
+
+	// A second import reconciles the server instead of duplicating it
+	if got := it.run(t, false)["survival"]; got.Result != importResultReconciled || got.ServerID == nil || *got.ServerID != server.ID {
+		t.Errorf("second import = %s of %v, want %s of %s", got.Result, got.ServerID, importResultReconciled, server.ID)
+	}
+	it.db.Model(&entities.Server{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d servers stored after importing twice, want 1", count)
+	}
+	it.db.Model(&entities.Allocation{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d allocations stored after importing twice, want 1", count)
+	}
+}
+
+func TestImportRejectsInvalidVariables(t *testing.T) {
+	it := newImportTest(t)
+	container := it.container("survival", 1024, 25565)
+	container.Environment["MAX_PLAYERS"] = "500"
+	it.containers = []agent.DiscoveredContainer{container}
+
+	if got := it.run(t, false)["survival"]; got.Result != importResultFailed {
+		t.Errorf("import with an invalid variable = %s, want %s", got.Result, importResultFailed)
+	}
+	var count int64
+	it.db.Model(&entities.Server{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d servers stored, want none", count)
+	}
+}
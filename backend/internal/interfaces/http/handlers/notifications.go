@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type DeleteNotificationsRequest struct {
+	IDs  []string `json:"ids" validate:"required_without=Read,max=100,dive,uuid"`
+	Read bool     `json:"read"` // Delete every read notification instead
+}
+
+type NotificationPreferenceRequest struct {
+	Type  string `json:"type" validate:"required,oneof=server_crashed server_oom resource_high resource_recovered *"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" validate:"max=20,dive"`
+}
+
+// GetNotifications returns a page of the user's notifications, newest first.
+// ?unread=true leaves out those already read.
+func (h *Handler) GetNotifications(c *fiber.Ctx) error {
+	params := pageParams(c, 25, 100)
+	userID, _ := middleware.GetUserID(c)
+
+	db := h.db.Model(&entities.Notification{}).Where("user_id = ?", userID)
+	if c.QueryBool("unread") {
+		db = db.Where("is_read = ?", false)
+	}
+
+	notifications := make([]entities.Notification, 0, params.PageSize)
+	total, err := database.Paginate(db, params, "created_at DESC", &notifications)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notifications",
+		})
+	}
+
+	var unread int64
+	h.db.Model(&entities.Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&unread)
+
+	body := paginated(notifications, params, total)
+	body["unread"] = unread
+	return c.JSON(body)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+func (h *Handler) MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserID(c)
+
+	res := h.db.Model(&entities.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	if res.Error != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to mark notifications as read",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"updated": res.RowsAffected,
+	})
+}
+
+// DeleteNotifications deletes the user's notifications with the given ids,
+// or all of their read notifications
+func (h *Handler) DeleteNotifications(c *fiber.Ctx) error {
+	var req DeleteNotificationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	db := h.db.Where("user_id = ?", userID)
+	if req.Read {
+		db = db.Where("is_read = ?", true)
+	} else {
+		db = db.Where("id IN ?", req.IDs)
+	}
+
+	res := db.Delete(&entities.Notification{})
+	if res.Error != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete notifications",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"deleted": res.RowsAffected,
+	})
+}
+
+// GetNotificationPreferences returns the user's notification preferences and
+// the types they can be set for
+func (h *Handler) GetNotificationPreferences(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserID(c)
+
+	prefs := make([]entities.NotificationPreference, 0)
+	if err := h.db.Where("user_id = ?", userID).Order("type ASC").Find(&prefs).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notification preferences",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  prefs,
+		"types": entities.NotificationTypes,
+	})
+}
+
+// UpdateNotificationPreferences replaces the user's notification preferences.
+// Types left out go back to the user's "*" preference, or in-app only.
+func (h *Handler) UpdateNotificationPreferences(c *fiber.Ctx) error {
+	var req UpdateNotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	prefs := make([]entities.NotificationPreference, 0, len(req.Preferences))
+	seen := make(map[string]bool, len(req.Preferences))
+	var off []string // Types turned off in-app
+	for _, p := range req.Preferences {
+		if seen[p.Type] {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Duplicate preference for " + p.Type,
+			})
+		}
+		seen[p.Type] = true
+		if !p.InApp {
+			off = append(off, p.Type)
+		}
+		prefs = append(prefs, entities.NotificationPreference{
+			UserID: userID,
+			Type:   p.Type,
+			InApp:  p.InApp,
+			Email:  p.Email,
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.NotificationPreference{}).Error; err != nil {
+			return err
+		}
+		if len(prefs) == 0 {
+			return nil
+		}
+		if err := tx.Create(&prefs).Error; err != nil {
+			return err
+		}
+		// Create writes the true in_app default in place of false, so the
+		// types turned off in-app are updated after it
+		if len(off) == 0 {
+			return nil
+		}
+		return tx.Model(&entities.NotificationPreference{}).
+			Where("user_id = ? AND type IN ?", userID, off).
+			Update("in_app", false).Error
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notification preferences",
+		})
+	}
+
+	for i := range prefs {
+		prefs[i].InApp = !slices.Contains(off, prefs[i].Type)
+	}
+	return c.JSON(fiber.Map{
+		"data": prefs,
+	})
+}
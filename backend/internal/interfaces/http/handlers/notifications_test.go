@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	db := newTestDB(t, &entities.NotificationPreference{})
+	userID := uuid.New()
+
+	h := &Handler{cfg: &config.Config{}, db: db, validator: middleware.NewValidator()}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, userID)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Put("/notifications/preferences", h.UpdateNotificationPreferences)
+	type stored struct {
+		Type  string `json:"type"`
+		InApp bool   `json:"in_app"`
+		Email bool   `json:"email"`
+	}
+	var returned []stored
+	put := func(prefs ...fiber.Map) int {
+		t.Helper()
+		data, _ := json.Marshal(fiber.Map{"preferences": prefs})
+		req := httptest.NewRequest(http.MethodPut, "/notifications/preferences", bytes.NewReader(data))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Data []stored `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		returned = body.Data
+		return resp.StatusCode
+	}
+	load := func() []stored {
+		t.Helper()
+		var prefs []stored
+		if err := db.Model(&entities.NotificationPreference{}).Select("type", "in_app", "email").
+			Where("user_id = ?", userID).Order("type").Scan(&prefs).Error; err != nil {
+			t.Fatal(err)
+		}
+		return prefs
+	}
+
+	if status := put(
+		fiber.Map{"type": entities.NotificationServerCrashed, "in_app": false, "email": true},
+		fiber.Map{"type": entities.NotificationResourceHigh, "in_app": false, "email": false},
+		fiber.Map{"type": "*", "in_app": true, "email": false},
+	); status != http.StatusOK {
+		t.Fatalf("update = %d", status)
+	}
+	// Turned off channels are stored off rather than as the column defaults
+	want := []stored{{"*", true, false}, {entities.NotificationResourceHigh, false, false}, {entities.NotificationServerCrashed, false, true}}
+	if got := load(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("stored %+v, want %+v", got, want)
+	}
+	if len(returned) != 3 || returned[0].InApp || !returned[2].InApp {
+		t.Errorf("returned %+v, want the preferences as given", returned)
+	}
+
+	// The preferences are replaced as a whole
+	if status := put(fiber.Map{"type": entities.NotificationServerOOM, "in_app": true, "email": true}); status != http.StatusOK {
+		t.Fatalf("update = %d", status)
+	}
+	if got := load(); len(got) != 1 || got[0] != (stored{entities.NotificationServerOOM, true, true}) {
+		t.Errorf("stored %+v after replacing, want only the OOM preference", got)
+	}
+
+	for _, prefs := range [][]fiber.Map{
+		{{"type": "server_exploded", "in_app": true}},
+		{{"type": "*", "in_app": true}, {"type": "*", "in_app": false}},
+	} {
+		if status := put(prefs...); status != http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+			t.Errorf("update with %v = %d, want it rejected", prefs, status)
+		}
+	}
+}
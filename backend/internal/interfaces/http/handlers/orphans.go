@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CleanNodeOrphansRequest struct {
+	DryRun *bool `json:"dry_run"` // Defaults to true, removal must be asked for explicitly
+}
+
+// CleanNodeOrphans reports the containers and data directories a node holds
+// for servers the panel no longer has, and removes them when the request
+// turns dry_run off. Soft deleted servers can still be restored, so their
+// data is never treated as orphaned.
+func (h *Handler) CleanNodeOrphans(c *fiber.Ctx) error {
+	var req CleanNodeOrphansRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+
+	var node entities.Node
+	if err := h.db.Where("id = ? AND deleted_at IS NULL", c.Params("id")).First(&node).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Node not found",
+		})
+	}
+
+	known := make([]string, 0)
+	if err := h.db.Model(&entities.Server{}).Where("node_id = ?", node.ID).Pluck("uuid", &known).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch servers",
+		})
+	}
+
+	orphans, err := h.agent.CleanOrphans(c.UserContext(), node.ID, known, dryRun)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to scan node for orphans",
+		})
+	}
+
+	var removed, failed int
+	var reclaimed uint64
+	for _, orphan := range orphans {
+		switch {
+		case orphan.Removed:
+			removed++
+			reclaimed += orphan.Size
+		case orphan.Error != "":
+			failed++
+		}
+	}
+
+	if !dryRun && len(orphans) > 0 {
+		userID, _ := middleware.GetUserID(c)
+		h.db.Create(&entities.AuditLog{
+			UserID:      &userID,
+			Action:      entities.AuditActionDelete,
+			Resource:    "node",
+			ResourceID:  &node.ID,
+			Description: fmt.Sprintf("Removed %d orphans from node %s", removed, node.Name),
+			Metadata: map[string]interface{}{
+				"orphans":   orphans,
+				"removed":   removed,
+				"failed":    failed,
+				"reclaimed": reclaimed,
+			},
+			IPAddress: c.IP(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run":   dryRun,
+		"found":     len(orphans),
+		"removed":   removed,
+		"failed":    failed,
+		"reclaimed": reclaimed,
+		"data":      orphans,
+	})
+}
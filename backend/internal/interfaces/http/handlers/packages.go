@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type OrderPackageRequest struct {
+	PackageID    uuid.UUID `json:"package_id" validate:"required"`
+	BillingCycle string    `json:"billing_cycle" validate:"required,oneof=monthly quarterly yearly"`
+	EggID        uuid.UUID `json:"egg_id" validate:"required"`
+	NodeID       uuid.UUID `json:"node_id"` // Omit to place the server on one of the package's nodes
+}
+
+// OrderPackage subscribes the current user to a package, paid from their
+// credits, and provisions its server
+func (h *Handler) OrderPackage(c *fiber.Ctx) error {
+	var req OrderPackageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	sub, err := h.provisioning.OrderPackage(c.UserContext(), userID, req.PackageID, req.BillingCycle, req.EggID, req.NodeID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": sub,
+	})
+}
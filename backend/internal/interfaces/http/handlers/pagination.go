@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/gofiber/fiber/v2"
+)
+
+// pageParams reads the page and page_size query parameters, clamped to sane
+// bounds instead of rejected, so list endpoints page the same way
+func pageParams(c *fiber.Ctx, defaultPageSize, maxPageSize int) repositories.ListParams {
+	params := repositories.ListParams{
+		Page:     c.QueryInt("page", 1),
+		PageSize: c.QueryInt("page_size", defaultPageSize),
+	}
+	if params.PageSize < 1 {
+		params.PageSize = defaultPageSize
+	}
+	return params.Clamp(maxPageSize)
+}
+
+// paginated is the body of a list response
+func paginated(data interface{}, params repositories.ListParams, total int64) fiber.Map {
+	return fiber.Map{
+		"data": data,
+		"meta": repositories.NewPageMeta(params, total),
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPageParamsClamping(t *testing.T) {
+	var got repositories.ListParams
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		got = pageParams(c, 25, 100)
+		return nil
+	})
+
+	for query, want := range map[string]repositories.ListParams{
+		"":                          {Page: 1, PageSize: 25},
+		"?page=3&page_size=10":      {Page: 3, PageSize: 10},
+		"?page=0":                   {Page: 1, PageSize: 25},
+		"?page=-4":                  {Page: 1, PageSize: 25},
+		"?page_size=0":              {Page: 1, PageSize: 25},
+		"?page_size=-1":             {Page: 1, PageSize: 25},
+		"?page_size=100":            {Page: 1, PageSize: 100},
+		"?page_size=1000000":        {Page: 1, PageSize: 100},
+		"?page=two&page_size=large": {Page: 1, PageSize: 25},
+	} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/"+query, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if got.Page != want.Page || got.PageSize != want.PageSize {
+			t.Errorf("%q: page %d size %d, want %d and %d", query, got.Page, got.PageSize, want.Page, want.PageSize)
+		}
+	}
+}
+
+func TestPaginatedTotalPages(t *testing.T) {
+	for _, tc := range []struct {
+		pageSize int
+		total    int64
+		pages    int64
+	}{
+		{25, 0, 0},
+		{25, 1, 1},
+		{25, 25, 1},
+		{25, 26, 2},
+		{10, 99, 10},
+		{10, 100, 10},
+		{100, 1001, 11},
+	} {
+		params := repositories.ListParams{Page: 2, PageSize: tc.pageSize}
+		body, err := json.Marshal(paginated([]int{}, params, tc.total))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct {
+			Data []int                 `json:"data"`
+			Meta repositories.PageMeta `json:"meta"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatal(err)
+		}
+		want := repositories.PageMeta{Page: 2, PageSize: tc.pageSize, Total: tc.total, TotalPages: tc.pages}
+		if resp.Meta != want || resp.Data == nil {
+			t.Errorf("%d rows by %d: %s, want meta %+v", tc.total, tc.pageSize, body, want)
+		}
+	}
+}
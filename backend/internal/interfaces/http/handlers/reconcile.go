@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReconcileServer corrects a server's stored status from what its node
+// reports, without waiting for the periodic reconciliation
+func (h *Handler) ReconcileServer(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	drift, err := h.reconcile.ReconcileServer(ctx, server)
+	if errors.Is(err, agent.ErrNotOnNode) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Server is not known to its node",
+		})
+	}
+	if errors.Is(err, services.ErrNodeTimeout) {
+		return err
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to reach the server's node",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"drifted": drift != nil,
+		"drift":   drift,
+		"data":    server,
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CreateRegistryCredentialRequest struct {
+	NodeID   string `json:"node_id" validate:"omitempty,uuid"` // Empty applies to every node
+	Host     string `json:"host" validate:"required,max=255"`
+	Username string `json:"username" validate:"required,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+// GetRegistryCredentials returns registry credentials, optionally filtered by node
+func (h *Handler) GetRegistryCredentials(c *fiber.Ctx) error {
+	var creds []entities.RegistryCredential
+
+	query := h.db.Order("host")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+
+	if err := query.Find(&creds).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch registry credentials",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": creds,
+	})
+}
+
+// CreateRegistryCredential stores encrypted registry credentials and pushes them to the affected nodes
+func (h *Handler) CreateRegistryCredential(c *fiber.Ctx) error {
+	var req CreateRegistryCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	secrets, err := crypto.NewCipher(h.cfg.Security.EncryptionKey)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Encryption key is not configured correctly",
+		})
+	}
+
+	encrypted, err := secrets.Encrypt(req.Password)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to encrypt password",
+		})
+	}
+
+	cred := entities.RegistryCredential{
+		Host:              req.Host,
+		Username:          req.Username,
+		PasswordEncrypted: encrypted,
+	}
+
+	if req.NodeID != "" {
+		nodeID := uuid.MustParse(req.NodeID)
+		var node entities.Node
+		if err := h.db.Where("id = ?", nodeID).First(&node).Error; err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Node not found",
+			})
+		}
+		cred.NodeID = &nodeID
+	}
+
+	if err := h.db.Create(&cred).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create registry credential",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data":        cred,
+		"sync_errors": h.syncRegistries(c.UserContext(), secrets, cred.NodeID),
+	})
+}
+
+// DeleteRegistryCredential deletes registry credentials and updates the affected nodes
+func (h *Handler) DeleteRegistryCredential(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var cred entities.RegistryCredential
+	if err := h.db.Where("id = ?", id).First(&cred).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Registry credential not found",
+		})
+	}
+
+	if err := h.db.Delete(&cred).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete registry credential",
+		})
+	}
+
+	secrets, err := crypto.NewCipher(h.cfg.Security.EncryptionKey)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Encryption key is not configured correctly",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"sync_errors": h.syncRegistries(c.UserContext(), secrets, cred.NodeID),
+	})
+}
+
+// syncRegistries pushes the effective credentials to one node, or to every node
+// when nodeID is nil. It returns the nodes that could not be updated.
+func (h *Handler) syncRegistries(ctx context.Context, secrets *crypto.Cipher, nodeID *uuid.UUID) map[string]string {
+	var nodes []entities.Node
+	query := h.db.Model(&entities.Node{})
+	if nodeID != nil {
+		query = query.Where("id = ?", *nodeID)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return map[string]string{"*": "failed to fetch nodes"}
+	}
+
+	failed := make(map[string]string)
+	for _, node := range nodes {
+		registries, err := h.nodeRegistries(secrets, node.ID)
+		if err == nil {
+			err = h.agent.SyncRegistries(ctx, node.ID, registries)
+		}
+		if err != nil {
+			failed[node.ID.String()] = err.Error()
+		}
+	}
+	return failed
+}
+
+// nodeRegistries returns the decrypted credentials that apply to a node. Node
+// specific credentials come first so they win over global ones for the same host.
+func (h *Handler) nodeRegistries(secrets *crypto.Cipher, nodeID uuid.UUID) ([]agent.RegistryAuth, error) {
+	var creds []entities.RegistryCredential
+	if err := h.db.Where("node_id = ? OR node_id IS NULL", nodeID).
+		Order("node_id IS NULL, host").
+		Find(&creds).Error; err != nil {
+		return nil, err
+	}
+
+	registries := make([]agent.RegistryAuth, 0, len(creds))
+	for _, cred := range creds {
+		password, err := secrets.Decrypt(cred.PasswordEncrypted)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, agent.RegistryAuth{
+			Host:     cred.Host,
+			Username: cred.Username,
+			Password: password,
+		})
+	}
+	return registries, nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AllocateCreditsRequest struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+type SubUserStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=active suspended"`
+}
+
+// subUserID parses the sub-user ID of the route
+func subUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, services.ErrUserNotFound
+	}
+	return id, nil
+}
+
+// GetSubUsers returns every user in the reseller's sub-tree
+func (h *Handler) GetSubUsers(c *fiber.Ctx) error {
+	resellerID, _ := middleware.GetUserID(c)
+
+	users, err := h.resellers.ListSubUsers(c.UserContext(), resellerID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": users,
+	})
+}
+
+// CreateSubUser creates a user owned by the reseller, optionally funded from
+// the reseller's balance
+func (h *Handler) CreateSubUser(c *fiber.Ctx) error {
+	var req services.CreateSubUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	user, err := h.resellers.CreateSubUser(c.UserContext(), resellerID, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": user,
+	})
+}
+
+// GetSubUser returns a user of the reseller's sub-tree
+func (h *Handler) GetSubUser(c *fiber.Ctx) error {
+	id, err := subUserID(c)
+	if err != nil {
+		return err
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	user, err := h.resellers.GetSubUser(c.UserContext(), resellerID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": user,
+	})
+}
+
+// UpdateSubUserStatus activates or suspends a sub-user
+func (h *Handler) UpdateSubUserStatus(c *fiber.Ctx) error {
+	id, err := subUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req SubUserStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	if err := h.resellers.SetSubUserStatus(c.UserContext(), resellerID, id, entities.UserStatus(req.Status)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User status updated",
+	})
+}
+
+// DeleteSubUser deletes a sub-user without servers
+func (h *Handler) DeleteSubUser(c *fiber.Ctx) error {
+	id, err := subUserID(c)
+	if err != nil {
+		return err
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	if err := h.resellers.DeleteSubUser(c.UserContext(), resellerID, id); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User deleted successfully",
+	})
+}
+
+// AllocateSubUserCredits moves credits from the reseller's balance to a
+// direct sub-user
+func (h *Handler) AllocateSubUserCredits(c *fiber.Ctx) error {
+	id, err := subUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req AllocateCreditsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	tx, err := h.resellers.AllocateCredits(c.UserContext(), resellerID, id, req.Amount)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": tx,
+	})
+}
+
+// SetSubUserQuota sets the resource limits of a sub-user
+func (h *Handler) SetSubUserQuota(c *fiber.Ctx) error {
+	id, err := subUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req services.QuotaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	quota, err := h.resellers.SetQuota(c.UserContext(), resellerID, id, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": quota,
+	})
+}
+
+// GetResellerServers returns a page of the servers in the reseller's sub-tree
+func (h *Handler) GetResellerServers(c *fiber.Ctx) error {
+	params := pageParams(c, 50, repositories.MaxPageSize)
+	resellerID, _ := middleware.GetUserID(c)
+
+	servers, total, err := h.resellers.ListServers(c.UserContext(), resellerID, params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(paginated(servers, params, total))
+}
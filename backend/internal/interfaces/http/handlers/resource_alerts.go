@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateResourceAlerts sets the thresholds past which a server's owner is told
+// its CPU or memory usage has stayed high, and told again once it recovers
+func (h *Handler) UpdateResourceAlerts(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	alerts := server.ResourceAlerts
+	if err := c.BodyParser(&alerts); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := services.ValidateResourceAlerts(alerts); err != nil {
+		return err
+	}
+
+	if err := h.db.Model(server).Update("resource_alerts", alerts).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update resource alerts",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"resource_alerts": alerts},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": alerts,
+	})
+}
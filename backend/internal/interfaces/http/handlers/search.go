@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Search result types
+const (
+	SearchTypeServer = "server"
+	SearchTypeNode   = "node"
+	SearchTypeUser   = "user"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type SearchQuery struct {
+	Q     string `query:"q" validate:"required,min=2,max=100"`
+	Limit int    `query:"limit" validate:"min=1,max=25"`
+}
+
+// SearchHit is one search result
+type SearchHit struct {
+	Type     string    `json:"type"`
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	Subtitle string    `json:"subtitle"`
+}
+
+// SearchResults are search hits grouped by type. Groups the user may not
+// search are left out.
+type SearchResults struct {
+	Servers []SearchHit `json:"servers"`
+	Nodes   []SearchHit `json:"nodes,omitempty"`
+	Users   []SearchHit `json:"users,omitempty"`
+}
+
+// Search looks up servers by name or UUID, nodes by name or FQDN and users by
+// email or username. Trashed rows are left out. Users without admin rights
+// only find their own servers, and resellers those of their sub-tree.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	query := SearchQuery{Limit: 5}
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+	query.Q = strings.TrimSpace(query.Q)
+	if fields := h.validator.Validate(query); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	// Matched with ILIKE, which the trigram indexes on these columns serve
+	pattern := "%" + likeEscaper.Replace(query.Q) + "%"
+	results := SearchResults{Servers: []SearchHit{}}
+
+	// Resellers find the servers and users of their own sub-tree
+	var tree []uuid.UUID
+	userID, _ := middleware.GetUserID(c)
+	if role, _ := middleware.GetRoleName(c); role == "reseller" {
+		var err error
+		if tree, err = h.resellers.TreeIDs(c.UserContext(), userID); err != nil {
+			return err
+		}
+	}
+
+	servers := h.db.Model(&entities.Server{}).Scopes(database.NotTrashed).
+		Where("name ILIKE ? OR uuid ILIKE ?", pattern, pattern)
+	switch {
+	case middleware.IsAdmin(c):
+	case tree != nil:
+		servers = servers.Where("owner_id IN ?", tree)
+	default:
+		servers = servers.Where("owner_id = ?", userID)
+	}
+	var serverRows []entities.Server
+	if err := servers.Order("name").Limit(query.Limit).Find(&serverRows).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Search failed",
+		})
+	}
+	for _, s := range serverRows {
+		results.Servers = append(results.Servers, SearchHit{Type: SearchTypeServer, ID: s.ID, Title: s.Name, Subtitle: s.UUID})
+	}
+
+	if middleware.HasPermission(c, "nodes.view") {
+		var nodes []entities.Node
+		if err := h.db.Scopes(database.NotTrashed).Where("name ILIKE ? OR fqdn ILIKE ?", pattern, pattern).
+			Order("name").Limit(query.Limit).Find(&nodes).Error; err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Search failed",
+			})
+		}
+		results.Nodes = []SearchHit{}
+		for _, n := range nodes {
+			results.Nodes = append(results.Nodes, SearchHit{Type: SearchTypeNode, ID: n.ID, Title: n.Name, Subtitle: n.FQDN})
+		}
+	}
+
+	if middleware.HasPermission(c, "users.view") {
+		users := h.db.Model(&entities.User{}).Scopes(database.NotTrashed).
+			Where("email ILIKE ? OR username ILIKE ?", pattern, pattern)
+		if tree != nil {
+			users = users.Where("id IN ?", tree)
+		}
+		var userRows []entities.User
+		if err := users.Order("username").Limit(query.Limit).Find(&userRows).Error; err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Search failed",
+			})
+		}
+		results.Users = []SearchHit{}
+		for _, u := range userRows {
+			results.Users = append(results.Users, SearchHit{Type: SearchTypeUser, ID: u.ID, Title: u.Username, Subtitle: u.Email})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": results,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestSearchScopesResultsToCaller(t *testing.T) {
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.User{})
+	deleted := time.Now()
+
+	reseller := &entities.User{ID: uuid.New(), Username: "reseller", Email: "reseller@example.com"}
+	sub := &entities.User{ID: uuid.New(), Username: "player-sub", Email: "sub@example.com", ResellerID: &reseller.ID}
+	gone := &entities.User{ID: uuid.New(), Username: "player-gone", Email: "gone@example.com", ResellerID: &reseller.ID, DeletedAt: &deleted}
+	other := &entities.User{ID: uuid.New(), Username: "player-other", Email: "other@example.com"}
+	node := &entities.Node{ID: uuid.New(), Name: "play-node", LocationID: uuid.New(), FQDN: "node-1.example.com"}
+	oldNode := &entities.Node{ID: uuid.New(), Name: "play-old-node", LocationID: uuid.New(), FQDN: "node-0.example.com", DeletedAt: &deleted}
+	rows := []interface{}{reseller, sub, gone, other, node, oldNode}
+	for name, owner := range map[string]*entities.User{"play-sub": sub, "play-other": other} {
+		rows = append(rows, &entities.Server{ID: uuid.New(), UUID: name, Name: name, NodeID: node.ID, EggID: uuid.New(), OwnerID: owner.ID})
+	}
+	rows = append(rows, &entities.Server{ID: uuid.New(), UUID: "play-gone", Name: "play-gone", NodeID: node.ID, EggID: uuid.New(), OwnerID: sub.ID, DeletedAt: &deleted})
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{
+		db:        db,
+		validator: middleware.NewValidator(),
+		resellers: services.NewResellerService(
+			database.NewUserRepository(db), nil, nil, database.NewServerRepository(db), nil,
+			database.NewUnitOfWork(db, database.NewTxRepositories), nil,
+		),
+	}
+
+	for name, tc := range map[string]struct {
+		caller      uuid.UUID
+		role        string
+		permissions []string
+		servers     []string
+		nodes       []string // nil when the group is left out
+		users       []string
+	}{
+		"admin":    {reseller.ID, "admin", nil, []string{"play-other", "play-sub"}, []string{"play-node"}, []string{"player-other", "player-sub"}},
+		"reseller": {reseller.ID, "reseller", []string{"users.view"}, []string{"play-sub"}, nil, []string{"player-sub"}},
+		"user":     {other.ID, "user", nil, []string{"play-other"}, nil, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				c.Locals(middleware.UserIDKey, tc.caller)
+				c.Locals(middleware.RoleNameKey, tc.role)
+				c.Locals(middleware.PermissionsKey, tc.permissions)
+				return c.Next()
+			})
+			app.Get("/search", h.Search)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/search?q=PLAY", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("search = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+
+			var body struct {
+				Data map[string][]SearchHit `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			for group, want := range map[string][]string{"servers": tc.servers, "nodes": tc.nodes, "users": tc.users} {
+				hits, ok := body.Data[group]
+				if want == nil {
+					if ok {
+						t.Errorf("%s group = %v, want it left out", group, hits)
+					}
+					continue
+				}
+				var titles []string
+				for _, hit := range hits {
+					if hit.Type+"s" != group {
+						t.Errorf("%s hit %q has type %s", group, hit.Title, hit.Type)
+					}
+					titles = append(titles, hit.Title)
+				}
+				if !slices.Equal(titles, want) {
+					t.Errorf("%s = %v, want %v", group, titles, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// accessibleServer loads the server named by the route's id, refusing callers
+// who may not manage it
+func (h *Handler) accessibleServer(c *fiber.Ctx) (*entities.Server, error) {
+	var server entities.Server
+	if err := h.db.WithContext(c.UserContext()).Where("id = ?", c.Params("id")).First(&server).Error; err != nil {
+		return nil, services.ErrServerNotFound
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if !server.ManageableBy(userID, middleware.IsAdmin(c)) {
+		return nil, fiber.NewError(http.StatusForbidden, "Access denied")
+	}
+	return &server, nil
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// checkServerLimit verifies that the owner may own another server under their
+// active subscriptions or, without one, their role. Admins create servers
+// without a limit.
+func (h *Handler) checkServerLimit(c *fiber.Ctx, ownerID uuid.UUID) error {
+	if middleware.IsAdmin(c) {
+		return nil
+	}
+	return h.servers.CheckServerLimit(c.UserContext(), ownerID)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestCreateServerEnforcesServerLimit(t *testing.T) {
+	db := newTestDB(t, &entities.User{}, &entities.Role{}, &entities.Subscription{}, &entities.Package{},
+		&entities.Location{}, &entities.Node{}, &entities.Server{}, &entities.ServerVariable{},
+		&entities.Egg{}, &entities.EggVariable{}, &entities.Allocation{}, &entities.UserQuota{}, &entities.Setting{})
+	// Relationships are not migrated, so the join table of roles is made here
+	if err := db.Exec("CREATE TABLE role_permissions (role_id TEXT, permission_id TEXT, PRIMARY KEY (role_id, permission_id))").Error; err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	role := &entities.Role{ID: uuid.New(), Name: "user", ServerLimit: 1}
+	owner := &entities.User{ID: uuid.New(), Email: "owner@example.com", Username: "owner", RoleID: role.ID}
+	location := &entities.Location{ID: uuid.New(), ShortCode: "eu", Name: "Europe"}
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: location.ID, FQDN: "node-1.example.com", IsOnline: true, MemoryTotal: 16384, DiskTotal: 100000}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "game", StartupCommand: "./run", DockerImages: []string{"game:1"}, Version: 1}
+	existing := &entities.Server{ID: uuid.New(), UUID: "existing", Name: "existing", NodeID: node.ID, OwnerID: owner.ID, MemoryLimit: 1024, DiskLimit: 10000}
+	rows := []interface{}{role, owner, location, node, egg, existing}
+	for _, port := range []int{25565, 25566, 25567} {
+		rows = append(rows, &entities.Allocation{ID: uuid.New(), NodeID: node.ID, IP: "10.0.0.1", Port: port})
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, db: db, redis: rdb, validator: middleware.NewValidator(),
+		settings: database.NewSettings(db, rdb), placer: services.NewNodePlacer(string(services.PlacementMostFree)),
+		servers: services.NewServerService(database.NewServerRepository(db), nil, nil, nil, nil, nil, nil,
+			database.NewUserRepository(db), database.NewRoleRepository(db), database.NewSubscriptionRepository(db),
+			nil, nil, nil, nil, nil, cfg)}
+	roleName := "user"
+	var handlerErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		handlerErr = err
+		return c.SendStatus(http.StatusForbidden)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, owner.ID)
+		c.Locals(middleware.RoleNameKey, roleName)
+		return c.Next()
+	})
+	app.Post("/servers", h.CreateServer)
+	create := func() int {
+		t.Helper()
+		handlerErr = nil
+		data, _ := json.Marshal(fiber.Map{
+			"name": "another", "auto_place": true, "location_id": location.ID.String(), "egg_id": egg.ID.String(),
+			"memory": 1024, "disk": 10000, "cpu": 100,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/servers", bytes.NewReader(data))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	owned := func() int64 {
+		t.Helper()
+		var n int64
+		if err := db.Model(&entities.Server{}).Where("owner_id = ?", owner.ID).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// The owner already has the one server their role allows
+	if status := create(); status != http.StatusForbidden || !errors.Is(handlerErr, services.ErrServerLimitReached) {
+		t.Errorf("create at the limit = %d %v, want %v", status, handlerErr, services.ErrServerLimitReached)
+	}
+	if n := owned(); n != 1 {
+		t.Errorf("owner has %d servers after the refused create, want 1", n)
+	}
+
+	// Raising the role's limit allows one more server, and no more
+	if err := db.Model(role).Update("server_limit", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status := create(); status != http.StatusCreated {
+		t.Fatalf("create under the raised limit = %d %v", status, handlerErr)
+	}
+	if status := create(); !errors.Is(handlerErr, services.ErrServerLimitReached) {
+		t.Errorf("create at the raised limit = %d %v, want %v", status, handlerErr, services.ErrServerLimitReached)
+	}
+	if n := owned(); n != 2 {
+		t.Errorf("owner has %d servers, want 2", n)
+	}
+
+	// Admins are not limited
+	roleName = "admin"
+	if status := create(); status != http.StatusCreated {
+		t.Errorf("admin create = %d %v", status, handlerErr)
+	}
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/apperror"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// serverPlan is a server as CreateServer would create it, before anything is
+// written
+type serverPlan struct {
+	Node        *entities.Node
+	Egg         *entities.Egg
+	Server      *entities.Server
+	Allocations []*entities.Allocation
+	Variables   []services.ResolvedVariable
+	Impact      PlacementImpact
+}
+
+// PlacementImpact is the node's resource use before and after adding a server
+type PlacementImpact struct {
+	MemoryTotal  int64 `json:"memory_total"` // MB
+	MemoryBefore int64 `json:"memory_before"`
+	MemoryAfter  int64 `json:"memory_after"`
+	DiskTotal    int64 `json:"disk_total"` // MB
+	DiskBefore   int64 `json:"disk_before"`
+	DiskAfter    int64 `json:"disk_after"`
+}
+
+// placementError is a planning failure caused by the node, such as missing
+// capacity or allocations, rather than by the request itself
+type placementError struct {
+	err error
+}
+
+func (e *placementError) Error() string { return e.err.Error() }
+func (e *placementError) Unwrap() error { return e.err }
+
+func unplaceable(err error) error {
+	return &placementError{err: err}
+}
+
+// planServer runs the capacity, allocation and egg checks of creating a
+// server and builds it, without writing anything
+func (h *Handler) planServer(c *fiber.Ctx, req *CreateServerRequest) (*serverPlan, error) {
+	ownerID, _ := middleware.GetUserID(c)
+	if err := h.checkServerLimit(c, ownerID); err != nil {
+		return nil, err
+	}
+
+	var egg entities.Egg
+	if err := h.db.Preload("Variables").Where("id = ?", req.EggID).First(&egg).Error; err != nil {
+		return nil, services.ErrEggNotFound
+	}
+
+	// Resources left out of the request get the egg's recommended values
+	if err := services.ApplyEggLimits(egg.Limits, &req.Memory, &req.Disk, &req.CPU); err != nil {
+		return nil, apperror.New(http.StatusBadRequest, "server.resource_out_of_bounds", err.Error())
+	}
+
+	// Sub-users may not exceed the limits set by their reseller
+	if err := services.CheckQuota(c.UserContext(), database.NewUserQuotaRepository(h.db), database.NewServerRepository(h.db),
+		ownerID, int64(req.Memory), int64(req.Disk), req.CPU); err != nil {
+		return nil, err
+	}
+
+	if req.AutoPlace {
+		placed, err := h.placeServer(req.LocationID, int64(req.Memory), int64(req.Disk))
+		if err != nil {
+			return nil, err
+		}
+		req.NodeID = placed.ID.String()
+	}
+
+	var node entities.Node
+	if err := h.db.Where("id = ?", req.NodeID).First(&node).Error; err != nil {
+		return nil, apperror.New(http.StatusBadRequest, "node.not_found", "Node not found")
+	}
+
+	if node.MaintenanceMode {
+		return nil, unplaceable(apperror.New(http.StatusConflict, "node.maintenance", "Node is in maintenance mode"))
+	}
+
+	impact, err := nodeCapacity(h.db, &node, int64(req.Memory), int64(req.Disk))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := services.ValidateCPUSet(req.CPUSet, &node); err != nil {
+		return nil, unplaceable(err)
+	}
+	if err := services.ValidateSwap(req.Swap, &node); err != nil {
+		return nil, unplaceable(err)
+	}
+
+	dockerImage, err := services.SelectEggImage(&egg, req.DockerImage)
+	if err != nil {
+		return nil, err
+	}
+
+	eggVars := make([]*entities.EggVariable, len(egg.Variables))
+	for i := range egg.Variables {
+		eggVars[i] = &egg.Variables[i]
+	}
+	variables, environment, err := services.ResolveEggVariables(eggVars, req.Environment)
+	if err != nil {
+		return nil, apperror.New(http.StatusBadRequest, "server.invalid_variables", err.Error())
+	}
+
+	var nodeAllocations []*entities.Allocation
+	if err := h.db.Where("node_id = ?", node.ID).Order("port").Find(&nodeAllocations).Error; err != nil {
+		return nil, unplaceable(services.ErrNoAvailableAllocation)
+	}
+	allocations, err := services.SelectAllocations(&egg, nodeAllocations, req.Allocation)
+	if err != nil {
+		return nil, unplaceable(err)
+	}
+
+	server := &entities.Server{
+		UUID:         uuid.New().String(),
+		Name:         req.Name,
+		Description:  req.Description,
+		Status:       entities.ServerStatusStopped,
+		OwnerID:      ownerID,
+		NodeID:       node.ID,
+		AllocationID: allocations[0].ID,
+		GameID:       egg.GameID,
+		EggID:        egg.ID,
+		EggVersion:   egg.Version,
+		DockerImage:  dockerImage,
+		MemoryLimit:  int64(req.Memory),
+		SwapLimit:    req.Swap,
+		DiskLimit:    int64(req.Disk),
+		CPULimit:     req.CPU,
+		CPUSet:       req.CPUSet,
+		NetworkIn:    req.NetworkIn,
+		NetworkOut:   req.NetworkOut,
+		BackupLimit:  h.settings.Int(c.UserContext(), database.SettingDefaultBackupLimit),
+		NetworkMode:  req.NetworkMode,
+		Tags:         []string{},
+	}
+
+	startup := services.NewStartupBuilder(&egg, server, allocations, environment).Build()
+	server.StartupCmd = startup.Command
+	server.Environment = startup.Environment
+
+	return &serverPlan{
+		Node:        &node,
+		Egg:         &egg,
+		Server:      server,
+		Allocations: allocations,
+		Variables:   variables,
+		Impact:      impact,
+	}, nil
+}
+
+// nodeCapacity checks that a server of memory and disk MB fits on a node next
+// to the servers already on it, and returns the impact of adding it
+func nodeCapacity(db *gorm.DB, node *entities.Node, memory, disk int64) (PlacementImpact, error) {
+	usage, err := usageOf(db, node.ID)
+	if err != nil {
+		return PlacementImpact{}, err
+	}
+
+	impact := PlacementImpact{
+		MemoryTotal:  node.MemoryTotal,
+		MemoryBefore: usage.Memory,
+		MemoryAfter:  usage.Memory + memory,
+		DiskTotal:    node.DiskTotal,
+		DiskBefore:   usage.Disk,
+		DiskAfter:    usage.Disk + disk,
+	}
+	if impact.MemoryAfter > node.MemoryTotal {
+		return impact, unplaceable(apperror.New(http.StatusBadRequest, "node.insufficient_memory", "Insufficient memory on node"))
+	}
+	if impact.DiskAfter > node.DiskTotal {
+		return impact, unplaceable(apperror.New(http.StatusBadRequest, "node.insufficient_disk", "Insufficient disk space on node"))
+	}
+	return impact, nil
+}
+
+// createServerVariables stores the resolved egg variables of a new server
+func createServerVariables(tx *gorm.DB, serverID uuid.UUID, variables []services.ResolvedVariable) error {
+	for _, v := range variables {
+		if err := tx.Create(&entities.ServerVariable{
+			ServerID:      serverID,
+			EggVariableID: v.Variable.ID,
+			Value:         v.Value,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usageOf sums the limits of the servers on a node. Capacity checks count
+// usage from the servers themselves rather than the node's allocated
+// counters, so they cannot drift.
+func usageOf(db *gorm.DB, nodeID uuid.UUID) (nodeUsage, error) {
+	usage := nodeUsage{NodeID: nodeID}
+	err := db.Model(&entities.Server{}).
+		Select("COUNT(*) AS servers, COALESCE(SUM(memory_limit), 0) AS memory, COALESCE(SUM(disk_limit), 0) AS disk, COALESCE(SUM(cpu_limit), 0) AS cpu").
+		Where("node_id = ?", nodeID).
+		Scan(&usage).Error
+	return usage, err
+}
+
+// placeServer picks the node of a location a new server is placed on, by the
+// configured placement strategy. Offline nodes, nodes in maintenance and
+// nodes without room for the server are never picked.
+func (h *Handler) placeServer(locationID string, memory, disk int64) (*entities.Node, error) {
+	var nodes []*entities.Node
+	if err := h.db.Scopes(database.NotTrashed).Where("location_id = ?", locationID).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		var location entities.Location
+		if err := h.db.Where("id = ?", locationID).First(&location).Error; err != nil {
+			return nil, services.ErrLocationNotFound
+		}
+		return nil, unplaceable(services.ErrNoAvailableNode)
+	}
+
+	// Count usage as the capacity check of planServer does, from the limits
+	// of the servers on each node
+	ids := make([]uuid.UUID, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	var usage []struct {
+		NodeID uuid.UUID
+		Memory int64
+		Disk   int64
+	}
+	if err := h.db.Model(&entities.Server{}).
+		Select("node_id, COALESCE(SUM(memory_limit), 0) AS memory, COALESCE(SUM(disk_limit), 0) AS disk").
+		Where("node_id IN ?", ids).
+		Group("node_id").
+		Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		node.MemoryAllocated, node.DiskAllocated = 0, 0
+		for _, u := range usage {
+			if u.NodeID == node.ID {
+				node.MemoryAllocated, node.DiskAllocated = u.Memory, u.Disk
+			}
+		}
+	}
+
+	// planServer does not overallocate, so neither does placement
+	for _, node := range h.placer.Order(nodes, memory, disk) {
+		if node.MemoryAllocated+memory <= node.MemoryTotal && node.DiskAllocated+disk <= node.DiskTotal {
+			return node, nil
+		}
+	}
+	return nil, unplaceable(services.ErrNoAvailableNode)
+}
+
+// PlanServer previews creating a server: the node and allocations it would
+// get and its effect on the node's resources, or why it does not fit. It
+// runs the same checks as CreateServer but writes nothing.
+func (h *Handler) PlanServer(c *fiber.Ctx) error {
+	var req CreateServerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	plan, err := h.planServer(c, &req)
+	var unfit *placementError
+	if errors.As(err, &unfit) {
+		return c.JSON(fiber.Map{
+			"data": fiber.Map{
+				"fits":   false,
+				"reason": placementReason(unfit.err),
+			},
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"fits":         true,
+			"node":         plan.Node,
+			"allocations":  plan.Allocations,
+			"docker_image": plan.Server.DockerImage,
+			"startup_cmd":  plan.Server.StartupCmd,
+			"impact":       plan.Impact,
+		},
+	})
+}
+
+// placementReason is the message shown for a placement failure
+func placementReason(err error) string {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	return err.Error()
+}
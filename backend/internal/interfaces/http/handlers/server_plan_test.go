@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestPlanServerMatchesCreate(t *testing.T) {
+	db := newTestDB(t, &entities.Location{}, &entities.Node{}, &entities.Server{}, &entities.ServerVariable{},
+		&entities.Egg{}, &entities.EggVariable{}, &entities.Allocation{}, &entities.UserQuota{}, &entities.Setting{})
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	location := &entities.Location{ID: uuid.New(), ShortCode: "eu", Name: "Europe"}
+	full := &entities.Node{ID: uuid.New(), Name: "full", LocationID: location.ID, FQDN: "full.example.com", IsOnline: true, MemoryTotal: 2048, DiskTotal: 100000}
+	roomy := &entities.Node{ID: uuid.New(), Name: "roomy", LocationID: location.ID, FQDN: "roomy.example.com", IsOnline: true, MemoryTotal: 16384, DiskTotal: 100000}
+	// Already hosting a server that leaves no room on the full node
+	existing := &entities.Server{ID: uuid.New(), UUID: "existing", Name: "existing", NodeID: full.ID, OwnerID: uuid.New(), MemoryLimit: 1536, DiskLimit: 10000}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "game", StartupCommand: "./run --world {{WORLD}} --port {{SERVER_PORT}}", DockerImages: []string{"game:1"}, Version: 1}
+	variable := &entities.EggVariable{ID: uuid.New(), EggID: egg.ID, Name: "World", EnvVariable: "WORLD", DefaultValue: "survival"}
+	rows := []interface{}{location, full, roomy, existing, egg, variable}
+	for _, node := range []*entities.Node{full, roomy} {
+		for _, port := range []int{25565, 25566} {
+			rows = append(rows, &entities.Allocation{ID: uuid.New(), NodeID: node.ID, IP: "10.0.0.1", Port: port})
+		}
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{}
+	h := &Handler{cfg: cfg, db: db, redis: rdb, validator: middleware.NewValidator(),
+		settings: database.NewSettings(db, rdb), placer: services.NewNodePlacer(string(services.PlacementMostFree))}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, uuid.New())
+		c.Locals(middleware.RoleNameKey, "admin")
+		return c.Next()
+	})
+	app.Post("/servers/plan", h.PlanServer)
+	app.Post("/servers", h.CreateServer)
+	post := func(path string, body fiber.Map) (int, map[string]json.RawMessage) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, decoded.Data
+	}
+	type stored struct {
+		Servers     int64
+		Variables   int64
+		Allocations int64
+	}
+	count := func() stored {
+		t.Helper()
+		var s stored
+		db.Model(&entities.Server{}).Count(&s.Servers)
+		db.Model(&entities.ServerVariable{}).Count(&s.Variables)
+		db.Model(&entities.Allocation{}).Where("server_id IS NOT NULL").Count(&s.Allocations)
+		return s
+	}
+
+	request := fiber.Map{
+		"name": "survival", "auto_place": true, "location_id": location.ID.String(), "egg_id": egg.ID.String(),
+		"memory": 1024, "disk": 10000, "cpu": 100,
+	}
+	status, plan := post("/servers/plan", request)
+	if status != http.StatusOK || string(plan["fits"]) != "true" {
+		t.Fatalf("plan = %d %s", status, plan["reason"])
+	}
+	var planned struct {
+		Node        entities.Node
+		Allocations []entities.Allocation
+		StartupCmd  string
+		Impact      PlacementImpact
+	}
+	_ = json.Unmarshal(plan["node"], &planned.Node)
+	_ = json.Unmarshal(plan["allocations"], &planned.Allocations)
+	_ = json.Unmarshal(plan["startup_cmd"], &planned.StartupCmd)
+	_ = json.Unmarshal(plan["impact"], &planned.Impact)
+	if planned.Node.ID != roomy.ID || len(planned.Allocations) != 1 || planned.Allocations[0].Port != 25565 {
+		t.Errorf("planned %s with %+v, want roomy on 25565", planned.Node.Name, planned.Allocations)
+	}
+	if want := (PlacementImpact{MemoryTotal: 16384, MemoryAfter: 1024, DiskTotal: 100000, DiskAfter: 10000}); planned.Impact != want {
+		t.Errorf("impact %+v, want %+v", planned.Impact, want)
+	}
+	if got := count(); got != (stored{Servers: 1}) {
+		t.Errorf("after planning %+v, want nothing written", got)
+	}
+
+	if status, _ := post("/servers", request); status != http.StatusCreated {
+		t.Fatalf("create = %d", status)
+	}
+	var created struct {
+		NodeID       string
+		AllocationID string
+		StartupCmd   string
+	}
+	if err := db.Model(&entities.Server{}).Select("node_id", "allocation_id", "startup_cmd").Where("name = ?", "survival").Scan(&created).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created.NodeID != planned.Node.ID.String() || created.AllocationID != planned.Allocations[0].ID.String() || created.StartupCmd != planned.StartupCmd {
+		t.Errorf("created %+v, want the plan's node %s, allocation %s and startup %q",
+			created, planned.Node.ID, planned.Allocations[0].ID, planned.StartupCmd)
+	}
+	if got := count(); got != (stored{Servers: 2, Variables: 1, Allocations: 1}) {
+		t.Errorf("after creating %+v, want the server, its variable and allocation", got)
+	}
+
+	// A server that fits nowhere is reported with the reason
+	request["memory"] = 32768
+	status, plan = post("/servers/plan", request)
+	if status != http.StatusOK || string(plan["fits"]) != "false" || len(plan["reason"]) == 0 {
+		t.Errorf("oversized plan = %d %v, want it not to fit with a reason", status, plan)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AddServerTagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1"`
+}
+
+// AddServerTags adds tags to a server. Tags are lowercased and duplicates
+// dropped.
+func (h *Handler) AddServerTags(c *fiber.Ctx) error {
+	var req AddServerTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := services.MergeTags(server.Tags, req.Tags)
+	if err != nil {
+		return err
+	}
+	return h.saveServerTags(c, server, tags)
+}
+
+// RemoveServerTag removes a tag from a server
+func (h *Handler) RemoveServerTag(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	return h.saveServerTags(c, server, services.RemoveTag(server.Tags, c.Params("tag")))
+}
+
+func (h *Handler) saveServerTags(c *fiber.Ctx, server *entities.Server, tags []string) error {
+	server.Tags = tags
+	if err := h.db.Model(server).Select("tags").Updates(server).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update tags",
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"tags": tags},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data": tags,
+	})
+}
+
+// filterByTags limits a server query to servers carrying every tag given as
+// ?tag=, which may be repeated. Tags that cannot exist match nothing.
+func filterByTags(c *fiber.Ctx, query *gorm.DB) *gorm.DB {
+	raw := c.Context().QueryArgs().PeekMulti("tag")
+	if len(raw) == 0 {
+		return query
+	}
+
+	tags := make([]string, 0, len(raw))
+	for _, r := range raw {
+		tag, err := services.NormalizeTag(string(r))
+		if err != nil {
+			return query.Where("1 = 0")
+		}
+		tags = append(tags, tag)
+	}
+	filter, _ := json.Marshal(tags)
+	return query.Where("tags @> ?::jsonb", string(filter))
+}
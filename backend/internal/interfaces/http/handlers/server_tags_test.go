@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestServerTags(t *testing.T) {
+	db := newTestDB(t, &entities.Server{}, &entities.AuditLog{})
+	owner := uuid.New()
+	server := &entities.Server{ID: uuid.New(), UUID: "survival", Name: "survival", NodeID: uuid.New(), OwnerID: owner, Tags: []string{}}
+	if err := db.Create(server).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{cfg: &config.Config{}, db: db, validator: middleware.NewValidator()}
+	var handlerErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		handlerErr = err
+		return c.SendStatus(http.StatusBadRequest)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, owner)
+		c.Locals(middleware.RoleNameKey, "user")
+		return c.Next()
+	})
+	app.Post("/servers/:id/tags", h.AddServerTags)
+	app.Delete("/servers/:id/tags/:tag", h.RemoveServerTag)
+	path := "/servers/" + server.ID.String() + "/tags"
+	send := func(method, path string, body interface{}) int {
+		t.Helper()
+		handlerErr = nil
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	stored := func() []string {
+		t.Helper()
+		var raw string
+		if err := db.Model(&entities.Server{}).Select("tags").Where("id = ?", server.ID).Scan(&raw).Error; err != nil {
+			t.Fatal(err)
+		}
+		var tags []string
+		if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+			t.Fatal(err)
+		}
+		return tags
+	}
+
+	// Tags are normalized and kept once
+	if status := send(http.MethodPost, path, fiber.Map{"tags": []string{"Production", "Game Night", "production"}}); status != http.StatusOK {
+		t.Fatalf("add = %d %v", status, handlerErr)
+	}
+	if got := stored(); !slices.Equal(got, []string{"production", "game-night"}) {
+		t.Errorf("tags %v, want production and game-night", got)
+	}
+
+	if status := send(http.MethodDelete, path+"/PRODUCTION", nil); status != http.StatusOK {
+		t.Fatalf("remove = %d %v", status, handlerErr)
+	}
+	if got := stored(); !slices.Equal(got, []string{"game-night"}) {
+		t.Errorf("tags %v after removing production, want game-night", got)
+	}
+
+	// The limit counts the tags already on the server
+	var many []string
+	for i := range services.MaxServerTags {
+		many = append(many, fmt.Sprintf("tag-%d", i))
+	}
+	send(http.MethodPost, path, fiber.Map{"tags": many})
+	if !errors.Is(handlerErr, services.ErrTooManyTags) {
+		t.Errorf("adding past the limit = %v, want %v", handlerErr, services.ErrTooManyTags)
+	}
+	send(http.MethodPost, path, fiber.Map{"tags": []string{"no/slashes"}})
+	if !errors.Is(handlerErr, services.ErrInvalidTag) {
+		t.Errorf("adding an invalid tag = %v, want %v", handlerErr, services.ErrInvalidTag)
+	}
+	if got := stored(); !slices.Equal(got, []string{"game-night"}) {
+		t.Errorf("tags %v after rejected adds, want them unchanged", got)
+	}
+	if status := send(http.MethodPost, path, fiber.Map{"tags": many[1:]}); status != http.StatusOK {
+		t.Errorf("adding up to the limit = %d %v", status, handlerErr)
+	}
+	if got := stored(); len(got) != services.MaxServerTags {
+		t.Errorf("%d tags stored, want %d", len(got), services.MaxServerTags)
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	db := newTestDB(t)
+	for query, want := range map[string]struct {
+		sql  string
+		vars []interface{}
+	}{
+		"":                                 {"SELECT * FROM `servers`", nil},
+		"?tag=Production":                  {"SELECT * FROM `servers` WHERE tags @> ?::jsonb", []interface{}{`["production"]`}},
+		"?tag=production&tag=Game%20Night": {"SELECT * FROM `servers` WHERE tags @> ?::jsonb", []interface{}{`["production","game-night"]`}},
+		// A tag no server can carry matches nothing
+		"?tag=no/slashes": {"SELECT * FROM `servers` WHERE 1 = 0", nil},
+	} {
+		var stmt *gorm.Statement
+		app := fiber.New()
+		app.Get("/servers", func(c *fiber.Ctx) error {
+			query := filterByTags(c, db.Session(&gorm.Session{DryRun: true}).Table("servers"))
+			stmt = query.Find(&[]entities.Server{}).Statement
+			return nil
+		})
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers"+query, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if stmt.SQL.String() != want.sql || !slices.Equal(stmt.Vars, want.vars) {
+			t.Errorf("%q: %s %v, want %s %v", query, stmt.SQL.String(), stmt.Vars, want.sql, want.vars)
+		}
+	}
+}
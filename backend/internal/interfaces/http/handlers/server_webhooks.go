@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxServerWebhooks is how many webhooks a server may have
+const maxServerWebhooks = 5
+
+type ServerWebhookRequest struct {
+	URL      string   `json:"url" validate:"required,http_url,max=500"`
+	Secret   *string  `json:"secret" validate:"omitempty,max=100"` // Omit on update to keep the current secret
+	Events   []string `json:"events" validate:"required,min=1,dive,oneof=server.started server.stopped server.crashed backup.completed backup.failed *"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// GetServerWebhooks returns the webhooks of a server
+func (h *Handler) GetServerWebhooks(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks := make([]entities.ServerWebhook, 0)
+	if err := h.db.Where("server_id = ?", server.ID).Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhooks",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   webhooks,
+		"events": entities.ServerWebhookEvents,
+	})
+}
+
+// CreateServerWebhook registers a webhook for the state transitions of a server
+func (h *Handler) CreateServerWebhook(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	var req ServerWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+	if err := services.CheckWebhookURL(c.UserContext(), req.URL); err != nil {
+		return middleware.ValidationFailed(c, []middleware.FieldError{{Field: "url", Rule: "public_url", Message: err.Error()}})
+	}
+
+	var count int64
+	h.db.Model(&entities.ServerWebhook{}).Where("server_id = ?", server.ID).Count(&count)
+	if count >= maxServerWebhooks {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Webhook limit reached for this server",
+		})
+	}
+
+	webhook := entities.ServerWebhook{
+		ServerID: server.ID,
+		URL:      req.URL,
+		Events:   req.Events,
+		IsActive: true,
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Create(&webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create webhook",
+		})
+	}
+	h.auditServerWebhook(c, entities.AuditActionCreate, &webhook)
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": webhook,
+	})
+}
+
+// UpdateServerWebhook replaces the settings of a server webhook
+func (h *Handler) UpdateServerWebhook(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	var req ServerWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+	if err := services.CheckWebhookURL(c.UserContext(), req.URL); err != nil {
+		return middleware.ValidationFailed(c, []middleware.FieldError{{Field: "url", Rule: "public_url", Message: err.Error()}})
+	}
+
+	webhook, err := h.findServerWebhook(c, server)
+	if err != nil {
+		return err
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update webhook",
+		})
+	}
+	h.auditServerWebhook(c, entities.AuditActionUpdate, webhook)
+
+	return c.JSON(fiber.Map{
+		"data": webhook,
+	})
+}
+
+// DeleteServerWebhook removes a server webhook and its delivery log
+func (h *Handler) DeleteServerWebhook(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+	webhook, err := h.findServerWebhook(c, server)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.Where("webhook_id = ?", webhook.ID).Delete(&entities.ServerWebhookDelivery{}).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+	if err := h.db.Delete(webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+	h.auditServerWebhook(c, entities.AuditActionDelete, webhook)
+
+	return c.Status(http.StatusNoContent).Send(nil)
+}
+
+// GetServerWebhookDeliveries returns a page of a server webhook's delivery
+// log, newest first
+func (h *Handler) GetServerWebhookDeliveries(c *fiber.Ctx) error {
+	params := pageParams(c, 25, 100)
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+	webhook, err := h.findServerWebhook(c, server)
+	if err != nil {
+		return err
+	}
+
+	db := h.db.Model(&entities.ServerWebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+
+	deliveries := make([]entities.ServerWebhookDelivery, 0, params.PageSize)
+	total, err := database.Paginate(db, params, "created_at DESC", &deliveries)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch deliveries",
+		})
+	}
+
+	return c.JSON(paginated(deliveries, params, total))
+}
+
+// findServerWebhook loads the webhook named by the request from a server's webhooks
+func (h *Handler) findServerWebhook(c *fiber.Ctx, server *entities.Server) (*entities.ServerWebhook, error) {
+	var webhook entities.ServerWebhook
+	if err := h.db.Where("id = ? AND server_id = ?", c.Params("webhookId"), server.ID).First(&webhook).Error; err != nil {
+		return nil, services.ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+func (h *Handler) auditServerWebhook(c *fiber.Ctx, action entities.AuditAction, webhook *entities.ServerWebhook) {
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     action,
+		Resource:   "server_webhook",
+		ResourceID: &webhook.ID,
+		Metadata:   map[string]interface{}{"server_id": webhook.ServerID, "url": webhook.URL, "events": webhook.Events},
+		IPAddress:  c.IP(),
+	})
+}
@@ -1,45 +1,93 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/apperror"
 	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var errServerAccessDenied = errors.New("access denied")
+
 type CreateServerRequest struct {
 	Name        string `json:"name" validate:"required,min=1,max=100"`
 	Description string `json:"description" validate:"max=500"`
-	NodeID      string `json:"node_id" validate:"required,uuid"`
-	Game        string `json:"game" validate:"required,oneof=minecraft rust csgo gmod ark valheim"`
-	GameVersion string `json:"game_version" validate:"required"`
-	Memory      int    `json:"memory" validate:"required,min=128"`
-	Disk        int    `json:"disk" validate:"required,min=512"`
-	CPU         int    `json:"cpu" validate:"required,min=50,max=400"`
+	NodeID      string `json:"node_id" validate:"required_unless=AutoPlace true,omitempty,uuid"`
+	AutoPlace   bool   `json:"auto_place"`                                                       // Pick a node of LocationID by the placement strategy instead
+	LocationID  string `json:"location_id" validate:"required_if=AutoPlace true,omitempty,uuid"` // Location to place in when AutoPlace is set
+	EggID       string `json:"egg_id" validate:"required,uuid"`
+	DockerImage string `json:"docker_image"`                        // Defaults to the egg's first image
+	Memory      int    `json:"memory" validate:"omitempty,min=128"` // Omit for the egg's recommended memory, as for disk and cpu
+	Swap        int64  `json:"swap" validate:"min=-1"`              // MB beyond memory, -1 for unlimited
+	Disk        int    `json:"disk" validate:"omitempty,min=512"`
+	CPU         int    `json:"cpu" validate:"omitempty,min=50,max=400"`
+	CPUSet      string `json:"cpu_set"` // Cores to pin to, e.g. "0-3,8"; empty for quota only
+	NetworkMode string `json:"network_mode" validate:"omitempty,oneof=node isolated"`
+
+	// Bandwidth caps in bytes/s between 8 KB/s and 10 Gbit/s, 0 for unlimited
+	NetworkIn  int64 `json:"network_in" validate:"omitempty,min=8192,max=1250000000"`
+	NetworkOut int64 `json:"network_out" validate:"omitempty,min=8192,max=1250000000"`
+
+	Environment map[string]string `json:"environment"` // Values for the egg's variables
+
+	Allocation services.AllocationPreferences `json:"allocation"`
 }
 
+// UpdateServerRequest is a partial update, omitted fields are left unchanged
 type UpdateServerRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=100"`
-	Description string `json:"description" validate:"max=500"`
-	Memory      int    `json:"memory" validate:"required,min=128"`
-	Disk        int    `json:"disk" validate:"required,min=512"`
-	CPU         int    `json:"cpu" validate:"required,min=50,max=400"`
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Memory      *int64  `json:"memory" validate:"omitempty,min=128"`
+	Swap        *int64  `json:"swap" validate:"omitempty,min=-1"` // MB beyond memory, -1 for unlimited
+	Disk        *int64  `json:"disk" validate:"omitempty,min=512"`
+	CPU         *int    `json:"cpu" validate:"omitempty,min=50,max=400"`
+	CPUSet      *string `json:"cpu_set"`      // Cores to pin to, empty for quota only
+	DockerImage *string `json:"docker_image"` // Must be one of the egg's images
+	NetworkIn   *int64  `json:"network_in" validate:"omitempty,eq=0|min=8192,max=1250000000"`
+	NetworkOut  *int64  `json:"network_out" validate:"omitempty,eq=0|min=8192,max=1250000000"`
+	Version     int     `json:"version" validate:"required,min=1"` // Version the client last read
 }
 
-// GetServers returns all servers
+// GetServers returns a page of servers
 func (h *Handler) GetServers(c *fiber.Ctx) error {
-	var servers []entities.Server
-	
-	if err := h.db.Preload("Node").Preload("Node.Location").Find(&servers).Error; err != nil {
+	params := pageParams(c, 50, repositories.MaxPageSize)
+	servers := make([]entities.Server, 0, params.PageSize)
+
+	query := h.db.Model(&entities.Server{})
+
+	// Resellers only see servers within their own sub-tree
+	if role, _ := middleware.GetRoleName(c); role == "reseller" {
+		userID, _ := middleware.GetUserID(c)
+		tree, err := h.resellers.TreeIDs(c.UserContext(), userID)
+		if err != nil {
+			return err
+		}
+		query = query.Where("owner_id IN ?", tree)
+	}
+	query = filterByTags(c, query)
+
+	total, err := database.Paginate(query, params, "created_at DESC", &servers, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Node").Preload("Node.Location")
+	})
+	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch servers",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"data": servers,
-	})
+	return c.JSON(paginated(servers, params, total))
 }
 
 // CreateServer creates a new game server
@@ -52,73 +100,63 @@ func (h *Handler) CreateServer(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
 	}
 
-	// Check if node exists
-	var node entities.Node
-	if err := h.db.Where("id = ?", req.NodeID).First(&node).Error; err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Node not found",
-		})
+	plan, err := h.planServer(c, &req)
+	if err != nil {
+		return err
 	}
-
-	// Check node resources
-	var totalMemory, totalDisk int
-	h.db.Model(&entities.Server{}).Where("node_id = ?", req.NodeID).Select("COALESCE(SUM(memory), 0), COALESCE(SUM(disk), 0)").Row().Scan(&totalMemory, &totalDisk)
-
-	if totalMemory+req.Memory > int(node.MemoryTotal) {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Insufficient memory on node",
-		})
-	}
-
-	if totalDisk+req.Disk > int(node.DiskTotal) {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Insufficient disk space on node",
-		})
+	node, server, allocations := plan.Node, plan.Server, plan.Allocations
+	allocation := allocations[0]
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(server).Error; err != nil {
+			return err
+		}
+		if err := createServerVariables(tx, server.ID, plan.Variables); err != nil {
+			return err
+		}
+		// Claim only allocations that are still free, another server may
+		// have taken one since they were selected
+		for i, alloc := range allocations {
+			result := tx.Model(alloc).Where("server_id IS NULL").Updates(map[string]interface{}{
+				"server_id":  server.ID,
+				"is_primary": i == 0,
+				"protocol":   alloc.Protocol,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return services.ErrNoAvailableAllocation
+			}
+		}
+		if req.Allocation.DedicatedIP {
+			var shared int64
+			if err := tx.Model(&entities.Allocation{}).
+				Where("node_id = ? AND ip = ? AND server_id IS NOT NULL AND server_id <> ?", node.ID, allocation.IP, server.ID).
+				Count(&shared).Error; err != nil {
+				return err
+			}
+			if shared > 0 {
+				return services.ErrNoDedicatedIP
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, services.ErrNoAvailableAllocation) || errors.Is(err, services.ErrNoDedicatedIP) {
+		return err
 	}
-
-	// Get Docker image and startup command based on game
-	dockerImage, startupCmd := getGameConfig(req.Game, req.GameVersion)
-
-	// Parse UUIDs
-	nodeUUID, _ := uuid.Parse(req.NodeID)
-	ownerUUID, _ := uuid.Parse("00000000-0000-0000-0000-000000000001") // Default admin user
-	gameUUID, _ := uuid.Parse("00000000-0000-0000-0000-000000000001") // Default game
-	eggUUID, _ := uuid.Parse("00000000-0000-0000-0000-000000000001") // Default egg
-	allocationUUID, _ := uuid.Parse("00000000-0000-0000-0000-000000000001") // Default allocation
-
-	server := entities.Server{
-		UUID:            uuid.New().String(),
-		Name:            req.Name,
-		Description:     req.Description,
-		Status:          entities.ServerStatusStopped,
-		OwnerID:         ownerUUID,
-		NodeID:          nodeUUID,
-		AllocationID:    allocationUUID,
-		GameID:          gameUUID,
-		EggID:           eggUUID,
-		DockerImage:     dockerImage,
-		StartupCmd:      startupCmd,
-		MemoryLimit:     int64(req.Memory),
-		DiskLimit:       int64(req.Disk),
-		CPULimit:        req.CPU,
-		Environment:     make(map[string]string),
-	}
-
-	if err := h.db.Create(&server).Error; err != nil {
+	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create server",
 		})
 	}
 
 	// Load relationships for response
-	h.db.Preload("Node").Preload("Node.Location").First(&server, "id = ?", server.ID)
+	h.db.Preload("Node").Preload("Node.Location").First(server, "id = ?", server.ID)
 
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
 		"data": server,
@@ -131,9 +169,7 @@ func (h *Handler) GetServer(c *fiber.Ctx) error {
 	
 	var server entities.Server
 	if err := h.db.Preload("Node").Preload("Node.Location").Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
-		})
+		return services.ErrServerNotFound
 	}
 
 	return c.JSON(fiber.Map{
@@ -143,8 +179,6 @@ func (h *Handler) GetServer(c *fiber.Ctx) error {
 
 // UpdateServer updates an existing server
 func (h *Handler) UpdateServer(c *fiber.Ctx) error {
-	id := c.Params("id")
-	
 	var req UpdateServerRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -153,31 +187,144 @@ func (h *Handler) UpdateServer(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if err := h.validator.Struct(req); err != nil {
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	found, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+	server := *found
+
+	if server.Version != req.Version {
+		return versionConflict(c, server.Version)
+	}
+
+	var node entities.Node
+	if err := h.db.Where("id = ?", server.NodeID).First(&node).Error; err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error": "Validation failed",
-			"details": err.Error(),
+			"error": "Node not found",
 		})
 	}
+	if req.CPUSet != nil {
+		if err := services.ValidateCPUSet(*req.CPUSet, &node); err != nil {
+			return err
+		}
+	}
+	if req.Swap != nil {
+		if err := services.ValidateSwap(*req.Swap, &node); err != nil {
+			return err
+		}
+	}
 
-	var server entities.Server
-	if err := h.db.Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", server.EggID).First(&egg).Error; err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Server egg not found",
 		})
 	}
+	if req.DockerImage != nil && *req.DockerImage != server.DockerImage {
+		if _, err := services.SelectEggImage(&egg, *req.DockerImage); err != nil {
+			return err
+		}
+	}
 
-	// Update server fields
-	server.Name = req.Name
-	server.Description = req.Description
-	server.MemoryLimit = int64(req.Memory)
-	server.DiskLimit = int64(req.Disk)
-	server.CPULimit = req.CPU
+	// Apply only the fields the request sets, so concurrent edits to other
+	// fields are not overwritten
+	previous := server
+	update := services.ServerUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		MemoryLimit: req.Memory,
+		SwapLimit:   req.Swap,
+		DiskLimit:   req.Disk,
+		CPULimit:    req.CPU,
+		CPUSet:      req.CPUSet,
+		DockerImage: req.DockerImage,
+		NetworkIn:   req.NetworkIn,
+		NetworkOut:  req.NetworkOut,
+	}
+	changes := update.Apply(&server)
+
+	memory := server.MemoryLimit - previous.MemoryLimit
+	disk := server.DiskLimit - previous.DiskLimit
+	cpu := server.CPULimit - previous.CPULimit
+	resized := memory != 0 || disk != 0 || cpu != 0
+	if resized {
+		if err := services.CheckEggLimits(egg.Limits, int(server.MemoryLimit), int(server.DiskLimit), server.CPULimit); err != nil {
+			return apperror.New(http.StatusBadRequest, "server.resource_out_of_bounds", err.Error())
+		}
+	}
 
-	if err := h.db.Save(&server).Error; err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update server",
+	if services.NeedsRestart(changes) && server.IsRunning() && !server.RestartRequired {
+		server.RestartRequired = true
+		changes["restart_required"] = true
+	}
+
+	if len(changes) > 0 {
+		changes["version"] = req.Version + 1
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			// Check the difference against the node's usage counted as
+			// planServer counts it, with the node locked so concurrent resizes
+			// cannot both fit
+			if resized {
+				var current entities.Node
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", server.NodeID).First(&current).Error; err != nil {
+					return err
+				}
+				usage, err := usageOf(tx, current.ID)
+				if err != nil {
+					return err
+				}
+				current.MemoryAllocated, current.DiskAllocated, current.CPUAllocated = usage.Memory, usage.Disk, int(usage.CPU)
+				if !services.ResizeFits(&current, memory, disk, cpu) {
+					return services.ErrInsufficientResources
+				}
+			}
+
+			result := tx.Model(&server).Where("version = ?", req.Version).Updates(changes)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errVersionConflict
+			}
+			return nil
 		})
+		if errors.Is(err, services.ErrInsufficientResources) {
+			return err
+		}
+		if errors.Is(err, errVersionConflict) {
+			var current entities.Server
+			h.db.Select("version").Where("id = ?", server.ID).First(&current)
+			return versionConflict(c, current.Version)
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update server",
+			})
+		}
+		server.Version = req.Version + 1
+	}
+
+	// The node pulls the new image and recreates the container on next start
+	if _, ok := changes["docker_image"]; ok {
+		if err := h.agent.UpdateServerImage(c.UserContext(), server.NodeID, server.ID, server.DockerImage); err != nil {
+			return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+				"error": "Server updated but the node could not be notified of the new image",
+			})
+		}
+	}
+
+	_, inChanged := changes["network_in"]
+	_, outChanged := changes["network_out"]
+	if inChanged || outChanged {
+		if err := h.agent.UpdateServerBandwidth(c.UserContext(), server.NodeID, server.ID, server.NetworkIn, server.NetworkOut); err != nil {
+			return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+				"error": "Server updated but the node could not apply the bandwidth limits",
+			})
+		}
 	}
 
 	// Load relationships for response
@@ -194,9 +341,7 @@ func (h *Handler) DeleteServer(c *fiber.Ctx) error {
 	
 	var server entities.Server
 	if err := h.db.Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
-		})
+		return services.ErrServerNotFound
 	}
 
 	if server.Status == entities.ServerStatusRunning {
@@ -205,6 +350,13 @@ func (h *Handler) DeleteServer(c *fiber.Ctx) error {
 		})
 	}
 
+	// Keep the server while databases remain, so a retry can drop them
+	if err := h.databases.DeleteAllForServer(c.UserContext(), server.ID); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to drop server databases",
+		})
+	}
+
 	if err := h.db.Delete(&server).Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete server",
@@ -216,95 +368,267 @@ func (h *Handler) DeleteServer(c *fiber.Ctx) error {
 
 // StartServer starts a server
 func (h *Handler) StartServer(c *fiber.Ctx) error {
-	id := c.Params("id")
-	
-	var server entities.Server
-	if err := h.db.Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
-		})
-	}
+	return h.powerSingle(c, services.PowerActionStart, entities.ActivityServerStart, "Server start command sent")
+}
 
-	if server.Status == entities.ServerStatusRunning {
-		return c.Status(http.StatusConflict).JSON(fiber.Map{
-			"error": "Server is already running",
-		})
-	}
+// StopServer stops a server
+func (h *Handler) StopServer(c *fiber.Ctx) error {
+	return h.powerSingle(c, services.PowerActionStop, entities.ActivityServerStop, "Server stop command sent")
+}
 
-	// TODO: Send start command to Wings daemon
-	server.Status = entities.ServerStatusStarting
-	h.db.Save(&server)
+// RestartServer restarts a server
+func (h *Handler) RestartServer(c *fiber.Ctx) error {
+	return h.powerSingle(c, services.PowerActionRestart, entities.ActivityServerRestart, "Server restart command sent")
+}
+
+// powerSingle sends a power action to the server of the route the way bulk
+// actions do, so starts and restarts confirm scheduled image rollouts
+func (h *Handler) powerSingle(c *fiber.Ctx, action services.PowerAction, activity, message string) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+	if err := h.powerServer(c.UserContext(), server, action); err != nil {
+		return err
+	}
+	h.recordActivity(c, server.ID, activity, "")
 
 	return c.JSON(fiber.Map{
-		"message": "Server start command sent",
+		"message": message,
 		"data":    server,
 	})
 }
 
-// StopServer stops a server
-func (h *Handler) StopServer(c *fiber.Ctx) error {
-	id := c.Params("id")
-	
-	var server entities.Server
-	if err := h.db.Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
-		})
+// GetCommandHistory returns the console commands the current user recently sent to a server
+func (h *Handler) GetCommandHistory(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
 	}
 
-	if server.Status == entities.ServerStatusStopped {
-		return c.Status(http.StatusConflict).JSON(fiber.Map{
-			"error": "Server is already stopped",
+	userID, _ := middleware.GetUserID(c)
+
+	commands, err := h.history.Recent(c.UserContext(), server.ID, userID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch command history",
 		})
 	}
 
-	// TODO: Send stop command to Wings daemon
-	server.Status = entities.ServerStatusStopping
-	h.db.Save(&server)
+	return c.JSON(fiber.Map{
+		"data": commands,
+	})
+}
+
+type ReinstallServerRequest struct {
+	WipeData     bool  `json:"wipe_data"`
+	SafetyBackup *bool `json:"safety_backup"` // Back up the server first, omit for the panel setting
+}
+
+// ReinstallServer reruns the install process of a server, optionally wiping
+// its data first. A safety backup is taken before anything is touched, and
+// the reinstall is abandoned when it fails.
+func (h *Handler) ReinstallServer(c *fiber.Ctx) error {
+	var req ReinstallServerRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if err := h.servers.Reinstall(c.UserContext(), server.ID, userID, req.WipeData, h.wantsSafetyBackup(c, req.SafetyBackup)); err != nil {
+		return err
+	}
+	server.Status = entities.ServerStatusInstalling
 
 	return c.JSON(fiber.Map{
-		"message": "Server stop command sent",
+		"message": "Server reinstall started",
 		"data":    server,
 	})
 }
 
-// RestartServer restarts a server
-func (h *Handler) RestartServer(c *fiber.Ctx) error {
-	id := c.Params("id")
-	
-	var server entities.Server
-	if err := h.db.Where("id = ?", id).First(&server).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "Server not found",
+type BulkPowerRequest struct {
+	ServerIDs []string `json:"server_ids" validate:"omitempty,max=500,dive,uuid"`
+	NodeID    string   `json:"node_id" validate:"omitempty,uuid"`
+	Action    string   `json:"action" validate:"required,oneof=start stop restart kill"`
+}
+
+// BulkPowerServers applies a power action to a selection of servers or every server on a node
+func (h *Handler) BulkPowerServers(c *fiber.Ctx) error {
+	var req BulkPowerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	if len(req.ServerIDs) == 0 && req.NodeID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either server_ids or node_id is required",
+		})
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	isAdmin := middleware.IsAdmin(c)
+	action := services.PowerAction(req.Action)
+	ctx := c.UserContext()
+
+	query := h.db.WithContext(ctx).Model(&entities.Server{})
+	if len(req.ServerIDs) > 0 {
+		query = query.Where("id IN ?", req.ServerIDs)
+	}
+	if req.NodeID != "" {
+		query = query.Where("node_id = ?", req.NodeID)
+		// Node wide actions only reach the caller's own servers unless admin
+		if !isAdmin {
+			query = query.Where("owner_id = ?", userID)
+		}
+	}
+
+	var servers []entities.Server
+	if err := query.Find(&servers).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch servers",
 		})
 	}
 
-	// TODO: Send restart command to Wings daemon
-	server.Status = entities.ServerStatusRestarting
-	h.db.Save(&server)
+	byID := make(map[uuid.UUID]*entities.Server, len(servers))
+	ids := make([]uuid.UUID, 0, len(servers))
+	for i := range servers {
+		byID[servers[i].ID] = &servers[i]
+		ids = append(ids, servers[i].ID)
+	}
+
+	// Requested servers that do not exist are still reported
+	for _, raw := range req.ServerIDs {
+		id := uuid.MustParse(raw)
+		if _, ok := byID[id]; !ok && req.NodeID == "" {
+			ids = append(ids, id)
+		}
+	}
+
+	batchID := uuid.New()
+	ip := c.IP()
+	results := services.RunBulk(ctx, ids, h.cfg.Agents.BulkConcurrency, func(ctx context.Context, id uuid.UUID) error {
+		server, ok := byID[id]
+		if !ok {
+			return services.ErrServerNotFound
+		}
+		if !server.ManageableBy(userID, isAdmin) {
+			return errServerAccessDenied
+		}
+		if err := h.powerServer(ctx, server, action); err != nil {
+			return err
+		}
+
+		h.db.Create(&entities.AuditLog{
+			UserID:     &userID,
+			Action:     entities.AuditAction(action),
+			Resource:   "server",
+			ResourceID: &server.ID,
+			Metadata:   map[string]interface{}{"batch_id": batchID},
+			IPAddress:  ip,
+		})
+		h.db.Create(&entities.ActivityLog{
+			UserID:    userID,
+			ServerID:  &server.ID,
+			Action:    "server." + string(action),
+			Details:   "Bulk action",
+			IPAddress: ip,
+		})
+		return nil
+	})
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditAction(action),
+		Resource:    "server",
+		Description: fmt.Sprintf("Bulk %s of %d servers", action, len(results)),
+		Metadata: map[string]interface{}{
+			"batch_id":  batchID,
+			"node_id":   req.NodeID,
+			"succeeded": succeeded,
+			"failed":    len(results) - succeeded,
+		},
+		IPAddress: ip,
+	})
 
 	return c.JSON(fiber.Map{
-		"message": "Server restart command sent",
-		"data":    server,
+		"batch_id":  batchID,
+		"action":    action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"data":      results,
 	})
 }
 
-// getGameConfig returns Docker image and startup command for a game
-func getGameConfig(game, version string) (string, string) {
-	switch game {
-	case "minecraft":
-		return "ghcr.io/pterodactyl/yolks:java_17", "java -Xms128M -Xmx{{SERVER_MEMORY}}M -jar {{SERVER_JARFILE}}"
-	case "rust":
-		return "ghcr.io/pterodactyl/games:rust", "./RustDedicated -batchmode +server.port {{SERVER_PORT}} +server.identity \"rust\" +rcon.port {{RCON_PORT}} +rcon.web true +server.hostname \"{{SERVER_NAME}}\" +server.level \"{{LEVEL}}\" +server.description \"{{DESCRIPTION}}\" +server.url \"{{SERVER_URL}}\" +server.headerimage \"{{SERVER_IMG}}\" +server.logoimage \"{{SERVER_LOGO}}\" +server.maxplayers {{MAX_PLAYERS}} +rcon.password \"{{RCON_PASS}}\" +server.saveinterval {{SAVE_INTERVAL}} {{ADDITIONAL_ARGS}}"
-	case "csgo":
-		return "ghcr.io/pterodactyl/games:source", "./srcds_run -game csgo -console -usercon +game_type 0 +game_mode 1 +mapgroup mg_active +map de_dust2 -tickrate {{TICKRATE}} -port {{SERVER_PORT}} +rcon_password \"{{RCON_PASSWORD}}\" +hostname \"{{HOSTNAME}}\" +sv_password \"{{SERVER_PASSWORD}}\""
-	case "gmod":
-		return "ghcr.io/pterodactyl/games:source", "./srcds_run -game garrysmod -console -usercon +gamemode {{GAMEMODE}} +map {{MAP}} -tickrate {{TICKRATE}} -port {{SERVER_PORT}} +hostname \"{{HOSTNAME}}\" +rcon_password \"{{RCON_PASSWORD}}\" +sv_password \"{{SERVER_PASSWORD}}\""
-	case "ark":
-		return "ghcr.io/pterodactyl/games:ark", "./Shooterentities.Server {{MAP}}?listen?SessionName=\"{{SESSION_NAME}}\"?ServerPassword={{SERVER_PASSWORD}}?ServerAdminPassword={{ADMIN_PASSWORD}}?Port={{SERVER_PORT}}?QueryPort={{QUERY_PORT}}?MaxPlayers={{MAX_PLAYERS}}"
-	case "valheim":
-		return "ghcr.io/pterodactyl/games:valheim", "./valheim_server.x86_64 -name \"{{SERVER_NAME}}\" -port {{SERVER_PORT}} -world \"{{WORLD_NAME}}\" -password \"{{PASSWORD}}\" -public {{PUBLIC_SERVER}}"
-	default:
-		return "ghcr.io/pterodactyl/yolks:debian", "/bin/bash"
+// powerServer sends a power action to the server's node and tracks its status
+func (h *Handler) powerServer(ctx context.Context, server *entities.Server, action services.PowerAction) error {
+	switch action {
+	case services.PowerActionStart:
+		if server.Suspended {
+			return services.ErrServerSuspended
+		}
+		if server.IsRunning() {
+			return services.ErrServerAlreadyRunning
+		}
+		if err := h.agent.StartServer(ctx, server.NodeID, server.ID); err != nil {
+			h.db.Model(server).Update("status", entities.ServerStatusError)
+			return err
+		}
+		h.confirmRollout(server.ID)
+		now := time.Now()
+		return h.db.Model(server).Updates(map[string]interface{}{
+			"status":           entities.ServerStatusStarting,
+			"restart_required": false,
+			"last_started_at":  &now,
+		}).Error
+	case services.PowerActionStop:
+		if !server.IsRunning() {
+			return services.ErrServerNotRunning
+		}
+		if err := h.agent.StopServer(ctx, server.NodeID, server.ID); err != nil {
+			return err
+		}
+		return h.db.Model(server).Update("status", entities.ServerStatusStopping).Error
+	case services.PowerActionRestart:
+		if server.Suspended {
+			return services.ErrServerSuspended
+		}
+		if err := h.agent.RestartServer(ctx, server.NodeID, server.ID); err != nil {
+			return err
+		}
+		h.confirmRollout(server.ID)
+		return h.db.Model(server).Updates(map[string]interface{}{
+			"status":           entities.ServerStatusRestarting,
+			"restart_required": false,
+		}).Error
+	case services.PowerActionKill:
+		if err := h.agent.KillServer(ctx, server.NodeID, server.ID); err != nil {
+			return err
+		}
+		return h.db.Model(server).Update("status", entities.ServerStatusStopped).Error
 	}
+
+	return services.ErrInvalidPowerAction
 }
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestUpdateServerResize(t *testing.T) {
+	vt := newVersionTest(t)
+	// Another server takes 12 of the node's 16 GB, leaving room for 2 more
+	// besides the 2 GB of the test server
+	other := &entities.Server{
+		ID: uuid.New(), UUID: "other", Name: "other", NodeID: vt.node.ID, EggID: vt.server.EggID, OwnerID: uuid.New(),
+		MemoryLimit: 12288, DiskLimit: 10240, CPULimit: 100, Status: entities.ServerStatusStopped, Version: 1,
+	}
+	if err := vt.db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	path := "/servers/" + vt.server.ID.String()
+
+	vt.put(t, path, fiber.Map{"memory": 6144, "version": 2})
+	if !errors.Is(vt.err, services.ErrInsufficientResources) {
+		t.Errorf("resize beyond the node = %v, want %v", vt.err, services.ErrInsufficientResources)
+	}
+	var server entities.Server
+	vt.db.First(&server, "id = ?", vt.server.ID)
+	if server.MemoryLimit != 2048 || server.Version != 2 {
+		t.Errorf("rejected resize was written: memory %d, version %d", server.MemoryLimit, server.Version)
+	}
+
+	if status, resp := vt.put(t, path, fiber.Map{"memory": 4096, "version": 2}); status != http.StatusOK {
+		t.Fatalf("resize within the node = %d %v, want %d", status, resp, http.StatusOK)
+	}
+	vt.db.First(&server, "id = ?", vt.server.ID)
+	if server.MemoryLimit != 4096 {
+		t.Errorf("memory = %d after the resize, want 4096", server.MemoryLimit)
+	}
+
+	usage, err := usageOf(vt.db, vt.node.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Servers != 2 || usage.Memory != 16384 {
+		t.Errorf("node usage = %d servers, %d MB, want 2 servers, 16384 MB", usage.Servers, usage.Memory)
+	}
+}
+
+func TestUpdateServerRequiresAccess(t *testing.T) {
+	vt := newVersionTest(t)
+	vt.caller = uuid.New()
+
+	status, _ := vt.put(t, "/servers/"+vt.server.ID.String(), fiber.Map{"memory": 4096, "version": 2})
+	if status != http.StatusForbidden {
+		t.Errorf("resize by another user = %d, want %d", status, http.StatusForbidden)
+	}
+	var server entities.Server
+	vt.db.First(&server, "id = ?", vt.server.ID)
+	if server.MemoryLimit != 2048 {
+		t.Errorf("memory = %d after a denied resize, want 2048", server.MemoryLimit)
+	}
+}
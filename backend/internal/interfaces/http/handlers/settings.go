@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UpdateSettingRequest struct {
+	Value json.RawMessage `json:"value"` // Must match the setting's type
+}
+
+// GetSettings returns every runtime setting with its effective value
+func (h *Handler) GetSettings(c *fiber.Ctx) error {
+	settings, err := h.settings.All(c.UserContext())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": settings,
+	})
+}
+
+// UpdateSetting changes a runtime setting. The change applies without a
+// restart on every panel instance.
+func (h *Handler) UpdateSetting(c *fiber.Ctx) error {
+	var req UpdateSettingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	key := c.Params("key")
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.UserContext()
+
+	err := h.settings.Set(ctx, key, req.Value, userID)
+	var valueErr *database.SettingValueError
+	switch {
+	case errors.Is(err, database.ErrUnknownSetting):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Setting not found",
+		})
+	case errors.As(err, &valueErr):
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   "Invalid setting value",
+			"setting": valueErr.Key,
+			"message": valueErr.Message,
+		})
+	case err != nil:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update setting",
+		})
+	}
+
+	h.db.Create(&entities.AuditLog{
+		UserID:      &userID,
+		Action:      entities.AuditActionUpdate,
+		Resource:    "setting",
+		Description: "Changed setting " + key,
+		Metadata:    map[string]interface{}{"key": key, "value": req.Value},
+		IPAddress:   c.IP(),
+	})
+
+	settings, err := h.settings.All(ctx)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+	for _, setting := range settings {
+		if setting.Key == key {
+			return c.JSON(fiber.Map{
+				"data": setting,
+			})
+		}
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
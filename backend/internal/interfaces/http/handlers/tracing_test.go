@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/tracing"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping ended spans in memory for
+// the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := tracing.Setup(context.Background(), config.TracingConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestAgentRequestsCarryTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+	db := newTestDB(t, &entities.Node{})
+	if err := tracing.InstrumentDB(db); err != nil {
+		t.Fatal(err)
+	}
+
+	traceparent := make(chan string, 1)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("Traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"running"}`))
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	client := agent.NewClient(config.AgentConfig{RequestTimeout: 5 * time.Second}, db)
+	app := fiber.New()
+	app.Use(middleware.Tracing())
+	app.Get("/servers/:id/stats", func(c *fiber.Ctx) error {
+		if _, err := client.GetServerStatus(c.UserContext(), node.ID, uuid.MustParse(c.Params("id"))); err != nil {
+			return err
+		}
+		return c.SendStatus(http.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+uuid.NewString()+"/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var request, agentCall, query sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.SpanKind() == trace.SpanKindServer:
+			request = span
+		case strings.HasPrefix(span.Name(), "agent "):
+			agentCall = span
+		case strings.HasPrefix(span.Name(), "gorm."):
+			query = span
+		}
+	}
+	if request == nil || agentCall == nil || query == nil {
+		t.Fatalf("spans %v, want the request, its node lookup and the agent call", recorder.Ended())
+	}
+	if request.Name() != "GET /servers/:id/stats" {
+		t.Errorf("request span %q, want it named after the route", request.Name())
+	}
+	for _, child := range []sdktrace.ReadOnlySpan{agentCall, query} {
+		if child.Parent().SpanID() != request.SpanContext().SpanID() || child.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			t.Errorf("%s is not a child of the request span", child.Name())
+		}
+	}
+
+	want := "00-" + agentCall.SpanContext().TraceID().String() + "-" + agentCall.SpanContext().SpanID().String() + "-01"
+	if got := <-traceparent; got != want {
+		t.Errorf("traceparent sent to the agent = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TransactionQuery struct {
+	UserID string `query:"user_id" validate:"omitempty,uuid"`
+	Type   string `query:"type" validate:"omitempty,oneof=credit debit refund transfer"`
+	From   string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To     string `query:"to" validate:"omitempty,datetime=2006-01-02"` // Inclusive
+}
+
+// TransactionSummary is a user's current balance and their completed
+// transactions summed by type over the requested period
+type TransactionSummary struct {
+	Balance float64                              `json:"balance"`
+	Totals  map[entities.TransactionType]float64 `json:"totals"`
+}
+
+// GetTransactions returns a page of a user's credit transactions, newest
+// first, each with the balance before and after it. Users see their own
+// history; billing admins and resellers may pass user_id for another user,
+// resellers only for their own sub-accounts.
+func (h *Handler) GetTransactions(c *fiber.Ctx) error {
+	params := pageParams(c, 25, 100)
+
+	var query TransactionQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+	if fields := h.validator.Validate(query); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	targetID, err := h.transactionOwner(c, query.UserID)
+	if err != nil {
+		return err
+	}
+
+	var user entities.User
+	if err := h.db.Select("id", "credits").Where("id = ?", targetID).First(&user).Error; err != nil {
+		return services.ErrUserNotFound
+	}
+
+	// Dates were validated above
+	period := func(tx *gorm.DB) *gorm.DB {
+		if query.From != "" {
+			from, _ := time.Parse(time.DateOnly, query.From)
+			tx = tx.Where("created_at >= ?", from)
+		}
+		if query.To != "" {
+			to, _ := time.Parse(time.DateOnly, query.To)
+			tx = tx.Where("created_at < ?", to.AddDate(0, 0, 1))
+		}
+		return tx
+	}
+
+	db := h.db.Model(&entities.Transaction{}).Where("user_id = ?", targetID).Scopes(period)
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+
+	transactions := make([]entities.Transaction, 0, params.PageSize)
+	total, err := database.Paginate(db, params, "created_at DESC", &transactions)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch transactions",
+		})
+	}
+
+	var sums []struct {
+		Type  entities.TransactionType
+		Total float64
+	}
+	if err := h.db.Model(&entities.Transaction{}).
+		Select("type, COALESCE(SUM(amount), 0) AS total").
+		Where("user_id = ? AND status = ?", targetID, entities.TransactionStatusCompleted).
+		Scopes(period).Group("type").Scan(&sums).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch transactions",
+		})
+	}
+
+	summary := TransactionSummary{
+		Balance: user.Credits,
+		Totals:  make(map[entities.TransactionType]float64, len(sums)),
+	}
+	for _, s := range sums {
+		summary.Totals[s.Type] = s.Total
+	}
+
+	body := paginated(transactions, params, total)
+	body["summary"] = summary
+	return c.JSON(body)
+}
+
+// transactionOwner returns the user whose transactions are requested, checking
+// the caller may see them
+func (h *Handler) transactionOwner(c *fiber.Ctx, requested string) (uuid.UUID, error) {
+	userID, _ := middleware.GetUserID(c)
+	if requested == "" {
+		return userID, nil
+	}
+
+	targetID, _ := uuid.Parse(requested)
+	if targetID == userID || middleware.HasPermission(c, "billing.manage") {
+		return targetID, nil
+	}
+
+	if role, _ := middleware.GetRoleName(c); role == "reseller" {
+		tree, err := h.resellers.TreeIDs(c.UserContext(), userID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if slices.Contains(tree, targetID) {
+			return targetID, nil
+		}
+		return uuid.Nil, services.ErrNotSubUser
+	}
+
+	return uuid.Nil, fiber.NewError(http.StatusForbidden, "Access denied")
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestGetTransactions(t *testing.T) {
+	db := newTestDB(t, &entities.User{}, &entities.Transaction{})
+
+	alex := &entities.User{ID: uuid.New(), Email: "alex@example.com", Username: "alex", Credits: 45}
+	rita := &entities.User{ID: uuid.New(), Email: "rita@example.com", Username: "rita"}
+	sam := &entities.User{ID: uuid.New(), Email: "sam@example.com", Username: "sam", ResellerID: &rita.ID}
+	for _, row := range []interface{}{alex, rita, sam} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, tx := range []struct {
+		typ    entities.TransactionType
+		status entities.TransactionStatus
+		amount float64
+	}{
+		{entities.TransactionTypeCredit, entities.TransactionStatusCompleted, 20},
+		{entities.TransactionTypeDebit, entities.TransactionStatusCompleted, -15},
+		{entities.TransactionTypeCredit, entities.TransactionStatusCompleted, 20},
+		{entities.TransactionTypeCredit, entities.TransactionStatusCompleted, 20},
+		// Pending transactions are listed but not summed
+		{entities.TransactionTypeRefund, entities.TransactionStatusPending, 5},
+	} {
+		if err := db.Create(&entities.Transaction{
+			ID: uuid.New(), UserID: alex.ID, Type: tx.typ, Status: tx.status, Amount: tx.amount, CreatedAt: start.AddDate(0, 0, i),
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&entities.Transaction{ID: uuid.New(), UserID: sam.ID, Type: entities.TransactionTypeCredit, Status: entities.TransactionStatusCompleted, Amount: 10}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{cfg: &config.Config{}, db: db, validator: middleware.NewValidator(),
+		resellers: services.NewResellerService(database.NewUserRepository(db), nil, nil, nil, nil, nil, nil)}
+	caller, role := alex.ID, "user"
+	var permissions []string
+	var handlerErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		handlerErr = err
+		return c.SendStatus(http.StatusForbidden)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, caller)
+		c.Locals(middleware.RoleNameKey, role)
+		c.Locals(middleware.PermissionsKey, permissions)
+		return c.Next()
+	})
+	app.Get("/billing/transactions", h.GetTransactions)
+
+	type page struct {
+		Data    []entities.Transaction `json:"data"`
+		Meta    repositories.PageMeta  `json:"meta"`
+		Summary TransactionSummary     `json:"summary"`
+	}
+	get := func(query string) (int, page) {
+		t.Helper()
+		handlerErr = nil
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/billing/transactions"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var p page
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, p
+	}
+
+	// Newest first, a page at a time
+	status, p := get("?page=2&page_size=2")
+	if status != http.StatusOK {
+		t.Fatalf("list = %d %v", status, handlerErr)
+	}
+	if p.Meta != (repositories.PageMeta{Page: 2, PageSize: 2, Total: 5, TotalPages: 3}) {
+		t.Errorf("meta %+v, want page 2 of 3", p.Meta)
+	}
+	if len(p.Data) != 2 || !p.Data[0].CreatedAt.Equal(start.AddDate(0, 0, 2)) || !p.Data[1].CreatedAt.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("page 2 = %+v, want the third and second transactions", p.Data)
+	}
+	want := map[entities.TransactionType]float64{entities.TransactionTypeCredit: 60, entities.TransactionTypeDebit: -15}
+	if p.Summary.Balance != 45 || len(p.Summary.Totals) != len(want) {
+		t.Errorf("summary %+v, want a balance of 45 and totals %v", p.Summary, want)
+	}
+	for typ, total := range want {
+		if p.Summary.Totals[typ] != total {
+			t.Errorf("%s total %.2f, want %.2f", typ, p.Summary.Totals[typ], total)
+		}
+	}
+
+	_, p = get("?type=credit")
+	if p.Meta.Total != 3 || len(p.Data) != 3 {
+		t.Errorf("%d of %d credits listed, want 3", len(p.Data), p.Meta.Total)
+	}
+	for _, tx := range p.Data {
+		if tx.Type != entities.TransactionTypeCredit {
+			t.Errorf("type filter listed a %s", tx.Type)
+		}
+	}
+	if _, p = get("?from=2026-10-02&to=2026-10-03"); p.Meta.Total != 2 {
+		t.Errorf("%d transactions in two days, want 2", p.Meta.Total)
+	}
+
+	// Users see only their own history
+	if status, _ := get("?user_id=" + sam.ID.String()); status != http.StatusForbidden {
+		t.Errorf("another user's history = %d, want %d", status, http.StatusForbidden)
+	}
+	// Resellers see their sub-users'
+	caller, role = rita.ID, "reseller"
+	if status, p := get("?user_id=" + sam.ID.String()); status != http.StatusOK || p.Meta.Total != 1 {
+		t.Errorf("sub-user history = %d with %d transactions, want sam's one", status, p.Meta.Total)
+	}
+	get("?user_id=" + alex.ID.String())
+	if !errors.Is(handlerErr, services.ErrNotSubUser) {
+		t.Errorf("history of a user outside the tree = %v, want %v", handlerErr, services.ErrNotSubUser)
+	}
+	// Billing admins see anyone's
+	caller, role, permissions = uuid.New(), "support", []string{"billing.manage"}
+	if status, p := get("?user_id=" + alex.ID.String()); status != http.StatusOK || p.Meta.Total != 5 {
+		t.Errorf("history as billing admin = %d with %d transactions, want alex's 5", status, p.Meta.Total)
+	}
+}
@@ -1,7 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/domain/repositories"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
 	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -11,17 +20,39 @@ import (
 
 // UserHandler handles user endpoints
 type UserHandler struct {
-	config *config.Config
-	db     *gorm.DB
-	redis  *redis.Client
+	config    *config.Config
+	db        *gorm.DB
+	redis     *redis.Client
+	validator *middleware.Validator
+	keys      *crypto.KeyRing
+	resellers *services.ResellerService
 }
 
 // NewUserHandler creates a new UserHandler
 func NewUserHandler(cfg *config.Config, db *gorm.DB, rdb *redis.Client) *UserHandler {
+	uow := database.NewUnitOfWork(db, database.NewTxRepositories)
+	billing := services.NewBillingService(
+		database.NewTransactionRepository(db),
+		database.NewUserRepository(db),
+		database.NewAuditLogRepository(db),
+		uow,
+		cfg,
+	)
 	return &UserHandler{
-		config: cfg,
-		db:     db,
-		redis:  rdb,
+		config:    cfg,
+		db:        db,
+		redis:     rdb,
+		validator: middleware.NewValidator(),
+		keys:      crypto.NewKeyRing(cfg.JWT),
+		resellers: services.NewResellerService(
+			database.NewUserRepository(db),
+			database.NewRoleRepository(db),
+			database.NewUserQuotaRepository(db),
+			database.NewServerRepository(db),
+			database.NewAuditLogRepository(db),
+			uow,
+			billing,
+		),
 	}
 }
 
@@ -37,23 +68,38 @@ type CreateUserRequest struct {
 
 // List returns paginated list of users
 func (h *UserHandler) List(c *fiber.Ctx) error {
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("page_size", 20)
-	search := c.Query("search")
-	status := c.Query("status")
+	params := pageParams(c, repositories.DefaultPageSize, repositories.MaxPageSize)
 
-	_ = search
-	_ = status
+	query := h.db.WithContext(c.UserContext()).Model(&entities.User{})
+	// Resellers only see users within their own sub-tree
+	if role, _ := middleware.GetRoleName(c); role == "reseller" {
+		userID, _ := middleware.GetUserID(c)
+		tree, err := h.resellers.TreeIDs(c.UserContext(), userID)
+		if err != nil {
+			return err
+		}
+		query = query.Where("id IN ?", tree)
+	}
+	if search := c.Query("search"); search != "" {
+		// Escape LIKE wildcards so the search matches literally
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search) + "%"
+		query = query.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-	return c.JSON(fiber.Map{
-		"data": []interface{}{},
-		"meta": fiber.Map{
-			"page":        page,
-			"page_size":   pageSize,
-			"total":       0,
-			"total_pages": 0,
-		},
+	users := make([]entities.User, 0, params.PageSize)
+	total, err := database.Paginate(query, params, "created_at DESC", &users, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload("Role")
 	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch users",
+		})
+	}
+
+	return c.JSON(paginated(users, params, total))
 }
 
 // Create creates a new user
@@ -80,7 +126,9 @@ func (h *UserHandler) GetByID(c *fiber.Ctx) error {
 			"error": "Invalid user ID",
 		})
 	}
-	_ = id
+	if err := h.checkScope(c, id); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{
 		"id": userID,
@@ -92,22 +140,85 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 	userID := c.Params("id")
 
 	var req struct {
-		Email     string `json:"email" validate:"email"`
-		Username  string `json:"username" validate:"min=3,max=50"`
+		Email     string `json:"email" validate:"omitempty,email"`
+		Username  string `json:"username" validate:"omitempty,min=3,max=50"`
 		FirstName string `json:"first_name" validate:"max=100"`
 		LastName  string `json:"last_name" validate:"max=100"`
-		RoleID    string `json:"role_id" validate:"uuid"`
-		Status    string `json:"status" validate:"oneof=active inactive suspended"`
+		RoleID    string `json:"role_id" validate:"omitempty,uuid"`
+		Status    string `json:"status" validate:"omitempty,oneof=active inactive suspended"`
+		Country   string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+		Region    string `json:"region" validate:"max=50"`
+		VATNumber string `json:"vat_number" validate:"max=50"`
+		TaxExempt *bool  `json:"tax_exempt"`
+		Timezone  string `json:"timezone" validate:"omitempty,timezone"` // IANA zone clients display times in
+		Version   int    `json:"version" validate:"required,min=1"`      // Version the client last read
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
-	_ = userID
+
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var user entities.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err := h.checkScope(c, user.ID); err != nil {
+		return err
+	}
+	// Resellers manage their sub-users' accounts but not their roles
+	if role, _ := middleware.GetRoleName(c); role == "reseller" && req.RoleID != "" && req.RoleID != user.RoleID.String() {
+		return fiber.NewError(fiber.StatusForbidden, "Resellers cannot change roles")
+	}
+
+	if user.Version != req.Version {
+		return versionConflict(c, user.Version)
+	}
+
+	if req.Email != "" {
+		user.Email = req.Email
+	}
+	if req.Username != "" {
+		user.Username = req.Username
+	}
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	if req.RoleID != "" {
+		user.RoleID = uuid.MustParse(req.RoleID)
+	}
+	if req.Status != "" {
+		user.Status = entities.UserStatus(req.Status)
+	}
+	user.Country = strings.ToUpper(req.Country)
+	user.Region = req.Region
+	user.VATNumber = strings.ToUpper(strings.ReplaceAll(req.VATNumber, " ", ""))
+	if req.TaxExempt != nil {
+		user.TaxExempt = *req.TaxExempt
+	}
+	if req.Timezone != "" {
+		user.Timezone = req.Timezone
+	}
+
+	if err := saveVersioned(h.db, &user, &user.Version, req.Version); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			var current entities.User
+			h.db.Select("version").Where("id = ?", user.ID).First(&current)
+			return versionConflict(c, current.Version)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update user",
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "User updated successfully",
+		"data":    user,
 	})
 }
 
@@ -128,8 +239,29 @@ func (h *UserHandler) Delete(c *fiber.Ctx) error {
 			"error": "Cannot delete your own account",
 		})
 	}
+	if err := h.checkScope(c, id); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "User deleted successfully",
 	})
 }
+
+// checkScope restricts resellers to the users in their own sub-tree
+func (h *UserHandler) checkScope(c *fiber.Ctx, id uuid.UUID) error {
+	role, _ := middleware.GetRoleName(c)
+	if role != "reseller" {
+		return nil
+	}
+
+	resellerID, _ := middleware.GetUserID(c)
+	tree, err := h.resellers.TreeIDs(c.UserContext(), resellerID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(tree, id) {
+		return services.ErrNotSubUser
+	}
+	return nil
+}
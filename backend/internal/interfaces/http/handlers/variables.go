@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type UpdateServerVariablesRequest struct {
+	Variables map[string]string `json:"variables" validate:"required_without=Settings"` // Keyed by environment variable name
+	Settings  map[string]string `json:"settings" validate:"required_without=Variables"` // Quick settings, keyed by setting key
+	Version   int               `json:"version" validate:"required,min=1"`              // Server version the client last read
+}
+
+// GetServerVariables returns the egg variables of a server with their current
+// values, along with the egg's quick settings for them and the server version
+// to send edits with. Variables that are not user viewable, and their quick
+// settings, are only shown to admins.
+func (h *Handler) GetServerVariables(c *fiber.Ctx) error {
+	server, egg, eggVars, err := h.variableServer(c)
+	if err != nil {
+		return err
+	}
+	admin := middleware.IsAdmin(c)
+
+	return c.JSON(fiber.Map{
+		"data":           services.VisibleServerVariables(eggVars, server.Environment, admin),
+		"quick_settings": services.VisibleQuickSettings(egg.QuickSettings, eggVars, server.Environment, admin),
+		"version":        server.Version,
+	})
+}
+
+// UpdateServerVariables changes the values of a server's egg variables, given
+// directly or through the egg's quick settings, and pushes the new startup to
+// its node. Running servers apply them on their next start and are flagged
+// restart_required until then. Like other server updates, edits made against
+// a stale server version are rejected.
+func (h *Handler) UpdateServerVariables(c *fiber.Ctx) error {
+	var req UpdateServerVariablesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, egg, eggVars, err := h.variableServer(c)
+	if err != nil {
+		return err
+	}
+	if server.Version != req.Version {
+		return versionConflict(c, server.Version)
+	}
+	admin := middleware.IsAdmin(c)
+
+	edits, err := services.QuickSettingEdits(egg.QuickSettings, req.Settings, req.Variables)
+	var changed []services.ResolvedVariable
+	if err == nil {
+		changed, err = services.ResolveVariableEdits(eggVars, server.Environment, edits, admin)
+	}
+	var varErr *services.VariableError
+	if errors.As(err, &varErr) {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "Invalid variable value",
+			"variable": varErr.Variable,
+			"message":  varErr.Message,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return c.JSON(fiber.Map{
+			"data":             services.VisibleServerVariables(eggVars, server.Environment, admin),
+			"quick_settings":   services.VisibleQuickSettings(egg.QuickSettings, eggVars, server.Environment, admin),
+			"restart_required": server.RestartRequired,
+			"version":          server.Version,
+		})
+	}
+
+	var allocations []*entities.Allocation
+	if err := h.db.Where("server_id = ?", server.ID).Find(&allocations).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch allocations",
+		})
+	}
+
+	environment := maps.Clone(server.Environment)
+	if environment == nil {
+		environment = make(map[string]string, len(changed))
+	}
+	for _, v := range changed {
+		environment[v.Variable.EnvVariable] = v.Value
+	}
+	startup := services.NewStartupBuilder(egg, server, allocations, environment).Build()
+	server.StartupCmd = startup.Command
+	server.Environment = startup.Environment
+	if server.IsRunning() {
+		server.RestartRequired = true
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(server).Where("version = ?", req.Version).Updates(map[string]interface{}{
+			"startup_cmd":      server.StartupCmd,
+			"environment":      server.Environment,
+			"restart_required": server.RestartRequired,
+			"version":          req.Version + 1,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		for _, v := range changed {
+			variable := entities.ServerVariable{ServerID: server.ID, EggVariableID: v.Variable.ID}
+			if err := tx.Where(variable).
+				Assign(entities.ServerVariable{Value: v.Value}).
+				FirstOrCreate(&variable).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errVersionConflict) {
+		var current entities.Server
+		h.db.Select("version").Where("id = ?", server.ID).First(&current)
+		return versionConflict(c, current.Version)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update variables",
+		})
+	}
+	server.Version = req.Version + 1
+
+	if err := h.agent.UpdateServerStartup(c.UserContext(), server.NodeID, server.ID, startup, allocations); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Variables saved but the node could not be updated",
+		})
+	}
+
+	names := make([]string, 0, len(changed))
+	for _, v := range changed {
+		names = append(names, v.Variable.EnvVariable)
+	}
+	userID, _ := middleware.GetUserID(c)
+	h.db.Create(&entities.AuditLog{
+		UserID:     &userID,
+		Action:     entities.AuditActionUpdate,
+		Resource:   "server",
+		ResourceID: &server.ID,
+		Metadata:   map[string]interface{}{"variables": names},
+		IPAddress:  c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"data":             services.VisibleServerVariables(eggVars, server.Environment, admin),
+		"quick_settings":   services.VisibleQuickSettings(egg.QuickSettings, eggVars, server.Environment, admin),
+		"restart_required": server.RestartRequired,
+		"version":          server.Version,
+	})
+}
+
+// variableServer loads the server of a variables request, checking access, and
+// its egg with the egg's variables
+func (h *Handler) variableServer(c *fiber.Ctx) (*entities.Server, *entities.Egg, []*entities.EggVariable, error) {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var egg entities.Egg
+	if err := h.db.Where("id = ?", server.EggID).First(&egg).Error; err != nil {
+		return nil, nil, nil, services.ErrEggNotFound
+	}
+	var eggVars []*entities.EggVariable
+	if err := h.db.Where("egg_id = ?", server.EggID).Find(&eggVars).Error; err != nil {
+		return nil, nil, nil, fiber.NewError(http.StatusInternalServerError, "Failed to fetch variables")
+	}
+	return server, &egg, eggVars, nil
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// variablesTest is a server of an egg with an editable MAX_PLAYERS, a
+// read-only SEED and a hidden RCON_PASSWORD. Requests are made as the owner
+// unless role is set.
+type variablesTest struct {
+	db     *gorm.DB
+	app    *fiber.App
+	server *entities.Server
+	role   string
+	err    error
+}
+
+func newVariablesTest(t *testing.T) *variablesTest {
+	t.Helper()
+	db := newTestDB(t, &entities.Node{}, &entities.Server{}, &entities.Egg{}, &entities.EggVariable{}, &entities.ServerVariable{},
+		&entities.Allocation{}, &entities.AuditLog{})
+	vt := &variablesTest{db: db, role: "user"}
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(daemon.Close)
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonPort, _ := strconv.Atoi(port)
+
+	node := &entities.Node{ID: uuid.New(), Name: "node-1", LocationID: uuid.New(), FQDN: host, Scheme: "http", DaemonPort: daemonPort}
+	egg := &entities.Egg{ID: uuid.New(), GameID: uuid.New(), Name: "game", StartupCommand: "./start --players {{MAX_PLAYERS}}", DockerImages: []string{"game:1"}, Version: 1}
+	vt.server = &entities.Server{
+		ID: uuid.New(), UUID: "survival", Name: "survival", NodeID: node.ID, EggID: egg.ID, OwnerID: uuid.New(),
+		DockerImage: "game:1", Status: entities.ServerStatusRunning, Version: 2,
+	}
+	for _, row := range []interface{}{node, egg, vt.server} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, v := range []struct {
+		env, rules         string
+		viewable, editable bool
+	}{
+		{"MAX_PLAYERS", "required|integer|max:100", true, true},
+		{"SEED", "string", true, false},
+		{"RCON_PASSWORD", "string", false, false},
+	} {
+		variable := &entities.EggVariable{ID: uuid.New(), EggID: egg.ID, Name: v.env, EnvVariable: v.env, DefaultValue: "20", Rules: v.rules, SortOrder: i}
+		if err := db.Create(variable).Error; err != nil {
+			t.Fatal(err)
+		}
+		// Created first, as a false flag would take the column's default
+		db.Model(variable).Updates(map[string]interface{}{"user_viewable": v.viewable, "user_editable": v.editable})
+	}
+
+	cfg := &config.Config{Agents: config.AgentConfig{RequestTimeout: 5 * time.Second}}
+	h := &Handler{cfg: cfg, db: db, validator: middleware.NewValidator(), agent: agent.NewClient(cfg.Agents, db)}
+	vt.app = fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		vt.err = err
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	vt.app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, vt.server.OwnerID)
+		c.Locals(middleware.RoleNameKey, vt.role)
+		return c.Next()
+	})
+	vt.app.Get("/servers/:id/variables", h.GetServerVariables)
+	vt.app.Put("/servers/:id/variables", h.UpdateServerVariables)
+	return vt
+}
+
+// request sends body to the variables endpoint, returning the status and the
+// decoded response
+func (vt *variablesTest) request(t *testing.T, method string, body fiber.Map) (int, map[string]json.RawMessage) {
+	t.Helper()
+	vt.err = nil
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, "/servers/"+vt.server.ID.String()+"/variables", bytes.NewReader(data))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := vt.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, decoded
+}
+
+// stored returns the version and environment of the test server
+func (vt *variablesTest) stored(t *testing.T) (int, map[string]string) {
+	t.Helper()
+	var row struct {
+		Version     int
+		Environment *string
+	}
+	if err := vt.db.Model(&entities.Server{}).Select("version", "environment").Where("id = ?", vt.server.ID).Scan(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	var environment map[string]string
+	if row.Environment != nil {
+		if err := json.Unmarshal([]byte(*row.Environment), &environment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return row.Version, environment
+}
+
+func TestServerVariablesGating(t *testing.T) {
+	for role, want := range map[string]map[string]bool{
+		"user":  {"MAX_PLAYERS": true, "SEED": false},
+		"admin": {"MAX_PLAYERS": true, "SEED": true, "RCON_PASSWORD": true},
+	} {
+		vt := newVariablesTest(t)
+		vt.role = role
+		status, resp := vt.request(t, http.MethodGet, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s GET = %d", role, status)
+		}
+		var variables []services.ServerVariableValue
+		_ = json.Unmarshal(resp["data"], &variables)
+		got := make(map[string]bool, len(variables))
+		for _, v := range variables {
+			got[v.EnvVariable] = v.Editable
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s sees %v, want %v", role, got, want)
+		}
+		for env, editable := range want {
+			if e, ok := got[env]; !ok || e != editable {
+				t.Errorf("%s sees %s editable=%v (shown %v), want editable=%v", role, env, e, ok, editable)
+			}
+		}
+	}
+
+	vt := newVariablesTest(t)
+	for env, wantErr := range map[string]error{"SEED": services.ErrVariableNotEditable, "RCON_PASSWORD": services.ErrVariableNotFound} {
+		vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{env: "1"}, "version": 2})
+		if !errors.Is(vt.err, wantErr) {
+			t.Errorf("user edit of %s = %v, want %v", env, vt.err, wantErr)
+		}
+	}
+	if version, environment := vt.stored(t); version != 2 || environment != nil {
+		t.Errorf("rejected edits stored version %d, environment %v", version, environment)
+	}
+
+	vt.role = "admin"
+	if status, resp := vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{"SEED": "42"}, "version": 2}); status != http.StatusOK {
+		t.Fatalf("admin edit of SEED = %d %s", status, resp["error"])
+	}
+	if _, environment := vt.stored(t); environment["SEED"] != "42" {
+		t.Errorf("SEED = %q after the admin edit, want 42", environment["SEED"])
+	}
+}
+
+func TestUpdateServerVariablesValidatesRules(t *testing.T) {
+	vt := newVariablesTest(t)
+
+	for _, value := range []string{"500", "many", ""} {
+		status, resp := vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{"MAX_PLAYERS": value}, "version": 2})
+		if status != http.StatusUnprocessableEntity {
+			t.Errorf("MAX_PLAYERS=%q = %d, want %d", value, status, http.StatusUnprocessableEntity)
+			continue
+		}
+		if variable := string(resp["variable"]); variable != `"MAX_PLAYERS"` {
+			t.Errorf("MAX_PLAYERS=%q reported variable %s", value, variable)
+		}
+	}
+	if version, environment := vt.stored(t); version != 2 || environment != nil {
+		t.Errorf("invalid edits stored version %d, environment %v", version, environment)
+	}
+
+	status, resp := vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{"MAX_PLAYERS": "50"}, "version": 2})
+	if status != http.StatusOK {
+		t.Fatalf("MAX_PLAYERS=50 = %d %s", status, resp["error"])
+	}
+	if string(resp["restart_required"]) != "true" || string(resp["version"]) != "3" {
+		t.Errorf("response restart_required %s, version %s, want true and 3", resp["restart_required"], resp["version"])
+	}
+	version, environment := vt.stored(t)
+	if version != 3 || environment["MAX_PLAYERS"] != "50" {
+		t.Errorf("stored version %d, MAX_PLAYERS %q, want 3 and 50", version, environment["MAX_PLAYERS"])
+	}
+	var value entities.ServerVariable
+	if err := vt.db.Where("server_id = ?", vt.server.ID).First(&value).Error; err != nil || value.Value != "50" {
+		t.Errorf("server variable = %q (%v), want 50", value.Value, err)
+	}
+}
+
+func TestUpdateServerVariablesRejectsStaleVersion(t *testing.T) {
+	vt := newVariablesTest(t)
+	status, resp := vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{"MAX_PLAYERS": "50"}, "version": 1})
+	if status != http.StatusConflict || string(resp["current_version"]) != "2" {
+		t.Errorf("stale edit = %d, current_version %s, want %d and 2", status, resp["current_version"], http.StatusConflict)
+	}
+
+	// A write landing between the version check and the update
+	writeConcurrently(t, vt.db, "servers", vt.server.ID)
+	status, resp = vt.request(t, http.MethodPut, fiber.Map{"variables": fiber.Map{"MAX_PLAYERS": "50"}, "version": 2})
+	if status != http.StatusConflict || string(resp["current_version"]) != "3" {
+		t.Errorf("edit racing a write = %d, current_version %s, want %d and 3", status, resp["current_version"], http.StatusConflict)
+	}
+	if _, environment := vt.stored(t); environment != nil {
+		t.Errorf("conflicting edits stored environment %v", environment)
+	}
+	var count int64
+	vt.db.Model(&entities.ServerVariable{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d server variables stored by conflicting edits, want 0", count)
+	}
+}
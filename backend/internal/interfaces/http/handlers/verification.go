@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// emailVerificationKey returns the Redis key of a verification token, stored
+// hashed so a leaked key does not verify anyone
+func emailVerificationKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return redis.BuildKey(redis.PrefixUser, "verify", hex.EncodeToString(sum[:]))
+}
+
+// sendVerificationEmail issues a verification token for a user and mails them
+// the link to use it
+func (h *AuthHandler) sendVerificationEmail(ctx context.Context, user *entities.User) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := h.redis.Set(ctx, emailVerificationKey(token), user.ID.String(), h.config.Security.EmailVerificationExpiry); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(h.config.App.URL, "/") + "/verify-email?token=" + token
+	body := fmt.Sprintf("Hello %s,\n\nConfirm your email address to activate your %s account:\n\n%s\n\nThe link expires in %s. If you did not register, ignore this email.\n",
+		user.Username, h.config.App.Name, link, h.config.Security.EmailVerificationExpiry)
+	return h.mailer.Send(ctx, user.Email, "Verify your email address", body)
+}
+
+// VerifyEmail marks a user's email as verified and activates their account
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token" validate:"required,len=64,hexadecimal"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	ctx := c.UserContext()
+	key := emailVerificationKey(req.Token)
+	id, err := h.redis.Get(ctx, key)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired verification link",
+		})
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired verification link",
+		})
+	}
+
+	var user entities.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired verification link",
+		})
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+	}
+	// Only pending accounts are activated, a suspended one stays suspended
+	if user.Status == entities.UserStatusPending {
+		updates["status"] = entities.UserStatusActive
+	}
+	if err := h.db.Model(&user).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify email",
+		})
+	}
+	_ = h.redis.Delete(ctx, key)
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified",
+	})
+}
+
+// ResendVerification sends a new verification email. It answers the same
+// whether or not the address belongs to an unverified account.
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	var user entities.User
+	if err := h.db.Where("LOWER(email) = LOWER(?) AND email_verified = ?", req.Email, false).First(&user).Error; err == nil {
+		_ = h.sendVerificationEmail(c.UserContext(), &user)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If the account exists and is not verified, a verification email has been sent",
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// WarmNode starts pre-pulling the images of a node's servers. Progress is
+// available from GetNodeWarmup.
+func (h *Handler) WarmNode(c *fiber.Ctx) error {
+	var node entities.Node
+	if err := h.db.Where("id = ?", c.Params("id")).First(&node).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Node not found",
+		})
+	}
+
+	running, err := h.warmer.Running(c.UserContext(), node.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check image warmup",
+		})
+	}
+	if running {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Image warmup is already running",
+		})
+	}
+
+	h.startWarmup(node.ID)
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// GetNodeWarmup returns the progress of a node's latest image warmup
+func (h *Handler) GetNodeWarmup(c *fiber.Ctx) error {
+	nodeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid node ID",
+		})
+	}
+
+	var progress agent.WarmupProgress
+	if err := h.redis.GetJSON(c.UserContext(), agent.WarmupKey(nodeID.String()), &progress); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "No image warmup has run on this node",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": progress,
+	})
+}
+
+// startWarmup pre-pulls a node's images in the background, outliving the request
+func (h *Handler) startWarmup(nodeID uuid.UUID) {
+	go func() {
+		_ = h.warmer.Warm(context.Background(), nodeID)
+	}()
+}
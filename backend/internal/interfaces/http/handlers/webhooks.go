@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateWebhookRequest struct {
+	Name     string   `json:"name" validate:"required,min=1,max=100"`
+	URL      string   `json:"url" validate:"required,url,max=500"`
+	Provider string   `json:"provider" validate:"required,oneof=discord slack generic"`
+	Secret   string   `json:"secret" validate:"max=100"`
+	Events   []string `json:"events" validate:"required,min=1,dive,oneof=node.offline server.crashed backup.failed resource.high *"`
+	Template string   `json:"template" validate:"max=2000"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type UpdateWebhookRequest struct {
+	Name     string   `json:"name" validate:"required,min=1,max=100"`
+	URL      string   `json:"url" validate:"required,url,max=500"`
+	Provider string   `json:"provider" validate:"required,oneof=discord slack generic"`
+	Secret   *string  `json:"secret" validate:"omitempty,max=100"`
+	Events   []string `json:"events" validate:"required,min=1,dive,oneof=node.offline server.crashed backup.failed resource.high *"`
+	Template string   `json:"template" validate:"max=2000"`
+	IsActive bool     `json:"is_active"`
+}
+
+// GetWebhooks returns all webhooks
+func (h *Handler) GetWebhooks(c *fiber.Ctx) error {
+	var webhooks []entities.Webhook
+
+	if err := h.db.Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhooks",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   webhooks,
+		"events": entities.WebhookEventTypes,
+	})
+}
+
+// CreateWebhook registers a new webhook
+func (h *Handler) CreateWebhook(c *fiber.Ctx) error {
+	var req CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	if req.Template != "" {
+		if err := services.ValidateWebhookTemplate(req.Template); err != nil {
+			return middleware.ValidationFailed(c, []middleware.FieldError{{
+				Field:   "template",
+				Rule:    "template",
+				Message: err.Error(),
+			}})
+		}
+	}
+
+	webhook := entities.Webhook{
+		Name:     req.Name,
+		URL:      req.URL,
+		Provider: entities.WebhookProvider(req.Provider),
+		Secret:   req.Secret,
+		Events:   req.Events,
+		Template: req.Template,
+		IsActive: true,
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+	if userID, ok := middleware.GetUserID(c); ok {
+		webhook.CreatedBy = &userID
+	}
+
+	if err := h.db.Create(&webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create webhook",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"data": webhook,
+	})
+}
+
+// GetWebhook returns a specific webhook
+func (h *Handler) GetWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var webhook entities.Webhook
+	if err := h.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": webhook,
+	})
+}
+
+// UpdateWebhook updates an existing webhook
+func (h *Handler) UpdateWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	if req.Template != "" {
+		if err := services.ValidateWebhookTemplate(req.Template); err != nil {
+			return middleware.ValidationFailed(c, []middleware.FieldError{{
+				Field:   "template",
+				Rule:    "template",
+				Message: err.Error(),
+			}})
+		}
+	}
+
+	var webhook entities.Webhook
+	if err := h.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+
+	webhook.Name = req.Name
+	webhook.URL = req.URL
+	webhook.Provider = entities.WebhookProvider(req.Provider)
+	webhook.Events = req.Events
+	webhook.Template = req.Template
+	webhook.IsActive = req.IsActive
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+
+	// Re-enabling a webhook clears its failure history
+	if webhook.IsActive {
+		webhook.FailureCount = 0
+		webhook.LastError = ""
+	}
+
+	if err := h.db.Save(&webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update webhook",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": webhook,
+	})
+}
+
+// DeleteWebhook deletes a webhook
+func (h *Handler) DeleteWebhook(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var webhook entities.Webhook
+	if err := h.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+
+	if err := h.db.Delete(&webhook).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.Status(http.StatusNoContent).Send(nil)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CreateWorldBackupRequest struct {
+	Name string `json:"name" validate:"max=100"` // Defaults to the world name and time
+}
+
+type RestoreWorldBackupRequest struct {
+	SafetyBackup *bool `json:"safety_backup"` // Back up the world first, omit for the panel setting
+}
+
+// ListWorlds scans a server's data directory for Minecraft worlds and returns them
+func (h *Handler) ListWorlds(c *fiber.Ctx) error {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return err
+	}
+
+	worlds, err := h.worlds.List(c.UserContext(), server.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": worlds,
+	})
+}
+
+// GetWorldBackups returns the backups of a world
+func (h *Handler) GetWorldBackups(c *fiber.Ctx) error {
+	server, world, err := h.findWorld(c)
+	if err != nil {
+		return err
+	}
+
+	backups, err := h.worlds.ListBackups(c.UserContext(), server.ID, world.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch world backups",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": backups,
+	})
+}
+
+// CreateWorldBackup archives a world on its node
+func (h *Handler) CreateWorldBackup(c *fiber.Ctx) error {
+	var req CreateWorldBackupRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if fields := h.validator.Validate(req); fields != nil {
+		return middleware.ValidationFailed(c, fields)
+	}
+
+	server, world, err := h.findWorld(c)
+	if err != nil {
+		return err
+	}
+
+	// Shutdown waits for the backup rather than leaving it half written
+	done, err := h.ops.Begin("world_backup", server.ID.String()+"/"+world.FolderName)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	userID, _ := middleware.GetUserID(c)
+	backup, err := h.worlds.CreateBackup(c.UserContext(), server.ID, world.ID, req.Name, userID)
+	if err != nil {
+		return err
+	}
+
+	h.recordActivity(c, world.ServerID, entities.ActivityBackupCreate, "World "+world.Name)
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "World backed up",
+		"data":    backup,
+	})
+}
+
+// RestoreWorldBackup replaces a world with one of its backups. The server has
+// to be stopped. A safety backup of the world is taken first when asked for,
+// and the restore is abandoned when it fails.
+func (h *Handler) RestoreWorldBackup(c *fiber.Ctx) error {
+	var req RestoreWorldBackupRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	server, world, err := h.findWorld(c)
+	if err != nil {
+		return err
+	}
+	backupID, err := uuid.Parse(c.Params("backupId"))
+	if err != nil {
+		return services.ErrWorldBackupNotFound
+	}
+
+	done, err := h.ops.Begin("world_restore", server.ID.String()+"/"+world.FolderName)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	safety := h.settings.Bool(c.UserContext(), database.SettingSafetyBackups)
+	if req.SafetyBackup != nil {
+		safety = *req.SafetyBackup
+	}
+	userID, _ := middleware.GetUserID(c)
+	if err := h.worlds.RestoreBackup(c.UserContext(), server.ID, world.ID, backupID, userID, safety); err != nil {
+		return err
+	}
+
+	h.recordActivity(c, world.ServerID, entities.ActivityBackupRestore, "World "+world.Name)
+
+	return c.JSON(fiber.Map{
+		"message": "World restored",
+	})
+}
+
+// DeleteWorld removes a world folder from a server. The server has to be stopped.
+func (h *Handler) DeleteWorld(c *fiber.Ctx) error {
+	server, world, err := h.findWorld(c)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if err := h.worlds.Delete(c.UserContext(), server.ID, world.ID, userID); err != nil {
+		return err
+	}
+
+	h.recordActivity(c, world.ServerID, entities.ActivityFileDelete, "World "+world.Name)
+
+	return c.JSON(fiber.Map{
+		"message": "World deleted",
+	})
+}
+
+// findWorld loads the accessible server and the world named by the request
+func (h *Handler) findWorld(c *fiber.Ctx) (*entities.Server, *entities.World, error) {
+	server, err := h.accessibleServer(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	worldID, err := uuid.Parse(c.Params("worldId"))
+	if err != nil {
+		return nil, nil, services.ErrWorldNotFound
+	}
+	world, err := h.worlds.Get(c.UserContext(), server.ID, worldID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return server, world, nil
+}
@@ -5,23 +5,32 @@ import (
 	"strings"
 
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // AuthMiddleware handles authentication and authorization
 type AuthMiddleware struct {
 	config *config.Config
+	db     *gorm.DB // Audits denied requests
 	redis  *redis.Client
+	keys   *crypto.KeyRing
 }
 
 // NewAuthMiddleware creates a new AuthMiddleware
-func NewAuthMiddleware(cfg *config.Config, rdb *redis.Client) *AuthMiddleware {
+func NewAuthMiddleware(cfg *config.Config, db *gorm.DB, rdb *redis.Client) *AuthMiddleware {
 	return &AuthMiddleware{
 		config: cfg,
+		db:     db,
 		redis:  rdb,
+		keys:   crypto.NewKeyRing(cfg.JWT),
 	}
 }
 
@@ -32,6 +41,8 @@ type Claims struct {
 	Email    string    `json:"email"`
 	RoleID   uuid.UUID `json:"role_id"`
 	RoleName string    `json:"role_name"`
+	// ImpersonatorID is the admin acting as the user, set on impersonation tokens only
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -43,12 +54,18 @@ const (
 	RoleIDKey      = "role_id"
 	RoleNameKey    = "role_name"
 	PermissionsKey = "permissions"
+
+	ImpersonatorIDKey = "impersonator_id"
 )
 
-// Authenticate validates JWT token and sets user context
+// Authenticate validates JWT token and sets user context. Browsers cannot set
+// headers on WebSocket upgrades, so those may pass the token as ?token=.
 func (m *AuthMiddleware) Authenticate(c *fiber.Ctx) error {
 	// Get token from header
 	authHeader := c.Get("Authorization")
+	if authHeader == "" && websocket.IsWebSocketUpgrade(c) && c.Query("token") != "" {
+		authHeader = "Bearer " + c.Query("token")
+	}
 	if authHeader == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Missing authorization header",
@@ -65,12 +82,7 @@ func (m *AuthMiddleware) Authenticate(c *fiber.Ctx) error {
 	tokenString := parts[1]
 
 	// Parse and validate token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid signing method")
-		}
-		return []byte(m.config.JWT.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keys.Keyfunc)
 
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -94,6 +106,17 @@ func (m *AuthMiddleware) Authenticate(c *fiber.Ctx) error {
 		})
 	}
 
+	// Impersonation tokens stay valid only while their session exists
+	if claims.ImpersonatorID != nil {
+		active, _ := m.redis.Exists(ctx, ImpersonationKey(claims.ID))
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Impersonation session has ended",
+			})
+		}
+		c.Locals(ImpersonatorIDKey, *claims.ImpersonatorID)
+	}
+
 	// Get user permissions from cache
 	permissions, err := m.getUserPermissions(ctx, claims.UserID)
 	if err != nil {
@@ -107,6 +130,7 @@ func (m *AuthMiddleware) Authenticate(c *fiber.Ctx) error {
 	c.Locals(RoleIDKey, claims.RoleID)
 	c.Locals(RoleNameKey, claims.RoleName)
 	c.Locals(PermissionsKey, permissions)
+	c.SetUserContext(logger.WithFields(c.UserContext(), zap.String("user_id", claims.UserID.String())))
 
 	return c.Next()
 }
@@ -122,9 +146,7 @@ func (m *AuthMiddleware) RequirePermission(permission string) fiber.Handler {
 
 		permissions, ok := c.Locals(PermissionsKey).([]string)
 		if !ok {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied",
-			})
+			return m.deny(c, permission, "Access denied")
 		}
 
 		for _, p := range permissions {
@@ -133,20 +155,17 @@ func (m *AuthMiddleware) RequirePermission(permission string) fiber.Handler {
 			}
 		}
 
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Insufficient permissions",
-		})
+		return m.deny(c, permission, "Insufficient permissions")
 	}
 }
 
 // RequireRole checks if user has required role
 func (m *AuthMiddleware) RequireRole(roles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		required := "role:" + strings.Join(roles, ",")
 		userRole, ok := c.Locals(RoleNameKey).(string)
 		if !ok {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied",
-			})
+			return m.deny(c, required, "Access denied")
 		}
 
 		for _, role := range roles {
@@ -155,9 +174,7 @@ func (m *AuthMiddleware) RequireRole(roles ...string) fiber.Handler {
 			}
 		}
 
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Insufficient role",
-		})
+		return m.deny(c, required, "Insufficient role")
 	}
 }
 
@@ -172,9 +189,7 @@ func (m *AuthMiddleware) RequireOwnership(getOwnerID func(*fiber.Ctx) (uuid.UUID
 
 		userID, ok := c.Locals(UserIDKey).(uuid.UUID)
 		if !ok {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied",
-			})
+			return m.deny(c, "ownership", "Access denied")
 		}
 
 		ownerID, err := getOwnerID(c)
@@ -185,9 +200,7 @@ func (m *AuthMiddleware) RequireOwnership(getOwnerID func(*fiber.Ctx) (uuid.UUID
 		}
 
 		if userID != ownerID {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied",
-			})
+			return m.deny(c, "ownership", "Access denied")
 		}
 
 		return c.Next()
@@ -232,3 +245,23 @@ func IsAdmin(c *fiber.Ctx) bool {
 	roleName, ok := GetRoleName(c)
 	return ok && roleName == "admin"
 }
+
+// GetImpersonatorID returns the admin acting as the user on impersonated requests
+func GetImpersonatorID(c *fiber.Ctx) (uuid.UUID, bool) {
+	adminID, ok := c.Locals(ImpersonatorIDKey).(uuid.UUID)
+	return adminID, ok
+}
+
+// HasPermission checks if the user has a permission, the way RequirePermission does
+func HasPermission(c *fiber.Ctx, permission string) bool {
+	if IsAdmin(c) {
+		return true
+	}
+	permissions, _ := c.Locals(PermissionsKey).([]string)
+	for _, p := range permissions {
+		if p == permission || p == "*" {
+			return true
+		}
+	}
+	return false
+}
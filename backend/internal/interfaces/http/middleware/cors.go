@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+)
+
+// originPattern is an allowed CORS origin. A host starting with "*." matches
+// any subdomain of the rest, but not the bare domain.
+type originPattern struct {
+	scheme string
+	host   string // Including the port, if any
+	suffix string // Set for subdomain patterns, e.g. ".example.com"
+}
+
+// OriginMatcher returns a function reporting whether an origin is in the
+// allowlist. "*" allows every origin. Entries that are not valid origins never
+// match.
+func OriginMatcher(origins []string) func(origin string) bool {
+	all := false
+	patterns := make([]originPattern, 0, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			all = true
+			continue
+		}
+		scheme, host, ok := splitOrigin(origin)
+		if !ok {
+			continue
+		}
+		p := originPattern{scheme: scheme, host: host}
+		if strings.HasPrefix(host, "*.") {
+			p.suffix = host[1:]
+		}
+		patterns = append(patterns, p)
+	}
+
+	return func(origin string) bool {
+		if all {
+			return true
+		}
+		scheme, host, ok := splitOrigin(origin)
+		if !ok {
+			return false
+		}
+		for _, p := range patterns {
+			if p.scheme != scheme {
+				continue
+			}
+			if p.suffix == "" && p.host == host {
+				return true
+			}
+			if p.suffix != "" && len(host) > len(p.suffix) && strings.HasSuffix(host, p.suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// splitOrigin returns the lowercased scheme and host of an origin, which has
+// no path, query or credentials
+func splitOrigin(origin string) (string, string, bool) {
+	u, err := url.Parse(strings.TrimRight(origin, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" {
+		return "", "", false
+	}
+	return strings.ToLower(u.Scheme), strings.ToLower(u.Host), true
+}
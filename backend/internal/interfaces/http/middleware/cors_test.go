@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+func TestOriginMatcher(t *testing.T) {
+	match := OriginMatcher([]string{"https://panel.example.com", "https://*.example.org", "not an origin"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://panel.example.com", true},
+		{"HTTPS://Panel.Example.com", true},
+		{"https://panel.example.com/", true},
+		{"http://panel.example.com", false}, // Scheme must match
+		{"https://evil.example.com", false},
+		{"https://panel.example.com.evil.net", false},
+		{"https://eu.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false}, // The pattern covers subdomains only
+		{"https://evilexample.org", false},
+		{"https://panel.example.com/path", false},
+		{"https://user@panel.example.com", false},
+		{"not an origin", false},
+		{"null", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.origin); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !OriginMatcher([]string{"*"})("https://anywhere.example.net") {
+		t.Error(`"*" does not match every origin`)
+	}
+}
+
+func TestCORSEchoesOnlyAllowedOrigins(t *testing.T) {
+	app := fiber.New()
+	app.Use(cors.New(cors.Config{
+		AllowOriginsFunc: OriginMatcher([]string{"https://panel.example.com"}),
+		AllowCredentials: true,
+	}))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) })
+
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(fiber.HeaderOrigin, origin)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get(fiber.HeaderAccessControlAllowOrigin)
+	}
+
+	if got := allowOrigin("https://panel.example.com"); got != "https://panel.example.com" {
+		t.Errorf("allowed origin got Access-Control-Allow-Origin %q, want it echoed", got)
+	}
+	for _, origin := range []string{"https://evil.example.com", "null"} {
+		if got := allowOrigin(origin); got != "" {
+			t.Errorf("origin %q got Access-Control-Allow-Origin %q, want none", origin, got)
+		}
+	}
+}
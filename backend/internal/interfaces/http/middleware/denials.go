@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// denialWindow is how long repeated denials of the same user, requirement and
+// route are counted together
+const denialWindow = 10 * time.Minute
+
+// deny rejects a request that lacks required, a permission such as
+// "servers.update", "role:admin" or "ownership", and audits the attempt.
+// Within denialWindow only the 1st, 10th, 100th and so on denial of a user,
+// requirement and route are written, each carrying the number of attempts so
+// far, so a client retrying in a loop cannot flood the audit log.
+func (m *AuthMiddleware) deny(c *fiber.Ctx, required, message string) error {
+	m.recordDenial(c, required)
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func (m *AuthMiddleware) recordDenial(c *fiber.Ctx, required string) {
+	var userID *uuid.UUID
+	subject := c.IP()
+	if id, ok := GetUserID(c); ok {
+		userID = &id
+		subject = id.String()
+	}
+	route := c.Method() + " " + c.Route().Path
+
+	ctx := context.Background()
+	key := redis.BuildKey(redis.PrefixDenial, subject, required, route)
+	attempts, err := m.redis.Incr(ctx, key)
+	if err != nil {
+		// Without the counter every denial is written
+		attempts = 1
+	} else if attempts == 1 {
+		_ = m.redis.Expire(ctx, key, denialWindow)
+	}
+	if !isAuditedAttempt(attempts) {
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"required": required,
+		"method":   c.Method(),
+		"path":     c.Path(),
+		"route":    c.Route().Path,
+		"attempts": attempts,
+		"window":   denialWindow.String(),
+	}
+	if adminID, ok := GetImpersonatorID(c); ok {
+		metadata["impersonator_id"] = adminID
+	}
+	m.db.Create(&entities.AuditLog{
+		UserID:      userID,
+		Action:      entities.AuditActionAccessDenied,
+		Resource:    "authorization",
+		Description: fmt.Sprintf("Denied %s %s, requires %s", c.Method(), c.Path(), required),
+		Metadata:    metadata,
+		IPAddress:   c.IP(),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+	})
+}
+
+// isAuditedAttempt reports whether the nth denial within a window is written:
+// the first, and then each power of ten
+func isAuditedAttempt(n int64) bool {
+	s := strconv.FormatInt(n, 10)
+	for _, r := range s[1:] {
+		if r != '0' {
+			return false
+		}
+	}
+	return s[0] == '1'
+}
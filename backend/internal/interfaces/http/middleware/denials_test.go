@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestIsAuditedAttempt(t *testing.T) {
+	audited := map[int64]bool{1: true, 10: true, 100: true, 1000: true, 1000000: true}
+	for _, n := range []int64{1, 2, 5, 9, 10, 11, 20, 99, 100, 101, 110, 200, 999, 1000, 1001, 1000000} {
+		if got := isAuditedAttempt(n); got != audited[n] {
+			t.Errorf("isAuditedAttempt(%d) = %v, want %v", n, got, audited[n])
+		}
+	}
+}
+
+// denialTest serves a route requiring a permission the caller lacks
+type denialTest struct {
+	app    *fiber.App
+	server *miniredis.Miniredis
+	audits *auditRecorder
+
+	userID  uuid.UUID
+	adminID uuid.UUID
+}
+
+func newDenialTest(t *testing.T) *denialTest {
+	t.Helper()
+	rdb, server := newTestRedis(t)
+	db, audits := newAuditDB(t)
+	auth := NewAuthMiddleware(&config.Config{}, db, rdb)
+	dt := &denialTest{app: fiber.New(), server: server, audits: audits, userID: uuid.New(), adminID: uuid.New()}
+
+	dt.app.Delete("/api/v1/servers/:id", func(c *fiber.Ctx) error {
+		c.Locals(UserIDKey, dt.userID)
+		c.Locals(RoleNameKey, "user")
+		c.Locals(PermissionsKey, []string{"servers.view"})
+		if c.Get("X-Impersonated") != "" {
+			c.Locals(ImpersonatorIDKey, dt.adminID)
+		}
+		return c.Next()
+	}, auth.RequirePermission("servers.delete"), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+	return dt
+}
+
+func (dt *denialTest) attempt(t *testing.T, impersonated bool) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/servers/"+uuid.NewString(), nil)
+	req.Header.Set(fiber.HeaderUserAgent, "curl/8.4.0")
+	if impersonated {
+		req.Header.Set("X-Impersonated", "1")
+	}
+	resp, err := dt.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestDenialAuditSampling(t *testing.T) {
+	dt := newDenialTest(t)
+
+	var written []interface{}
+	for i := 1; i <= 100; i++ {
+		dt.attempt(t, false)
+		entries := dt.audits.of("authorization")
+		if len(entries) > len(written) {
+			written = append(written, entries[len(entries)-1].Metadata["attempts"])
+		}
+	}
+	if len(written) != 3 || written[0] != int64(1) || written[1] != int64(10) || written[2] != int64(100) {
+		t.Fatalf("audited attempts = %v, want [1 10 100]", written)
+	}
+
+	key := redis.BuildKey(redis.PrefixDenial, dt.userID.String(), "servers.delete", "DELETE /api/v1/servers/:id")
+	if ttl := dt.server.TTL(key); ttl <= 0 || ttl > denialWindow {
+		t.Errorf("counter TTL = %v, want at most %v", ttl, denialWindow)
+	}
+
+	// A new window starts counting again
+	dt.server.FastForward(denialWindow)
+	dt.attempt(t, false)
+	if got := len(dt.audits.of("authorization")); got != 4 {
+		t.Errorf("audit entries after the window = %d, want 4", got)
+	}
+}
+
+func TestDenialAuditEntry(t *testing.T) {
+	dt := newDenialTest(t)
+	dt.attempt(t, true)
+
+	entries := dt.audits.of("authorization")
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Action != entities.AuditActionAccessDenied {
+		t.Errorf("action = %q, want %q", entry.Action, entities.AuditActionAccessDenied)
+	}
+	if entry.UserID == nil || *entry.UserID != dt.userID {
+		t.Errorf("user = %v, want %s", entry.UserID, dt.userID)
+	}
+	if entry.IPAddress == "" || entry.UserAgent != "curl/8.4.0" {
+		t.Errorf("ip = %q, user agent = %q", entry.IPAddress, entry.UserAgent)
+	}
+
+	want := map[string]interface{}{
+		"required":        "servers.delete",
+		"method":          http.MethodDelete,
+		"route":           "/api/v1/servers/:id",
+		"attempts":        int64(1),
+		"window":          denialWindow.String(),
+		"impersonator_id": dt.adminID,
+	}
+	for key, value := range want {
+		if entry.Metadata[key] != value {
+			t.Errorf("metadata[%s] = %v, want %v", key, entry.Metadata[key], value)
+		}
+	}
+	if path, _ := entry.Metadata["path"].(string); len(path) <= len("/api/v1/servers/") {
+		t.Errorf("metadata[path] = %q, want the requested path", path)
+	}
+}
+
+func TestDenialAuditWithoutRedis(t *testing.T) {
+	dt := newDenialTest(t)
+	dt.server.Close()
+
+	// Without the counter nothing is sampled away
+	for i := 0; i < 3; i++ {
+		dt.attempt(t, false)
+	}
+	if got := len(dt.audits.of("authorization")); got != 3 {
+		t.Errorf("audit entries = %d, want 3", got)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/alicebob/miniredis/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRedis connects a client to an in-memory Redis server
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	port, _ := strconv.Atoi(server.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: server.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, server
+}
+
+// auditRecorder holds the audit entries created through its database
+type auditRecorder struct {
+	mu      sync.Mutex
+	entries []*entities.AuditLog
+}
+
+// of returns the recorded entries of a resource
+func (r *auditRecorder) of(resource string) []*entities.AuditLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []*entities.AuditLog
+	for _, entry := range r.entries {
+		if entry.Resource == resource {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// newAuditDB opens a GORM database that runs no SQL, so queries find nothing,
+// and records the audit entries created through it
+func newAuditDB(t *testing.T) (*gorm.DB, *auditRecorder) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &auditRecorder{}
+	err = db.Callback().Create().Before("gorm:create").Register("test:record_audit", func(tx *gorm.DB) {
+		if entry, ok := tx.Statement.Dest.(*entities.AuditLog); ok {
+			recorder.mu.Lock()
+			recorder.entries = append(recorder.entries, entry)
+			recorder.mu.Unlock()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, recorder
+}
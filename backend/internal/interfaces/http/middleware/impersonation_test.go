@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/crypto"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// impersonationTest serves a few protected routes the way the panel does
+type impersonationTest struct {
+	app    *fiber.App
+	rdb    *redis.Client
+	keys   *crypto.KeyRing
+	audits *auditRecorder
+
+	adminID uuid.UUID
+	userID  uuid.UUID
+}
+
+func newImpersonationTest(t *testing.T) *impersonationTest {
+	t.Helper()
+	rdb, _ := newTestRedis(t)
+	db, audits := newAuditDB(t)
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", CurrentKey: config.LegacyJWTKeyID}}
+	auth := NewAuthMiddleware(cfg, db, rdb)
+
+	app := fiber.New()
+	protected := app.Group("/api/v1", auth.Authenticate, NewImpersonation(db).Audit)
+	protected.Get("/auth/me", func(c *fiber.Ctx) error {
+		userID, _ := GetUserID(c)
+		role, _ := GetRoleName(c)
+		adminID, _ := GetImpersonatorID(c)
+		return c.JSON(fiber.Map{"user_id": userID, "role": role, "impersonator_id": adminID})
+	})
+	protected.Post("/auth/2fa/enable", DenyImpersonated, func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) })
+	protected.Put("/servers/:id", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) })
+	protected.Get("/admin/settings", auth.RequireRole("admin"), func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) })
+
+	return &impersonationTest{
+		app:     app,
+		rdb:     rdb,
+		keys:    crypto.NewKeyRing(cfg.JWT),
+		audits:  audits,
+		adminID: uuid.New(),
+		userID:  uuid.New(),
+	}
+}
+
+// token signs a token for the user, as an impersonation by the admin when
+// impersonated is set. Impersonation tokens get a live session.
+func (it *impersonationTest) token(t *testing.T, impersonated bool) string {
+	t.Helper()
+	claims := &Claims{
+		UserID:   it.userID,
+		Username: "steve",
+		RoleName: "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+		},
+	}
+	if impersonated {
+		claims.ImpersonatorID = &it.adminID
+		ctx := context.Background()
+		if err := it.rdb.Set(ctx, ImpersonationKey(claims.ID), it.adminID.String(), 15*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := it.rdb.SAdd(ctx, ImpersonationUserKey(it.userID), claims.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := it.keys.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (it *impersonationTest) do(t *testing.T, method, path, token string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	resp, err := it.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestImpersonationIsScopedToTheUser(t *testing.T) {
+	it := newImpersonationTest(t)
+	token := it.token(t, true)
+
+	resp := it.do(t, http.MethodGet, "/api/v1/auth/me", token)
+	var me struct {
+		UserID         uuid.UUID `json:"user_id"`
+		Role           string    `json:"role"`
+		ImpersonatorID uuid.UUID `json:"impersonator_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		t.Fatal(err)
+	}
+	if me.UserID != it.userID || me.Role != "user" || me.ImpersonatorID != it.adminID {
+		t.Errorf("impersonated request ran as %+v, want user %s with role user impersonated by %s", me, it.userID, it.adminID)
+	}
+
+	// The admin's own rights do not carry over
+	if resp := it.do(t, http.MethodGet, "/api/v1/admin/settings", token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("admin route = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestImpersonationRevocation(t *testing.T) {
+	it := newImpersonationTest(t)
+	impersonated := it.token(t, true)
+	own := it.token(t, false)
+
+	if resp := it.do(t, http.MethodGet, "/api/v1/auth/me", impersonated); resp.StatusCode != http.StatusOK {
+		t.Fatalf("before revocation = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if err := RevokeImpersonations(context.Background(), it.rdb, it.userID); err != nil {
+		t.Fatal(err)
+	}
+	if resp := it.do(t, http.MethodGet, "/api/v1/auth/me", impersonated); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("after revocation = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if exists, _ := it.rdb.Exists(context.Background(), ImpersonationUserKey(it.userID)); exists {
+		t.Error("the user's session set was kept")
+	}
+
+	// The user's own sessions are untouched
+	if resp := it.do(t, http.MethodGet, "/api/v1/auth/me", own); resp.StatusCode != http.StatusOK {
+		t.Errorf("user's own token = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestImpersonationCannotChangeTwoFactor(t *testing.T) {
+	it := newImpersonationTest(t)
+
+	if resp := it.do(t, http.MethodPost, "/api/v1/auth/2fa/enable", it.token(t, true)); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("impersonated 2FA change = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if resp := it.do(t, http.MethodPost, "/api/v1/auth/2fa/enable", it.token(t, false)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("user's own 2FA change = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestImpersonationAuditRecordsBothIdentities(t *testing.T) {
+	it := newImpersonationTest(t)
+	token := it.token(t, true)
+
+	it.do(t, http.MethodGet, "/api/v1/auth/me", token)
+	if entries := it.audits.of("impersonation"); len(entries) != 0 {
+		t.Fatalf("reads were audited: %d entries", len(entries))
+	}
+
+	it.do(t, http.MethodPut, "/api/v1/servers/42", token)
+	it.do(t, http.MethodPut, "/api/v1/servers/42", it.token(t, false))
+
+	entries := it.audits.of("impersonation")
+	if len(entries) != 1 {
+		t.Fatalf("audited %d impersonated writes, want 1", len(entries))
+	}
+	entry := entries[0]
+	if *entry.UserID != it.userID || entry.Metadata["impersonator_id"] != it.adminID {
+		t.Errorf("audit entry is for user %s by %v, want user %s by %s", *entry.UserID, entry.Metadata["impersonator_id"], it.userID, it.adminID)
+	}
+	if entry.Metadata["method"] != http.MethodPut || entry.Metadata["path"] != "/api/v1/servers/42" || entry.Metadata["succeeded"] != true {
+		t.Errorf("audit metadata = %v", entry.Metadata)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RequestIDKey is the local requestid.New stores the request ID under. It is
+// the ID returned in the X-Request-ID header.
+const RequestIDKey = "requestid"
+
+// RequestLogging adds the request ID to the user context, so loggers derived
+// from it with logger.Ctx tie their lines to the request. It has to run after
+// requestid.New.
+func RequestLogging() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id := GetRequestID(c); id != "" {
+			c.SetUserContext(logger.WithFields(c.UserContext(), zap.String("request_id", id)))
+		}
+		return c.Next()
+	}
+}
+
+// GetRequestID extracts the request ID from context
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(RequestIDKey).(string)
+	return id
+}
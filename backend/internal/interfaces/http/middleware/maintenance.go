@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// MaintenanceKey is the Redis key holding the panel maintenance state
+const MaintenanceKey = "panel:maintenance"
+
+// Default Retry-After sent while the panel is in maintenance
+const defaultMaintenanceRetryAfter = 300 // seconds
+
+// MaintenanceState describes an active maintenance window
+type MaintenanceState struct {
+	Enabled      bool        `json:"enabled"`
+	Message      string      `json:"message"`
+	RetryAfter   int         `json:"retry_after"`   // Seconds clients should wait before retrying
+	AllowedUsers []uuid.UUID `json:"allowed_users"` // Users who may still make changes
+	StartedAt    *time.Time  `json:"started_at,omitempty"`
+	StartedBy    *uuid.UUID  `json:"started_by,omitempty"`
+}
+
+// allows reports whether a user may make changes during maintenance
+func (s *MaintenanceState) allows(userID uuid.UUID) bool {
+	for _, id := range s.AllowedUsers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Maintenance puts the whole panel in read-only mode. The state lives in
+// Redis so every panel instance enforces the same window.
+type Maintenance struct {
+	redis    *redis.Client
+	settings *database.Settings
+}
+
+// NewMaintenance creates a new Maintenance
+func NewMaintenance(rdb *redis.Client, settings *database.Settings) *Maintenance {
+	return &Maintenance{redis: rdb, settings: settings}
+}
+
+// State returns the current maintenance state. A missing or unreadable state
+// means the panel is not in maintenance.
+func (m *Maintenance) State(ctx context.Context) *MaintenanceState {
+	var state MaintenanceState
+	if err := m.redis.GetJSON(ctx, MaintenanceKey, &state); err != nil {
+		return &MaintenanceState{}
+	}
+	return &state
+}
+
+// Enable starts maintenance with the given state
+func (m *Maintenance) Enable(ctx context.Context, state *MaintenanceState) error {
+	state.Enabled = true
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = defaultMaintenanceRetryAfter
+	}
+	return m.redis.SetJSON(ctx, MaintenanceKey, state, 0)
+}
+
+// Disable ends maintenance
+func (m *Maintenance) Disable(ctx context.Context) error {
+	return m.redis.Delete(ctx, MaintenanceKey)
+}
+
+// Enforce rejects mutating requests with 503 while maintenance is enabled.
+// Reads, auth and admin endpoints and allowlisted users are let through. It
+// must run after Authenticate.
+func (m *Maintenance) Enforce(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+
+	path := c.Path()
+	if strings.HasPrefix(path, "/api/v1/auth/") || strings.HasPrefix(path, "/api/v1/admin/") {
+		return c.Next()
+	}
+
+	state := m.State(c.UserContext())
+	if !state.Enabled {
+		return c.Next()
+	}
+	if userID, ok := GetUserID(c); ok && state.allows(userID) {
+		return c.Next()
+	}
+
+	message := state.Message
+	if message == "" {
+		message = m.settings.String(c.UserContext(), database.SettingMaintenanceMessage)
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(state.RetryAfter))
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error":       message,
+		"code":        "panel.maintenance",
+		"maintenance": true,
+	})
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maintenanceApp serves routes behind Enforce for the user in the X-User header
+func maintenanceApp(m *Maintenance) *fiber.App {
+	app := fiber.New()
+	api := app.Group("/api/v1", func(c *fiber.Ctx) error {
+		if id, err := uuid.Parse(c.Get("X-User")); err == nil {
+			c.Locals(UserIDKey, id)
+		}
+		return c.Next()
+	}, m.Enforce)
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) }
+	api.Get("/servers", ok)
+	api.Post("/servers", ok)
+	api.Post("/auth/login", ok)
+	api.Put("/admin/maintenance", ok)
+	return app
+}
+
+func maintenanceRequest(t *testing.T, app *fiber.App, method, path string, userID uuid.UUID) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-User", userID.String())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMaintenanceBlocksWritesOfNormalUsers(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	db, _ := newAuditDB(t)
+	m := NewMaintenance(rdb, database.NewSettings(db, rdb))
+	app := maintenanceApp(m)
+	user := uuid.New()
+
+	if resp := maintenanceRequest(t, app, http.MethodPost, "/api/v1/servers", user); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("write before maintenance = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	if err := m.Enable(context.Background(), &MaintenanceState{Message: "Upgrading to 2.0", RetryAfter: 120}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := maintenanceRequest(t, app, http.MethodPost, "/api/v1/servers", user)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("write during maintenance = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "120" {
+		t.Errorf("Retry-After = %q, want 120", got)
+	}
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error != "Upgrading to 2.0" || body.Code != "panel.maintenance" {
+		t.Errorf("body = %+v", body)
+	}
+
+	// Reads and signing in keep working
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/servers"},
+		{http.MethodPost, "/api/v1/auth/login"},
+	} {
+		if resp := maintenanceRequest(t, app, tt.method, tt.path, user); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("%s %s during maintenance = %d, want %d", tt.method, tt.path, resp.StatusCode, http.StatusNoContent)
+		}
+	}
+}
+
+func TestMaintenanceLetsAdminsThrough(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	db, _ := newAuditDB(t)
+	m := NewMaintenance(rdb, database.NewSettings(db, rdb))
+	app := maintenanceApp(m)
+	allowed, other := uuid.New(), uuid.New()
+
+	if err := m.Enable(context.Background(), &MaintenanceState{AllowedUsers: []uuid.UUID{allowed}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := maintenanceRequest(t, app, http.MethodPost, "/api/v1/servers", allowed); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("allowlisted admin write = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	resp := maintenanceRequest(t, app, http.MethodPost, "/api/v1/servers", other)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("other user write = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "300" {
+		t.Errorf("default Retry-After = %q, want 300", got)
+	}
+
+	// Admin endpoints stay open so maintenance can be turned off again
+	if resp := maintenanceRequest(t, app, http.MethodPut, "/api/v1/admin/maintenance", other); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("admin endpoint = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestMaintenanceTogglePersists(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	db, _ := newAuditDB(t)
+	ctx := context.Background()
+	startedBy := uuid.New()
+
+	if err := NewMaintenance(rdb, database.NewSettings(db, rdb)).Enable(ctx, &MaintenanceState{Message: "Back soon", StartedBy: &startedBy}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another panel instance, or this one after a restart, sees the same window
+	other := NewMaintenance(rdb, database.NewSettings(db, rdb))
+	state := other.State(ctx)
+	if !state.Enabled || state.Message != "Back soon" || state.StartedBy == nil || *state.StartedBy != startedBy {
+		t.Fatalf("state seen by another instance = %+v", state)
+	}
+	if resp := maintenanceRequest(t, maintenanceApp(other), http.MethodPost, "/api/v1/servers", uuid.New()); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("write on another instance = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	if err := other.Disable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMaintenance(rdb, database.NewSettings(db, rdb))
+	if m.State(ctx).Enabled {
+		t.Error("maintenance still enabled after Disable")
+	}
+	if resp := maintenanceRequest(t, maintenanceApp(m), http.MethodPost, "/api/v1/servers", uuid.New()); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("write after maintenance = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
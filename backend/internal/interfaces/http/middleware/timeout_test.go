@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTimeoutBoundsTheUserContext(t *testing.T) {
+	var ctxErr error
+	var deadline time.Duration
+	app := fiber.New()
+	handler := func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		if d, ok := ctx.Deadline(); ok {
+			deadline = time.Until(d)
+		}
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-time.After(time.Second):
+		}
+		return c.SendStatus(http.StatusOK)
+	}
+	app.Get("/bounded", Timeout(50*time.Millisecond), handler)
+	app.Get("/unbounded", Timeout(0), handler)
+
+	started := time.Now()
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/bounded", nil), -1); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(ctxErr, context.DeadlineExceeded) || time.Since(started) > 500*time.Millisecond {
+		t.Errorf("context ended with %v after %s, want the 50ms deadline", ctxErr, time.Since(started))
+	}
+	if deadline <= 0 || deadline > 50*time.Millisecond {
+		t.Errorf("deadline in %s, want within 50ms", deadline)
+	}
+
+	ctxErr, deadline = nil, 0
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/unbounded", nil), -1); err != nil {
+		t.Fatal(err)
+	}
+	if ctxErr != nil || deadline != 0 {
+		t.Errorf("zero timeout set a deadline (%s, %v)", deadline, ctxErr)
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// defaultMessages maps validation tags to message templates.
+// {field} and {param} are replaced with the field name and rule parameter.
+var defaultMessages = map[string]string{
+	"required": "{field} is required",
+	"email":    "{field} must be a valid email address",
+	"url":      "{field} must be a valid URL",
+	"uuid":     "{field} must be a valid UUID",
+	"fqdn":     "{field} must be a fully qualified domain name",
+	"ip":       "{field} must be a valid IP address",
+	"alphanum": "{field} may only contain letters and numbers",
+	"oneof":    "{field} must be one of: {param}",
+	"len":      "{field} must be exactly {param}",
+	"eq":       "{field} must be equal to {param}",
+	"gt":       "{field} must be greater than {param}",
+	"gte":      "{field} must be at least {param}",
+	"lt":       "{field} must be less than {param}",
+	"lte":      "{field} must be at most {param}",
+	"gtefield": "{field} must be greater than or equal to {param}",
+	"eqfield":  "{field} must match {param}",
+}
+
+// Validator runs struct validation and produces structured field errors
+type Validator struct {
+	validate *validator.Validate
+	messages map[string]string
+}
+
+// NewValidator creates a new Validator that reports fields by their JSON name
+func NewValidator() *Validator {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	messages := make(map[string]string, len(defaultMessages))
+	for tag, msg := range defaultMessages {
+		messages[tag] = msg
+	}
+
+	return &Validator{validate: v, messages: messages}
+}
+
+// RegisterMessage sets a custom message template for a validation tag
+func (v *Validator) RegisterMessage(tag, message string) {
+	v.messages[tag] = message
+}
+
+// Engine returns the underlying validator, e.g. to register custom rules
+func (v *Validator) Engine() *validator.Validate {
+	return v.validate
+}
+
+// Validate validates a struct and returns its field errors, or nil if it is valid
+func (v *Validator) Validate(s interface{}) []FieldError {
+	err := v.validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []FieldError{{Rule: "invalid", Message: err.Error()}}
+	}
+
+	fields := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: v.message(fe),
+		})
+	}
+	return fields
+}
+
+// message renders the human readable message for a failed rule
+func (v *Validator) message(fe validator.FieldError) string {
+	field := fe.Field()
+	tmpl, ok := v.messages[fe.Tag()]
+	if !ok {
+		switch fe.Tag() {
+		case "min", "max":
+			tmpl = sizeMessage(fe)
+		default:
+			tmpl = "{field} is invalid"
+		}
+	}
+
+	return strings.NewReplacer("{field}", field, "{param}", fe.Param()).Replace(tmpl)
+}
+
+// sizeMessage returns the min/max message appropriate to the field's kind
+func sizeMessage(fe validator.FieldError) string {
+	bound := "at least"
+	if fe.Tag() == "max" {
+		bound = "at most"
+	}
+
+	switch fe.Kind() {
+	case reflect.String:
+		return "{field} must be " + bound + " {param} characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "{field} must contain " + bound + " {param} items"
+	default:
+		return "{field} must be " + bound + " {param}"
+	}
+}
+
+// fieldPath returns the JSON path of a field without the root struct name
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// ValidationFailed writes the standard validation error response
+func ValidationFailed(c *fiber.Ctx, fields []FieldError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "Validation failed",
+		"details": fields,
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type validatedRequest struct {
+	Name   string `json:"name" validate:"required"`
+	Memory int    `json:"memory" validate:"min=128,max=65536"`
+	Port   int    `json:"port" validate:"omitempty,gte=1024"`
+}
+
+func TestValidateReportsFieldErrors(t *testing.T) {
+	v := NewValidator()
+
+	for name, tc := range map[string]struct {
+		req  validatedRequest
+		want []FieldError
+	}{
+		"missing required field": {
+			validatedRequest{Memory: 1024},
+			[]FieldError{{Field: "name", Rule: "required", Message: "name is required"}},
+		},
+		"number above its range": {
+			validatedRequest{Name: "survival", Memory: 131072},
+			[]FieldError{{Field: "memory", Rule: "max", Param: "65536", Message: "memory must be at most 65536"}},
+		},
+		"number below its range": {
+			validatedRequest{Name: "survival", Memory: 1024, Port: 80},
+			[]FieldError{{Field: "port", Rule: "gte", Param: "1024", Message: "port must be at least 1024"}},
+		},
+		"valid": {validatedRequest{Name: "survival", Memory: 1024}, nil},
+	} {
+		if got := v.Validate(tc.req); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: field errors %+v, want %+v", name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateUsesCustomMessages(t *testing.T) {
+	v := NewValidator()
+	v.RegisterMessage("required", "please fill in {field}")
+
+	fields := v.Validate(validatedRequest{Memory: 1024})
+	if len(fields) != 1 || fields[0].Message != "please fill in name" {
+		t.Errorf("field errors %+v, want the custom required message", fields)
+	}
+}
+
+func TestValidationFailedResponse(t *testing.T) {
+	v := NewValidator()
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return ValidationFailed(c, v.Validate(validatedRequest{Memory: 64}))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	var body struct {
+		Error   string       `json:"error"`
+		Details []FieldError `json:"details"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "memory", Rule: "min", Param: "128", Message: "memory must be at least 128"},
+	}
+	if body.Error != "Validation failed" || !slices.Equal(body.Details, want) {
+		t.Errorf("body = %+v, want the validation error with %+v", body, want)
+	}
+}
@@ -0,0 +1,426 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Channels of the multiplexed server socket, each carried in frames of the
+// same type
+const (
+	channelConsole      = "console"
+	channelStats        = "stats"
+	channelStatus       = "status"
+	channelNotification = "notification"
+)
+
+// socketConsoleKey is the local recording whether the user may use the console
+const socketConsoleKey = "socket_console"
+
+// socketCommanderKey is the local holding the socketCommander of a connection
+const socketCommanderKey = "socket_commander"
+
+// socketCommander is who sends the console commands of a server socket, for
+// checking them against the server's command policy. The owner, admins and
+// holders of entities.PermissionBypassCommandPolicy are trusted with every
+// command.
+type socketCommander struct {
+	policy  entities.CommandPolicy
+	trusted bool
+	userID  uuid.UUID
+	ip      string
+}
+
+// check refuses a command the server's policy blocks for the commander,
+// auditing the attempt through audit
+func (cmd *socketCommander) check(serverID, command string, audit func(entry *entities.AuditLog)) error {
+	if cmd.trusted || cmd.policy.Allows(command) {
+		return nil
+	}
+
+	id, _ := uuid.Parse(serverID)
+	audit(&entities.AuditLog{
+		UserID:     &cmd.userID,
+		Action:     entities.AuditActionCommandBlocked,
+		Resource:   "server",
+		ResourceID: &id,
+		Metadata:   map[string]interface{}{"command": command},
+		IPAddress:  cmd.ip,
+	})
+	return services.ErrCommandBlocked
+}
+
+// socketFrame is a message on the multiplexed server socket. The server sends
+// channel frames with Data, "subscribed" after each change and "error". The
+// client sends "subscribe" and "unsubscribe" with Channels, and "command" with
+// the console command as Data.
+type socketFrame struct {
+	Type     string          `json:"type"`
+	Channels []string        `json:"channels,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// serverSocketAccess lets the upgrade of a server socket through for the
+// server's owner and admins
+func serverSocketAccess(db *gorm.DB) fiber.Handler {
+	return socketAccess(func(id string) (*entities.Server, error) {
+		var server entities.Server
+		if err := db.Select("id", "owner_id", "command_policy").Where("id = ?", id).First(&server).Error; err != nil {
+			return nil, err
+		}
+		return &server, nil
+	})
+}
+
+// socketAccess is serverSocketAccess with the server looked up by load. It
+// records whether the user may use the console and who sends its commands.
+func socketAccess(load func(id string) (*entities.Server, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		server, err := load(c.Params("serverId"))
+		if err != nil {
+			return services.ErrServerNotFound
+		}
+
+		userID, _ := middleware.GetUserID(c)
+		if !server.ManageableBy(userID, middleware.IsAdmin(c)) {
+			return fiber.NewError(http.StatusForbidden, "Access denied")
+		}
+
+		c.Locals(socketConsoleKey, middleware.HasPermission(c, "servers.console"))
+		c.Locals(socketCommanderKey, &socketCommander{
+			policy:  server.CommandPolicy,
+			trusted: server.OwnerID == userID || middleware.HasPermission(c, entities.PermissionBypassCommandPolicy),
+			userID:  userID,
+			ip:      c.IP(),
+		})
+		return c.Next()
+	}
+}
+
+// serverSocket is one multiplexed connection for a server's console, stats,
+// status changes and notifications
+type serverSocket struct {
+	rdb      *redis.Client
+	pubsub   *goredis.PubSub
+	serverID string
+	console  bool // User may use the console
+
+	mu         sync.Mutex
+	subscribed map[string]bool
+
+	out chan socketFrame
+}
+
+// handleServerSocket carries the channels a client subscribes to over one
+// connection, so a browser keeps a single authenticated socket per server.
+// Initial channels may be given as ?channels=console,stats.
+func handleServerSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client, db *gorm.DB, log *zap.Logger) {
+	limits := cfg.Console
+	mayConsole, _ := c.Locals(socketConsoleKey).(bool)
+	commander, _ := c.Locals(socketCommanderKey).(*socketCommander)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &serverSocket{
+		rdb:        rdb,
+		pubsub:     rdb.Subscribe(ctx),
+		serverID:   c.Params("serverId"),
+		console:    mayConsole,
+		subscribed: make(map[string]bool),
+		out:        make(chan socketFrame, max(limits.OutputBuffer, 1)),
+	}
+	defer s.pubsub.Close()
+
+	go s.relay()
+
+	// Write queued frames to the client. This is the only goroutine writing
+	// data frames to the connection.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case frame := <-s.out:
+				payload, err := json.Marshal(frame)
+				if err != nil {
+					continue
+				}
+				if limits.WriteTimeout > 0 {
+					_ = c.SetWriteDeadline(time.Now().Add(limits.WriteTimeout))
+				}
+				if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+					cancel()
+					_ = c.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	if channels := c.Query("channels"); channels != "" {
+		s.subscribe(ctx, strings.Split(channels, ","))
+	}
+
+	// Frames far above the command limit are rejected by the connection itself
+	if limits.MaxCommandLength > 0 {
+		c.SetReadLimit(int64(limits.MaxCommandLength)*4 + 1024)
+	}
+
+	limiter := newInputLimiter(limits.InputRate, limits.InputBurst)
+	violations := 0
+
+	for {
+		_, raw, err := c.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		var frame socketFrame
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			s.send(socketFrame{Type: "error", Error: "invalid frame"})
+			continue
+		}
+
+		switch frame.Type {
+		case "subscribe":
+			s.subscribe(ctx, frame.Channels)
+		case "unsubscribe":
+			s.unsubscribe(ctx, frame.Channels)
+		case "command":
+			var command string
+			if err := json.Unmarshal(frame.Data, &command); err != nil || command == "" {
+				s.send(socketFrame{Type: "error", Error: "command must be a string"})
+				continue
+			}
+			if !s.console {
+				s.send(socketFrame{Type: "error", Error: "console access denied"})
+				continue
+			}
+			if commander != nil {
+				if err := commander.check(s.serverID, command, func(entry *entities.AuditLog) { db.Create(entry) }); err != nil {
+					s.send(socketFrame{Type: "error", Error: err.Error()})
+					continue
+				}
+			}
+
+			var reason string
+			switch {
+			case limits.MaxCommandLength > 0 && len(command) > limits.MaxCommandLength:
+				reason = fmt.Sprintf("command exceeds %d bytes", limits.MaxCommandLength)
+			case !limiter.Allow():
+				reason = "too many commands, slow down"
+			}
+			if reason == "" {
+				_ = rdb.Publish(ctx, redis.PrefixConsole+s.serverID+":input", command)
+				continue
+			}
+
+			violations++
+			if limits.MaxViolations > 0 && violations >= limits.MaxViolations {
+				log.Warn("Closing abusive server socket",
+					zap.String("server_id", s.serverID),
+					zap.String("remote_addr", c.RemoteAddr().String()),
+					zap.Int("violations", violations),
+				)
+				_ = c.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "console input limit exceeded"),
+					time.Now().Add(time.Second))
+				return
+			}
+			s.send(socketFrame{Type: "error", Error: "command rejected: " + reason})
+		default:
+			s.send(socketFrame{Type: "error", Error: "unknown frame type " + frame.Type})
+		}
+	}
+}
+
+// relay turns Pub/Sub messages into frames of the subscribed channels. Stats
+// samples also produce a status frame whenever the server's status changes.
+func (s *serverSocket) relay() {
+	consoleChannel := redis.PrefixConsole + s.serverID
+	statsChannel := agent.StatsKey(s.serverID)
+	eventsChannel := agent.EventsKey(s.serverID)
+	lastStatus := ""
+
+	for msg := range s.pubsub.Channel() {
+		switch msg.Channel {
+		case consoleChannel:
+			if s.wants(channelConsole) {
+				line, _ := json.Marshal(msg.Payload)
+				s.send(socketFrame{Type: channelConsole, Data: line})
+			}
+		case statsChannel:
+			if s.wants(channelStats) {
+				s.send(socketFrame{Type: channelStats, Data: json.RawMessage(msg.Payload)})
+			}
+			if status := statsStatus(msg.Payload); status != "" && status != lastStatus {
+				lastStatus = status
+				if s.wants(channelStatus) {
+					data, _ := json.Marshal(map[string]string{"status": status})
+					s.send(socketFrame{Type: channelStatus, Data: data})
+				}
+			}
+		case eventsChannel:
+			if s.wants(channelNotification) {
+				s.send(socketFrame{Type: channelNotification, Data: json.RawMessage(msg.Payload)})
+			}
+		}
+	}
+}
+
+// subscribe adds channels and confirms the resulting subscriptions. Stats
+// subscribers get the last known sample right away.
+func (s *serverSocket) subscribe(ctx context.Context, channels []string) {
+	var rejected []string
+	var added []string
+
+	s.mu.Lock()
+	before := s.redisChannels()
+	for _, ch := range channels {
+		ch = strings.TrimSpace(ch)
+		switch ch {
+		case channelConsole:
+			if !s.console {
+				rejected = append(rejected, "console access denied")
+				continue
+			}
+		case channelStats, channelStatus, channelNotification:
+		default:
+			rejected = append(rejected, "unknown channel "+ch)
+			continue
+		}
+		if !s.subscribed[ch] {
+			s.subscribed[ch] = true
+			added = append(added, ch)
+		}
+	}
+	after := s.redisChannels()
+	s.mu.Unlock()
+
+	if diff := channelDiff(after, before); len(diff) > 0 {
+		_ = s.pubsub.Subscribe(ctx, diff...)
+	}
+	for _, reason := range rejected {
+		s.send(socketFrame{Type: "error", Error: reason})
+	}
+	s.confirm()
+
+	for _, ch := range added {
+		if ch != channelStats {
+			continue
+		}
+		if stats, err := s.rdb.Get(ctx, agent.StatsKey(s.serverID)); err == nil {
+			s.send(socketFrame{Type: channelStats, Data: json.RawMessage(stats)})
+		}
+	}
+}
+
+// unsubscribe removes channels and confirms the remaining subscriptions
+func (s *serverSocket) unsubscribe(ctx context.Context, channels []string) {
+	s.mu.Lock()
+	before := s.redisChannels()
+	for _, ch := range channels {
+		delete(s.subscribed, strings.TrimSpace(ch))
+	}
+	after := s.redisChannels()
+	s.mu.Unlock()
+
+	if diff := channelDiff(before, after); len(diff) > 0 {
+		_ = s.pubsub.Unsubscribe(ctx, diff...)
+	}
+	s.confirm()
+}
+
+// confirm tells the client which channels it is subscribed to
+func (s *serverSocket) confirm() {
+	s.mu.Lock()
+	channels := make([]string, 0, len(s.subscribed))
+	for ch := range s.subscribed {
+		channels = append(channels, ch)
+	}
+	s.mu.Unlock()
+	sort.Strings(channels)
+
+	s.send(socketFrame{Type: "subscribed", Channels: channels})
+}
+
+// redisChannels returns the Pub/Sub channels the subscribed channels are read
+// from. The caller must hold mu.
+func (s *serverSocket) redisChannels() map[string]bool {
+	channels := make(map[string]bool, 3)
+	if s.subscribed[channelConsole] {
+		channels[redis.PrefixConsole+s.serverID] = true
+	}
+	if s.subscribed[channelStats] || s.subscribed[channelStatus] {
+		channels[agent.StatsKey(s.serverID)] = true
+	}
+	if s.subscribed[channelNotification] {
+		channels[agent.EventsKey(s.serverID)] = true
+	}
+	return channels
+}
+
+func (s *serverSocket) wants(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribed[channel]
+}
+
+// send queues a frame, dropping the oldest queued frame when the client is not
+// keeping up
+func (s *serverSocket) send(frame socketFrame) {
+	select {
+	case s.out <- frame:
+		return
+	default:
+	}
+	select {
+	case <-s.out:
+	default:
+	}
+	select {
+	case s.out <- frame:
+	default:
+	}
+}
+
+// channelDiff returns the channels in a that are not in b
+func channelDiff(a, b map[string]bool) []string {
+	var diff []string
+	for ch := range a {
+		if !b[ch] {
+			diff = append(diff, ch)
+		}
+	}
+	return diff
+}
+
+// statsStatus returns the server status of a stats payload
+func statsStatus(payload string) string {
+	var stats struct {
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal([]byte(payload), &stats)
+	return stats.Status
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aetherpanel/aether-panel/internal/application/services"
+	"github.com/aetherpanel/aether-panel/internal/domain/entities"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
+	"github.com/alicebob/miniredis/v2"
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// socketUser sets the locals Authenticate would for a user
+func socketUser(userID uuid.UUID, role string, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserIDKey, userID)
+		c.Locals(middleware.RoleNameKey, role)
+		c.Locals(middleware.PermissionsKey, permissions)
+		return c.Next()
+	}
+}
+
+func TestSocketAccessCommandPolicy(t *testing.T) {
+	policy := entities.CommandPolicy{Mode: entities.CommandPolicyDeny, Prefixes: []string{"op", "stop"}}
+	server := &entities.Server{ID: uuid.New(), OwnerID: uuid.New(), CommandPolicy: policy}
+	load := func(id string) (*entities.Server, error) {
+		if id != server.ID.String() {
+			return nil, services.ErrServerNotFound
+		}
+		return server, nil
+	}
+
+	var audited []*entities.AuditLog
+	audit := func(entry *entities.AuditLog) { audited = append(audited, entry) }
+
+	// send runs a command through the access checks of the server socket as user
+	send := func(user fiber.Handler, command string) int {
+		app := fiber.New(fiber.Config{ErrorHandler: errorHandler(zap.NewNop())})
+		app.Get("/ws/servers/:serverId", user, socketAccess(load), func(c *fiber.Ctx) error {
+			if console, _ := c.Locals(socketConsoleKey).(bool); !console {
+				return fiber.ErrForbidden
+			}
+			commander := c.Locals(socketCommanderKey).(*socketCommander)
+			if err := commander.check(c.Params("serverId"), command, audit); err != nil {
+				return fiber.NewError(http.StatusForbidden, err.Error())
+			}
+			return c.SendStatus(http.StatusNoContent)
+		})
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/ws/servers/"+server.ID.String(), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// The owner, admins and holders of the bypass permission are trusted with
+	// every command
+	trusted := map[string]fiber.Handler{
+		"owner":  socketUser(server.OwnerID, "user", "servers.console"),
+		"admin":  socketUser(uuid.New(), "admin"),
+		"bypass": socketUser(server.OwnerID, "user", "servers.console", entities.PermissionBypassCommandPolicy),
+	}
+	for name, user := range trusted {
+		if got := send(user, "stop"); got != http.StatusNoContent {
+			t.Errorf("%s stop = %d, want %d", name, got, http.StatusNoContent)
+		}
+	}
+	if len(audited) != 0 {
+		t.Errorf("audited %d entries for trusted users, want none", len(audited))
+	}
+
+	// Anyone else is held to the policy
+	subuser := &socketCommander{policy: policy, userID: uuid.New(), ip: "203.0.113.7"}
+	if err := subuser.check(server.ID.String(), "minecraft:op Steve", audit); !errors.Is(err, services.ErrCommandBlocked) {
+		t.Fatalf("subuser namespaced op = %v, want %v", err, services.ErrCommandBlocked)
+	}
+	if len(audited) != 1 {
+		t.Fatalf("audited %d entries, want 1", len(audited))
+	}
+	entry := audited[0]
+	if entry.Action != entities.AuditActionCommandBlocked || *entry.UserID != subuser.userID || *entry.ResourceID != server.ID ||
+		entry.Metadata["command"] != "minecraft:op Steve" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+	if err := subuser.check(server.ID.String(), "say hello", audit); err != nil {
+		t.Errorf("subuser say = %v", err)
+	}
+
+	// Users who may not manage the server never reach the console
+	if got := send(socketUser(uuid.New(), "user", "servers.console", entities.PermissionBypassCommandPolicy), "say hi"); got != http.StatusForbidden {
+		t.Errorf("stranger = %d, want %d", got, http.StatusForbidden)
+	}
+	if got := send(socketUser(server.OwnerID, "user"), "say hi"); got != http.StatusForbidden {
+		t.Errorf("owner without console permission = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestCommandPolicyAllows(t *testing.T) {
+	deny := entities.CommandPolicy{Mode: entities.CommandPolicyDeny, Prefixes: []string{"op", "/deop", "whitelist add"}}
+	allow := entities.CommandPolicy{Mode: entities.CommandPolicyAllow, Prefixes: []string{"say", "list"}}
+
+	tests := []struct {
+		name    string
+		policy  entities.CommandPolicy
+		command string
+		want    bool
+	}{
+		{"deny exact", deny, "op", false},
+		{"deny with argument", deny, "op Steve", false},
+		{"deny ignores case and slash", deny, "/OP Steve", false},
+		{"deny slash in prefix", deny, "deop Steve", false},
+		{"deny multi-word prefix", deny, "whitelist add Steve", false},
+		{"deny matches whole words only", deny, "options", true},
+		{"deny other subcommand", deny, "whitelist list", true},
+		{"deny namespaced command", deny, "minecraft:op Steve", false},
+		{"deny namespaced with slash", deny, "/Minecraft:DEOP Steve", false},
+		{"deny namespace only in arguments", deny, "say minecraft:op", true},
+		{"allow listed", allow, "say hi", true},
+		{"allow unlisted", allow, "stop", false},
+		{"allow prefix of a longer word", allow, "listen", false},
+		{"no mode allows everything", entities.CommandPolicy{}, "stop", true},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Allows(tt.command); got != tt.want {
+			t.Errorf("%s: Allows(%q) = %v, want %v", tt.name, tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestServerSocketMultiplexesChannels(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	rdb, err := redis.NewRedisClient(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	server := &entities.Server{ID: uuid.New(), OwnerID: uuid.New()}
+	load := func(id string) (*entities.Server, error) { return server, nil }
+	cfg := &config.Config{Console: config.ConsoleConfig{OutputBuffer: 16}}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws/servers/:serverId", socketUser(server.OwnerID, "user", "servers.console"), socketAccess(load),
+		websocket.New(func(c *websocket.Conn) {
+			handleServerSocket(c, cfg, rdb, nil, zap.NewNop())
+		}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	conn, _, err := fastws.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/ws/servers/"+server.ID.String()+"?channels=console,stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	read := func() socketFrame {
+		t.Helper()
+		var frame socketFrame
+		if err := json.Unmarshal([]byte(readText(t, conn)), &frame); err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+	if frame := read(); frame.Type != "subscribed" || strings.Join(frame.Channels, ",") != "console,stats" {
+		t.Fatalf("first frame %+v, want the console and stats subscription", frame)
+	}
+
+	console, stats := redis.PrefixConsole+server.ID.String(), agent.StatsKey(server.ID.String())
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		subs := mr.PubSubNumSub(console, stats)
+		if subs[console] == 1 && subs[stats] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriptions %v, want the console and stats channels", subs)
+		}
+	}
+	publish := func(channel, message string) {
+		t.Helper()
+		if err := rdb.Publish(context.Background(), channel, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both streams arrive on the one connection
+	publish(console, "Server started")
+	publish(stats, `{"cpu":12.5}`)
+	if frame := read(); frame.Type != channelConsole || string(frame.Data) != `"Server started"` {
+		t.Errorf("frame %+v, want the console line", frame)
+	}
+	if frame := read(); frame.Type != channelStats || string(frame.Data) != `{"cpu":12.5}` {
+		t.Errorf("frame %+v, want the stats sample", frame)
+	}
+
+	// Unsubscribing stops stats but leaves the console streaming
+	if err := conn.WriteMessage(fastws.TextMessage, []byte(`{"type":"unsubscribe","channels":["stats"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if frame := read(); frame.Type != "subscribed" || strings.Join(frame.Channels, ",") != "console" {
+		t.Fatalf("frame %+v, want the console subscription alone", frame)
+	}
+	publish(stats, `{"cpu":99}`)
+	publish(console, "Player joined")
+	if frame := read(); frame.Type != channelConsole || string(frame.Data) != `"Player joined"` {
+		t.Errorf("frame %+v after unsubscribing from stats, want the next console line", frame)
+	}
+}
@@ -2,10 +2,14 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/config"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/database"
 	"github.com/aetherpanel/aether-panel/internal/infrastructure/redis"
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/shutdown"
 	"github.com/aetherpanel/aether-panel/internal/interfaces/http/handlers"
 	"github.com/aetherpanel/aether-panel/internal/interfaces/http/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -20,8 +24,9 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewServer creates and configures a new Fiber server
-func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logger) *fiber.App {
+// NewServer creates and configures a new Fiber server. Long operations and
+// WebSockets register with ops so shutdown can drain them.
+func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logger, ops *shutdown.Coordinator) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName:               cfg.App.Name,
 		ReadTimeout:           cfg.Server.ReadTimeout,
@@ -29,7 +34,7 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 		IdleTimeout:           cfg.Server.IdleTimeout,
 		BodyLimit:             cfg.Server.BodyLimit * 1024 * 1024,
 		DisableStartupMessage: cfg.App.Environment == "production",
-		ErrorHandler:          errorHandler,
+		ErrorHandler:          errorHandler(log),
 	})
 
 	// Global middleware
@@ -37,19 +42,25 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 		EnableStackTrace: cfg.App.Debug,
 	}))
 
-	app.Use(requestid.New())
+	app.Use(requestid.New(requestid.Config{
+		ContextKey: middleware.RequestIDKey,
+	}))
+
+	app.Use(middleware.Tracing())
+
+	app.Use(middleware.RequestLogging())
 
 	app.Use(logger.New(logger.Config{
-		Format:     "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path}\n",
+		Format:     "${time} | ${locals:" + middleware.RequestIDKey + "} | ${status} | ${latency} | ${ip} | ${method} | ${path}\n",
 		TimeFormat: "2006-01-02 15:04:05",
 	}))
 
 	app.Use(helmet.New())
 
-	// CORS
+	// CORS, reflecting the matched origin rather than "*" so credentials work
 	if cfg.Server.CORS.Enabled {
 		app.Use(cors.New(cors.Config{
-			AllowOrigins:     joinStrings(cfg.Server.CORS.AllowOrigins),
+			AllowOriginsFunc: middleware.OriginMatcher(cfg.Server.CORS.AllowOrigins),
 			AllowMethods:     joinStrings(cfg.Server.CORS.AllowMethods),
 			AllowHeaders:     joinStrings(cfg.Server.CORS.AllowHeaders),
 			ExposeHeaders:    joinStrings(cfg.Server.CORS.ExposeHeaders),
@@ -73,10 +84,12 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 	}))
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg, rdb)
+	authMiddleware := middleware.NewAuthMiddleware(cfg, db, rdb)
+	maintenance := middleware.NewMaintenance(rdb, database.NewSettings(db, rdb))
+	impersonation := middleware.NewImpersonation(db)
 
 	// Initialize handlers
-	handler := handlers.NewHandler(cfg, db, rdb)
+	handler := handlers.NewHandler(cfg, db, rdb, log, ops)
 	authHandler := handlers.NewAuthHandler(cfg, db, rdb)
 	userHandler := handlers.NewUserHandler(cfg, db, rdb)
 
@@ -95,19 +108,21 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 	auth := api.Group("/auth")
 	auth.Post("/login", authHandler.Login)
 	auth.Post("/register", authHandler.Register)
+	auth.Post("/verify-email", authHandler.VerifyEmail)
+	auth.Post("/resend-verification", authHandler.ResendVerification)
 	auth.Post("/refresh", authHandler.RefreshToken)
 	auth.Post("/forgot-password", authHandler.ForgotPassword)
 	auth.Post("/reset-password", authHandler.ResetPassword)
 
 	// Protected routes
-	protected := api.Group("", authMiddleware.Authenticate)
+	protected := api.Group("", authMiddleware.Authenticate, maintenance.Enforce, impersonation.Audit)
 
 	// Auth (protected)
 	protected.Post("/auth/logout", authHandler.Logout)
 	protected.Get("/auth/me", authHandler.Me)
-	protected.Post("/auth/2fa/enable", authHandler.Enable2FA)
-	protected.Post("/auth/2fa/verify", authHandler.Verify2FA)
-	protected.Post("/auth/2fa/disable", authHandler.Disable2FA)
+	protected.Post("/auth/2fa/enable", middleware.DenyImpersonated, authHandler.Enable2FA)
+	protected.Post("/auth/2fa/verify", middleware.DenyImpersonated, authHandler.Verify2FA)
+	protected.Post("/auth/2fa/disable", middleware.DenyImpersonated, authHandler.Disable2FA)
 
 	// Users
 	users := protected.Group("/users")
@@ -116,18 +131,77 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 	users.Get("/:id", authMiddleware.RequirePermission("users.view"), userHandler.GetByID)
 	users.Put("/:id", authMiddleware.RequirePermission("users.update"), userHandler.Update)
 	users.Delete("/:id", authMiddleware.RequirePermission("users.delete"), userHandler.Delete)
+	users.Post("/:id/impersonate", authMiddleware.RequireRole("admin"), userHandler.Impersonate)
+	users.Delete("/:id/impersonate", authMiddleware.RequireRole("admin"), userHandler.RevokeImpersonation)
+
+	// Reseller sub-accounts
+	reseller := protected.Group("/reseller", authMiddleware.RequireRole("reseller"))
+	reseller.Get("/users", handler.GetSubUsers)
+	reseller.Post("/users", handler.CreateSubUser)
+	reseller.Get("/users/:id", handler.GetSubUser)
+	reseller.Put("/users/:id/status", handler.UpdateSubUserStatus)
+	reseller.Delete("/users/:id", handler.DeleteSubUser)
+	reseller.Post("/users/:id/credits", handler.AllocateSubUserCredits)
+	reseller.Put("/users/:id/quota", handler.SetSubUserQuota)
+	reseller.Get("/servers", handler.GetResellerServers)
+
+	// Allocations
+	protected.Put("/allocations/:id", handler.UpdateAllocation)
+
+	// Invoices
+	protected.Get("/invoices/:id/pdf", handler.DownloadInvoice)
+	protected.Get("/search", handler.Search)
+
+	// Billing
+	protected.Get("/billing/transactions", handler.GetTransactions)
+	protected.Post("/billing/orders", handler.OrderPackage)
+
+	// Notifications
+	notifications := protected.Group("/notifications")
+	notifications.Get("/", handler.GetNotifications)
+	notifications.Delete("/", handler.DeleteNotifications)
+	notifications.Post("/read-all", handler.MarkAllNotificationsRead)
+	notifications.Get("/preferences", handler.GetNotificationPreferences)
+	notifications.Put("/preferences", handler.UpdateNotificationPreferences)
 
 	// Servers
 	servers := protected.Group("/servers")
 
+	// Panel administration
+	admin := protected.Group("/admin", authMiddleware.RequirePermission("admin.settings"))
+	admin.Get("/maintenance", handler.GetMaintenance)
+	admin.Put("/maintenance", handler.UpdateMaintenance)
+	admin.Get("/settings", handler.GetSettings)
+	admin.Put("/settings/:key", handler.UpdateSetting)
+	admin.Put("/eggs/:id", handler.UpdateEgg)
+	admin.Get("/eggs/:id/outdated", handler.GetOutdatedEggServers)
+	admin.Post("/eggs/:id/reapply", handler.ReapplyEgg)
+	admin.Get("/eggs/:id/rollouts", handler.GetImageRollouts)
+	admin.Post("/eggs/:id/rollouts", handler.CreateImageRollout)
+	admin.Get("/eggs/:id/rollouts/:rollout", handler.GetImageRollout)
+
 	// Locations (admin only)
 	locations := protected.Group("/locations", authMiddleware.RequirePermission("nodes.view"))
 	locations.Get("/", handler.GetLocations)
 	locations.Post("/", authMiddleware.RequirePermission("nodes.create"), handler.CreateLocation)
+	locations.Get("/metrics", handler.GetLocationMetrics)
 	locations.Get("/:id", handler.GetLocation)
+	locations.Get("/:id/metrics", handler.GetLocationMetricsByID)
 	locations.Put("/:id", authMiddleware.RequirePermission("nodes.update"), handler.UpdateLocation)
 	locations.Delete("/:id", authMiddleware.RequirePermission("nodes.delete"), handler.DeleteLocation)
 
+	// Private registry credentials (admin only)
+	registries := protected.Group("/registries", authMiddleware.RequirePermission("nodes.view"))
+	registries.Get("/", handler.GetRegistryCredentials)
+	registries.Post("/", authMiddleware.RequirePermission("nodes.update"), handler.CreateRegistryCredential)
+	registries.Delete("/:id", authMiddleware.RequirePermission("nodes.update"), handler.DeleteRegistryCredential)
+
+	// Database hosts for server databases (admin only)
+	databaseHosts := protected.Group("/database-hosts", authMiddleware.RequirePermission("nodes.view"))
+	databaseHosts.Get("/", handler.GetDatabaseHosts)
+	databaseHosts.Post("/", authMiddleware.RequirePermission("nodes.update"), handler.CreateDatabaseHost)
+	databaseHosts.Delete("/:id", authMiddleware.RequirePermission("nodes.update"), handler.DeleteDatabaseHost)
+
 	// Nodes (admin only)
 	nodes := protected.Group("/nodes", authMiddleware.RequirePermission("nodes.view"))
 	nodes.Get("/", handler.GetNodes)
@@ -136,47 +210,129 @@ func NewServer(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log *zap.Logg
 	nodes.Put("/:id", authMiddleware.RequirePermission("nodes.update"), handler.UpdateNode)
 	nodes.Delete("/:id", authMiddleware.RequirePermission("nodes.delete"), handler.DeleteNode)
 	nodes.Get("/:id/configuration", handler.GetNodeConfiguration)
-
-	// Servers - update to use new handler
+	nodes.Post("/:id/health", authMiddleware.RequirePermission("nodes.update"), handler.CheckNodeHealth)
+	nodes.Post("/:id/allocations", authMiddleware.RequirePermission("nodes.update"), handler.CreateNodeAllocations)
+	nodes.Post("/:id/import", authMiddleware.RequirePermission("nodes.update"), handler.ImportNodeServers)
+	nodes.Post("/:id/orphans", authMiddleware.RequirePermission("nodes.update"), handler.CleanNodeOrphans)
+	nodes.Get("/:id/warmup", handler.GetNodeWarmup)
+	nodes.Post("/:id/warmup", authMiddleware.RequirePermission("nodes.update"), handler.WarmNode)
+
+	// Webhooks (admin only)
+	webhooks := protected.Group("/webhooks", authMiddleware.RequirePermission("admin.settings"))
+	webhooks.Get("/", handler.GetWebhooks)
+	webhooks.Post("/", handler.CreateWebhook)
+	webhooks.Get("/:id", handler.GetWebhook)
+	webhooks.Put("/:id", handler.UpdateWebhook)
+	webhooks.Delete("/:id", handler.DeleteWebhook)
+
+	// Servers - update to use new handler. Routes that wait on an agent are
+	// bounded by the timeout of its operation class.
+	timeouts := cfg.Agents.Timeouts
 	servers.Get("/", handler.GetServers)
 	servers.Post("/", authMiddleware.RequirePermission("servers.create"), handler.CreateServer)
+	servers.Post("/plan", authMiddleware.RequirePermission("servers.create"), handler.PlanServer)
+	servers.Post("/power/bulk", authMiddleware.RequirePermission("servers.power"), middleware.Timeout(timeouts.Power), handler.BulkPowerServers)
 	servers.Get("/:id", handler.GetServer)
 	servers.Put("/:id", handler.UpdateServer)
 	servers.Delete("/:id", authMiddleware.RequirePermission("servers.delete"), handler.DeleteServer)
 
 	// Server power actions - update to use new handler
-	servers.Post("/:id/start", handler.StartServer)
-	servers.Post("/:id/stop", handler.StopServer)
-	servers.Post("/:id/restart", handler.RestartServer)
+	servers.Post("/:id/start", middleware.Timeout(timeouts.Power), handler.StartServer)
+	servers.Post("/:id/stop", middleware.Timeout(timeouts.Power), handler.StopServer)
+	servers.Post("/:id/restart", middleware.Timeout(timeouts.Power), handler.RestartServer)
+	servers.Post("/:id/reinstall", middleware.Timeout(timeouts.Install+timeouts.Backup), handler.ReinstallServer)
+	servers.Get("/:id/command-history", authMiddleware.RequirePermission("servers.console"), handler.GetCommandHistory)
+	servers.Post("/:id/allocations/:allocId/primary", authMiddleware.RequirePermission("servers.update"), handler.SetPrimaryAllocation)
+	servers.Get("/:id/chat", authMiddleware.RequirePermission("servers.console"), middleware.Timeout(timeouts.Query), handler.GetChatLogs)
+	servers.Get("/:id/leaderboard", handler.GetLeaderboard)
+	servers.Get("/:id/activity", handler.GetServerActivity)
+	servers.Post("/:id/reconcile", middleware.Timeout(timeouts.Query), handler.ReconcileServer)
+	servers.Put("/:id/crash-recovery", authMiddleware.RequirePermission("servers.update"), middleware.Timeout(timeouts.Update), handler.UpdateCrashRecovery)
+	servers.Put("/:id/console-output", authMiddleware.RequirePermission("servers.update"), middleware.Timeout(timeouts.Update), handler.UpdateConsoleOutput)
+	servers.Put("/:id/resource-alerts", authMiddleware.RequirePermission("servers.update"), handler.UpdateResourceAlerts)
+	servers.Post("/:id/mounts", authMiddleware.RequirePermission("nodes.update"), middleware.Timeout(timeouts.Update), handler.AttachServerMount)
+	servers.Delete("/:id/mounts", authMiddleware.RequirePermission("nodes.update"), middleware.Timeout(timeouts.Update), handler.DetachServerMount)
+	servers.Post("/:id/tags", authMiddleware.RequirePermission("servers.update"), handler.AddServerTags)
+	servers.Delete("/:id/tags/:tag", authMiddleware.RequirePermission("servers.update"), handler.RemoveServerTag)
+	servers.Put("/:id/command-policy", authMiddleware.RequirePermission("servers.update"), handler.UpdateCommandPolicy)
+	servers.Get("/:id/webhooks", handler.GetServerWebhooks)
+	servers.Post("/:id/webhooks", authMiddleware.RequirePermission("servers.update"), handler.CreateServerWebhook)
+	servers.Put("/:id/webhooks/:webhookId", authMiddleware.RequirePermission("servers.update"), handler.UpdateServerWebhook)
+	servers.Delete("/:id/webhooks/:webhookId", authMiddleware.RequirePermission("servers.update"), handler.DeleteServerWebhook)
+	servers.Get("/:id/webhooks/:webhookId/deliveries", handler.GetServerWebhookDeliveries)
+	servers.Get("/:id/variables", handler.GetServerVariables)
+	servers.Put("/:id/variables", handler.UpdateServerVariables)
+
+	// Backups
+	servers.Get("/:id/backups/:backupId/download", authMiddleware.RequirePermission("servers.backup"), handler.DownloadBackup)
+	servers.Post("/:id/backups/:backupId/verify", authMiddleware.RequirePermission("servers.backup"), middleware.Timeout(timeouts.Backup), handler.VerifyBackup)
+	servers.Post("/:id/backups/:backupId/restore", authMiddleware.RequirePermission("servers.backup"), middleware.Timeout(2*timeouts.Backup), handler.RestoreBackup)
+
+	// Minecraft worlds
+	servers.Get("/:id/worlds", authMiddleware.RequirePermission("servers.files"), middleware.Timeout(timeouts.Query), handler.ListWorlds)
+	servers.Delete("/:id/worlds/:worldId", authMiddleware.RequirePermission("servers.files"), handler.DeleteWorld)
+	servers.Get("/:id/worlds/:worldId/backups", authMiddleware.RequirePermission("servers.files"), handler.GetWorldBackups)
+	servers.Post("/:id/worlds/:worldId/backups", authMiddleware.RequirePermission("servers.files"), middleware.Timeout(timeouts.Backup), handler.CreateWorldBackup)
+	servers.Post("/:id/worlds/:worldId/backups/:backupId/restore", authMiddleware.RequirePermission("servers.files"), middleware.Timeout(2*timeouts.Backup), handler.RestoreWorldBackup)
+
+	// Server databases
+	servers.Get("/:id/databases", authMiddleware.RequirePermission("servers.databases"), handler.GetServerDatabases)
+	servers.Post("/:id/databases", authMiddleware.RequirePermission("servers.databases"), handler.CreateServerDatabase)
+	servers.Post("/:id/databases/:databaseId/rotate-password", authMiddleware.RequirePermission("servers.databases"), handler.RotateServerDatabasePassword)
+	servers.Delete("/:id/databases/:databaseId", authMiddleware.RequirePermission("servers.databases"), handler.DeleteServerDatabase)
+
+	// No new WebSockets once shutdown has begun
+	ws := app.Group("/ws", func(c *fiber.Ctx) error {
+		if ops.Draining() {
+			return shutdown.ErrShuttingDown
+		}
+		return c.Next()
+	})
 
-	// WebSocket for real-time console
-	app.Get("/ws/console/:serverId", websocket.New(func(c *websocket.Conn) {
-		handleConsoleWebSocket(c, cfg, rdb)
-	}))
+	// WebSocket for real-time console, with the access checks of the
+	// multiplexed socket
+	ws.Get("/console/:serverId", authMiddleware.Authenticate, serverSocketAccess(db), websocket.New(drainable(ops, func(c *websocket.Conn) {
+		handleConsoleWebSocket(c, cfg, rdb, db, log)
+	})))
 
-	// WebSocket for real-time stats
-	app.Get("/ws/stats/:serverId", websocket.New(func(c *websocket.Conn) {
+	// WebSocket for real-time stats, with the same access checks
+	ws.Get("/stats/:serverId", authMiddleware.Authenticate, serverSocketAccess(db), websocket.New(drainable(ops, func(c *websocket.Conn) {
 		handleStatsWebSocket(c, cfg, rdb)
-	}))
+	})))
+
+	// One WebSocket per server carrying console, stats, status and
+	// notification frames for the channels the client subscribes to
+	ws.Get("/servers/:serverId", authMiddleware.Authenticate, serverSocketAccess(db), websocket.New(drainable(ops, func(c *websocket.Conn) {
+		handleServerSocket(c, cfg, rdb, db, log)
+	})))
+
+	// Live feed of system events and audit logs for admins
+	ws.Get("/events", authMiddleware.Authenticate, authMiddleware.RequirePermission("admin.audit"), websocket.New(drainable(ops, func(c *websocket.Conn) {
+		handleFeedSocket(c, cfg, rdb)
+	})))
 
 	return app
 }
 
-// errorHandler handles errors globally
-func errorHandler(c *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-	message := "Internal Server Error"
+// drainable registers a WebSocket with ops for as long as handler serves it.
+// On shutdown the client is sent a going away close frame and the connection
+// is closed, which ends the handler's reads and writes.
+func drainable(ops *shutdown.Coordinator, handler func(*websocket.Conn)) func(*websocket.Conn) {
+	return func(c *websocket.Conn) {
+		remove, err := ops.Socket(func() {
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			_ = c.Close()
+		})
+		if err != nil {
+			msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "server shutting down")
+			_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			return
+		}
+		defer remove()
 
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
+		handler(c)
 	}
-
-	return c.Status(code).JSON(fiber.Map{
-		"error":   message,
-		"code":    code,
-		"success": false,
-	})
 }
 
 // joinStrings joins a slice of strings with comma
@@ -191,53 +347,69 @@ func joinStrings(s []string) string {
 	return result
 }
 
-// handleConsoleWebSocket handles WebSocket connections for server console
-func handleConsoleWebSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client) {
+// handleStatsWebSocket handles WebSocket connections for server stats
+func handleStatsWebSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client) {
 	serverID := c.Params("serverId")
-	
-	// Subscribe to console channel
-	ctx := context.Background()
-	pubsub := rdb.Subscribe(ctx, "console:"+serverID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribe before reading the cache so no update is missed
+	pubsub := rdb.Subscribe(ctx, agent.StatsKey(serverID))
 	defer pubsub.Close()
 
-	ch := pubsub.Channel()
+	// Send the last known sample immediately
+	if stats, err := rdb.Get(ctx, agent.StatsKey(serverID)); err == nil {
+		if err := c.WriteMessage(websocket.TextMessage, []byte(stats)); err != nil {
+			return
+		}
+		if isServerHalted(stats) {
+			return
+		}
+	}
 
-	// Read messages from Redis and send to client
+	// Detect client disconnects
 	go func() {
-		for msg := range ch {
-			if err := c.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+		defer cancel()
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
 				return
 			}
 		}
 	}()
 
-	// Read messages from client and publish to Redis
+	ch := pubsub.Channel()
 	for {
-		_, msg, err := c.ReadMessage()
-		if err != nil {
-			break
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := c.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+				return
+			}
+			// Stop streaming once the server is no longer running
+			if isServerHalted(msg.Payload) {
+				return
+			}
 		}
-		_ = rdb.Publish(ctx, "console:"+serverID+":input", string(msg))
 	}
 }
 
-// handleStatsWebSocket handles WebSocket connections for server stats
-func handleStatsWebSocket(c *websocket.Conn, cfg *config.Config, rdb *redis.Client) {
-	serverID := c.Params("serverId")
-	
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	ctx := context.Background()
-	for range ticker.C {
-		// Get stats from Redis cache
-		stats, err := rdb.Get(ctx, "stats:"+serverID)
-		if err != nil {
-			continue
-		}
-		
-		if err := c.WriteMessage(websocket.TextMessage, []byte(stats)); err != nil {
-			break
-		}
+// isServerHalted reports whether a stats payload describes a stopped server
+func isServerHalted(payload string) bool {
+	var stats struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(payload), &stats); err != nil {
+		return false
+	}
+
+	switch stats.Status {
+	case "stopped", "exited", "dead", "offline":
+		return true
 	}
+	return false
 }
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/aetherpanel/aether-panel/internal/infrastructure/agent"
+)
+
+func TestIsServerHalted(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    bool
+	}{
+		{agent.StoppedStats, true},
+		{`{"status":"exited","cpu_percent":0}`, true},
+		{`{"status":"offline"}`, true},
+		{`{"status":"running","cpu_percent":12.5}`, false},
+		{`{"status":"starting"}`, false},
+		{`not json`, false},
+	}
+	for _, tt := range tests {
+		if got := isServerHalted(tt.payload); got != tt.want {
+			t.Errorf("isServerHalted(%s) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
@@ -240,11 +240,10 @@ function CreateServerModal({ onClose, onCreated }: { onClose: () => void; onCrea
 
   const gameOptions = [
     { value: 'minecraft-java', label: 'Minecraft Java Edition' },
-    { value: 'minecraft-bedrock', label: 'Minecraft Bedrock Edition' },
     { value: 'rust', label: 'Rust' },
-    { value: 'csgo', label: 'Counter-Strike: Global Offensive' },
-    { value: 'gmod', label: 'Garry\'s Mod' },
-    { value: 'ark', label: 'ARK: Survival Evolved' }
+    { value: 'ark', label: 'ARK: Survival Evolved' },
+    { value: 'valheim', label: 'Valheim' },
+    { value: 'cs2', label: 'Counter-Strike 2' }
   ]
 
   const handleSubmit = async (e: React.FormEvent) => {